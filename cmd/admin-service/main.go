@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -14,11 +15,14 @@ import (
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/handler"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/repository"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/service"
+	ledgerRepository "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/repository"
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/database"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/middleware"
 	adminpb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/admin"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
 )
 
 func main() {
@@ -60,9 +64,32 @@ func main() {
 	defer db.Close()
 	log.Info("✅ Connected to PostgreSQL")
 
+	// Connect to Order Service (order cancellations go through its lifecycle)
+	orderConn, err := grpc.Dial(
+		cfg.Services.OrderService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to Order Service: %v (order cancellation disabled)", err)
+	}
+	defer func() {
+		if orderConn != nil {
+			orderConn.Close()
+		}
+	}()
+
+	var orderClient orderPb.OrderServiceClient
+	if orderConn != nil {
+		orderClient = orderPb.NewOrderServiceClient(orderConn)
+		log.Info("✅ Connected to Order Service")
+	}
+
 	// Initialize repository, service, and handler
 	adminRepo := repository.NewAdminRepository(db)
-	adminService := service.NewAdminService(adminRepo)
+	ledgerServ := ledgerService.NewLedgerService(ledgerRepository.NewLedgerRepository(db), log)
+	adminService := service.NewAdminService(adminRepo, orderClient, ledgerServ)
 	adminHandler := handler.NewAdminHandler(adminService)
 
 	// Create gRPC server with RBAC middleware
@@ -17,11 +17,17 @@ import (
 	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/service"
 	feeRepository "github.com/vvkuzmych/sneakers_marketplace/internal/fees/repository"
 	feeService "github.com/vvkuzmych/sneakers_marketplace/internal/fees/service"
+	fxProvider "github.com/vvkuzmych/sneakers_marketplace/internal/fx/provider"
+	fxRepository "github.com/vvkuzmych/sneakers_marketplace/internal/fx/repository"
+	fxService "github.com/vvkuzmych/sneakers_marketplace/internal/fx/service"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/database"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	pb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/bidding"
 	notificationPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/notification"
+	paymentPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
+	productPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/product"
 )
 
 func main() {
@@ -38,6 +44,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	money.DefaultCurrency = cfg.FX.SettlementCurrency
 
 	// Initialize database
 	ctx := context.Background()
@@ -57,6 +64,17 @@ func main() {
 	feeServ := feeService.NewFeeService(feeRepo, log)
 	log.Info("Fee Service initialized")
 
+	// Initialize FX Service (listings in other currencies settle at these rates)
+	rateProvider, err := fxProvider.New(cfg.FX)
+	if err != nil {
+		log.Fatalf("Failed to initialize rate provider: %v", err)
+	}
+	fxServ := fxService.NewFXService(fxRepository.NewFXRepository(db), rateProvider, cfg.FX, log)
+	if _, err := fxServ.SyncRates(ctx); err != nil {
+		log.Warnf("Failed to sync exchange rates: %v (using stored rates)", err)
+	}
+	log.Infof("FX Service initialized (settlement currency %s, rates from %s)", cfg.FX.SettlementCurrency, rateProvider.Name())
+
 	// Connect to Notification Service
 	notificationConn, err := grpc.Dial(
 		"localhost:50056", // Notification Service port
@@ -79,8 +97,71 @@ func main() {
 		log.Info("Connected to Notification Service")
 	}
 
+	// Connect to Product Service (asks reserve inventory on placement)
+	productConn, err := grpc.Dial(
+		cfg.Services.ProductService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to Product Service: %v (asks will be rejected)", err)
+	}
+	defer func() {
+		if productConn != nil {
+			productConn.Close()
+		}
+	}()
+
+	var productClient productPb.ProductServiceClient
+	if productConn != nil {
+		productClient = productPb.NewProductServiceClient(productConn)
+		log.Info("Connected to Product Service")
+	}
+
+	// Connect to Payment Service (authorization holds on bids)
+	paymentConn, err := grpc.Dial(
+		cfg.Services.PaymentService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to Payment Service: %v (continuing without payment holds)", err)
+	}
+	defer func() {
+		if paymentConn != nil {
+			paymentConn.Close()
+		}
+	}()
+
+	var paymentClient paymentPb.PaymentServiceClient
+	if paymentConn != nil {
+		paymentClient = paymentPb.NewPaymentServiceClient(paymentConn)
+		log.Info("Connected to Payment Service")
+	}
+
+	if cfg.Bidding.RequirePaymentHold {
+		log.Info("Payment holds enabled: bids activate only after authorization")
+	}
+
 	// Initialize service
-	biddingService := service.NewBiddingService(biddingRepo, notificationClient, feeServ)
+	biddingService := service.NewBiddingService(
+		biddingRepo,
+		notificationClient,
+		productClient,
+		paymentClient,
+		feeServ,
+		fxServ,
+		cfg.Bidding.RequirePaymentHold,
+	)
+
+	// Retry bid captures and expire stale bids/asks in the background
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	go biddingService.StartExpiryWorker(workerCtx, cfg.Bidding.ExpiryInterval)
+	log.Infof("Expiry worker started (interval %s)", cfg.Bidding.ExpiryInterval)
+	go fxServ.StartRateSync(workerCtx, cfg.FX.RefreshInterval)
 
 	// Initialize gRPC handler
 	biddingHandler := handler.NewBiddingHandler(biddingService)
@@ -123,6 +204,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down Bidding Service...")
+	stopWorkers()
 	grpcServer.GracefulStop()
 	log.Info("Bidding Service stopped")
 }
@@ -12,13 +12,28 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	feeRepository "github.com/vvkuzmych/sneakers_marketplace/internal/fees/repository"
+	fxProvider "github.com/vvkuzmych/sneakers_marketplace/internal/fx/provider"
+	fxRepository "github.com/vvkuzmych/sneakers_marketplace/internal/fx/repository"
+	fxService "github.com/vvkuzmych/sneakers_marketplace/internal/fx/service"
+	ledgerRepository "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/repository"
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/carrier"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/export"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/handler"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/invoice"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/repository"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/service"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/database"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	biddingPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/bidding"
+	notificationPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/notification"
 	pb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
+	paymentPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
+	productPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/product"
+	userPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/user"
 )
 
 // loggingInterceptor logs all gRPC requests
@@ -60,6 +75,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	money.DefaultCurrency = cfg.FX.SettlementCurrency
 
 	// Use ORDER_SERVICE_PORT env var, or default to 50054
 	orderPort := os.Getenv("ORDER_SERVICE_PORT")
@@ -79,10 +95,219 @@ func main() {
 
 	log.Info("Connected to database")
 
-	// Initialize repository, service, and handler
+	// Connect to Product Service (completed orders finalize inventory)
+	productConn, err := grpc.Dial(
+		cfg.Services.ProductService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to Product Service: %v (continuing without inventory sync)", err)
+	}
+	defer func() {
+		if productConn != nil {
+			productConn.Close()
+		}
+	}()
+
+	var productClient productPb.ProductServiceClient
+	if productConn != nil {
+		productClient = productPb.NewProductServiceClient(productConn)
+		log.Info("Connected to Product Service")
+	}
+
+	// Connect to Notification Service (buyer/seller status updates)
+	notificationConn, err := grpc.Dial(
+		cfg.Services.NotificationService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to Notification Service: %v (continuing without notifications)", err)
+	}
+	defer func() {
+		if notificationConn != nil {
+			notificationConn.Close()
+		}
+	}()
+
+	var notificationClient notificationPb.NotificationServiceClient
+	if notificationConn != nil {
+		notificationClient = notificationPb.NewNotificationServiceClient(notificationConn)
+		log.Info("Connected to Notification Service")
+	}
+
+	// Connect to Payment Service (completed orders trigger seller payouts)
+	paymentConn, err := grpc.Dial(
+		cfg.Services.PaymentService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to Payment Service: %v (continuing without payouts)", err)
+	}
+	defer func() {
+		if paymentConn != nil {
+			paymentConn.Close()
+		}
+	}()
+
+	var paymentClient paymentPb.PaymentServiceClient
+	if paymentConn != nil {
+		paymentClient = paymentPb.NewPaymentServiceClient(paymentConn)
+		log.Info("Connected to Payment Service")
+	}
+
+	// Connect to Bidding Service (unpaid orders relist the seller's ask)
+	biddingConn, err := grpc.Dial(
+		cfg.Services.BiddingService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to Bidding Service: %v (continuing without relisting)", err)
+	}
+	defer func() {
+		if biddingConn != nil {
+			biddingConn.Close()
+		}
+	}()
+
+	var biddingClient biddingPb.BiddingServiceClient
+	if biddingConn != nil {
+		biddingClient = biddingPb.NewBiddingServiceClient(biddingConn)
+		log.Info("Connected to Bidding Service")
+	}
+
+	// Connect to User Service (strikes for non-paying buyers)
+	userConn, err := grpc.Dial(
+		cfg.Services.UserService,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		log.Warnf("Failed to connect to User Service: %v (continuing without buyer strikes)", err)
+	}
+	defer func() {
+		if userConn != nil {
+			userConn.Close()
+		}
+	}()
+
+	var userClient userPb.UserServiceClient
+	if userConn != nil {
+		userClient = userPb.NewUserServiceClient(userConn)
+		log.Info("Connected to User Service")
+	}
+
+	// Initialize shipping label provider
+	var carrierProvider carrier.Provider
+	switch cfg.Shipping.CarrierProvider {
+	case "fake":
+		carrierProvider = carrier.NewFakeProvider()
+	default:
+		log.Fatalf("Unknown carrier provider: %s", cfg.Shipping.CarrierProvider)
+	}
+	log.Infof("Carrier provider: %s", cfg.Shipping.CarrierProvider)
+
+	authCenter := carrier.Address{
+		Name:       cfg.Shipping.AuthCenterName,
+		Street1:    cfg.Shipping.AuthCenterStreet,
+		City:       cfg.Shipping.AuthCenterCity,
+		State:      cfg.Shipping.AuthCenterState,
+		PostalCode: cfg.Shipping.AuthCenterPostalCode,
+		Country:    cfg.Shipping.AuthCenterCountry,
+	}
+
+	// Buyers pay in their currency at the stored rates; the Bidding Service
+	// keeps them in sync
+	rateProvider, err := fxProvider.New(cfg.FX)
+	if err != nil {
+		log.Fatalf("Failed to initialize rate provider: %v", err)
+	}
+	fxServ := fxService.NewFXService(fxRepository.NewFXRepository(db), rateProvider, cfg.FX, log)
+
+	// Completed sales are posted to the ledger
+	ledgerServ := ledgerService.NewLedgerService(ledgerRepository.NewLedgerRepository(db), log)
+
+	// Initialize repository, service, and handler. Fees recorded at match
+	// time are reversed when the order is refunded or canceled.
 	orderRepo := repository.NewOrderRepository(db)
-	orderService := service.NewOrderService(orderRepo)
-	orderHandler := handler.NewOrderHandler(orderService)
+	feeRepo := feeRepository.NewFeeRepository(db)
+	orderService := service.NewOrderService(
+		orderRepo,
+		productClient,
+		notificationClient,
+		paymentClient,
+		biddingClient,
+		userClient,
+		carrierProvider,
+		authCenter,
+		fxServ,
+		ledgerServ,
+		feeRepo,
+	)
+
+	// Receipts and sale statements use the fees recorded at match time
+	invoiceService := service.NewInvoiceService(
+		orderRepo,
+		feeRepo,
+		invoice.Party{
+			Name:         cfg.Invoice.IssuerName,
+			AddressLines: cfg.Invoice.IssuerAddress,
+			TaxID:        cfg.Invoice.IssuerTaxID,
+		},
+		invoice.TaxPolicy{
+			Label: cfg.Invoice.TaxLabel,
+			Rate:  cfg.Invoice.TaxRate,
+		},
+	)
+
+	exportService := service.NewExportService(orderRepo, notificationClient, export.Threshold{
+		Gross:  money.FromMajor(cfg.Order.TaxReportGrossThreshold, money.DefaultCurrency, money.HalfUp),
+		Orders: cfg.Order.TaxReportOrderThreshold,
+	})
+	orderHandler := handler.NewOrderHandler(orderService, invoiceService, exportService)
+
+	// Background workers
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+
+	// Cancel orders the buyer never paid for
+	go orderService.StartUnpaidOrderWorker(workerCtx, cfg.Order.UnpaidCheckInterval, service.UnpaidOrderPolicy{
+		Timeout:      cfg.Order.UnpaidTimeout,
+		RelistAsk:    cfg.Order.RelistUnpaidAsk,
+		StrikePoints: int32(cfg.Order.UnpaidStrikePoints),
+	})
+	log.Infof("Unpaid order worker started (timeout=%v, relist=%v)", cfg.Order.UnpaidTimeout, cfg.Order.RelistUnpaidAsk)
+
+	// Complete delivered orders once the buyer's inspection window closes
+	go orderService.StartAutoCompleteWorker(workerCtx, cfg.Order.AutoCompleteInterval, service.AutoCompletePolicy{
+		InspectionDays: cfg.Order.InspectionDays,
+		ReminderBefore: cfg.Order.InspectionReminder,
+	})
+	log.Infof("Auto-complete worker started (inspection=%dd, reminder=%v)", cfg.Order.InspectionDays, cfg.Order.InspectionReminder)
+
+	// Cancel and refund orders the seller did not ship in time
+	go orderService.StartShippingSLAWorker(workerCtx, cfg.Order.ShippingSLAInterval, service.ShippingSLAPolicy{
+		ShipWithinHours: cfg.Order.ShipWithinHours,
+		ReminderBefore:  cfg.Order.ShipReminder,
+		PenaltyRate:     cfg.Order.LateShipPenaltyRate,
+	})
+	log.Infof("Shipping SLA worker started (ship within=%dh, penalty=%.0f%%)", cfg.Order.ShipWithinHours, cfg.Order.LateShipPenaltyRate*100)
+
+	// Retry status hooks (refunds, payouts, ledger postings) that failed
+	go orderService.StartHookWorker(workerCtx, cfg.Order.HookRetryInterval)
+	log.Infof("Hook worker started (interval=%v)", cfg.Order.HookRetryInterval)
+
+	// Build queued seller sales exports
+	go exportService.StartExportWorker(workerCtx, cfg.Order.ExportInterval)
+	log.Infof("Export worker started (interval=%v)", cfg.Order.ExportInterval)
 
 	// Create gRPC server
 	grpcServer := grpc.NewServer(
@@ -117,6 +342,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down Order Service...")
+	stopWorkers()
 	grpcServer.GracefulStop()
 	log.Info("Order Service stopped")
 }
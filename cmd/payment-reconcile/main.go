@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+
+	ledgerRepository "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/repository"
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/reconcile"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/service"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/database"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
+)
+
+const dateLayout = "2006-01-02"
+
+// payment-reconcile compares the payment provider's charges, refunds and
+// transfers for a date range with the payments and payouts tables. It
+// exits with status 1 while mismatches remain unresolved.
+//
+//	payment-reconcile -from 2026-10-01 -to 2026-10-08 [-repair] [-json]
+func main() {
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(dateLayout)
+
+	fromFlag := flag.String("from", yesterday, "first day to reconcile (YYYY-MM-DD, UTC)")
+	toFlag := flag.String("to", "", "day after the last day to reconcile (default: the day after -from)")
+	repair := flag.Bool("repair", false, "repair mismatches a missed webhook explains")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	log := logger.New(logger.Config{
+		Level:  "info",
+		Format: "console",
+		Output: os.Stderr,
+	})
+
+	from, err := time.Parse(dateLayout, *fromFlag)
+	if err != nil {
+		log.Fatalf("Invalid -from date: %v", err)
+	}
+	to := from.AddDate(0, 0, 1)
+	if *toFlag != "" {
+		if to, err = time.Parse(dateLayout, *toFlag); err != nil {
+			log.Fatalf("Invalid -to date: %v", err)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	money.DefaultCurrency = cfg.FX.SettlementCurrency
+
+	ctx := context.Background()
+	db, err := database.NewPostgresPool(ctx, database.PostgresConfig{
+		URL: cfg.Database.URL,
+	}, log)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	paymentProvider, err := provider.New(cfg.Stripe)
+	if err != nil {
+		log.Fatalf("Failed to initialize payment provider: %v", err)
+	}
+
+	// Repairs move orders through the Order Service like webhooks do
+	var orderClient orderPb.OrderServiceClient
+	if *repair {
+		orderConn, err := grpc.Dial(cfg.Services.OrderService, grpc.WithInsecure())
+		if err != nil {
+			log.Fatalf("Failed to set up Order Service client: %v", err)
+		}
+		defer orderConn.Close()
+		orderClient = orderPb.NewOrderServiceClient(orderConn)
+	}
+
+	ledgerServ := ledgerService.NewLedgerService(ledgerRepository.NewLedgerRepository(db), log)
+	paymentService := service.NewPaymentService(repository.NewPaymentRepository(db), paymentProvider, orderClient, ledgerServ, service.PayoutPolicy{
+		DefaultSchedule: cfg.Payout.DefaultSchedule,
+		MinimumPayout:   money.FromMajor(cfg.Payout.MinimumPayout, money.DefaultCurrency, money.HalfUp),
+	})
+
+	log.Infof("Reconciling %s payments from %s to %s", paymentProvider.Name(), from.Format(dateLayout), to.Format(dateLayout))
+
+	report, err := paymentService.ReconcilePayments(ctx, from, to, *repair)
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+	} else {
+		printReport(report)
+	}
+
+	if report.Unresolved() > 0 {
+		os.Exit(1)
+	}
+}
+
+func printReport(report *reconcile.Report) {
+	fmt.Printf("Checked %d charges, %d refunds and %d transfers from %s to %s\n",
+		report.Charges, report.Refunds, report.Transfers,
+		report.From.Format(dateLayout), report.To.Format(dateLayout))
+
+	for _, m := range report.Mismatches {
+		state := "needs review"
+		switch {
+		case m.Repaired:
+			state = "repaired"
+		case m.RepairError != "":
+			state = "repair failed: " + m.RepairError
+		case m.Repair != "":
+			state = "repairable (" + m.Repair + ")"
+		}
+
+		ref := m.ProviderID
+		if ref == "" {
+			ref = "-"
+		}
+		fmt.Printf("  %-20s %-9s %-24s %s [%s]\n", m.Kind, m.Object, ref, m.Detail, state)
+	}
+
+	fmt.Printf("%d mismatches, %d unresolved\n", len(report.Mismatches), report.Unresolved())
+}
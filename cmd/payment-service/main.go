@@ -12,12 +12,17 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	ledgerRepository "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/repository"
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/handler"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/repository"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/service"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/database"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
 	pb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
 )
 
@@ -59,6 +64,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	money.DefaultCurrency = cfg.FX.SettlementCurrency
 
 	// Use PAYMENT_SERVICE_PORT env var, or default to 50055
 	paymentPort := os.Getenv("PAYMENT_SERVICE_PORT")
@@ -78,11 +84,50 @@ func main() {
 
 	log.Info("Connected to database")
 
+	// Connect to Order Service (webhooks move orders to paid/refunded). The
+	// Order Service also dials us, so connect lazily instead of blocking on
+	// whichever starts second.
+	orderConn, err := grpc.Dial(
+		cfg.Services.OrderService,
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		log.Warnf("Failed to set up Order Service client: %v (webhooks will not update orders)", err)
+	}
+	defer func() {
+		if orderConn != nil {
+			orderConn.Close()
+		}
+	}()
+
+	var orderClient orderPb.OrderServiceClient
+	if orderConn != nil {
+		orderClient = orderPb.NewOrderServiceClient(orderConn)
+	}
+
+	// Initialize payment provider (STRIPE_MODE=real calls Stripe, otherwise the in-process fake)
+	paymentProvider, err := provider.New(cfg.Stripe)
+	if err != nil {
+		log.Fatalf("Failed to initialize payment provider: %v", err)
+	}
+	log.Infof("Using payment provider: %s", paymentProvider.Name())
+
 	// Initialize repository, service, and handler
+	ledgerServ := ledgerService.NewLedgerService(ledgerRepository.NewLedgerRepository(db), log)
 	paymentRepo := repository.NewPaymentRepository(db)
-	paymentService := service.NewPaymentService(paymentRepo)
+	paymentService := service.NewPaymentService(paymentRepo, paymentProvider, orderClient, ledgerServ, service.PayoutPolicy{
+		DefaultSchedule: cfg.Payout.DefaultSchedule,
+		MinimumPayout:   money.FromMajor(cfg.Payout.MinimumPayout, money.DefaultCurrency, money.HalfUp),
+	})
 	paymentHandler := handler.NewPaymentHandler(paymentService)
 
+	// Pay out seller wallets whose scheduled batch is due
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+
+	go paymentService.StartPayoutWorker(workerCtx, cfg.Payout.BatchInterval)
+	log.Infof("Payout worker started (interval=%v, default schedule=%s)", cfg.Payout.BatchInterval, cfg.Payout.DefaultSchedule)
+
 	// Create gRPC server
 	grpcServer := grpc.NewServer(
 		grpc.UnaryInterceptor(loggingInterceptor(log)),
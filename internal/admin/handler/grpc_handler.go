@@ -10,7 +10,9 @@ import (
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/service"
+	orderModel "github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/middleware"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	adminpb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/admin"
 )
 
@@ -185,23 +187,38 @@ func (h *AdminHandler) UpdateUserRole(ctx context.Context, req *adminpb.UpdateUs
 // ListAllOrders retrieves all orders
 func (h *AdminHandler) ListAllOrders(ctx context.Context, req *adminpb.ListAllOrdersRequest) (*adminpb.ListAllOrdersResponse, error) {
 	params := model.ListOrdersParams{
-		Page:      req.Page,
-		PageSize:  req.PageSize,
-		Status:    req.Status,
-		SortBy:    req.SortBy,
-		SortOrder: req.SortOrder,
+		Page: req.Page,
+		Search: orderModel.OrderSearch{
+			BuyerID:           req.BuyerId,
+			SellerID:          req.SellerId,
+			CounterpartyID:    req.CounterpartyId,
+			ProductID:         req.ProductId,
+			SKU:               req.Sku,
+			SizeID:            req.SizeId,
+			MinPrice:          money.FromProto(0, req.MinPrice, money.DefaultCurrency),
+			MaxPrice:          money.FromProto(0, req.MaxPrice, money.DefaultCurrency),
+			OrderNumberPrefix: req.OrderNumberPrefix,
+			Carrier:           req.Carrier,
+			SortBy:            req.SortBy,
+			SortOrder:         req.SortOrder,
+			Cursor:            req.Cursor,
+			Limit:             req.PageSize,
+		},
+	}
+
+	if req.Status != "" && req.Status != "all" {
+		params.Search.Statuses = []string{req.Status}
 	}
-
 	if req.DateFrom != nil {
 		t := req.DateFrom.AsTime()
-		params.DateFrom = &t
+		params.Search.CreatedFrom = &t
 	}
 	if req.DateTo != nil {
 		t := req.DateTo.AsTime()
-		params.DateTo = &t
+		params.Search.CreatedTo = &t
 	}
 
-	orders, total, err := h.service.ListAllOrders(ctx, params)
+	orders, total, nextCursor, err := h.service.ListAllOrders(ctx, params)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -212,10 +229,11 @@ func (h *AdminHandler) ListAllOrders(ctx context.Context, req *adminpb.ListAllOr
 	}
 
 	return &adminpb.ListAllOrdersResponse{
-		Orders:   pbOrders,
-		Total:    total,
-		Page:     req.Page,
-		PageSize: req.PageSize,
+		Orders:     pbOrders,
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		NextCursor: nextCursor,
 	}, nil
 }
 
@@ -438,6 +456,155 @@ func (h *AdminHandler) GetUserActivityReport(ctx context.Context, req *adminpb.G
 	}, nil
 }
 
+// GetSellerShippingMetrics retrieves per-seller shipping SLA metrics
+func (h *AdminHandler) GetSellerShippingMetrics(ctx context.Context, req *adminpb.GetSellerShippingMetricsRequest) (*adminpb.GetSellerShippingMetricsResponse, error) {
+	if req.DateFrom == nil || req.DateTo == nil {
+		return nil, status.Error(codes.InvalidArgument, "date_from and date_to are required")
+	}
+
+	params := model.GetSellerShippingMetricsParams{
+		DateFrom: req.DateFrom.AsTime(),
+		DateTo:   req.DateTo.AsTime(),
+		SellerID: req.SellerId,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}
+
+	metrics, total, err := h.service.GetSellerShippingMetrics(ctx, params)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	pbSellers := make([]*adminpb.SellerShippingMetrics, len(metrics))
+	for i, m := range metrics {
+		pbSellers[i] = &adminpb.SellerShippingMetrics{
+			SellerId:          m.SellerID,
+			SellerEmail:       m.SellerEmail,
+			PaidOrders:        m.PaidOrders,
+			ShippedOrders:     m.ShippedOrders,
+			ShippedOnTime:     m.ShippedOnTime,
+			LateCancellations: m.LateCancellations,
+			AwaitingShipment:  m.AwaitingShipment,
+			AvgHoursToShip:    m.AvgHoursToShip,
+			MaxHoursToShip:    m.MaxHoursToShip,
+			OnTimeRate:        m.OnTimeRate,
+			PenaltyTotal:      m.PenaltyTotal,
+		}
+	}
+
+	return &adminpb.GetSellerShippingMetricsResponse{
+		Sellers:  pbSellers,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+// GetHeldFunds retrieves the funds held for sellers
+func (h *AdminHandler) GetHeldFunds(ctx context.Context, req *adminpb.GetHeldFundsRequest) (*adminpb.GetHeldFundsResponse, error) {
+	params := model.GetHeldFundsParams{
+		SellerID: req.SellerId,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}
+
+	funds, total, err := h.service.GetHeldFunds(ctx, params)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	pbSellers := make([]*adminpb.SellerHeldFunds, len(funds))
+	for i, f := range funds {
+		pbSellers[i] = &adminpb.SellerHeldFunds{
+			SellerId:                f.SellerID,
+			SellerEmail:             f.SellerEmail,
+			Currency:                f.Currency,
+			AwaitingCompletionMinor: f.AwaitingCompletion.Amount,
+			DisputedMinor:           f.Disputed.Amount,
+			FrozenMinor:             f.Frozen.Amount,
+			WalletBalanceMinor:      f.WalletBalance.Amount,
+			OwedMinor:               f.Owed.Amount,
+		}
+	}
+
+	return &adminpb.GetHeldFundsResponse{
+		Sellers:  pbSellers,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+// ==================== Ledger ====================
+
+// GetLedgerBalances lists ledger accounts and their balances
+func (h *AdminHandler) GetLedgerBalances(ctx context.Context, req *adminpb.GetLedgerBalancesRequest) (*adminpb.GetLedgerBalancesResponse, error) {
+	accounts, err := h.service.GetLedgerBalances(ctx, req.AccountType, req.OwnerId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	pbAccounts := make([]*adminpb.LedgerAccount, len(accounts))
+	for i, a := range accounts {
+		pbAccounts[i] = &adminpb.LedgerAccount{
+			Id:           a.ID,
+			Type:         a.Type,
+			OwnerId:      a.OwnerID,
+			Currency:     a.Currency,
+			BalanceMinor: a.Balance.Amount,
+			UpdatedAt:    timestamppb.New(a.UpdatedAt),
+		}
+	}
+
+	return &adminpb.GetLedgerBalancesResponse{Accounts: pbAccounts}, nil
+}
+
+// CheckLedger proves the ledger balances
+func (h *AdminHandler) CheckLedger(ctx context.Context, req *adminpb.CheckLedgerRequest) (*adminpb.CheckLedgerResponse, error) {
+	report, err := h.service.CheckLedger(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &adminpb.CheckLedgerResponse{
+		Ok:         report.OK(),
+		Entries:    report.Entries,
+		Lines:      report.Lines,
+		Totals:     make([]*adminpb.LedgerCurrencyTotals, len(report.Totals)),
+		Unbalanced: make([]*adminpb.LedgerUnbalancedEntry, len(report.Unbalanced)),
+		Drift:      make([]*adminpb.LedgerBalanceDrift, len(report.Drift)),
+		CheckedAt:  timestamppb.New(report.CheckedAt),
+	}
+	for i, t := range report.Totals {
+		resp.Totals[i] = &adminpb.LedgerCurrencyTotals{
+			Currency:     t.Currency,
+			DebitsMinor:  t.Debits.Amount,
+			CreditsMinor: t.Credits.Amount,
+		}
+	}
+	for i, u := range report.Unbalanced {
+		resp.Unbalanced[i] = &adminpb.LedgerUnbalancedEntry{
+			EntryId:      u.EntryID,
+			Key:          u.Key,
+			Currency:     u.Currency,
+			DebitsMinor:  u.Debits.Amount,
+			CreditsMinor: u.Credits.Amount,
+		}
+	}
+	for i, d := range report.Drift {
+		resp.Drift[i] = &adminpb.LedgerBalanceDrift{
+			AccountId:     d.AccountID,
+			Type:          d.Type,
+			OwnerId:       d.OwnerID,
+			Currency:      d.Stored.Currency,
+			StoredMinor:   d.Stored.Amount,
+			ComputedMinor: d.Computed.Amount,
+		}
+	}
+
+	return resp, nil
+}
+
 // ==================== System Health ====================
 
 // GetSystemHealth retrieves system health status
@@ -9,7 +9,7 @@ import (
 type AuditLog struct {
 	CreatedAt  time.Time
 	Details    map[string]interface{}
-	AdminEmail string
+	AdminEmail string // empty for buyer/seller dispute steps
 	ActionType string
 	EntityType string
 	IPAddress  string
@@ -27,6 +27,12 @@ const (
 	ActionOrderCancelled  = "order_canceled"
 	ActionProductFeatured = "product_featured"
 	ActionProductHidden   = "product_hidden"
+
+	// Dispute steps are logged by the Order Service; buyer and seller
+	// steps have no admin_id
+	ActionDisputeOpened          = "dispute_opened"
+	ActionDisputeSellerResponded = "dispute_seller_responded"
+	ActionDisputeResolved        = "dispute_resolved"
 )
 
 // EntityType types
@@ -34,6 +40,7 @@ const (
 	EntityUser    = "user"
 	EntityOrder   = "order"
 	EntityProduct = "product"
+	EntityDispute = "dispute"
 )
 
 // CreateAuditLogParams contains parameters for creating an audit log
@@ -88,11 +95,16 @@ func IsValidActionType(actionType string) bool {
 		ActionOrderCancelled:  true,
 		ActionProductFeatured: true,
 		ActionProductHidden:   true,
+
+		ActionDisputeOpened:          true,
+		ActionDisputeSellerResponded: true,
+		ActionDisputeResolved:        true,
 	}
 	return validTypes[actionType]
 }
 
 // IsValidEntityType checks if entity type is valid
 func IsValidEntityType(entityType string) bool {
-	return entityType == EntityUser || entityType == EntityOrder || entityType == EntityProduct
+	return entityType == EntityUser || entityType == EntityOrder || entityType == EntityProduct ||
+		entityType == EntityDispute
 }
@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	orderModel "github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
 
 // PlatformStats represents overall platform statistics
 type PlatformStats struct {
@@ -46,6 +51,33 @@ type UserActivityReport struct {
 	TotalMatchesCreated int32
 }
 
+// SellerShippingMetrics summarizes how fast a seller ships paid orders
+type SellerShippingMetrics struct {
+	SellerEmail       string
+	SellerID          int64
+	PaidOrders        int32   // orders paid in the period
+	ShippedOrders     int32   // of those, shipped
+	ShippedOnTime     int32   // shipped by the deadline
+	LateCancellations int32   // canceled for missing the deadline
+	AwaitingShipment  int32   // paid, not shipped, deadline not reached yet
+	AvgHoursToShip    float64 // paid -> shipped
+	MaxHoursToShip    float64
+	OnTimeRate        float64 // on-time shipments / (shipments + late cancellations)
+	PenaltyTotal      float64 // late shipment penalties in the period
+}
+
+// SellerHeldFunds is what the platform holds for a seller in one currency
+type SellerHeldFunds struct {
+	SellerEmail        string
+	Currency           string
+	SellerID           int64
+	AwaitingCompletion money.Money // paid orders not completed yet
+	Disputed           money.Money // orders with an open dispute
+	Frozen             money.Money // wallet funds held by open chargebacks
+	WalletBalance      money.Money // includes the frozen funds
+	Owed               money.Money // clawbacks not yet recovered from sales
+}
+
 // OrderSummary represents an order summary for admin view
 type OrderSummary struct {
 	CreatedAt   time.Time
@@ -131,15 +163,27 @@ type GetUserActivityReportParams struct {
 	DateTo   time.Time
 }
 
-// ListOrdersParams contains parameters for listing orders
+// GetSellerShippingMetricsParams contains parameters for seller SLA metrics
+type GetSellerShippingMetricsParams struct {
+	DateFrom time.Time
+	DateTo   time.Time
+	SellerID int64 // 0 = all sellers, worst first
+	Page     int32
+	PageSize int32
+}
+
+// GetHeldFundsParams contains parameters for the held funds report
+type GetHeldFundsParams struct {
+	SellerID int64 // 0 = all sellers
+	Page     int32
+	PageSize int32
+}
+
+// ListOrdersParams contains parameters for listing orders. The filters,
+// sort and cursor are the order search's; Search.Limit is the page size.
 type ListOrdersParams struct {
-	DateFrom  *time.Time
-	DateTo    *time.Time
-	Status    string
-	SortBy    string
-	SortOrder string
-	Page      int32
-	PageSize  int32
+	Search orderModel.OrderSearch
+	Page   int32 // offset pagination; ignored when Search.Cursor is set
 }
 
 // ListProductsParams contains parameters for listing products
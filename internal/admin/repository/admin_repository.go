@@ -271,10 +271,10 @@ func (r *AdminRepository) CreateAuditLog(ctx context.Context, params model.Creat
 // ListAuditLogs retrieves a paginated list of audit logs
 func (r *AdminRepository) ListAuditLogs(ctx context.Context, params model.ListAuditLogsParams) ([]model.AuditLog, int32, error) {
 	query := `
-		SELECT al.id, al.admin_id, u.email as admin_email, al.action_type, 
-		       al.entity_type, al.entity_id, al.details, al.ip_address, al.created_at
+		SELECT al.id, COALESCE(al.admin_id, 0), COALESCE(u.email, '') as admin_email, al.action_type, 
+		       al.entity_type, al.entity_id, al.details, COALESCE(al.ip_address, ''), al.created_at
 		FROM audit_logs al
-		JOIN users u ON u.id = al.admin_id
+		LEFT JOIN users u ON u.id = al.admin_id
 		WHERE 1=1
 	`
 	args := []interface{}{}
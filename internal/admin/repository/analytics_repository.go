@@ -6,6 +6,9 @@ import (
 	"time"
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/model"
+	orderModel "github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	orderRepository "github.com/vvkuzmych/sneakers_marketplace/internal/order/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // ==================== Platform Statistics ====================
@@ -141,90 +144,226 @@ func (r *AdminRepository) GetUserActivityReport(ctx context.Context, params mode
 	return &report, nil
 }
 
-// ==================== Order Management ====================
+// ==================== Seller Shipping SLA ====================
 
-// ListAllOrders retrieves all orders for admin view
-func (r *AdminRepository) ListAllOrders(ctx context.Context, params model.ListOrdersParams) ([]model.OrderSummary, int32, error) {
+// GetSellerShippingMetrics retrieves per-seller shipping SLA metrics for
+// orders paid in the period, sellers with the most late cancellations first
+func (r *AdminRepository) GetSellerShippingMetrics(ctx context.Context, params model.GetSellerShippingMetricsParams) ([]model.SellerShippingMetrics, int32, error) {
 	query := `
-		SELECT 
-			o.id, o.order_number, o.buyer_id, o.seller_id,
-			buyer.email as buyer_email, seller.email as seller_email,
-			o.product_id, p.name as product_name,
-			o.subtotal, o.buyer_fee, o.seller_fee, o.total, o.status, o.created_at
+		SELECT
+			o.seller_id, u.email,
+			COUNT(*) as paid_orders,
+			COUNT(o.shipped_at) as shipped_orders,
+			COUNT(*) FILTER (WHERE o.shipped_at <= o.ship_by) as shipped_on_time,
+			COUNT(sp.id) as late_cancellations,
+			COUNT(*) FILTER (WHERE o.status IN ('paid', 'processing') AND o.ship_by > NOW()) as awaiting_shipment,
+			COALESCE(AVG(EXTRACT(EPOCH FROM o.shipped_at - o.payment_at) / 3600), 0) as avg_hours_to_ship,
+			COALESCE(MAX(EXTRACT(EPOCH FROM o.shipped_at - o.payment_at) / 3600), 0) as max_hours_to_ship,
+			COALESCE(SUM(sp.amount), 0) as penalty_total
 		FROM orders o
-		JOIN users buyer ON buyer.id = o.buyer_id
-		JOIN users seller ON seller.id = o.seller_id
-		JOIN products p ON p.id = o.product_id
-		WHERE 1=1
+		JOIN users u ON u.id = o.seller_id
+		LEFT JOIN seller_penalties sp ON sp.order_id = o.id AND sp.reason = 'late_shipment'
+		WHERE o.payment_at >= $1 AND o.payment_at <= $2
+			AND ($3 = 0 OR o.seller_id = $3)
+		GROUP BY o.seller_id, u.email
 	`
-	args := []interface{}{}
-	argCount := 1
+	args := []interface{}{params.DateFrom, params.DateTo, params.SellerID}
 
-	// Apply filters
-	if params.Status != "" && params.Status != "all" {
-		query += fmt.Sprintf(" AND o.status = $%d", argCount)
-		args = append(args, params.Status)
-		argCount++
+	// Count total
+	countQuery := "SELECT COUNT(*) FROM (" + query + ") AS filtered"
+	var total int32
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count sellers: %w", err)
 	}
 
-	if params.DateFrom != nil {
-		query += fmt.Sprintf(" AND o.created_at >= $%d", argCount)
-		args = append(args, *params.DateFrom)
-		argCount++
+	query += " ORDER BY late_cancellations DESC, avg_hours_to_ship DESC, o.seller_id ASC LIMIT $4 OFFSET $5"
+	offset := (params.Page - 1) * params.PageSize
+	args = append(args, params.PageSize, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get seller shipping metrics: %w", err)
 	}
+	defer rows.Close()
 
-	if params.DateTo != nil {
-		query += fmt.Sprintf(" AND o.created_at <= $%d", argCount)
-		args = append(args, *params.DateTo)
-		argCount++
+	metrics := []model.SellerShippingMetrics{}
+	for rows.Next() {
+		var m model.SellerShippingMetrics
+		err := rows.Scan(
+			&m.SellerID, &m.SellerEmail,
+			&m.PaidOrders, &m.ShippedOrders, &m.ShippedOnTime, &m.LateCancellations, &m.AwaitingShipment,
+			&m.AvgHoursToShip, &m.MaxHoursToShip, &m.PenaltyTotal,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan seller shipping metrics: %w", err)
+		}
+		if settled := m.ShippedOrders + m.LateCancellations; settled > 0 {
+			m.OnTimeRate = float64(m.ShippedOnTime) / float64(settled)
+		}
+		metrics = append(metrics, m)
 	}
 
+	return metrics, total, nil
+}
+
+// ==================== Held Funds ====================
+
+// GetHeldFunds retrieves what the platform holds per seller and currency:
+// proceeds of orders not completed yet, orders under an open dispute, wallet
+// funds frozen by chargebacks and negative balances still owed. Sellers with
+// nothing held or owed are left out.
+func (r *AdminRepository) GetHeldFunds(ctx context.Context, params model.GetHeldFundsParams) ([]model.SellerHeldFunds, int32, error) {
+	query := `
+		WITH held_orders AS (
+			SELECT
+				o.seller_id, o.currency,
+				COALESCE(SUM(o.seller_payout) FILTER (WHERE d.id IS NULL), 0) as awaiting,
+				COALESCE(SUM(o.seller_payout) FILTER (WHERE d.id IS NOT NULL), 0) as disputed
+			FROM orders o
+			LEFT JOIN disputes d ON d.order_id = o.id AND d.status <> 'resolved'
+			WHERE o.status IN ('paid', 'processing', 'shipped', 'received_at_authentication', 'authenticated', 'delivered')
+				OR (o.status = 'completed' AND d.id IS NOT NULL)
+			GROUP BY o.seller_id, o.currency
+		)
+		SELECT
+			COALESCE(h.seller_id, w.seller_id) as seller_id, u.email,
+			COALESCE(h.currency, w.currency) as currency,
+			COALESCE(h.awaiting, 0)::float8, COALESCE(h.disputed, 0)::float8,
+			COALESCE(w.frozen_balance, 0), COALESCE(w.balance, 0)
+		FROM held_orders h
+		FULL OUTER JOIN seller_wallets w ON w.seller_id = h.seller_id AND w.currency = h.currency
+		JOIN users u ON u.id = COALESCE(h.seller_id, w.seller_id)
+		WHERE ($1 = 0 OR COALESCE(h.seller_id, w.seller_id) = $1)
+			AND (h.seller_id IS NOT NULL OR w.frozen_balance > 0 OR w.balance < 0)
+	`
+	args := []interface{}{params.SellerID}
+
 	// Count total
 	countQuery := "SELECT COUNT(*) FROM (" + query + ") AS filtered"
 	var total int32
 	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count orders: %w", err)
+		return nil, 0, fmt.Errorf("failed to count sellers: %w", err)
+	}
+
+	query += " ORDER BY seller_id ASC, currency ASC LIMIT $2 OFFSET $3"
+	offset := (params.Page - 1) * params.PageSize
+	args = append(args, params.PageSize, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get held funds: %w", err)
+	}
+	defer rows.Close()
+
+	funds := []model.SellerHeldFunds{}
+	for rows.Next() {
+		var (
+			f                  model.SellerHeldFunds
+			awaiting, disputed float64
+			frozen, balance    int64
+		)
+		if err := rows.Scan(&f.SellerID, &f.SellerEmail, &f.Currency, &awaiting, &disputed, &frozen, &balance); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan held funds: %w", err)
+		}
+
+		f.AwaitingCompletion = money.FromMajor(awaiting, f.Currency, money.HalfUp)
+		f.Disputed = money.FromMajor(disputed, f.Currency, money.HalfUp)
+		f.Frozen = money.New(frozen, f.Currency)
+		f.WalletBalance = money.New(balance, f.Currency)
+		f.Owed = money.Zero(f.Currency)
+		if f.WalletBalance.IsNegative() {
+			f.Owed = f.WalletBalance.Neg()
+		}
+		funds = append(funds, f)
 	}
 
-	// Apply sorting
-	sortBy := "o.created_at"
-	if params.SortBy == "total_amount" {
-		sortBy = "o.total"
+	return funds, total, rows.Err()
+}
+
+// ==================== Order Management ====================
+
+// ListAllOrders retrieves all orders for admin view, filtered and sorted
+// like the order search. With a cursor it pages by keyset instead of
+// offset; nextCursor is empty on the last page.
+func (r *AdminRepository) ListAllOrders(ctx context.Context, params model.ListOrdersParams) ([]model.OrderSummary, int32, string, error) {
+	search := &params.Search
+	q, err := orderRepository.BuildOrderSearch(search, "o")
+	if err != nil {
+		return nil, 0, "", err
 	}
-	sortOrder := "DESC"
-	if params.SortOrder == "asc" {
-		sortOrder = "ASC"
+
+	query := `
+		SELECT 
+			o.id, o.order_number, o.buyer_id, o.seller_id,
+			buyer.email as buyer_email, seller.email as seller_email,
+			o.product_id, p.name as product_name,
+			o.price, o.buyer_fee, o.seller_fee, o.total_amount, o.status, o.created_at
+		FROM orders o
+		JOIN users buyer ON buyer.id = o.buyer_id
+		JOIN users seller ON seller.id = o.seller_id
+		JOIN products p ON p.id = o.product_id
+		WHERE ` + q.Where
+
+	// Count total
+	countQuery := "SELECT COUNT(*) FROM (" + query + ") AS filtered"
+	var total int32
+	err = r.db.QueryRow(ctx, countQuery, q.Args...).Scan(&total)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to count orders: %w", err)
 	}
-	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
 
-	// Add pagination
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	offset := (params.Page - 1) * params.PageSize
-	args = append(args, params.PageSize, offset)
+	args := append(q.Args, q.KeysetArgs...)
+	if q.Keyset != "" {
+		query += " AND " + q.Keyset
+	}
+	query += " ORDER BY " + q.OrderBy
+
+	// Add pagination; one extra row tells us there is another page
+	query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+	args = append(args, search.Limit+1)
+	if q.Keyset == "" {
+		query += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+		args = append(args, (params.Page-1)*search.Limit)
+	}
 
 	// Execute query
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list orders: %w", err)
+		return nil, 0, "", fmt.Errorf("failed to list orders: %w", err)
 	}
 	defer rows.Close()
 
 	orders := []model.OrderSummary{}
+	keys := []*orderModel.Order{} // sort keys of the rows, for the cursor
 	for rows.Next() {
 		var o model.OrderSummary
+		key := &orderModel.Order{}
 		err := rows.Scan(
 			&o.ID, &o.OrderNumber, &o.BuyerID, &o.SellerID,
 			&o.BuyerEmail, &o.SellerEmail, &o.ProductID, &o.ProductName,
-			&o.Subtotal, &o.BuyerFee, &o.SellerFee, &o.Total, &o.Status, &o.CreatedAt,
+			&key.Price, &o.BuyerFee, &o.SellerFee, &key.TotalAmount, &o.Status, &o.CreatedAt,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
+			return nil, 0, "", fmt.Errorf("failed to scan order: %w", err)
 		}
+		key.ID, key.CreatedAt = o.ID, o.CreatedAt
+		o.Subtotal, o.Total = key.Price.Major(), key.TotalAmount.Major()
 		orders = append(orders, o)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, "", fmt.Errorf("failed to list orders: %w", err)
 	}
 
-	return orders, total, nil
+	var nextCursor string
+	if len(orders) > int(search.Limit) {
+		orders = orders[:search.Limit]
+		nextCursor = search.NextCursor(keys[search.Limit-1])
+	}
+
+	return orders, total, nextCursor, nil
 }
 
 // GetOrderDetails retrieves detailed order information
@@ -255,7 +394,7 @@ func (r *AdminRepository) GetOrderDetails(ctx context.Context, orderID int64) (*
 
 	// Get status history
 	historyQuery := `
-		SELECT status, notes, created_at
+		SELECT to_status, COALESCE(note, ''), created_by, created_at
 		FROM order_status_history
 		WHERE order_id = $1
 		ORDER BY created_at ASC
@@ -270,8 +409,7 @@ func (r *AdminRepository) GetOrderDetails(ctx context.Context, orderID int64) (*
 	history := []model.OrderStatusChange{}
 	for rows.Next() {
 		var change model.OrderStatusChange
-		change.ChangedBy = "system" // Default
-		err := rows.Scan(&change.Status, &change.Notes, &change.ChangedAt)
+		err := rows.Scan(&change.Status, &change.Notes, &change.ChangedBy, &change.ChangedAt)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to scan status change: %w", err)
 		}
@@ -281,37 +419,6 @@ func (r *AdminRepository) GetOrderDetails(ctx context.Context, orderID int64) (*
 	return &order, history, nil
 }
 
-// CancelOrder cancels an order
-func (r *AdminRepository) CancelOrder(ctx context.Context, orderID int64, reason string) error {
-	query := `
-		UPDATE orders 
-		SET status = 'cancelled',
-		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1 AND status NOT IN ('cancelled', 'delivered', 'completed')
-	`
-
-	result, err := r.db.Exec(ctx, query, orderID)
-	if err != nil {
-		return fmt.Errorf("failed to cancel order: %w", err)
-	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("order not found or cannot be canceled")
-	}
-
-	// Add status history
-	historyQuery := `
-		INSERT INTO order_status_history (order_id, status, notes, created_at)
-		VALUES ($1, 'cancelled', $2, CURRENT_TIMESTAMP)
-	`
-	_, err = r.db.Exec(ctx, historyQuery, orderID, reason)
-	if err != nil {
-		return fmt.Errorf("failed to add status history: %w", err)
-	}
-
-	return nil
-}
-
 // ==================== Product Management ====================
 
 // ListAllProducts retrieves all products for admin view
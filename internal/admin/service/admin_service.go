@@ -2,21 +2,27 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/admin/repository"
+	ledgerModel "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/model"
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
 )
 
 // AdminService handles business logic for admin operations
 type AdminService struct {
-	repo *repository.AdminRepository
+	repo        *repository.AdminRepository
+	orderClient orderPb.OrderServiceClient
+	ledger      *ledgerService.LedgerService
 }
 
 // NewAdminService creates a new admin service
-func NewAdminService(repo *repository.AdminRepository) *AdminService {
-	return &AdminService{repo: repo}
+func NewAdminService(repo *repository.AdminRepository, orderClient orderPb.OrderServiceClient, ledger *ledgerService.LedgerService) *AdminService {
+	return &AdminService{repo: repo, orderClient: orderClient, ledger: ledger}
 }
 
 // ==================== User Management ====================
@@ -246,10 +252,26 @@ func (s *AdminService) GetUserActivityReport(ctx context.Context, params model.G
 	return s.repo.GetUserActivityReport(ctx, params)
 }
 
-// ==================== Order Management ====================
+// GetSellerShippingMetrics retrieves per-seller shipping SLA metrics
+func (s *AdminService) GetSellerShippingMetrics(ctx context.Context, params model.GetSellerShippingMetricsParams) ([]model.SellerShippingMetrics, int32, error) {
+	// Validate params
+	if params.PageSize <= 0 || params.PageSize > 100 {
+		params.PageSize = 20
+	}
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+	if !params.DateTo.After(params.DateFrom) {
+		return nil, 0, fmt.Errorf("date_to must be after date_from")
+	}
 
-// ListAllOrders retrieves all orders
-func (s *AdminService) ListAllOrders(ctx context.Context, params model.ListOrdersParams) ([]model.OrderSummary, int32, error) {
+	return s.repo.GetSellerShippingMetrics(ctx, params)
+}
+
+// ==================== Held Funds ====================
+
+// GetHeldFunds retrieves the funds held for sellers
+func (s *AdminService) GetHeldFunds(ctx context.Context, params model.GetHeldFundsParams) ([]model.SellerHeldFunds, int32, error) {
 	// Validate params
 	if params.PageSize <= 0 || params.PageSize > 100 {
 		params.PageSize = 20
@@ -258,6 +280,21 @@ func (s *AdminService) ListAllOrders(ctx context.Context, params model.ListOrder
 		params.Page = 1
 	}
 
+	return s.repo.GetHeldFunds(ctx, params)
+}
+
+// ==================== Order Management ====================
+
+// ListAllOrders retrieves all orders
+func (s *AdminService) ListAllOrders(ctx context.Context, params model.ListOrdersParams) ([]model.OrderSummary, int32, string, error) {
+	// Validate params
+	if err := params.Search.Normalize(); err != nil {
+		return nil, 0, "", err
+	}
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+
 	return s.repo.ListAllOrders(ctx, params)
 }
 
@@ -274,10 +311,23 @@ func (s *AdminService) CancelOrder(ctx context.Context, orderID, adminID int64,
 		return err
 	}
 
-	// Cancel order
-	err = s.repo.CancelOrder(ctx, orderID, reason)
+	// Cancel through the Order Service so the lifecycle records the admin
+	// as actor and runs its hooks (inventory release, notifications)
+	if s.orderClient == nil {
+		return fmt.Errorf("order service unavailable")
+	}
+
+	resp, err := s.orderClient.CancelOrder(ctx, &orderPb.CancelOrderRequest{
+		OrderId:           orderID,
+		Reason:            reason,
+		CancelledByUserId: adminID,
+		CancelledByRole:   "admin",
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
 	}
 
 	// Create audit log
@@ -370,6 +420,23 @@ func (s *AdminService) GetAuditLogs(ctx context.Context, params model.ListAuditL
 	return s.repo.ListAuditLogs(ctx, params)
 }
 
+// ==================== Ledger ====================
+
+// GetLedgerBalances lists ledger accounts and their balances. An empty
+// accountType matches every type and ownerID 0 every owner.
+func (s *AdminService) GetLedgerBalances(ctx context.Context, accountType string, ownerID int64) ([]*ledgerModel.Account, error) {
+	if ownerID == 0 {
+		ownerID = -1
+	}
+
+	return s.ledger.ListAccounts(ctx, accountType, ownerID)
+}
+
+// CheckLedger runs the ledger's invariant checks
+func (s *AdminService) CheckLedger(ctx context.Context) (*ledgerModel.InvariantReport, error) {
+	return s.ledger.CheckInvariants(ctx)
+}
+
 // ==================== Helper Functions ====================
 
 func isValidGroupBy(groupBy string) bool {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/service"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	pb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/bidding"
 )
 
@@ -26,10 +27,9 @@ func NewBiddingHandler(biddingService *service.BiddingService) *BiddingHandler {
 
 // PlaceBid handles bid placement
 func (h *BiddingHandler) PlaceBid(ctx context.Context, req *pb.PlaceBidRequest) (*pb.PlaceBidResponse, error) {
-	// DEBUG: Log received request
-	println("🔍 DEBUG PlaceBid: userId=", req.UserId, "productId=", req.ProductId, "sizeId=", req.SizeId, "price=", req.Price)
+	price := money.FromProto(req.PriceMinor, req.Price, req.Currency)
 
-	if req.UserId == 0 || req.ProductId == 0 || req.SizeId == 0 || req.Price <= 0 {
+	if req.UserId == 0 || req.ProductId == 0 || req.SizeId == 0 || !price.IsPositive() {
 		return &pb.PlaceBidResponse{
 			Error: "user_id, product_id, size_id, and price are required",
 		}, nil
@@ -40,9 +40,11 @@ func (h *BiddingHandler) PlaceBid(ctx context.Context, req *pb.PlaceBidRequest)
 		req.UserId,
 		req.ProductId,
 		req.SizeId,
-		req.Price,
+		price,
 		int(req.Quantity),
 		int(req.ExpiresInHours),
+		req.PaymentMethodId,
+		req.StripeCustomerId,
 	)
 
 	if err != nil {
@@ -166,10 +168,9 @@ func (h *BiddingHandler) CancelBid(ctx context.Context, req *pb.CancelBidRequest
 
 // PlaceAsk handles ask placement
 func (h *BiddingHandler) PlaceAsk(ctx context.Context, req *pb.PlaceAskRequest) (*pb.PlaceAskResponse, error) {
-	// DEBUG: Log received request
-	println("🔍 DEBUG PlaceAsk: userId=", req.UserId, "productId=", req.ProductId, "sizeId=", req.SizeId, "price=", req.Price)
+	price := money.FromProto(req.PriceMinor, req.Price, req.Currency)
 
-	if req.UserId == 0 || req.ProductId == 0 || req.SizeId == 0 || req.Price <= 0 {
+	if req.UserId == 0 || req.ProductId == 0 || req.SizeId == 0 || !price.IsPositive() {
 		return &pb.PlaceAskResponse{
 			Error: "user_id, product_id, size_id, and price are required",
 		}, nil
@@ -180,7 +181,7 @@ func (h *BiddingHandler) PlaceAsk(ctx context.Context, req *pb.PlaceAskRequest)
 		req.UserId,
 		req.ProductId,
 		req.SizeId,
-		req.Price,
+		price,
 		int(req.Quantity),
 		int(req.ExpiresInHours),
 	)
@@ -354,7 +355,7 @@ func (h *BiddingHandler) GetMarketPrice(ctx context.Context, req *pb.GetMarketPr
 		return nil, status.Error(codes.InvalidArgument, "product_id and size_id are required")
 	}
 
-	marketPrice, err := h.biddingService.GetMarketPrice(ctx, req.ProductId, req.SizeId)
+	marketPrice, err := h.biddingService.GetMarketPrice(ctx, req.ProductId, req.SizeId, req.Currency)
 	if err != nil {
 		return &pb.GetMarketPriceResponse{
 			Error: err.Error(),
@@ -362,11 +363,15 @@ func (h *BiddingHandler) GetMarketPrice(ctx context.Context, req *pb.GetMarketPr
 	}
 
 	return &pb.GetMarketPriceResponse{
-		HighestBid: marketPrice.HighestBid,
-		LowestAsk:  marketPrice.LowestAsk,
-		LastSale:   marketPrice.LastSale,
-		TotalBids:  marketPrice.TotalBids,
-		TotalAsks:  marketPrice.TotalAsks,
+		HighestBid:      marketPrice.HighestBid.Major(),
+		HighestBidMinor: marketPrice.HighestBid.Amount,
+		LowestAsk:       marketPrice.LowestAsk.Major(),
+		LowestAskMinor:  marketPrice.LowestAsk.Amount,
+		LastSale:        marketPrice.LastSale.Major(),
+		LastSaleMinor:   marketPrice.LastSale.Amount,
+		TotalBids:       marketPrice.TotalBids,
+		TotalAsks:       marketPrice.TotalAsks,
+		Currency:        marketPrice.Currency,
 	}, nil
 }
 
@@ -420,19 +425,125 @@ func (h *BiddingHandler) GetUserMatches(ctx context.Context, req *pb.GetUserMatc
 	}, nil
 }
 
+// RelistAsk re-activates the ask of a match whose order was never paid
+func (h *BiddingHandler) RelistAsk(ctx context.Context, req *pb.RelistAskRequest) (*pb.RelistAskResponse, error) {
+	if req.MatchId == 0 {
+		return &pb.RelistAskResponse{
+			Error: "match_id is required",
+		}, nil
+	}
+
+	ask, match, err := h.biddingService.RelistAsk(ctx, req.MatchId)
+	if err != nil {
+		response := &pb.RelistAskResponse{
+			Error: err.Error(),
+		}
+		if ask != nil {
+			response.Ask = modelAskToProto(ask)
+		}
+		return response, nil
+	}
+
+	response := &pb.RelistAskResponse{
+		Ask: modelAskToProto(ask),
+	}
+	if match != nil {
+		response.Match = modelMatchToProto(match)
+	}
+
+	return response, nil
+}
+
+// CancelMatch withdraws the ask of a match whose order was never paid
+func (h *BiddingHandler) CancelMatch(ctx context.Context, req *pb.CancelMatchRequest) (*pb.CancelMatchResponse, error) {
+	if req.MatchId == 0 {
+		return &pb.CancelMatchResponse{
+			Error: "match_id is required",
+		}, nil
+	}
+
+	ask, err := h.biddingService.CancelMatch(ctx, req.MatchId)
+	if err != nil {
+		return &pb.CancelMatchResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.CancelMatchResponse{
+		Ask: modelAskToProto(ask),
+	}, nil
+}
+
+// CheckoutAsks matches every cart item against its current lowest ask
+func (h *BiddingHandler) CheckoutAsks(ctx context.Context, req *pb.CheckoutAsksRequest) (*pb.CheckoutAsksResponse, error) {
+	if req.BuyerId == 0 {
+		return &pb.CheckoutAsksResponse{
+			Error: "buyer_id is required",
+		}, nil
+	}
+
+	items := make([]model.CheckoutItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = model.CheckoutItem{
+			ProductID: item.ProductId,
+			SizeID:    item.SizeId,
+			MaxPrice:  money.FromProto(item.MaxPriceMinor, item.MaxPrice, money.DefaultCurrency),
+		}
+	}
+
+	matches, err := h.biddingService.CheckoutAsks(ctx, req.BuyerId, items)
+	if err != nil {
+		return &pb.CheckoutAsksResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	protoMatches := make([]*pb.Match, len(matches))
+	for i, match := range matches {
+		protoMatches[i] = modelMatchToProto(match)
+	}
+
+	return &pb.CheckoutAsksResponse{
+		Matches: protoMatches,
+	}, nil
+}
+
+// RevertCheckout releases the asks of checkout matches that never became orders
+func (h *BiddingHandler) RevertCheckout(ctx context.Context, req *pb.RevertCheckoutRequest) (*pb.RevertCheckoutResponse, error) {
+	if len(req.MatchIds) == 0 {
+		return &pb.RevertCheckoutResponse{
+			Error: "match_ids are required",
+		}, nil
+	}
+
+	if err := h.biddingService.RevertCheckout(ctx, req.MatchIds); err != nil {
+		return &pb.RevertCheckoutResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.RevertCheckoutResponse{}, nil
+}
+
 // Helper functions to convert between model and proto
 
 func modelBidToProto(bid *model.Bid) *pb.Bid {
 	protoBid := &pb.Bid{
-		Id:        bid.ID,
-		UserId:    bid.UserID,
-		ProductId: bid.ProductID,
-		SizeId:    bid.SizeID,
-		Price:     bid.Price,
-		Quantity:  int32(bid.Quantity),
-		Status:    bid.Status,
-		CreatedAt: bid.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: bid.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Id:         bid.ID,
+		UserId:     bid.UserID,
+		ProductId:  bid.ProductID,
+		SizeId:     bid.SizeID,
+		Price:      bid.Price.Major(),
+		PriceMinor: bid.Price.Amount,
+		Quantity:   int32(bid.Quantity),
+		Status:     bid.Status,
+		CreatedAt:  bid.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:  bid.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+
+		Currency:             bid.Price.Currency,
+		SettlementPrice:      bid.SettlementPrice.Major(),
+		SettlementPriceMinor: bid.SettlementPrice.Amount,
+		FxRate:               bid.FXRate.String(),
 	}
 
 	if bid.ExpiresAt != nil {
@@ -448,15 +559,21 @@ func modelBidToProto(bid *model.Bid) *pb.Bid {
 
 func modelAskToProto(ask *model.Ask) *pb.Ask {
 	protoAsk := &pb.Ask{
-		Id:        ask.ID,
-		UserId:    ask.UserID,
-		ProductId: ask.ProductID,
-		SizeId:    ask.SizeID,
-		Price:     ask.Price,
-		Quantity:  int32(ask.Quantity),
-		Status:    ask.Status,
-		CreatedAt: ask.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: ask.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Id:         ask.ID,
+		UserId:     ask.UserID,
+		ProductId:  ask.ProductID,
+		SizeId:     ask.SizeID,
+		Price:      ask.Price.Major(),
+		PriceMinor: ask.Price.Amount,
+		Quantity:   int32(ask.Quantity),
+		Status:     ask.Status,
+		CreatedAt:  ask.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:  ask.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+
+		Currency:             ask.Price.Currency,
+		SettlementPrice:      ask.SettlementPrice.Major(),
+		SettlementPriceMinor: ask.SettlementPrice.Amount,
+		FxRate:               ask.FXRate.String(),
 	}
 
 	if ask.ExpiresAt != nil {
@@ -472,17 +589,28 @@ func modelAskToProto(ask *model.Ask) *pb.Ask {
 
 func modelMatchToProto(match *model.Match) *pb.Match {
 	protoMatch := &pb.Match{
-		Id:        match.ID,
-		BidId:     match.BidID,
-		AskId:     match.AskID,
-		BuyerId:   match.BuyerID,
-		SellerId:  match.SellerID,
-		ProductId: match.ProductID,
-		SizeId:    match.SizeID,
-		Price:     match.Price,
-		Quantity:  int32(match.Quantity),
-		Status:    match.Status,
-		CreatedAt: match.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Id:         match.ID,
+		BidId:      match.BidID,
+		AskId:      match.AskID,
+		BuyerId:    match.BuyerID,
+		SellerId:   match.SellerID,
+		ProductId:  match.ProductID,
+		SizeId:     match.SizeID,
+		Price:      match.Price.Major(),
+		PriceMinor: match.Price.Amount,
+		Quantity:   int32(match.Quantity),
+		Status:     match.Status,
+		CreatedAt:  match.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+
+		Currency:         match.Price.Currency,
+		SellerCurrency:   match.SellerPrice.Currency,
+		SellerPrice:      match.SellerPrice.Major(),
+		SellerPriceMinor: match.SellerPrice.Amount,
+		SellerFxRate:     match.SellerFXRate.String(),
+		BuyerCurrency:    match.BuyerCurrency,
+		BuyerFxRate:      match.BuyerFXRate.String(),
+
+		InventoryReference: match.InventoryReference,
 	}
 
 	if match.CompletedAt != nil {
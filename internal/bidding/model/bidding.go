@@ -1,75 +1,130 @@
 package model
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // Bid represents a buyer's offer to purchase at a specific price
 type Bid struct {
-	ID        int64      `json:"id"`
-	UserID    int64      `json:"user_id"`
-	ProductID int64      `json:"product_id"`
-	SizeID    int64      `json:"size_id"`
-	Price     float64    `json:"price"`
-	Quantity  int        `json:"quantity"`
-	Status    string     `json:"status"` // active, matched, canceled, expired
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
-	MatchedAt *time.Time `json:"matched_at,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	ProductID int64       `json:"product_id"`
+	SizeID    int64       `json:"size_id"`
+	Price     money.Money `json:"price"` // in the buyer's currency
+	Quantity  int         `json:"quantity"`
+	Status    string      `json:"status"` // pending_authorization, active, matched, canceled, expired
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+	MatchedAt *time.Time  `json:"matched_at,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+
+	// Price converted when placed; the order book matches on it
+	SettlementPrice money.Money `json:"settlement_price"`
+	FXRate          money.Rate  `json:"fx_rate"` // Price currency -> settlement currency
 }
 
 // Ask represents a seller's offer to sell at a specific price
 type Ask struct {
-	ID        int64      `json:"id"`
-	UserID    int64      `json:"user_id"`
-	ProductID int64      `json:"product_id"`
-	SizeID    int64      `json:"size_id"`
-	Price     float64    `json:"price"`
-	Quantity  int        `json:"quantity"`
-	Status    string     `json:"status"` // active, matched, canceled, expired
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
-	MatchedAt *time.Time `json:"matched_at,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	ProductID int64       `json:"product_id"`
+	SizeID    int64       `json:"size_id"`
+	Price     money.Money `json:"price"` // in the seller's currency
+	Quantity  int         `json:"quantity"`
+	Status    string      `json:"status"` // pending_reservation, active, matched, canceled, expired
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+	MatchedAt *time.Time  `json:"matched_at,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+
+	// Price converted when placed; the order book matches on it
+	SettlementPrice money.Money `json:"settlement_price"`
+	FXRate          money.Rate  `json:"fx_rate"` // Price currency -> settlement currency
+
+	// SplitFromID is the ask placed by the seller when this one holds units
+	// split off it; its reservation holds the units
+	SplitFromID *int64 `json:"split_from_id,omitempty"`
 }
 
 // Match represents a matched bid and ask
 type Match struct {
-	ID          int64      `json:"id"`
-	BidID       int64      `json:"bid_id"`
-	AskID       int64      `json:"ask_id"`
-	BuyerID     int64      `json:"buyer_id"`
-	SellerID    int64      `json:"seller_id"`
-	ProductID   int64      `json:"product_id"`
-	SizeID      int64      `json:"size_id"`
-	Price       float64    `json:"price"`
-	Quantity    int        `json:"quantity"`
-	Status      string     `json:"status"` // pending, completed, failed
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID          int64       `json:"id"`
+	BidID       int64       `json:"bid_id"`
+	AskID       int64       `json:"ask_id"`
+	BuyerID     int64       `json:"buyer_id"`
+	SellerID    int64       `json:"seller_id"`
+	ProductID   int64       `json:"product_id"`
+	SizeID      int64       `json:"size_id"`
+	Price       money.Money `json:"price"` // settlement currency
+	Quantity    int         `json:"quantity"`
+	Status      string      `json:"status"` // pending, completed, failed
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+
+	// Rates snapshotted from the ask and bid
+	SellerPrice   money.Money `json:"seller_price"`   // ask price in the seller's currency
+	SellerFXRate  money.Rate  `json:"seller_fx_rate"` // seller currency -> settlement
+	BuyerCurrency string      `json:"buyer_currency"`
+	BuyerFXRate   money.Rate  `json:"buyer_fx_rate"` // buyer currency -> settlement
+
+	// InventoryReference is the sold ask's reservation, which the order
+	// completes or releases
+	InventoryReference string `json:"inventory_reference"`
+}
+
+// Capture statuses
+const (
+	CaptureStatusPending  = "pending"
+	CaptureStatusCaptured = "captured"
+	CaptureStatusFailed   = "failed" // out of attempts; the match was undone
+)
+
+// Capture charges a matched bid's payment hold. It is queued with the match
+// and retried in the background until it succeeds or runs out of attempts.
+type Capture struct {
+	MatchID       int64       `json:"match_id"`
+	BidID         int64       `json:"bid_id"`
+	AskID         int64       `json:"ask_id"`
+	Amount        money.Money `json:"amount"` // in the bid's currency
+	Status        string      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	NextAttemptAt time.Time   `json:"next_attempt_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// CheckoutItem is one cart line bought at the current lowest ask
+type CheckoutItem struct {
+	ProductID int64       `json:"product_id"`
+	SizeID    int64       `json:"size_id"`
+	MaxPrice  money.Money `json:"max_price"` // settlement currency; reject the checkout if the lowest ask rose above this (0 = no limit)
 }
 
 // MarketPrice represents current market data for a product/size
 type MarketPrice struct {
-	ProductID  int64   `json:"product_id"`
-	SizeID     int64   `json:"size_id"`
-	HighestBid float64 `json:"highest_bid"`
-	LowestAsk  float64 `json:"lowest_ask"`
-	LastSale   float64 `json:"last_sale"`
-	TotalBids  int64   `json:"total_bids"`
-	TotalAsks  int64   `json:"total_asks"`
+	ProductID  int64       `json:"product_id"`
+	SizeID     int64       `json:"size_id"`
+	HighestBid money.Money `json:"highest_bid"`
+	LowestAsk  money.Money `json:"lowest_ask"`
+	LastSale   money.Money `json:"last_sale"`
+	TotalBids  int64       `json:"total_bids"`
+	TotalAsks  int64       `json:"total_asks"`
+	Currency   string      `json:"currency"` // the prices above, converted for display
 }
 
 // Status constants
 const (
-	StatusActive    = "active"
-	StatusMatched   = "matched"
-	StatusCancelled = "canceled"
-	StatusExpired   = "expired"
-	StatusPending   = "pending"
-	StatusCompleted = "completed"
-	StatusFailed    = "failed"
+	StatusPendingAuthorization = "pending_authorization"
+	StatusPendingReservation   = "pending_reservation"
+	StatusActive               = "active"
+	StatusMatched              = "matched"
+	StatusCancelled            = "canceled"
+	StatusExpired              = "expired"
+	StatusPending              = "pending"
+	StatusCompleted            = "completed"
+	StatusFailed               = "failed"
 )
 
 // IsActive returns true if bid/ask is active
@@ -82,12 +137,49 @@ func (a *Ask) IsActive() bool {
 	return a.Status == StatusActive
 }
 
+// InventoryReference is the reference the ask's units are reserved,
+// released and sold under. Splits share the reference of the ask they were
+// split from.
+func (a *Ask) InventoryReference() string {
+	id := a.ID
+	if a.SplitFromID != nil {
+		id = *a.SplitFromID
+	}
+	return fmt.Sprintf("ask-%d", id)
+}
+
+// Split moves quantity units of an active ask into a new ask with the given
+// status and returns it. The split keeps the ask's placement, prices and
+// reservation; the ask keeps the remaining units.
+func (a *Ask) Split(quantity int, status string) (*Ask, error) {
+	if !a.IsActive() {
+		return nil, fmt.Errorf("ask %d is %s, not active", a.ID, a.Status)
+	}
+	if quantity <= 0 || a.Quantity <= quantity {
+		return nil, fmt.Errorf("ask %d has no %d spare units", a.ID, quantity)
+	}
+
+	splitFrom := a.ID
+	if a.SplitFromID != nil {
+		splitFrom = *a.SplitFromID
+	}
+
+	split := *a
+	split.ID = 0
+	split.Quantity = quantity
+	split.Status = status
+	split.SplitFromID = &splitFrom
+	a.Quantity -= quantity
+
+	return &split, nil
+}
+
 // CanMatch checks if a bid and ask can be matched
 func CanMatch(bid *Bid, ask *Ask) bool {
 	return bid.IsActive() &&
 		ask.IsActive() &&
 		bid.ProductID == ask.ProductID &&
 		bid.SizeID == ask.SizeID &&
-		bid.Price >= ask.Price &&
+		!bid.SettlementPrice.LessThan(ask.SettlementPrice) &&
 		bid.Quantity == ask.Quantity // For simplicity, match exact quantities
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -10,6 +11,25 @@ import (
 	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/model"
 )
 
+// bidColumns is the column list scanned by scanBid
+const bidColumns = `
+		id, user_id, product_id, size_id, price, quantity, status,
+		expires_at, matched_at, created_at, updated_at,
+		currency, settlement_price, fx_rate`
+
+// askColumns is the column list scanned by scanAsk
+const askColumns = `
+		id, user_id, product_id, size_id, price, quantity, status,
+		expires_at, matched_at, created_at, updated_at,
+		currency, settlement_price, fx_rate, split_from_id`
+
+// matchColumns is the column list scanned by scanMatch
+const matchColumns = `
+		id, bid_id, ask_id, buyer_id, seller_id, product_id, size_id,
+		price, quantity, status, completed_at, created_at,
+		currency, seller_currency, seller_price, seller_fx_rate, buyer_currency, buyer_fx_rate,
+		inventory_reference`
+
 // BiddingRepository handles database operations for bidding
 type BiddingRepository struct {
 	db *pgxpool.Pool
@@ -23,14 +43,12 @@ func NewBiddingRepository(db *pgxpool.Pool) *BiddingRepository {
 // PlaceBid creates a new bid
 func (r *BiddingRepository) PlaceBid(ctx context.Context, bid *model.Bid) error {
 	query := `
-		INSERT INTO bids (user_id, product_id, size_id, price, quantity, status, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO bids (user_id, product_id, size_id, price, quantity, status, expires_at,
+		                  currency, settlement_price, fx_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at
 	`
 
-	// DEBUG: Log what we're about to insert
-	println("🔍 Repository PlaceBid SQL params: userID=", bid.UserID, "productID=", bid.ProductID, "sizeID=", bid.SizeID, "price=", bid.Price, "quantity=", bid.Quantity, "status=", bid.Status)
-
 	err := r.db.QueryRow(ctx, query,
 		bid.UserID,
 		bid.ProductID,
@@ -39,40 +57,27 @@ func (r *BiddingRepository) PlaceBid(ctx context.Context, bid *model.Bid) error
 		bid.Quantity,
 		bid.Status,
 		bid.ExpiresAt,
+		bid.Price.Currency,
+		bid.SettlementPrice,
+		bid.FXRate,
 	).Scan(&bid.ID, &bid.CreatedAt, &bid.UpdatedAt)
 
 	if err != nil {
-		println("🔴 Repository PlaceBid SQL ERROR:", err.Error())
 		return fmt.Errorf("failed to place bid: %w", err)
 	}
 
-	println("✅ Repository PlaceBid SUCCESS: bidID=", bid.ID)
 	return nil
 }
 
 // GetBidByID retrieves a bid by ID
 func (r *BiddingRepository) GetBidByID(ctx context.Context, bidID int64) (*model.Bid, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + bidColumns + `
 		FROM bids
 		WHERE id = $1
 	`
 
-	bid := &model.Bid{}
-	err := r.db.QueryRow(ctx, query, bidID).Scan(
-		&bid.ID,
-		&bid.UserID,
-		&bid.ProductID,
-		&bid.SizeID,
-		&bid.Price,
-		&bid.Quantity,
-		&bid.Status,
-		&bid.ExpiresAt,
-		&bid.MatchedAt,
-		&bid.CreatedAt,
-		&bid.UpdatedAt,
-	)
+	bid, err := scanBid(r.db.QueryRow(ctx, query, bidID))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bid: %w", err)
@@ -84,8 +89,7 @@ func (r *BiddingRepository) GetBidByID(ctx context.Context, bidID int64) (*model
 // GetUserBids retrieves bids for a user
 func (r *BiddingRepository) GetUserBids(ctx context.Context, userID int64, status string, page, pageSize int) ([]*model.Bid, int64, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + bidColumns + `
 		FROM bids
 		WHERE user_id = $1
 	`
@@ -120,20 +124,7 @@ func (r *BiddingRepository) GetUserBids(ctx context.Context, userID int64, statu
 
 	var bids []*model.Bid
 	for rows.Next() {
-		bid := &model.Bid{}
-		err := rows.Scan(
-			&bid.ID,
-			&bid.UserID,
-			&bid.ProductID,
-			&bid.SizeID,
-			&bid.Price,
-			&bid.Quantity,
-			&bid.Status,
-			&bid.ExpiresAt,
-			&bid.MatchedAt,
-			&bid.CreatedAt,
-			&bid.UpdatedAt,
-		)
+		bid, err := scanBid(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan bid: %w", err)
 		}
@@ -146,8 +137,7 @@ func (r *BiddingRepository) GetUserBids(ctx context.Context, userID int64, statu
 // GetProductBids retrieves bids for a product/size
 func (r *BiddingRepository) GetProductBids(ctx context.Context, productID, sizeID int64, status string, page, pageSize int) ([]*model.Bid, int64, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + bidColumns + `
 		FROM bids
 		WHERE product_id = $1 AND size_id = $2
 	`
@@ -171,7 +161,7 @@ func (r *BiddingRepository) GetProductBids(ctx context.Context, productID, sizeI
 	}
 
 	// Add pagination
-	query += fmt.Sprintf(" ORDER BY price DESC, created_at ASC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	query += fmt.Sprintf(" ORDER BY settlement_price DESC, created_at ASC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
 	args = append(args, pageSize, (page-1)*pageSize)
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -182,20 +172,7 @@ func (r *BiddingRepository) GetProductBids(ctx context.Context, productID, sizeI
 
 	var bids []*model.Bid
 	for rows.Next() {
-		bid := &model.Bid{}
-		err := rows.Scan(
-			&bid.ID,
-			&bid.UserID,
-			&bid.ProductID,
-			&bid.SizeID,
-			&bid.Price,
-			&bid.Quantity,
-			&bid.Status,
-			&bid.ExpiresAt,
-			&bid.MatchedAt,
-			&bid.CreatedAt,
-			&bid.UpdatedAt,
-		)
+		bid, err := scanBid(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan bid: %w", err)
 		}
@@ -229,31 +206,38 @@ func (r *BiddingRepository) UpdateBidStatus(ctx context.Context, tx pgx.Tx, bidI
 	return nil
 }
 
+// ActivateBid activates a bid whose payment hold succeeded. It fails if the
+// bid is no longer pending authorization, e.g. because it expired meanwhile.
+func (r *BiddingRepository) ActivateBid(ctx context.Context, bidID int64) error {
+	query := `
+		UPDATE bids SET status = 'active', updated_at = NOW()
+		WHERE id = $1 AND status = 'pending_authorization'
+	`
+
+	result, err := r.db.Exec(ctx, query, bidID)
+	if err != nil {
+		return fmt.Errorf("failed to activate bid: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("bid not found or not pending authorization")
+	}
+
+	return nil
+}
+
 // GetHighestBid retrieves the highest active bid for a product/size
 func (r *BiddingRepository) GetHighestBid(ctx context.Context, productID, sizeID int64) (*model.Bid, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + bidColumns + `
 		FROM bids
 		WHERE product_id = $1 AND size_id = $2 AND status = 'active'
-		ORDER BY price DESC, created_at ASC
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY settlement_price DESC, created_at ASC
 		LIMIT 1
 	`
 
-	bid := &model.Bid{}
-	err := r.db.QueryRow(ctx, query, productID, sizeID).Scan(
-		&bid.ID,
-		&bid.UserID,
-		&bid.ProductID,
-		&bid.SizeID,
-		&bid.Price,
-		&bid.Quantity,
-		&bid.Status,
-		&bid.ExpiresAt,
-		&bid.MatchedAt,
-		&bid.CreatedAt,
-		&bid.UpdatedAt,
-	)
+	bid, err := scanBid(r.db.QueryRow(ctx, query, productID, sizeID))
 
 	if err == pgx.ErrNoRows {
 		return nil, nil // No bids found
@@ -269,14 +253,12 @@ func (r *BiddingRepository) GetHighestBid(ctx context.Context, productID, sizeID
 // PlaceAsk creates a new ask
 func (r *BiddingRepository) PlaceAsk(ctx context.Context, ask *model.Ask) error {
 	query := `
-		INSERT INTO asks (user_id, product_id, size_id, price, quantity, status, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO asks (user_id, product_id, size_id, price, quantity, status, expires_at,
+		                  currency, settlement_price, fx_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at
 	`
 
-	// DEBUG: Log what we're about to insert
-	println("🔍 Repository PlaceAsk SQL params: userID=", ask.UserID, "productID=", ask.ProductID, "sizeID=", ask.SizeID, "price=", ask.Price, "quantity=", ask.Quantity, "status=", ask.Status)
-
 	err := r.db.QueryRow(ctx, query,
 		ask.UserID,
 		ask.ProductID,
@@ -285,40 +267,27 @@ func (r *BiddingRepository) PlaceAsk(ctx context.Context, ask *model.Ask) error
 		ask.Quantity,
 		ask.Status,
 		ask.ExpiresAt,
+		ask.Price.Currency,
+		ask.SettlementPrice,
+		ask.FXRate,
 	).Scan(&ask.ID, &ask.CreatedAt, &ask.UpdatedAt)
 
 	if err != nil {
-		println("🔴 Repository PlaceAsk SQL ERROR:", err.Error())
 		return fmt.Errorf("failed to place ask: %w", err)
 	}
 
-	println("✅ Repository PlaceAsk SUCCESS: askID=", ask.ID)
 	return nil
 }
 
 // GetAskByID retrieves an ask by ID
 func (r *BiddingRepository) GetAskByID(ctx context.Context, askID int64) (*model.Ask, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + askColumns + `
 		FROM asks
 		WHERE id = $1
 	`
 
-	ask := &model.Ask{}
-	err := r.db.QueryRow(ctx, query, askID).Scan(
-		&ask.ID,
-		&ask.UserID,
-		&ask.ProductID,
-		&ask.SizeID,
-		&ask.Price,
-		&ask.Quantity,
-		&ask.Status,
-		&ask.ExpiresAt,
-		&ask.MatchedAt,
-		&ask.CreatedAt,
-		&ask.UpdatedAt,
-	)
+	ask, err := scanAsk(r.db.QueryRow(ctx, query, askID))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ask: %w", err)
@@ -330,8 +299,7 @@ func (r *BiddingRepository) GetAskByID(ctx context.Context, askID int64) (*model
 // GetUserAsks retrieves asks for a user
 func (r *BiddingRepository) GetUserAsks(ctx context.Context, userID int64, status string, page, pageSize int) ([]*model.Ask, int64, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + askColumns + `
 		FROM asks
 		WHERE user_id = $1
 	`
@@ -366,20 +334,7 @@ func (r *BiddingRepository) GetUserAsks(ctx context.Context, userID int64, statu
 
 	var asks []*model.Ask
 	for rows.Next() {
-		ask := &model.Ask{}
-		err := rows.Scan(
-			&ask.ID,
-			&ask.UserID,
-			&ask.ProductID,
-			&ask.SizeID,
-			&ask.Price,
-			&ask.Quantity,
-			&ask.Status,
-			&ask.ExpiresAt,
-			&ask.MatchedAt,
-			&ask.CreatedAt,
-			&ask.UpdatedAt,
-		)
+		ask, err := scanAsk(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan ask: %w", err)
 		}
@@ -392,8 +347,7 @@ func (r *BiddingRepository) GetUserAsks(ctx context.Context, userID int64, statu
 // GetProductAsks retrieves asks for a product/size
 func (r *BiddingRepository) GetProductAsks(ctx context.Context, productID, sizeID int64, status string, page, pageSize int) ([]*model.Ask, int64, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + askColumns + `
 		FROM asks
 		WHERE product_id = $1 AND size_id = $2
 	`
@@ -417,7 +371,7 @@ func (r *BiddingRepository) GetProductAsks(ctx context.Context, productID, sizeI
 	}
 
 	// Add pagination
-	query += fmt.Sprintf(" ORDER BY price ASC, created_at ASC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	query += fmt.Sprintf(" ORDER BY settlement_price ASC, created_at ASC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
 	args = append(args, pageSize, (page-1)*pageSize)
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -428,20 +382,7 @@ func (r *BiddingRepository) GetProductAsks(ctx context.Context, productID, sizeI
 
 	var asks []*model.Ask
 	for rows.Next() {
-		ask := &model.Ask{}
-		err := rows.Scan(
-			&ask.ID,
-			&ask.UserID,
-			&ask.ProductID,
-			&ask.SizeID,
-			&ask.Price,
-			&ask.Quantity,
-			&ask.Status,
-			&ask.ExpiresAt,
-			&ask.MatchedAt,
-			&ask.CreatedAt,
-			&ask.UpdatedAt,
-		)
+		ask, err := scanAsk(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan ask: %w", err)
 		}
@@ -475,31 +416,81 @@ func (r *BiddingRepository) UpdateAskStatus(ctx context.Context, tx pgx.Tx, askI
 	return nil
 }
 
+// ActivateAsk puts an ask whose inventory was reserved on the market. It
+// fails if the ask is no longer pending reservation.
+func (r *BiddingRepository) ActivateAsk(ctx context.Context, askID int64) error {
+	query := `
+		UPDATE asks SET status = 'active', updated_at = NOW()
+		WHERE id = $1 AND status = 'pending_reservation'
+	`
+
+	result, err := r.db.Exec(ctx, query, askID)
+	if err != nil {
+		return fmt.Errorf("failed to activate ask: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("ask not found or not pending reservation")
+	}
+
+	return nil
+}
+
+// SplitAsk stores split, the units model.Ask.Split moved off the active
+// ask askID, and takes them off the original
+func (r *BiddingRepository) SplitAsk(ctx context.Context, tx pgx.Tx, askID int64, split *model.Ask) (*model.Ask, error) {
+	result, err := tx.Exec(ctx, `
+		UPDATE asks SET quantity = quantity - $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'active' AND quantity > $2
+	`, askID, split.Quantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split ask: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("ask %d has no %d spare units", askID, split.Quantity)
+	}
+
+	query := `
+		INSERT INTO asks (user_id, product_id, size_id, price, quantity, status, expires_at,
+		                  currency, settlement_price, fx_rate, split_from_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING` + askColumns + `
+	`
+
+	ask, err := scanAsk(tx.QueryRow(ctx, query,
+		split.UserID,
+		split.ProductID,
+		split.SizeID,
+		split.Price,
+		split.Quantity,
+		split.Status,
+		split.ExpiresAt,
+		split.Price.Currency,
+		split.SettlementPrice,
+		split.FXRate,
+		split.SplitFromID,
+		split.CreatedAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to split ask: %w", err)
+	}
+
+	return ask, nil
+}
+
 // GetLowestAsk retrieves the lowest active ask for a product/size
 func (r *BiddingRepository) GetLowestAsk(ctx context.Context, productID, sizeID int64) (*model.Ask, error) {
 	query := `
-		SELECT id, user_id, product_id, size_id, price, quantity, status, 
-		       expires_at, matched_at, created_at, updated_at
+		SELECT` + askColumns + `
 		FROM asks
 		WHERE product_id = $1 AND size_id = $2 AND status = 'active'
-		ORDER BY price ASC, created_at ASC
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY settlement_price ASC, created_at ASC
 		LIMIT 1
 	`
 
-	ask := &model.Ask{}
-	err := r.db.QueryRow(ctx, query, productID, sizeID).Scan(
-		&ask.ID,
-		&ask.UserID,
-		&ask.ProductID,
-		&ask.SizeID,
-		&ask.Price,
-		&ask.Quantity,
-		&ask.Status,
-		&ask.ExpiresAt,
-		&ask.MatchedAt,
-		&ask.CreatedAt,
-		&ask.UpdatedAt,
-	)
+	ask, err := scanAsk(r.db.QueryRow(ctx, query, productID, sizeID))
 
 	if err == pgx.ErrNoRows {
 		return nil, nil // No asks found
@@ -512,11 +503,71 @@ func (r *BiddingRepository) GetLowestAsk(ctx context.Context, productID, sizeID
 	return ask, nil
 }
 
+// LockLowestAsk locks the lowest active ask for a product/size inside tx,
+// skipping the buyer's own asks and asks locked by concurrent checkouts.
+// It returns nil when no ask is available.
+func (r *BiddingRepository) LockLowestAsk(ctx context.Context, tx pgx.Tx, productID, sizeID, buyerID int64) (*model.Ask, error) {
+	query := `
+		SELECT` + askColumns + `
+		FROM asks
+		WHERE product_id = $1 AND size_id = $2 AND status = 'active'
+		  AND user_id <> $3
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY settlement_price ASC, created_at ASC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	ask, err := scanAsk(tx.QueryRow(ctx, query, productID, sizeID, buyerID))
+
+	if err == pgx.ErrNoRows {
+		return nil, nil // No asks available
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock lowest ask: %w", err)
+	}
+
+	return ask, nil
+}
+
+// CreateBidTx inserts a bid inside tx (used by checkout, where the bid is
+// matched immediately)
+func (r *BiddingRepository) CreateBidTx(ctx context.Context, tx pgx.Tx, bid *model.Bid) error {
+	query := `
+		INSERT INTO bids (user_id, product_id, size_id, price, quantity, status, expires_at,
+		                  currency, settlement_price, fx_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := tx.QueryRow(ctx, query,
+		bid.UserID,
+		bid.ProductID,
+		bid.SizeID,
+		bid.Price,
+		bid.Quantity,
+		bid.Status,
+		bid.ExpiresAt,
+		bid.Price.Currency,
+		bid.SettlementPrice,
+		bid.FXRate,
+	).Scan(&bid.ID, &bid.CreatedAt, &bid.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create bid: %w", err)
+	}
+
+	return nil
+}
+
 // CreateMatch creates a new match
 func (r *BiddingRepository) CreateMatch(ctx context.Context, tx pgx.Tx, match *model.Match) error {
 	query := `
-		INSERT INTO matches (bid_id, ask_id, buyer_id, seller_id, product_id, size_id, price, quantity, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO matches (bid_id, ask_id, buyer_id, seller_id, product_id, size_id, price, quantity, status,
+		                     currency, seller_currency, seller_price, seller_fx_rate, buyer_currency, buyer_fx_rate,
+		                     inventory_reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, created_at
 	`
 
@@ -530,6 +581,13 @@ func (r *BiddingRepository) CreateMatch(ctx context.Context, tx pgx.Tx, match *m
 		match.Price,
 		match.Quantity,
 		match.Status,
+		match.Price.Currency,
+		match.SellerPrice.Currency,
+		match.SellerPrice,
+		match.SellerFXRate,
+		match.BuyerCurrency,
+		match.BuyerFXRate,
+		match.InventoryReference,
 	).Scan(&match.ID, &match.CreatedAt)
 
 	if err != nil {
@@ -539,30 +597,39 @@ func (r *BiddingRepository) CreateMatch(ctx context.Context, tx pgx.Tx, match *m
 	return nil
 }
 
+// UpdateMatchStatus updates match status
+func (r *BiddingRepository) UpdateMatchStatus(ctx context.Context, tx pgx.Tx, matchID int64, status string) error {
+	query := `UPDATE matches SET status = $1 WHERE id = $2`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, status, matchID)
+	} else {
+		result, err = r.db.Exec(ctx, query, status, matchID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to update match status: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("match not found")
+	}
+
+	return nil
+}
+
 // GetMatchByID retrieves a match by ID
 func (r *BiddingRepository) GetMatchByID(ctx context.Context, matchID int64) (*model.Match, error) {
 	query := `
-		SELECT id, bid_id, ask_id, buyer_id, seller_id, product_id, size_id, 
-		       price, quantity, status, completed_at, created_at
+		SELECT` + matchColumns + `
 		FROM matches
 		WHERE id = $1
 	`
 
-	match := &model.Match{}
-	err := r.db.QueryRow(ctx, query, matchID).Scan(
-		&match.ID,
-		&match.BidID,
-		&match.AskID,
-		&match.BuyerID,
-		&match.SellerID,
-		&match.ProductID,
-		&match.SizeID,
-		&match.Price,
-		&match.Quantity,
-		&match.Status,
-		&match.CompletedAt,
-		&match.CreatedAt,
-	)
+	match, err := scanMatch(r.db.QueryRow(ctx, query, matchID))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get match: %w", err)
@@ -574,8 +641,7 @@ func (r *BiddingRepository) GetMatchByID(ctx context.Context, matchID int64) (*m
 // GetUserMatches retrieves matches for a user
 func (r *BiddingRepository) GetUserMatches(ctx context.Context, userID int64, asBuyer, asSeller bool, page, pageSize int) ([]*model.Match, int64, error) {
 	query := `
-		SELECT id, bid_id, ask_id, buyer_id, seller_id, product_id, size_id, 
-		       price, quantity, status, completed_at, created_at
+		SELECT` + matchColumns + `
 		FROM matches
 		WHERE 1=1
 	`
@@ -620,21 +686,7 @@ func (r *BiddingRepository) GetUserMatches(ctx context.Context, userID int64, as
 
 	var matches []*model.Match
 	for rows.Next() {
-		match := &model.Match{}
-		err := rows.Scan(
-			&match.ID,
-			&match.BidID,
-			&match.AskID,
-			&match.BuyerID,
-			&match.SellerID,
-			&match.ProductID,
-			&match.SizeID,
-			&match.Price,
-			&match.Quantity,
-			&match.Status,
-			&match.CompletedAt,
-			&match.CreatedAt,
-		)
+		match, err := scanMatch(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan match: %w", err)
 		}
@@ -644,7 +696,122 @@ func (r *BiddingRepository) GetUserMatches(ctx context.Context, userID int64, as
 	return matches, total, nil
 }
 
+// ExpireBids marks active bids past their expiration as expired, along with
+// bids still pending authorization that expired or were placed before
+// pendingBefore, and returns them
+func (r *BiddingRepository) ExpireBids(ctx context.Context, pendingBefore time.Time) ([]*model.Bid, error) {
+	query := `
+		UPDATE bids
+		SET status = 'expired', updated_at = NOW()
+		WHERE (status IN ('active', 'pending_authorization') AND expires_at IS NOT NULL AND expires_at <= NOW())
+			OR (status = 'pending_authorization' AND created_at <= $1)
+		RETURNING` + bidColumns + `
+	`
+
+	rows, err := r.db.Query(ctx, query, pendingBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire bids: %w", err)
+	}
+	defer rows.Close()
+
+	bids := []*model.Bid{}
+	for rows.Next() {
+		bid, err := scanBid(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expired bid: %w", err)
+		}
+		bids = append(bids, bid)
+	}
+
+	return bids, rows.Err()
+}
+
+// ExpireAsks marks active asks past their expiration as expired and returns them
+func (r *BiddingRepository) ExpireAsks(ctx context.Context) ([]*model.Ask, error) {
+	query := `
+		UPDATE asks
+		SET status = 'expired', updated_at = NOW()
+		WHERE status = 'active' AND expires_at IS NOT NULL AND expires_at <= NOW()
+		RETURNING` + askColumns + `
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire asks: %w", err)
+	}
+	defer rows.Close()
+
+	asks := []*model.Ask{}
+	for rows.Next() {
+		ask, err := scanAsk(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expired ask: %w", err)
+		}
+		asks = append(asks, ask)
+	}
+
+	return asks, rows.Err()
+}
+
 // BeginTx starts a new transaction
 func (r *BiddingRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
 	return r.db.Begin(ctx)
 }
+
+// scanBid scans a row selected with bidColumns. Price is in the bid's own
+// currency; SettlementPrice is in the currency amounts default to.
+func scanBid(row pgx.Row) (*model.Bid, error) {
+	bid := &model.Bid{}
+	var currency string
+	err := row.Scan(
+		&bid.ID, &bid.UserID, &bid.ProductID, &bid.SizeID,
+		&bid.Price, &bid.Quantity, &bid.Status,
+		&bid.ExpiresAt, &bid.MatchedAt, &bid.CreatedAt, &bid.UpdatedAt,
+		&currency, &bid.SettlementPrice, &bid.FXRate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	bid.Price = bid.Price.WithCurrency(currency)
+
+	return bid, nil
+}
+
+// scanAsk scans a row selected with askColumns
+func scanAsk(row pgx.Row) (*model.Ask, error) {
+	ask := &model.Ask{}
+	var currency string
+	err := row.Scan(
+		&ask.ID, &ask.UserID, &ask.ProductID, &ask.SizeID,
+		&ask.Price, &ask.Quantity, &ask.Status,
+		&ask.ExpiresAt, &ask.MatchedAt, &ask.CreatedAt, &ask.UpdatedAt,
+		&currency, &ask.SettlementPrice, &ask.FXRate, &ask.SplitFromID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	ask.Price = ask.Price.WithCurrency(currency)
+
+	return ask, nil
+}
+
+// scanMatch scans a row selected with matchColumns
+func scanMatch(row pgx.Row) (*model.Match, error) {
+	match := &model.Match{}
+	var currency, sellerCurrency string
+	err := row.Scan(
+		&match.ID, &match.BidID, &match.AskID, &match.BuyerID, &match.SellerID,
+		&match.ProductID, &match.SizeID,
+		&match.Price, &match.Quantity, &match.Status, &match.CompletedAt, &match.CreatedAt,
+		&currency, &sellerCurrency, &match.SellerPrice, &match.SellerFXRate,
+		&match.BuyerCurrency, &match.BuyerFXRate,
+		&match.InventoryReference,
+	)
+	if err != nil {
+		return nil, err
+	}
+	match.Price = match.Price.WithCurrency(currency)
+	match.SellerPrice = match.SellerPrice.WithCurrency(sellerCurrency)
+
+	return match, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/model"
+)
+
+// captureColumns is the column list scanned by scanCapture
+const captureColumns = `
+		match_id, bid_id, ask_id, amount, currency, status, attempts, next_attempt_at, created_at`
+
+// CreateCapture queues the capture of a match's bid hold in the match's
+// transaction
+func (r *BiddingRepository) CreateCapture(ctx context.Context, tx pgx.Tx, capture *model.Capture) error {
+	query := `
+		INSERT INTO match_captures (match_id, bid_id, ask_id, amount, currency, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+
+	err := tx.QueryRow(ctx, query,
+		capture.MatchID,
+		capture.BidID,
+		capture.AskID,
+		capture.Amount,
+		capture.Amount.Currency,
+		capture.Status,
+		capture.NextAttemptAt,
+	).Scan(&capture.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to queue capture: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueCaptures returns up to limit pending captures whose next attempt
+// is due, pushing that attempt lease into the future so concurrent workers
+// skip them
+func (r *BiddingRepository) ClaimDueCaptures(ctx context.Context, lease time.Duration, limit int) ([]*model.Capture, error) {
+	query := `
+		UPDATE match_captures
+		SET next_attempt_at = $1
+		WHERE match_id IN (
+			SELECT match_id FROM match_captures
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING` + captureColumns + `
+	`
+
+	rows, err := r.db.Query(ctx, query, time.Now().Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim captures: %w", err)
+	}
+	defer rows.Close()
+
+	captures := []*model.Capture{}
+	for rows.Next() {
+		capture, err := scanCapture(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan capture: %w", err)
+		}
+		captures = append(captures, capture)
+	}
+
+	return captures, rows.Err()
+}
+
+// CompleteCapture marks a capture as done
+func (r *BiddingRepository) CompleteCapture(ctx context.Context, matchID int64) error {
+	query := `
+		UPDATE match_captures
+		SET status = 'captured', attempts = attempts + 1, last_error = NULL
+		WHERE match_id = $1 AND status = 'pending'
+	`
+
+	if _, err := r.db.Exec(ctx, query, matchID); err != nil {
+		return fmt.Errorf("failed to complete capture: %w", err)
+	}
+
+	return nil
+}
+
+// RetryCapture records a failed attempt and schedules the next one
+func (r *BiddingRepository) RetryCapture(ctx context.Context, matchID int64, nextAttemptAt time.Time, reason string) error {
+	query := `
+		UPDATE match_captures
+		SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+		WHERE match_id = $3 AND status = 'pending'
+	`
+
+	if _, err := r.db.Exec(ctx, query, reason, nextAttemptAt, matchID); err != nil {
+		return fmt.Errorf("failed to reschedule capture: %w", err)
+	}
+
+	return nil
+}
+
+// FailCapture records the last failed attempt inside the transaction that
+// undoes the match
+func (r *BiddingRepository) FailCapture(ctx context.Context, tx pgx.Tx, matchID int64, reason string) error {
+	query := `
+		UPDATE match_captures
+		SET status = 'failed', attempts = attempts + 1, last_error = $1
+		WHERE match_id = $2 AND status = 'pending'
+	`
+
+	result, err := tx.Exec(ctx, query, reason, matchID)
+	if err != nil {
+		return fmt.Errorf("failed to mark capture failed: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("capture of match %d is no longer pending", matchID)
+	}
+
+	return nil
+}
+
+// scanCapture scans a row selected with captureColumns
+func scanCapture(row pgx.Row) (*model.Capture, error) {
+	capture := &model.Capture{}
+	var currency string
+	err := row.Scan(
+		&capture.MatchID, &capture.BidID, &capture.AskID,
+		&capture.Amount, &currency, &capture.Status, &capture.Attempts,
+		&capture.NextAttemptAt, &capture.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	capture.Amount = capture.Amount.WithCurrency(currency)
+
+	return capture, nil
+}
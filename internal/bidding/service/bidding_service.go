@@ -6,37 +6,96 @@ import (
 	"log"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/repository"
+	feeModel "github.com/vvkuzmych/sneakers_marketplace/internal/fees/model"
 	feeService "github.com/vvkuzmych/sneakers_marketplace/internal/fees/service"
+	fxModel "github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	fxService "github.com/vvkuzmych/sneakers_marketplace/internal/fx/service"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	notificationPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/notification"
+	paymentPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
+	productPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/product"
+)
+
+const (
+	// A matched bid's capture is attempted this many times, waiting
+	// captureRetryDelay after the first failure and twice as long after
+	// each one after that. An attempt holds the capture for captureLease.
+	captureAttempts   = 5
+	captureRetryDelay = time.Minute
+	captureLease      = 5 * time.Minute
+	captureBatchSize  = 50
+
+	// matchFeeVertical is the fee schedule matches are charged under
+	// TODO: Get vertical from product (for now assume sneakers)
+	matchFeeVertical = "sneakers"
+
+	// Bids whose payment hold never completed expire after this long
+	pendingAuthorizationTimeout = 15 * time.Minute
 )
 
 // BiddingService handles business logic for bidding
 type BiddingService struct {
 	repo               *repository.BiddingRepository
 	notificationClient notificationPb.NotificationServiceClient
+	productClient      productPb.ProductServiceClient
+	paymentClient      paymentPb.PaymentServiceClient
 	feeService         *feeService.FeeService
+	fxService          *fxService.FXService // nil: only the settlement currency is accepted
+
+	// When set, bids stay in pending_authorization until a payment hold succeeds
+	requirePaymentHold bool
 }
 
 // NewBiddingService creates a new bidding service
-func NewBiddingService(repo *repository.BiddingRepository, notificationClient notificationPb.NotificationServiceClient, feeService *feeService.FeeService) *BiddingService {
+func NewBiddingService(
+	repo *repository.BiddingRepository,
+	notificationClient notificationPb.NotificationServiceClient,
+	productClient productPb.ProductServiceClient,
+	paymentClient paymentPb.PaymentServiceClient,
+	feeService *feeService.FeeService,
+	fxService *fxService.FXService,
+	requirePaymentHold bool,
+) *BiddingService {
 	return &BiddingService{
 		repo:               repo,
 		notificationClient: notificationClient,
+		productClient:      productClient,
+		paymentClient:      paymentClient,
 		feeService:         feeService,
+		fxService:          fxService,
+		requirePaymentHold: requirePaymentHold,
 	}
 }
 
-// PlaceBid places a new bid and attempts to match it
-func (s *BiddingService) PlaceBid(ctx context.Context, userID, productID, sizeID int64, price float64, quantity int, expiresInHours int) (*model.Bid, *model.Match, error) {
+// PlaceBid places a new bid and attempts to match it. The price may be in
+// any currency with a rate; it is converted to the settlement currency once,
+// at placement, and the bid competes on that price.
+// In payment-hold mode the bid is only activated once the buyer's card is authorized.
+func (s *BiddingService) PlaceBid(ctx context.Context, userID, productID, sizeID int64, price money.Money, quantity int, expiresInHours int, paymentMethodID, stripeCustomerID string) (*model.Bid, *model.Match, error) {
+	settlementPrice, quote, err := s.toSettlement(ctx, price)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	bid := &model.Bid{
-		UserID:    userID,
-		ProductID: productID,
-		SizeID:    sizeID,
-		Price:     price,
-		Quantity:  quantity,
-		Status:    model.StatusActive,
+		UserID:          userID,
+		ProductID:       productID,
+		SizeID:          sizeID,
+		Price:           price,
+		Quantity:        quantity,
+		Status:          model.StatusActive,
+		SettlementPrice: settlementPrice,
+		FXRate:          quote.Rate,
+	}
+
+	if s.requirePaymentHold {
+		if paymentMethodID == "" {
+			return nil, nil, fmt.Errorf("payment method is required to place a bid")
+		}
+		bid.Status = model.StatusPendingAuthorization
 	}
 
 	// Set expiration
@@ -49,25 +108,53 @@ func (s *BiddingService) PlaceBid(ctx context.Context, userID, productID, sizeID
 	if err := s.repo.PlaceBid(ctx, bid); err != nil {
 		return nil, nil, fmt.Errorf("failed to place bid: %w", err)
 	}
+	s.fxService.RecordBestEffort(ctx, quote, price, fxModel.PurposeBidPrice, fxModel.ReferenceBid, bid.ID)
+
+	// Place the payment hold and only then activate the bid
+	if s.requirePaymentHold {
+		if err := s.authorizeBid(ctx, bid, paymentMethodID, stripeCustomerID); err != nil {
+			if cancelErr := s.repo.UpdateBidStatus(ctx, nil, bid.ID, model.StatusCancelled); cancelErr != nil {
+				log.Printf("Failed to cancel bid %d after authorization failure: %v", bid.ID, cancelErr)
+			}
+			return nil, nil, fmt.Errorf("payment authorization failed: %w", err)
+		}
+
+		// The bid may have expired while the hold was placed
+		if err := s.repo.ActivateBid(ctx, bid.ID); err != nil {
+			if releaseErr := s.releaseBidAuthorization(ctx, bid, model.StatusExpired); releaseErr != nil {
+				log.Printf("Failed to release authorization for bid %d after activation failure: %v", bid.ID, releaseErr)
+			}
+			return nil, nil, fmt.Errorf("failed to activate bid: %w", err)
+		}
+		bid.Status = model.StatusActive
+	}
 
 	// Try to match immediately
 	match, err := s.tryMatchBid(ctx, bid)
 	if err != nil {
-		return bid, nil, fmt.Errorf("bid placed but matching failed: %w", err)
+		return bid, match, fmt.Errorf("bid placed but matching failed: %w", err)
 	}
 
 	return bid, match, nil
 }
 
-// PlaceAsk places a new ask and attempts to match it
-func (s *BiddingService) PlaceAsk(ctx context.Context, userID, productID, sizeID int64, price float64, quantity int, expiresInHours int) (*model.Ask, *model.Match, error) {
+// PlaceAsk places a new ask and attempts to match it. Like bids, asks in
+// other currencies are converted to the settlement currency when placed.
+func (s *BiddingService) PlaceAsk(ctx context.Context, userID, productID, sizeID int64, price money.Money, quantity int, expiresInHours int) (*model.Ask, *model.Match, error) {
+	settlementPrice, quote, err := s.toSettlement(ctx, price)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	ask := &model.Ask{
-		UserID:    userID,
-		ProductID: productID,
-		SizeID:    sizeID,
-		Price:     price,
-		Quantity:  quantity,
-		Status:    model.StatusActive,
+		UserID:          userID,
+		ProductID:       productID,
+		SizeID:          sizeID,
+		Price:           price,
+		Quantity:        quantity,
+		Status:          model.StatusPendingReservation,
+		SettlementPrice: settlementPrice,
+		FXRate:          quote.Rate,
 	}
 
 	// Set expiration
@@ -76,15 +163,28 @@ func (s *BiddingService) PlaceAsk(ctx context.Context, userID, productID, sizeID
 		ask.ExpiresAt = &expiresAt
 	}
 
-	// Create ask
+	// Create ask; it cannot be matched until its units are reserved
 	if err := s.repo.PlaceAsk(ctx, ask); err != nil {
 		return nil, nil, fmt.Errorf("failed to place ask: %w", err)
 	}
+	s.fxService.RecordBestEffort(ctx, quote, price, fxModel.PurposeAskPrice, fxModel.ReferenceAsk, ask.ID)
+
+	// Reserve the units on the product size so an ask can never exceed real stock
+	if err := s.reserveAskInventory(ctx, ask); err != nil {
+		if cancelErr := s.repo.UpdateAskStatus(ctx, nil, ask.ID, model.StatusCancelled); cancelErr != nil {
+			log.Printf("Failed to cancel ask %d after reservation failure: %v", ask.ID, cancelErr)
+		}
+		return nil, nil, fmt.Errorf("failed to reserve inventory for ask: %w", err)
+	}
+
+	if err := s.activateAsk(ctx, ask); err != nil {
+		return nil, nil, err
+	}
 
 	// Try to match immediately
 	match, err := s.tryMatchAsk(ctx, ask)
 	if err != nil {
-		return ask, nil, fmt.Errorf("ask placed but matching failed: %w", err)
+		return ask, match, fmt.Errorf("ask placed but matching failed: %w", err)
 	}
 
 	return ask, match, nil
@@ -111,7 +211,7 @@ func (s *BiddingService) tryMatchBid(ctx context.Context, bid *model.Bid) (*mode
 	// Create match in transaction
 	match, err := s.createMatch(ctx, bid, lowestAsk)
 	if err != nil {
-		return nil, err
+		return match, err
 	}
 
 	return match, nil
@@ -138,13 +238,15 @@ func (s *BiddingService) tryMatchAsk(ctx context.Context, ask *model.Ask) (*mode
 	// Create match in transaction
 	match, err := s.createMatch(ctx, highestBid, ask)
 	if err != nil {
-		return nil, err
+		return match, err
 	}
 
 	return match, nil
 }
 
-// createMatch creates a match between bid and ask
+// createMatch creates a match between bid and ask. With payment holds the
+// capture of the bid's hold is queued with the match and tried right away;
+// the expiry worker retries it if that fails.
 func (s *BiddingService) createMatch(ctx context.Context, bid *model.Bid, ask *model.Ask) (*model.Match, error) {
 	// Start transaction
 	tx, err := s.repo.BeginTx(ctx)
@@ -153,21 +255,15 @@ func (s *BiddingService) createMatch(ctx context.Context, bid *model.Bid, ask *m
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Match price is the ask price (seller's price)
-	matchPrice := ask.Price
+	// Take the bid's units off the ask
+	sold, err := s.sellAskUnits(ctx, tx, ask, bid.Quantity)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create match record
-	match := &model.Match{
-		BidID:     bid.ID,
-		AskID:     ask.ID,
-		BuyerID:   bid.UserID,
-		SellerID:  ask.UserID,
-		ProductID: bid.ProductID,
-		SizeID:    bid.SizeID,
-		Price:     matchPrice,
-		Quantity:  bid.Quantity, // Assuming equal quantities
-		Status:    model.StatusPending,
-	}
+	match := newMatch(bid, sold)
+	fees, buyerTotal := s.calculateMatchFees(ctx, match)
 
 	if err := s.repo.CreateMatch(ctx, tx, match); err != nil {
 		return nil, fmt.Errorf("failed to create match: %w", err)
@@ -178,9 +274,22 @@ func (s *BiddingService) createMatch(ctx context.Context, bid *model.Bid, ask *m
 		return nil, fmt.Errorf("failed to update bid status: %w", err)
 	}
 
-	// Update ask status to matched
-	if err := s.repo.UpdateAskStatus(ctx, tx, ask.ID, model.StatusMatched); err != nil {
-		return nil, fmt.Errorf("failed to update ask status: %w", err)
+	// The hold is in the bid's currency, at the rate the bid was placed at
+	var capture *model.Capture
+	var quote *fxModel.Quote
+	if s.requirePaymentHold && s.paymentClient != nil {
+		quote = fxModel.Snapshot(bid.Price.Currency, match.Price.Currency, bid.FXRate).Inverse()
+		capture = &model.Capture{
+			MatchID:       match.ID,
+			BidID:         bid.ID,
+			AskID:         match.AskID,
+			Amount:        quote.Apply(buyerTotal),
+			Status:        model.CaptureStatusPending,
+			NextAttemptAt: time.Now().Add(captureLease),
+		}
+		if err := s.repo.CreateCapture(ctx, tx, capture); err != nil {
+			return nil, err
+		}
 	}
 
 	// Commit transaction
@@ -188,43 +297,328 @@ func (s *BiddingService) createMatch(ctx context.Context, bid *model.Bid, ask *m
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	// Calculate and record fees (after transaction committed)
-	if s.feeService != nil {
-		// TODO: Get vertical from product (for now assume sneakers)
-		vertical := "sneakers"
-		includeAuth := true // TODO: Make this configurable
+	s.recordMatchFees(ctx, match, fees)
+
+	if capture != nil {
+		s.fxService.RecordBestEffort(ctx, quote, buyerTotal, fxModel.PurposeBidCapture, fxModel.ReferenceMatch, match.ID)
+		s.captureBidAuthorization(ctx, capture)
+	}
+
+	s.notifyMatchCreated(match)
+
+	return match, nil
+}
+
+// RetryCaptures attempts the captures that are due and returns how many
+// succeeded
+func (s *BiddingService) RetryCaptures(ctx context.Context) (int, error) {
+	captures, err := s.repo.ClaimDueCaptures(ctx, captureLease, captureBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	captured := 0
+	for _, capture := range captures {
+		if s.captureBidAuthorization(ctx, capture) {
+			captured++
+		}
+	}
+
+	return captured, nil
+}
+
+// captureBidAuthorization captures the matched bid's hold, which also pays
+// the match's order, and reports whether it succeeded. Capturing is safe to
+// retry, so a failure is retried later, backing off by captureRetryDelay
+// doubled each time; after captureAttempts the match is undone.
+func (s *BiddingService) captureBidAuthorization(ctx context.Context, capture *model.Capture) bool {
+	resp, err := s.paymentClient.CaptureBidAuthorization(ctx, &paymentPb.CaptureBidAuthorizationRequest{
+		BidId:       capture.BidID,
+		MatchId:     capture.MatchID,
+		Amount:      capture.Amount.Major(),
+		AmountMinor: capture.Amount.Amount,
+		Currency:    capture.Amount.Currency,
+	})
+	if err == nil && resp.Error != "" {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	if err == nil {
+		if err := s.repo.CompleteCapture(ctx, capture.MatchID); err != nil {
+			log.Printf("Captured bid %d but failed to record it: %v", capture.BidID, err)
+		}
+		return true
+	}
+
+	attempt := capture.Attempts + 1
+	log.Printf("Failed to capture authorization for bid %d (attempt %d/%d): %v", capture.BidID, attempt, captureAttempts, err)
+
+	if attempt < captureAttempts {
+		next := time.Now().Add(captureRetryDelay << (attempt - 1))
+		if err := s.repo.RetryCapture(ctx, capture.MatchID, next, err.Error()); err != nil {
+			log.Printf("Failed to reschedule capture of match %d: %v", capture.MatchID, err)
+		}
+		return false
+	}
+
+	if err := s.undoMatch(ctx, capture, err); err != nil {
+		log.Printf("Failed to undo match %d after its capture failed: %v", capture.MatchID, err)
+	}
+	return false
+}
+
+// undoMatch gives up on a match whose capture ran out of attempts: the
+// buyer's hold is released and the bid expires, and the ask goes back on
+// the market with the units it still has reserved. A hold that cannot be
+// released may have been captured after all, so the capture is retried
+// instead.
+func (s *BiddingService) undoMatch(ctx context.Context, capture *model.Capture, captureErr error) error {
+	bid, err := s.repo.GetBidByID(ctx, capture.BidID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.releaseBidAuthorization(ctx, bid, model.StatusExpired); err != nil {
+		next := time.Now().Add(captureRetryDelay << (captureAttempts - 1))
+		if retryErr := s.repo.RetryCapture(ctx, capture.MatchID, next, captureErr.Error()); retryErr != nil {
+			log.Printf("Failed to reschedule capture of match %d: %v", capture.MatchID, retryErr)
+		}
+		return fmt.Errorf("hold of bid %d not released: %w", bid.ID, err)
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.repo.FailCapture(ctx, tx, capture.MatchID, captureErr.Error()); err != nil {
+		return err
+	}
 
-		feeBreakdown, err := s.feeService.CalculateFees(ctx, vertical, matchPrice, includeAuth)
+	if err := s.repo.UpdateMatchStatus(ctx, tx, capture.MatchID, model.StatusFailed); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateBidStatus(ctx, tx, bid.ID, model.StatusExpired); err != nil {
+		return err
+	}
+
+	// An ask that expired meanwhile is expired on the next sweep
+	if err := s.repo.UpdateAskStatus(ctx, tx, capture.AskID, model.StatusActive); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Match %d undone: bid %d expired, ask %d relisted", capture.MatchID, bid.ID, capture.AskID)
+	return nil
+}
+
+// sellAskUnits marks quantity units of the ask matched inside tx and
+// returns the ask holding them. An ask with more units is split; the rest
+// stay on the market, and both keep selling from the ask's reservation.
+func (s *BiddingService) sellAskUnits(ctx context.Context, tx pgx.Tx, ask *model.Ask, quantity int) (*model.Ask, error) {
+	if ask.Quantity <= quantity {
+		if err := s.repo.UpdateAskStatus(ctx, tx, ask.ID, model.StatusMatched); err != nil {
+			return nil, fmt.Errorf("failed to update ask status: %w", err)
+		}
+		return ask, nil
+	}
+
+	split, err := ask.Split(quantity, model.StatusMatched)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.SplitAsk(ctx, tx, ask.ID, split)
+}
+
+// newMatch matches bid and ask at the ask's settlement price, snapshotting
+// both sides' rates
+func newMatch(bid *model.Bid, ask *model.Ask) *model.Match {
+	return &model.Match{
+		BidID:     bid.ID,
+		AskID:     ask.ID,
+		BuyerID:   bid.UserID,
+		SellerID:  ask.UserID,
+		ProductID: ask.ProductID,
+		SizeID:    ask.SizeID,
+		Price:     ask.SettlementPrice, // the seller's price
+		Quantity:  bid.Quantity,        // Assuming equal quantities
+		Status:    model.StatusPending,
+
+		SellerPrice:   ask.Price,
+		SellerFXRate:  ask.FXRate,
+		BuyerCurrency: bid.Price.Currency,
+		BuyerFXRate:   bid.FXRate,
+
+		InventoryReference: ask.InventoryReference(),
+	}
+}
+
+// calculateMatchFees calculates the match's fee breakdown and returns it
+// with the amount the buyer owes. Without a breakdown the buyer owes the
+// price alone.
+func (s *BiddingService) calculateMatchFees(ctx context.Context, match *model.Match) (*feeModel.FeeBreakdown, money.Money) {
+	// Amount the buyer owes; refined by the fee breakdown below
+	buyerTotal := match.Price.Mul(int64(match.Quantity))
+
+	if s.feeService == nil {
+		return nil, buyerTotal
+	}
+
+	includeAuth := true // TODO: Make this configurable
+
+	feeBreakdown, err := s.feeService.CalculateFees(ctx, matchFeeVertical, match.Price, includeAuth)
+	if err != nil {
+		log.Printf("Failed to calculate fees for bid %d: %v", match.BidID, err)
+		return nil, buyerTotal
+	}
+
+	return feeBreakdown, feeBreakdown.BuyerTotal.Mul(int64(match.Quantity))
+}
+
+// recordMatchFees records the committed match's fee breakdown
+func (s *BiddingService) recordMatchFees(ctx context.Context, match *model.Match, fees *feeModel.FeeBreakdown) {
+	if fees == nil {
+		return
+	}
+
+	if err := s.feeService.RecordTransactionFee(ctx, match.ID, nil, fees, matchFeeVertical); err != nil {
+		log.Printf("Failed to record transaction fee for match %d: %v", match.ID, err)
+	}
+}
+
+// notifyMatchCreated sends the match notification asynchronously (don't block the response)
+func (s *BiddingService) notifyMatchCreated(match *model.Match) {
+	if s.notificationClient == nil {
+		return
+	}
+
+	go func() {
+		notifyCtx := context.Background()
+		_, err := s.notificationClient.NotifyMatchCreated(notifyCtx, &notificationPb.NotifyMatchCreatedRequest{
+			MatchId:     match.ID,
+			BuyerId:     match.BuyerID,
+			SellerId:    match.SellerID,
+			ProductId:   match.ProductID,
+			ProductName: "Product", // TODO: Get product name from Product Service
+			Size:        "10",      // TODO: Get size from Size table
+			Price:       match.Price.Major(),
+		})
 		if err != nil {
-			log.Printf("Failed to calculate fees for match %d: %v", match.ID, err)
-		} else {
-			// Record transaction fee
-			if err := s.feeService.RecordTransactionFee(ctx, match.ID, nil, feeBreakdown, vertical); err != nil {
-				log.Printf("Failed to record transaction fee for match %d: %v", match.ID, err)
-			}
+			log.Printf("Failed to send match notification: %v", err)
 		}
+	}()
+}
+
+// CheckoutAsks buys the current lowest ask for every cart item in one
+// transaction. Each ask is locked, matched against a new bid from the buyer
+// and becomes its own match (and later its own order with that ask's
+// seller). An ask with more units sells one and keeps the rest listed.
+// Either every item is matched or none is.
+func (s *BiddingService) CheckoutAsks(ctx context.Context, buyerID int64, items []model.CheckoutItem) ([]*model.Match, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items to check out")
 	}
 
-	// Send notification asynchronously (don't block the response)
-	if s.notificationClient != nil {
-		go func() {
-			notifyCtx := context.Background()
-			_, err := s.notificationClient.NotifyMatchCreated(notifyCtx, &notificationPb.NotifyMatchCreatedRequest{
-				MatchId:     match.ID,
-				BuyerId:     match.BuyerID,
-				SellerId:    match.SellerID,
-				ProductId:   match.ProductID,
-				ProductName: "Product", // TODO: Get product name from Product Service
-				Size:        "10",      // TODO: Get size from Size table
-				Price:       match.Price,
-			})
-			if err != nil {
-				log.Printf("Failed to send match notification: %v", err)
-			}
-		}()
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	return match, nil
+	matches := make([]*model.Match, 0, len(items))
+	for _, item := range items {
+		ask, err := s.repo.LockLowestAsk(ctx, tx, item.ProductID, item.SizeID, buyerID)
+		if err != nil {
+			return nil, err
+		}
+		if ask == nil {
+			return nil, fmt.Errorf("product %d size %d is no longer available", item.ProductID, item.SizeID)
+		}
+		if item.MaxPrice.IsPositive() && ask.SettlementPrice.GreaterThan(item.MaxPrice) {
+			return nil, fmt.Errorf("price of product %d size %d changed to %s", item.ProductID, item.SizeID, ask.SettlementPrice)
+		}
+
+		// The buyer's currency is handled by the order; the bid is in settlement currency
+		bid := &model.Bid{
+			UserID:          buyerID,
+			ProductID:       ask.ProductID,
+			SizeID:          ask.SizeID,
+			Price:           ask.SettlementPrice,
+			Quantity:        1,
+			Status:          model.StatusMatched,
+			SettlementPrice: ask.SettlementPrice,
+			FXRate:          money.OneRate(),
+		}
+		if err := s.repo.CreateBidTx(ctx, tx, bid); err != nil {
+			return nil, err
+		}
+
+		sold, err := s.sellAskUnits(ctx, tx, ask, bid.Quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		match := newMatch(bid, sold)
+		if err := s.repo.CreateMatch(ctx, tx, match); err != nil {
+			return nil, fmt.Errorf("failed to create match: %w", err)
+		}
+
+		matches = append(matches, match)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, match := range matches {
+		fees, _ := s.calculateMatchFees(ctx, match)
+		s.recordMatchFees(ctx, match, fees)
+		s.notifyMatchCreated(match)
+	}
+
+	return matches, nil
+}
+
+// RevertCheckout undoes checkout matches whose orders could not be created:
+// matches fail, bids are canceled and asks go back on the market. The asks
+// never released their inventory, so nothing is re-reserved.
+func (s *BiddingService) RevertCheckout(ctx context.Context, matchIDs []int64) error {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, id := range matchIDs {
+		match, err := s.repo.GetMatchByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if match.Status != model.StatusPending {
+			return fmt.Errorf("match %d cannot be reverted: status is %s", id, match.Status)
+		}
+
+		if err := s.repo.UpdateMatchStatus(ctx, tx, match.ID, model.StatusFailed); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateBidStatus(ctx, tx, match.BidID, model.StatusCancelled); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateAskStatus(ctx, tx, match.AskID, model.StatusActive); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
 }
 
 // GetBid retrieves a bid by ID
@@ -275,7 +669,16 @@ func (s *BiddingService) CancelBid(ctx context.Context, bidID, userID int64) err
 	}
 
 	// Update status
-	return s.repo.UpdateBidStatus(ctx, nil, bidID, model.StatusCancelled)
+	if err := s.repo.UpdateBidStatus(ctx, nil, bidID, model.StatusCancelled); err != nil {
+		return err
+	}
+
+	// Release the buyer's payment hold
+	if err := s.releaseBidAuthorization(ctx, bid, model.StatusCancelled); err != nil {
+		log.Printf("Failed to release authorization for canceled bid %d: %v", bid.ID, err)
+	}
+
+	return nil
 }
 
 // GetAsk retrieves an ask by ID
@@ -326,7 +729,270 @@ func (s *BiddingService) CancelAsk(ctx context.Context, askID, userID int64) err
 	}
 
 	// Update status
-	return s.repo.UpdateAskStatus(ctx, nil, askID, model.StatusCancelled)
+	if err := s.repo.UpdateAskStatus(ctx, nil, askID, model.StatusCancelled); err != nil {
+		return err
+	}
+
+	// Give the reserved units back to the product size
+	if err := s.releaseAskInventory(ctx, ask); err != nil {
+		log.Printf("Failed to release inventory for canceled ask %d: %v", ask.ID, err)
+	}
+
+	return nil
+}
+
+// RelistAsk puts the ask of a failed match back on the market. It is used
+// when the buyer never paid: the match is marked failed, the ask's inventory
+// is re-reserved, the ask becomes active again and matching is retried.
+func (s *BiddingService) RelistAsk(ctx context.Context, matchID int64) (*model.Ask, *model.Match, error) {
+	match, err := s.repo.GetMatchByID(ctx, matchID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ask, err := s.failMatch(ctx, match, model.StatusPendingReservation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The order released the units when it was canceled; take them back
+	if err := s.reserveAskInventory(ctx, ask); err != nil {
+		if cancelErr := s.repo.UpdateAskStatus(ctx, nil, ask.ID, model.StatusCancelled); cancelErr != nil {
+			log.Printf("Failed to cancel ask %d after reservation failure: %v", ask.ID, cancelErr)
+		}
+		return nil, nil, fmt.Errorf("failed to reserve inventory for relisted ask: %w", err)
+	}
+
+	if err := s.activateAsk(ctx, ask); err != nil {
+		return nil, nil, err
+	}
+
+	newMatch, err := s.tryMatchAsk(ctx, ask)
+	if err != nil {
+		return ask, newMatch, fmt.Errorf("ask relisted but matching failed: %w", err)
+	}
+
+	return ask, newMatch, nil
+}
+
+// CancelMatch fails the match of a canceled order and withdraws the
+// seller's ask, for when unpaid asks are not relisted. The order released
+// the units when it was canceled.
+func (s *BiddingService) CancelMatch(ctx context.Context, matchID int64) (*model.Ask, error) {
+	match, err := s.repo.GetMatchByID(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.failMatch(ctx, match, model.StatusCancelled)
+}
+
+// failMatch marks a match failed and moves its matched ask to askStatus
+func (s *BiddingService) failMatch(ctx context.Context, match *model.Match, askStatus string) (*model.Ask, error) {
+	ask, err := s.repo.GetAskByID(ctx, match.AskID)
+	if err != nil {
+		return nil, fmt.Errorf("ask not found: %w", err)
+	}
+
+	if ask.Status != model.StatusMatched {
+		return nil, fmt.Errorf("ask of match %d is %s, not matched", match.ID, ask.Status)
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.repo.UpdateMatchStatus(ctx, tx, match.ID, model.StatusFailed); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateAskStatus(ctx, tx, ask.ID, askStatus); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	ask.Status = askStatus
+	return ask, nil
+}
+
+// ExpireStale marks bids and asks past their expiration, and bids stuck
+// pending authorization, as expired and releases the payment holds and
+// inventory they were keeping
+func (s *BiddingService) ExpireStale(ctx context.Context) (int, int, error) {
+	bids, err := s.repo.ExpireBids(ctx, time.Now().Add(-pendingAuthorizationTimeout))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, bid := range bids {
+		if err := s.releaseBidAuthorization(ctx, bid, model.StatusExpired); err != nil {
+			log.Printf("Failed to release authorization for expired bid %d: %v", bid.ID, err)
+		}
+	}
+
+	asks, err := s.repo.ExpireAsks(ctx)
+	if err != nil {
+		return len(bids), 0, err
+	}
+
+	for _, ask := range asks {
+		if err := s.releaseAskInventory(ctx, ask); err != nil {
+			log.Printf("Failed to release inventory for expired ask %d: %v", ask.ID, err)
+		}
+	}
+
+	return len(bids), len(asks), nil
+}
+
+// StartExpiryWorker retries due captures and runs ExpireStale on every tick
+// until ctx is canceled
+func (s *BiddingService) StartExpiryWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			captured, err := s.RetryCaptures(ctx)
+			if err != nil {
+				log.Printf("Capture retry failed: %v", err)
+			} else if captured > 0 {
+				log.Printf("Captured %d matched bids on retry", captured)
+			}
+
+			expiredBids, expiredAsks, err := s.ExpireStale(ctx)
+			if err != nil {
+				log.Printf("Expiry worker failed: %v", err)
+				continue
+			}
+			if expiredBids > 0 || expiredAsks > 0 {
+				log.Printf("Expired %d bids and %d asks", expiredBids, expiredAsks)
+			}
+		}
+	}
+}
+
+// authorizeBid places a manual-capture hold for the bid through the Payment Service.
+// The hold covers the bid price plus buyer fees; the match may capture less.
+func (s *BiddingService) authorizeBid(ctx context.Context, bid *model.Bid, paymentMethodID, stripeCustomerID string) error {
+	if s.paymentClient == nil {
+		return fmt.Errorf("payment service unavailable")
+	}
+
+	// Fees are priced in the settlement currency, then held in the bid's
+	amount := bid.SettlementPrice.Mul(int64(bid.Quantity))
+	if s.feeService != nil {
+		// TODO: Get vertical from product (for now assume sneakers)
+		feeBreakdown, err := s.feeService.CalculateFees(ctx, "sneakers", bid.SettlementPrice, true)
+		if err == nil {
+			amount = feeBreakdown.BuyerTotal.Mul(int64(bid.Quantity))
+		}
+	}
+	amount = fxModel.Snapshot(bid.Price.Currency, amount.Currency, bid.FXRate).Inverse().Apply(amount)
+
+	resp, err := s.paymentClient.AuthorizeBid(ctx, &paymentPb.AuthorizeBidRequest{
+		BidId:            bid.ID,
+		UserId:           bid.UserID,
+		Amount:           amount.Major(),
+		AmountMinor:      amount.Amount,
+		Currency:         amount.Currency,
+		StripeCustomerId: stripeCustomerID,
+		PaymentMethodId:  paymentMethodID,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+
+	return nil
+}
+
+// releaseBidAuthorization releases the bid's payment hold, if holds are enabled
+func (s *BiddingService) releaseBidAuthorization(ctx context.Context, bid *model.Bid, reason string) error {
+	if !s.requirePaymentHold {
+		return nil
+	}
+	if s.paymentClient == nil {
+		return fmt.Errorf("payment service unavailable")
+	}
+
+	resp, err := s.paymentClient.ReleaseBidAuthorization(ctx, &paymentPb.ReleaseBidAuthorizationRequest{
+		BidId:  bid.ID,
+		Reason: reason,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+
+	return nil
+}
+
+// reserveAskInventory reserves the ask's units through the Product Service
+func (s *BiddingService) reserveAskInventory(ctx context.Context, ask *model.Ask) error {
+	if s.productClient == nil {
+		return fmt.Errorf("product service unavailable")
+	}
+
+	resp, err := s.productClient.ReserveInventory(ctx, &productPb.ReserveInventoryRequest{
+		SizeId:   ask.SizeID,
+		Quantity: int32(ask.Quantity),
+		OrderId:  ask.InventoryReference(),
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Error)
+	}
+
+	return nil
+}
+
+// activateAsk puts an ask whose units were reserved on the market, giving
+// the units back if it can no longer be activated
+func (s *BiddingService) activateAsk(ctx context.Context, ask *model.Ask) error {
+	if err := s.repo.ActivateAsk(ctx, ask.ID); err != nil {
+		if releaseErr := s.releaseAskInventory(ctx, ask); releaseErr != nil {
+			log.Printf("Failed to release inventory for ask %d after activation failure: %v", ask.ID, releaseErr)
+		}
+		return fmt.Errorf("failed to activate ask: %w", err)
+	}
+	ask.Status = model.StatusActive
+
+	return nil
+}
+
+// releaseAskInventory releases the ask's reserved units through the Product Service
+func (s *BiddingService) releaseAskInventory(ctx context.Context, ask *model.Ask) error {
+	if s.productClient == nil {
+		return fmt.Errorf("product service unavailable")
+	}
+
+	resp, err := s.productClient.ReleaseInventory(ctx, &productPb.ReleaseInventoryRequest{
+		SizeId:   ask.SizeID,
+		Quantity: int32(ask.Quantity),
+		OrderId:  ask.InventoryReference(),
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Error)
+	}
+
+	return nil
 }
 
 // GetHighestBid retrieves the highest bid for a product/size
@@ -339,23 +1005,32 @@ func (s *BiddingService) GetLowestAsk(ctx context.Context, productID, sizeID int
 	return s.repo.GetLowestAsk(ctx, productID, sizeID)
 }
 
-// GetMarketPrice retrieves market data for a product/size
-func (s *BiddingService) GetMarketPrice(ctx context.Context, productID, sizeID int64) (*model.MarketPrice, error) {
+// GetMarketPrice retrieves market data for a product/size, with prices
+// converted to currency for display (empty: the settlement currency)
+func (s *BiddingService) GetMarketPrice(ctx context.Context, productID, sizeID int64, currency string) (*model.MarketPrice, error) {
+	quote, err := s.displayQuote(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
 	marketPrice := &model.MarketPrice{
-		ProductID: productID,
-		SizeID:    sizeID,
+		ProductID:  productID,
+		SizeID:     sizeID,
+		Currency:   quote.To,
+		HighestBid: money.Zero(quote.To),
+		LowestAsk:  money.Zero(quote.To),
 	}
 
 	// Get highest bid
 	highestBid, err := s.repo.GetHighestBid(ctx, productID, sizeID)
 	if err == nil && highestBid != nil {
-		marketPrice.HighestBid = highestBid.Price
+		marketPrice.HighestBid = quote.Apply(highestBid.SettlementPrice)
 	}
 
 	// Get lowest ask
 	lowestAsk, err := s.repo.GetLowestAsk(ctx, productID, sizeID)
 	if err == nil && lowestAsk != nil {
-		marketPrice.LowestAsk = lowestAsk.Price
+		marketPrice.LowestAsk = quote.Apply(lowestAsk.SettlementPrice)
 	}
 
 	// Get total counts
@@ -372,11 +1047,42 @@ func (s *BiddingService) GetMarketPrice(ctx context.Context, productID, sizeID i
 	_ = asks // unused
 
 	// TODO: Get last sale price from matches table
-	marketPrice.LastSale = 0
+	marketPrice.LastSale = money.Zero(quote.To)
 
 	return marketPrice, nil
 }
 
+// toSettlement converts a listing price to the settlement currency at the
+// current rate
+func (s *BiddingService) toSettlement(ctx context.Context, price money.Money) (money.Money, *fxModel.Quote, error) {
+	if s.fxService == nil {
+		if price.Currency != money.DefaultCurrency {
+			return money.Money{}, nil, fmt.Errorf("prices must be in %s", money.DefaultCurrency)
+		}
+		return price, fxModel.Snapshot(price.Currency, price.Currency, money.OneRate()), nil
+	}
+
+	settled, quote, err := s.fxService.ToSettlement(ctx, price)
+	if err != nil {
+		return money.Money{}, nil, fmt.Errorf("failed to convert price: %w", err)
+	}
+	if !settled.IsPositive() {
+		return money.Money{}, nil, fmt.Errorf("price %s is below the smallest %s amount", price, settled.Currency)
+	}
+
+	return settled, quote, nil
+}
+
+// displayQuote quotes settlement prices in currency for display only; these
+// conversions are not recorded
+func (s *BiddingService) displayQuote(ctx context.Context, currency string) (*fxModel.Quote, error) {
+	if currency == "" || s.fxService == nil {
+		return fxModel.Snapshot(money.DefaultCurrency, money.DefaultCurrency, money.OneRate()), nil
+	}
+
+	return s.fxService.Quote(ctx, money.DefaultCurrency, currency)
+}
+
 // GetMatch retrieves a match by ID
 func (s *BiddingService) GetMatch(ctx context.Context, matchID int64) (*model.Match, error) {
 	return s.repo.GetMatchByID(ctx, matchID)
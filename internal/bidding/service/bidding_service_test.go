@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/bidding/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+func listedAsk(id int64, quantity int) *model.Ask {
+	price := money.New(25000, money.DefaultCurrency)
+	return &model.Ask{
+		ID:              id,
+		UserID:          8,
+		ProductID:       1,
+		SizeID:          2,
+		Price:           price,
+		Quantity:        quantity,
+		Status:          model.StatusActive,
+		SettlementPrice: price,
+		FXRate:          money.OneRate(),
+	}
+}
+
+func buyerBid(id int64) *model.Bid {
+	price := money.New(26000, money.DefaultCurrency)
+	return &model.Bid{
+		ID:              id,
+		UserID:          7,
+		ProductID:       1,
+		SizeID:          2,
+		Price:           price,
+		Quantity:        1,
+		Status:          model.StatusActive,
+		SettlementPrice: price,
+		FXRate:          money.OneRate(),
+	}
+}
+
+func TestAskSoldInTwoSplits(t *testing.T) {
+	ask := listedAsk(10, 3)
+	const reference = "ask-10"
+
+	// Each sale splits one unit off; the database numbers the splits
+	var matches []*model.Match
+	for i, id := range []int64{11, 12} {
+		sold, err := ask.Split(1, model.StatusMatched)
+		if err != nil {
+			t.Fatalf("split %d: %v", i+1, err)
+		}
+		sold.ID = id
+
+		if sold.Quantity != 1 || sold.Status != model.StatusMatched {
+			t.Errorf("split %d holds %d units as %s, want 1 as matched", i+1, sold.Quantity, sold.Status)
+		}
+		if got := sold.InventoryReference(); got != reference {
+			t.Errorf("split %d reference = %s, want %s", i+1, got, reference)
+		}
+
+		matches = append(matches, newMatch(buyerBid(int64(i+1)), sold))
+	}
+
+	if ask.Quantity != 1 || ask.Status != model.StatusActive {
+		t.Errorf("ask keeps %d units as %s, want 1 as active", ask.Quantity, ask.Status)
+	}
+	if got := ask.InventoryReference(); got != reference {
+		t.Errorf("remaining ask reference = %s, want %s", got, reference)
+	}
+
+	// The orders complete or release the ask's reservation
+	for i, match := range matches {
+		if match.InventoryReference != reference {
+			t.Errorf("match %d reference = %s, want %s", i+1, match.InventoryReference, reference)
+		}
+		if match.AskID != int64(11+i) {
+			t.Errorf("match %d sold ask %d, want %d", i+1, match.AskID, 11+i)
+		}
+	}
+
+	// The last unit sells as the ask itself
+	if _, err := ask.Split(1, model.StatusMatched); err == nil {
+		t.Error("splitting the last unit off an ask succeeded")
+	}
+}
+
+func TestRelistedSplitKeepsReservation(t *testing.T) {
+	ask := listedAsk(10, 4)
+
+	sold, err := ask.Split(2, model.StatusMatched)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	sold.ID = 11
+
+	// The buyer never paid; the split goes back on the market and sells again
+	sold.Status = model.StatusActive
+	resold, err := sold.Split(1, model.StatusMatched)
+	if err != nil {
+		t.Fatalf("split of relisted split: %v", err)
+	}
+
+	if resold.SplitFromID == nil || *resold.SplitFromID != 10 {
+		t.Errorf("resold split from %v, want 10", resold.SplitFromID)
+	}
+	if got := resold.InventoryReference(); got != "ask-10" {
+		t.Errorf("resold reference = %s, want ask-10", got)
+	}
+}
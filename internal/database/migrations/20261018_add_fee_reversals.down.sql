@@ -0,0 +1,12 @@
+-- Rollback: Remove fee reversals
+-- Author: Sneakers Marketplace Team
+-- Date: 2026-10-18
+-- Description: Rollback fee reversals migration
+
+BEGIN;
+
+ALTER TABLE IF EXISTS transaction_fees
+    DROP COLUMN IF EXISTS reversal_reason,
+    DROP COLUMN IF EXISTS reversed_at;
+
+COMMIT;
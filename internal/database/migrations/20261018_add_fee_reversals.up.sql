@@ -0,0 +1,14 @@
+-- Migration: Add fee reversals
+-- Author: Sneakers Marketplace Team
+-- Date: 2026-10-18
+-- Description: Mark transaction fees reversed when their order is refunded or canceled
+
+BEGIN;
+
+ALTER TABLE transaction_fees
+    ADD COLUMN IF NOT EXISTS reversed_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS reversal_reason TEXT;
+
+COMMENT ON COLUMN transaction_fees.reversed_at IS 'Set when the order was refunded or canceled; reversed fees are not revenue';
+
+COMMIT;
@@ -1,41 +1,49 @@
 package model
 
+import "github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+
 // FeeBreakdown provides a detailed breakdown of all fees for a transaction
 // Used for displaying fee information to users before they commit to a transaction
 type FeeBreakdown struct {
-	SalePrice            float64 `json:"sale_price"`
-	BuyerProcessingFee   float64 `json:"buyer_processing_fee"`
-	BuyerShippingFee     float64 `json:"buyer_shipping_fee"`
-	BuyerTotal           float64 `json:"buyer_total"`
-	SellerTransactionFee float64 `json:"seller_transaction_fee"`
-	SellerAuthFee        float64 `json:"seller_auth_fee"`
-	SellerShippingCost   float64 `json:"seller_shipping_cost"`
-	SellerPayout         float64 `json:"seller_payout"`
-	PlatformRevenue      float64 `json:"platform_revenue"`
+	SalePrice            money.Money `json:"sale_price"`
+	BuyerProcessingFee   money.Money `json:"buyer_processing_fee"`
+	BuyerShippingFee     money.Money `json:"buyer_shipping_fee"`
+	BuyerTotal           money.Money `json:"buyer_total"`
+	SellerTransactionFee money.Money `json:"seller_transaction_fee"`
+	SellerAuthFee        money.Money `json:"seller_auth_fee"`
+	SellerShippingCost   money.Money `json:"seller_shipping_cost"`
+	SellerPayout         money.Money `json:"seller_payout"`
+	PlatformRevenue      money.Money `json:"platform_revenue"`
 }
 
 // NewFeeBreakdown creates a new FeeBreakdown from basic parameters
-func NewFeeBreakdown(salePrice float64) *FeeBreakdown {
+func NewFeeBreakdown(salePrice money.Money) *FeeBreakdown {
+	zero := money.Zero(salePrice.Currency)
 	return &FeeBreakdown{
-		SalePrice: salePrice,
+		SalePrice:            salePrice,
+		BuyerProcessingFee:   zero,
+		BuyerShippingFee:     zero,
+		SellerTransactionFee: zero,
+		SellerAuthFee:        zero,
+		SellerShippingCost:   zero,
 	}
 }
 
 // CalculateTotals calculates buyer total, seller payout, and platform revenue
 func (fb *FeeBreakdown) CalculateTotals() {
 	// Buyer pays: sale price + fees
-	fb.BuyerTotal = fb.SalePrice + fb.BuyerProcessingFee + fb.BuyerShippingFee
+	fb.BuyerTotal = money.Sum(fb.SalePrice, fb.BuyerProcessingFee, fb.BuyerShippingFee)
 
 	// Seller receives: sale price - fees
-	fb.SellerPayout = fb.SalePrice - fb.SellerTransactionFee - fb.SellerAuthFee - fb.SellerShippingCost
+	fb.SellerPayout = fb.SalePrice.Sub(money.Sum(fb.SellerTransactionFee, fb.SellerAuthFee, fb.SellerShippingCost))
 
 	// Platform earns: all fees
-	fb.PlatformRevenue = fb.BuyerProcessingFee + fb.SellerTransactionFee + fb.SellerAuthFee
+	fb.PlatformRevenue = money.Sum(fb.BuyerProcessingFee, fb.SellerTransactionFee, fb.SellerAuthFee)
 
 	// Add shipping markup (buyer pays more than seller costs)
-	shippingMarkup := fb.BuyerShippingFee - fb.SellerShippingCost
-	if shippingMarkup > 0 {
-		fb.PlatformRevenue += shippingMarkup
+	shippingMarkup := fb.BuyerShippingFee.Sub(fb.SellerShippingCost)
+	if shippingMarkup.IsPositive() {
+		fb.PlatformRevenue = fb.PlatformRevenue.Add(shippingMarkup)
 	}
 }
 
@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
 
 // TransactionFee represents a record of fees charged for a transaction
 type TransactionFee struct {
@@ -8,16 +12,17 @@ type TransactionFee struct {
 	MatchID                 int64                  `json:"match_id" db:"match_id"`
 	OrderID                 *int64                 `json:"order_id,omitempty" db:"order_id"`
 	Vertical                string                 `json:"vertical" db:"vertical"`
-	SalePrice               float64                `json:"sale_price" db:"sale_price"`
-	BuyerProcessingFee      float64                `json:"buyer_processing_fee" db:"buyer_processing_fee"`
-	BuyerShippingFee        float64                `json:"buyer_shipping_fee" db:"buyer_shipping_fee"`
-	BuyerTotal              float64                `json:"buyer_total" db:"buyer_total"`
-	SellerTransactionFee    float64                `json:"seller_transaction_fee" db:"seller_transaction_fee"`
-	SellerAuthenticationFee float64                `json:"seller_authentication_fee" db:"seller_authentication_fee"`
-	SellerShippingCost      float64                `json:"seller_shipping_cost" db:"seller_shipping_cost"`
-	SellerPayout            float64                `json:"seller_payout" db:"seller_payout"`
-	PlatformRevenue         float64                `json:"platform_revenue" db:"platform_revenue"`
+	SalePrice               money.Money            `json:"sale_price" db:"sale_price"`
+	BuyerProcessingFee      money.Money            `json:"buyer_processing_fee" db:"buyer_processing_fee"`
+	BuyerShippingFee        money.Money            `json:"buyer_shipping_fee" db:"buyer_shipping_fee"`
+	BuyerTotal              money.Money            `json:"buyer_total" db:"buyer_total"`
+	SellerTransactionFee    money.Money            `json:"seller_transaction_fee" db:"seller_transaction_fee"`
+	SellerAuthenticationFee money.Money            `json:"seller_authentication_fee" db:"seller_authentication_fee"`
+	SellerShippingCost      money.Money            `json:"seller_shipping_cost" db:"seller_shipping_cost"`
+	SellerPayout            money.Money            `json:"seller_payout" db:"seller_payout"`
+	PlatformRevenue         money.Money            `json:"platform_revenue" db:"platform_revenue"`
 	FeeConfigSnapshot       map[string]interface{} `json:"fee_config_snapshot" db:"fee_config_snapshot"`
+	ReversedAt              *time.Time             `json:"reversed_at,omitempty" db:"reversed_at"` // order refunded or canceled
 	CreatedAt               time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt               time.Time              `json:"updated_at" db:"updated_at"`
 }
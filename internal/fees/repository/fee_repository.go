@@ -8,6 +8,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/fees/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 type FeeRepository struct {
@@ -141,7 +142,7 @@ func (r *FeeRepository) GetTransactionFeeByMatchID(ctx context.Context, matchID
 			id, match_id, order_id, vertical, sale_price,
 			buyer_processing_fee, buyer_shipping_fee, buyer_total,
 			seller_transaction_fee, seller_authentication_fee, seller_shipping_cost, seller_payout,
-			platform_revenue, fee_config_snapshot, created_at, updated_at
+			platform_revenue, fee_config_snapshot, reversed_at, created_at, updated_at
 		FROM transaction_fees
 		WHERE match_id = $1
 	`
@@ -164,6 +165,7 @@ func (r *FeeRepository) GetTransactionFeeByMatchID(ctx context.Context, matchID
 		&fee.SellerPayout,
 		&fee.PlatformRevenue,
 		&snapshotJSON,
+		&fee.ReversedAt,
 		&fee.CreatedAt,
 		&fee.UpdatedAt,
 	)
@@ -182,29 +184,47 @@ func (r *FeeRepository) GetTransactionFeeByMatchID(ctx context.Context, matchID
 	return &fee, nil
 }
 
+// ReverseTransactionFee marks the fee recorded for a match reversed, so it
+// no longer counts as revenue. It reports false when there is no fee or it
+// was already reversed.
+func (r *FeeRepository) ReverseTransactionFee(ctx context.Context, matchID int64, reason string) (bool, error) {
+	query := `
+		UPDATE transaction_fees
+		SET reversed_at = NOW(), reversal_reason = $2, updated_at = NOW()
+		WHERE match_id = $1 AND reversed_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, matchID, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to reverse transaction fee for match %d: %w", matchID, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
 // GetTotalRevenue calculates total platform revenue for a date range
-func (r *FeeRepository) GetTotalRevenue(ctx context.Context, startDate, endDate time.Time) (float64, error) {
+func (r *FeeRepository) GetTotalRevenue(ctx context.Context, startDate, endDate time.Time) (money.Money, error) {
 	query := `
 		SELECT COALESCE(SUM(platform_revenue), 0)
 		FROM transaction_fees
-		WHERE created_at >= $1 AND created_at < $2
+		WHERE created_at >= $1 AND created_at < $2 AND reversed_at IS NULL
 	`
 
-	var total float64
+	var total money.Money
 	err := r.db.QueryRow(ctx, query, startDate, endDate).Scan(&total)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get total revenue: %w", err)
+		return money.Money{}, fmt.Errorf("failed to get total revenue: %w", err)
 	}
 
 	return total, nil
 }
 
 // GetRevenueByVertical calculates revenue per vertical for a date range
-func (r *FeeRepository) GetRevenueByVertical(ctx context.Context, startDate, endDate time.Time) (map[string]float64, error) {
+func (r *FeeRepository) GetRevenueByVertical(ctx context.Context, startDate, endDate time.Time) (map[string]money.Money, error) {
 	query := `
 		SELECT vertical, COALESCE(SUM(platform_revenue), 0) as revenue
 		FROM transaction_fees
-		WHERE created_at >= $1 AND created_at < $2
+		WHERE created_at >= $1 AND created_at < $2 AND reversed_at IS NULL
 		GROUP BY vertical
 	`
 
@@ -214,10 +234,10 @@ func (r *FeeRepository) GetRevenueByVertical(ctx context.Context, startDate, end
 	}
 	defer rows.Close()
 
-	revenueMap := make(map[string]float64)
+	revenueMap := make(map[string]money.Money)
 	for rows.Next() {
 		var vertical string
-		var revenue float64
+		var revenue money.Money
 		if err := rows.Scan(&vertical, &revenue); err != nil {
 			return nil, fmt.Errorf("failed to scan revenue row: %w", err)
 		}
@@ -232,7 +252,7 @@ func (r *FeeRepository) GetTransactionCount(ctx context.Context, startDate, endD
 	query := `
 		SELECT COUNT(*)
 		FROM transaction_fees
-		WHERE created_at >= $1 AND created_at < $2
+		WHERE created_at >= $1 AND created_at < $2 AND reversed_at IS NULL
 	`
 
 	var count int64
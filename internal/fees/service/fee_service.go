@@ -3,11 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
-	"math"
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/fees/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/fees/repository"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 type FeeService struct {
@@ -24,10 +24,10 @@ func NewFeeService(repo *repository.FeeRepository, log *logger.Logger) *FeeServi
 
 // CalculateFees calculates all fees for a transaction
 // Returns detailed fee breakdown for buyer and seller
-func (s *FeeService) CalculateFees(ctx context.Context, vertical string, salePrice float64, includeAuth bool) (*model.FeeBreakdown, error) {
+func (s *FeeService) CalculateFees(ctx context.Context, vertical string, salePrice money.Money, includeAuth bool) (*model.FeeBreakdown, error) {
 	// Validate inputs
-	if salePrice <= 0 {
-		return nil, fmt.Errorf("sale price must be positive, got: %.2f", salePrice)
+	if !salePrice.IsPositive() {
+		return nil, fmt.Errorf("sale price must be positive, got: %s", salePrice)
 	}
 
 	if vertical == "" {
@@ -44,26 +44,20 @@ func (s *FeeService) CalculateFees(ctx context.Context, vertical string, salePri
 	breakdown := model.NewFeeBreakdown(salePrice)
 
 	// NEW MODEL: Buyer pays transaction fee, Seller receives full price
-	transactionFee := config.CalculateTransactionFee(salePrice)
+	// Buyer fees (transaction fee instead of seller); no shipping fees
+	breakdown.BuyerProcessingFee = config.CalculateTransactionFee(salePrice)
 
-	// Buyer fees (transaction fee instead of seller)
-	breakdown.BuyerProcessingFee = s.roundToTwoDecimals(transactionFee)
-	breakdown.BuyerShippingFee = 0.0 // No shipping fees
-
-	// Seller fees (zero - seller receives full price)
-	breakdown.SellerTransactionFee = 0.0
-	breakdown.SellerAuthFee = 0.0
-	breakdown.SellerShippingCost = 0.0
+	// Seller fees stay zero - seller receives full price
 
 	// Calculate totals
 	breakdown.CalculateTotals()
 
-	s.log.Infof("Fee calculation for %s @ $%.2f: Platform Revenue = $%.2f (Buyer: $%.2f, Seller pays: $%.2f)",
+	s.log.Infof("Fee calculation for %s @ $%s: Platform Revenue = $%s (Buyer: $%s, Seller pays: $%s)",
 		vertical,
 		salePrice,
 		breakdown.PlatformRevenue,
 		breakdown.BuyerTotal,
-		breakdown.SellerTransactionFee+breakdown.SellerAuthFee+breakdown.SellerShippingCost,
+		money.Sum(breakdown.SellerTransactionFee, breakdown.SellerAuthFee, breakdown.SellerShippingCost),
 	)
 
 	return breakdown, nil
@@ -87,7 +81,7 @@ func (s *FeeService) RecordTransactionFee(ctx context.Context, matchID int64, or
 		return fmt.Errorf("failed to record transaction fee: %w", err)
 	}
 
-	s.log.Infof("Transaction fee recorded: MatchID=%d, Revenue=$%.2f", matchID, breakdown.PlatformRevenue)
+	s.log.Infof("Transaction fee recorded: MatchID=%d, Revenue=$%s", matchID, breakdown.PlatformRevenue)
 
 	return nil
 }
@@ -110,8 +104,3 @@ func (s *FeeService) GetFeeConfig(ctx context.Context, vertical string) (*model.
 func (s *FeeService) GetAllFeeConfigs(ctx context.Context) ([]*model.FeeConfig, error) {
 	return s.repo.GetAllFeeConfigs(ctx)
 }
-
-// roundToTwoDecimals rounds a float to 2 decimal places
-func (s *FeeService) roundToTwoDecimals(value float64) float64 {
-	return math.Round(value*100) / 100
-}
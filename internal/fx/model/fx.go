@@ -0,0 +1,109 @@
+package model
+
+import (
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// ExchangeRate is one published rate: one unit of Base buys Rate units of Quote
+type ExchangeRate struct {
+	ID        int64      `json:"id"`
+	Base      string     `json:"base_currency"`
+	Quote     string     `json:"quote_currency"`
+	Rate      money.Rate `json:"rate"`
+	Source    string     `json:"source"`
+	AsOf      time.Time  `json:"as_of"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Quote is the rate used to convert From into To. It is a stored rate, its
+// inverse, or a cross rate through a shared base; RateIDs lists the
+// fx_rates rows it came from (none between a currency and itself).
+type Quote struct {
+	From    string     `json:"from"`
+	To      string     `json:"to"`
+	Rate    money.Rate `json:"rate"`
+	RateIDs []int64    `json:"rate_ids"`
+	AsOf    time.Time  `json:"as_of"`
+}
+
+// Snapshot rebuilds a quote from a rate stored on a record, so later
+// conversions for that record use the rate it was priced at
+func Snapshot(from, to string, rate money.Rate) *Quote {
+	if rate.IsZero() {
+		rate = money.OneRate()
+	}
+	return &Quote{From: from, To: to, Rate: rate}
+}
+
+// Inverse returns the quote converting To back into From
+func (q *Quote) Inverse() *Quote {
+	if q.Identity() {
+		return &Quote{From: q.To, To: q.From, Rate: q.Rate, AsOf: q.AsOf}
+	}
+	return &Quote{From: q.To, To: q.From, Rate: q.Rate.Inverse(), RateIDs: q.RateIDs, AsOf: q.AsOf}
+}
+
+// Identity reports whether the quote converts a currency to itself
+func (q *Quote) Identity() bool {
+	return q.From == q.To
+}
+
+// Apply converts amount, which must be in q.From, to q.To
+func (q *Quote) Apply(amount money.Money) money.Money {
+	if q.Identity() {
+		return amount
+	}
+	return amount.Exchange(q.Rate, q.To, money.HalfUp)
+}
+
+// Conversion records one conversion and what it priced
+type Conversion struct {
+	ID            int64       `json:"id"`
+	Purpose       string      `json:"purpose"`
+	ReferenceType string      `json:"reference_type"`
+	ReferenceID   int64       `json:"reference_id"`
+	From          money.Money `json:"from"`
+	To            money.Money `json:"to"`
+	Rate          money.Rate  `json:"rate"`
+	RateIDs       []int64     `json:"rate_ids"`
+	RateAsOf      *time.Time  `json:"rate_as_of,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// NewConversion describes the conversion of from at q
+func NewConversion(q *Quote, from money.Money, purpose, referenceType string, referenceID int64) *Conversion {
+	c := &Conversion{
+		Purpose:       purpose,
+		ReferenceType: referenceType,
+		ReferenceID:   referenceID,
+		From:          from,
+		To:            q.Apply(from),
+		Rate:          q.Rate,
+		RateIDs:       q.RateIDs,
+	}
+	if !q.AsOf.IsZero() {
+		asOf := q.AsOf
+		c.RateAsOf = &asOf
+	}
+	return c
+}
+
+// Conversion purposes
+const (
+	PurposeBidPrice      = "bid_price"      // bid currency -> settlement
+	PurposeAskPrice      = "ask_price"      // ask currency -> settlement
+	PurposeBidCapture    = "bid_capture"    // settlement -> bid currency
+	PurposeCheckout      = "checkout"       // settlement -> buyer currency
+	PurposeDisputeRefund = "dispute_refund" // settlement -> buyer currency
+)
+
+// Records a conversion can reference
+const (
+	ReferenceBid     = "bid"
+	ReferenceAsk     = "ask"
+	ReferenceMatch   = "match"
+	ReferenceOrder   = "order"
+	ReferenceDispute = "dispute"
+)
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// FileProvider reads rates from a JSON file, re-read on every fetch so an
+// updated file is picked up on the next sync:
+//
+//	{"base": "USD", "as_of": "2026-10-18T00:00:00Z", "rates": {"EUR": "0.92", "JPY": "149.5"}}
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider creates a provider reading path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+type ratesFile struct {
+	Base  string                `json:"base"`
+	AsOf  time.Time             `json:"as_of"`
+	Rates map[string]money.Rate `json:"rates"`
+}
+
+func (p *FileProvider) Name() string { return "file" }
+
+// FetchRates reads the file's rates. A file without as_of is dated by its
+// modification time.
+func (p *FileProvider) FetchRates(ctx context.Context) ([]*model.ExchangeRate, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file %s: %w", p.path, err)
+	}
+	if file.Base == "" {
+		return nil, fmt.Errorf("rates file %s has no base currency", p.path)
+	}
+
+	asOf := file.AsOf
+	if asOf.IsZero() {
+		info, err := os.Stat(p.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat rates file: %w", err)
+		}
+		asOf = info.ModTime()
+	}
+
+	for currency, rate := range file.Rates {
+		if rate.IsZero() {
+			return nil, fmt.Errorf("rates file %s: missing rate for %s", p.path, currency)
+		}
+	}
+
+	return ratesFrom(file.Base, file.Rates, p.Name(), asOf.UTC()), nil
+}
@@ -0,0 +1,86 @@
+// Package provider loads exchange rates from wherever they are published:
+// a rates file or rates fixed in configuration.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// RateProvider supplies the latest exchange rates
+type RateProvider interface {
+	// Name identifies the provider ("file", "static"); stored as the rate source
+	Name() string
+
+	// FetchRates returns the current rates. Rates need not cover every
+	// pair; missing pairs are derived through a shared base.
+	FetchRates(ctx context.Context) ([]*model.ExchangeRate, error)
+}
+
+// New builds the provider selected by cfg.RateProvider
+func New(cfg config.FXConfig) (RateProvider, error) {
+	switch cfg.RateProvider {
+	case "file":
+		if cfg.RatesFile == "" {
+			return nil, fmt.Errorf("FX_RATES_FILE is required for the file rate provider")
+		}
+		return NewFileProvider(cfg.RatesFile), nil
+	case "static", "":
+		rates, err := ParseRates(cfg.StaticRates)
+		if err != nil {
+			return nil, err
+		}
+		return NewStaticProvider(cfg.SettlementCurrency, rates), nil
+	default:
+		return nil, fmt.Errorf("unknown rate provider %q", cfg.RateProvider)
+	}
+}
+
+// ParseRates reads "EUR=0.92,GBP=0.79" into quote currency -> rate
+func ParseRates(spec string) (map[string]money.Rate, error) {
+	rates := make(map[string]money.Rate)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		currency, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate %q: want CURRENCY=RATE", part)
+		}
+		rate, err := money.ParseRate(value)
+		if err != nil {
+			return nil, err
+		}
+		rates[strings.ToUpper(strings.TrimSpace(currency))] = rate
+	}
+
+	return rates, nil
+}
+
+// ratesFrom builds one ExchangeRate per quote currency against base
+func ratesFrom(base string, rates map[string]money.Rate, source string, asOf time.Time) []*model.ExchangeRate {
+	base = strings.ToUpper(base)
+	out := make([]*model.ExchangeRate, 0, len(rates))
+	for quote, rate := range rates {
+		quote = strings.ToUpper(quote)
+		if quote == base {
+			continue
+		}
+		out = append(out, &model.ExchangeRate{
+			Base:   base,
+			Quote:  quote,
+			Rate:   rate,
+			Source: source,
+			AsOf:   asOf,
+		})
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// StaticProvider serves rates fixed at startup (FX_STATIC_RATES). With no
+// rates the marketplace only accepts its settlement currency.
+type StaticProvider struct {
+	base  string
+	rates map[string]money.Rate
+}
+
+// NewStaticProvider creates a provider quoting rates against base
+func NewStaticProvider(base string, rates map[string]money.Rate) *StaticProvider {
+	return &StaticProvider{base: base, rates: rates}
+}
+
+func (p *StaticProvider) Name() string { return "static" }
+
+// FetchRates returns the configured rates, dated now
+func (p *StaticProvider) FetchRates(ctx context.Context) ([]*model.ExchangeRate, error) {
+	return ratesFrom(p.base, p.rates, p.Name(), time.Now().UTC()), nil
+}
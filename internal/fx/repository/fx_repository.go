@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+)
+
+type FXRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFXRepository(db *pgxpool.Pool) *FXRepository {
+	return &FXRepository{db: db}
+}
+
+// SaveRates stores fetched rates. A rate equal to the latest stored one for
+// its pair only moves that row's as_of forward, so unchanged rates do not
+// pile up rows.
+func (r *FXRepository) SaveRates(ctx context.Context, rates []*model.ExchangeRate) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	inserted := 0
+	for _, rate := range rates {
+		latest, err := latestRate(ctx, tx, rate.Base, rate.Quote)
+		if err != nil {
+			return 0, err
+		}
+
+		if latest != nil && latest.Rate.Equal(rate.Rate) {
+			_, err := tx.Exec(ctx, `UPDATE fx_rates SET as_of = GREATEST(as_of, $1) WHERE id = $2`, rate.AsOf, latest.ID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to refresh rate %s/%s: %w", rate.Base, rate.Quote, err)
+			}
+			rate.ID, rate.CreatedAt = latest.ID, latest.CreatedAt
+			continue
+		}
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO fx_rates (base_currency, quote_currency, rate, source, as_of)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`, rate.Base, rate.Quote, rate.Rate, rate.Source, rate.AsOf).Scan(&rate.ID, &rate.CreatedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save rate %s/%s: %w", rate.Base, rate.Quote, err)
+		}
+		inserted++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return inserted, nil
+}
+
+// GetLatestRate returns the newest rate quoting base in quote, or nil
+func (r *FXRepository) GetLatestRate(ctx context.Context, base, quote string) (*model.ExchangeRate, error) {
+	return latestRate(ctx, r.db, base, quote)
+}
+
+// rowQuerier is implemented by both the pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func latestRate(ctx context.Context, q rowQuerier, base, quote string) (*model.ExchangeRate, error) {
+	query := `
+		SELECT id, base_currency, quote_currency, rate, source, as_of, created_at
+		FROM fx_rates
+		WHERE base_currency = $1 AND quote_currency = $2
+		ORDER BY as_of DESC, id DESC
+		LIMIT 1
+	`
+
+	rate := &model.ExchangeRate{}
+	err := q.QueryRow(ctx, query, base, quote).Scan(
+		&rate.ID, &rate.Base, &rate.Quote, &rate.Rate, &rate.Source, &rate.AsOf, &rate.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate %s/%s: %w", base, quote, err)
+	}
+
+	return rate, nil
+}
+
+// GetBaseCurrencies lists the currencies rates are quoted against; cross
+// rates are derived through them
+func (r *FXRepository) GetBaseCurrencies(ctx context.Context) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT DISTINCT base_currency FROM fx_rates ORDER BY base_currency`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base currencies: %w", err)
+	}
+	defer rows.Close()
+
+	var bases []string
+	for rows.Next() {
+		var base string
+		if err := rows.Scan(&base); err != nil {
+			return nil, fmt.Errorf("failed to scan base currency: %w", err)
+		}
+		bases = append(bases, base)
+	}
+
+	return bases, rows.Err()
+}
+
+// RecordConversion stores a conversion in the audit log
+func (r *FXRepository) RecordConversion(ctx context.Context, c *model.Conversion) error {
+	query := `
+		INSERT INTO fx_conversions (
+			purpose, reference_type, reference_id,
+			from_currency, from_amount, to_currency, to_amount,
+			rate, rate_ids, rate_as_of
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+
+	rateIDs := c.RateIDs
+	if rateIDs == nil {
+		rateIDs = []int64{}
+	}
+
+	err := r.db.QueryRow(ctx, query,
+		c.Purpose, c.ReferenceType, c.ReferenceID,
+		c.From.Currency, c.From, c.To.Currency, c.To,
+		c.Rate, rateIDs, c.RateAsOf,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record conversion: %w", err)
+	}
+
+	return nil
+}
+
+// GetConversions returns the conversions recorded for a record, oldest first
+func (r *FXRepository) GetConversions(ctx context.Context, referenceType string, referenceID int64) ([]*model.Conversion, error) {
+	query := `
+		SELECT id, purpose, reference_type, reference_id,
+		       from_currency, from_amount, to_currency, to_amount,
+		       rate, rate_ids, rate_as_of, created_at
+		FROM fx_conversions
+		WHERE reference_type = $1 AND reference_id = $2
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, referenceType, referenceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversions: %w", err)
+	}
+	defer rows.Close()
+
+	conversions := make([]*model.Conversion, 0)
+	for rows.Next() {
+		c := &model.Conversion{}
+		var fromCurrency, toCurrency string
+		err := rows.Scan(
+			&c.ID, &c.Purpose, &c.ReferenceType, &c.ReferenceID,
+			&fromCurrency, &c.From, &toCurrency, &c.To,
+			&c.Rate, &c.RateIDs, &c.RateAsOf, &c.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversion: %w", err)
+		}
+		c.From = c.From.WithCurrency(fromCurrency)
+		c.To = c.To.WithCurrency(toCurrency)
+		conversions = append(conversions, c)
+	}
+
+	return conversions, rows.Err()
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/fx/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/fx/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// FXService quotes exchange rates from the fx_rates table, keeps it in sync
+// with the rate provider and records every conversion it prices
+type FXService struct {
+	repo       *repository.FXRepository
+	provider   provider.RateProvider
+	settlement string
+	maxRateAge time.Duration
+	log        *logger.Logger
+}
+
+func NewFXService(repo *repository.FXRepository, rateProvider provider.RateProvider, cfg config.FXConfig, log *logger.Logger) *FXService {
+	return &FXService{
+		repo:       repo,
+		provider:   rateProvider,
+		settlement: strings.ToUpper(cfg.SettlementCurrency),
+		maxRateAge: cfg.MaxRateAge,
+		log:        log,
+	}
+}
+
+// SettlementCurrency is the currency fees, orders and payouts are computed in
+func (s *FXService) SettlementCurrency() string {
+	return s.settlement
+}
+
+// SyncRates loads the provider's current rates into fx_rates
+func (s *FXService) SyncRates(ctx context.Context) (int, error) {
+	rates, err := s.provider.FetchRates(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch rates from %s: %w", s.provider.Name(), err)
+	}
+
+	return s.repo.SaveRates(ctx, rates)
+}
+
+// StartRateSync runs SyncRates on every tick until ctx is canceled
+func (s *FXService) StartRateSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.SyncRates(ctx)
+			if err != nil {
+				s.log.Errorf("Rate sync failed: %v", err)
+				continue
+			}
+			if changed > 0 {
+				s.log.Infof("Rate sync stored %d changed rates", changed)
+			}
+		}
+	}
+}
+
+// Quote returns the latest rate converting from into to. Pairs without a
+// stored rate use its inverse, or a cross rate through the settlement
+// currency or another base the provider quotes against.
+func (s *FXService) Quote(ctx context.Context, from, to string) (*model.Quote, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return &model.Quote{From: from, To: to, Rate: money.OneRate()}, nil
+	}
+
+	quote, err := s.leg(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if quote == nil {
+		bases, err := s.repo.GetBaseCurrencies(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pivot := range append([]string{s.settlement}, bases...) {
+			if pivot == from || pivot == to {
+				continue
+			}
+			quote, err = s.cross(ctx, from, pivot, to)
+			if err != nil {
+				return nil, err
+			}
+			if quote != nil {
+				break
+			}
+		}
+	}
+
+	if quote == nil {
+		return nil, fmt.Errorf("no exchange rate for %s/%s", from, to)
+	}
+	if s.maxRateAge > 0 && time.Since(quote.AsOf) > s.maxRateAge {
+		return nil, fmt.Errorf("exchange rate for %s/%s is stale (as of %s)", from, to, quote.AsOf.Format(time.RFC3339))
+	}
+
+	return quote, nil
+}
+
+// leg quotes a pair from its stored rate or the inverse of the opposite pair
+func (s *FXService) leg(ctx context.Context, from, to string) (*model.Quote, error) {
+	rate, err := s.repo.GetLatestRate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if rate != nil {
+		return &model.Quote{From: from, To: to, Rate: rate.Rate, RateIDs: []int64{rate.ID}, AsOf: rate.AsOf}, nil
+	}
+
+	rate, err = s.repo.GetLatestRate(ctx, to, from)
+	if err != nil {
+		return nil, err
+	}
+	if rate != nil {
+		return &model.Quote{From: from, To: to, Rate: rate.Rate.Inverse(), RateIDs: []int64{rate.ID}, AsOf: rate.AsOf}, nil
+	}
+
+	return nil, nil
+}
+
+// cross chains from->pivot and pivot->to, dated by the older of the two
+func (s *FXService) cross(ctx context.Context, from, pivot, to string) (*model.Quote, error) {
+	first, err := s.leg(ctx, from, pivot)
+	if err != nil || first == nil {
+		return nil, err
+	}
+	second, err := s.leg(ctx, pivot, to)
+	if err != nil || second == nil {
+		return nil, err
+	}
+
+	asOf := first.AsOf
+	if second.AsOf.Before(asOf) {
+		asOf = second.AsOf
+	}
+
+	return &model.Quote{
+		From:    from,
+		To:      to,
+		Rate:    first.Rate.Mul(second.Rate),
+		RateIDs: append(append([]int64{}, first.RateIDs...), second.RateIDs...),
+		AsOf:    asOf,
+	}, nil
+}
+
+// ToSettlement converts amount into the settlement currency at the latest rate
+func (s *FXService) ToSettlement(ctx context.Context, amount money.Money) (money.Money, *model.Quote, error) {
+	quote, err := s.Quote(ctx, amount.Currency, s.settlement)
+	if err != nil {
+		return money.Money{}, nil, err
+	}
+
+	return quote.Apply(amount), quote, nil
+}
+
+// Record stores the conversion of from at quote in the audit log. A
+// currency converted to itself is not recorded.
+func (s *FXService) Record(ctx context.Context, quote *model.Quote, from money.Money, purpose, referenceType string, referenceID int64) (*model.Conversion, error) {
+	if quote.Identity() {
+		return nil, nil
+	}
+
+	conversion := model.NewConversion(quote, from, purpose, referenceType, referenceID)
+	if err := s.repo.RecordConversion(ctx, conversion); err != nil {
+		return nil, err
+	}
+
+	return conversion, nil
+}
+
+// RecordBestEffort records a conversion whose priced record already
+// exists, so a failure is logged rather than returned. It does nothing on a
+// nil service, for callers running without FX.
+func (s *FXService) RecordBestEffort(ctx context.Context, quote *model.Quote, from money.Money, purpose, referenceType string, referenceID int64) {
+	if s == nil {
+		return
+	}
+	if _, err := s.Record(ctx, quote, from, purpose, referenceType, referenceID); err != nil {
+		s.log.Errorf("Failed to record %s conversion for %s %d: %v", purpose, referenceType, referenceID, err)
+	}
+}
+
+// GetConversions returns the audit log of conversions for a record
+func (s *FXService) GetConversions(ctx context.Context, referenceType string, referenceID int64) ([]*model.Conversion, error) {
+	return s.repo.GetConversions(ctx, referenceType, referenceID)
+}
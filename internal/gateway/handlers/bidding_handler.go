@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	biddingPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/bidding"
 )
 
@@ -28,11 +31,14 @@ func NewBiddingHandler(client biddingPb.BiddingServiceClient) *BiddingHandler {
 func (h *BiddingHandler) PlaceBid(c *gin.Context) {
 	// Parse JSON body manually to handle camelCase
 	var body struct {
-		ProductID      int64   `json:"productId"`
-		SizeID         int64   `json:"sizeId"`
-		Price          float64 `json:"price"`
-		Quantity       int32   `json:"quantity"`
-		ExpiresInHours int32   `json:"expiresInHours"`
+		ProductID        int64       `json:"productId"`
+		SizeID           int64       `json:"sizeId"`
+		Price            json.Number `json:"price"`
+		Currency         string      `json:"currency"`
+		Quantity         int32       `json:"quantity"`
+		ExpiresInHours   int32       `json:"expiresInHours"`
+		PaymentMethodID  string      `json:"paymentMethodId"`
+		StripeCustomerID string      `json:"stripeCustomerId"`
 	}
 
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -40,10 +46,15 @@ func (h *BiddingHandler) PlaceBid(c *gin.Context) {
 		return
 	}
 
+	price, err := listingPrice(body.Price, body.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// DEBUG: Log received data (visible in response headers)
 	c.Writer.Header().Set("X-Debug-ProductID", strconv.FormatInt(body.ProductID, 10))
 	c.Writer.Header().Set("X-Debug-SizeID", strconv.FormatInt(body.SizeID, 10))
-	println("🔍 API Gateway PlaceBid: productId=", body.ProductID, "sizeId=", body.SizeID, "price=", body.Price)
 
 	// Extract user_id from JWT claims (set by AuthMiddleware)
 	userID, exists := c.Get("user_id")
@@ -68,12 +79,16 @@ func (h *BiddingHandler) PlaceBid(c *gin.Context) {
 
 	// Build gRPC request with snake_case fields
 	req := &biddingPb.PlaceBidRequest{
-		UserId:         userIDInt64,
-		ProductId:      body.ProductID,
-		SizeId:         body.SizeID,
-		Price:          body.Price,
-		Quantity:       body.Quantity,
-		ExpiresInHours: body.ExpiresInHours,
+		UserId:           userIDInt64,
+		ProductId:        body.ProductID,
+		SizeId:           body.SizeID,
+		Price:            price.Major(),
+		PriceMinor:       price.Amount,
+		Currency:         price.Currency,
+		Quantity:         body.Quantity,
+		ExpiresInHours:   body.ExpiresInHours,
+		PaymentMethodId:  body.PaymentMethodID,
+		StripeCustomerId: body.StripeCustomerID,
 	}
 
 	resp, err := h.client.PlaceBid(c.Request.Context(), req)
@@ -97,11 +112,12 @@ func (h *BiddingHandler) PlaceBid(c *gin.Context) {
 func (h *BiddingHandler) PlaceAsk(c *gin.Context) {
 	// Parse JSON body manually to handle camelCase
 	var body struct {
-		ProductID      int64   `json:"productId"`
-		SizeID         int64   `json:"sizeId"`
-		Price          float64 `json:"price"`
-		Quantity       int32   `json:"quantity"`
-		ExpiresInHours int32   `json:"expiresInHours"`
+		ProductID      int64       `json:"productId"`
+		SizeID         int64       `json:"sizeId"`
+		Price          json.Number `json:"price"`
+		Currency       string      `json:"currency"`
+		Quantity       int32       `json:"quantity"`
+		ExpiresInHours int32       `json:"expiresInHours"`
 	}
 
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -109,8 +125,11 @@ func (h *BiddingHandler) PlaceAsk(c *gin.Context) {
 		return
 	}
 
-	// DEBUG: Log received data
-	println("🔍 API Gateway PlaceAsk: productId=", body.ProductID, "sizeId=", body.SizeID, "price=", body.Price)
+	price, err := listingPrice(body.Price, body.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Extract user_id from JWT claims (set by AuthMiddleware)
 	userID, exists := c.Get("user_id")
@@ -138,7 +157,9 @@ func (h *BiddingHandler) PlaceAsk(c *gin.Context) {
 		UserId:         userIDInt64,
 		ProductId:      body.ProductID,
 		SizeId:         body.SizeID,
-		Price:          body.Price,
+		Price:          price.Major(),
+		PriceMinor:     price.Amount,
+		Currency:       price.Currency,
 		Quantity:       body.Quantity,
 		ExpiresInHours: body.ExpiresInHours,
 	}
@@ -158,6 +179,7 @@ func (h *BiddingHandler) PlaceAsk(c *gin.Context) {
 // @Produce json
 // @Param product_id path int true "Product ID"
 // @Param size_id path int true "Size ID"
+// @Param currency query string false "Display currency (default: settlement currency)"
 // @Success 200 {object} biddingPb.GetMarketPriceResponse
 // @Router /api/v1/market/{product_id}/{size_id} [get]
 func (h *BiddingHandler) GetMarketPrice(c *gin.Context) {
@@ -175,6 +197,7 @@ func (h *BiddingHandler) GetMarketPrice(c *gin.Context) {
 	resp, err := h.client.GetMarketPrice(c.Request.Context(), &biddingPb.GetMarketPriceRequest{
 		ProductId: productID,
 		SizeId:    sizeID,
+		Currency:  strings.ToUpper(c.Query("currency")),
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -259,3 +282,12 @@ func (h *BiddingHandler) GetProductAsks(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// listingPrice reads a bid or ask price in currency (empty: the settlement
+// currency)
+func listingPrice(price json.Number, currency string) (money.Money, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	return money.Parse(price.String(), currency)
+}
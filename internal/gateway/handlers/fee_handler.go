@@ -10,6 +10,7 @@ import (
 	"github.com/vvkuzmych/sneakers_marketplace/internal/fees/repository"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/fees/service"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 type FeeHandler struct {
@@ -39,8 +40,8 @@ func (h *FeeHandler) CalculateFees(c *gin.Context) {
 		return
 	}
 
-	price, err := strconv.ParseFloat(priceStr, 64)
-	if err != nil || price <= 0 {
+	price, err := money.Parse(priceStr, money.DefaultCurrency)
+	if err != nil || !price.IsPositive() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid price, must be a positive number"})
 		return
 	}
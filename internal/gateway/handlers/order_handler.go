@@ -1,19 +1,28 @@
 package handlers
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/gateway/websocket"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
 )
 
 type OrderHandler struct {
 	client orderPb.OrderServiceClient
+	hub    *websocket.Hub // delivers order messages in real time
 }
 
-func NewOrderHandler(client orderPb.OrderServiceClient) *OrderHandler {
-	return &OrderHandler{client: client}
+func NewOrderHandler(client orderPb.OrderServiceClient, hub *websocket.Hub) *OrderHandler {
+	return &OrderHandler{client: client, hub: hub}
 }
 
 // GetOrder godoc
@@ -68,3 +77,938 @@ func (h *OrderHandler) ListBuyerOrders(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// SearchOrders godoc
+// @Summary Search the current user's orders
+// @Description Filters combine with AND. Dates are YYYY-MM-DD (to is inclusive).
+// @Description Pass next_cursor back as cursor to get the following page.
+// @Tags orders
+// @Produce json
+// @Param role query string false "buyer or seller (default: both)"
+// @Param status query string false "Comma-separated statuses"
+// @Param from query string false "Created on or after (YYYY-MM-DD)"
+// @Param to query string false "Created on or before (YYYY-MM-DD)"
+// @Param product_id query int false "Product ID"
+// @Param sku query string false "Product SKU"
+// @Param size_id query int false "Size ID"
+// @Param size query string false "Size label, e.g. 10.5"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param counterparty_id query int false "Other party of the order"
+// @Param order_number query string false "Order number prefix"
+// @Param carrier query string false "Carrier"
+// @Param sort_by query string false "created_at, price or total_amount"
+// @Param sort_order query string false "desc or asc"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} orderPb.SearchOrdersResponse
+// @Security BearerAuth
+// @Router /api/v1/orders/search [get]
+func (h *OrderHandler) SearchOrders(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	req, err := searchOrdersRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.Query("role") {
+	case "buyer":
+		req.BuyerId = userID
+	case "seller":
+		req.SellerId = userID
+	case "":
+		req.UserId = userID
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be buyer or seller"})
+		return
+	}
+
+	// counterparty_id applies to the other side of the user's orders
+	if req.CounterpartyId != 0 {
+		switch {
+		case req.BuyerId != 0:
+			req.SellerId, req.CounterpartyId = req.CounterpartyId, 0
+		case req.SellerId != 0:
+			req.BuyerId, req.CounterpartyId = req.CounterpartyId, 0
+		}
+	}
+
+	resp, err := h.client.SearchOrders(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// searchOrdersRequest reads the search filters from the query string
+func searchOrdersRequest(c *gin.Context) (*orderPb.SearchOrdersRequest, error) {
+	req := &orderPb.SearchOrdersRequest{
+		Sku:               c.Query("sku"),
+		Size:              c.Query("size"),
+		OrderNumberPrefix: c.Query("order_number"),
+		Carrier:           c.Query("carrier"),
+		SortBy:            c.Query("sort_by"),
+		SortOrder:         c.Query("sort_order"),
+		Cursor:            c.Query("cursor"),
+	}
+
+	if status := c.Query("status"); status != "" {
+		req.Statuses = strings.Split(status, ",")
+	}
+
+	ints := map[string]*int64{
+		"product_id":      &req.ProductId,
+		"size_id":         &req.SizeId,
+		"counterparty_id": &req.CounterpartyId,
+	}
+	for name, dest := range ints {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*dest = n
+		}
+	}
+
+	prices := map[string]*int64{
+		"min_price": &req.MinPriceMinor,
+		"max_price": &req.MaxPriceMinor,
+	}
+	for name, dest := range prices {
+		if v := c.Query(name); v != "" {
+			m, err := money.Parse(v, money.DefaultCurrency)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*dest = m.Amount
+		}
+	}
+	req.MinPrice = money.New(req.MinPriceMinor, money.DefaultCurrency).Major()
+	req.MaxPrice = money.New(req.MaxPriceMinor, money.DefaultCurrency).Major()
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit")
+		}
+		req.Limit = int32(n)
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from format, use YYYY-MM-DD")
+		}
+		req.CreatedFrom = timestamppb.New(from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to format, use YYYY-MM-DD")
+		}
+		// Include the whole end day
+		req.CreatedTo = timestamppb.New(to.Add(24*time.Hour - time.Microsecond))
+	}
+
+	return req, nil
+}
+
+// GetShippingStatus godoc
+// @Summary Get shipping status and tracking timeline for an order
+// @Tags orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} orderPb.GetShippingStatusResponse
+// @Security BearerAuth
+// @Router /api/v1/orders/{id}/shipping [get]
+func (h *OrderHandler) GetShippingStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	resp, err := h.client.GetShippingStatus(c.Request.Context(), &orderPb.GetShippingStatusRequest{
+		OrderId: id,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetShippingRates godoc
+// @Summary Quote prepaid shipping labels for an order (seller)
+// @Tags orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} orderPb.GetShippingRatesResponse
+// @Security BearerAuth
+// @Router /api/v1/orders/{id}/shipping-rates [get]
+func (h *OrderHandler) GetShippingRates(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.GetShippingRates(c.Request.Context(), &orderPb.GetShippingRatesRequest{
+		OrderId:  id,
+		SellerId: sellerID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateShippingLabel godoc
+// @Summary Buy a prepaid shipping label and mark the order shipped (seller)
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} orderPb.CreateShippingLabelResponse
+// @Security BearerAuth
+// @Router /api/v1/orders/{id}/label [post]
+func (h *OrderHandler) CreateShippingLabel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	// Service is optional; the cheapest rate is used when omitted
+	var body struct {
+		Service string `json:"service"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.CreateShippingLabel(c.Request.Context(), &orderPb.CreateShippingLabelRequest{
+		OrderId:  id,
+		SellerId: sellerID,
+		Service:  body.Service,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// OpenDispute godoc
+// @Summary Open a dispute against a delivered order (buyer)
+// @Tags disputes
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} orderPb.OpenDisputeResponse
+// @Security BearerAuth
+// @Router /api/v1/orders/{id}/disputes [post]
+func (h *OrderHandler) OpenDispute(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	var body struct {
+		Reason       string   `json:"reason" binding:"required"`
+		Description  string   `json:"description" binding:"required"`
+		EvidenceURLs []string `json:"evidenceUrls"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	buyerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.OpenDispute(c.Request.Context(), &orderPb.OpenDisputeRequest{
+		OrderId:      id,
+		BuyerId:      buyerID,
+		Reason:       body.Reason,
+		Description:  body.Description,
+		EvidenceUrls: body.EvidenceURLs,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetDispute godoc
+// @Summary Get a dispute and its timeline
+// @Tags disputes
+// @Produce json
+// @Param id path int true "Dispute ID"
+// @Success 200 {object} orderPb.GetDisputeResponse
+// @Security BearerAuth
+// @Router /api/v1/disputes/{id} [get]
+func (h *OrderHandler) GetDispute(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dispute id"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.GetDispute(c.Request.Context(), &orderPb.GetDisputeRequest{
+		DisputeId: id,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		return
+	}
+
+	// Only the parties see the case here; admins use the admin tools
+	if resp.Dispute.BuyerId != userID && resp.Dispute.SellerId != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a party to this dispute"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RespondToDispute godoc
+// @Summary Respond to a dispute (seller)
+// @Tags disputes
+// @Accept json
+// @Produce json
+// @Param id path int true "Dispute ID"
+// @Success 200 {object} orderPb.RespondToDisputeResponse
+// @Security BearerAuth
+// @Router /api/v1/disputes/{id}/response [post]
+func (h *OrderHandler) RespondToDispute(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dispute id"})
+		return
+	}
+
+	var body struct {
+		Response     string   `json:"response" binding:"required"`
+		EvidenceURLs []string `json:"evidenceUrls"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.RespondToDispute(c.Request.Context(), &orderPb.RespondToDisputeRequest{
+		DisputeId:    id,
+		SellerId:     sellerID,
+		Response:     body.Response,
+		EvidenceUrls: body.EvidenceURLs,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ResolveDispute godoc
+// @Summary Decide a dispute (admin)
+// @Tags disputes
+// @Accept json
+// @Produce json
+// @Param id path int true "Dispute ID"
+// @Success 200 {object} orderPb.ResolveDisputeResponse
+// @Security BearerAuth
+// @Router /api/v1/disputes/{id}/resolve [post]
+func (h *OrderHandler) ResolveDispute(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dispute id"})
+		return
+	}
+
+	var body struct {
+		Outcome      string      `json:"outcome" binding:"required"`
+		RefundAmount money.Money `json:"refundAmount"`
+		Note         string      `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The Order Service checks that the caller is an admin
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.ResolveDispute(c.Request.Context(), &orderPb.ResolveDisputeRequest{
+		DisputeId:         id,
+		AdminId:           adminID,
+		Outcome:           body.Outcome,
+		RefundAmount:      body.RefundAmount.Major(),
+		RefundAmountMinor: body.RefundAmount.Amount,
+		Note:              body.Note,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetInvoice godoc
+// @Summary Download the buyer receipt or seller sale statement of an order
+// @Tags orders
+// @Produce application/pdf
+// @Produce html
+// @Param id path int true "Order ID"
+// @Param format query string false "pdf (default) or html"
+// @Success 200 {file} file
+// @Security BearerAuth
+// @Router /api/v1/orders/{id}/invoice [get]
+func (h *OrderHandler) GetInvoice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.GetInvoice(c.Request.Context(), &orderPb.GetInvoiceRequest{
+		OrderId: id,
+		UserId:  userID,
+		Format:  c.DefaultQuery("format", "pdf"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	disposition := "attachment"
+	if c.Query("inline") == "true" {
+		disposition = "inline"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, resp.Filename))
+	c.Data(http.StatusOK, resp.ContentType, resp.Content)
+}
+
+// RequestSalesExport godoc
+// @Summary Queue a sales export or yearly tax summary (seller)
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Success 202 {object} orderPb.RequestSalesExportResponse
+// @Security BearerAuth
+// @Router /api/v1/exports [post]
+func (h *OrderHandler) RequestSalesExport(c *gin.Context) {
+	var body struct {
+		Kind   string `json:"kind" binding:"required"` // sales, tax_summary
+		Format string `json:"format"`                  // csv (default), xlsx
+		Year   int32  `json:"year"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.RequestSalesExport(c.Request.Context(), &orderPb.RequestSalesExportRequest{
+		SellerId: sellerID,
+		Kind:     body.Kind,
+		Format:   body.Format,
+		Year:     body.Year,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// GetSalesExport godoc
+// @Summary Get the status of a sales export (seller)
+// @Tags exports
+// @Produce json
+// @Param id path int true "Export ID"
+// @Success 200 {object} orderPb.GetSalesExportResponse
+// @Security BearerAuth
+// @Router /api/v1/exports/{id} [get]
+func (h *OrderHandler) GetSalesExport(c *gin.Context) {
+	resp, ok := h.getSalesExport(c, false)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DownloadSalesExport godoc
+// @Summary Download a finished sales export (seller)
+// @Tags exports
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param id path int true "Export ID"
+// @Success 200 {file} file
+// @Security BearerAuth
+// @Router /api/v1/exports/{id}/download [get]
+func (h *OrderHandler) DownloadSalesExport(c *gin.Context) {
+	resp, ok := h.getSalesExport(c, true)
+	if !ok {
+		return
+	}
+
+	if resp.Job.Status != "ready" {
+		c.JSON(http.StatusConflict, gin.H{"error": "export is not ready", "status": resp.Job.Status})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, resp.Job.Filename))
+	c.Data(http.StatusOK, resp.ContentType, resp.Content)
+}
+
+func (h *OrderHandler) getSalesExport(c *gin.Context, includeContent bool) (*orderPb.GetSalesExportResponse, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid export id"})
+		return nil, false
+	}
+
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return nil, false
+	}
+
+	resp, err := h.client.GetSalesExport(c.Request.Context(), &orderPb.GetSalesExportRequest{
+		JobId:          id,
+		SellerId:       sellerID,
+		IncludeContent: includeContent,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return nil, false
+	}
+
+	return resp, true
+}
+
+// GetCart godoc
+// @Summary Get the current user's cart with current lowest asks
+// @Tags cart
+// @Produce json
+// @Param currency query string false "Display currency (default: settlement currency)"
+// @Success 200 {object} orderPb.GetCartResponse
+// @Security BearerAuth
+// @Router /api/v1/cart [get]
+func (h *OrderHandler) GetCart(c *gin.Context) {
+	buyerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.GetCart(c.Request.Context(), &orderPb.GetCartRequest{
+		BuyerId:  buyerID,
+		Currency: strings.ToUpper(c.Query("currency")),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// AddToCart godoc
+// @Summary Add a product size at its lowest ask to the cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Success 201 {object} orderPb.AddToCartResponse
+// @Security BearerAuth
+// @Router /api/v1/cart/items [post]
+func (h *OrderHandler) AddToCart(c *gin.Context) {
+	var body struct {
+		ProductID int64 `json:"product_id" binding:"required"`
+		SizeID    int64 `json:"size_id" binding:"required"`
+		Quantity  int32 `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	buyerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.AddToCart(c.Request.Context(), &orderPb.AddToCartRequest{
+		BuyerId:   buyerID,
+		ProductId: body.ProductID,
+		SizeId:    body.SizeID,
+		Quantity:  body.Quantity,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// RemoveFromCart godoc
+// @Summary Remove an item from the cart
+// @Tags cart
+// @Produce json
+// @Param id path int true "Cart item ID"
+// @Success 200 {object} orderPb.RemoveFromCartResponse
+// @Security BearerAuth
+// @Router /api/v1/cart/items/{id} [delete]
+func (h *OrderHandler) RemoveFromCart(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cart item id"})
+		return
+	}
+
+	buyerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.RemoveFromCart(c.Request.Context(), &orderPb.RemoveFromCartRequest{
+		BuyerId: buyerID,
+		ItemId:  id,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Checkout godoc
+// @Summary Buy every item in the cart with one payment
+// @Description Locks the lowest asks, creates one order per ask and returns
+// @Description the client secret of the combined PaymentIntent, charged in
+// @Description currency (default: settlement currency)
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Success 201 {object} orderPb.CheckoutResponse
+// @Security BearerAuth
+// @Router /api/v1/cart/checkout [post]
+func (h *OrderHandler) Checkout(c *gin.Context) {
+	// Body is optional; the buyer's default shipping address is used when omitted
+	var body struct {
+		ShippingAddressID int64  `json:"shipping_address_id"`
+		StripeCustomerID  string `json:"stripe_customer_id"`
+		Currency          string `json:"currency"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	buyerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.Checkout(c.Request.Context(), &orderPb.CheckoutRequest{
+		BuyerId:           buyerID,
+		ShippingAddressId: body.ShippingAddressID,
+		StripeCustomerId:  body.StripeCustomerID,
+		Currency:          strings.ToUpper(body.Currency),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetCheckout godoc
+// @Summary Get a checkout and its orders
+// @Tags cart
+// @Produce json
+// @Param id path int true "Checkout ID"
+// @Success 200 {object} orderPb.GetCheckoutResponse
+// @Security BearerAuth
+// @Router /api/v1/checkouts/{id} [get]
+func (h *OrderHandler) GetCheckout(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid checkout id"})
+		return
+	}
+
+	buyerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.GetCheckout(c.Request.Context(), &orderPb.GetCheckoutRequest{
+		CheckoutId: id,
+		BuyerId:    buyerID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SendOrderMessage godoc
+// @Summary Send a message to the other party of an order
+// @Description Phone numbers and emails are removed. Recipients connected to
+// @Description /ws receive an "order_message" event.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 201 {object} orderPb.SendOrderMessageResponse
+// @Security BearerAuth
+// @Router /api/v1/orders/{id}/messages [post]
+func (h *OrderHandler) SendOrderMessage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	var body struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	senderID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.SendOrderMessage(c.Request.Context(), &orderPb.SendOrderMessageRequest{
+		OrderId:  id,
+		SenderId: senderID,
+		Body:     body.Body,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	for _, userID := range resp.RecipientIds {
+		if err := h.hub.SendToUser(userID, websocket.Message{Type: "order_message", Data: resp.Message}); err != nil {
+			log.Printf("Failed to push order message %d to user %d: %v", resp.Message.Id, userID, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetOrderMessages godoc
+// @Summary Get the message thread of an order
+// @Tags orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param after_id query int false "Only messages newer than this ID"
+// @Success 200 {object} orderPb.GetOrderMessagesResponse
+// @Security BearerAuth
+// @Router /api/v1/orders/{id}/messages [get]
+func (h *OrderHandler) GetOrderMessages(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	afterID, _ := strconv.ParseInt(c.DefaultQuery("after_id", "0"), 10, 64)
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.GetOrderMessages(c.Request.Context(), &orderPb.GetOrderMessagesRequest{
+		OrderId: id,
+		UserId:  userID,
+		AfterId: afterID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// currentUserID extracts user_id from JWT claims (set by AuthMiddleware).
+// It writes the error response and returns false when the claim is missing.
+func currentUserID(c *gin.Context) (int64, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id not found in token"})
+		return 0, false
+	}
+
+	switch v := userID.(type) {
+	case float64:
+		return int64(v), true
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user_id type"})
+	return 0, false
+}
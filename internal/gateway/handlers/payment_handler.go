@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
@@ -22,6 +23,7 @@ func NewPaymentHandler(client paymentPb.PaymentServiceClient) *PaymentHandler {
 // @Accept json
 // @Produce json
 // @Param request body paymentPb.CreatePaymentIntentRequest true "Payment Intent Request"
+// @Param Idempotency-Key header string false "Replays the first response for retries with the same key"
 // @Success 200 {object} paymentPb.CreatePaymentIntentResponse
 // @Security BearerAuth
 // @Router /api/v1/payments/intent [post]
@@ -31,6 +33,9 @@ func (h *PaymentHandler) CreatePaymentIntent(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	}
 
 	resp, err := h.client.CreatePaymentIntent(c.Request.Context(), &req)
 	if err != nil {
@@ -76,3 +81,149 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// HandleStripeWebhook godoc
+// @Summary Stripe webhook
+// @Description Verified with the Stripe-Signature header. Redelivered events are acknowledged without being applied again.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Success 200 {object} paymentPb.HandleStripeWebhookResponse
+// @Router /api/v1/webhooks/stripe [post]
+func (h *PaymentHandler) HandleStripeWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+
+	resp, err := h.client.HandleStripeWebhook(c.Request.Context(), &paymentPb.HandleStripeWebhookRequest{
+		Payload:   payload,
+		Signature: c.GetHeader("Stripe-Signature"),
+	})
+	if err != nil {
+		// Stripe redelivers on non-2xx
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Rejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetWallet godoc
+// @Summary Get the seller's wallet balance, payout settings and latest transactions (seller)
+// @Tags payouts
+// @Produce json
+// @Param currency query string false "Wallet currency (default: settlement currency)"
+// @Success 200 {object} paymentPb.GetSellerWalletResponse
+// @Security BearerAuth
+// @Router /api/v1/payouts/wallet [get]
+func (h *PaymentHandler) GetWallet(c *gin.Context) {
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.GetSellerWallet(c.Request.Context(), &paymentPb.GetSellerWalletRequest{
+		SellerId: sellerID,
+		Currency: c.Query("currency"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdatePayoutSettings godoc
+// @Summary Change the payout schedule, minimum or connected account (seller)
+// @Tags payouts
+// @Accept json
+// @Produce json
+// @Success 200 {object} paymentPb.UpdatePayoutSettingsResponse
+// @Security BearerAuth
+// @Router /api/v1/payouts/wallet/settings [put]
+func (h *PaymentHandler) UpdatePayoutSettings(c *gin.Context) {
+	var body struct {
+		Currency           string `json:"currency"`
+		PayoutSchedule     string `json:"payout_schedule"` // daily, weekly, on_demand
+		MinimumPayoutMinor *int64 `json:"minimum_payout_minor"`
+		StripeAccountID    string `json:"stripe_account_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.UpdatePayoutSettings(c.Request.Context(), &paymentPb.UpdatePayoutSettingsRequest{
+		SellerId:           sellerID,
+		Currency:           body.Currency,
+		PayoutSchedule:     body.PayoutSchedule,
+		MinimumPayoutMinor: body.MinimumPayoutMinor,
+		StripeAccountId:    body.StripeAccountID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RequestPayout godoc
+// @Summary Pay out the wallet balance now (seller)
+// @Description The minimum payout applies whatever the schedule. The payout is paid once the transfer is confirmed.
+// @Tags payouts
+// @Produce json
+// @Param currency query string false "Wallet currency (default: settlement currency)"
+// @Success 200 {object} paymentPb.RequestPayoutResponse
+// @Security BearerAuth
+// @Router /api/v1/payouts/wallet/request [post]
+func (h *PaymentHandler) RequestPayout(c *gin.Context) {
+	sellerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.client.RequestPayout(c.Request.Context(), &paymentPb.RequestPayoutRequest{
+		SellerId: sellerID,
+		Currency: c.Query("currency"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/carrier"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
+)
+
+// TrackingWebhookHandler receives tracking updates pushed by carriers
+type TrackingWebhookHandler struct {
+	client orderPb.OrderServiceClient
+	secret string
+}
+
+func NewTrackingWebhookHandler(client orderPb.OrderServiceClient, secret string) *TrackingWebhookHandler {
+	return &TrackingWebhookHandler{client: client, secret: secret}
+}
+
+// HandleTrackingEvent godoc
+// @Summary Carrier tracking webhook
+// @Description Requires an X-Carrier-Signature header: t=<unix>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Success 200 {object} orderPb.RecordTrackingEventResponse
+// @Router /api/v1/webhooks/carrier/tracking [post]
+func (h *TrackingWebhookHandler) HandleTrackingEvent(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+
+	signature := c.GetHeader(carrier.SignatureHeader)
+	if err := carrier.VerifySignature(payload, signature, h.secret, carrier.DefaultSignatureTolerance, time.Now()); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature: " + err.Error()})
+		return
+	}
+
+	event, err := carrier.ParseWebhookEvent(payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.client.RecordTrackingEvent(c.Request.Context(), &orderPb.RecordTrackingEventRequest{
+		EventId:        event.EventID,
+		TrackingNumber: event.TrackingNumber,
+		Carrier:        event.Carrier,
+		Status:         event.Status,
+		Description:    event.Description,
+		Location:       event.Location,
+		OccurredAt:     timestamppb.New(event.OccurredAt),
+	})
+	if err != nil {
+		// Carriers retry on 5xx
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if resp.Error != "" {
+		// Unknown tracking numbers will not succeed on retry
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
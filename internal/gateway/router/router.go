@@ -10,11 +10,13 @@ import (
 	"github.com/vvkuzmych/sneakers_marketplace/internal/gateway/handlers"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/gateway/middleware"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/gateway/websocket"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
 	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
 )
 
-// SetupRouter configures all routes
-func SetupRouter(grpcClients *clients.GRPCClients, wsHub *websocket.Hub, db *pgxpool.Pool, log *logger.Logger) *gin.Engine {
+// SetupRouter configures all routes. Carrier webhooks are verified with
+// shipping.CarrierWebhookSecret.
+func SetupRouter(grpcClients *clients.GRPCClients, wsHub *websocket.Hub, db *pgxpool.Pool, shipping config.ShippingConfig, log *logger.Logger) *gin.Engine {
 	router := gin.Default()
 
 	// CORS middleware
@@ -39,8 +41,9 @@ func SetupRouter(grpcClients *clients.GRPCClients, wsHub *websocket.Hub, db *pgx
 	userHandler := handlers.NewUserHandler(grpcClients.UserClient)
 	productHandler := handlers.NewProductHandler(grpcClients.ProductClient)
 	biddingHandler := handlers.NewBiddingHandler(grpcClients.BiddingClient)
-	orderHandler := handlers.NewOrderHandler(grpcClients.OrderClient)
+	orderHandler := handlers.NewOrderHandler(grpcClients.OrderClient, wsHub)
 	paymentHandler := handlers.NewPaymentHandler(grpcClients.PaymentClient)
+	trackingWebhookHandler := handlers.NewTrackingWebhookHandler(grpcClients.OrderClient, shipping.CarrierWebhookSecret)
 
 	// Initialize fee handler (requires database connection)
 	feeRepo := feeRepository.NewFeeRepository(db)
@@ -91,8 +94,57 @@ func SetupRouter(grpcClients *clients.GRPCClients, wsHub *websocket.Hub, db *pgx
 		orders := v1.Group("/orders")
 		orders.Use(middleware.AuthMiddleware())
 		{
+			orders.GET("/search", orderHandler.SearchOrders)
 			orders.GET("/:id", orderHandler.GetOrder)
 			orders.GET("/buyer/:buyer_id", orderHandler.ListBuyerOrders)
+			orders.GET("/:id/shipping", orderHandler.GetShippingStatus)
+			orders.GET("/:id/shipping-rates", orderHandler.GetShippingRates)
+			orders.POST("/:id/label", orderHandler.CreateShippingLabel)
+			orders.POST("/:id/disputes", orderHandler.OpenDispute)
+			orders.GET("/:id/invoice", orderHandler.GetInvoice)
+			orders.GET("/:id/messages", orderHandler.GetOrderMessages)
+			orders.POST("/:id/messages", orderHandler.SendOrderMessage)
+		}
+
+		// Dispute routes (protected)
+		disputes := v1.Group("/disputes")
+		disputes.Use(middleware.AuthMiddleware())
+		{
+			disputes.GET("/:id", orderHandler.GetDispute)
+			disputes.POST("/:id/response", orderHandler.RespondToDispute)
+			disputes.POST("/:id/resolve", orderHandler.ResolveDispute)
+		}
+
+		// Seller exports (protected)
+		exports := v1.Group("/exports")
+		exports.Use(middleware.AuthMiddleware())
+		{
+			exports.POST("", orderHandler.RequestSalesExport)
+			exports.GET("/:id", orderHandler.GetSalesExport)
+			exports.GET("/:id/download", orderHandler.DownloadSalesExport)
+		}
+
+		// Cart and checkout (protected)
+		cart := v1.Group("/cart")
+		cart.Use(middleware.AuthMiddleware())
+		{
+			cart.GET("", orderHandler.GetCart)
+			cart.POST("/items", orderHandler.AddToCart)
+			cart.DELETE("/items/:id", orderHandler.RemoveFromCart)
+			cart.POST("/checkout", orderHandler.Checkout)
+		}
+
+		checkouts := v1.Group("/checkouts")
+		checkouts.Use(middleware.AuthMiddleware())
+		{
+			checkouts.GET("/:id", orderHandler.GetCheckout)
+		}
+
+		// Carrier and Stripe webhooks (public, signed)
+		webhooks := v1.Group("/webhooks")
+		{
+			webhooks.POST("/carrier/tracking", trackingWebhookHandler.HandleTrackingEvent)
+			webhooks.POST("/stripe", paymentHandler.HandleStripeWebhook)
 		}
 
 		// Payment routes (protected)
@@ -103,6 +155,15 @@ func SetupRouter(grpcClients *clients.GRPCClients, wsHub *websocket.Hub, db *pgx
 			payments.GET("/:id", paymentHandler.GetPayment)
 		}
 
+		// Seller wallet and payouts (protected)
+		payouts := v1.Group("/payouts")
+		payouts.Use(middleware.AuthMiddleware())
+		{
+			payouts.GET("/wallet", paymentHandler.GetWallet)
+			payouts.PUT("/wallet/settings", paymentHandler.UpdatePayoutSettings)
+			payouts.POST("/wallet/request", paymentHandler.RequestPayout)
+		}
+
 		// Fee routes (public for calculation, protected for revenue data)
 		fees := v1.Group("/fees")
 		{
@@ -0,0 +1,290 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Account types
+const (
+	AccountProcessorCash   = "processor_cash"   // asset: funds held by the payment processor
+	AccountBuyerFunds      = "buyer_funds"      // liability: buyer payments for sales not completed yet
+	AccountSellerPayable   = "seller_payable"   // liability: owed to a seller
+	AccountPlatformRevenue = "platform_revenue" // revenue: fees and subscriptions
+	AccountProcessorFees   = "processor_fees"   // expense: charged by the payment processor
+	AccountFXClearing      = "fx_clearing"      // converts buyer currencies into the settlement currency
+)
+
+// Line sides
+const (
+	SideDebit  = "debit"
+	SideCredit = "credit"
+)
+
+// Entry kinds
+const (
+	KindPaymentReceived      = "payment_received"
+	KindAuthorizationCapture = "authorization_captured"
+	KindProcessorFee         = "processor_fee"
+	KindRefund               = "refund"
+	KindSaleCompleted        = "sale_completed"
+	KindPayout               = "payout"
+	KindPayoutReversed       = "payout_reversed"
+	KindSubscriptionPayment  = "subscription_payment"
+	KindChargebackLost       = "chargeback_lost"
+	KindClawback             = "clawback"
+)
+
+// Records an entry can reference
+const (
+	ReferencePayment       = "payment"
+	ReferenceAuthorization = "authorization"
+	ReferenceOrder         = "order"
+	ReferencePayout        = "payout"
+	ReferenceSubscription  = "subscription_transaction"
+	ReferenceChargeback    = "chargeback"
+)
+
+// DebitNormal reports whether debits increase an account of accountType.
+// Assets and expenses are debit-normal; liabilities and revenue are
+// credit-normal.
+func DebitNormal(accountType string) bool {
+	switch accountType {
+	case AccountProcessorCash, AccountProcessorFees, AccountFXClearing:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidAccountType reports whether accountType is a ledger account type
+func ValidAccountType(accountType string) bool {
+	switch accountType {
+	case AccountProcessorCash, AccountBuyerFunds, AccountSellerPayable,
+		AccountPlatformRevenue, AccountProcessorFees, AccountFXClearing:
+		return true
+	default:
+		return false
+	}
+}
+
+// AccountRef names an account; its currency is that of the line posted to it
+type AccountRef struct {
+	Type    string `json:"type"`
+	OwnerID int64  `json:"owner_id"` // buyer or seller; 0 for platform accounts
+}
+
+// Platform accounts
+var (
+	ProcessorCash   = AccountRef{Type: AccountProcessorCash}
+	PlatformRevenue = AccountRef{Type: AccountPlatformRevenue}
+	ProcessorFees   = AccountRef{Type: AccountProcessorFees}
+	FXClearing      = AccountRef{Type: AccountFXClearing}
+)
+
+// BuyerFunds is the account holding a buyer's payments
+func BuyerFunds(buyerID int64) AccountRef {
+	return AccountRef{Type: AccountBuyerFunds, OwnerID: buyerID}
+}
+
+// SellerPayable is the account of what the platform owes a seller
+func SellerPayable(sellerID int64) AccountRef {
+	return AccountRef{Type: AccountSellerPayable, OwnerID: sellerID}
+}
+
+// Account is a ledger account in one currency. Balance is on the account's
+// normal side, so a positive balance is what the account type expects.
+type Account struct {
+	ID        int64       `json:"id"`
+	Type      string      `json:"type"`
+	OwnerID   int64       `json:"owner_id"`
+	Currency  string      `json:"currency"`
+	Balance   money.Money `json:"balance"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Line is one debit or credit of an entry
+type Line struct {
+	ID        int64       `json:"id"`
+	EntryID   int64       `json:"entry_id"`
+	AccountID int64       `json:"account_id"`
+	Account   AccountRef  `json:"account"`
+	Side      string      `json:"side"`
+	Amount    money.Money `json:"amount"`
+}
+
+// Signed returns the line's effect on its account's balance
+func (l *Line) Signed() money.Money {
+	if (l.Side == SideDebit) == DebitNormal(l.Account.Type) {
+		return l.Amount
+	}
+	return l.Amount.Neg()
+}
+
+// Entry is one money movement. Key identifies the movement, so posting the
+// same movement twice leaves a single entry.
+type Entry struct {
+	ID            int64     `json:"id"`
+	Key           string    `json:"key"`
+	Kind          string    `json:"kind"`
+	ReferenceType string    `json:"reference_type"`
+	ReferenceID   int64     `json:"reference_id"`
+	Description   string    `json:"description"`
+	Lines         []*Line   `json:"lines"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// NewEntry starts an entry for a movement of kind on a record
+func NewEntry(key, kind, referenceType string, referenceID int64, description string) *Entry {
+	return &Entry{
+		Key:           key,
+		Kind:          kind,
+		ReferenceType: referenceType,
+		ReferenceID:   referenceID,
+		Description:   description,
+	}
+}
+
+// Debit adds a debit line; zero amounts are skipped
+func (e *Entry) Debit(account AccountRef, amount money.Money) *Entry {
+	return e.add(account, SideDebit, amount)
+}
+
+// Credit adds a credit line; zero amounts are skipped
+func (e *Entry) Credit(account AccountRef, amount money.Money) *Entry {
+	return e.add(account, SideCredit, amount)
+}
+
+func (e *Entry) add(account AccountRef, side string, amount money.Money) *Entry {
+	if amount.IsZero() {
+		return e
+	}
+	if amount.IsNegative() {
+		// A negative debit is a credit
+		side = opposite(side)
+		amount = amount.Neg()
+	}
+	e.Lines = append(e.Lines, &Line{Account: account, Side: side, Amount: amount})
+	return e
+}
+
+func opposite(side string) string {
+	if side == SideDebit {
+		return SideCredit
+	}
+	return SideDebit
+}
+
+// Validate checks the entry can be posted: it has a key, at least one debit
+// and one credit, and its debits equal its credits in every currency
+func (e *Entry) Validate() error {
+	if e.Key == "" {
+		return fmt.Errorf("entry key is required")
+	}
+	if len(e.Lines) < 2 {
+		return fmt.Errorf("entry %s needs at least two lines", e.Key)
+	}
+
+	net := make(map[string]int64)
+	for _, line := range e.Lines {
+		if !ValidAccountType(line.Account.Type) {
+			return fmt.Errorf("entry %s: unknown account type %q", e.Key, line.Account.Type)
+		}
+		if !line.Amount.IsPositive() || line.Amount.Currency == "" {
+			return fmt.Errorf("entry %s: line amounts must be positive with a currency", e.Key)
+		}
+		if line.Side == SideDebit {
+			net[line.Amount.Currency] += line.Amount.Amount
+		} else {
+			net[line.Amount.Currency] -= line.Amount.Amount
+		}
+	}
+
+	for currency, diff := range net {
+		if diff != 0 {
+			return fmt.Errorf("entry %s is unbalanced in %s by %s", e.Key, currency, money.New(diff, currency))
+		}
+	}
+
+	return nil
+}
+
+// Sale is a completed order as the ledger sees it. The buyer's funds move to
+// the seller and the platform; all amounts are net of refunds already given.
+type Sale struct {
+	OrderID         int64
+	BuyerID         int64
+	SellerID        int64
+	BuyerPaid       money.Money // in the currency the buyer paid
+	Total           money.Money // BuyerPaid in the settlement currency
+	SellerPayable   money.Money
+	PlatformRevenue money.Money
+}
+
+// CurrencyTotals sums all posted debits and credits in one currency
+type CurrencyTotals struct {
+	Currency string      `json:"currency"`
+	Debits   money.Money `json:"debits"`
+	Credits  money.Money `json:"credits"`
+}
+
+// UnbalancedEntry is an entry whose debits and credits differ in a currency
+type UnbalancedEntry struct {
+	EntryID  int64       `json:"entry_id"`
+	Key      string      `json:"key"`
+	Currency string      `json:"currency"`
+	Debits   money.Money `json:"debits"`
+	Credits  money.Money `json:"credits"`
+}
+
+// BalanceDrift is an account whose stored balance differs from its lines
+type BalanceDrift struct {
+	AccountID int64       `json:"account_id"`
+	Type      string      `json:"type"`
+	OwnerID   int64       `json:"owner_id"`
+	Stored    money.Money `json:"stored"`
+	Computed  money.Money `json:"computed"`
+}
+
+// InvariantReport is the result of checking the ledger
+type InvariantReport struct {
+	Entries    int64              `json:"entries"`
+	Lines      int64              `json:"lines"`
+	Totals     []*CurrencyTotals  `json:"totals"`
+	Unbalanced []*UnbalancedEntry `json:"unbalanced"`
+	Drift      []*BalanceDrift    `json:"drift"`
+	CheckedAt  time.Time          `json:"checked_at"`
+}
+
+// OK reports whether debits equal credits everywhere and every stored
+// balance matches its lines
+func (r *InvariantReport) OK() bool {
+	if len(r.Unbalanced) > 0 || len(r.Drift) > 0 {
+		return false
+	}
+	for _, t := range r.Totals {
+		if !t.Debits.Equal(t.Credits) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortLines orders lines by account so concurrent postings lock accounts in
+// the same order
+func SortLines(lines []*Line) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		a, b := lines[i], lines[j]
+		if a.Account.Type != b.Account.Type {
+			return a.Account.Type < b.Account.Type
+		}
+		if a.Account.OwnerID != b.Account.OwnerID {
+			return a.Account.OwnerID < b.Account.OwnerID
+		}
+		return a.Amount.Currency < b.Amount.Currency
+	})
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+func usd(cents int64) money.Money { return money.New(cents, "USD") }
+
+func TestEntryValidate(t *testing.T) {
+	ok := NewEntry("payment_received:1", KindPaymentReceived, ReferencePayment, 1, "").
+		Debit(ProcessorCash, usd(10000)).
+		Credit(BuyerFunds(7), usd(10000))
+	if err := ok.Validate(); err != nil {
+		t.Errorf("balanced entry: %v", err)
+	}
+
+	unbalanced := NewEntry("sale_completed:1", KindSaleCompleted, ReferenceOrder, 1, "").
+		Debit(BuyerFunds(7), usd(10000)).
+		Credit(SellerPayable(8), usd(9000))
+	if err := unbalanced.Validate(); err == nil {
+		t.Error("unbalanced entry should fail")
+	}
+
+	oneSided := NewEntry("refund:1:0", KindRefund, ReferencePayment, 1, "").
+		Debit(BuyerFunds(7), usd(500))
+	if err := oneSided.Validate(); err == nil {
+		t.Error("entry with one line should fail")
+	}
+
+	noKey := NewEntry("", KindPayout, ReferencePayout, 1, "").
+		Debit(SellerPayable(8), usd(500)).
+		Credit(ProcessorCash, usd(500))
+	if err := noKey.Validate(); err == nil {
+		t.Error("entry without a key should fail")
+	}
+}
+
+func TestEntryBalancesPerCurrency(t *testing.T) {
+	// 92.00 EUR paid for a 100.00 USD sale, cleared through FX
+	sale := NewEntry("sale_completed:2", KindSaleCompleted, ReferenceOrder, 2, "").
+		Debit(BuyerFunds(7), money.New(9200, "EUR")).
+		Credit(FXClearing, money.New(9200, "EUR")).
+		Debit(FXClearing, usd(10000)).
+		Credit(SellerPayable(8), usd(9100)).
+		Credit(PlatformRevenue, usd(900))
+	if err := sale.Validate(); err != nil {
+		t.Errorf("cleared sale: %v", err)
+	}
+
+	// Debits equal credits in total but not per currency
+	mixed := NewEntry("sale_completed:3", KindSaleCompleted, ReferenceOrder, 3, "").
+		Debit(BuyerFunds(7), money.New(10000, "EUR")).
+		Credit(SellerPayable(8), usd(10000))
+	if err := mixed.Validate(); err == nil {
+		t.Error("entry unbalanced per currency should fail")
+	}
+}
+
+func TestEntryLines(t *testing.T) {
+	e := NewEntry("sale_completed:4", KindSaleCompleted, ReferenceOrder, 4, "").
+		Debit(BuyerFunds(7), usd(1000)).
+		Credit(SellerPayable(8), usd(-200)). // refunds beyond the payout
+		Credit(PlatformRevenue, usd(1200)).
+		Credit(ProcessorFees, usd(0))
+
+	if len(e.Lines) != 3 {
+		t.Fatalf("zero amounts are skipped: got %d lines", len(e.Lines))
+	}
+	if l := e.Lines[1]; l.Side != SideDebit || l.Amount.Amount != 200 {
+		t.Errorf("negative credit = %s %s, want debit 2.00", l.Side, l.Amount)
+	}
+	if err := e.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestLineSigned(t *testing.T) {
+	tests := []struct {
+		account AccountRef
+		side    string
+		want    int64
+	}{
+		{ProcessorCash, SideDebit, 100},
+		{ProcessorCash, SideCredit, -100},
+		{BuyerFunds(1), SideCredit, 100},
+		{BuyerFunds(1), SideDebit, -100},
+		{PlatformRevenue, SideCredit, 100},
+		{ProcessorFees, SideDebit, 100},
+	}
+
+	for _, tt := range tests {
+		l := &Line{Account: tt.account, Side: tt.side, Amount: usd(100)}
+		if got := l.Signed().Amount; got != tt.want {
+			t.Errorf("%s %s: Signed = %d, want %d", tt.account.Type, tt.side, got, tt.want)
+		}
+	}
+}
+
+func TestInvariantReportOK(t *testing.T) {
+	r := &InvariantReport{Totals: []*CurrencyTotals{{Currency: "USD", Debits: usd(500), Credits: usd(500)}}}
+	if !r.OK() {
+		t.Error("balanced report should be OK")
+	}
+
+	r.Totals[0].Credits = usd(499)
+	if r.OK() {
+		t.Error("totals that differ should not be OK")
+	}
+
+	r.Totals[0].Credits = usd(500)
+	r.Drift = []*BalanceDrift{{AccountID: 1}}
+	if r.OK() {
+		t.Error("drifted balance should not be OK")
+	}
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/ledger/model"
+)
+
+type LedgerRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLedgerRepository(db *pgxpool.Pool) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// PostEntry stores a validated entry and moves its accounts' balances in
+// one transaction. It reports false, and changes nothing, when an entry with
+// the same key was already posted.
+func (r *LedgerRepository) PostEntry(ctx context.Context, entry *model.Entry) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ledger_entries (entry_key, kind, reference_type, reference_id, description)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (entry_key) DO NOTHING
+		RETURNING id, created_at
+	`, entry.Key, entry.Kind, entry.ReferenceType, entry.ReferenceID, entry.Description).Scan(&entry.ID, &entry.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create ledger entry: %w", err)
+	}
+
+	model.SortLines(entry.Lines)
+	for _, line := range entry.Lines {
+		accountID, err := lockAccount(ctx, tx, line.Account, line.Amount.Currency)
+		if err != nil {
+			return false, err
+		}
+		line.AccountID, line.EntryID = accountID, entry.ID
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO ledger_lines (entry_id, account_id, side, amount, currency)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, entry.ID, accountID, line.Side, line.Amount, line.Amount.Currency).Scan(&line.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to create ledger line: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE ledger_accounts SET balance = balance + $1 WHERE id = $2`, line.Signed(), accountID)
+		if err != nil {
+			return false, fmt.Errorf("failed to update account balance: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// lockAccount returns the account's ID, creating it on first use, and locks
+// it until the transaction ends
+func lockAccount(ctx context.Context, tx pgx.Tx, ref model.AccountRef, currency string) (int64, error) {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO ledger_accounts (type, owner_id, currency)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (type, owner_id, currency) DO NOTHING
+	`, ref.Type, ref.OwnerID, currency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create ledger account: %w", err)
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM ledger_accounts
+		WHERE type = $1 AND owner_id = $2 AND currency = $3
+		FOR UPDATE
+	`, ref.Type, ref.OwnerID, currency).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock ledger account: %w", err)
+	}
+
+	return id, nil
+}
+
+// EntryExists reports whether an entry with key was posted
+func (r *LedgerRepository) EntryExists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE entry_key = $1)`, key).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check ledger entry: %w", err)
+	}
+
+	return exists, nil
+}
+
+const accountColumns = `id, type, owner_id, currency, balance, created_at, updated_at`
+
+func scanAccount(row pgx.Row) (*model.Account, error) {
+	a := &model.Account{}
+	if err := row.Scan(&a.ID, &a.Type, &a.OwnerID, &a.Currency, &a.Balance, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	a.Balance = a.Balance.WithCurrency(a.Currency)
+	return a, nil
+}
+
+// GetAccount returns an account, or nil when nothing was posted to it yet
+func (r *LedgerRepository) GetAccount(ctx context.Context, ref model.AccountRef, currency string) (*model.Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM ledger_accounts WHERE type = $1 AND owner_id = $2 AND currency = $3`
+
+	account, err := scanAccount(r.db.QueryRow(ctx, query, ref.Type, ref.OwnerID, currency))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger account: %w", err)
+	}
+
+	return account, nil
+}
+
+// ListAccounts returns accounts filtered by type and owner (empty type and
+// a negative owner match all)
+func (r *LedgerRepository) ListAccounts(ctx context.Context, accountType string, ownerID int64) ([]*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM ledger_accounts
+		WHERE ($1 = '' OR type = $1) AND ($2 < 0 OR owner_id = $2)
+		ORDER BY type, owner_id, currency
+	`
+
+	rows, err := r.db.Query(ctx, query, accountType, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make([]*model.Account, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// GetEntries returns the entries posted for a record with their lines,
+// oldest first
+func (r *LedgerRepository) GetEntries(ctx context.Context, referenceType string, referenceID int64) ([]*model.Entry, error) {
+	query := `
+		SELECT e.id, e.entry_key, e.kind, e.reference_type, e.reference_id, COALESCE(e.description, ''), e.created_at,
+		       l.id, l.account_id, a.type, a.owner_id, l.side, l.amount, l.currency
+		FROM ledger_entries e
+		JOIN ledger_lines l ON l.entry_id = e.id
+		JOIN ledger_accounts a ON a.id = l.account_id
+		WHERE e.reference_type = $1 AND e.reference_id = $2
+		ORDER BY e.id ASC, l.id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, referenceType, referenceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*model.Entry, 0)
+	var current *model.Entry
+	for rows.Next() {
+		e := &model.Entry{}
+		line := &model.Line{}
+		var currency string
+		err := rows.Scan(
+			&e.ID, &e.Key, &e.Kind, &e.ReferenceType, &e.ReferenceID, &e.Description, &e.CreatedAt,
+			&line.ID, &line.AccountID, &line.Account.Type, &line.Account.OwnerID, &line.Side, &line.Amount, &currency,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		line.EntryID = e.ID
+		line.Amount = line.Amount.WithCurrency(currency)
+
+		if current == nil || current.ID != e.ID {
+			current = e
+			entries = append(entries, current)
+		}
+		current.Lines = append(current.Lines, line)
+	}
+
+	return entries, rows.Err()
+}
+
+// CheckInvariants recomputes the ledger from its lines: debits against
+// credits per currency and per entry, and every account's balance
+func (r *LedgerRepository) CheckInvariants(ctx context.Context) (*model.InvariantReport, error) {
+	report := &model.InvariantReport{
+		Totals:     make([]*model.CurrencyTotals, 0),
+		Unbalanced: make([]*model.UnbalancedEntry, 0),
+		Drift:      make([]*model.BalanceDrift, 0),
+	}
+
+	err := r.db.QueryRow(ctx, `SELECT (SELECT COUNT(*) FROM ledger_entries), (SELECT COUNT(*) FROM ledger_lines)`).
+		Scan(&report.Entries, &report.Lines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ledger entries: %w", err)
+	}
+
+	// Totals per currency
+	rows, err := r.db.Query(ctx, `
+		SELECT currency,
+		       COALESCE(SUM(amount) FILTER (WHERE side = 'debit'), 0),
+		       COALESCE(SUM(amount) FILTER (WHERE side = 'credit'), 0)
+		FROM ledger_lines
+		GROUP BY currency
+		ORDER BY currency
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total ledger lines: %w", err)
+	}
+	for rows.Next() {
+		t := &model.CurrencyTotals{}
+		if err := rows.Scan(&t.Currency, &t.Debits, &t.Credits); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan ledger totals: %w", err)
+		}
+		t.Debits, t.Credits = t.Debits.WithCurrency(t.Currency), t.Credits.WithCurrency(t.Currency)
+		report.Totals = append(report.Totals, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Entries that do not balance on their own
+	rows, err = r.db.Query(ctx, `
+		SELECT e.id, e.entry_key, l.currency,
+		       COALESCE(SUM(l.amount) FILTER (WHERE l.side = 'debit'), 0) AS debits,
+		       COALESCE(SUM(l.amount) FILTER (WHERE l.side = 'credit'), 0) AS credits
+		FROM ledger_entries e
+		JOIN ledger_lines l ON l.entry_id = e.id
+		GROUP BY e.id, e.entry_key, l.currency
+		HAVING COALESCE(SUM(l.amount) FILTER (WHERE l.side = 'debit'), 0)
+		    <> COALESCE(SUM(l.amount) FILTER (WHERE l.side = 'credit'), 0)
+		ORDER BY e.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ledger entries: %w", err)
+	}
+	for rows.Next() {
+		u := &model.UnbalancedEntry{}
+		if err := rows.Scan(&u.EntryID, &u.Key, &u.Currency, &u.Debits, &u.Credits); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan unbalanced entry: %w", err)
+		}
+		u.Debits, u.Credits = u.Debits.WithCurrency(u.Currency), u.Credits.WithCurrency(u.Currency)
+		report.Unbalanced = append(report.Unbalanced, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Stored balances against their lines
+	rows, err = r.db.Query(ctx, `
+		SELECT a.id, a.type, a.owner_id, a.currency, a.balance,
+		       COALESCE(SUM(CASE
+		           WHEN (l.side = 'debit') = (a.type IN ($1, $2, $3)) THEN l.amount
+		           ELSE -l.amount
+		       END), 0) AS computed
+		FROM ledger_accounts a
+		LEFT JOIN ledger_lines l ON l.account_id = a.id
+		GROUP BY a.id
+		HAVING a.balance <> COALESCE(SUM(CASE
+		           WHEN (l.side = 'debit') = (a.type IN ($1, $2, $3)) THEN l.amount
+		           ELSE -l.amount
+		       END), 0)
+		ORDER BY a.id
+	`, model.AccountProcessorCash, model.AccountProcessorFees, model.AccountFXClearing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ledger balances: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d := &model.BalanceDrift{}
+		var currency string
+		if err := rows.Scan(&d.AccountID, &d.Type, &d.OwnerID, &currency, &d.Stored, &d.Computed); err != nil {
+			return nil, fmt.Errorf("failed to scan balance drift: %w", err)
+		}
+		d.Stored, d.Computed = d.Stored.WithCurrency(currency), d.Computed.WithCurrency(currency)
+		report.Drift = append(report.Drift, d)
+	}
+
+	return report, rows.Err()
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/ledger/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/ledger/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/logger"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// LedgerService posts a balanced journal entry for every money movement and
+// answers balance queries from the ledger. Entries are keyed by the movement
+// they record, so posting again after a retry or a replayed webhook is safe.
+type LedgerService struct {
+	repo *repository.LedgerRepository
+	log  *logger.Logger
+}
+
+func NewLedgerService(repo *repository.LedgerRepository, log *logger.Logger) *LedgerService {
+	return &LedgerService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// Post validates and stores an entry. It reports false when the movement
+// was already posted.
+func (s *LedgerService) Post(ctx context.Context, entry *model.Entry) (bool, error) {
+	if err := entry.Validate(); err != nil {
+		return false, err
+	}
+
+	posted, err := s.repo.PostEntry(ctx, entry)
+	if err != nil {
+		return false, fmt.Errorf("failed to post %s: %w", entry.Key, err)
+	}
+
+	if posted {
+		s.log.Debugf("Ledger entry %s posted (%d lines)", entry.Key, len(entry.Lines))
+	}
+
+	return posted, nil
+}
+
+// RecordPayment posts a buyer's payment: the processor holds the cash and
+// the platform owes it to the buyer until the sale completes
+func (s *LedgerService) RecordPayment(ctx context.Context, paymentID, buyerID int64, amount money.Money) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d", model.KindPaymentReceived, paymentID),
+		model.KindPaymentReceived, model.ReferencePayment, paymentID,
+		"Payment received",
+	).
+		Debit(model.ProcessorCash, amount).
+		Credit(model.BuyerFunds(buyerID), amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordCapture posts the capture of a bid authorization, which is a
+// payment collected from a held card
+func (s *LedgerService) RecordCapture(ctx context.Context, authorizationID, buyerID int64, amount money.Money) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d", model.KindAuthorizationCapture, authorizationID),
+		model.KindAuthorizationCapture, model.ReferenceAuthorization, authorizationID,
+		"Bid authorization captured",
+	).
+		Debit(model.ProcessorCash, amount).
+		Credit(model.BuyerFunds(buyerID), amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordProcessorFee posts the fee the processor kept from a payment
+func (s *LedgerService) RecordProcessorFee(ctx context.Context, paymentID int64, fee money.Money) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%s:%d", model.KindProcessorFee, model.ReferencePayment, paymentID),
+		model.KindProcessorFee, model.ReferencePayment, paymentID,
+		"Processor fee",
+	).
+		Debit(model.ProcessorFees, fee).
+		Credit(model.ProcessorCash, fee)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordRefund posts a refund that took a payment's refunded total from
+// before to after. The key carries the new total, so each increase is
+// posted once however it was reported.
+func (s *LedgerService) RecordRefund(ctx context.Context, paymentID, buyerID int64, before, after money.Money) error {
+	amount := after.Sub(before)
+	if !amount.IsPositive() {
+		return nil
+	}
+
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d:%d", model.KindRefund, paymentID, after.Amount),
+		model.KindRefund, model.ReferencePayment, paymentID,
+		"Refund to buyer",
+	).
+		Debit(model.BuyerFunds(buyerID), amount).
+		Credit(model.ProcessorCash, amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordSale posts a completed order: the buyer's funds become the seller's
+// payable and the platform's revenue. Payments in another currency than the
+// settlement one pass through the FX clearing account.
+func (s *LedgerService) RecordSale(ctx context.Context, sale *model.Sale) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d", model.KindSaleCompleted, sale.OrderID),
+		model.KindSaleCompleted, model.ReferenceOrder, sale.OrderID,
+		"Sale completed",
+	).
+		Debit(model.BuyerFunds(sale.BuyerID), sale.BuyerPaid)
+
+	if !sale.BuyerPaid.SameCurrency(sale.Total) {
+		entry.
+			Credit(model.FXClearing, sale.BuyerPaid).
+			Debit(model.FXClearing, sale.Total)
+	}
+
+	entry.
+		Credit(model.SellerPayable(sale.SellerID), sale.SellerPayable).
+		Credit(model.PlatformRevenue, sale.PlatformRevenue)
+
+	if len(entry.Lines) == 0 {
+		// Fully refunded before completion
+		return nil
+	}
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordPayout posts money transferred to a seller
+func (s *LedgerService) RecordPayout(ctx context.Context, payoutID, sellerID int64, amount money.Money) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d", model.KindPayout, payoutID),
+		model.KindPayout, model.ReferencePayout, payoutID,
+		"Payout to seller",
+	).
+		Debit(model.SellerPayable(sellerID), amount).
+		Credit(model.ProcessorCash, amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordPayoutReversal posts a payout whose transfer failed after it was
+// recorded; the seller is owed the amount again. Payouts that were never
+// posted have nothing to reverse.
+func (s *LedgerService) RecordPayoutReversal(ctx context.Context, payoutID, sellerID int64, amount money.Money) error {
+	paid, err := s.repo.EntryExists(ctx, fmt.Sprintf("%s:%d", model.KindPayout, payoutID))
+	if err != nil || !paid {
+		return err
+	}
+
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d", model.KindPayoutReversed, payoutID),
+		model.KindPayoutReversed, model.ReferencePayout, payoutID,
+		"Payout transfer failed",
+	).
+		Debit(model.ProcessorCash, amount).
+		Credit(model.SellerPayable(sellerID), amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordChargeback posts a chargeback the platform lost: the bank took the
+// buyer's money back from the processor
+func (s *LedgerService) RecordChargeback(ctx context.Context, chargebackID, buyerID int64, amount money.Money) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d", model.KindChargebackLost, chargebackID),
+		model.KindChargebackLost, model.ReferenceChargeback, chargebackID,
+		"Chargeback lost",
+	).
+		Debit(model.BuyerFunds(buyerID), amount).
+		Credit(model.ProcessorCash, amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordClawback posts money reclaimed from a seller for an order the buyer
+// got money back on after the sale. reference identifies the refund or
+// chargeback reclaimed.
+func (s *LedgerService) RecordClawback(ctx context.Context, orderID, sellerID, buyerID int64, reference string, amount money.Money) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%s", model.KindClawback, reference),
+		model.KindClawback, model.ReferenceOrder, orderID,
+		"Clawback from seller",
+	).
+		Debit(model.SellerPayable(sellerID), amount).
+		Credit(model.BuyerFunds(buyerID), amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// RecordSubscriptionPayment posts a paid subscription invoice, which is
+// platform revenue as soon as it is collected
+func (s *LedgerService) RecordSubscriptionPayment(ctx context.Context, transactionID int64, amount money.Money) error {
+	entry := model.NewEntry(
+		fmt.Sprintf("%s:%d", model.KindSubscriptionPayment, transactionID),
+		model.KindSubscriptionPayment, model.ReferenceSubscription, transactionID,
+		"Subscription payment",
+	).
+		Debit(model.ProcessorCash, amount).
+		Credit(model.PlatformRevenue, amount)
+
+	return s.postMovement(ctx, entry)
+}
+
+// postMovement posts an entry whose duplicate is not an error
+func (s *LedgerService) postMovement(ctx context.Context, entry *model.Entry) error {
+	_, err := s.Post(ctx, entry)
+	return err
+}
+
+// GetBalance returns an account's balance; accounts nothing was posted to
+// have a zero balance
+func (s *LedgerService) GetBalance(ctx context.Context, ref model.AccountRef, currency string) (money.Money, error) {
+	currency = strings.ToUpper(currency)
+
+	account, err := s.repo.GetAccount(ctx, ref, currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if account == nil {
+		return money.Zero(currency), nil
+	}
+
+	return account.Balance, nil
+}
+
+// ListAccounts returns accounts with their balances. An empty accountType
+// matches every type and ownerID < 0 every owner.
+func (s *LedgerService) ListAccounts(ctx context.Context, accountType string, ownerID int64) ([]*model.Account, error) {
+	if accountType != "" && !model.ValidAccountType(accountType) {
+		return nil, fmt.Errorf("unknown account type: %s", accountType)
+	}
+
+	return s.repo.ListAccounts(ctx, accountType, ownerID)
+}
+
+// GetEntries returns the entries posted for a record
+func (s *LedgerService) GetEntries(ctx context.Context, referenceType string, referenceID int64) ([]*model.Entry, error) {
+	return s.repo.GetEntries(ctx, referenceType, referenceID)
+}
+
+// CheckInvariants proves the ledger balances: debits equal credits in every
+// currency and every entry, and stored balances match their lines
+func (s *LedgerService) CheckInvariants(ctx context.Context) (*model.InvariantReport, error) {
+	report, err := s.repo.CheckInvariants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.CheckedAt = time.Now()
+
+	if !report.OK() {
+		s.log.Errorf("Ledger invariants violated: %d unbalanced entries, %d drifted balances",
+			len(report.Unbalanced), len(report.Drift))
+	}
+
+	return report, nil
+}
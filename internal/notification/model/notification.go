@@ -14,6 +14,11 @@ const (
 	TypeOrderShipped     = "order_shipped"
 	TypeOrderDelivered   = "order_delivered"
 	TypeOrderCompleted   = "order_completed"
+	TypeInspectionEnding = "order_inspection_ending" // delivered order auto-completes soon
+	TypeExportReady      = "export_ready"            // seller sales export can be downloaded
+	TypeOrderMessage     = "order_message"           // new message in an order's buyer/seller thread
+	TypeShipReminder     = "order_ship_reminder"     // seller's shipping deadline is close
+	TypeSellerPenalty    = "seller_penalty"          // order canceled for a missed deadline
 	TypePaymentSucceeded = "payment_succeeded"
 	TypePaymentFailed    = "payment_failed"
 	TypeRefundIssued     = "refund_issued"
@@ -84,7 +89,7 @@ func (p *NotificationPreferences) ShouldSendPush(notificationType string) bool {
 	switch notificationType {
 	case TypeMatchCreated:
 		return p.PushMatchCreated
-	case TypeOrderCreated, TypeOrderPaid, TypeOrderShipped, TypeOrderDelivered, TypeOrderCompleted:
+	case TypeOrderCreated, TypeOrderPaid, TypeOrderShipped, TypeOrderDelivered, TypeOrderCompleted, TypeOrderMessage, TypeShipReminder, TypeSellerPenalty:
 		return p.PushOrderUpdates
 	case TypePaymentSucceeded, TypePaymentFailed, TypeRefundIssued, TypePayoutCompleted:
 		return p.PushPaymentUpdates
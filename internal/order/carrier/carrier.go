@@ -0,0 +1,91 @@
+package carrier
+
+import (
+	"context"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Address is a shipping origin or destination
+type Address struct {
+	Name       string
+	Street1    string
+	Street2    string
+	City       string
+	State      string
+	PostalCode string
+	Country    string
+}
+
+// Parcel describes the package being shipped
+type Parcel struct {
+	WeightOz float64
+	LengthIn float64
+	WidthIn  float64
+	HeightIn float64
+}
+
+// Rate is a price quote for a shipping service
+type Rate struct {
+	Carrier       string
+	Service       string
+	Amount        money.Money
+	EstimatedDays int
+}
+
+// LabelRequest asks the carrier for a prepaid label
+type LabelRequest struct {
+	Reference string // our reference, e.g. the order number
+	From      Address
+	To        Address
+	Parcel    Parcel
+	Service   string // empty selects the cheapest service
+}
+
+// Label is a purchased prepaid shipping label
+type Label struct {
+	Carrier        string
+	Service        string
+	TrackingNumber string
+	LabelURL       string // where the label PDF can be downloaded
+	Cost           money.Money
+	CreatedAt      time.Time
+}
+
+// TrackingEvent is a single scan reported by the carrier
+type TrackingEvent struct {
+	Status      string
+	Description string
+	Location    string
+	OccurredAt  time.Time
+}
+
+// TrackingInfo is the current state of a shipment
+type TrackingInfo struct {
+	Carrier        string
+	TrackingNumber string
+	Status         string // label_created, in_transit, out_for_delivery, delivered, exception
+	Events         []TrackingEvent
+}
+
+// Tracking statuses
+const (
+	TrackingLabelCreated   = "label_created"
+	TrackingInTransit      = "in_transit"
+	TrackingOutForDelivery = "out_for_delivery"
+	TrackingDelivered      = "delivered"
+	TrackingException      = "exception"
+)
+
+// Provider is a shipping carrier (or carrier aggregator) integration
+type Provider interface {
+	// GetRates returns the available services for a parcel, cheapest first
+	GetRates(ctx context.Context, from, to Address, parcel Parcel) ([]Rate, error)
+
+	// CreateLabel purchases a prepaid label
+	CreateLabel(ctx context.Context, req LabelRequest) (*Label, error)
+
+	// Track returns the scan history of a shipment
+	Track(ctx context.Context, trackingNumber string) (*TrackingInfo, error)
+}
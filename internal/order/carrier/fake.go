@@ -0,0 +1,189 @@
+package carrier
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// FakeCarrierName is the carrier reported by FakeProvider
+const FakeCarrierName = "FAKE"
+
+// fakeServices are the services FakeProvider quotes: base price and transit days
+var fakeServices = []struct {
+	name     string
+	base     float64
+	perOz    float64
+	days     int
+	perZone  float64
+	zoneDays int
+}{
+	{name: "ground", base: 8.50, perOz: 0.05, days: 5, perZone: 0.75, zoneDays: 1},
+	{name: "express", base: 18.00, perOz: 0.10, days: 2, perZone: 1.50, zoneDays: 0},
+	{name: "overnight", base: 35.00, perOz: 0.20, days: 1, perZone: 2.50, zoneDays: 0},
+}
+
+// FakeProvider is a deterministic in-memory carrier for local development
+// and tests. Rates depend only on the parcel and postal codes, tracking
+// numbers only on the label reference and service, so the same request
+// always yields the same label. Tracking history is kept in memory.
+type FakeProvider struct {
+	mu     sync.Mutex
+	labels map[string]*Label
+
+	// Now returns the current time; override it for reproducible timestamps
+	Now func() time.Time
+}
+
+// NewFakeProvider creates a fake carrier
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		labels: make(map[string]*Label),
+		Now:    time.Now,
+	}
+}
+
+// GetRates quotes every fake service, cheapest first
+func (p *FakeProvider) GetRates(ctx context.Context, from, to Address, parcel Parcel) ([]Rate, error) {
+	if err := validateShipment(from, to, parcel); err != nil {
+		return nil, err
+	}
+
+	zones := zoneDistance(from.PostalCode, to.PostalCode)
+	extraOz := parcel.WeightOz - 16
+	if extraOz < 0 {
+		extraOz = 0
+	}
+
+	rates := make([]Rate, 0, len(fakeServices))
+	for _, svc := range fakeServices {
+		amount := svc.base + svc.perOz*extraOz + svc.perZone*float64(zones)
+		rates = append(rates, Rate{
+			Carrier:       FakeCarrierName,
+			Service:       svc.name,
+			Amount:        money.FromMajor(amount, "USD", money.HalfUp),
+			EstimatedDays: svc.days + svc.zoneDays*zones/3,
+		})
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Amount.LessThan(rates[j].Amount) })
+
+	return rates, nil
+}
+
+// CreateLabel "purchases" a label for the requested (or cheapest) service
+func (p *FakeProvider) CreateLabel(ctx context.Context, req LabelRequest) (*Label, error) {
+	if req.Reference == "" {
+		return nil, fmt.Errorf("label reference is required")
+	}
+
+	rates, err := p.GetRates(ctx, req.From, req.To, req.Parcel)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := rates[0]
+	if req.Service != "" {
+		found := false
+		for _, r := range rates {
+			if r.Service == req.Service {
+				rate, found = r, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown service: %s", req.Service)
+		}
+	}
+
+	trackingNumber := fakeTrackingNumber(req.Reference, rate.Service)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if label, ok := p.labels[trackingNumber]; ok {
+		copied := *label
+		return &copied, nil
+	}
+
+	label := &Label{
+		Carrier:        FakeCarrierName,
+		Service:        rate.Service,
+		TrackingNumber: trackingNumber,
+		LabelURL:       fmt.Sprintf("fake://labels/%s.pdf", trackingNumber),
+		Cost:           rate.Amount,
+		CreatedAt:      p.Now(),
+	}
+	p.labels[trackingNumber] = label
+
+	copied := *label
+	return &copied, nil
+}
+
+// Track reports the label creation scan for labels this provider created
+func (p *FakeProvider) Track(ctx context.Context, trackingNumber string) (*TrackingInfo, error) {
+	p.mu.Lock()
+	label, ok := p.labels[trackingNumber]
+	p.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("tracking number not found: %s", trackingNumber)
+	}
+
+	return &TrackingInfo{
+		Carrier:        FakeCarrierName,
+		TrackingNumber: trackingNumber,
+		Status:         TrackingLabelCreated,
+		Events: []TrackingEvent{
+			{
+				Status:      TrackingLabelCreated,
+				Description: "Shipping label created, awaiting item",
+				OccurredAt:  label.CreatedAt,
+			},
+		},
+	}, nil
+}
+
+func validateShipment(from, to Address, parcel Parcel) error {
+	if from.PostalCode == "" {
+		return fmt.Errorf("origin postal code is required")
+	}
+	if to.PostalCode == "" {
+		return fmt.Errorf("destination postal code is required")
+	}
+	if parcel.WeightOz <= 0 {
+		return fmt.Errorf("parcel weight must be greater than 0")
+	}
+	return nil
+}
+
+// zoneDistance approximates shipping zones from the first postal code digit
+func zoneDistance(from, to string) int {
+	a, b := postalPrefix(from), postalPrefix(to)
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func postalPrefix(postalCode string) int {
+	postalCode = strings.TrimSpace(postalCode)
+	if postalCode == "" || postalCode[0] < '0' || postalCode[0] > '9' {
+		return 0
+	}
+	return int(postalCode[0] - '0')
+}
+
+func fakeTrackingNumber(reference, service string) string {
+	h := fnv.New64a()
+	h.Write([]byte(reference))
+	h.Write([]byte{0})
+	h.Write([]byte(service))
+	return fmt.Sprintf("FK%016d", h.Sum64()%10000000000000000)
+}
@@ -0,0 +1,117 @@
+package carrier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the webhook signature: "t=<unix>,v1=<hex hmac>"
+const SignatureHeader = "X-Carrier-Signature"
+
+// DefaultSignatureTolerance is how old a signed webhook may be before it is rejected
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// WebhookEvent is a tracking update pushed by a carrier
+type WebhookEvent struct {
+	EventID        string    `json:"event_id"`
+	TrackingNumber string    `json:"tracking_number"`
+	Carrier        string    `json:"carrier"`
+	Status         string    `json:"status"`
+	Description    string    `json:"description"`
+	Location       string    `json:"location"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// Sign computes the signature header value for a payload. The signed content
+// is "<unix timestamp>.<payload>" so a captured request cannot be replayed
+// later with a fresh timestamp.
+func Sign(payload []byte, secret string, timestamp time.Time) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, computeSignature(payload, secret, ts))
+}
+
+// VerifySignature checks a signature header produced by Sign
+func VerifySignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("webhook secret is not configured")
+	}
+
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	if ts == "" || sig == "" {
+		return fmt.Errorf("malformed signature header")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed signature timestamp")
+	}
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("signature timestamp outside tolerance")
+	}
+
+	expected := computeSignature(payload, secret, ts)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	return nil
+}
+
+// ParseWebhookEvent decodes and validates a tracking webhook payload
+func ParseWebhookEvent(payload []byte) (*WebhookEvent, error) {
+	event := &WebhookEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	if event.TrackingNumber == "" {
+		return nil, fmt.Errorf("tracking_number is required")
+	}
+	if event.Carrier == "" {
+		return nil, fmt.Errorf("carrier is required")
+	}
+	if !IsTrackingStatus(event.Status) {
+		return nil, fmt.Errorf("unknown tracking status: %s", event.Status)
+	}
+	if event.OccurredAt.IsZero() {
+		return nil, fmt.Errorf("occurred_at is required")
+	}
+
+	return event, nil
+}
+
+// IsTrackingStatus checks if a status is one of the normalized tracking statuses
+func IsTrackingStatus(status string) bool {
+	switch status {
+	case TrackingLabelCreated, TrackingInTransit, TrackingOutForDelivery, TrackingDelivered, TrackingException:
+		return true
+	}
+	return false
+}
+
+func computeSignature(payload []byte, secret, ts string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// WriteCSV encodes sheets as one CSV file; sheets are separated by a blank row
+func WriteCSV(sheets ...Sheet) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	for i, sheet := range sheets {
+		if i > 0 {
+			if err := w.Write([]string{}); err != nil {
+				return nil, fmt.Errorf("failed to write csv: %w", err)
+			}
+		}
+
+		if err := w.Write(sheet.Header); err != nil {
+			return nil, fmt.Errorf("failed to write csv: %w", err)
+		}
+
+		for _, row := range sheet.Rows {
+			record := make([]string, len(row))
+			for j, cell := range row {
+				record[j] = cellText(cell)
+			}
+			if err := w.Write(record); err != nil {
+				return nil, fmt.Errorf("failed to write csv: %w", err)
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func cellText(cell interface{}) string {
+	switch v := cell.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case money.Money:
+		return v.String()
+	case string:
+		return v
+	}
+	return fmt.Sprint(cell)
+}
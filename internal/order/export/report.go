@@ -0,0 +1,145 @@
+package export
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Sheet is a table written as a CSV file or an XLSX worksheet. Cells are
+// strings, float64 counts or money amounts.
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// Sale is one completed order in a seller's export
+type Sale struct {
+	OrderNumber  string
+	CompletedAt  time.Time
+	Gross        money.Money // sale price
+	Fees         money.Money // seller fees
+	NetPayout    money.Money // payout actually sent, or expected payout while pending
+	PayoutStatus string      // pending, processing, paid, failed, reversed; empty when no payout exists
+	PayoutID     string
+	PaidAt       *time.Time
+}
+
+// Threshold is the yearly volume above which a marketplace must report a
+// seller's sales (e.g. Form 1099-K). Both limits must be reached.
+type Threshold struct {
+	Gross  money.Money
+	Orders int
+}
+
+// MonthTotal sums a seller's completed orders for one month
+type MonthTotal struct {
+	Month     time.Month
+	Orders    int
+	Gross     money.Money
+	Fees      money.Money
+	NetPayout money.Money
+}
+
+// TaxSummary is a seller's yearly sales with monthly totals
+type TaxSummary struct {
+	Year      int
+	Months    [12]MonthTotal
+	Total     MonthTotal
+	Threshold Threshold
+	Crossed   bool
+	CrossedIn time.Month // month in which both limits were first reached
+}
+
+// SalesSheet lists sales one order per row
+func SalesSheet(sales []Sale) Sheet {
+	sheet := Sheet{
+		Name:   "Sales",
+		Header: []string{"Order", "Completed", "Gross", "Fees", "Net payout", "Payout status", "Payout ID", "Paid"},
+	}
+
+	for _, s := range sales {
+		paid := ""
+		if s.PaidAt != nil {
+			paid = s.PaidAt.Format("2006-01-02")
+		}
+		sheet.Rows = append(sheet.Rows, []interface{}{
+			s.OrderNumber,
+			s.CompletedAt.Format("2006-01-02"),
+			s.Gross,
+			s.Fees,
+			s.NetPayout,
+			s.PayoutStatus,
+			s.PayoutID,
+			paid,
+		})
+	}
+
+	return sheet
+}
+
+// Summarize totals sales by completion month and checks the threshold.
+// Sales outside the year are ignored.
+func Summarize(year int, sales []Sale, threshold Threshold) *TaxSummary {
+	summary := &TaxSummary{Year: year, Threshold: threshold}
+	for i := range summary.Months {
+		summary.Months[i].Month = time.Month(i + 1)
+	}
+
+	// Walk the months in order so CrossedIn is the first month over the limit
+	for i := range summary.Months {
+		month := &summary.Months[i]
+		for _, s := range sales {
+			if s.CompletedAt.Year() != year || s.CompletedAt.Month() != month.Month {
+				continue
+			}
+			month.Orders++
+			month.Gross = month.Gross.Add(s.Gross)
+			month.Fees = month.Fees.Add(s.Fees)
+			month.NetPayout = month.NetPayout.Add(s.NetPayout)
+		}
+
+		summary.Total.Orders += month.Orders
+		summary.Total.Gross = summary.Total.Gross.Add(month.Gross)
+		summary.Total.Fees = summary.Total.Fees.Add(month.Fees)
+		summary.Total.NetPayout = summary.Total.NetPayout.Add(month.NetPayout)
+
+		if !summary.Crossed && threshold.reached(summary.Total) {
+			summary.Crossed = true
+			summary.CrossedIn = month.Month
+		}
+	}
+
+	return summary
+}
+
+// SummarySheet lays out the monthly totals followed by the threshold result
+func SummarySheet(s *TaxSummary) Sheet {
+	sheet := Sheet{
+		Name:   fmt.Sprintf("Summary %d", s.Year),
+		Header: []string{"Month", "Orders", "Gross", "Fees", "Net payout"},
+	}
+
+	for _, m := range s.Months {
+		sheet.Rows = append(sheet.Rows, []interface{}{m.Month.String(), float64(m.Orders), m.Gross, m.Fees, m.NetPayout})
+	}
+	sheet.Rows = append(sheet.Rows,
+		[]interface{}{"Total", float64(s.Total.Orders), s.Total.Gross, s.Total.Fees, s.Total.NetPayout},
+		[]interface{}{},
+		[]interface{}{"Reporting threshold", fmt.Sprintf("%d orders and %s gross", s.Threshold.Orders, s.Threshold.Gross)},
+	)
+
+	if s.Crossed {
+		sheet.Rows = append(sheet.Rows, []interface{}{"Threshold reached", fmt.Sprintf("Yes, in %s", s.CrossedIn)})
+	} else {
+		sheet.Rows = append(sheet.Rows, []interface{}{"Threshold reached", "No"})
+	}
+
+	return sheet
+}
+
+func (t Threshold) reached(total MonthTotal) bool {
+	return total.Orders >= t.Orders && !total.Gross.LessThan(t.Gross)
+}
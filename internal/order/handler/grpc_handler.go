@@ -2,22 +2,30 @@ package handler
 
 import (
 	"context"
+	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/carrier"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/service"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	pb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
 )
 
 type OrderHandler struct {
 	pb.UnimplementedOrderServiceServer
-	service *service.OrderService
+	service  *service.OrderService
+	invoices *service.InvoiceService
+	exports  *service.ExportService
 }
 
-func NewOrderHandler(service *service.OrderService) *OrderHandler {
+func NewOrderHandler(service *service.OrderService, invoices *service.InvoiceService, exports *service.ExportService) *OrderHandler {
 	return &OrderHandler{
-		service: service,
+		service:  service,
+		invoices: invoices,
+		exports:  exports,
 	}
 }
 
@@ -35,31 +43,67 @@ func orderToProto(o *model.Order) *pb.Order {
 		SellerId:     o.SellerID,
 		ProductId:    o.ProductID,
 		SizeId:       o.SizeID,
-		Price:        o.Price,
+		Price:        o.Price.Major(),
 		Quantity:     o.Quantity,
-		BuyerFee:     o.BuyerFee,
-		SellerFee:    o.SellerFee,
-		PlatformFee:  o.PlatformFee,
-		TotalAmount:  o.TotalAmount,
-		SellerPayout: o.SellerPayout,
+		BuyerFee:     o.BuyerFee.Major(),
+		SellerFee:    o.SellerFee.Major(),
+		PlatformFee:  o.PlatformFee.Major(),
+		TotalAmount:  o.TotalAmount.Major(),
+		SellerPayout: o.SellerPayout.Major(),
 		Status:       o.Status,
 		CreatedAt:    timestamppb.New(o.CreatedAt),
 		UpdatedAt:    timestamppb.New(o.UpdatedAt),
+
+		PriceMinor:        o.Price.Amount,
+		BuyerFeeMinor:     o.BuyerFee.Amount,
+		SellerFeeMinor:    o.SellerFee.Amount,
+		PlatformFeeMinor:  o.PlatformFee.Amount,
+		TotalAmountMinor:  o.TotalAmount.Amount,
+		SellerPayoutMinor: o.SellerPayout.Amount,
+
+		Currency:        o.Price.Currency,
+		BuyerCurrency:   o.BuyerTotal.Currency,
+		BuyerTotal:      o.BuyerTotal.Major(),
+		BuyerTotalMinor: o.BuyerTotal.Amount,
+		BuyerFxRate:     o.BuyerFXRate.String(),
+		SellerCurrency:  o.SellerCurrency,
+		SellerFxRate:    o.SellerFXRate.String(),
 	}
 
 	// Optional fields
 	if o.ShippingAddressID.Valid {
 		order.ShippingAddressId = o.ShippingAddressID.Int64
 	}
+	if o.CheckoutID.Valid {
+		order.CheckoutId = o.CheckoutID.Int64
+	}
 	if o.TrackingNumber.Valid {
 		order.TrackingNumber = o.TrackingNumber.String
 	}
 	if o.Carrier.Valid {
 		order.Carrier = o.Carrier.String
 	}
+	if o.ShippingService.Valid {
+		order.ShippingService = o.ShippingService.String
+	}
+	if o.LabelURL.Valid {
+		order.LabelUrl = o.LabelURL.String
+	}
+	if o.OutboundTrackingNumber.Valid {
+		order.OutboundTrackingNumber = o.OutboundTrackingNumber.String
+	}
+	if o.OutboundCarrier.Valid {
+		order.OutboundCarrier = o.OutboundCarrier.String
+	}
+	if o.OutboundLabelURL.Valid {
+		order.OutboundLabelUrl = o.OutboundLabelURL.String
+	}
 	if o.PaymentAt.Valid {
 		order.PaymentAt = timestamppb.New(o.PaymentAt.Time)
 	}
+	if o.ShipBy.Valid {
+		order.ShipBy = timestamppb.New(o.ShipBy.Time)
+	}
 	if o.ShippedAt.Valid {
 		order.ShippedAt = timestamppb.New(o.ShippedAt.Time)
 	}
@@ -99,6 +143,7 @@ func statusHistoryToProto(h *model.OrderStatusHistory) *pb.OrderStatusHistory {
 		OrderId:   h.OrderID,
 		ToStatus:  h.ToStatus,
 		CreatedBy: h.CreatedBy,
+		ActorRole: h.ActorRole,
 		CreatedAt: timestamppb.New(h.CreatedAt),
 	}
 
@@ -108,10 +153,214 @@ func statusHistoryToProto(h *model.OrderStatusHistory) *pb.OrderStatusHistory {
 	if h.Note.Valid {
 		history.Note = h.Note.String
 	}
+	if h.ActorID.Valid {
+		history.ActorId = h.ActorID.Int64
+	}
 
 	return history
 }
 
+// Helper function to convert model.OrderAdjustment to pb.OrderAdjustment
+func adjustmentToProto(a *model.OrderAdjustment) *pb.OrderAdjustment {
+	adj := &pb.OrderAdjustment{
+		Id:          a.ID,
+		Type:        a.Type,
+		AmountMinor: a.Amount.Amount,
+		Currency:    a.Amount.Currency,
+		Reference:   a.Reference,
+		CreatedAt:   timestamppb.New(a.CreatedAt),
+	}
+
+	if a.Reason.Valid {
+		adj.Reason = a.Reason.String
+	}
+
+	return adj
+}
+
+// Helper function to convert model.ShipmentEvent to pb.TrackingEvent
+func shipmentEventToProto(e *model.ShipmentEvent) *pb.TrackingEvent {
+	event := &pb.TrackingEvent{
+		Status:         e.Status,
+		Leg:            e.Leg,
+		TrackingNumber: e.TrackingNumber,
+		Carrier:        e.Carrier,
+		OccurredAt:     timestamppb.New(e.OccurredAt),
+	}
+
+	if e.Description.Valid {
+		event.Description = e.Description.String
+	}
+	if e.Location.Valid {
+		event.Location = e.Location.String
+	}
+
+	return event
+}
+
+// Helper function to convert model.Authentication to pb.Authentication
+func authenticationToProto(a *model.Authentication) *pb.Authentication {
+	if a == nil {
+		return nil
+	}
+
+	auth := &pb.Authentication{
+		Id:              a.ID,
+		OrderId:         a.OrderID,
+		AuthenticatorId: a.AuthenticatorID,
+		Result:          a.Result,
+		PhotoUrls:       a.PhotoURLs,
+		CreatedAt:       timestamppb.New(a.CreatedAt),
+	}
+
+	if a.Notes.Valid {
+		auth.Notes = a.Notes.String
+	}
+
+	return auth
+}
+
+// Helper function to convert model.Dispute to pb.Dispute
+func disputeToProto(d *model.Dispute) *pb.Dispute {
+	if d == nil {
+		return nil
+	}
+
+	dispute := &pb.Dispute{
+		Id:          d.ID,
+		OrderId:     d.OrderID,
+		BuyerId:     d.BuyerID,
+		SellerId:    d.SellerID,
+		Reason:      d.Reason,
+		Description: d.Description,
+		Status:      d.Status,
+		PayoutHeld:  d.PayoutHeld,
+		CreatedAt:   timestamppb.New(d.CreatedAt),
+		UpdatedAt:   timestamppb.New(d.UpdatedAt),
+	}
+
+	if d.Outcome.Valid {
+		dispute.Outcome = d.Outcome.String
+	}
+	if d.RefundAmount != nil {
+		dispute.RefundAmount = d.RefundAmount.Major()
+		dispute.RefundAmountMinor = d.RefundAmount.Amount
+	}
+	if d.ResolvedBy.Valid {
+		dispute.ResolvedBy = d.ResolvedBy.Int64
+	}
+	if d.ResolvedAt.Valid {
+		dispute.ResolvedAt = timestamppb.New(d.ResolvedAt.Time)
+	}
+
+	return dispute
+}
+
+// Helper function to convert model.DisputeEvent to pb.DisputeEvent
+func disputeEventToProto(e *model.DisputeEvent) *pb.DisputeEvent {
+	event := &pb.DisputeEvent{
+		Id:           e.ID,
+		DisputeId:    e.DisputeID,
+		ActorRole:    e.ActorRole,
+		Action:       e.Action,
+		EvidenceUrls: e.EvidenceURLs,
+		CreatedAt:    timestamppb.New(e.CreatedAt),
+	}
+
+	if e.ActorID.Valid {
+		event.ActorId = e.ActorID.Int64
+	}
+	if e.Note.Valid {
+		event.Note = e.Note.String
+	}
+
+	return event
+}
+
+// Helper function to convert model.ExportJob to pb.ExportJob
+func exportJobToProto(j *model.ExportJob) *pb.ExportJob {
+	job := &pb.ExportJob{
+		Id:        j.ID,
+		SellerId:  j.SellerID,
+		Kind:      j.Kind,
+		Format:    j.Format,
+		Year:      int32(j.Year),
+		Status:    j.Status,
+		CreatedAt: timestamppb.New(j.CreatedAt),
+	}
+
+	if j.Filename.Valid {
+		job.Filename = j.Filename.String
+	}
+	if j.Error.Valid {
+		job.Error = j.Error.String
+	}
+	if j.CompletedAt.Valid {
+		job.CompletedAt = timestamppb.New(j.CompletedAt.Time)
+	}
+
+	return job
+}
+
+// Helper function to convert model.CartItem to pb.CartItem
+func cartItemToProto(i *model.CartItem) *pb.CartItem {
+	return &pb.CartItem{
+		Id:           i.ID,
+		ProductId:    i.ProductID,
+		SizeId:       i.SizeID,
+		Quantity:     i.Quantity,
+		PriceAtAdd:   i.PriceAtAdd.Major(),
+		CurrentPrice: i.CurrentPrice.Major(),
+		CreatedAt:    timestamppb.New(i.CreatedAt),
+
+		PriceAtAddMinor:   i.PriceAtAdd.Amount,
+		CurrentPriceMinor: i.CurrentPrice.Amount,
+
+		Currency:          i.PriceAtAdd.Currency,
+		DisplayPrice:      i.DisplayPrice.Major(),
+		DisplayPriceMinor: i.DisplayPrice.Amount,
+		DisplayCurrency:   i.DisplayPrice.Currency,
+	}
+}
+
+// Helper function to convert model.Checkout to pb.Checkout
+func checkoutToProto(c *model.Checkout) *pb.Checkout {
+	checkout := &pb.Checkout{
+		Id:               c.ID,
+		BuyerId:          c.BuyerID,
+		ItemCount:        c.ItemCount,
+		TotalAmount:      c.TotalAmount.Major(),
+		TotalAmountMinor: c.TotalAmount.Amount,
+		Currency:         c.TotalAmount.Currency,
+		CreatedAt:        timestamppb.New(c.CreatedAt),
+	}
+
+	if c.StripePaymentIntentID.Valid {
+		checkout.StripePaymentIntentId = c.StripePaymentIntentID.String
+	}
+
+	return checkout
+}
+
+// Helper function to convert model.OrderMessage to pb.OrderMessage
+func orderMessageToProto(m *model.OrderMessage) *pb.OrderMessage {
+	msg := &pb.OrderMessage{
+		Id:         m.ID,
+		OrderId:    m.OrderID,
+		SenderId:   m.SenderID,
+		SenderRole: m.SenderRole,
+		Body:       m.Body,
+		Redacted:   m.Redacted,
+		CreatedAt:  timestamppb.New(m.CreatedAt),
+	}
+
+	if m.OriginalBody.Valid {
+		msg.OriginalBody = m.OriginalBody.String
+	}
+
+	return msg
+}
+
 // CreateOrder creates a new order from a match
 func (h *OrderHandler) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
 	if req.MatchId == 0 {
@@ -120,14 +369,28 @@ func (h *OrderHandler) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 		}, nil
 	}
 
-	// TODO: Fetch match details from Bidding Service to get buyer, seller, product, etc.
-	// For now, we'll require these to be passed somehow or fetched
-	// This is a simplified version
+	// Zero means "use the default"
+	var shippingAddressID *int64
+	if req.ShippingAddressId != 0 {
+		shippingAddressID = &req.ShippingAddressId
+	}
+	var buyerFee, sellerFee *float64
+	if req.BuyerFeePercentage != 0 {
+		buyerFee = &req.BuyerFeePercentage
+	}
+	if req.SellerFeePercentage != 0 {
+		sellerFee = &req.SellerFeePercentage
+	}
+
+	order, err := h.service.CreateOrderForMatch(ctx, req.MatchId, shippingAddressID, req.BuyerNotes, buyerFee, sellerFee)
+	if err != nil {
+		return &pb.CreateOrderResponse{
+			Error: err.Error(),
+		}, nil
+	}
 
-	// Note: In a real implementation, we'd fetch match details here
-	// For now, return error asking for implementation
 	return &pb.CreateOrderResponse{
-		Error: "CreateOrder requires integration with Bidding Service to fetch match details",
+		Order: orderToProto(order),
 	}, nil
 }
 
@@ -146,8 +409,21 @@ func (h *OrderHandler) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*
 		}, nil
 	}
 
+	adjustments, err := h.service.GetAdjustments(ctx, order.ID)
+	if err != nil {
+		return &pb.GetOrderResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbAdjustments := make([]*pb.OrderAdjustment, len(adjustments))
+	for i, adj := range adjustments {
+		pbAdjustments[i] = adjustmentToProto(adj)
+	}
+
 	return &pb.GetOrderResponse{
-		Order: orderToProto(order),
+		Order:       orderToProto(order),
+		Adjustments: pbAdjustments,
 	}, nil
 }
 
@@ -198,10 +474,35 @@ func (h *OrderHandler) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrde
 
 	updatedBy := req.UpdatedBy
 	if updatedBy == "" {
-		updatedBy = "system"
+		updatedBy = string(lifecycle.RoleSystem)
 	}
 
-	order, err := h.service.UpdateOrderStatus(ctx, req.OrderId, req.NewStatus, updatedBy)
+	role, ok := lifecycle.ParseRole(updatedBy)
+	if !ok {
+		return &pb.UpdateOrderStatusResponse{
+			Error: "updated_by must be one of system, buyer, seller, authenticator, admin",
+		}, nil
+	}
+
+	actor := lifecycle.System()
+	if role != lifecycle.RoleSystem {
+		if req.ActorId == 0 {
+			return &pb.UpdateOrderStatusResponse{
+				Error: "actor_id is required",
+			}, nil
+		}
+
+		// The claimed role is only trusted as far as the user's account backs it
+		var err error
+		actor, err = h.service.ResolveActor(ctx, role, req.ActorId)
+		if err != nil {
+			return &pb.UpdateOrderStatusResponse{
+				Error: err.Error(),
+			}, nil
+		}
+	}
+
+	order, err := h.service.UpdateOrderStatus(ctx, req.OrderId, req.NewStatus, actor, req.Note)
 	if err != nil {
 		return &pb.UpdateOrderStatusResponse{
 			Error: err.Error(),
@@ -231,7 +532,26 @@ func (h *OrderHandler) CancelOrder(ctx context.Context, req *pb.CancelOrderReque
 		}, nil
 	}
 
-	order, err := h.service.CancelOrder(ctx, req.OrderId, req.CancelledByUserId, req.Reason)
+	var actor lifecycle.Actor
+	if req.CancelledByRole != "" {
+		role, ok := lifecycle.ParseRole(req.CancelledByRole)
+		if !ok || role == lifecycle.RoleSystem {
+			return &pb.CancelOrderResponse{
+				Error: "cancelled_by_role must be one of buyer, seller, admin",
+			}, nil
+		}
+		actor = lifecycle.Actor{Role: role, UserID: req.CancelledByUserId}
+	} else {
+		var err error
+		actor, err = h.service.PartyActor(ctx, req.OrderId, req.CancelledByUserId)
+		if err != nil {
+			return &pb.CancelOrderResponse{
+				Error: err.Error(),
+			}, nil
+		}
+	}
+
+	order, err := h.service.CancelOrder(ctx, req.OrderId, actor, req.Reason)
 	if err != nil {
 		return &pb.CancelOrderResponse{
 			Error: err.Error(),
@@ -243,6 +563,33 @@ func (h *OrderHandler) CancelOrder(ctx context.Context, req *pb.CancelOrderReque
 	}, nil
 }
 
+// ApplyRefund records a refund of the order's payment and closes fully
+// refunded orders (called by Payment Service)
+func (h *OrderHandler) ApplyRefund(ctx context.Context, req *pb.ApplyRefundRequest) (*pb.ApplyRefundResponse, error) {
+	if req.OrderId == 0 {
+		return &pb.ApplyRefundResponse{
+			Error: "order_id is required",
+		}, nil
+	}
+	if req.Reference == "" {
+		return &pb.ApplyRefundResponse{
+			Error: "reference is required",
+		}, nil
+	}
+
+	amount := money.New(req.AmountMinor, req.Currency)
+	order, err := h.service.ApplyRefund(ctx, req.OrderId, req.Reference, amount, req.Full, req.Reason)
+	if err != nil {
+		return &pb.ApplyRefundResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.ApplyRefundResponse{
+		Order: orderToProto(order),
+	}, nil
+}
+
 // AddTrackingNumber adds tracking information to an order
 func (h *OrderHandler) AddTrackingNumber(ctx context.Context, req *pb.AddTrackingNumberRequest) (*pb.AddTrackingNumberResponse, error) {
 	if req.OrderId == 0 {
@@ -286,7 +633,7 @@ func (h *OrderHandler) GetShippingStatus(ctx context.Context, req *pb.GetShippin
 		}, nil
 	}
 
-	order, err := h.service.GetShippingStatus(ctx, req.OrderId)
+	order, events, err := h.service.GetShippingStatus(ctx, req.OrderId)
 	if err != nil {
 		return &pb.GetShippingStatusResponse{
 			Error: err.Error(),
@@ -309,10 +656,149 @@ func (h *OrderHandler) GetShippingStatus(ctx context.Context, req *pb.GetShippin
 	if order.DeliveredAt.Valid {
 		response.DeliveredAt = timestamppb.New(order.DeliveredAt.Time)
 	}
+	if order.LabelURL.Valid {
+		response.LabelUrl = order.LabelURL.String
+	}
+	if order.OutboundTrackingNumber.Valid {
+		response.OutboundTrackingNumber = order.OutboundTrackingNumber.String
+	}
+	if order.OutboundCarrier.Valid {
+		response.OutboundCarrier = order.OutboundCarrier.String
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, shipmentEventToProto(event))
+	}
+	if len(events) > 0 {
+		response.TrackingStatus = events[len(events)-1].Status
+	}
 
 	return response, nil
 }
 
+// GetShippingRates quotes prepaid label options for the seller
+func (h *OrderHandler) GetShippingRates(ctx context.Context, req *pb.GetShippingRatesRequest) (*pb.GetShippingRatesResponse, error) {
+	if req.OrderId == 0 || req.SellerId == 0 {
+		return &pb.GetShippingRatesResponse{
+			Error: "order_id and seller_id are required",
+		}, nil
+	}
+
+	rates, err := h.service.GetShippingRates(ctx, req.OrderId, req.SellerId)
+	if err != nil {
+		return &pb.GetShippingRatesResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbRates := make([]*pb.ShippingRate, len(rates))
+	for i, rate := range rates {
+		pbRates[i] = &pb.ShippingRate{
+			Carrier:       rate.Carrier,
+			Service:       rate.Service,
+			Amount:        rate.Amount.Major(),
+			AmountMinor:   rate.Amount.Amount,
+			Currency:      rate.Amount.Currency,
+			EstimatedDays: int32(rate.EstimatedDays),
+		}
+	}
+
+	return &pb.GetShippingRatesResponse{
+		Rates: pbRates,
+	}, nil
+}
+
+// CreateShippingLabel buys a prepaid label and marks the order as shipped
+func (h *OrderHandler) CreateShippingLabel(ctx context.Context, req *pb.CreateShippingLabelRequest) (*pb.CreateShippingLabelResponse, error) {
+	if req.OrderId == 0 || req.SellerId == 0 {
+		return &pb.CreateShippingLabelResponse{
+			Error: "order_id and seller_id are required",
+		}, nil
+	}
+
+	order, label, err := h.service.CreateShippingLabel(ctx, req.OrderId, req.SellerId, req.Service)
+	if err != nil {
+		return &pb.CreateShippingLabelResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.CreateShippingLabelResponse{
+		Order:          orderToProto(order),
+		TrackingNumber: label.TrackingNumber,
+		Carrier:        label.Carrier,
+		Service:        label.Service,
+		LabelUrl:       label.LabelURL,
+		Cost:           label.Cost.Major(),
+		CostMinor:      label.Cost.Amount,
+		Currency:       label.Cost.Currency,
+	}, nil
+}
+
+// ShipToBuyer buys the label for sending an authenticated item to the buyer
+func (h *OrderHandler) ShipToBuyer(ctx context.Context, req *pb.ShipToBuyerRequest) (*pb.ShipToBuyerResponse, error) {
+	if req.OrderId == 0 || req.AuthenticatorId == 0 {
+		return &pb.ShipToBuyerResponse{
+			Error: "order_id and authenticator_id are required",
+		}, nil
+	}
+
+	order, label, err := h.service.ShipToBuyer(ctx, req.OrderId, req.AuthenticatorId)
+	if err != nil {
+		return &pb.ShipToBuyerResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.ShipToBuyerResponse{
+		Order:          orderToProto(order),
+		TrackingNumber: label.TrackingNumber,
+		Carrier:        label.Carrier,
+		LabelUrl:       label.LabelURL,
+	}, nil
+}
+
+// RecordTrackingEvent stores a carrier scan and updates the order status
+func (h *OrderHandler) RecordTrackingEvent(ctx context.Context, req *pb.RecordTrackingEventRequest) (*pb.RecordTrackingEventResponse, error) {
+	event := &carrier.WebhookEvent{
+		EventID:        req.EventId,
+		TrackingNumber: req.TrackingNumber,
+		Carrier:        req.Carrier,
+		Status:         req.Status,
+		Description:    req.Description,
+		Location:       req.Location,
+	}
+	if req.OccurredAt != nil {
+		event.OccurredAt = req.OccurredAt.AsTime()
+	}
+
+	if event.TrackingNumber == "" || event.Carrier == "" {
+		return &pb.RecordTrackingEventResponse{
+			Error: "tracking_number and carrier are required",
+		}, nil
+	}
+	if !carrier.IsTrackingStatus(event.Status) {
+		return &pb.RecordTrackingEventResponse{
+			Error: "unknown tracking status: " + event.Status,
+		}, nil
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	order, recorded, err := h.service.RecordTrackingEvent(ctx, event)
+	if err != nil {
+		return &pb.RecordTrackingEventResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.RecordTrackingEventResponse{
+		OrderId:     order.ID,
+		OrderStatus: order.Status,
+		Duplicate:   !recorded,
+	}, nil
+}
+
 // GetBuyerOrders retrieves orders for a buyer
 func (h *OrderHandler) GetBuyerOrders(ctx context.Context, req *pb.GetBuyerOrdersRequest) (*pb.GetBuyerOrdersResponse, error) {
 	if req.BuyerId == 0 {
@@ -385,6 +871,54 @@ func (h *OrderHandler) GetSellerOrders(ctx context.Context, req *pb.GetSellerOrd
 	}, nil
 }
 
+// SearchOrders searches orders with filters and cursor pagination
+func (h *OrderHandler) SearchOrders(ctx context.Context, req *pb.SearchOrdersRequest) (*pb.SearchOrdersResponse, error) {
+	search := &model.OrderSearch{
+		UserID:            req.UserId,
+		BuyerID:           req.BuyerId,
+		SellerID:          req.SellerId,
+		CounterpartyID:    req.CounterpartyId,
+		Statuses:          req.Statuses,
+		ProductID:         req.ProductId,
+		SKU:               req.Sku,
+		SizeID:            req.SizeId,
+		Size:              req.Size,
+		MinPrice:          money.FromProto(req.MinPriceMinor, req.MinPrice, money.DefaultCurrency),
+		MaxPrice:          money.FromProto(req.MaxPriceMinor, req.MaxPrice, money.DefaultCurrency),
+		OrderNumberPrefix: req.OrderNumberPrefix,
+		Carrier:           req.Carrier,
+		SortBy:            req.SortBy,
+		SortOrder:         req.SortOrder,
+		Cursor:            req.Cursor,
+		Limit:             req.Limit,
+	}
+	if req.CreatedFrom != nil {
+		t := req.CreatedFrom.AsTime()
+		search.CreatedFrom = &t
+	}
+	if req.CreatedTo != nil {
+		t := req.CreatedTo.AsTime()
+		search.CreatedTo = &t
+	}
+
+	orders, nextCursor, err := h.service.SearchOrders(ctx, search)
+	if err != nil {
+		return &pb.SearchOrdersResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbOrders := make([]*pb.Order, 0, len(orders))
+	for _, order := range orders {
+		pbOrders = append(pbOrders, orderToProto(order))
+	}
+
+	return &pb.SearchOrdersResponse{
+		Orders:     pbOrders,
+		NextCursor: nextCursor,
+	}, nil
+}
+
 // GetOrderStatusHistory retrieves the status history for an order
 func (h *OrderHandler) GetOrderStatusHistory(ctx context.Context, req *pb.GetOrderStatusHistoryRequest) (*pb.GetOrderStatusHistoryResponse, error) {
 	if req.OrderId == 0 {
@@ -409,3 +943,430 @@ func (h *OrderHandler) GetOrderStatusHistory(ctx context.Context, req *pb.GetOrd
 		History: pbHistory,
 	}, nil
 }
+
+// ReceiveForAuthentication records that the item arrived for authentication
+func (h *OrderHandler) ReceiveForAuthentication(ctx context.Context, req *pb.ReceiveForAuthenticationRequest) (*pb.ReceiveForAuthenticationResponse, error) {
+	if req.OrderId == 0 {
+		return &pb.ReceiveForAuthenticationResponse{
+			Error: "order_id is required",
+		}, nil
+	}
+	if req.AuthenticatorId == 0 {
+		return &pb.ReceiveForAuthenticationResponse{
+			Error: "authenticator_id is required",
+		}, nil
+	}
+
+	order, err := h.service.ReceiveForAuthentication(ctx, req.OrderId, req.AuthenticatorId, req.Notes)
+	if err != nil {
+		return &pb.ReceiveForAuthenticationResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.ReceiveForAuthenticationResponse{
+		Order: orderToProto(order),
+	}, nil
+}
+
+// RecordAuthenticationResult records whether the item passed authentication
+func (h *OrderHandler) RecordAuthenticationResult(ctx context.Context, req *pb.RecordAuthenticationResultRequest) (*pb.RecordAuthenticationResultResponse, error) {
+	if req.OrderId == 0 {
+		return &pb.RecordAuthenticationResultResponse{
+			Error: "order_id is required",
+		}, nil
+	}
+	if req.AuthenticatorId == 0 {
+		return &pb.RecordAuthenticationResultResponse{
+			Error: "authenticator_id is required",
+		}, nil
+	}
+	if !req.Passed && req.Notes == "" {
+		return &pb.RecordAuthenticationResultResponse{
+			Error: "notes are required when authentication fails",
+		}, nil
+	}
+
+	order, err := h.service.RecordAuthenticationResult(ctx, req.OrderId, req.AuthenticatorId, req.Passed, req.Notes, req.PhotoUrls)
+	if err != nil {
+		return &pb.RecordAuthenticationResultResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.RecordAuthenticationResultResponse{
+		Order: orderToProto(order),
+	}, nil
+}
+
+// GetOrderAuthentications retrieves the authentication records for an order
+func (h *OrderHandler) GetOrderAuthentications(ctx context.Context, req *pb.GetOrderAuthenticationsRequest) (*pb.GetOrderAuthenticationsResponse, error) {
+	if req.OrderId == 0 {
+		return &pb.GetOrderAuthenticationsResponse{
+			Error: "order_id is required",
+		}, nil
+	}
+
+	authentications, err := h.service.GetAuthentications(ctx, req.OrderId)
+	if err != nil {
+		return &pb.GetOrderAuthenticationsResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbAuthentications := make([]*pb.Authentication, len(authentications))
+	for i, a := range authentications {
+		pbAuthentications[i] = authenticationToProto(a)
+	}
+
+	return &pb.GetOrderAuthenticationsResponse{
+		Authentications: pbAuthentications,
+	}, nil
+}
+
+// OpenDispute opens a buyer's case against a delivered order
+func (h *OrderHandler) OpenDispute(ctx context.Context, req *pb.OpenDisputeRequest) (*pb.OpenDisputeResponse, error) {
+	if req.OrderId == 0 || req.BuyerId == 0 {
+		return &pb.OpenDisputeResponse{
+			Error: "order_id and buyer_id are required",
+		}, nil
+	}
+
+	dispute, err := h.service.OpenDispute(ctx, req.OrderId, req.BuyerId, req.Reason, req.Description, req.EvidenceUrls)
+	if err != nil {
+		return &pb.OpenDisputeResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.OpenDisputeResponse{
+		Dispute: disputeToProto(dispute),
+	}, nil
+}
+
+// RespondToDispute records the seller's response
+func (h *OrderHandler) RespondToDispute(ctx context.Context, req *pb.RespondToDisputeRequest) (*pb.RespondToDisputeResponse, error) {
+	if req.DisputeId == 0 || req.SellerId == 0 {
+		return &pb.RespondToDisputeResponse{
+			Error: "dispute_id and seller_id are required",
+		}, nil
+	}
+
+	dispute, err := h.service.RespondToDispute(ctx, req.DisputeId, req.SellerId, req.Response, req.EvidenceUrls)
+	if err != nil {
+		return &pb.RespondToDisputeResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.RespondToDisputeResponse{
+		Dispute: disputeToProto(dispute),
+	}, nil
+}
+
+// ResolveDispute records an admin's decision
+func (h *OrderHandler) ResolveDispute(ctx context.Context, req *pb.ResolveDisputeRequest) (*pb.ResolveDisputeResponse, error) {
+	if req.DisputeId == 0 || req.AdminId == 0 {
+		return &pb.ResolveDisputeResponse{
+			Error: "dispute_id and admin_id are required",
+		}, nil
+	}
+
+	dispute, err := h.service.ResolveDispute(ctx, req.DisputeId, req.AdminId, req.Outcome, money.FromProto(req.RefundAmountMinor, req.RefundAmount, money.DefaultCurrency), req.Note)
+	if err != nil {
+		return &pb.ResolveDisputeResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.ResolveDisputeResponse{
+		Dispute: disputeToProto(dispute),
+	}, nil
+}
+
+// GetDispute retrieves a dispute and its timeline
+func (h *OrderHandler) GetDispute(ctx context.Context, req *pb.GetDisputeRequest) (*pb.GetDisputeResponse, error) {
+	if req.DisputeId == 0 {
+		return &pb.GetDisputeResponse{
+			Error: "dispute_id is required",
+		}, nil
+	}
+
+	dispute, events, err := h.service.GetDispute(ctx, req.DisputeId)
+	if err != nil {
+		return &pb.GetDisputeResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbEvents := make([]*pb.DisputeEvent, len(events))
+	for i, e := range events {
+		pbEvents[i] = disputeEventToProto(e)
+	}
+
+	return &pb.GetDisputeResponse{
+		Dispute: disputeToProto(dispute),
+		Events:  pbEvents,
+	}, nil
+}
+
+// ListDisputes retrieves disputes for the admin queue
+func (h *OrderHandler) ListDisputes(ctx context.Context, req *pb.ListDisputesRequest) (*pb.ListDisputesResponse, error) {
+	disputes, total, err := h.service.ListDisputes(ctx, req.Status, req.Page, req.PageSize)
+	if err != nil {
+		return &pb.ListDisputesResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbDisputes := make([]*pb.Dispute, len(disputes))
+	for i, d := range disputes {
+		pbDisputes[i] = disputeToProto(d)
+	}
+
+	return &pb.ListDisputesResponse{
+		Disputes: pbDisputes,
+		Total:    total,
+	}, nil
+}
+
+// GetInvoice renders the buyer's receipt or the seller's sale statement
+func (h *OrderHandler) GetInvoice(ctx context.Context, req *pb.GetInvoiceRequest) (*pb.GetInvoiceResponse, error) {
+	if req.OrderId == 0 || req.UserId == 0 {
+		return &pb.GetInvoiceResponse{
+			Error: "order_id and user_id are required",
+		}, nil
+	}
+
+	doc, err := h.invoices.GetInvoice(ctx, req.OrderId, req.UserId, req.Format)
+	if err != nil {
+		return &pb.GetInvoiceResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.GetInvoiceResponse{
+		InvoiceNumber: doc.Number,
+		ContentType:   doc.ContentType,
+		Filename:      doc.Filename,
+		Content:       doc.Content,
+	}, nil
+}
+
+// RequestSalesExport queues a sales export or tax summary for a seller
+func (h *OrderHandler) RequestSalesExport(ctx context.Context, req *pb.RequestSalesExportRequest) (*pb.RequestSalesExportResponse, error) {
+	if req.SellerId == 0 {
+		return &pb.RequestSalesExportResponse{
+			Error: "seller_id is required",
+		}, nil
+	}
+
+	job, err := h.exports.RequestExport(ctx, req.SellerId, req.Kind, req.Format, int(req.Year))
+	if err != nil {
+		return &pb.RequestSalesExportResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.RequestSalesExportResponse{
+		Job: exportJobToProto(job),
+	}, nil
+}
+
+// GetSalesExport retrieves an export job and, when ready, its file
+func (h *OrderHandler) GetSalesExport(ctx context.Context, req *pb.GetSalesExportRequest) (*pb.GetSalesExportResponse, error) {
+	if req.JobId == 0 || req.SellerId == 0 {
+		return &pb.GetSalesExportResponse{
+			Error: "job_id and seller_id are required",
+		}, nil
+	}
+
+	job, err := h.exports.GetExport(ctx, req.JobId, req.SellerId, req.IncludeContent)
+	if err != nil {
+		return &pb.GetSalesExportResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	resp := &pb.GetSalesExportResponse{
+		Job:     exportJobToProto(job),
+		Content: job.Content,
+	}
+	if job.ContentType.Valid {
+		resp.ContentType = job.ContentType.String
+	}
+
+	return resp, nil
+}
+
+// AddToCart adds a product/size at its lowest ask to the buyer's cart
+func (h *OrderHandler) AddToCart(ctx context.Context, req *pb.AddToCartRequest) (*pb.AddToCartResponse, error) {
+	if req.BuyerId == 0 || req.ProductId == 0 || req.SizeId == 0 {
+		return &pb.AddToCartResponse{
+			Error: "buyer_id, product_id and size_id are required",
+		}, nil
+	}
+
+	item, err := h.service.AddToCart(ctx, req.BuyerId, req.ProductId, req.SizeId, req.Quantity)
+	if err != nil {
+		return &pb.AddToCartResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.AddToCartResponse{
+		Item: cartItemToProto(item),
+	}, nil
+}
+
+// RemoveFromCart removes an item from the buyer's cart
+func (h *OrderHandler) RemoveFromCart(ctx context.Context, req *pb.RemoveFromCartRequest) (*pb.RemoveFromCartResponse, error) {
+	if req.BuyerId == 0 || req.ItemId == 0 {
+		return &pb.RemoveFromCartResponse{
+			Error: "buyer_id and item_id are required",
+		}, nil
+	}
+
+	if err := h.service.RemoveFromCart(ctx, req.BuyerId, req.ItemId); err != nil {
+		return &pb.RemoveFromCartResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.RemoveFromCartResponse{
+		Success: true,
+	}, nil
+}
+
+// GetCart retrieves the buyer's cart with current prices
+func (h *OrderHandler) GetCart(ctx context.Context, req *pb.GetCartRequest) (*pb.GetCartResponse, error) {
+	if req.BuyerId == 0 {
+		return &pb.GetCartResponse{
+			Error: "buyer_id is required",
+		}, nil
+	}
+
+	items, err := h.service.GetCart(ctx, req.BuyerId, req.Currency)
+	if err != nil {
+		return &pb.GetCartResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbItems := make([]*pb.CartItem, 0, len(items))
+	for _, item := range items {
+		pbItems = append(pbItems, cartItemToProto(item))
+	}
+
+	return &pb.GetCartResponse{
+		Items: pbItems,
+	}, nil
+}
+
+// Checkout buys the whole cart with one payment
+func (h *OrderHandler) Checkout(ctx context.Context, req *pb.CheckoutRequest) (*pb.CheckoutResponse, error) {
+	if req.BuyerId == 0 {
+		return &pb.CheckoutResponse{
+			Error: "buyer_id is required",
+		}, nil
+	}
+
+	var shippingAddressID *int64
+	if req.ShippingAddressId != 0 {
+		shippingAddressID = &req.ShippingAddressId
+	}
+
+	result, err := h.service.Checkout(ctx, req.BuyerId, shippingAddressID, req.StripeCustomerId, req.Currency)
+	if err != nil {
+		return &pb.CheckoutResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbOrders := make([]*pb.Order, 0, len(result.Orders))
+	for _, order := range result.Orders {
+		pbOrders = append(pbOrders, orderToProto(order))
+	}
+
+	return &pb.CheckoutResponse{
+		Checkout:     checkoutToProto(result.Checkout),
+		Orders:       pbOrders,
+		ClientSecret: result.ClientSecret,
+	}, nil
+}
+
+// GetCheckout retrieves a checkout and its orders
+func (h *OrderHandler) GetCheckout(ctx context.Context, req *pb.GetCheckoutRequest) (*pb.GetCheckoutResponse, error) {
+	if req.CheckoutId == 0 || req.BuyerId == 0 {
+		return &pb.GetCheckoutResponse{
+			Error: "checkout_id and buyer_id are required",
+		}, nil
+	}
+
+	checkout, orders, err := h.service.GetCheckout(ctx, req.CheckoutId, req.BuyerId)
+	if err != nil {
+		return &pb.GetCheckoutResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbOrders := make([]*pb.Order, 0, len(orders))
+	for _, order := range orders {
+		pbOrders = append(pbOrders, orderToProto(order))
+	}
+
+	return &pb.GetCheckoutResponse{
+		Checkout: checkoutToProto(checkout),
+		Orders:   pbOrders,
+	}, nil
+}
+
+// SendOrderMessage posts a message to an order's thread
+func (h *OrderHandler) SendOrderMessage(ctx context.Context, req *pb.SendOrderMessageRequest) (*pb.SendOrderMessageResponse, error) {
+	if req.OrderId == 0 || req.SenderId == 0 {
+		return &pb.SendOrderMessageResponse{
+			Error: "order_id and sender_id are required",
+		}, nil
+	}
+
+	msg, recipients, err := h.service.SendOrderMessage(ctx, req.OrderId, req.SenderId, req.Body)
+	if err != nil {
+		return &pb.SendOrderMessageResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	// The sender's copy never includes the original text
+	pbMsg := orderMessageToProto(msg)
+	pbMsg.OriginalBody = ""
+
+	return &pb.SendOrderMessageResponse{
+		Message:      pbMsg,
+		RecipientIds: recipients,
+	}, nil
+}
+
+// GetOrderMessages retrieves an order's thread
+func (h *OrderHandler) GetOrderMessages(ctx context.Context, req *pb.GetOrderMessagesRequest) (*pb.GetOrderMessagesResponse, error) {
+	if req.OrderId == 0 || req.UserId == 0 {
+		return &pb.GetOrderMessagesResponse{
+			Error: "order_id and user_id are required",
+		}, nil
+	}
+
+	messages, err := h.service.GetOrderMessages(ctx, req.OrderId, req.UserId, req.AfterId)
+	if err != nil {
+		return &pb.GetOrderMessagesResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	pbMessages := make([]*pb.OrderMessage, 0, len(messages))
+	for _, msg := range messages {
+		pbMessages = append(pbMessages, orderMessageToProto(msg))
+	}
+
+	return &pb.GetOrderMessagesResponse{
+		Messages: pbMessages,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money": formatMoney,
+	"percent": func(rate float64) string {
+		return fmt.Sprintf("%g%%", rate*100)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Kind.Title}} {{.Number}}</title>
+<style>
+  body { font-family: Helvetica, Arial, sans-serif; color: #111; max-width: 720px; margin: 40px auto; }
+  h1 { font-size: 24px; margin-bottom: 4px; }
+  .meta, .party { font-size: 13px; color: #444; }
+  .parties { display: flex; justify-content: space-between; margin: 24px 0; }
+  table { width: 100%; border-collapse: collapse; font-size: 14px; }
+  td { padding: 8px 0; border-bottom: 1px solid #eee; }
+  td.amount { text-align: right; white-space: nowrap; }
+  tr.total td { font-weight: bold; border-top: 2px solid #111; border-bottom: none; }
+  .tax { font-size: 12px; color: #666; margin-top: 8px; }
+</style>
+</head>
+<body>
+<h1>{{.Kind.Title}}</h1>
+<div class="meta">
+  No. {{.Number}} &middot; Issued {{.IssuedAt.Format "January 2, 2006"}}<br>
+  Order {{.OrderNumber}} &middot; {{.OrderDate.Format "January 2, 2006"}}
+</div>
+<div class="parties">
+  <div class="party">
+    <strong>{{.Issuer.Name}}</strong><br>
+    {{range .Issuer.AddressLines}}{{.}}<br>{{end}}
+    {{if .Issuer.TaxID}}Tax ID: {{.Issuer.TaxID}}{{end}}
+  </div>
+  <div class="party">
+    <strong>{{.Recipient.Name}}</strong><br>
+    {{if .Recipient.Email}}{{.Recipient.Email}}<br>{{end}}
+    {{range .Recipient.AddressLines}}{{.}}<br>{{end}}
+    {{if .Recipient.TaxID}}Tax ID: {{.Recipient.TaxID}}{{end}}
+  </div>
+</div>
+<table>
+  {{range .Lines}}
+  <tr><td>{{.Description}}</td><td class="amount">{{money .Amount $.Currency}}</td></tr>
+  {{end}}
+  <tr class="total"><td>{{.TotalLabel}}</td><td class="amount">{{money .Total .Currency}}</td></tr>
+</table>
+{{if .Tax.Amount.IsPositive}}
+<p class="tax">Fees include {{.Tax.Label}} at {{percent .Tax.Rate}}: {{money .Tax.Amount .Currency}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML renders the invoice as a standalone HTML page
+func RenderHTML(inv *Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, inv); err != nil {
+		return nil, fmt.Errorf("failed to render invoice: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package invoice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Kind is the type of document issued for an order
+type Kind string
+
+// Invoice kinds
+const (
+	KindBuyerReceipt    Kind = "buyer_receipt"
+	KindSellerStatement Kind = "seller_statement"
+)
+
+// Title returns the document heading
+func (k Kind) Title() string {
+	if k == KindSellerStatement {
+		return "Sale Statement"
+	}
+	return "Receipt"
+}
+
+// Party is the issuer or recipient of a document
+type Party struct {
+	Name         string
+	Email        string
+	AddressLines []string
+	TaxID        string
+}
+
+// Line is a single amount on the document. Negative amounts are deductions.
+type Line struct {
+	Description string
+	Amount      money.Money
+}
+
+// Tax is the tax contained in the platform fees
+type Tax struct {
+	Label  string  // e.g. "VAT"
+	Rate   float64 // e.g. 0.2 for 20%
+	Amount money.Money
+}
+
+// Invoice is a rendered-ready receipt or sale statement
+type Invoice struct {
+	Number      string
+	Kind        Kind
+	IssuedAt    time.Time
+	OrderNumber string
+	OrderDate   time.Time
+	Item        string // product and size
+	Currency    string
+	Issuer      Party
+	Recipient   Party
+	Lines       []Line
+	Tax         Tax
+	TotalLabel  string // "Total paid" or "Payout"
+	Total       money.Money
+}
+
+// Order holds the order amounts an invoice is built from
+type Order struct {
+	Number    string
+	CreatedAt time.Time
+	Item      string
+	Price     money.Money
+	Quantity  int32
+
+	// Buyer side
+	BuyerProcessingFee money.Money
+	BuyerShippingFee   money.Money
+	BuyerTotal         money.Money
+
+	// Seller side
+	SellerTransactionFee    money.Money
+	SellerAuthenticationFee money.Money
+	SellerShippingCost      money.Money
+	SellerPayout            money.Money
+}
+
+// TaxPolicy describes the tax included in platform fees
+type TaxPolicy struct {
+	Label string
+	Rate  float64
+}
+
+// BuyerReceipt builds the buyer's receipt: item price plus buyer fees
+func BuyerReceipt(number string, issuedAt time.Time, o Order, issuer, buyer Party, tax TaxPolicy) *Invoice {
+	inv := newInvoice(number, KindBuyerReceipt, issuedAt, o, issuer, buyer)
+
+	inv.Lines = append(inv.Lines, Line{Description: itemLine(o), Amount: o.Price})
+	fees := addFee(inv, "Processing fee", o.BuyerProcessingFee, false)
+	fees = fees.Add(addFee(inv, "Shipping", o.BuyerShippingFee, false))
+
+	inv.Tax = includedTax(fees, tax)
+	inv.TotalLabel = "Total paid"
+	inv.Total = o.BuyerTotal
+
+	return inv
+}
+
+// SellerStatement builds the seller's statement: item price less seller fees
+func SellerStatement(number string, issuedAt time.Time, o Order, issuer, seller Party, tax TaxPolicy) *Invoice {
+	inv := newInvoice(number, KindSellerStatement, issuedAt, o, issuer, seller)
+
+	inv.Lines = append(inv.Lines, Line{Description: itemLine(o), Amount: o.Price})
+	fees := addFee(inv, "Transaction fee", o.SellerTransactionFee, true)
+	fees = fees.Add(addFee(inv, "Authentication fee", o.SellerAuthenticationFee, true))
+	fees = fees.Add(addFee(inv, "Shipping", o.SellerShippingCost, true))
+
+	inv.Tax = includedTax(fees, tax)
+	inv.TotalLabel = "Payout"
+	inv.Total = o.SellerPayout
+
+	return inv
+}
+
+// Filename returns the download name for the given extension ("pdf", "html")
+func (inv *Invoice) Filename(ext string) string {
+	return fmt.Sprintf("%s.%s", inv.Number, ext)
+}
+
+func newInvoice(number string, kind Kind, issuedAt time.Time, o Order, issuer, recipient Party) *Invoice {
+	return &Invoice{
+		Number:      number,
+		Kind:        kind,
+		IssuedAt:    issuedAt,
+		OrderNumber: o.Number,
+		OrderDate:   o.CreatedAt,
+		Item:        o.Item,
+		Currency:    currencyOf(o.Price),
+		Issuer:      issuer,
+		Recipient:   recipient,
+	}
+}
+
+func itemLine(o Order) string {
+	if o.Quantity > 1 {
+		return fmt.Sprintf("%s (x%d)", o.Item, o.Quantity)
+	}
+	return o.Item
+}
+
+// addFee appends a non-zero fee line, negated for deductions, and returns
+// the fee
+func addFee(inv *Invoice, description string, amount money.Money, deduct bool) money.Money {
+	if amount.IsZero() {
+		return amount
+	}
+
+	line := amount
+	if deduct {
+		line = amount.Neg()
+	}
+	inv.Lines = append(inv.Lines, Line{Description: description, Amount: line})
+	return amount
+}
+
+// includedTax extracts the tax contained in tax-inclusive fees:
+// fees * rate / (1 + rate), rounded half up to the cent
+func includedTax(fees money.Money, tax TaxPolicy) Tax {
+	if tax.Rate <= 0 || !fees.IsPositive() {
+		return Tax{Label: tax.Label, Rate: tax.Rate, Amount: money.Zero(fees.Currency)}
+	}
+
+	bps := money.BasisPoints(tax.Rate * 100)
+	return Tax{
+		Label:  tax.Label,
+		Rate:   tax.Rate,
+		Amount: fees.MulRat(bps, 10000+bps, money.HalfUp),
+	}
+}
+
+func currencyOf(m money.Money) string {
+	if m.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return m.Currency
+}
+
+// formatMoney formats an amount as "$1,234.56" or "-$12.00"
+func formatMoney(amount money.Money, currency string) string {
+	sign := ""
+	if amount.IsNegative() {
+		sign = "-"
+		amount = amount.Neg()
+	}
+
+	whole, frac, hasFrac := strings.Cut(amount.String(), ".")
+
+	grouped := ""
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped += ","
+		}
+		grouped += string(d)
+	}
+	if hasFrac {
+		grouped += "." + frac
+	}
+
+	symbol := currency + " "
+	if currency == "USD" {
+		symbol = "$"
+	}
+
+	return sign + symbol + grouped
+}
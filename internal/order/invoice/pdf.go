@@ -0,0 +1,173 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Letter size in points
+const (
+	pageWidth    = 612.0
+	pageHeight   = 792.0
+	marginLeft   = 56.0
+	marginRight  = 56.0
+	amountColumn = pageWidth - marginRight
+)
+
+// RenderPDF renders the invoice as a single-page PDF using the standard
+// Helvetica fonts, so no font files need to be embedded
+func RenderPDF(inv *Invoice) ([]byte, error) {
+	c := &pdfContent{y: pageHeight - 64}
+
+	c.text("F2", 22, marginLeft, inv.Kind.Title())
+	c.advance(26)
+	c.text("F1", 10, marginLeft, fmt.Sprintf("No. %s  -  Issued %s", inv.Number, inv.IssuedAt.Format("January 2, 2006")))
+	c.advance(14)
+	c.text("F1", 10, marginLeft, fmt.Sprintf("Order %s  -  %s", inv.OrderNumber, inv.OrderDate.Format("January 2, 2006")))
+	c.advance(30)
+
+	issuer, recipient := partyLines(inv.Issuer), partyLines(inv.Recipient)
+	rows := len(issuer)
+	if len(recipient) > rows {
+		rows = len(recipient)
+	}
+	for i := 0; i < rows; i++ {
+		font := "F1"
+		if i == 0 {
+			font = "F2"
+		}
+		if i < len(issuer) {
+			c.text(font, 10, marginLeft, issuer[i])
+		}
+		if i < len(recipient) {
+			c.text(font, 10, pageWidth/2+20, recipient[i])
+		}
+		c.advance(14)
+	}
+	c.advance(20)
+
+	for _, line := range inv.Lines {
+		c.text("F1", 11, marginLeft, line.Description)
+		c.textRight("F1", 11, amountColumn, formatMoney(line.Amount, inv.Currency))
+		c.advance(8)
+		c.rule(0.5)
+		c.advance(14)
+	}
+
+	c.advance(2)
+	c.rule(1.5)
+	c.advance(16)
+	c.text("F2", 12, marginLeft, inv.TotalLabel)
+	c.textRight("F2", 12, amountColumn, formatMoney(inv.Total, inv.Currency))
+	c.advance(24)
+
+	if inv.Tax.Amount.IsPositive() {
+		c.text("F1", 9, marginLeft, fmt.Sprintf("Fees include %s at %g%%: %s",
+			inv.Tax.Label, inv.Tax.Rate*100, formatMoney(inv.Tax.Amount, inv.Currency)))
+	}
+
+	return buildPDF(c.buf.Bytes()), nil
+}
+
+func partyLines(p Party) []string {
+	lines := []string{p.Name}
+	if p.Email != "" {
+		lines = append(lines, p.Email)
+	}
+	lines = append(lines, p.AddressLines...)
+	if p.TaxID != "" {
+		lines = append(lines, "Tax ID: "+p.TaxID)
+	}
+	return lines
+}
+
+// pdfContent builds a page content stream top to bottom
+type pdfContent struct {
+	buf bytes.Buffer
+	y   float64
+}
+
+func (c *pdfContent) advance(dy float64) {
+	c.y -= dy
+}
+
+func (c *pdfContent) text(font string, size, x float64, s string) {
+	fmt.Fprintf(&c.buf, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, c.y, escapePDF(s))
+}
+
+// textRight draws s ending at x. Widths are estimated from Helvetica's
+// average glyph width, which is close enough for short amounts.
+func (c *pdfContent) textRight(font string, size, x float64, s string) {
+	width := 0.0
+	for _, r := range s {
+		width += glyphWidth(r) * size / 1000
+	}
+	c.text(font, size, x-width, s)
+}
+
+func (c *pdfContent) rule(width float64) {
+	fmt.Fprintf(&c.buf, "%.1f w %.2f %.2f m %.2f %.2f l S\n", width, marginLeft, c.y, amountColumn, c.y)
+}
+
+// glyphWidth returns Helvetica advance widths (1/1000 em) for the characters
+// used in amounts; other characters use an average width
+func glyphWidth(r rune) float64 {
+	switch {
+	case r >= '0' && r <= '9', r == '$':
+		return 556
+	case r == ',' || r == '.' || r == ' ':
+		return 278
+	case r == '-':
+		return 333
+	}
+	return 600
+}
+
+// escapePDF escapes a string literal and replaces characters outside
+// printable ASCII, which the standard fonts cannot show
+func escapePDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// buildPDF wraps a content stream in a minimal one-page document
+func buildPDF(content []byte) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
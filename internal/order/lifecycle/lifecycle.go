@@ -0,0 +1,346 @@
+package lifecycle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// Role identifies the kind of party triggering an order transition
+type Role string
+
+// Actor roles
+const (
+	RoleBuyer         Role = "buyer"
+	RoleSeller        Role = "seller"
+	RoleAuthenticator Role = "authenticator"
+	RoleAdmin         Role = "admin"
+	RoleSystem        Role = "system"
+)
+
+// ParseRole converts a string into a known role
+func ParseRole(s string) (Role, bool) {
+	switch Role(s) {
+	case RoleBuyer, RoleSeller, RoleAuthenticator, RoleAdmin, RoleSystem:
+		return Role(s), true
+	}
+	return "", false
+}
+
+// Actor is the party triggering a transition
+type Actor struct {
+	Role   Role
+	UserID int64 // 0 for system
+}
+
+// System returns the actor used by background jobs and other services
+func System() Actor {
+	return Actor{Role: RoleSystem}
+}
+
+// String formats the actor for the audit trail (e.g. "seller_12", "system")
+func (a Actor) String() string {
+	if a.UserID == 0 {
+		return string(a.Role)
+	}
+	return fmt.Sprintf("%s_%d", a.Role, a.UserID)
+}
+
+// Change describes a requested status change
+type Change struct {
+	Order *model.Order // order as it was before the transition
+	From  string
+	To    string
+	Actor Actor
+	Note  string // free-form note, used as the reason for cancellations
+}
+
+// Guard rejects a transition by returning an error
+type Guard func(ctx context.Context, c *Change) error
+
+// Hook runs a side effect after a transition has been committed. Hooks are
+// retried until they succeed, so they must be safe to run more than once.
+type Hook func(ctx context.Context, c *Change) error
+
+// Transition declares an allowed status change and who may trigger it
+type Transition struct {
+	From   string
+	To     string
+	Actors []Role
+	Guards []Guard
+}
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+// Machine validates order transitions and runs their hooks
+type Machine struct {
+	transitions map[string]map[string]Transition
+	hooks       map[string][]namedHook
+}
+
+// NewMachine creates a state machine from a transition table
+func NewMachine(transitions []Transition) *Machine {
+	m := &Machine{
+		transitions: make(map[string]map[string]Transition),
+		hooks:       make(map[string][]namedHook),
+	}
+
+	for _, t := range transitions {
+		if m.transitions[t.From] == nil {
+			m.transitions[t.From] = make(map[string]Transition)
+		}
+		m.transitions[t.From][t.To] = t
+	}
+
+	return m
+}
+
+// DefaultTransitions returns the marketplace order lifecycle
+//
+//	pending_payment -> paid -> processing -> shipped -> received_at_authentication
+//	  -> authenticated -> delivered -> completed
+//
+// with cancellation and refund branches. Buyers can back out until the
+// seller starts working on the order, sellers until it ships; later
+// cancellations go through support, or follow a full refund support issued
+// while the item is on its way. An item that fails authentication
+// ends in failed_authentication (buyer refunded, item returned). Disputes
+// decided for the buyer move delivered or completed orders to refunded.
+func DefaultTransitions() []Transition {
+	return []Transition{
+		{From: model.StatusPendingPayment, To: model.StatusPaid, Actors: []Role{RoleSystem, RoleAdmin}},
+		{From: model.StatusPendingPayment, To: model.StatusCancelled, Actors: []Role{RoleBuyer, RoleSeller, RoleAdmin, RoleSystem}, Guards: []Guard{RequireNote}},
+
+		{From: model.StatusPaid, To: model.StatusProcessing, Actors: []Role{RoleSeller, RoleAdmin}},
+		{From: model.StatusPaid, To: model.StatusShipped, Actors: []Role{RoleSeller, RoleAdmin, RoleSystem}, Guards: []Guard{RequireTracking}},
+		{From: model.StatusPaid, To: model.StatusRefunded, Actors: []Role{RoleAdmin, RoleSystem}},
+		{From: model.StatusPaid, To: model.StatusCancelled, Actors: []Role{RoleBuyer, RoleSeller, RoleAdmin, RoleSystem}, Guards: []Guard{RequireNote}},
+
+		{From: model.StatusProcessing, To: model.StatusShipped, Actors: []Role{RoleSeller, RoleAdmin, RoleSystem}, Guards: []Guard{RequireTracking}},
+		{From: model.StatusProcessing, To: model.StatusCancelled, Actors: []Role{RoleSeller, RoleAdmin, RoleSystem}, Guards: []Guard{RequireNote}},
+
+		{From: model.StatusShipped, To: model.StatusReceivedAtAuthentication, Actors: []Role{RoleAuthenticator, RoleAdmin, RoleSystem}},
+		{From: model.StatusShipped, To: model.StatusCancelled, Actors: []Role{RoleAdmin, RoleSystem}, Guards: []Guard{RequireNote}},
+
+		{From: model.StatusReceivedAtAuthentication, To: model.StatusAuthenticated, Actors: []Role{RoleAuthenticator, RoleAdmin}},
+		{From: model.StatusReceivedAtAuthentication, To: model.StatusFailedAuthentication, Actors: []Role{RoleAuthenticator, RoleAdmin}, Guards: []Guard{RequireNote}},
+		{From: model.StatusReceivedAtAuthentication, To: model.StatusCancelled, Actors: []Role{RoleAdmin, RoleSystem}, Guards: []Guard{RequireNote}},
+
+		{From: model.StatusAuthenticated, To: model.StatusDelivered, Actors: []Role{RoleSystem, RoleAdmin}},
+		{From: model.StatusAuthenticated, To: model.StatusCancelled, Actors: []Role{RoleAdmin, RoleSystem}, Guards: []Guard{RequireNote}},
+
+		{From: model.StatusFailedAuthentication, To: model.StatusRefunded, Actors: []Role{RoleAdmin, RoleSystem}},
+
+		{From: model.StatusDelivered, To: model.StatusCompleted, Actors: []Role{RoleBuyer, RoleSystem, RoleAdmin}},
+		{From: model.StatusDelivered, To: model.StatusCancelled, Actors: []Role{RoleAdmin}, Guards: []Guard{RequireNote}},
+		{From: model.StatusDelivered, To: model.StatusRefunded, Actors: []Role{RoleAdmin, RoleSystem}},
+
+		{From: model.StatusCompleted, To: model.StatusRefunded, Actors: []Role{RoleAdmin, RoleSystem}},
+	}
+}
+
+// RequireNote rejects transitions without a note (cancellation reason)
+func RequireNote(ctx context.Context, c *Change) error {
+	if c.Note == "" {
+		return fmt.Errorf("a reason is required to move order to %s", c.To)
+	}
+	return nil
+}
+
+// RequireTracking rejects shipping an order without tracking information
+func RequireTracking(ctx context.Context, c *Change) error {
+	if !c.Order.TrackingNumber.Valid || c.Order.TrackingNumber.String == "" {
+		return fmt.Errorf("tracking number is required to ship order")
+	}
+	return nil
+}
+
+// Check validates that the actor may move the order from c.From to c.To
+func (m *Machine) Check(ctx context.Context, c *Change) error {
+	if !model.IsValidStatus(c.To) {
+		return fmt.Errorf("invalid status: %s", c.To)
+	}
+
+	t, ok := m.transitions[c.From][c.To]
+	if !ok {
+		return fmt.Errorf("cannot transition from %s to %s", c.From, c.To)
+	}
+
+	if !t.allows(c.Actor.Role) {
+		return fmt.Errorf("unauthorized: %s cannot move order from %s to %s", c.Actor.Role, c.From, c.To)
+	}
+
+	switch c.Actor.Role {
+	case RoleBuyer:
+		if c.Order.BuyerID != c.Actor.UserID {
+			return fmt.Errorf("unauthorized: not the buyer of this order")
+		}
+	case RoleSeller:
+		if c.Order.SellerID != c.Actor.UserID {
+			return fmt.Errorf("unauthorized: not the seller of this order")
+		}
+	case RoleAuthenticator, RoleAdmin:
+		if c.Actor.UserID == 0 {
+			return fmt.Errorf("unauthorized: %s user is required", c.Actor.Role)
+		}
+	}
+
+	for _, guard := range t.Guards {
+		if err := guard(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Next returns the statuses an order can move to from the given status
+func (m *Machine) Next(from string) []string {
+	next := make([]string, 0, len(m.transitions[from]))
+	for _, status := range model.ValidStatuses() {
+		if _, ok := m.transitions[from][status]; ok {
+			next = append(next, status)
+		}
+	}
+	return next
+}
+
+// OnEnter registers a hook that runs after an order enters the given status
+func (m *Machine) OnEnter(status, name string, hook Hook) {
+	m.hooks[status] = append(m.hooks[status], namedHook{name: name, hook: hook})
+}
+
+// Hook jobs that fail are retried after HookRetryDelay, doubling with each
+// attempt, and given up after MaxHookAttempts
+const (
+	MaxHookAttempts = 10
+	HookRetryDelay  = time.Minute
+)
+
+// JobStore keeps the hook jobs queued by order transitions
+type JobStore interface {
+	GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error)
+	CompleteHookJob(ctx context.Context, jobID int64) error
+	RetryHookJob(ctx context.Context, jobID int64, runAfter time.Time, reason string) error
+	FailHookJob(ctx context.Context, jobID int64, reason string) error
+}
+
+// Jobs returns a job for each hook registered for c.To, in registration
+// order. They are queued in the transaction that commits the change, so the
+// side effects of a committed transition are never lost.
+func (m *Machine) Jobs(c *Change) []*model.HookJob {
+	jobs := make([]*model.HookJob, 0, len(m.hooks[c.To]))
+	for _, h := range m.hooks[c.To] {
+		jobs = append(jobs, newJob(c, h.name))
+	}
+	return jobs
+}
+
+// Job returns a job that runs one of c.To's hooks for c again, e.g. to
+// release a payout that was held back when the order was completed
+func (m *Machine) Job(c *Change, hook string) (*model.HookJob, error) {
+	for _, h := range m.hooks[c.To] {
+		if h.name == hook {
+			return newJob(c, hook), nil
+		}
+	}
+	return nil, fmt.Errorf("no %s hook on %s", hook, c.To)
+}
+
+func newJob(c *Change, hook string) *model.HookJob {
+	job := &model.HookJob{
+		OrderID:    c.Order.ID,
+		Hook:       hook,
+		FromStatus: c.From,
+		ToStatus:   c.To,
+		ActorRole:  string(c.Actor.Role),
+	}
+	if c.Actor.UserID != 0 {
+		job.ActorID = sql.NullInt64{Int64: c.Actor.UserID, Valid: true}
+	}
+	if c.Note != "" {
+		job.Note = sql.NullString{String: c.Note, Valid: true}
+	}
+	return job
+}
+
+// RunJobs runs claimed hook jobs in order and returns how many succeeded. A
+// failing hook does not stop the ones after it; it is retried later, or
+// marked failed once it is out of attempts.
+func (m *Machine) RunJobs(ctx context.Context, store JobStore, jobs []*model.HookJob) int {
+	done := 0
+	for _, job := range jobs {
+		if err := m.runJob(ctx, store, job); err != nil {
+			m.retryJob(ctx, store, job, err)
+			continue
+		}
+
+		if err := store.CompleteHookJob(ctx, job.ID); err != nil {
+			log.Printf("Order %d: %s hook ran but was not marked done: %v", job.OrderID, job.Hook, err)
+			continue
+		}
+		done++
+	}
+	return done
+}
+
+// runJob runs the job's hook against the order as it was before the transition
+func (m *Machine) runJob(ctx context.Context, store JobStore, job *model.HookJob) error {
+	var hook Hook
+	for _, h := range m.hooks[job.ToStatus] {
+		if h.name == job.Hook {
+			hook = h.hook
+			break
+		}
+	}
+	if hook == nil {
+		return fmt.Errorf("no %s hook on %s", job.Hook, job.ToStatus)
+	}
+
+	order, err := store.GetOrderByID(ctx, job.OrderID)
+	if err != nil {
+		return err
+	}
+	order.Status = job.FromStatus
+
+	return hook(ctx, &Change{
+		Order: order,
+		From:  job.FromStatus,
+		To:    job.ToStatus,
+		Actor: Actor{Role: Role(job.ActorRole), UserID: job.ActorID.Int64},
+		Note:  job.Note.String,
+	})
+}
+
+// retryJob schedules the next attempt of a failed job, or gives up
+func (m *Machine) retryJob(ctx context.Context, store JobStore, job *model.HookJob, hookErr error) {
+	attempts := job.Attempts + 1
+	log.Printf("Order %d: %s hook failed on %s -> %s (attempt %d/%d): %v",
+		job.OrderID, job.Hook, job.FromStatus, job.ToStatus, attempts, MaxHookAttempts, hookErr)
+
+	var err error
+	if attempts >= MaxHookAttempts {
+		err = store.FailHookJob(ctx, job.ID, hookErr.Error())
+	} else {
+		err = store.RetryHookJob(ctx, job.ID, time.Now().Add(HookRetryDelay<<(attempts-1)), hookErr.Error())
+	}
+	if err != nil {
+		log.Printf("Order %d: failed to record %s hook failure: %v", job.OrderID, job.Hook, err)
+	}
+}
+
+func (t Transition) allows(role Role) bool {
+	for _, r := range t.Actors {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
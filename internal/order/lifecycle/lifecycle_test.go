@@ -0,0 +1,281 @@
+package lifecycle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+const (
+	buyerID  = 7
+	sellerID = 8
+)
+
+func order(status string) *model.Order {
+	return &model.Order{OrderNumber: "ORD-1", BuyerID: buyerID, SellerID: sellerID, Status: status}
+}
+
+func shippable(status string) *model.Order {
+	o := order(status)
+	o.TrackingNumber = sql.NullString{String: "794612345678", Valid: true}
+	return o
+}
+
+func check(o *model.Order, to string, actor Actor, note string) error {
+	m := NewMachine(DefaultTransitions())
+	return m.Check(context.Background(), &Change{Order: o, From: o.Status, To: to, Actor: actor, Note: note})
+}
+
+func TestCheckTransitions(t *testing.T) {
+	admin := Actor{Role: RoleAdmin, UserID: 1}
+
+	tests := []struct {
+		name    string
+		order   *model.Order
+		to      string
+		actor   Actor
+		note    string
+		allowed bool
+	}{
+		{"system marks paid", order(model.StatusPendingPayment), model.StatusPaid, System(), "", true},
+		{"seller starts processing", order(model.StatusPaid), model.StatusProcessing, Actor{Role: RoleSeller, UserID: sellerID}, "", true},
+		{"seller ships with tracking", shippable(model.StatusProcessing), model.StatusShipped, Actor{Role: RoleSeller, UserID: sellerID}, "", true},
+		{"authenticator receives", order(model.StatusShipped), model.StatusReceivedAtAuthentication, Actor{Role: RoleAuthenticator, UserID: 3}, "", true},
+		{"buyer completes delivery", order(model.StatusDelivered), model.StatusCompleted, Actor{Role: RoleBuyer, UserID: buyerID}, "", true},
+		{"admin refunds completed", order(model.StatusCompleted), model.StatusRefunded, admin, "", true},
+
+		{"skip payment", order(model.StatusPendingPayment), model.StatusShipped, admin, "", false},
+		{"leave completed", order(model.StatusCompleted), model.StatusCancelled, admin, "closed", false},
+		{"leave canceled", order(model.StatusCancelled), model.StatusPaid, System(), "", false},
+		{"unknown status", order(model.StatusPaid), "lost", admin, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(tt.order, tt.to, tt.actor, tt.note)
+			if tt.allowed && err != nil {
+				t.Errorf("%s -> %s: %v", tt.order.Status, tt.to, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("%s -> %s should be rejected", tt.order.Status, tt.to)
+			}
+		})
+	}
+}
+
+func TestCheckActors(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   *model.Order
+		to      string
+		actor   Actor
+		allowed bool
+	}{
+		{"buyer cancels own order", order(model.StatusPaid), model.StatusCancelled, Actor{Role: RoleBuyer, UserID: buyerID}, true},
+		{"other buyer", order(model.StatusPaid), model.StatusCancelled, Actor{Role: RoleBuyer, UserID: 99}, false},
+		{"seller cancels own order", order(model.StatusProcessing), model.StatusCancelled, Actor{Role: RoleSeller, UserID: sellerID}, true},
+		{"other seller", order(model.StatusProcessing), model.StatusCancelled, Actor{Role: RoleSeller, UserID: 99}, false},
+		{"buyer after processing", order(model.StatusProcessing), model.StatusCancelled, Actor{Role: RoleBuyer, UserID: buyerID}, false},
+		{"buyer marks paid", order(model.StatusPendingPayment), model.StatusPaid, Actor{Role: RoleBuyer, UserID: buyerID}, false},
+		{"seller authenticates", order(model.StatusReceivedAtAuthentication), model.StatusAuthenticated, Actor{Role: RoleSeller, UserID: sellerID}, false},
+		{"admin without user", order(model.StatusDelivered), model.StatusRefunded, Actor{Role: RoleAdmin}, false},
+		{"authenticator without user", order(model.StatusShipped), model.StatusReceivedAtAuthentication, Actor{Role: RoleAuthenticator}, false},
+		{"system cancels delivered", order(model.StatusDelivered), model.StatusCancelled, System(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(tt.order, tt.to, tt.actor, "reason")
+			if tt.allowed && err != nil {
+				t.Errorf("%s: %v", tt.actor, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("%s should be rejected", tt.actor)
+			}
+		})
+	}
+}
+
+func TestCheckGuards(t *testing.T) {
+	seller := Actor{Role: RoleSeller, UserID: sellerID}
+
+	if err := check(order(model.StatusPaid), model.StatusCancelled, seller, ""); err == nil {
+		t.Error("cancellation without a reason should be rejected")
+	}
+	if err := check(order(model.StatusPaid), model.StatusCancelled, seller, "out of stock"); err != nil {
+		t.Errorf("cancellation with a reason: %v", err)
+	}
+
+	if err := check(order(model.StatusPaid), model.StatusShipped, seller, ""); err == nil {
+		t.Error("shipping without tracking should be rejected")
+	}
+	blank := shippable(model.StatusPaid)
+	blank.TrackingNumber.String = ""
+	if err := check(blank, model.StatusShipped, seller, ""); err == nil {
+		t.Error("shipping with a blank tracking number should be rejected")
+	}
+	if err := check(shippable(model.StatusPaid), model.StatusShipped, seller, ""); err != nil {
+		t.Errorf("shipping with tracking: %v", err)
+	}
+}
+
+// jobStore records what happened to the jobs it was handed
+type jobStore struct {
+	order     *model.Order
+	completed []int64
+	retried   map[int64]time.Time
+	failed    []int64
+}
+
+func newJobStore(o *model.Order) *jobStore {
+	return &jobStore{order: o, retried: make(map[int64]time.Time)}
+}
+
+func (s *jobStore) GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error) {
+	o := *s.order
+	return &o, nil
+}
+
+func (s *jobStore) CompleteHookJob(ctx context.Context, jobID int64) error {
+	s.completed = append(s.completed, jobID)
+	return nil
+}
+
+func (s *jobStore) RetryHookJob(ctx context.Context, jobID int64, runAfter time.Time, reason string) error {
+	s.retried[jobID] = runAfter
+	return nil
+}
+
+func (s *jobStore) FailHookJob(ctx context.Context, jobID int64, reason string) error {
+	s.failed = append(s.failed, jobID)
+	return nil
+}
+
+// queue numbers the jobs of a change like the database would
+func queue(m *Machine, c *Change) []*model.HookJob {
+	jobs := m.Jobs(c)
+	for i, job := range jobs {
+		job.ID = int64(i + 1)
+	}
+	return jobs
+}
+
+func TestRunJobsInOrder(t *testing.T) {
+	m := NewMachine(DefaultTransitions())
+
+	var ran []string
+	hook := func(name string, err error) Hook {
+		return func(ctx context.Context, c *Change) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+	m.OnEnter(model.StatusCancelled, "release_inventory", hook("release_inventory", nil))
+	m.OnEnter(model.StatusCancelled, "refund", hook("refund", errors.New("provider down")))
+	m.OnEnter(model.StatusCancelled, "notify", hook("notify", nil))
+	m.OnEnter(model.StatusPaid, "other_status", hook("other_status", nil))
+
+	o := order(model.StatusCancelled)
+	store := newJobStore(o)
+	jobs := queue(m, &Change{Order: o, From: model.StatusPaid, To: model.StatusCancelled, Actor: System(), Note: "seller out of stock"})
+
+	if done := m.RunJobs(context.Background(), store, jobs); done != 2 {
+		t.Errorf("RunJobs() = %d, want 2", done)
+	}
+
+	// A failing hook does not stop the ones after it
+	want := []string{"release_inventory", "refund", "notify"}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("hooks ran %v, want %v", ran, want)
+	}
+	if !reflect.DeepEqual(store.completed, []int64{1, 3}) {
+		t.Errorf("completed jobs %v, want [1 3]", store.completed)
+	}
+	if _, ok := store.retried[2]; !ok || len(store.retried) != 1 {
+		t.Errorf("retried jobs %v, want the refund job", store.retried)
+	}
+}
+
+func TestRunJobsRetriesFailingHook(t *testing.T) {
+	m := NewMachine(DefaultTransitions())
+
+	var changes []*Change
+	fail := true
+	m.OnEnter(model.StatusCancelled, "refund", func(ctx context.Context, c *Change) error {
+		changes = append(changes, c)
+		if fail {
+			return errors.New("provider down")
+		}
+		return nil
+	})
+
+	o := order(model.StatusCancelled)
+	store := newJobStore(o)
+	seller := Actor{Role: RoleSeller, UserID: sellerID}
+	jobs := queue(m, &Change{Order: o, From: model.StatusPaid, To: model.StatusCancelled, Actor: seller, Note: "damaged"})
+
+	before := time.Now()
+	m.RunJobs(context.Background(), store, jobs)
+
+	runAfter, ok := store.retried[1]
+	if !ok {
+		t.Fatalf("failed job was not scheduled for a retry")
+	}
+	if runAfter.Before(before.Add(HookRetryDelay)) {
+		t.Errorf("retry at %v, want at least %v later", runAfter, HookRetryDelay)
+	}
+
+	// The worker claims the job again once it is due
+	jobs[0].Attempts = 1
+	fail = false
+	if done := m.RunJobs(context.Background(), store, jobs); done != 1 {
+		t.Fatalf("retry RunJobs() = %d, want 1", done)
+	}
+	if !reflect.DeepEqual(store.completed, []int64{1}) {
+		t.Errorf("completed jobs %v, want [1]", store.completed)
+	}
+
+	// The retry sees the change as it was made
+	c := changes[1]
+	if c.From != model.StatusPaid || c.To != model.StatusCancelled || c.Actor != seller || c.Note != "damaged" {
+		t.Errorf("retried change = %+v", c)
+	}
+	if c.Order.Status != model.StatusPaid {
+		t.Errorf("retried change order status = %s, want %s", c.Order.Status, model.StatusPaid)
+	}
+}
+
+func TestRunJobsGivesUpAfterMaxAttempts(t *testing.T) {
+	m := NewMachine(DefaultTransitions())
+	m.OnEnter(model.StatusCancelled, "refund", func(ctx context.Context, c *Change) error {
+		return errors.New("provider down")
+	})
+
+	o := order(model.StatusCancelled)
+	store := newJobStore(o)
+	jobs := queue(m, &Change{Order: o, From: model.StatusPaid, To: model.StatusCancelled, Actor: System(), Note: "fraud"})
+	jobs[0].Attempts = MaxHookAttempts - 1
+
+	m.RunJobs(context.Background(), store, jobs)
+
+	if !reflect.DeepEqual(store.failed, []int64{1}) || len(store.retried) != 0 {
+		t.Errorf("failed %v, retried %v; want the job failed", store.failed, store.retried)
+	}
+}
+
+func TestNext(t *testing.T) {
+	m := NewMachine(DefaultTransitions())
+
+	want := []string{model.StatusPaid, model.StatusCancelled}
+	if got := m.Next(model.StatusPendingPayment); !reflect.DeepEqual(got, want) {
+		t.Errorf("Next(pending_payment) = %v, want %v", got, want)
+	}
+	if got := m.Next(model.StatusRefunded); len(got) != 0 {
+		t.Errorf("Next(refunded) = %v, want none", got)
+	}
+}
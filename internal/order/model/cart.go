@@ -0,0 +1,34 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// CartItem is a product/size the buyer wants at the current lowest ask
+type CartItem struct {
+	ID         int64       `json:"id"`
+	BuyerID    int64       `json:"buyer_id"`
+	ProductID  int64       `json:"product_id"`
+	SizeID     int64       `json:"size_id"`
+	Quantity   int32       `json:"quantity"`
+	PriceAtAdd money.Money `json:"price_at_add"` // lowest ask when added; checkout fails if it rose
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+
+	// Filled from the Bidding Service when the cart is viewed
+	CurrentPrice money.Money `json:"current_price"` // 0 when no ask is available
+	DisplayPrice money.Money `json:"display_price"` // CurrentPrice in the requested currency
+}
+
+// Checkout groups the orders created from one cart and their shared payment
+type Checkout struct {
+	ID                    int64          `json:"id"`
+	BuyerID               int64          `json:"buyer_id"`
+	ItemCount             int32          `json:"item_count"`
+	TotalAmount           money.Money    `json:"total_amount"` // sum of the orders' buyer_total, in the buyer's currency
+	StripePaymentIntentID sql.NullString `json:"stripe_payment_intent_id"`
+	CreatedAt             time.Time      `json:"created_at"`
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Dispute is a buyer's case against a delivered order
+type Dispute struct {
+	ID           int64          `json:"id"`
+	OrderID      int64          `json:"order_id"`
+	BuyerID      int64          `json:"buyer_id"`
+	SellerID     int64          `json:"seller_id"`
+	Reason       string         `json:"reason"`
+	Description  string         `json:"description"`
+	Status       string         `json:"status"`
+	Outcome      sql.NullString `json:"outcome"`
+	RefundAmount *money.Money   `json:"refund_amount"`
+	PayoutHeld   bool           `json:"payout_held"` // seller payout skipped because the case was open
+	ResolvedBy   sql.NullInt64  `json:"resolved_by"`
+	ResolvedAt   sql.NullTime   `json:"resolved_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// DisputeEvent is one step in a dispute's timeline
+type DisputeEvent struct {
+	ID           int64          `json:"id"`
+	DisputeID    int64          `json:"dispute_id"`
+	ActorRole    string         `json:"actor_role"`
+	ActorID      sql.NullInt64  `json:"actor_id"`
+	Action       string         `json:"action"`
+	Note         sql.NullString `json:"note"`
+	EvidenceURLs []string       `json:"evidence_urls"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// Dispute statuses
+const (
+	DisputeStatusOpen            = "open"             // waiting for the seller
+	DisputeStatusSellerResponded = "seller_responded" // waiting for an admin decision
+	DisputeStatusResolved        = "resolved"
+)
+
+// Dispute reasons
+const (
+	DisputeReasonNotAsDescribed = "not_as_described"
+	DisputeReasonWrongItem      = "wrong_item"
+	DisputeReasonDamaged        = "damaged"
+	DisputeReasonNotReceived    = "not_received"
+	DisputeReasonOther          = "other"
+)
+
+// Dispute outcomes
+const (
+	DisputeOutcomeFullRefund    = "full_refund"
+	DisputeOutcomePartialRefund = "partial_refund"
+	DisputeOutcomeReturn        = "return" // buyer ships the item back for a full refund
+	DisputeOutcomeSellerFavored = "seller_favored"
+)
+
+// Dispute timeline actions
+const (
+	DisputeActionOpened          = "opened"
+	DisputeActionSellerResponded = "seller_responded"
+	DisputeActionResolved        = "resolved"
+)
+
+// IsValidDisputeReason checks if a reason is valid
+func IsValidDisputeReason(reason string) bool {
+	switch reason {
+	case DisputeReasonNotAsDescribed, DisputeReasonWrongItem, DisputeReasonDamaged,
+		DisputeReasonNotReceived, DisputeReasonOther:
+		return true
+	}
+	return false
+}
+
+// IsValidDisputeOutcome checks if an outcome is valid
+func IsValidDisputeOutcome(outcome string) bool {
+	switch outcome {
+	case DisputeOutcomeFullRefund, DisputeOutcomePartialRefund, DisputeOutcomeReturn, DisputeOutcomeSellerFavored:
+		return true
+	}
+	return false
+}
+
+// IsOpen checks if the dispute still awaits a decision
+func (d *Dispute) IsOpen() bool {
+	return d.Status != DisputeStatusResolved
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Export kinds
+const (
+	ExportKindSales      = "sales"       // completed orders with gross, fees and net payout
+	ExportKindTaxSummary = "tax_summary" // yearly monthly totals and reporting threshold
+)
+
+// Export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// Export job statuses
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusReady      = "ready"
+	ExportStatusFailed     = "failed"
+)
+
+// ExportJob is a seller's request for a sales file, built in the background
+type ExportJob struct {
+	ID          int64          `json:"id"`
+	SellerID    int64          `json:"seller_id"`
+	Kind        string         `json:"kind"`
+	Format      string         `json:"format"`
+	Year        int            `json:"year"`
+	Status      string         `json:"status"`
+	Filename    sql.NullString `json:"filename"`
+	ContentType sql.NullString `json:"content_type"`
+	Content     []byte         `json:"-"` // only loaded when downloading
+	Error       sql.NullString `json:"error"`
+	StartedAt   sql.NullTime   `json:"started_at"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// IsValidExportKind checks if the export kind is supported
+func IsValidExportKind(kind string) bool {
+	return kind == ExportKindSales || kind == ExportKindTaxSummary
+}
+
+// IsValidExportFormat checks if the export format is supported
+func IsValidExportFormat(format string) bool {
+	return format == ExportFormatCSV || format == ExportFormatXLSX
+}
+
+// SellerPayout is a payout sent to a seller for an order
+type SellerPayout struct {
+	OrderID     int64        `json:"order_id"`
+	PayoutID    string       `json:"payout_id"`
+	Amount      money.Money  `json:"amount"`
+	Status      string       `json:"status"`
+	ProcessedAt sql.NullTime `json:"processed_at"`
+}
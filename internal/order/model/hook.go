@@ -0,0 +1,34 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Hook job statuses
+const (
+	HookJobStatusPending    = "pending"
+	HookJobStatusProcessing = "processing"
+	HookJobStatusDone       = "done"
+	HookJobStatusFailed     = "failed" // out of attempts
+)
+
+// HookJob is one status hook of an order transition, queued with the
+// transition and run until it succeeds
+type HookJob struct {
+	ID          int64          `json:"id"`
+	OrderID     int64          `json:"order_id"`
+	Hook        string         `json:"hook"`
+	FromStatus  string         `json:"from_status"`
+	ToStatus    string         `json:"to_status"`
+	ActorRole   string         `json:"actor_role"`
+	ActorID     sql.NullInt64  `json:"actor_id"`
+	Note        sql.NullString `json:"note"`
+	Status      string         `json:"status"`
+	Attempts    int32          `json:"attempts"`
+	LastError   sql.NullString `json:"last_error"`
+	RunAfter    time.Time      `json:"run_after"`
+	StartedAt   sql.NullTime   `json:"started_at"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
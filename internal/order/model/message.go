@@ -0,0 +1,21 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// MaxMessageLength caps the length of an order message
+const MaxMessageLength = 2000
+
+// OrderMessage is one message in an order's buyer/seller thread
+type OrderMessage struct {
+	ID           int64          `json:"id"`
+	OrderID      int64          `json:"order_id"`
+	SenderID     int64          `json:"sender_id"`
+	SenderRole   string         `json:"sender_role"` // buyer, seller, admin
+	Body         string         `json:"body"`        // contact details removed
+	OriginalBody sql.NullString `json:"-"`           // as written; only shown to admins
+	Redacted     bool           `json:"redacted"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
@@ -3,6 +3,8 @@ package model
 import (
 	"database/sql"
 	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // Order represents an order created from a matched bid/ask
@@ -19,25 +21,46 @@ type Order struct {
 	ProductID int64 `json:"product_id"`
 	SizeID    int64 `json:"size_id"`
 
-	// Pricing
-	Price        float64 `json:"price"`
-	Quantity     int32   `json:"quantity"`
-	BuyerFee     float64 `json:"buyer_fee"`
-	SellerFee    float64 `json:"seller_fee"`
-	PlatformFee  float64 `json:"platform_fee"`
-	TotalAmount  float64 `json:"total_amount"`  // price + buyer_fee
-	SellerPayout float64 `json:"seller_payout"` // price - seller_fee
+	// Pricing, in the settlement currency
+	Price        money.Money `json:"price"`
+	Quantity     int32       `json:"quantity"`
+	BuyerFee     money.Money `json:"buyer_fee"`
+	SellerFee    money.Money `json:"seller_fee"`
+	PlatformFee  money.Money `json:"platform_fee"`
+	TotalAmount  money.Money `json:"total_amount"`  // price + buyer_fee
+	SellerPayout money.Money `json:"seller_payout"` // price - seller_fee
+
+	// Currencies: the buyer pays BuyerTotal (TotalAmount at BuyerFXRate);
+	// the seller listed at SellerFXRate
+	BuyerTotal     money.Money `json:"buyer_total"`
+	BuyerFXRate    money.Rate  `json:"buyer_fx_rate"` // settlement -> buyer currency
+	SellerCurrency string      `json:"seller_currency"`
+	SellerFXRate   money.Rate  `json:"seller_fx_rate"` // seller currency -> settlement
 
 	// Status
 	Status string `json:"status"`
 
+	CheckoutID sql.NullInt64 `json:"checkout_id"` // set when bought through the cart
+
+	// InventoryReference is the seller's reservation the order completes or
+	// releases (the sold ask's, shared by the asks split off it)
+	InventoryReference string `json:"inventory_reference"`
+
 	// Shipping
 	ShippingAddressID sql.NullInt64  `json:"shipping_address_id"`
 	TrackingNumber    sql.NullString `json:"tracking_number"`
 	Carrier           sql.NullString `json:"carrier"`
+	ShippingService   sql.NullString `json:"shipping_service"` // set when the label was bought through us
+	LabelURL          sql.NullString `json:"label_url"`        // prepaid label PDF
+
+	// Authentication center -> buyer
+	OutboundTrackingNumber sql.NullString `json:"outbound_tracking_number"`
+	OutboundCarrier        sql.NullString `json:"outbound_carrier"`
+	OutboundLabelURL       sql.NullString `json:"outbound_label_url"`
 
 	// Timestamps
 	PaymentAt   sql.NullTime `json:"payment_at"`
+	ShipBy      sql.NullTime `json:"ship_by"` // seller's shipping deadline, set once paid
 	ShippedAt   sql.NullTime `json:"shipped_at"`
 	DeliveredAt sql.NullTime `json:"delivered_at"`
 	CompletedAt sql.NullTime `json:"completed_at"`
@@ -53,6 +76,34 @@ type Order struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PendingShipment is a paid order and the seller's shipping deadline
+type PendingShipment struct {
+	Order  *Order
+	ShipBy time.Time
+}
+
+// Seller penalty reasons
+const (
+	PenaltyLateShipment = "late_shipment"
+)
+
+// SellerPenalty is a charge recorded against a seller for a policy violation
+type SellerPenalty struct {
+	ID        int64          `json:"id"`
+	SellerID  int64          `json:"seller_id"`
+	OrderID   int64          `json:"order_id"`
+	Reason    string         `json:"reason"`
+	Amount    money.Money    `json:"amount"`
+	Note      sql.NullString `json:"note"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// PendingCompletion is a delivered order and the end of its inspection window
+type PendingCompletion struct {
+	Order      *Order
+	CompleteAt time.Time
+}
+
 // OrderStatusHistory represents a status change event for an order
 type OrderStatusHistory struct {
 	ID         int64          `json:"id"`
@@ -60,20 +111,114 @@ type OrderStatusHistory struct {
 	FromStatus sql.NullString `json:"from_status"`
 	ToStatus   string         `json:"to_status"`
 	Note       sql.NullString `json:"note"`
-	CreatedBy  string         `json:"created_by"` // system, buyer, seller, admin
+	CreatedBy  string         `json:"created_by"` // e.g. seller_12, system
+	ActorRole  string         `json:"actor_role"` // system, buyer, seller, admin
+	ActorID    sql.NullInt64  `json:"actor_id"`
 	CreatedAt  time.Time      `json:"created_at"`
 }
 
+// Address is a shipping address of a buyer or seller
+type Address struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	Name        string `json:"name"`
+	StreetLine1 string `json:"street_line1"`
+	StreetLine2 string `json:"street_line2"`
+	City        string `json:"city"`
+	State       string `json:"state"`
+	PostalCode  string `json:"postal_code"`
+	Country     string `json:"country"`
+}
+
+// Authentication records an authenticator's inspection of an order's item
+type Authentication struct {
+	ID              int64          `json:"id"`
+	OrderID         int64          `json:"order_id"`
+	AuthenticatorID int64          `json:"authenticator_id"`
+	Result          string         `json:"result"` // received, passed, failed
+	Notes           sql.NullString `json:"notes"`
+	PhotoURLs       []string       `json:"photo_urls"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// Authentication results
+const (
+	AuthenticationReceived = "received"
+	AuthenticationPassed   = "passed"
+	AuthenticationFailed   = "failed"
+)
+
+// OrderReturn is a shipment sending an item back to the seller
+type OrderReturn struct {
+	ID             int64          `json:"id"`
+	OrderID        int64          `json:"order_id"`
+	SellerID       int64          `json:"seller_id"`
+	Reason         string         `json:"reason"`
+	Status         string         `json:"status"` // pending, shipped, delivered
+	TrackingNumber sql.NullString `json:"tracking_number"`
+	Carrier        sql.NullString `json:"carrier"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// OrderAdjustment is money returned to the buyer after the order was paid,
+// in the currency the buyer paid
+type OrderAdjustment struct {
+	ID        int64          `json:"id"`
+	OrderID   int64          `json:"order_id"`
+	Type      string         `json:"type"` // refund
+	Amount    money.Money    `json:"amount"`
+	Reference string         `json:"reference"` // the refund it records
+	Reason    sql.NullString `json:"reason"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Adjustment types
+const (
+	AdjustmentTypeRefund = "refund"
+)
+
+// Return statuses
+const (
+	ReturnStatusPending   = "pending"
+	ReturnStatusShipped   = "shipped"
+	ReturnStatusDelivered = "delivered"
+)
+
+// ShipmentEvent is a carrier scan reported for one of an order's shipments
+type ShipmentEvent struct {
+	ID             int64          `json:"id"`
+	OrderID        int64          `json:"order_id"`
+	Leg            string         `json:"leg"` // inbound, outbound
+	TrackingNumber string         `json:"tracking_number"`
+	Carrier        string         `json:"carrier"`
+	Status         string         `json:"status"`
+	Description    sql.NullString `json:"description"`
+	Location       sql.NullString `json:"location"`
+	EventID        sql.NullString `json:"event_id"` // carrier's event ID, used to drop redelivered webhooks
+	OccurredAt     time.Time      `json:"occurred_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// Shipment legs
+const (
+	ShipmentLegInbound  = "inbound"  // seller -> authentication center
+	ShipmentLegOutbound = "outbound" // authentication center -> buyer
+)
+
 // Order status constants
 const (
-	StatusPendingPayment = "pending_payment"
-	StatusPaid           = "paid"
-	StatusProcessing     = "processing"
-	StatusShipped        = "shipped"
-	StatusDelivered      = "delivered"
-	StatusCompleted      = "completed"
-	StatusCancelled      = "canceled"
-	StatusRefunded       = "refunded"
+	StatusPendingPayment           = "pending_payment"
+	StatusPaid                     = "paid"
+	StatusProcessing               = "processing"
+	StatusShipped                  = "shipped"                    // on its way to authentication
+	StatusReceivedAtAuthentication = "received_at_authentication" // authenticator has the item
+	StatusAuthenticated            = "authenticated"
+	StatusFailedAuthentication     = "failed_authentication" // buyer refunded, item returned to seller
+	StatusDelivered                = "delivered"
+	StatusCompleted                = "completed"
+	StatusCancelled                = "canceled"
+	StatusRefunded                 = "refunded"
 )
 
 // ValidStatuses returns all valid order statuses
@@ -83,6 +228,9 @@ func ValidStatuses() []string {
 		StatusPaid,
 		StatusProcessing,
 		StatusShipped,
+		StatusReceivedAtAuthentication,
+		StatusAuthenticated,
+		StatusFailedAuthentication,
 		StatusDelivered,
 		StatusCompleted,
 		StatusCancelled,
@@ -100,33 +248,6 @@ func IsValidStatus(status string) bool {
 	return false
 }
 
-// CanTransitionTo checks if status transition is allowed
-func (o *Order) CanTransitionTo(newStatus string) bool {
-	// Define allowed transitions
-	allowedTransitions := map[string][]string{
-		StatusPendingPayment: {StatusPaid, StatusCancelled},
-		StatusPaid:           {StatusProcessing, StatusRefunded, StatusCancelled},
-		StatusProcessing:     {StatusShipped, StatusCancelled},
-		StatusShipped:        {StatusDelivered, StatusCancelled},
-		StatusDelivered:      {StatusCompleted, StatusCancelled},
-		StatusCompleted:      {StatusRefunded}, // Can refund completed orders
-		StatusCancelled:      {},               // Final state
-		StatusRefunded:       {},               // Final state
-	}
-
-	allowed, exists := allowedTransitions[o.Status]
-	if !exists {
-		return false
-	}
-
-	for _, s := range allowed {
-		if s == newStatus {
-			return true
-		}
-	}
-	return false
-}
-
 // IsFinalStatus checks if the order is in a final state
 func (o *Order) IsFinalStatus() bool {
 	return o.Status == StatusCompleted ||
@@ -134,9 +255,26 @@ func (o *Order) IsFinalStatus() bool {
 		o.Status == StatusRefunded
 }
 
-// CanBeCancelled checks if the order can be canceled
-func (o *Order) CanBeCancelled() bool {
-	return o.Status != StatusCompleted &&
-		o.Status != StatusCancelled &&
-		o.Status != StatusRefunded
+// Invoice kinds
+const (
+	InvoiceKindBuyerReceipt    = "buyer_receipt"
+	InvoiceKindSellerStatement = "seller_statement"
+)
+
+// Invoice is the stable number assigned to an order's receipt or statement
+type Invoice struct {
+	ID            int64     `json:"id"`
+	OrderID       int64     `json:"order_id"`
+	Kind          string    `json:"kind"` // buyer_receipt, seller_statement
+	InvoiceNumber string    `json:"invoice_number"`
+	IssuedAt      time.Time `json:"issued_at"`
+}
+
+// InvoiceDetails is what an invoice shows beyond the order amounts
+type InvoiceDetails struct {
+	Item        string // product name and size
+	BuyerName   string
+	BuyerEmail  string
+	SellerName  string
+	SellerEmail string
 }
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/pagination"
+)
+
+// Order search sort keys
+const (
+	SortByCreatedAt   = "created_at"
+	SortByPrice       = "price"
+	SortByTotalAmount = "total_amount"
+)
+
+// Search page size limits
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// OrderSearch filters orders. Zero values mean "no filter".
+type OrderSearch struct {
+	// Scope: UserID limits results to orders where the user is buyer or
+	// seller. BuyerID/SellerID pin one side.
+	UserID   int64
+	BuyerID  int64
+	SellerID int64
+
+	// Other party of the order. With UserID it is the side opposite the
+	// user; without it, either side.
+	CounterpartyID int64
+
+	Statuses    []string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+
+	ProductID int64
+	SKU       string // exact, case-insensitive
+	SizeID    int64
+	Size      string // size label, e.g. "10.5"
+
+	MinPrice money.Money
+	MaxPrice money.Money
+
+	OrderNumberPrefix string
+	Carrier           string // matches the inbound or outbound carrier
+
+	SortBy    string // created_at (default), price, total_amount
+	SortOrder string // desc (default), asc
+	Cursor    string // from the previous page's NextCursor
+	Limit     int32
+}
+
+// Normalize validates the search and fills in defaults
+func (s *OrderSearch) Normalize() error {
+	switch s.SortBy {
+	case "":
+		s.SortBy = SortByCreatedAt
+	case SortByCreatedAt, SortByPrice, SortByTotalAmount:
+	default:
+		return fmt.Errorf("invalid sort_by: %s", s.SortBy)
+	}
+
+	switch strings.ToLower(s.SortOrder) {
+	case "", "desc":
+		s.SortOrder = "desc"
+	case "asc":
+		s.SortOrder = "asc"
+	default:
+		return fmt.Errorf("invalid sort_order: %s", s.SortOrder)
+	}
+
+	if s.Limit <= 0 {
+		s.Limit = DefaultSearchLimit
+	}
+	if s.Limit > MaxSearchLimit {
+		s.Limit = MaxSearchLimit
+	}
+
+	for _, status := range s.Statuses {
+		if !IsValidStatus(status) {
+			return fmt.Errorf("invalid status: %s", status)
+		}
+	}
+
+	if s.MinPrice.IsNegative() || s.MaxPrice.IsNegative() {
+		return fmt.Errorf("price range cannot be negative")
+	}
+	if s.MaxPrice.IsPositive() && s.MinPrice.GreaterThan(s.MaxPrice) {
+		return fmt.Errorf("min_price cannot exceed max_price")
+	}
+	if s.CreatedFrom != nil && s.CreatedTo != nil && s.CreatedTo.Before(*s.CreatedFrom) {
+		return fmt.Errorf("created_to cannot be before created_from")
+	}
+
+	return nil
+}
+
+// SortKey identifies the search's sort for pagination cursors
+func (s *OrderSearch) SortKey() string {
+	return s.SortBy + ":" + s.SortOrder
+}
+
+// DecodeCursor parses the search's cursor, nil on the first page
+func (s *OrderSearch) DecodeCursor() (*pagination.Cursor, error) {
+	c, err := pagination.Decode(s.Cursor, s.SortKey())
+	if err != nil || c == nil {
+		return c, err
+	}
+
+	valid := c.ValidAmount()
+	if s.SortBy == SortByCreatedAt {
+		valid = c.ValidTime()
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// NextCursor returns the cursor that continues after order o
+func (s *OrderSearch) NextCursor(o *Order) string {
+	c := pagination.Cursor{Sort: s.SortKey(), ID: o.ID}
+
+	switch s.SortBy {
+	case SortByPrice:
+		c.Value = o.Price.String() // exact decimal, compared as NUMERIC
+	case SortByTotalAmount:
+		c.Value = o.TotalAmount.String()
+	default:
+		c.Value = pagination.TimeValue(o.CreatedAt)
+	}
+
+	return c.Encode()
+}
@@ -0,0 +1,76 @@
+// Package redact removes contact details from order messages so buyers and
+// sellers cannot move a trade off the platform.
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Placeholder replaces removed contact details
+const Placeholder = "[removed]"
+
+var (
+	// Plain and obfuscated addresses: name@host.com, name (at) host (dot) com
+	emailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+\s*(?:@|\(at\)|\[at\])\s*[a-z0-9\-]+(?:\s*(?:\.|\(dot\)|\[dot\])\s*[a-z0-9\-]+)*\s*(?:\.|\(dot\)|\[dot\])\s*[a-z]{2,}\b`)
+
+	// Seven or more digits, optionally with a leading + and separated by
+	// spaces, dots, dashes or parentheses
+	phonePattern = regexp.MustCompile(`\+?\(?\d(?:[\s.\-()]*\d){6,}`)
+
+	// Numbers that read like phone numbers but are not: ISO dates (2026-10-18),
+	// amounts ($12,500 or 12500.00) and carrier tracking numbers, which are
+	// unbroken runs of twelve or more digits (phone numbers that long are
+	// written with a leading +)
+	notPhonePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b|[$€£]\s?\d[\d,]*(?:\.\d+)?|\b\d[\d,]*\.\d{2}\b|\b\d{12,}\b`)
+)
+
+// Contacts replaces emails and phone numbers in text with Placeholder and
+// reports whether anything was removed. Dates, amounts and tracking
+// numbers are kept, as are digit runs that are part of a longer token,
+// such as order numbers (ORD-2026-000001).
+func Contacts(text string) (string, bool) {
+	out := emailPattern.ReplaceAllString(text, Placeholder)
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range notPhonePattern.FindAllStringIndex(out, -1) {
+		if loc[0] > 0 && out[loc[0]-1] == '+' {
+			continue // +<digits> is an international phone number
+		}
+		redactPhones(&b, out, last, loc[0])
+		b.WriteString(out[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	redactPhones(&b, out, last, len(out))
+	out = b.String()
+
+	return out, out != text
+}
+
+// redactPhones writes s[from:to] to b with its phone numbers replaced by
+// Placeholder
+func redactPhones(b *strings.Builder, s string, from, to int) {
+	last := from
+	for _, loc := range phonePattern.FindAllStringIndex(s[from:to], -1) {
+		start, end := from+loc[0], from+loc[1]
+		if partOfToken(s, start) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Placeholder)
+		last = end
+	}
+	b.WriteString(s[last:to])
+}
+
+// partOfToken reports whether the match at i continues a word or identifier
+func partOfToken(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '#'
+}
@@ -0,0 +1,42 @@
+package redact
+
+import "testing"
+
+func TestContacts(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"email", "write me at jane.doe@example.com", "write me at [removed]"},
+		{"obfuscated email", "jane (at) example (dot) com", "[removed]"},
+		{"bracketed email", "jane[at]example[dot]co[dot]uk please", "[removed] please"},
+		{"spaced email", "jane @ example . com", "[removed]"},
+		{"phone", "call 555-123-4567 tonight", "call [removed] tonight"},
+		{"phone with parentheses", "(555) 123 4567", "[removed]"},
+		{"international phone", "text +380501234567", "text [removed]"},
+		{"dotted phone", "555.123.4567", "[removed]"},
+
+		{"iso date", "Shipped on 2026-10-18", "Shipped on 2026-10-18"},
+		{"date and time", "Arrives 2026-10-18 14:30", "Arrives 2026-10-18 14:30"},
+		{"tracking number", "FedEx tracking 794612345678", "FedEx tracking 794612345678"},
+		{"long tracking number", "USPS 9400111899223197428490", "USPS 9400111899223197428490"},
+		{"amount", "I paid $12500.00 for these", "I paid $12500.00 for these"},
+		{"amount with separators", "offer 1,250,000.00 or best", "offer 1,250,000.00 or best"},
+		{"order number", "about ORD-2026-000001", "about ORD-2026-000001"},
+
+		{"date next to phone", "2026-10-18 call 555 123 4567", "2026-10-18 call [removed]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := Contacts(tt.in)
+			if got != tt.want {
+				t.Errorf("Contacts(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if removed != (tt.want != tt.in) {
+				t.Errorf("Contacts(%q) removed = %v", tt.in, removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// CreateAdjustment records an adjustment to what the buyer paid. An
+// adjustment with the same reference is recorded once; it reports false
+// when it already was.
+func (r *OrderRepository) CreateAdjustment(ctx context.Context, adj *model.OrderAdjustment) (bool, error) {
+	query := `
+		INSERT INTO order_adjustments (order_id, type, amount, currency, reference, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (reference) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		adj.OrderID, adj.Type, adj.Amount, adj.Amount.Currency, adj.Reference, adj.Reason,
+	).Scan(&adj.ID, &adj.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create order adjustment: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetAdjustments retrieves an order's adjustments, oldest first
+func (r *OrderRepository) GetAdjustments(ctx context.Context, orderID int64) ([]*model.OrderAdjustment, error) {
+	query := `
+		SELECT id, order_id, type, amount, currency, reference, reason, created_at
+		FROM order_adjustments
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := make([]*model.OrderAdjustment, 0)
+	for rows.Next() {
+		adj := &model.OrderAdjustment{}
+		var currency string
+		if err := rows.Scan(&adj.ID, &adj.OrderID, &adj.Type, &adj.Amount, &currency, &adj.Reference, &adj.Reason, &adj.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order adjustment: %w", err)
+		}
+		adj.Amount = adj.Amount.WithCurrency(currency)
+		adjustments = append(adjustments, adj)
+	}
+
+	return adjustments, rows.Err()
+}
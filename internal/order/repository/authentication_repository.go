@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// GetUserRole retrieves a user's role (user, authenticator, admin)
+func (r *OrderRepository) GetUserRole(ctx context.Context, userID int64) (string, error) {
+	var role string
+	err := r.db.QueryRow(ctx, `SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", fmt.Errorf("user not found")
+		}
+		return "", fmt.Errorf("failed to get user role: %w", err)
+	}
+
+	return role, nil
+}
+
+// CreateAuthentication records an authenticator's inspection result
+func (r *OrderRepository) CreateAuthentication(ctx context.Context, tx pgx.Tx, auth *model.Authentication) error {
+	query := `
+		INSERT INTO order_authentications (
+			order_id, authenticator_id, result, notes, photo_urls
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	photoURLs := auth.PhotoURLs
+	if photoURLs == nil {
+		photoURLs = []string{}
+	}
+
+	err := tx.QueryRow(
+		ctx, query,
+		auth.OrderID, auth.AuthenticatorID, auth.Result, auth.Notes, photoURLs,
+	).Scan(&auth.ID, &auth.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record authentication: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuthentications retrieves the authentication records for an order
+func (r *OrderRepository) GetAuthentications(ctx context.Context, orderID int64) ([]*model.Authentication, error) {
+	query := `
+		SELECT 
+			id, order_id, authenticator_id, result,
+			notes, photo_urls, created_at
+		FROM order_authentications
+		WHERE order_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authentications: %w", err)
+	}
+	defer rows.Close()
+
+	authentications := make([]*model.Authentication, 0)
+	for rows.Next() {
+		a := &model.Authentication{}
+		err := rows.Scan(
+			&a.ID, &a.OrderID, &a.AuthenticatorID, &a.Result,
+			&a.Notes, &a.PhotoURLs, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan authentication: %w", err)
+		}
+		authentications = append(authentications, a)
+	}
+
+	return authentications, nil
+}
+
+// CreateReturn records a shipment sending the item back to the seller
+func (r *OrderRepository) CreateReturn(ctx context.Context, ret *model.OrderReturn) error {
+	query := `
+		INSERT INTO order_returns (order_id, seller_id, reason, status, tracking_number, carrier)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (order_id) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		ret.OrderID, ret.SellerID, ret.Reason, ret.Status, ret.TrackingNumber, ret.Carrier,
+	).Scan(&ret.ID, &ret.CreatedAt, &ret.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil // Return already exists for this order
+		}
+		return fmt.Errorf("failed to create return: %w", err)
+	}
+
+	return nil
+}
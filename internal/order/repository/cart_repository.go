@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// AddCartItem adds a product/size to the buyer's cart. Adding the same
+// product/size again increases the quantity and refreshes the price.
+func (r *OrderRepository) AddCartItem(ctx context.Context, item *model.CartItem) error {
+	query := `
+		INSERT INTO cart_items (buyer_id, product_id, size_id, quantity, price_at_add)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (buyer_id, product_id, size_id) DO UPDATE
+		SET quantity = cart_items.quantity + EXCLUDED.quantity,
+			price_at_add = EXCLUDED.price_at_add
+		RETURNING id, quantity, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		item.BuyerID, item.ProductID, item.SizeID, item.Quantity, item.PriceAtAdd,
+	).Scan(&item.ID, &item.Quantity, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add cart item: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveCartItem deletes an item from the buyer's cart
+func (r *OrderRepository) RemoveCartItem(ctx context.Context, buyerID, itemID int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM cart_items WHERE id = $1 AND buyer_id = $2`, itemID, buyerID)
+	if err != nil {
+		return fmt.Errorf("failed to remove cart item: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("cart item not found")
+	}
+
+	return nil
+}
+
+// GetCartItems retrieves the buyer's cart, oldest first
+func (r *OrderRepository) GetCartItems(ctx context.Context, buyerID int64) ([]*model.CartItem, error) {
+	query := `
+		SELECT id, buyer_id, product_id, size_id, quantity, price_at_add, created_at, updated_at
+		FROM cart_items
+		WHERE buyer_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, buyerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*model.CartItem, 0)
+	for rows.Next() {
+		item := &model.CartItem{}
+		err := rows.Scan(
+			&item.ID, &item.BuyerID, &item.ProductID, &item.SizeID,
+			&item.Quantity, &item.PriceAtAdd, &item.CreatedAt, &item.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// CreateCheckout stores a checkout and its orders in one transaction
+func (r *OrderRepository) CreateCheckout(ctx context.Context, checkout *model.Checkout, orders []*model.Order) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+		INSERT INTO checkouts (buyer_id, item_count, total_amount, currency)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(ctx, query, checkout.BuyerID, checkout.ItemCount, checkout.TotalAmount, checkout.TotalAmount.Currency).Scan(&checkout.ID, &checkout.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create checkout: %w", err)
+	}
+
+	for _, order := range orders {
+		order.CheckoutID.Int64, order.CheckoutID.Valid = checkout.ID, true
+		if err := insertOrder(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteCheckout records the checkout's PaymentIntent and empties the
+// items that were bought from the cart
+func (r *OrderRepository) CompleteCheckout(ctx context.Context, checkoutID int64, paymentIntentID string, cartItemIDs []int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `UPDATE checkouts SET stripe_payment_intent_id = $1 WHERE id = $2`, paymentIntentID, checkoutID)
+	if err != nil {
+		return fmt.Errorf("failed to update checkout: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM cart_items WHERE id = ANY($1)`, cartItemIDs)
+	if err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetCheckout retrieves a checkout and its orders
+func (r *OrderRepository) GetCheckout(ctx context.Context, checkoutID int64) (*model.Checkout, []*model.Order, error) {
+	query := `
+		SELECT id, buyer_id, item_count, total_amount, currency, stripe_payment_intent_id, created_at
+		FROM checkouts
+		WHERE id = $1
+	`
+
+	checkout := &model.Checkout{}
+	var currency string
+	err := r.db.QueryRow(ctx, query, checkoutID).Scan(
+		&checkout.ID, &checkout.BuyerID, &checkout.ItemCount, &checkout.TotalAmount, &currency,
+		&checkout.StripePaymentIntentID, &checkout.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, fmt.Errorf("checkout not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get checkout: %w", err)
+	}
+	checkout.TotalAmount = checkout.TotalAmount.WithCurrency(currency)
+
+	rows, err := r.db.Query(ctx, `SELECT`+orderColumns+` FROM orders WHERE checkout_id = $1 ORDER BY id ASC`, checkoutID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get checkout orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]*model.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	return checkout, orders, rows.Err()
+}
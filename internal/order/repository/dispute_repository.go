@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+const disputeColumns = `
+			id, order_id, buyer_id, seller_id,
+			reason, description, status,
+			outcome, refund_amount, payout_held,
+			resolved_by, resolved_at,
+			created_at, updated_at`
+
+// CreateDispute opens a dispute
+func (r *OrderRepository) CreateDispute(ctx context.Context, tx pgx.Tx, dispute *model.Dispute) error {
+	query := `
+		INSERT INTO disputes (order_id, buyer_id, seller_id, reason, description, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := tx.QueryRow(
+		ctx, query,
+		dispute.OrderID, dispute.BuyerID, dispute.SellerID,
+		dispute.Reason, dispute.Description, dispute.Status,
+	).Scan(&dispute.ID, &dispute.CreatedAt, &dispute.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create dispute: %w", err)
+	}
+
+	return nil
+}
+
+// GetDispute retrieves a dispute by ID
+func (r *OrderRepository) GetDispute(ctx context.Context, disputeID int64) (*model.Dispute, error) {
+	query := `
+		SELECT` + disputeColumns + `
+		FROM disputes
+		WHERE id = $1
+	`
+
+	dispute, err := scanDispute(r.db.QueryRow(ctx, query, disputeID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("dispute not found")
+		}
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+
+	return dispute, nil
+}
+
+// GetOpenDisputeByOrderID retrieves the unresolved dispute of an order, if any
+func (r *OrderRepository) GetOpenDisputeByOrderID(ctx context.Context, orderID int64) (*model.Dispute, error) {
+	query := `
+		SELECT` + disputeColumns + `
+		FROM disputes
+		WHERE order_id = $1 AND status <> 'resolved'
+	`
+
+	dispute, err := scanDispute(r.db.QueryRow(ctx, query, orderID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get open dispute: %w", err)
+	}
+
+	return dispute, nil
+}
+
+// ListDisputes retrieves disputes with optional status filter, oldest first
+func (r *OrderRepository) ListDisputes(ctx context.Context, status string, page, pageSize int32) ([]*model.Dispute, int64, error) {
+	query := `
+		SELECT` + disputeColumns + `
+		FROM disputes
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	var total int64
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM disputes WHERE ($1 = '' OR status = $1)`, status).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count disputes: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	rows, err := r.db.Query(ctx, query, status, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	defer rows.Close()
+
+	disputes := make([]*model.Dispute, 0)
+	for rows.Next() {
+		dispute, err := scanDispute(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan dispute: %w", err)
+		}
+		disputes = append(disputes, dispute)
+	}
+
+	return disputes, total, nil
+}
+
+// UpdateDisputeStatus moves an unresolved dispute to a new status
+func (r *OrderRepository) UpdateDisputeStatus(ctx context.Context, tx pgx.Tx, disputeID int64, status string) error {
+	query := `UPDATE disputes SET status = $1 WHERE id = $2 AND status <> 'resolved'`
+
+	result, err := tx.Exec(ctx, query, status, disputeID)
+	if err != nil {
+		return fmt.Errorf("failed to update dispute status: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("dispute not found or already resolved")
+	}
+
+	return nil
+}
+
+// ResolveDispute records the admin's decision
+func (r *OrderRepository) ResolveDispute(ctx context.Context, tx pgx.Tx, dispute *model.Dispute) error {
+	query := `
+		UPDATE disputes
+		SET 
+			status = 'resolved',
+			outcome = $1,
+			refund_amount = $2,
+			resolved_by = $3,
+			resolved_at = NOW()
+		WHERE id = $4 AND status <> 'resolved'
+		RETURNING resolved_at
+	`
+
+	err := tx.QueryRow(
+		ctx, query,
+		dispute.Outcome, dispute.RefundAmount, dispute.ResolvedBy, dispute.ID,
+	).Scan(&dispute.ResolvedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("dispute not found or already resolved")
+		}
+		return fmt.Errorf("failed to resolve dispute: %w", err)
+	}
+
+	dispute.Status = model.DisputeStatusResolved
+	return nil
+}
+
+// MarkDisputePayoutHeld records that the seller payout was skipped for this case
+func (r *OrderRepository) MarkDisputePayoutHeld(ctx context.Context, disputeID int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE disputes SET payout_held = TRUE WHERE id = $1`, disputeID)
+	if err != nil {
+		return fmt.Errorf("failed to hold payout: %w", err)
+	}
+
+	return nil
+}
+
+// GetDisputeRefundTotal sums the partial refunds granted on an order's
+// disputes, in the order's currency
+func (r *OrderRepository) GetDisputeRefundTotal(ctx context.Context, orderID int64) (money.Money, error) {
+	query := `
+		SELECT COALESCE(SUM(d.refund_amount), 0), o.currency
+		FROM orders o
+		LEFT JOIN disputes d ON d.order_id = o.id
+			AND d.status = 'resolved' AND d.outcome = 'partial_refund'
+		WHERE o.id = $1
+		GROUP BY o.currency
+	`
+
+	var total money.Money
+	var currency string
+	if err := r.db.QueryRow(ctx, query, orderID).Scan(&total, &currency); err != nil {
+		return money.Money{}, fmt.Errorf("failed to sum dispute refunds: %w", err)
+	}
+
+	return total.WithCurrency(currency), nil
+}
+
+// AddDisputeEvent appends a step to the dispute timeline and mirrors it into
+// the admin audit log. details are stored with the audit entry.
+func (r *OrderRepository) AddDisputeEvent(ctx context.Context, tx pgx.Tx, event *model.DisputeEvent, details map[string]interface{}) error {
+	query := `
+		INSERT INTO dispute_events (dispute_id, actor_role, actor_id, action, note, evidence_urls)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	evidenceURLs := event.EvidenceURLs
+	if evidenceURLs == nil {
+		evidenceURLs = []string{}
+	}
+
+	err := tx.QueryRow(
+		ctx, query,
+		event.DisputeID, event.ActorRole, event.ActorID, event.Action, event.Note, evidenceURLs,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add dispute event: %w", err)
+	}
+
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["actor_role"] = event.ActorRole
+	if event.ActorID.Valid {
+		details["actor_id"] = event.ActorID.Int64
+	}
+	if event.Note.Valid {
+		details["note"] = event.Note.String
+	}
+	if len(event.EvidenceURLs) > 0 {
+		details["evidence_urls"] = event.EvidenceURLs
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	// Only admin actions carry an admin_id; buyer and seller steps are
+	// logged with the actor in details
+	var adminID interface{}
+	if event.ActorRole == "admin" && event.ActorID.Valid {
+		adminID = event.ActorID.Int64
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audit_logs (admin_id, action_type, entity_type, entity_id, details, created_at)
+		VALUES ($1, $2, 'dispute', $3, $4, CURRENT_TIMESTAMP)
+	`, adminID, "dispute_"+event.Action, event.DisputeID, detailsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+// GetDisputeEvents retrieves a dispute's timeline, oldest first
+func (r *OrderRepository) GetDisputeEvents(ctx context.Context, disputeID int64) ([]*model.DisputeEvent, error) {
+	query := `
+		SELECT id, dispute_id, actor_role, actor_id, action, note, evidence_urls, created_at
+		FROM dispute_events
+		WHERE dispute_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*model.DisputeEvent
+	for rows.Next() {
+		event := &model.DisputeEvent{}
+		err := rows.Scan(
+			&event.ID, &event.DisputeID, &event.ActorRole, &event.ActorID,
+			&event.Action, &event.Note, &event.EvidenceURLs, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispute event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func scanDispute(row pgx.Row) (*model.Dispute, error) {
+	dispute := &model.Dispute{}
+	err := row.Scan(
+		&dispute.ID, &dispute.OrderID, &dispute.BuyerID, &dispute.SellerID,
+		&dispute.Reason, &dispute.Description, &dispute.Status,
+		&dispute.Outcome, &dispute.RefundAmount, &dispute.PayoutHeld,
+		&dispute.ResolvedBy, &dispute.ResolvedAt,
+		&dispute.CreatedAt, &dispute.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dispute, nil
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+const exportJobColumns = `
+	id, seller_id, kind, format, year, status,
+	filename, content_type, error,
+	started_at, completed_at, created_at
+`
+
+func scanExportJob(row pgx.Row, extra ...interface{}) (*model.ExportJob, error) {
+	job := &model.ExportJob{}
+	dest := append([]interface{}{
+		&job.ID, &job.SellerID, &job.Kind, &job.Format, &job.Year, &job.Status,
+		&job.Filename, &job.ContentType, &job.Error,
+		&job.StartedAt, &job.CompletedAt, &job.CreatedAt,
+	}, extra...)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// CreateExportJob queues a new export for the worker
+func (r *OrderRepository) CreateExportJob(ctx context.Context, job *model.ExportJob) error {
+	query := `
+		INSERT INTO export_jobs (seller_id, kind, format, year, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, job.SellerID, job.Kind, job.Format, job.Year, job.Status).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	return nil
+}
+
+// GetExportJob retrieves an export job, including the file when withContent is set
+func (r *OrderRepository) GetExportJob(ctx context.Context, jobID int64, withContent bool) (*model.ExportJob, error) {
+	query := `SELECT` + exportJobColumns + `, CASE WHEN $2 THEN content END FROM export_jobs WHERE id = $1`
+
+	var content []byte
+	job, err := scanExportJob(r.db.QueryRow(ctx, query, jobID, withContent), &content)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("export job not found")
+		}
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+	job.Content = content
+
+	return job, nil
+}
+
+// ClaimExportJobs marks up to limit pending jobs as processing and returns
+// them. Jobs stuck in processing longer than staleAfter (worker crashed) are
+// claimed again.
+func (r *OrderRepository) ClaimExportJobs(ctx context.Context, staleAfter time.Duration, limit int) ([]*model.ExportJob, error) {
+	query := `
+		UPDATE export_jobs
+		SET status = 'processing', started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM export_jobs
+			WHERE status = 'pending'
+				OR (status = 'processing' AND started_at < $1)
+			ORDER BY created_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING` + exportJobColumns
+
+	rows, err := r.db.Query(ctx, query, time.Now().Add(-staleAfter), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*model.ExportJob, 0)
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// CompleteExportJob stores the generated file and marks the job ready
+func (r *OrderRepository) CompleteExportJob(ctx context.Context, jobID int64, filename, contentType string, content []byte) error {
+	query := `
+		UPDATE export_jobs
+		SET status = 'ready', filename = $1, content_type = $2, content = $3, error = NULL, completed_at = NOW()
+		WHERE id = $4
+	`
+
+	if _, err := r.db.Exec(ctx, query, filename, contentType, content, jobID); err != nil {
+		return fmt.Errorf("failed to complete export job: %w", err)
+	}
+
+	return nil
+}
+
+// FailExportJob marks the job failed with the reason shown to the seller
+func (r *OrderRepository) FailExportJob(ctx context.Context, jobID int64, reason string) error {
+	query := `
+		UPDATE export_jobs
+		SET status = 'failed', error = $1, completed_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, reason, jobID); err != nil {
+		return fmt.Errorf("failed to mark export job failed: %w", err)
+	}
+
+	return nil
+}
+
+// GetSellerPayouts retrieves a seller's payouts keyed by order. Orders paid
+// out in a wallet batch carry the batch's payout with the order's share as
+// amount. When an order has several payouts (e.g. a failed attempt and a
+// retry), the latest wins.
+func (r *OrderRepository) GetSellerPayouts(ctx context.Context, sellerID int64) (map[int64]*model.SellerPayout, error) {
+	query := `
+		SELECT order_id, payout_id, amount, status, processed_at
+		FROM (
+			SELECT order_id, payout_id, amount, status, processed_at, created_at, id
+			FROM payouts
+			WHERE seller_id = $1 AND order_id IS NOT NULL
+			UNION ALL
+			SELECT wt.order_id, p.payout_id, wt.amount, p.status, p.processed_at, p.created_at, p.id
+			FROM wallet_transactions wt
+			JOIN payouts p ON p.id = wt.payout_id
+			WHERE p.seller_id = $1 AND wt.type = 'sale'
+		) seller_payouts
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, sellerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seller payouts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := make(map[int64]*model.SellerPayout)
+	for rows.Next() {
+		p := &model.SellerPayout{}
+		if err := rows.Scan(&p.OrderID, &p.PayoutID, &p.Amount, &p.Status, &p.ProcessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payout: %w", err)
+		}
+		payouts[p.OrderID] = p
+	}
+
+	return payouts, rows.Err()
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+const hookJobColumns = `
+	id, order_id, hook, from_status, to_status, actor_role, actor_id, note,
+	status, attempts, last_error, run_after, started_at, completed_at, created_at
+`
+
+func scanHookJob(row pgx.Row) (*model.HookJob, error) {
+	job := &model.HookJob{}
+	err := row.Scan(
+		&job.ID, &job.OrderID, &job.Hook, &job.FromStatus, &job.ToStatus, &job.ActorRole, &job.ActorID, &job.Note,
+		&job.Status, &job.Attempts, &job.LastError, &job.RunAfter, &job.StartedAt, &job.CompletedAt, &job.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// EnqueueHookJobs queues the hooks of a transition in its transaction. The
+// jobs are created claimed, as the caller runs them right after commit; the
+// worker picks them up if it never gets to.
+func (r *OrderRepository) EnqueueHookJobs(ctx context.Context, tx pgx.Tx, jobs []*model.HookJob) error {
+	query := `
+		INSERT INTO order_hook_jobs (order_id, hook, from_status, to_status, actor_role, actor_id, note, status, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING` + hookJobColumns
+
+	for i, job := range jobs {
+		queued, err := scanHookJob(tx.QueryRow(ctx, query,
+			job.OrderID, job.Hook, job.FromStatus, job.ToStatus, job.ActorRole, job.ActorID, job.Note,
+			model.HookJobStatusProcessing,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to queue %s hook: %w", job.Hook, err)
+		}
+		jobs[i] = queued
+	}
+
+	return nil
+}
+
+// ClaimHookJobs marks up to limit due jobs as processing and returns them
+// oldest first. Jobs stuck in processing longer than staleAfter (the
+// process died before running them) are claimed again.
+func (r *OrderRepository) ClaimHookJobs(ctx context.Context, staleAfter time.Duration, limit int) ([]*model.HookJob, error) {
+	query := `
+		UPDATE order_hook_jobs
+		SET status = 'processing', started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM order_hook_jobs
+			WHERE (status = 'pending' AND run_after <= NOW())
+				OR (status = 'processing' AND started_at < $1)
+			ORDER BY id ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING` + hookJobColumns
+
+	rows, err := r.db.Query(ctx, query, time.Now().Add(-staleAfter), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim hook jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*model.HookJob, 0)
+	for rows.Next() {
+		job, err := scanHookJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hook job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the subquery's order
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+
+	return jobs, nil
+}
+
+// CompleteHookJob marks a job's hook as run
+func (r *OrderRepository) CompleteHookJob(ctx context.Context, jobID int64) error {
+	query := `
+		UPDATE order_hook_jobs
+		SET status = 'done', attempts = attempts + 1, last_error = NULL, completed_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, jobID); err != nil {
+		return fmt.Errorf("failed to complete hook job: %w", err)
+	}
+
+	return nil
+}
+
+// RetryHookJob records a failed attempt and puts the job back in the queue
+// until runAfter
+func (r *OrderRepository) RetryHookJob(ctx context.Context, jobID int64, runAfter time.Time, reason string) error {
+	query := `
+		UPDATE order_hook_jobs
+		SET status = 'pending', attempts = attempts + 1, last_error = $1, run_after = $2
+		WHERE id = $3
+	`
+
+	if _, err := r.db.Exec(ctx, query, reason, runAfter, jobID); err != nil {
+		return fmt.Errorf("failed to reschedule hook job: %w", err)
+	}
+
+	return nil
+}
+
+// FailHookJob records the last failed attempt; the job is not retried again
+func (r *OrderRepository) FailHookJob(ctx context.Context, jobID int64, reason string) error {
+	query := `
+		UPDATE order_hook_jobs
+		SET status = 'failed', attempts = attempts + 1, last_error = $1, completed_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, reason, jobID); err != nil {
+		return fmt.Errorf("failed to mark hook job failed: %w", err)
+	}
+
+	return nil
+}
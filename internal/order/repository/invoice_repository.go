@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// invoicePrefixes maps an invoice kind to its number prefix
+var invoicePrefixes = map[string]string{
+	model.InvoiceKindBuyerReceipt:    "INV-B",
+	model.InvoiceKindSellerStatement: "INV-S",
+}
+
+// GetOrCreateInvoice returns the invoice of the given kind for an order,
+// assigning the next number (e.g. INV-B-2026-000042) on first use
+func (r *OrderRepository) GetOrCreateInvoice(ctx context.Context, orderID int64, kind string) (*model.Invoice, error) {
+	prefix, ok := invoicePrefixes[kind]
+	if !ok {
+		return nil, fmt.Errorf("invalid invoice kind: %s", kind)
+	}
+
+	invoice, err := r.getInvoice(ctx, orderID, kind)
+	if err == nil {
+		return invoice, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	insert := `
+		INSERT INTO invoices (order_id, kind, invoice_number)
+		VALUES (
+			$1, $2,
+			$3 || '-' || TO_CHAR(NOW(), 'YYYY') || '-' || LPAD(nextval('invoice_number_seq')::TEXT, 6, '0')
+		)
+		ON CONFLICT (order_id, kind) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, insert, orderID, kind, prefix); err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	// Read back: a concurrent request may have assigned the number first
+	invoice, err = r.getInvoice(ctx, orderID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	return invoice, nil
+}
+
+func (r *OrderRepository) getInvoice(ctx context.Context, orderID int64, kind string) (*model.Invoice, error) {
+	query := `
+		SELECT id, order_id, kind, invoice_number, issued_at
+		FROM invoices
+		WHERE order_id = $1 AND kind = $2
+	`
+
+	invoice := &model.Invoice{}
+	err := r.db.QueryRow(ctx, query, orderID, kind).Scan(
+		&invoice.ID, &invoice.OrderID, &invoice.Kind, &invoice.InvoiceNumber, &invoice.IssuedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// GetInvoiceDetails retrieves the item and party names printed on an order's invoices
+func (r *OrderRepository) GetInvoiceDetails(ctx context.Context, orderID int64) (*model.InvoiceDetails, error) {
+	query := `
+		SELECT 
+			CONCAT(p.name, ' - Size ', s.size),
+			COALESCE(NULLIF(TRIM(CONCAT(b.first_name, ' ', b.last_name)), ''), b.email), b.email,
+			COALESCE(NULLIF(TRIM(CONCAT(sl.first_name, ' ', sl.last_name)), ''), sl.email), sl.email
+		FROM orders o
+		JOIN products p ON p.id = o.product_id
+		JOIN sizes s ON s.id = o.size_id
+		JOIN users b ON b.id = o.buyer_id
+		JOIN users sl ON sl.id = o.seller_id
+		WHERE o.id = $1
+	`
+
+	details := &model.InvoiceDetails{}
+	err := r.db.QueryRow(ctx, query, orderID).Scan(
+		&details.Item,
+		&details.BuyerName, &details.BuyerEmail,
+		&details.SellerName, &details.SellerEmail,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("order not found")
+		}
+		return nil, fmt.Errorf("failed to get invoice details: %w", err)
+	}
+
+	return details, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// CreateOrderMessage stores a message in an order's thread
+func (r *OrderRepository) CreateOrderMessage(ctx context.Context, msg *model.OrderMessage) error {
+	query := `
+		INSERT INTO order_messages (order_id, sender_id, sender_role, body, original_body, redacted)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		msg.OrderID, msg.SenderID, msg.SenderRole,
+		msg.Body, msg.OriginalBody, msg.Redacted,
+	).Scan(&msg.ID, &msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order message: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrderMessages retrieves an order's thread, oldest first. Messages
+// after afterID are returned when afterID is set, so clients can catch up
+// after reconnecting.
+func (r *OrderRepository) GetOrderMessages(ctx context.Context, orderID, afterID int64) ([]*model.OrderMessage, error) {
+	query := `
+		SELECT id, order_id, sender_id, sender_role, body, original_body, redacted, created_at
+		FROM order_messages
+		WHERE order_id = $1 AND id > $2
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*model.OrderMessage, 0)
+	for rows.Next() {
+		msg := &model.OrderMessage{}
+		err := rows.Scan(
+			&msg.ID, &msg.OrderID, &msg.SenderID, &msg.SenderRole,
+			&msg.Body, &msg.OriginalBody, &msg.Redacted, &msg.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
@@ -2,13 +2,35 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
 )
 
+// orderColumns is the column list scanned by scanOrder
+const orderColumns = `
+			id, order_number, match_id,
+			buyer_id, seller_id,
+			product_id, size_id,
+			price, quantity,
+			buyer_fee, seller_fee, platform_fee,
+			total_amount, seller_payout,
+			status,
+			shipping_address_id,
+			tracking_number, carrier, shipping_service, label_url,
+			outbound_tracking_number, outbound_carrier, outbound_label_url,
+			payment_at, shipped_at, delivered_at, completed_at, canceled_at,
+			buyer_notes, seller_notes, admin_notes, cancellation_reason,
+			checkout_id, ship_by,
+			currency, buyer_currency, buyer_total, buyer_fx_rate,
+			seller_currency, seller_fx_rate,
+			inventory_reference,
+			created_at, updated_at`
+
 type OrderRepository struct {
 	db *pgxpool.Pool
 }
@@ -19,6 +41,19 @@ func NewOrderRepository(db *pgxpool.Pool) *OrderRepository {
 
 // CreateOrder creates a new order
 func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) (*model.Order, error) {
+	if err := insertOrder(ctx, r.db, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// rowQuerier is implemented by both the pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func insertOrder(ctx context.Context, q rowQuerier, order *model.Order) error {
 	query := `
 		INSERT INTO orders (
 			order_number, match_id,
@@ -29,7 +64,11 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) (
 			total_amount, seller_payout,
 			status,
 			shipping_address_id,
-			buyer_notes
+			buyer_notes,
+			checkout_id,
+			currency, buyer_currency, buyer_total, buyer_fx_rate,
+			seller_currency, seller_fx_rate,
+			inventory_reference
 		) VALUES (
 			$1, $2,
 			$3, $4,
@@ -39,12 +78,16 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) (
 			$12, $13,
 			$14,
 			$15,
-			$16
+			$16,
+			$17,
+			$18, $19, $20, $21,
+			$22, $23,
+			$24
 		)
 		RETURNING id, order_number, created_at, updated_at
 	`
 
-	err := r.db.QueryRow(
+	err := q.QueryRow(
 		ctx, query,
 		order.OrderNumber, order.MatchID,
 		order.BuyerID, order.SellerID,
@@ -55,6 +98,10 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) (
 		order.Status,
 		order.ShippingAddressID,
 		order.BuyerNotes,
+		order.CheckoutID,
+		order.Price.Currency, order.BuyerTotal.Currency, order.BuyerTotal, order.BuyerFXRate,
+		order.SellerCurrency, order.SellerFXRate,
+		order.InventoryReference,
 	).Scan(
 		&order.ID,
 		&order.OrderNumber,
@@ -63,52 +110,23 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) (
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+		return fmt.Errorf("failed to create order: %w", err)
 	}
 
-	return order, nil
+	return nil
 }
 
 // GetOrderByID retrieves an order by ID
 func (r *OrderRepository) GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error) {
 	query := `
-		SELECT 
-			id, order_number, match_id,
-			buyer_id, seller_id,
-			product_id, size_id,
-			price, quantity,
-			buyer_fee, seller_fee, platform_fee,
-			total_amount, seller_payout,
-			status,
-			shipping_address_id,
-			tracking_number, carrier,
-			payment_at, shipped_at, delivered_at, completed_at, canceled_at,
-			buyer_notes, seller_notes, admin_notes, cancellation_reason,
-			created_at, updated_at
+		SELECT` + orderColumns + `
 		FROM orders
 		WHERE id = $1
 	`
 
-	order := &model.Order{}
-	err := r.db.QueryRow(ctx, query, orderID).Scan(
-		&order.ID, &order.OrderNumber, &order.MatchID,
-		&order.BuyerID, &order.SellerID,
-		&order.ProductID, &order.SizeID,
-		&order.Price, &order.Quantity,
-		&order.BuyerFee, &order.SellerFee, &order.PlatformFee,
-		&order.TotalAmount, &order.SellerPayout,
-		&order.Status,
-		&order.ShippingAddressID,
-		&order.TrackingNumber, &order.Carrier,
-		&order.PaymentAt, &order.ShippedAt, &order.DeliveredAt,
-		&order.CompletedAt, &order.CancelledAt,
-		&order.BuyerNotes, &order.SellerNotes, &order.AdminNotes,
-		&order.CancellationReason,
-		&order.CreatedAt, &order.UpdatedAt,
-	)
-
+	order, err := scanOrder(r.db.QueryRow(ctx, query, orderID))
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("order not found")
 		}
 		return nil, fmt.Errorf("failed to get order: %w", err)
@@ -120,43 +138,14 @@ func (r *OrderRepository) GetOrderByID(ctx context.Context, orderID int64) (*mod
 // GetOrderByOrderNumber retrieves an order by order number
 func (r *OrderRepository) GetOrderByOrderNumber(ctx context.Context, orderNumber string) (*model.Order, error) {
 	query := `
-		SELECT 
-			id, order_number, match_id,
-			buyer_id, seller_id,
-			product_id, size_id,
-			price, quantity,
-			buyer_fee, seller_fee, platform_fee,
-			total_amount, seller_payout,
-			status,
-			shipping_address_id,
-			tracking_number, carrier,
-			payment_at, shipped_at, delivered_at, completed_at, canceled_at,
-			buyer_notes, seller_notes, admin_notes, cancellation_reason,
-			created_at, updated_at
+		SELECT` + orderColumns + `
 		FROM orders
 		WHERE order_number = $1
 	`
 
-	order := &model.Order{}
-	err := r.db.QueryRow(ctx, query, orderNumber).Scan(
-		&order.ID, &order.OrderNumber, &order.MatchID,
-		&order.BuyerID, &order.SellerID,
-		&order.ProductID, &order.SizeID,
-		&order.Price, &order.Quantity,
-		&order.BuyerFee, &order.SellerFee, &order.PlatformFee,
-		&order.TotalAmount, &order.SellerPayout,
-		&order.Status,
-		&order.ShippingAddressID,
-		&order.TrackingNumber, &order.Carrier,
-		&order.PaymentAt, &order.ShippedAt, &order.DeliveredAt,
-		&order.CompletedAt, &order.CancelledAt,
-		&order.BuyerNotes, &order.SellerNotes, &order.AdminNotes,
-		&order.CancellationReason,
-		&order.CreatedAt, &order.UpdatedAt,
-	)
-
+	order, err := scanOrder(r.db.QueryRow(ctx, query, orderNumber))
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("order not found")
 		}
 		return nil, fmt.Errorf("failed to get order: %w", err)
@@ -168,19 +157,7 @@ func (r *OrderRepository) GetOrderByOrderNumber(ctx context.Context, orderNumber
 // ListOrders retrieves orders with optional status filter and pagination
 func (r *OrderRepository) ListOrders(ctx context.Context, status string, page, pageSize int32) ([]*model.Order, int64, error) {
 	baseQuery := `
-		SELECT 
-			id, order_number, match_id,
-			buyer_id, seller_id,
-			product_id, size_id,
-			price, quantity,
-			buyer_fee, seller_fee, platform_fee,
-			total_amount, seller_payout,
-			status,
-			shipping_address_id,
-			tracking_number, carrier,
-			payment_at, shipped_at, delivered_at, completed_at, canceled_at,
-			buyer_notes, seller_notes, admin_notes, cancellation_reason,
-			created_at, updated_at
+		SELECT` + orderColumns + `
 		FROM orders
 	`
 
@@ -223,23 +200,7 @@ func (r *OrderRepository) ListOrders(ctx context.Context, status string, page, p
 
 	orders := make([]*model.Order, 0)
 	for rows.Next() {
-		order := &model.Order{}
-		err := rows.Scan(
-			&order.ID, &order.OrderNumber, &order.MatchID,
-			&order.BuyerID, &order.SellerID,
-			&order.ProductID, &order.SizeID,
-			&order.Price, &order.Quantity,
-			&order.BuyerFee, &order.SellerFee, &order.PlatformFee,
-			&order.TotalAmount, &order.SellerPayout,
-			&order.Status,
-			&order.ShippingAddressID,
-			&order.TrackingNumber, &order.Carrier,
-			&order.PaymentAt, &order.ShippedAt, &order.DeliveredAt,
-			&order.CompletedAt, &order.CancelledAt,
-			&order.BuyerNotes, &order.SellerNotes, &order.AdminNotes,
-			&order.CancellationReason,
-			&order.CreatedAt, &order.UpdatedAt,
-		)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
@@ -252,19 +213,7 @@ func (r *OrderRepository) ListOrders(ctx context.Context, status string, page, p
 // GetBuyerOrders retrieves orders for a specific buyer
 func (r *OrderRepository) GetBuyerOrders(ctx context.Context, buyerID int64, status string, page, pageSize int32) ([]*model.Order, int64, error) {
 	baseQuery := `
-		SELECT 
-			id, order_number, match_id,
-			buyer_id, seller_id,
-			product_id, size_id,
-			price, quantity,
-			buyer_fee, seller_fee, platform_fee,
-			total_amount, seller_payout,
-			status,
-			shipping_address_id,
-			tracking_number, carrier,
-			payment_at, shipped_at, delivered_at, completed_at, canceled_at,
-			buyer_notes, seller_notes, admin_notes, cancellation_reason,
-			created_at, updated_at
+		SELECT` + orderColumns + `
 		FROM orders
 		WHERE buyer_id = $1
 	`
@@ -308,23 +257,7 @@ func (r *OrderRepository) GetBuyerOrders(ctx context.Context, buyerID int64, sta
 
 	orders := make([]*model.Order, 0)
 	for rows.Next() {
-		order := &model.Order{}
-		err := rows.Scan(
-			&order.ID, &order.OrderNumber, &order.MatchID,
-			&order.BuyerID, &order.SellerID,
-			&order.ProductID, &order.SizeID,
-			&order.Price, &order.Quantity,
-			&order.BuyerFee, &order.SellerFee, &order.PlatformFee,
-			&order.TotalAmount, &order.SellerPayout,
-			&order.Status,
-			&order.ShippingAddressID,
-			&order.TrackingNumber, &order.Carrier,
-			&order.PaymentAt, &order.ShippedAt, &order.DeliveredAt,
-			&order.CompletedAt, &order.CancelledAt,
-			&order.BuyerNotes, &order.SellerNotes, &order.AdminNotes,
-			&order.CancellationReason,
-			&order.CreatedAt, &order.UpdatedAt,
-		)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
@@ -337,19 +270,7 @@ func (r *OrderRepository) GetBuyerOrders(ctx context.Context, buyerID int64, sta
 // GetSellerOrders retrieves orders for a specific seller
 func (r *OrderRepository) GetSellerOrders(ctx context.Context, sellerID int64, status string, page, pageSize int32) ([]*model.Order, int64, error) {
 	baseQuery := `
-		SELECT 
-			id, order_number, match_id,
-			buyer_id, seller_id,
-			product_id, size_id,
-			price, quantity,
-			buyer_fee, seller_fee, platform_fee,
-			total_amount, seller_payout,
-			status,
-			shipping_address_id,
-			tracking_number, carrier,
-			payment_at, shipped_at, delivered_at, completed_at, canceled_at,
-			buyer_notes, seller_notes, admin_notes, cancellation_reason,
-			created_at, updated_at
+		SELECT` + orderColumns + `
 		FROM orders
 		WHERE seller_id = $1
 	`
@@ -393,23 +314,7 @@ func (r *OrderRepository) GetSellerOrders(ctx context.Context, sellerID int64, s
 
 	orders := make([]*model.Order, 0)
 	for rows.Next() {
-		order := &model.Order{}
-		err := rows.Scan(
-			&order.ID, &order.OrderNumber, &order.MatchID,
-			&order.BuyerID, &order.SellerID,
-			&order.ProductID, &order.SizeID,
-			&order.Price, &order.Quantity,
-			&order.BuyerFee, &order.SellerFee, &order.PlatformFee,
-			&order.TotalAmount, &order.SellerPayout,
-			&order.Status,
-			&order.ShippingAddressID,
-			&order.TrackingNumber, &order.Carrier,
-			&order.PaymentAt, &order.ShippedAt, &order.DeliveredAt,
-			&order.CompletedAt, &order.CancelledAt,
-			&order.BuyerNotes, &order.SellerNotes, &order.AdminNotes,
-			&order.CancellationReason,
-			&order.CreatedAt, &order.UpdatedAt,
-		)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
@@ -419,11 +324,13 @@ func (r *OrderRepository) GetSellerOrders(ctx context.Context, sellerID int64, s
 	return orders, total, nil
 }
 
-// UpdateOrderStatus updates the status of an order
-func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string) error {
+// TransitionStatus moves an order from entry.FromStatus to entry.ToStatus and
+// records the change in order_status_history. The update only applies while
+// the order is still in FromStatus, so concurrent transitions cannot both win.
+func (r *OrderRepository) TransitionStatus(ctx context.Context, tx pgx.Tx, entry *model.OrderStatusHistory) error {
 	var timestampCol string
 
-	switch newStatus {
+	switch entry.ToStatus {
 	case model.StatusPaid:
 		timestampCol = ", payment_at = NOW()"
 	case model.StatusShipped:
@@ -432,41 +339,71 @@ func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID int64,
 		timestampCol = ", delivered_at = NOW()"
 	case model.StatusCompleted:
 		timestampCol = ", completed_at = NOW()"
-	case model.StatusCancelled, model.StatusRefunded:
+	case model.StatusCancelled:
+		timestampCol = ", canceled_at = NOW(), cancellation_reason = $4"
+	case model.StatusRefunded:
 		timestampCol = ", canceled_at = NOW()"
 	}
 
 	query := fmt.Sprintf(`
 		UPDATE orders
 		SET status = $1%s
-		WHERE id = $2
+		WHERE id = $2 AND status = $3
 	`, timestampCol)
 
-	result, err := r.db.Exec(ctx, query, newStatus, orderID)
+	args := []interface{}{entry.ToStatus, entry.OrderID, entry.FromStatus.String}
+	if entry.ToStatus == model.StatusCancelled {
+		args = append(args, entry.Note)
+	}
+
+	result, err := tx.Exec(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("order not found")
+		return fmt.Errorf("order not found or status changed concurrently")
+	}
+
+	historyQuery := `
+		INSERT INTO order_status_history (
+			order_id, from_status, to_status, note,
+			created_by, actor_role, actor_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(
+		ctx, historyQuery,
+		entry.OrderID, entry.FromStatus, entry.ToStatus, entry.Note,
+		entry.CreatedBy, entry.ActorRole, entry.ActorID,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record status history: %w", err)
 	}
 
 	return nil
 }
 
 // AddTrackingNumber adds tracking information to an order
-func (r *OrderRepository) AddTrackingNumber(ctx context.Context, orderID int64, trackingNumber, carrier string) error {
+func (r *OrderRepository) AddTrackingNumber(ctx context.Context, tx pgx.Tx, orderID int64, trackingNumber, carrier string) error {
 	query := `
 		UPDATE orders
 		SET 
 			tracking_number = $1,
-			carrier = $2,
-			status = $3,
-			shipped_at = NOW()
-		WHERE id = $4
+			carrier = $2
+		WHERE id = $3
 	`
 
-	result, err := r.db.Exec(ctx, query, trackingNumber, carrier, model.StatusShipped, orderID)
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, trackingNumber, carrier, orderID)
+	} else {
+		result, err = r.db.Exec(ctx, query, trackingNumber, carrier, orderID)
+	}
+
 	if err != nil {
 		return fmt.Errorf("failed to add tracking number: %w", err)
 	}
@@ -478,35 +415,137 @@ func (r *OrderRepository) AddTrackingNumber(ctx context.Context, orderID int64,
 	return nil
 }
 
-// CancelOrder cancels an order
-func (r *OrderRepository) CancelOrder(ctx context.Context, orderID int64, reason string) error {
+// AttachLabel stores a purchased shipping label on an order
+func (r *OrderRepository) AttachLabel(ctx context.Context, orderID int64, trackingNumber, carrier, service, labelURL string) error {
 	query := `
 		UPDATE orders
 		SET 
-			status = $1,
-			cancellation_reason = $2,
-			canceled_at = NOW()
-		WHERE id = $3
+			tracking_number = $1,
+			carrier = $2,
+			shipping_service = $3,
+			label_url = $4
+		WHERE id = $5 AND label_url IS NULL
 	`
 
-	result, err := r.db.Exec(ctx, query, model.StatusCancelled, reason, orderID)
+	result, err := r.db.Exec(ctx, query, trackingNumber, carrier, service, labelURL, orderID)
 	if err != nil {
-		return fmt.Errorf("failed to cancel order: %w", err)
+		return fmt.Errorf("failed to attach shipping label: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("order not found")
+		return fmt.Errorf("order not found or label already created")
 	}
 
 	return nil
 }
 
+// AttachOutboundLabel stores the label for shipping an authenticated item to the buyer
+func (r *OrderRepository) AttachOutboundLabel(ctx context.Context, orderID int64, trackingNumber, carrier, labelURL string) error {
+	query := `
+		UPDATE orders
+		SET 
+			outbound_tracking_number = $1,
+			outbound_carrier = $2,
+			outbound_label_url = $3
+		WHERE id = $4 AND outbound_tracking_number IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, trackingNumber, carrier, labelURL, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to attach outbound label: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("order not found or already shipped to buyer")
+	}
+
+	return nil
+}
+
+// GetOrderByTrackingNumber finds the order a carrier tracking number belongs to
+// and reports which leg of the shipment it tracks
+func (r *OrderRepository) GetOrderByTrackingNumber(ctx context.Context, trackingNumber string) (*model.Order, string, error) {
+	query := `
+		SELECT` + orderColumns + `
+		FROM orders
+		WHERE tracking_number = $1 OR outbound_tracking_number = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	order, err := scanOrder(r.db.QueryRow(ctx, query, trackingNumber))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, "", fmt.Errorf("no order for tracking number %s", trackingNumber)
+		}
+		return nil, "", fmt.Errorf("failed to get order by tracking number: %w", err)
+	}
+
+	leg := model.ShipmentLegInbound
+	if order.OutboundTrackingNumber.Valid && order.OutboundTrackingNumber.String == trackingNumber {
+		leg = model.ShipmentLegOutbound
+	}
+
+	return order, leg, nil
+}
+
+// GetShippingAddress retrieves an address by ID
+func (r *OrderRepository) GetShippingAddress(ctx context.Context, addressID int64) (*model.Address, error) {
+	query := `
+		SELECT 
+			a.id, a.user_id,
+			COALESCE(NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), ''), u.email),
+			a.street_line1, COALESCE(a.street_line2, ''),
+			a.city, COALESCE(a.state, ''), a.postal_code, a.country
+		FROM addresses a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.id = $1
+	`
+
+	return scanAddress(r.db.QueryRow(ctx, query, addressID))
+}
+
+// GetDefaultShippingAddress retrieves a user's preferred shipping address
+func (r *OrderRepository) GetDefaultShippingAddress(ctx context.Context, userID int64) (*model.Address, error) {
+	query := `
+		SELECT 
+			a.id, a.user_id,
+			COALESCE(NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), ''), u.email),
+			a.street_line1, COALESCE(a.street_line2, ''),
+			a.city, COALESCE(a.state, ''), a.postal_code, a.country
+		FROM addresses a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.user_id = $1 AND a.address_type = 'shipping'
+		ORDER BY a.is_default DESC, a.created_at ASC
+		LIMIT 1
+	`
+
+	return scanAddress(r.db.QueryRow(ctx, query, userID))
+}
+
+func scanAddress(row pgx.Row) (*model.Address, error) {
+	address := &model.Address{}
+	err := row.Scan(
+		&address.ID, &address.UserID, &address.Name,
+		&address.StreetLine1, &address.StreetLine2,
+		&address.City, &address.State, &address.PostalCode, &address.Country,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("address not found")
+		}
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+
+	return address, nil
+}
+
 // GetOrderStatusHistory retrieves the status history for an order
 func (r *OrderRepository) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusHistory, error) {
 	query := `
 		SELECT 
 			id, order_id, from_status, to_status,
-			note, created_by, created_at
+			note, created_by, actor_role, actor_id, created_at
 		FROM order_status_history
 		WHERE order_id = $1
 		ORDER BY created_at ASC
@@ -523,7 +562,7 @@ func (r *OrderRepository) GetOrderStatusHistory(ctx context.Context, orderID int
 		h := &model.OrderStatusHistory{}
 		err := rows.Scan(
 			&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus,
-			&h.Note, &h.CreatedBy, &h.CreatedAt,
+			&h.Note, &h.CreatedBy, &h.ActorRole, &h.ActorID, &h.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan status history: %w", err)
@@ -537,25 +576,124 @@ func (r *OrderRepository) GetOrderStatusHistory(ctx context.Context, orderID int
 // GetOrderByMatchID retrieves an order by match ID
 func (r *OrderRepository) GetOrderByMatchID(ctx context.Context, matchID int64) (*model.Order, error) {
 	query := `
-		SELECT 
-			id, order_number, match_id,
-			buyer_id, seller_id,
-			product_id, size_id,
-			price, quantity,
-			buyer_fee, seller_fee, platform_fee,
-			total_amount, seller_payout,
-			status,
-			shipping_address_id,
-			tracking_number, carrier,
-			payment_at, shipped_at, delivered_at, completed_at, canceled_at,
-			buyer_notes, seller_notes, admin_notes, cancellation_reason,
-			created_at, updated_at
+		SELECT` + orderColumns + `
 		FROM orders
 		WHERE match_id = $1
 	`
 
+	order, err := scanOrder(r.db.QueryRow(ctx, query, matchID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil // Order doesn't exist yet for this match
+		}
+		return nil, fmt.Errorf("failed to get order by match ID: %w", err)
+	}
+
+	return order, nil
+}
+
+// ListUnpaidOrders retrieves orders still awaiting payment that were created before the cutoff
+func (r *OrderRepository) ListUnpaidOrders(ctx context.Context, createdBefore time.Time, limit int) ([]*model.Order, error) {
+	query := `
+		SELECT` + orderColumns + `
+		FROM orders
+		WHERE status = $1 AND created_at < $2
+		ORDER BY created_at ASC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, model.StatusPendingPayment, createdBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unpaid orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]*model.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// ListOrdersToAutoComplete retrieves delivered orders whose inspection window
+// closed before now. The window comes from vertical_order_policies for the
+// product's category, or defaultDays. Orders with an open dispute are skipped.
+func (r *OrderRepository) ListOrdersToAutoComplete(ctx context.Context, defaultDays int, now time.Time, limit int) ([]*model.PendingCompletion, error) {
+	return r.listPendingCompletions(ctx, defaultDays, now, false, limit)
+}
+
+// ListOrdersToRemind retrieves delivered orders whose inspection window closes
+// before dueBefore and whose buyer has not been reminded yet
+func (r *OrderRepository) ListOrdersToRemind(ctx context.Context, defaultDays int, dueBefore time.Time, limit int) ([]*model.PendingCompletion, error) {
+	return r.listPendingCompletions(ctx, defaultDays, dueBefore, true, limit)
+}
+
+func (r *OrderRepository) listPendingCompletions(ctx context.Context, defaultDays int, dueBefore time.Time, unremindedOnly bool, limit int) ([]*model.PendingCompletion, error) {
+	query := `
+		SELECT` + orderColumns + `, complete_at
+		FROM (
+			SELECT o.*,
+				o.delivered_at + make_interval(days => COALESCE(v.inspection_days, $2)) AS complete_at
+			FROM orders o
+			JOIN products p ON p.id = o.product_id
+			LEFT JOIN vertical_order_policies v ON v.vertical = p.category
+			WHERE o.status = $1 AND o.delivered_at IS NOT NULL
+		) orders
+		WHERE complete_at <= $3
+			AND ($4 = FALSE OR inspection_reminder_sent_at IS NULL)
+			AND NOT EXISTS (
+				SELECT 1 FROM disputes d
+				WHERE d.order_id = orders.id AND d.status <> 'resolved'
+			)
+		ORDER BY complete_at ASC
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, model.StatusDelivered, defaultDays, dueBefore, unremindedOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delivered orders: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make([]*model.PendingCompletion, 0)
+	for rows.Next() {
+		var completeAt time.Time
+		order, err := scanOrder(rows, &completeAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		pending = append(pending, &model.PendingCompletion{Order: order, CompleteAt: completeAt})
+	}
+
+	return pending, nil
+}
+
+// MarkInspectionReminderSent records that the buyer was told the window is closing
+func (r *OrderRepository) MarkInspectionReminderSent(ctx context.Context, orderID int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE orders SET inspection_reminder_sent_at = NOW() WHERE id = $1`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to mark reminder sent: %w", err)
+	}
+
+	return nil
+}
+
+// BeginTx starts a new transaction
+func (r *OrderRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+// scanOrder scans a row selected with orderColumns. extra receives any
+// columns selected after them.
+func scanOrder(row pgx.Row, extra ...interface{}) (*model.Order, error) {
 	order := &model.Order{}
-	err := r.db.QueryRow(ctx, query, matchID).Scan(
+	var currency, buyerCurrency string
+	dest := []interface{}{
 		&order.ID, &order.OrderNumber, &order.MatchID,
 		&order.BuyerID, &order.SellerID,
 		&order.ProductID, &order.SizeID,
@@ -564,20 +702,29 @@ func (r *OrderRepository) GetOrderByMatchID(ctx context.Context, matchID int64)
 		&order.TotalAmount, &order.SellerPayout,
 		&order.Status,
 		&order.ShippingAddressID,
-		&order.TrackingNumber, &order.Carrier,
+		&order.TrackingNumber, &order.Carrier, &order.ShippingService, &order.LabelURL,
+		&order.OutboundTrackingNumber, &order.OutboundCarrier, &order.OutboundLabelURL,
 		&order.PaymentAt, &order.ShippedAt, &order.DeliveredAt,
 		&order.CompletedAt, &order.CancelledAt,
 		&order.BuyerNotes, &order.SellerNotes, &order.AdminNotes,
 		&order.CancellationReason,
+		&order.CheckoutID, &order.ShipBy,
+		&currency, &buyerCurrency, &order.BuyerTotal, &order.BuyerFXRate,
+		&order.SellerCurrency, &order.SellerFXRate,
+		&order.InventoryReference,
 		&order.CreatedAt, &order.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Order doesn't exist yet for this match
-		}
-		return nil, fmt.Errorf("failed to get order by match ID: %w", err)
 	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	order.Price = order.Price.WithCurrency(currency)
+	order.BuyerFee = order.BuyerFee.WithCurrency(currency)
+	order.SellerFee = order.SellerFee.WithCurrency(currency)
+	order.PlatformFee = order.PlatformFee.WithCurrency(currency)
+	order.TotalAmount = order.TotalAmount.WithCurrency(currency)
+	order.SellerPayout = order.SellerPayout.WithCurrency(currency)
+	order.BuyerTotal = order.BuyerTotal.WithCurrency(buyerCurrency)
 
 	return order, nil
 }
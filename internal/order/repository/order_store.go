@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// OrderStore defines the database operations the order service uses.
+// OrderRepository implements it on Postgres. Methods taking a pgx.Tx write
+// in the caller's transaction, started with BeginTx.
+type OrderStore interface {
+	BeginTx(ctx context.Context) (pgx.Tx, error)
+
+	// ============================
+	// ORDERS
+	// ============================
+
+	CreateOrder(ctx context.Context, order *model.Order) (*model.Order, error)
+	GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error)
+	GetOrderByOrderNumber(ctx context.Context, orderNumber string) (*model.Order, error)
+	GetOrderByMatchID(ctx context.Context, matchID int64) (*model.Order, error)
+	GetOrderByTrackingNumber(ctx context.Context, trackingNumber string) (*model.Order, string, error)
+	ListOrders(ctx context.Context, status string, page, pageSize int32) ([]*model.Order, int64, error)
+	GetBuyerOrders(ctx context.Context, buyerID int64, status string, page, pageSize int32) ([]*model.Order, int64, error)
+	GetSellerOrders(ctx context.Context, sellerID int64, status string, page, pageSize int32) ([]*model.Order, int64, error)
+	SearchOrders(ctx context.Context, search *model.OrderSearch) ([]*model.Order, string, error)
+	TransitionStatus(ctx context.Context, tx pgx.Tx, entry *model.OrderStatusHistory) error
+	GetOrderStatusHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusHistory, error)
+	GetShippingAddress(ctx context.Context, addressID int64) (*model.Address, error)
+	GetDefaultShippingAddress(ctx context.Context, userID int64) (*model.Address, error)
+	ListUnpaidOrders(ctx context.Context, createdBefore time.Time, limit int) ([]*model.Order, error)
+	ListOrdersToAutoComplete(ctx context.Context, defaultDays int, now time.Time, limit int) ([]*model.PendingCompletion, error)
+	ListOrdersToRemind(ctx context.Context, defaultDays int, dueBefore time.Time, limit int) ([]*model.PendingCompletion, error)
+	MarkInspectionReminderSent(ctx context.Context, orderID int64) error
+
+	// ============================
+	// SHIPPING
+	// ============================
+
+	AddTrackingNumber(ctx context.Context, tx pgx.Tx, orderID int64, trackingNumber, carrier string) error
+	AttachLabel(ctx context.Context, orderID int64, trackingNumber, carrier, service, labelURL string) error
+	AttachOutboundLabel(ctx context.Context, orderID int64, trackingNumber, carrier, labelURL string) error
+	CreateShipmentEvent(ctx context.Context, event *model.ShipmentEvent) (bool, error)
+	GetShipmentEvents(ctx context.Context, orderID int64) ([]*model.ShipmentEvent, error)
+	AssignShipDeadlines(ctx context.Context, defaultHours int) (int64, error)
+	ListShipmentsToRemind(ctx context.Context, dueBefore time.Time, limit int) ([]*model.PendingShipment, error)
+	ListOverdueShipments(ctx context.Context, now time.Time, limit int) ([]*model.PendingShipment, error)
+	MarkShipReminderSent(ctx context.Context, orderID int64) error
+	CreateSellerPenalty(ctx context.Context, penalty *model.SellerPenalty) (bool, error)
+
+	// ============================
+	// AUTHENTICATION AND RETURNS
+	// ============================
+
+	GetUserRole(ctx context.Context, userID int64) (string, error)
+	CreateAuthentication(ctx context.Context, tx pgx.Tx, auth *model.Authentication) error
+	GetAuthentications(ctx context.Context, orderID int64) ([]*model.Authentication, error)
+	CreateReturn(ctx context.Context, ret *model.OrderReturn) error
+
+	// ============================
+	// REFUNDS AND DISPUTES
+	// ============================
+
+	// CreateAdjustment records an adjustment once per reference; the flag
+	// is false when it already was
+	CreateAdjustment(ctx context.Context, adj *model.OrderAdjustment) (bool, error)
+	GetAdjustments(ctx context.Context, orderID int64) ([]*model.OrderAdjustment, error)
+
+	CreateDispute(ctx context.Context, tx pgx.Tx, dispute *model.Dispute) error
+	GetDispute(ctx context.Context, disputeID int64) (*model.Dispute, error)
+	GetOpenDisputeByOrderID(ctx context.Context, orderID int64) (*model.Dispute, error)
+	ListDisputes(ctx context.Context, status string, page, pageSize int32) ([]*model.Dispute, int64, error)
+	UpdateDisputeStatus(ctx context.Context, tx pgx.Tx, disputeID int64, status string) error
+	ResolveDispute(ctx context.Context, tx pgx.Tx, dispute *model.Dispute) error
+	MarkDisputePayoutHeld(ctx context.Context, disputeID int64) error
+	GetDisputeRefundTotal(ctx context.Context, orderID int64) (money.Money, error)
+	AddDisputeEvent(ctx context.Context, tx pgx.Tx, event *model.DisputeEvent, details map[string]interface{}) error
+	GetDisputeEvents(ctx context.Context, disputeID int64) ([]*model.DisputeEvent, error)
+
+	// ============================
+	// CARTS
+	// ============================
+
+	AddCartItem(ctx context.Context, item *model.CartItem) error
+	RemoveCartItem(ctx context.Context, buyerID, itemID int64) error
+	GetCartItems(ctx context.Context, buyerID int64) ([]*model.CartItem, error)
+	CreateCheckout(ctx context.Context, checkout *model.Checkout, orders []*model.Order) error
+	CompleteCheckout(ctx context.Context, checkoutID int64, paymentIntentID string, cartItemIDs []int64) error
+	GetCheckout(ctx context.Context, checkoutID int64) (*model.Checkout, []*model.Order, error)
+
+	// ============================
+	// MESSAGES
+	// ============================
+
+	CreateOrderMessage(ctx context.Context, msg *model.OrderMessage) error
+	GetOrderMessages(ctx context.Context, orderID, afterID int64) ([]*model.OrderMessage, error)
+
+	// ============================
+	// STATUS HOOK JOBS
+	// ============================
+
+	EnqueueHookJobs(ctx context.Context, tx pgx.Tx, jobs []*model.HookJob) error
+	ClaimHookJobs(ctx context.Context, staleAfter time.Duration, limit int) ([]*model.HookJob, error)
+	CompleteHookJob(ctx context.Context, jobID int64) error
+	RetryHookJob(ctx context.Context, jobID int64, runAfter time.Time, reason string) error
+	FailHookJob(ctx context.Context, jobID int64, reason string) error
+}
+
+var _ OrderStore = (*OrderRepository)(nil)
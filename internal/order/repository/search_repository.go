@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// OrderSearchSQL is the SQL of an order search, for listings that join
+// orders to other tables
+type OrderSearchSQL struct {
+	Where string // search filters, "TRUE" without any
+	Args  []interface{}
+
+	Keyset     string // cursor condition, empty on the first page
+	KeysetArgs []interface{}
+
+	OrderBy string // sort key and then ID
+}
+
+// BuildOrderSearch builds the SQL of a normalized search. Order columns are
+// qualified with alias, if set; placeholders are numbered Args first, then
+// KeysetArgs.
+func BuildOrderSearch(search *model.OrderSearch, alias string) (*OrderSearchSQL, error) {
+	cursor, err := search.DecodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	col := func(name string) string {
+		if alias == "" {
+			return name
+		}
+		return alias + "." + name
+	}
+
+	var conditions []string
+	q := &OrderSearchSQL{}
+	arg := func(v interface{}) string {
+		q.Args = append(q.Args, v)
+		return fmt.Sprintf("$%d", len(q.Args))
+	}
+
+	var user string
+	if search.UserID != 0 {
+		user = arg(search.UserID)
+		conditions = append(conditions, fmt.Sprintf("(%s = %s OR %s = %s)", col("buyer_id"), user, col("seller_id"), user))
+	}
+	if search.BuyerID != 0 {
+		conditions = append(conditions, col("buyer_id")+" = "+arg(search.BuyerID))
+	}
+	if search.SellerID != 0 {
+		conditions = append(conditions, col("seller_id")+" = "+arg(search.SellerID))
+	}
+	if search.CounterpartyID != 0 {
+		cp := arg(search.CounterpartyID)
+		if user != "" {
+			conditions = append(conditions, fmt.Sprintf("((%s = %s AND %s = %s) OR (%s = %s AND %s = %s))",
+				col("buyer_id"), user, col("seller_id"), cp, col("seller_id"), user, col("buyer_id"), cp))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%s = %s OR %s = %s)", col("buyer_id"), cp, col("seller_id"), cp))
+		}
+	}
+
+	if len(search.Statuses) > 0 {
+		conditions = append(conditions, col("status")+" = ANY("+arg(search.Statuses)+")")
+	}
+	if search.CreatedFrom != nil {
+		conditions = append(conditions, col("created_at")+" >= "+arg(*search.CreatedFrom))
+	}
+	if search.CreatedTo != nil {
+		conditions = append(conditions, col("created_at")+" <= "+arg(*search.CreatedTo))
+	}
+
+	if search.ProductID != 0 {
+		conditions = append(conditions, col("product_id")+" = "+arg(search.ProductID))
+	}
+	if search.SKU != "" {
+		conditions = append(conditions, col("product_id")+" IN (SELECT id FROM products WHERE LOWER(sku) = LOWER("+arg(search.SKU)+"))")
+	}
+	if search.SizeID != 0 {
+		conditions = append(conditions, col("size_id")+" = "+arg(search.SizeID))
+	}
+	if search.Size != "" {
+		conditions = append(conditions, col("size_id")+" IN (SELECT id FROM sizes WHERE size = "+arg(search.Size)+")")
+	}
+
+	if search.MinPrice.IsPositive() {
+		conditions = append(conditions, col("price")+" >= "+arg(search.MinPrice))
+	}
+	if search.MaxPrice.IsPositive() {
+		conditions = append(conditions, col("price")+" <= "+arg(search.MaxPrice))
+	}
+
+	if search.OrderNumberPrefix != "" {
+		conditions = append(conditions, col("order_number")+" LIKE "+arg(escapeLike(strings.ToUpper(search.OrderNumberPrefix))+"%"))
+	}
+	if search.Carrier != "" {
+		c := arg(search.Carrier)
+		conditions = append(conditions, fmt.Sprintf("(LOWER(%s) = LOWER(%s) OR LOWER(%s) = LOWER(%s))", col("carrier"), c, col("outbound_carrier"), c))
+	}
+
+	q.Where = "TRUE"
+	if len(conditions) > 0 {
+		q.Where = strings.Join(conditions, " AND ")
+	}
+
+	// Keyset pagination on (sort key, id)
+	sortColumn, cast := col("created_at"), "timestamp"
+	switch search.SortBy {
+	case model.SortByPrice:
+		sortColumn, cast = col("price"), "numeric"
+	case model.SortByTotalAmount:
+		sortColumn, cast = col("total_amount"), "numeric"
+	}
+	direction, cmp := "DESC", "<"
+	if search.SortOrder == "asc" {
+		direction, cmp = "ASC", ">"
+	}
+	if cursor != nil {
+		n := len(q.Args)
+		q.Keyset = fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d)", sortColumn, col("id"), cmp, n+1, cast, n+2)
+		q.KeysetArgs = []interface{}{cursor.Value, cursor.ID}
+	}
+	q.OrderBy = fmt.Sprintf("%s %s, %s %s", sortColumn, direction, col("id"), direction)
+
+	return q, nil
+}
+
+// SearchOrders retrieves one page of orders matching the search, ordered by
+// the sort key and then ID. nextCursor is empty on the last page. The search
+// must be normalized.
+func (r *OrderRepository) SearchOrders(ctx context.Context, search *model.OrderSearch) (orders []*model.Order, nextCursor string, err error) {
+	q, err := BuildOrderSearch(search, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	args := append(q.Args, q.KeysetArgs...)
+	query := `
+		SELECT` + orderColumns + `
+		FROM orders
+		WHERE ` + q.Where
+	if q.Keyset != "" {
+		query += " AND " + q.Keyset
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", q.OrderBy, len(args)+1)
+	args = append(args, search.Limit+1)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to search orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders = make([]*model.Order, 0, search.Limit)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to search orders: %w", err)
+	}
+
+	// One extra row tells us there is another page
+	if len(orders) > int(search.Limit) {
+		orders = orders[:search.Limit]
+		nextCursor = search.NextCursor(orders[len(orders)-1])
+	}
+
+	return orders, nextCursor, nil
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
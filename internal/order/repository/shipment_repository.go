@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// CreateShipmentEvent stores a carrier scan. It returns false without error
+// when the carrier already delivered an event with the same ID.
+func (r *OrderRepository) CreateShipmentEvent(ctx context.Context, event *model.ShipmentEvent) (bool, error) {
+	query := `
+		INSERT INTO shipment_events (
+			order_id, leg, tracking_number, carrier,
+			status, description, location, event_id, occurred_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		ON CONFLICT (carrier, event_id) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	rows, err := r.db.Query(
+		ctx, query,
+		event.OrderID, event.Leg, event.TrackingNumber, event.Carrier,
+		event.Status, event.Description, event.Location, event.EventID, event.OccurredAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create shipment event: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+
+	if err := rows.Scan(&event.ID, &event.CreatedAt); err != nil {
+		return false, fmt.Errorf("failed to scan shipment event: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetShipmentEvents retrieves the tracking timeline of an order, oldest first
+func (r *OrderRepository) GetShipmentEvents(ctx context.Context, orderID int64) ([]*model.ShipmentEvent, error) {
+	query := `
+		SELECT 
+			id, order_id, leg, tracking_number, carrier,
+			status, description, location, event_id,
+			occurred_at, created_at
+		FROM shipment_events
+		WHERE order_id = $1
+		ORDER BY occurred_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipment events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*model.ShipmentEvent
+	for rows.Next() {
+		event := &model.ShipmentEvent{}
+		err := rows.Scan(
+			&event.ID, &event.OrderID, &event.Leg, &event.TrackingNumber, &event.Carrier,
+			&event.Status, &event.Description, &event.Location, &event.EventID,
+			&event.OccurredAt, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shipment event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// AssignShipDeadlines sets ship_by on paid orders that don't have one yet.
+// The window comes from vertical_order_policies for the product's category,
+// or defaultHours, and starts when the order was paid.
+func (r *OrderRepository) AssignShipDeadlines(ctx context.Context, defaultHours int) (int64, error) {
+	query := `
+		UPDATE orders o
+		SET ship_by = o.payment_at + make_interval(hours => COALESCE(v.ship_within_hours, $3))
+		FROM products p
+		LEFT JOIN vertical_order_policies v ON v.vertical = p.category
+		WHERE p.id = o.product_id
+			AND o.status IN ($1, $2)
+			AND o.payment_at IS NOT NULL
+			AND o.ship_by IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, model.StatusPaid, model.StatusProcessing, defaultHours)
+	if err != nil {
+		return 0, fmt.Errorf("failed to assign ship deadlines: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ListOverdueShipments retrieves paid orders whose shipping deadline passed before now
+func (r *OrderRepository) ListOverdueShipments(ctx context.Context, now time.Time, limit int) ([]*model.PendingShipment, error) {
+	return r.listPendingShipments(ctx, now, false, limit)
+}
+
+// ListShipmentsToRemind retrieves paid orders due to ship before dueBefore
+// whose seller has not been reminded yet
+func (r *OrderRepository) ListShipmentsToRemind(ctx context.Context, dueBefore time.Time, limit int) ([]*model.PendingShipment, error) {
+	return r.listPendingShipments(ctx, dueBefore, true, limit)
+}
+
+func (r *OrderRepository) listPendingShipments(ctx context.Context, dueBefore time.Time, unremindedOnly bool, limit int) ([]*model.PendingShipment, error) {
+	query := `
+		SELECT` + orderColumns + `
+		FROM orders
+		WHERE status IN ($1, $2)
+			AND ship_by <= $3
+			AND ($4 = FALSE OR ship_reminder_sent_at IS NULL)
+		ORDER BY ship_by ASC
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, model.StatusPaid, model.StatusProcessing, dueBefore, unremindedOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending shipments: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make([]*model.PendingShipment, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		pending = append(pending, &model.PendingShipment{Order: order, ShipBy: order.ShipBy.Time})
+	}
+
+	return pending, rows.Err()
+}
+
+// MarkShipReminderSent records that the seller was told the deadline is near
+func (r *OrderRepository) MarkShipReminderSent(ctx context.Context, orderID int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE orders SET ship_reminder_sent_at = NOW() WHERE id = $1`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to mark ship reminder sent: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSellerPenalty records a penalty. Recording the same penalty for an
+// order twice is a no-op; created reports whether a new row was written.
+func (r *OrderRepository) CreateSellerPenalty(ctx context.Context, penalty *model.SellerPenalty) (created bool, err error) {
+	query := `
+		INSERT INTO seller_penalties (seller_id, order_id, reason, amount, note)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id, reason) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, penalty.SellerID, penalty.OrderID, penalty.Reason, penalty.Amount, penalty.Note)
+	if err != nil {
+		return false, fmt.Errorf("failed to create seller penalty: %w", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&penalty.ID, &penalty.CreatedAt); err != nil {
+			return false, fmt.Errorf("failed to create seller penalty: %w", err)
+		}
+		created = true
+	}
+
+	return created, rows.Err()
+}
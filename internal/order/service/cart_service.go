@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	fxModel "github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	biddingPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/bidding"
+	paymentPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
+)
+
+// maxCartQuantity caps how many pairs of one product/size a cart may hold
+const maxCartQuantity = 10
+
+// CheckoutResult is returned to the buyer after a successful checkout
+type CheckoutResult struct {
+	Checkout     *model.Checkout
+	Orders       []*model.Order
+	ClientSecret string // confirms the combined PaymentIntent on the client
+}
+
+// AddToCart adds a product/size at its current lowest ask to the buyer's cart
+func (s *OrderService) AddToCart(ctx context.Context, buyerID, productID, sizeID int64, quantity int32) (*model.CartItem, error) {
+	if quantity <= 0 {
+		quantity = 1
+	}
+	if quantity > maxCartQuantity {
+		return nil, fmt.Errorf("quantity cannot exceed %d", maxCartQuantity)
+	}
+
+	price, err := s.lowestAskPrice(ctx, productID, sizeID)
+	if err != nil {
+		return nil, err
+	}
+	if price.IsZero() {
+		return nil, fmt.Errorf("no asks available for this product and size")
+	}
+
+	item := &model.CartItem{
+		BuyerID:    buyerID,
+		ProductID:  productID,
+		SizeID:     sizeID,
+		Quantity:   quantity,
+		PriceAtAdd: price,
+	}
+
+	if err := s.repo.AddCartItem(ctx, item); err != nil {
+		return nil, err
+	}
+	item.CurrentPrice = price
+
+	return item, nil
+}
+
+// RemoveFromCart removes an item from the buyer's cart
+func (s *OrderService) RemoveFromCart(ctx context.Context, buyerID, itemID int64) error {
+	return s.repo.RemoveCartItem(ctx, buyerID, itemID)
+}
+
+// GetCart returns the buyer's cart with current lowest asks, also shown in
+// currency (empty: the settlement currency)
+func (s *OrderService) GetCart(ctx context.Context, buyerID int64, currency string) ([]*model.CartItem, error) {
+	quote, err := s.buyerQuote(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.GetCartItems(ctx, buyerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		price, err := s.lowestAskPrice(ctx, item.ProductID, item.SizeID)
+		if err != nil {
+			log.Printf("Cart %d: failed to refresh price for product %d size %d: %v", buyerID, item.ProductID, item.SizeID, err)
+			continue
+		}
+		item.CurrentPrice = price
+		item.DisplayPrice = quote.Apply(price)
+	}
+
+	return items, nil
+}
+
+// Checkout buys every item in the buyer's cart at once. The lowest asks are
+// locked and matched in one Bidding Service transaction, one order is created
+// per matched ask (each ask has a single seller), and the buyer pays all of
+// them with one PaymentIntent split across the orders' payments so refunds
+// stay per order. Orders are priced in the settlement currency and charged in
+// currency at one rate, snapshotted on every order.
+func (s *OrderService) Checkout(ctx context.Context, buyerID int64, shippingAddressID *int64, stripeCustomerID, currency string) (*CheckoutResult, error) {
+	if s.biddingClient == nil || s.paymentClient == nil {
+		return nil, fmt.Errorf("checkout is unavailable")
+	}
+
+	quote, err := s.buyerQuote(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.GetCartItems(ctx, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("cart is empty")
+	}
+
+	// One ask per pair, capped at the price the buyer saw
+	var checkoutItems []*biddingPb.CheckoutItem
+	itemIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		for i := int32(0); i < item.Quantity; i++ {
+			checkoutItems = append(checkoutItems, &biddingPb.CheckoutItem{
+				ProductId:     item.ProductID,
+				SizeId:        item.SizeID,
+				MaxPrice:      item.PriceAtAdd.Major(),
+				MaxPriceMinor: item.PriceAtAdd.Amount,
+			})
+		}
+		itemIDs = append(itemIDs, item.ID)
+	}
+
+	matchResp, err := s.biddingClient.CheckoutAsks(ctx, &biddingPb.CheckoutAsksRequest{
+		BuyerId: buyerID,
+		Items:   checkoutItems,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve asks: %w", err)
+	}
+	if matchResp.Error != "" {
+		return nil, errors.New(matchResp.Error)
+	}
+
+	checkout := &model.Checkout{
+		BuyerID:     buyerID,
+		ItemCount:   int32(len(matchResp.Matches)),
+		TotalAmount: money.Zero(quote.To),
+	}
+	orders := make([]*model.Order, 0, len(matchResp.Matches))
+	matchIDs := make([]int64, 0, len(matchResp.Matches))
+	for _, match := range matchResp.Matches {
+		order := s.buildOrder(
+			match.Id, match.BuyerId, match.SellerId, match.ProductId, match.SizeId,
+			money.FromProto(match.PriceMinor, match.Price, match.Currency), match.Quantity, s.defaultBuyerFeePercentage, s.defaultSellerFeePercentage,
+		)
+		order.InventoryReference = match.InventoryReference
+		if shippingAddressID != nil {
+			order.ShippingAddressID = sql.NullInt64{Int64: *shippingAddressID, Valid: true}
+		}
+		if match.SellerCurrency != "" {
+			order.SellerCurrency = match.SellerCurrency
+			order.SellerFXRate = matchRate(match.SellerFxRate)
+		}
+		order.BuyerTotal = quote.Apply(order.TotalAmount)
+		order.BuyerFXRate = quote.Rate
+
+		checkout.TotalAmount = checkout.TotalAmount.Add(order.BuyerTotal)
+		orders = append(orders, order)
+		matchIDs = append(matchIDs, match.Id)
+	}
+
+	if err := s.repo.CreateCheckout(ctx, checkout, orders); err != nil {
+		s.revertCheckout(ctx, matchIDs)
+		return nil, err
+	}
+
+	// Each order's payment row carries its own share of the intent
+	allocations := make([]*paymentPb.PaymentAllocation, 0, len(orders))
+	for _, order := range orders {
+		s.fxService.RecordBestEffort(ctx, quote, order.TotalAmount, fxModel.PurposeCheckout, fxModel.ReferenceOrder, order.ID)
+		allocations = append(allocations, &paymentPb.PaymentAllocation{
+			OrderId:     order.ID,
+			Amount:      order.BuyerTotal.Major(),
+			AmountMinor: order.BuyerTotal.Amount,
+		})
+	}
+
+	payResp, err := s.paymentClient.CreateCheckoutPaymentIntent(ctx, &paymentPb.CreateCheckoutPaymentIntentRequest{
+		CheckoutId:       checkout.ID,
+		UserId:           buyerID,
+		Allocations:      allocations,
+		Currency:         checkout.TotalAmount.Currency,
+		StripeCustomerId: stripeCustomerID,
+	})
+	if err == nil && payResp.Error != "" {
+		err = errors.New(payResp.Error)
+	}
+	if err != nil {
+		s.abandonCheckout(ctx, orders)
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	paymentIntentID := ""
+	if len(payResp.Payments) > 0 {
+		paymentIntentID = payResp.Payments[0].StripePaymentIntentId
+	}
+	checkout.StripePaymentIntentID = sql.NullString{String: paymentIntentID, Valid: paymentIntentID != ""}
+
+	if err := s.repo.CompleteCheckout(ctx, checkout.ID, paymentIntentID, itemIDs); err != nil {
+		// The orders and payment exist; the buyer can still pay
+		log.Printf("Checkout %d: %v", checkout.ID, err)
+	}
+
+	return &CheckoutResult{
+		Checkout:     checkout,
+		Orders:       orders,
+		ClientSecret: payResp.ClientSecret,
+	}, nil
+}
+
+// GetCheckout returns a buyer's checkout and its orders
+func (s *OrderService) GetCheckout(ctx context.Context, checkoutID, buyerID int64) (*model.Checkout, []*model.Order, error) {
+	checkout, orders, err := s.repo.GetCheckout(ctx, checkoutID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if checkout.BuyerID != buyerID {
+		return nil, nil, fmt.Errorf("unauthorized: not your checkout")
+	}
+
+	return checkout, orders, nil
+}
+
+// lowestAskPrice returns the lowest active ask, or zero when there is none
+func (s *OrderService) lowestAskPrice(ctx context.Context, productID, sizeID int64) (money.Money, error) {
+	if s.biddingClient == nil {
+		return money.Money{}, fmt.Errorf("bidding service is unavailable")
+	}
+
+	resp, err := s.biddingClient.GetLowestAsk(ctx, &biddingPb.GetLowestAskRequest{
+		ProductId: productID,
+		SizeId:    sizeID,
+	})
+	if err != nil {
+		return money.Money{}, err
+	}
+	if resp.Error != "" {
+		return money.Money{}, errors.New(resp.Error)
+	}
+	if resp.Ask == nil {
+		return money.Zero(money.DefaultCurrency), nil
+	}
+
+	// Asks may be listed in any currency; carts compare settlement prices
+	return money.FromProto(resp.Ask.SettlementPriceMinor, resp.Ask.SettlementPrice, money.DefaultCurrency), nil
+}
+
+// matchRate reads a rate snapshotted on a match; matches made before
+// multi-currency listings carry none
+func matchRate(s string) money.Rate {
+	rate, err := money.ParseRate(s)
+	if err != nil {
+		return money.OneRate()
+	}
+	return rate
+}
+
+// revertCheckout puts the matched asks back on the market
+func (s *OrderService) revertCheckout(ctx context.Context, matchIDs []int64) {
+	resp, err := s.biddingClient.RevertCheckout(ctx, &biddingPb.RevertCheckoutRequest{MatchIds: matchIDs})
+	if err != nil {
+		log.Printf("Failed to revert checkout matches %v: %v", matchIDs, err)
+	} else if resp.Error != "" {
+		log.Printf("Failed to revert checkout matches %v: %s", matchIDs, resp.Error)
+	}
+}
+
+// abandonCheckout cancels the checkout's orders when the payment could not
+// be created, releasing inventory and relisting the sellers' asks
+func (s *OrderService) abandonCheckout(ctx context.Context, orders []*model.Order) {
+	for _, order := range orders {
+		if _, err := s.CancelOrder(ctx, order.ID, lifecycle.System(), "Checkout payment could not be created"); err != nil {
+			log.Printf("Failed to cancel checkout order %s: %v", order.OrderNumber, err)
+			continue
+		}
+		s.relistAsk(ctx, order)
+	}
+}
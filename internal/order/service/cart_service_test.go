@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	biddingPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/bidding"
+)
+
+// biddingClient matches checkout items to the asks it was given, in order
+type biddingClient struct {
+	biddingPb.BiddingServiceClient
+	asks []*biddingPb.Match
+
+	items    []*biddingPb.CheckoutItem
+	reverted []int64
+	relisted []int64
+}
+
+func (c *biddingClient) CheckoutAsks(ctx context.Context, in *biddingPb.CheckoutAsksRequest, opts ...grpc.CallOption) (*biddingPb.CheckoutAsksResponse, error) {
+	c.items = in.Items
+	if len(in.Items) > len(c.asks) {
+		return &biddingPb.CheckoutAsksResponse{Error: "not enough asks"}, nil
+	}
+
+	matches := make([]*biddingPb.Match, len(in.Items))
+	for i, item := range in.Items {
+		match := *c.asks[i]
+		match.BuyerId = in.BuyerId
+		match.ProductId, match.SizeId = item.ProductId, item.SizeId
+		matches[i] = &match
+	}
+	return &biddingPb.CheckoutAsksResponse{Matches: matches}, nil
+}
+
+func (c *biddingClient) RevertCheckout(ctx context.Context, in *biddingPb.RevertCheckoutRequest, opts ...grpc.CallOption) (*biddingPb.RevertCheckoutResponse, error) {
+	c.reverted = append(c.reverted, in.MatchIds...)
+	return &biddingPb.RevertCheckoutResponse{}, nil
+}
+
+func (c *biddingClient) RelistAsk(ctx context.Context, in *biddingPb.RelistAskRequest, opts ...grpc.CallOption) (*biddingPb.RelistAskResponse, error) {
+	c.relisted = append(c.relisted, in.MatchId)
+	return &biddingPb.RelistAskResponse{}, nil
+}
+
+// newCheckoutService sets up a cart of two pairs of one size and one of
+// another, sold by two sellers
+func newCheckoutService() (*testService, *biddingClient) {
+	ts := newTestService()
+	bids := &biddingClient{}
+	for i, ask := range []struct {
+		seller int64
+		price  int64
+	}{{8, 25000}, {9, 24000}, {9, 30000}} {
+		matchID := int64(21 + i)
+		bids.asks = append(bids.asks, &biddingPb.Match{
+			Id:                 matchID,
+			SellerId:           ask.seller,
+			PriceMinor:         ask.price,
+			Currency:           "USD",
+			Quantity:           1,
+			InventoryReference: fmt.Sprintf("ask-%d", matchID),
+		})
+	}
+	ts.biddingClient = bids
+
+	ts.store.addCartItem(7, 100, 200, 2, usd(26000))
+	ts.store.addCartItem(7, 101, 201, 1, usd(30000))
+	return ts, bids
+}
+
+func TestCheckoutCreatesOrderPerAsk(t *testing.T) {
+	ts, bids := newCheckoutService()
+
+	result, err := ts.Checkout(context.Background(), 7, nil, "cus_1", "")
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	// One ask per pair, capped at the cart price
+	if len(bids.items) != 3 || bids.items[0].MaxPriceMinor != 26000 || bids.items[2].SizeId != 201 {
+		t.Errorf("checkout items = %+v", bids.items)
+	}
+
+	if len(result.Orders) != 3 {
+		t.Fatalf("orders = %d, want 3", len(result.Orders))
+	}
+	wantTotals := []int64{25750, 24720, 30900}
+	for i, order := range result.Orders {
+		if order.Status != model.StatusPendingPayment || order.CheckoutID.Int64 != result.Checkout.ID {
+			t.Errorf("order %d = %s in checkout %d", i, order.Status, order.CheckoutID.Int64)
+		}
+		if order.BuyerTotal.Amount != wantTotals[i] {
+			t.Errorf("order %d buyer total = %s, want %d", i, order.BuyerTotal, wantTotals[i])
+		}
+		if want := fmt.Sprintf("ask-%d", order.MatchID); order.InventoryReference != want {
+			t.Errorf("order %d inventory reference = %s, want %s", i, order.InventoryReference, want)
+		}
+	}
+	if result.Orders[0].SellerID != 8 || result.Orders[1].SellerID != 9 {
+		t.Errorf("sellers = %d, %d; want 8, 9", result.Orders[0].SellerID, result.Orders[1].SellerID)
+	}
+
+	// One payment intent, split over the orders
+	if len(ts.payments.intents) != 1 {
+		t.Fatalf("payment intents = %d, want 1", len(ts.payments.intents))
+	}
+	var allocated int64
+	for _, allocation := range ts.payments.intents[0].Allocations {
+		allocated += allocation.AmountMinor
+	}
+	if allocated != result.Checkout.TotalAmount.Amount || allocated != 81370 {
+		t.Errorf("allocated %d of checkout total %s, want 813.70", allocated, result.Checkout.TotalAmount)
+	}
+
+	if result.ClientSecret == "" || !result.Checkout.StripePaymentIntentID.Valid {
+		t.Error("checkout has no payment intent")
+	}
+	if len(ts.store.cart) != 0 {
+		t.Errorf("cart still holds %d items", len(ts.store.cart))
+	}
+}
+
+func TestCheckoutPaymentFailureRelistsAsks(t *testing.T) {
+	ts, bids := newCheckoutService()
+	ts.payments.failWith = "customer has no payment method"
+
+	if _, err := ts.Checkout(context.Background(), 7, nil, "cus_1", ""); err == nil {
+		t.Fatal("Checkout succeeded without a payment")
+	}
+
+	for _, order := range ts.store.orders {
+		if order.Status != model.StatusCancelled {
+			t.Errorf("order %s = %s, want %s", order.OrderNumber, order.Status, model.StatusCancelled)
+		}
+	}
+	ts.checkHooks(t)
+
+	if len(bids.relisted) != 3 {
+		t.Errorf("relisted matches %v, want all 3", bids.relisted)
+	}
+	if len(ts.products.released) != 3 {
+		t.Errorf("released %d reservations, want 3", len(ts.products.released))
+	}
+	if len(ts.payments.refunds) != 0 {
+		t.Error("unpaid orders were refunded")
+	}
+	if len(ts.store.cart) != 2 {
+		t.Errorf("cart holds %d items, want them kept", len(ts.store.cart))
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/carrier"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	paymentPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
+	productPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/product"
+)
+
+// paymentClient records what the order service asks of the Payment Service.
+// A refund is applied back to the order the way the Payment Service does,
+// once per idempotency key.
+type paymentClient struct {
+	paymentPb.PaymentServiceClient
+	orders *OrderService
+
+	refunds  []*paymentPb.CreateRefundRequest
+	keys     map[string]bool
+	credits  []*paymentPb.CreditSellerWalletRequest
+	intents  []*paymentPb.CreateCheckoutPaymentIntentRequest
+	failWith string
+}
+
+func (c *paymentClient) CreateRefund(ctx context.Context, in *paymentPb.CreateRefundRequest, opts ...grpc.CallOption) (*paymentPb.CreateRefundResponse, error) {
+	if c.failWith != "" {
+		return &paymentPb.CreateRefundResponse{Error: c.failWith}, nil
+	}
+	if c.keys[in.IdempotencyKey] {
+		return &paymentPb.CreateRefundResponse{}, nil
+	}
+	c.keys[in.IdempotencyKey] = true
+	c.refunds = append(c.refunds, in)
+
+	order, err := c.orders.GetOrder(ctx, in.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	amount, full := order.BuyerTotal, in.AmountMinor == 0
+	if !full {
+		amount = money.New(in.AmountMinor, in.Currency)
+	}
+
+	reference := fmt.Sprintf("re_%d", len(c.refunds))
+	if _, err := c.orders.ApplyRefund(ctx, in.OrderId, reference, amount, full, in.Reason); err != nil {
+		return &paymentPb.CreateRefundResponse{Error: err.Error()}, nil
+	}
+	return &paymentPb.CreateRefundResponse{StripeRefundId: reference}, nil
+}
+
+func (c *paymentClient) CreditSellerWallet(ctx context.Context, in *paymentPb.CreditSellerWalletRequest, opts ...grpc.CallOption) (*paymentPb.CreditSellerWalletResponse, error) {
+	c.credits = append(c.credits, in)
+	return &paymentPb.CreditSellerWalletResponse{}, nil
+}
+
+func (c *paymentClient) CreateCheckoutPaymentIntent(ctx context.Context, in *paymentPb.CreateCheckoutPaymentIntentRequest, opts ...grpc.CallOption) (*paymentPb.CreateCheckoutPaymentIntentResponse, error) {
+	c.intents = append(c.intents, in)
+	if c.failWith != "" {
+		return &paymentPb.CreateCheckoutPaymentIntentResponse{Error: c.failWith}, nil
+	}
+	return &paymentPb.CreateCheckoutPaymentIntentResponse{
+		Payments:     []*paymentPb.Payment{{StripePaymentIntentId: fmt.Sprintf("pi_%d", in.CheckoutId)}},
+		ClientSecret: fmt.Sprintf("pi_%d_secret", in.CheckoutId),
+	}, nil
+}
+
+// productClient records the reservations released
+type productClient struct {
+	productPb.ProductServiceClient
+	released []*productPb.ReleaseInventoryRequest
+}
+
+func (c *productClient) ReleaseInventory(ctx context.Context, in *productPb.ReleaseInventoryRequest, opts ...grpc.CallOption) (*productPb.ReleaseInventoryResponse, error) {
+	c.released = append(c.released, in)
+	return &productPb.ReleaseInventoryResponse{Success: true}, nil
+}
+
+// feeReverser records the matches whose fee was reversed
+type feeReverser struct {
+	matches []int64
+}
+
+func (f *feeReverser) ReverseTransactionFee(ctx context.Context, matchID int64, reason string) (bool, error) {
+	for _, id := range f.matches {
+		if id == matchID {
+			return false, nil
+		}
+	}
+	f.matches = append(f.matches, matchID)
+	return true, nil
+}
+
+// testService wires an order service to fakes
+type testService struct {
+	*OrderService
+	store    *memoryStore
+	payments *paymentClient
+	products *productClient
+	fees     *feeReverser
+}
+
+func newTestService() *testService {
+	store := newMemoryStore()
+	payments := &paymentClient{keys: make(map[string]bool)}
+	products := &productClient{}
+	fees := &feeReverser{}
+
+	s := NewOrderService(store, products, nil, payments, nil, nil, nil, carrier.Address{}, nil, nil, fees)
+	payments.orders = s
+
+	return &testService{OrderService: s, store: store, payments: payments, products: products, fees: fees}
+}
+
+// addOrder stores a 250.00 sale of match matchID in status
+func (ts *testService) addOrder(matchID int64, status string) *model.Order {
+	order := ts.buildOrder(matchID, 7, 8, 100, 200, usd(25000), 1, ts.defaultBuyerFeePercentage, ts.defaultSellerFeePercentage)
+	order.Status = status
+	order.InventoryReference = fmt.Sprintf("ask-%d", matchID)
+	return ts.store.addOrder(order)
+}
+
+// status returns the order's current status
+func (ts *testService) status(t *testing.T, orderID int64) string {
+	t.Helper()
+	order, err := ts.GetOrder(context.Background(), orderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	return order.Status
+}
+
+// checkHooks fails the test when a status hook did not succeed
+func (ts *testService) checkHooks(t *testing.T) {
+	t.Helper()
+	for _, job := range ts.store.pendingJobs() {
+		t.Errorf("hook did not run: %s", job)
+	}
+}
+
+func usd(minor int64) money.Money {
+	return money.New(minor, money.DefaultCurrency)
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	fxModel "github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/carrier"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	paymentPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
+)
+
+// OpenDispute lets the buyer contest a delivered order. Disputes must be
+// opened before the order is completed; the seller payout is held until an
+// admin decides the case.
+func (s *OrderService) OpenDispute(ctx context.Context, orderID, buyerID int64, reason, description string, evidenceURLs []string) (*model.Dispute, error) {
+	if !model.IsValidDisputeReason(reason) {
+		return nil, fmt.Errorf("invalid dispute reason: %s", reason)
+	}
+	if description == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.BuyerID != buyerID {
+		return nil, fmt.Errorf("unauthorized: not the buyer of this order")
+	}
+	if order.Status != model.StatusDelivered {
+		return nil, fmt.Errorf("disputes can only be opened on delivered orders")
+	}
+
+	existing, err := s.repo.GetOpenDisputeByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("order already has an open dispute")
+	}
+
+	dispute := &model.Dispute{
+		OrderID:     order.ID,
+		BuyerID:     order.BuyerID,
+		SellerID:    order.SellerID,
+		Reason:      reason,
+		Description: description,
+		Status:      model.DisputeStatusOpen,
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.repo.CreateDispute(ctx, tx, dispute); err != nil {
+		return nil, err
+	}
+
+	event := disputeEvent(dispute.ID, lifecycle.Actor{Role: lifecycle.RoleBuyer, UserID: buyerID}, model.DisputeActionOpened, description, evidenceURLs)
+	details := map[string]interface{}{
+		"order_id":     order.ID,
+		"order_number": order.OrderNumber,
+		"reason":       reason,
+	}
+	if err := s.repo.AddDisputeEvent(ctx, tx, event, details); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return dispute, nil
+}
+
+// RespondToDispute records the seller's side of the case
+func (s *OrderService) RespondToDispute(ctx context.Context, disputeID, sellerID int64, response string, evidenceURLs []string) (*model.Dispute, error) {
+	if response == "" {
+		return nil, fmt.Errorf("response is required")
+	}
+
+	dispute, err := s.repo.GetDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.SellerID != sellerID {
+		return nil, fmt.Errorf("unauthorized: not the seller of this order")
+	}
+	if dispute.Status != model.DisputeStatusOpen {
+		return nil, fmt.Errorf("dispute is not awaiting a seller response")
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.repo.UpdateDisputeStatus(ctx, tx, disputeID, model.DisputeStatusSellerResponded); err != nil {
+		return nil, err
+	}
+
+	event := disputeEvent(disputeID, lifecycle.Actor{Role: lifecycle.RoleSeller, UserID: sellerID}, model.DisputeActionSellerResponded, response, evidenceURLs)
+	if err := s.repo.AddDisputeEvent(ctx, tx, event, map[string]interface{}{"order_id": dispute.OrderID}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.repo.GetDispute(ctx, disputeID)
+}
+
+// ResolveDispute records an admin's decision and carries it out:
+//
+//	full_refund    - buyer refunded in full, order refunded
+//	partial_refund - buyer refunded refundAmount, seller paid the rest
+//	return         - return label to the seller, buyer refunded in full
+//	seller_favored - seller paid in full
+//
+// The refund is issued before the decision is recorded, so a failed refund
+// leaves the case open.
+func (s *OrderService) ResolveDispute(ctx context.Context, disputeID, adminID int64, outcome string, refundAmount money.Money, note string) (*model.Dispute, error) {
+	if !model.IsValidDisputeOutcome(outcome) {
+		return nil, fmt.Errorf("invalid dispute outcome: %s", outcome)
+	}
+	if note == "" {
+		return nil, fmt.Errorf("a note explaining the decision is required")
+	}
+
+	role, err := s.repo.GetUserRole(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if role != string(lifecycle.RoleAdmin) {
+		return nil, fmt.Errorf("unauthorized: only admins can resolve disputes")
+	}
+	admin := lifecycle.Actor{Role: lifecycle.RoleAdmin, UserID: adminID}
+
+	dispute, err := s.repo.GetDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if !dispute.IsOpen() {
+		return nil, fmt.Errorf("dispute is already resolved")
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, dispute.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch outcome {
+	case model.DisputeOutcomePartialRefund:
+		if !refundAmount.IsPositive() || !refundAmount.LessThan(order.TotalAmount) {
+			return nil, fmt.Errorf("partial refund must be between 0 and %s", order.TotalAmount)
+		}
+	case model.DisputeOutcomeFullRefund, model.DisputeOutcomeReturn:
+		refundAmount = order.TotalAmount
+	default:
+		refundAmount = money.Zero(order.TotalAmount.Currency)
+	}
+
+	if refundAmount.IsPositive() {
+		if err := s.refundDispute(ctx, dispute, order, outcome, refundAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	dispute.Outcome = sql.NullString{String: outcome, Valid: true}
+	dispute.ResolvedBy = sql.NullInt64{Int64: adminID, Valid: true}
+	if refundAmount.IsPositive() {
+		dispute.RefundAmount = &refundAmount
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.repo.ResolveDispute(ctx, tx, dispute); err != nil {
+		return nil, err
+	}
+
+	event := disputeEvent(disputeID, admin, model.DisputeActionResolved, note, nil)
+	details := map[string]interface{}{
+		"order_id":      order.ID,
+		"order_number":  order.OrderNumber,
+		"outcome":       outcome,
+		"refund_amount": refundAmount.String(),
+	}
+	if err := s.repo.AddDisputeEvent(ctx, tx, event, details); err != nil {
+		return nil, err
+	}
+
+	// A decision that leaves the seller paid releases the credit held when
+	// the order completed, through the payout hook that held it
+	refunded := outcome == model.DisputeOutcomeFullRefund || outcome == model.DisputeOutcomeReturn
+	var jobs []*model.HookJob
+	if dispute.PayoutHeld && !refunded {
+		job, err := s.machine.Job(&lifecycle.Change{
+			Order: order,
+			From:  model.StatusDelivered,
+			To:    model.StatusCompleted,
+			Actor: admin,
+			Note:  fmt.Sprintf("Dispute %d resolved: %s", disputeID, outcome),
+		}, "payout")
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+		if err := s.repo.EnqueueHookJobs(ctx, tx, jobs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.machine.RunJobs(ctx, s.repo, jobs)
+
+	// The decision is recorded; follow-up failures are logged for support
+	if refunded {
+		if outcome == model.DisputeOutcomeReturn {
+			if err := s.createDisputeReturn(ctx, dispute, order); err != nil {
+				log.Printf("Dispute %d: failed to create return: %v", disputeID, err)
+			}
+		}
+		// The refund normally closed the order already
+		current, err := s.repo.GetOrderByID(ctx, order.ID)
+		if err != nil {
+			log.Printf("Dispute %d: failed to reload order %s: %v", disputeID, order.OrderNumber, err)
+		} else if current.Status != model.StatusRefunded {
+			reason := fmt.Sprintf("Dispute %d: %s", disputeID, note)
+			if _, err := s.transition(ctx, current, model.StatusRefunded, admin, reason, nil); err != nil {
+				log.Printf("Dispute %d: failed to mark order %s refunded: %v", disputeID, order.OrderNumber, err)
+			}
+		}
+	}
+
+	return s.repo.GetDispute(ctx, disputeID)
+}
+
+// GetDispute retrieves a dispute and its timeline
+func (s *OrderService) GetDispute(ctx context.Context, disputeID int64) (*model.Dispute, []*model.DisputeEvent, error) {
+	dispute, err := s.repo.GetDispute(ctx, disputeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events, err := s.repo.GetDisputeEvents(ctx, disputeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dispute, events, nil
+}
+
+// ListDisputes retrieves disputes, oldest first (the admin queue)
+func (s *OrderService) ListDisputes(ctx context.Context, status string, page, pageSize int32) ([]*model.Dispute, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	return s.repo.ListDisputes(ctx, status, page, pageSize)
+}
+
+// refundDispute refunds the buyer through the Payment Service. A partial
+// refund is decided in the settlement currency and paid back in the buyer's
+// currency at the rate they paid.
+func (s *OrderService) refundDispute(ctx context.Context, dispute *model.Dispute, order *model.Order, outcome string, amount money.Money) error {
+	if s.paymentClient == nil {
+		return fmt.Errorf("payment service unavailable")
+	}
+
+	req := &paymentPb.CreateRefundRequest{
+		OrderId:        order.ID,
+		Reason:         fmt.Sprintf("Dispute %d (%s): %s", dispute.ID, dispute.Reason, outcome),
+		IdempotencyKey: fmt.Sprintf("dispute:%d:refund", dispute.ID),
+	}
+	if outcome == model.DisputeOutcomePartialRefund {
+		quote := fxModel.Snapshot(order.TotalAmount.Currency, order.BuyerTotal.Currency, order.BuyerFXRate)
+		refund := quote.Apply(amount)
+		s.fxService.RecordBestEffort(ctx, quote, amount, fxModel.PurposeDisputeRefund, fxModel.ReferenceDispute, dispute.ID)
+
+		req.Amount = refund.Major()
+		req.AmountMinor = refund.Amount
+		req.Currency = refund.Currency
+	}
+
+	resp, err := s.paymentClient.CreateRefund(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to refund buyer: %w", err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+
+	return nil
+}
+
+// createDisputeReturn issues a return label from the buyer back to the seller
+func (s *OrderService) createDisputeReturn(ctx context.Context, dispute *model.Dispute, order *model.Order) error {
+	ret := &model.OrderReturn{
+		OrderID:  order.ID,
+		SellerID: order.SellerID,
+		Reason:   fmt.Sprintf("Dispute %d: %s", dispute.ID, dispute.Reason),
+		Status:   model.ReturnStatusPending,
+	}
+
+	if s.carrierProvider != nil {
+		from, err := s.buyerAddress(ctx, order)
+		if err != nil {
+			return err
+		}
+		to, err := s.sellerAddress(ctx, order)
+		if err != nil {
+			return err
+		}
+
+		label, err := s.carrierProvider.CreateLabel(ctx, carrier.LabelRequest{
+			Reference: fmt.Sprintf("%s-RET", order.OrderNumber),
+			From:      from,
+			To:        to,
+			Parcel:    defaultSneakerParcel,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create return label: %w", err)
+		}
+
+		ret.TrackingNumber = sql.NullString{String: label.TrackingNumber, Valid: true}
+		ret.Carrier = sql.NullString{String: label.Carrier, Valid: true}
+	}
+
+	return s.repo.CreateReturn(ctx, ret)
+}
+
+func disputeEvent(disputeID int64, actor lifecycle.Actor, action, note string, evidenceURLs []string) *model.DisputeEvent {
+	event := &model.DisputeEvent{
+		DisputeID:    disputeID,
+		ActorRole:    string(actor.Role),
+		Action:       action,
+		EvidenceURLs: evidenceURLs,
+	}
+	if actor.UserID != 0 {
+		event.ActorID = sql.NullInt64{Int64: actor.UserID, Valid: true}
+	}
+	if note != "" {
+		event.Note = sql.NullString{String: note, Valid: true}
+	}
+	return event
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+const admin = int64(1)
+
+func TestResolveDispute(t *testing.T) {
+	tests := []struct {
+		outcome  string
+		amount   money.Money
+		status   string
+		refund   int64 // minor units asked of the Payment Service, 0 in full
+		refunded bool
+		credit   int64 // seller wallet credit, 0 for none
+		returned bool
+	}{
+		{model.DisputeOutcomeFullRefund, money.Money{}, model.StatusRefunded, 0, true, 0, false},
+		{model.DisputeOutcomeReturn, money.Money{}, model.StatusRefunded, 0, true, 0, true},
+		{model.DisputeOutcomePartialRefund, usd(5000), model.StatusCompleted, 5000, true, 17750, false},
+		{model.DisputeOutcomeSellerFavored, money.Money{}, model.StatusCompleted, 0, false, 22750, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.outcome, func(t *testing.T) {
+			ctx := context.Background()
+			ts := newTestService()
+			ts.store.roles[admin] = "admin"
+			order := ts.addOrder(11, model.StatusCompleted)
+			dispute := ts.store.addDispute(order, true)
+
+			resolved, err := ts.ResolveDispute(ctx, dispute.ID, admin, tt.outcome, tt.amount, "Photos show a different colorway")
+			if err != nil {
+				t.Fatalf("ResolveDispute: %v", err)
+			}
+			if resolved.IsOpen() || resolved.Outcome.String != tt.outcome {
+				t.Errorf("dispute = %s/%s, want resolved with %s", resolved.Status, resolved.Outcome.String, tt.outcome)
+			}
+			ts.checkHooks(t)
+
+			if status := ts.status(t, order.ID); status != tt.status {
+				t.Errorf("order status = %s, want %s", status, tt.status)
+			}
+
+			switch refunds := ts.payments.refunds; {
+			case !tt.refunded && len(refunds) != 0:
+				t.Errorf("refunds = %+v, want none", refunds)
+			case tt.refunded && len(refunds) != 1:
+				t.Errorf("refunds = %d, want 1", len(refunds))
+			case tt.refunded:
+				if refunds[0].AmountMinor != tt.refund {
+					t.Errorf("refund amount = %d, want %d", refunds[0].AmountMinor, tt.refund)
+				}
+				if key := fmt.Sprintf("dispute:%d:refund", dispute.ID); refunds[0].IdempotencyKey != key {
+					t.Errorf("refund idempotency key = %s, want %s", refunds[0].IdempotencyKey, key)
+				}
+			}
+
+			// The held payout is released less what the buyer got back
+			switch credits := ts.payments.credits; {
+			case tt.credit == 0 && len(credits) != 0:
+				t.Errorf("seller credited %+v after a full refund", credits)
+			case tt.credit != 0 && (len(credits) != 1 || credits[0].AmountMinor != tt.credit):
+				t.Errorf("seller credits = %+v, want one of %d", credits, tt.credit)
+			}
+
+			if returned := len(ts.store.returns) == 1; returned != tt.returned {
+				t.Errorf("return created = %v, want %v", returned, tt.returned)
+			}
+		})
+	}
+}
+
+func TestResolveDisputeRefundFailureKeepsItOpen(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestService()
+	ts.store.roles[admin] = "admin"
+	order := ts.addOrder(11, model.StatusCompleted)
+	dispute := ts.store.addDispute(order, true)
+	ts.payments.failWith = "charge already refunded"
+
+	if _, err := ts.ResolveDispute(ctx, dispute.ID, admin, model.DisputeOutcomeFullRefund, money.Money{}, "Item never arrived"); err == nil {
+		t.Fatal("ResolveDispute succeeded with the refund failing")
+	}
+
+	if !ts.store.disputes[dispute.ID].IsOpen() {
+		t.Error("dispute resolved without the refund")
+	}
+	if status := ts.status(t, order.ID); status != model.StatusCompleted {
+		t.Errorf("order status = %s, want %s", status, model.StatusCompleted)
+	}
+	if len(ts.payments.credits) != 0 {
+		t.Error("held payout released")
+	}
+}
+
+func TestResolveDisputeRequiresAdmin(t *testing.T) {
+	ts := newTestService()
+	ts.store.roles[7] = "buyer"
+	order := ts.addOrder(11, model.StatusCompleted)
+	dispute := ts.store.addDispute(order, false)
+
+	if _, err := ts.ResolveDispute(context.Background(), dispute.ID, 7, model.DisputeOutcomeFullRefund, money.Money{}, "Refund me"); err == nil {
+		t.Fatal("buyer resolved their own dispute")
+	}
+	if len(ts.payments.refunds) != 0 || !ts.store.disputes[dispute.ID].IsOpen() {
+		t.Error("rejected resolution changed the dispute")
+	}
+}
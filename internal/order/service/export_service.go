@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/export"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/repository"
+	notificationPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/notification"
+)
+
+const (
+	// exportBatchSize caps how many jobs one sweep builds
+	exportBatchSize = 10
+	// exportPageSize is the page size used to read a seller's orders
+	exportPageSize = 100
+	// exportStaleAfter is when a processing job is assumed abandoned
+	exportStaleAfter = 30 * time.Minute
+)
+
+var exportContentTypes = map[string]string{
+	model.ExportFormatCSV:  "text/csv; charset=utf-8",
+	model.ExportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportService builds seller sales exports and yearly tax summaries
+type ExportService struct {
+	repo               *repository.OrderRepository
+	notificationClient notificationPb.NotificationServiceClient
+	threshold          export.Threshold
+}
+
+// NewExportService creates an export service. threshold is the yearly
+// volume at which the tax summary flags the seller as reportable.
+func NewExportService(repo *repository.OrderRepository, notificationClient notificationPb.NotificationServiceClient, threshold export.Threshold) *ExportService {
+	return &ExportService{
+		repo:               repo,
+		notificationClient: notificationClient,
+		threshold:          threshold,
+	}
+}
+
+// RequestExport queues an export of the seller's completed orders for a
+// year. The file is built by the export worker; the seller is notified
+// when it can be downloaded.
+func (s *ExportService) RequestExport(ctx context.Context, sellerID int64, kind, format string, year int) (*model.ExportJob, error) {
+	if !model.IsValidExportKind(kind) {
+		return nil, fmt.Errorf("invalid export kind: %s", kind)
+	}
+	if format == "" {
+		format = model.ExportFormatCSV
+	}
+	if !model.IsValidExportFormat(format) {
+		return nil, fmt.Errorf("invalid export format: %s", format)
+	}
+
+	currentYear := time.Now().Year()
+	if year == 0 {
+		year = currentYear
+	}
+	if year < 2000 || year > currentYear {
+		return nil, fmt.Errorf("invalid year: %d", year)
+	}
+
+	job := &model.ExportJob{
+		SellerID: sellerID,
+		Kind:     kind,
+		Format:   format,
+		Year:     year,
+		Status:   model.ExportStatusPending,
+	}
+
+	if err := s.repo.CreateExportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetExport retrieves one of the seller's export jobs; the file is included
+// once the job is ready and withContent is set
+func (s *ExportService) GetExport(ctx context.Context, jobID, sellerID int64, withContent bool) (*model.ExportJob, error) {
+	job, err := s.repo.GetExportJob(ctx, jobID, withContent)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.SellerID != sellerID {
+		return nil, fmt.Errorf("unauthorized: not your export")
+	}
+
+	return job, nil
+}
+
+// ProcessExportJobs builds pending exports and returns how many finished
+func (s *ExportService) ProcessExportJobs(ctx context.Context) (int, error) {
+	jobs, err := s.repo.ClaimExportJobs(ctx, exportStaleAfter, exportBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, job := range jobs {
+		if err := s.runExport(ctx, job); err != nil {
+			log.Printf("Export job %d failed: %v", job.ID, err)
+			if err := s.repo.FailExportJob(ctx, job.ID, err.Error()); err != nil {
+				log.Printf("Export job %d: %v", job.ID, err)
+			}
+			continue
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// StartExportWorker periodically builds queued exports until ctx is canceled
+func (s *ExportService) StartExportWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			processed, err := s.ProcessExportJobs(ctx)
+			if err != nil {
+				log.Printf("Export worker failed: %v", err)
+				continue
+			}
+			if processed > 0 {
+				log.Printf("Built %d seller exports", processed)
+			}
+		}
+	}
+}
+
+func (s *ExportService) runExport(ctx context.Context, job *model.ExportJob) error {
+	sales, err := s.completedSales(ctx, job.SellerID, job.Year)
+	if err != nil {
+		return err
+	}
+
+	var sheets []export.Sheet
+	switch job.Kind {
+	case model.ExportKindSales:
+		sheets = []export.Sheet{export.SalesSheet(sales)}
+	case model.ExportKindTaxSummary:
+		sheets = []export.Sheet{export.SummarySheet(export.Summarize(job.Year, sales, s.threshold))}
+	}
+
+	var content []byte
+	if job.Format == model.ExportFormatXLSX {
+		content, err = export.WriteXLSX(sheets...)
+	} else {
+		content, err = export.WriteCSV(sheets...)
+	}
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%s-%d.%s", job.Kind, job.Year, job.Format)
+	if err := s.repo.CompleteExportJob(ctx, job.ID, filename, exportContentTypes[job.Format], content); err != nil {
+		return err
+	}
+
+	s.notifyExportReady(ctx, job, filename)
+	return nil
+}
+
+// completedSales collects the seller's orders completed in the year with
+// the payout sent for each
+func (s *ExportService) completedSales(ctx context.Context, sellerID int64, year int) ([]export.Sale, error) {
+	payouts, err := s.repo.GetSellerPayouts(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	sales := make([]export.Sale, 0)
+	for page := int32(1); ; page++ {
+		orders, total, err := s.repo.GetSellerOrders(ctx, sellerID, model.StatusCompleted, page, exportPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, order := range orders {
+			if !order.CompletedAt.Valid || order.CompletedAt.Time.Year() != year {
+				continue
+			}
+			sales = append(sales, saleFromOrder(order, payouts[order.ID]))
+		}
+
+		if int64(page)*exportPageSize >= total {
+			break
+		}
+	}
+
+	return sales, nil
+}
+
+func saleFromOrder(order *model.Order, payout *model.SellerPayout) export.Sale {
+	sale := export.Sale{
+		OrderNumber: order.OrderNumber,
+		CompletedAt: order.CompletedAt.Time,
+		Gross:       order.Price,
+		Fees:        order.SellerFee,
+		NetPayout:   order.SellerPayout,
+	}
+
+	if payout != nil {
+		sale.NetPayout = payout.Amount
+		sale.PayoutStatus = payout.Status
+		sale.PayoutID = payout.PayoutID
+		if payout.ProcessedAt.Valid {
+			paidAt := payout.ProcessedAt.Time
+			sale.PaidAt = &paidAt
+		}
+	}
+
+	return sale
+}
+
+// notifyExportReady tells the seller the file can be downloaded
+func (s *ExportService) notifyExportReady(ctx context.Context, job *model.ExportJob, filename string) {
+	if s.notificationClient == nil {
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"export_id": job.ID,
+		"kind":      job.Kind,
+		"year":      job.Year,
+		"filename":  filename,
+	})
+
+	title := "Your sales export is ready"
+	if job.Kind == model.ExportKindTaxSummary {
+		title = fmt.Sprintf("Your %d tax summary is ready", job.Year)
+	}
+
+	resp, err := s.notificationClient.SendNotification(ctx, &notificationPb.SendNotificationRequest{
+		UserId:    job.SellerID,
+		Type:      "export_ready",
+		Title:     title,
+		Message:   fmt.Sprintf("%s can be downloaded from your seller dashboard.", filename),
+		Data:      string(data),
+		SendEmail: true,
+	})
+	if err != nil {
+		log.Printf("Failed to notify seller %d of export %d: %v", job.SellerID, job.ID, err)
+		return
+	}
+	if resp.Error != "" {
+		log.Printf("Failed to notify seller %d of export %d: %s", job.SellerID, job.ID, resp.Error)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	feeRepository "github.com/vvkuzmych/sneakers_marketplace/internal/fees/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/invoice"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/repository"
+)
+
+// Invoice formats
+const (
+	InvoiceFormatPDF  = "pdf"
+	InvoiceFormatHTML = "html"
+)
+
+// InvoiceDocument is a rendered receipt or sale statement
+type InvoiceDocument struct {
+	Number      string
+	ContentType string
+	Filename    string
+	Content     []byte
+}
+
+// InvoiceService renders buyer receipts and seller sale statements
+type InvoiceService struct {
+	repo    *repository.OrderRepository
+	feeRepo *feeRepository.FeeRepository
+	issuer  invoice.Party
+	tax     invoice.TaxPolicy
+}
+
+// NewInvoiceService creates an invoice service. issuer is printed as the
+// marketplace on every document; tax describes the tax included in fees.
+func NewInvoiceService(repo *repository.OrderRepository, feeRepo *feeRepository.FeeRepository, issuer invoice.Party, tax invoice.TaxPolicy) *InvoiceService {
+	return &InvoiceService{
+		repo:    repo,
+		feeRepo: feeRepo,
+		issuer:  issuer,
+		tax:     tax,
+	}
+}
+
+// GetInvoice renders the caller's document for an order: a receipt for the
+// buyer, a sale statement for the seller. Documents exist once the order
+// has been paid; the invoice number is assigned on first download.
+func (s *InvoiceService) GetInvoice(ctx context.Context, orderID, userID int64, format string) (*InvoiceDocument, error) {
+	if format == "" {
+		format = InvoiceFormatPDF
+	}
+	if format != InvoiceFormatPDF && format != InvoiceFormatHTML {
+		return nil, fmt.Errorf("invalid invoice format: %s", format)
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var kind string
+	switch userID {
+	case order.BuyerID:
+		kind = model.InvoiceKindBuyerReceipt
+	case order.SellerID:
+		kind = model.InvoiceKindSellerStatement
+	default:
+		return nil, fmt.Errorf("unauthorized: not a party to this order")
+	}
+
+	if !order.PaymentAt.Valid {
+		return nil, fmt.Errorf("invoice is available once the order is paid")
+	}
+
+	amounts, err := s.invoiceAmounts(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := s.repo.GetInvoiceDetails(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	amounts.Item = details.Item
+
+	record, err := s.repo.GetOrCreateInvoice(ctx, order.ID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var inv *invoice.Invoice
+	if kind == model.InvoiceKindBuyerReceipt {
+		buyer := invoice.Party{Name: details.BuyerName, Email: details.BuyerEmail}
+		inv = invoice.BuyerReceipt(record.InvoiceNumber, record.IssuedAt, amounts, s.issuer, buyer, s.tax)
+	} else {
+		seller := invoice.Party{Name: details.SellerName, Email: details.SellerEmail}
+		inv = invoice.SellerStatement(record.InvoiceNumber, record.IssuedAt, amounts, s.issuer, seller, s.tax)
+	}
+
+	doc := &InvoiceDocument{
+		Number:   inv.Number,
+		Filename: inv.Filename(format),
+	}
+	if format == InvoiceFormatHTML {
+		doc.ContentType = "text/html; charset=utf-8"
+		doc.Content, err = invoice.RenderHTML(inv)
+	} else {
+		doc.ContentType = "application/pdf"
+		doc.Content, err = invoice.RenderPDF(inv)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// invoiceAmounts takes the itemized fees recorded at match time. Orders
+// without a transaction_fees row fall back to the order's fee totals.
+func (s *InvoiceService) invoiceAmounts(ctx context.Context, order *model.Order) (invoice.Order, error) {
+	amounts := invoice.Order{
+		Number:    order.OrderNumber,
+		CreatedAt: order.CreatedAt,
+		Price:     order.Price,
+		Quantity:  order.Quantity,
+	}
+
+	fee, err := s.feeRepo.GetTransactionFeeByMatchID(ctx, order.MatchID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return amounts, err
+		}
+
+		amounts.BuyerProcessingFee = order.BuyerFee
+		amounts.BuyerTotal = order.TotalAmount
+		amounts.SellerTransactionFee = order.SellerFee
+		amounts.SellerPayout = order.SellerPayout
+		return amounts, nil
+	}
+
+	amounts.Price = fee.SalePrice
+	amounts.BuyerProcessingFee = fee.BuyerProcessingFee
+	amounts.BuyerShippingFee = fee.BuyerShippingFee
+	amounts.BuyerTotal = fee.BuyerTotal
+	amounts.SellerTransactionFee = fee.SellerTransactionFee
+	amounts.SellerAuthenticationFee = fee.SellerAuthenticationFee
+	amounts.SellerShippingCost = fee.SellerShippingCost
+	amounts.SellerPayout = fee.SellerPayout
+
+	return amounts, nil
+}
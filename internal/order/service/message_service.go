@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/redact"
+	notificationPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/notification"
+)
+
+// SendOrderMessage posts a message to an order's thread. Buyer and seller can
+// write to each other; admins can read every thread and post into it. Phone
+// numbers and emails are removed before the message is stored for delivery,
+// the original text is kept for admins. It returns the stored message and
+// the users it should be delivered to.
+func (s *OrderService) SendOrderMessage(ctx context.Context, orderID, senderID int64, body string) (*model.OrderMessage, []int64, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, nil, fmt.Errorf("message body is required")
+	}
+	if utf8.RuneCountInString(body) > model.MaxMessageLength {
+		return nil, nil, fmt.Errorf("message cannot exceed %d characters", model.MaxMessageLength)
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actor, err := s.messageActor(ctx, order, senderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg := &model.OrderMessage{
+		OrderID:    order.ID,
+		SenderID:   senderID,
+		SenderRole: string(actor.Role),
+		Body:       body,
+	}
+	if clean, redacted := redact.Contacts(body); redacted {
+		msg.Body = clean
+		msg.OriginalBody = sql.NullString{String: body, Valid: true}
+		msg.Redacted = true
+		log.Printf("Order %s: contact details removed from message by %s", order.OrderNumber, actor)
+	}
+
+	if err := s.repo.CreateOrderMessage(ctx, msg); err != nil {
+		return nil, nil, err
+	}
+
+	var recipients []int64
+	switch actor.Role {
+	case lifecycle.RoleBuyer:
+		recipients = []int64{order.SellerID}
+	case lifecycle.RoleSeller:
+		recipients = []int64{order.BuyerID}
+	default:
+		recipients = []int64{order.BuyerID, order.SellerID}
+	}
+
+	for _, userID := range recipients {
+		s.notifyOrderMessage(ctx, order, msg, userID)
+	}
+
+	return msg, recipients, nil
+}
+
+// GetOrderMessages returns an order's thread to the buyer, the seller or an
+// admin. Only admins see the original text of redacted messages.
+func (s *OrderService) GetOrderMessages(ctx context.Context, orderID, userID, afterID int64) ([]*model.OrderMessage, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, err := s.messageActor(ctx, order, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repo.GetOrderMessages(ctx, orderID, afterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if actor.Role != lifecycle.RoleAdmin {
+		for _, msg := range messages {
+			msg.OriginalBody = sql.NullString{}
+		}
+	}
+
+	return messages, nil
+}
+
+// messageActor resolves who may take part in an order's thread
+func (s *OrderService) messageActor(ctx context.Context, order *model.Order, userID int64) (lifecycle.Actor, error) {
+	switch userID {
+	case order.BuyerID:
+		return lifecycle.Actor{Role: lifecycle.RoleBuyer, UserID: userID}, nil
+	case order.SellerID:
+		return lifecycle.Actor{Role: lifecycle.RoleSeller, UserID: userID}, nil
+	}
+
+	role, err := s.repo.GetUserRole(ctx, userID)
+	if err != nil {
+		return lifecycle.Actor{}, err
+	}
+	if role != string(lifecycle.RoleAdmin) {
+		return lifecycle.Actor{}, fmt.Errorf("unauthorized: not a party to this order")
+	}
+
+	return lifecycle.Actor{Role: lifecycle.RoleAdmin, UserID: userID}, nil
+}
+
+// notifyOrderMessage mirrors a new message into the recipient's notifications
+func (s *OrderService) notifyOrderMessage(ctx context.Context, order *model.Order, msg *model.OrderMessage, userID int64) {
+	if s.notificationClient == nil {
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"order_id":     order.ID,
+		"order_number": order.OrderNumber,
+		"message_id":   msg.ID,
+		"sender_role":  msg.SenderRole,
+	})
+
+	title := fmt.Sprintf("New message about order %s", order.OrderNumber)
+	if msg.SenderRole == string(lifecycle.RoleAdmin) {
+		title = fmt.Sprintf("Support replied about order %s", order.OrderNumber)
+	}
+
+	resp, err := s.notificationClient.SendNotification(ctx, &notificationPb.SendNotificationRequest{
+		UserId:  userID,
+		Type:    "order_message",
+		Title:   title,
+		Message: preview(msg.Body, 140),
+		Data:    string(data),
+	})
+	if err != nil {
+		log.Printf("Failed to notify user %d of message %d: %v", userID, msg.ID, err)
+		return
+	}
+	if resp.Error != "" {
+		log.Printf("Failed to notify user %d of message %d: %s", userID, msg.ID, resp.Error)
+	}
+}
+
+// preview shortens text to at most n runes
+func preview(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
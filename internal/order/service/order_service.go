@@ -3,26 +3,89 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+
+	fxModel "github.com/vvkuzmych/sneakers_marketplace/internal/fx/model"
+	fxService "github.com/vvkuzmych/sneakers_marketplace/internal/fx/service"
+	ledgerModel "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/model"
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/carrier"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/order/repository"
+	userModel "github.com/vvkuzmych/sneakers_marketplace/internal/user/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	biddingPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/bidding"
+	notificationPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/notification"
+	paymentPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
+	productPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/product"
+	userPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/user"
 )
 
+// FeeReverser takes the fee recorded for a match out of revenue.
+// FeeRepository implements it.
+type FeeReverser interface {
+	ReverseTransactionFee(ctx context.Context, matchID int64, reason string) (bool, error)
+}
+
 type OrderService struct {
-	repo *repository.OrderRepository
+	repo               repository.OrderStore
+	machine            *lifecycle.Machine
+	productClient      productPb.ProductServiceClient
+	notificationClient notificationPb.NotificationServiceClient
+	paymentClient      paymentPb.PaymentServiceClient
+	biddingClient      biddingPb.BiddingServiceClient
+	userClient         userPb.UserServiceClient
+	carrierProvider    carrier.Provider
+	authCenter         carrier.Address              // prepaid labels ship here for authentication
+	fxService          *fxService.FXService         // nil: buyers pay in the settlement currency
+	ledger             *ledgerService.LedgerService // nil: sales are not posted
+	feeRepo            FeeReverser                  // nil: fees are not reversed on refund
 
 	// Fee percentages (can be loaded from config)
 	defaultBuyerFeePercentage  float64
 	defaultSellerFeePercentage float64
 }
 
-func NewOrderService(repo *repository.OrderRepository) *OrderService {
-	return &OrderService{
+func NewOrderService(
+	repo repository.OrderStore,
+	productClient productPb.ProductServiceClient,
+	notificationClient notificationPb.NotificationServiceClient,
+	paymentClient paymentPb.PaymentServiceClient,
+	biddingClient biddingPb.BiddingServiceClient,
+	userClient userPb.UserServiceClient,
+	carrierProvider carrier.Provider,
+	authCenter carrier.Address,
+	fxService *fxService.FXService,
+	ledger *ledgerService.LedgerService,
+	feeRepo FeeReverser,
+) *OrderService {
+	s := &OrderService{
 		repo:                       repo,
+		machine:                    lifecycle.NewMachine(lifecycle.DefaultTransitions()),
+		productClient:              productClient,
+		notificationClient:         notificationClient,
+		paymentClient:              paymentClient,
+		biddingClient:              biddingClient,
+		userClient:                 userClient,
+		carrierProvider:            carrierProvider,
+		authCenter:                 authCenter,
+		fxService:                  fxService,
+		ledger:                     ledger,
+		feeRepo:                    feeRepo,
 		defaultBuyerFeePercentage:  0.03, // 3% buyer processing fee
 		defaultSellerFeePercentage: 0.09, // 9% seller commission
 	}
+
+	s.registerHooks()
+
+	return s
 }
 
 // CreateOrderFromMatch creates an order from a match
@@ -32,8 +95,9 @@ func (s *OrderService) CreateOrderFromMatch(
 	matchID int64,
 	buyerID, sellerID int64,
 	productID, sizeID int64,
-	price float64,
+	price money.Money,
 	quantity int32,
+	inventoryReference string,
 	shippingAddressID *int64,
 	buyerNotes string,
 	buyerFeePercentage, sellerFeePercentage *float64,
@@ -58,31 +122,8 @@ func (s *OrderService) CreateOrderFromMatch(
 		sellerFee = *sellerFeePercentage
 	}
 
-	// Calculate fees
-	buyerFeeAmount := price * buyerFee
-	sellerFeeAmount := price * sellerFee
-	platformFeeAmount := sellerFeeAmount // Platform earns from seller commission
-	totalAmount := price + buyerFeeAmount
-	sellerPayout := price - sellerFeeAmount
-
-	order := &model.Order{
-		MatchID:  matchID,
-		BuyerID:  buyerID,
-		SellerID: sellerID,
-
-		ProductID: productID,
-		SizeID:    sizeID,
-
-		Price:        price,
-		Quantity:     quantity,
-		BuyerFee:     buyerFeeAmount,
-		SellerFee:    sellerFeeAmount,
-		PlatformFee:  platformFeeAmount,
-		TotalAmount:  totalAmount,
-		SellerPayout: sellerPayout,
-
-		Status: model.StatusPendingPayment,
-	}
+	order := s.buildOrder(matchID, buyerID, sellerID, productID, sizeID, price, quantity, buyerFee, sellerFee)
+	order.InventoryReference = inventoryReference
 
 	// Set shipping address if provided
 	if shippingAddressID != nil {
@@ -109,6 +150,86 @@ func (s *OrderService) CreateOrderFromMatch(
 	return createdOrder, nil
 }
 
+// CreateOrderForMatch creates the order of a match fetched from the Bidding
+// Service. Like CreateOrderFromMatch it returns the existing order when the
+// match already has one, so callers may retry.
+func (s *OrderService) CreateOrderForMatch(
+	ctx context.Context,
+	matchID int64,
+	shippingAddressID *int64,
+	buyerNotes string,
+	buyerFeePercentage, sellerFeePercentage *float64,
+) (*model.Order, error) {
+	if s.biddingClient == nil {
+		return nil, fmt.Errorf("bidding service unavailable")
+	}
+
+	resp, err := s.biddingClient.GetMatch(ctx, &biddingPb.GetMatchRequest{MatchId: matchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("failed to get match: %s", resp.Error)
+	}
+
+	match := resp.Match
+	if match.Status == "failed" {
+		return nil, fmt.Errorf("match %d has failed", matchID)
+	}
+
+	return s.CreateOrderFromMatch(
+		ctx, match.Id,
+		match.BuyerId, match.SellerId,
+		match.ProductId, match.SizeId,
+		money.FromProto(match.PriceMinor, match.Price, match.Currency), match.Quantity,
+		match.InventoryReference,
+		shippingAddressID, buyerNotes,
+		buyerFeePercentage, sellerFeePercentage,
+	)
+}
+
+// buildOrder prices a pending order for a matched sale
+func (s *OrderService) buildOrder(
+	matchID int64,
+	buyerID, sellerID int64,
+	productID, sizeID int64,
+	price money.Money,
+	quantity int32,
+	buyerFee, sellerFee float64,
+) *model.Order {
+	// Calculate fees
+	buyerFeeAmount := price.MulBasisPoints(money.BasisPoints(buyerFee*100), money.HalfUp)
+	sellerFeeAmount := price.MulBasisPoints(money.BasisPoints(sellerFee*100), money.HalfUp)
+	platformFeeAmount := sellerFeeAmount // Platform earns from seller commission
+	totalAmount := price.Add(buyerFeeAmount)
+	sellerPayout := price.Sub(sellerFeeAmount)
+
+	return &model.Order{
+		MatchID:  matchID,
+		BuyerID:  buyerID,
+		SellerID: sellerID,
+
+		ProductID: productID,
+		SizeID:    sizeID,
+
+		Price:        price,
+		Quantity:     quantity,
+		BuyerFee:     buyerFeeAmount,
+		SellerFee:    sellerFeeAmount,
+		PlatformFee:  platformFeeAmount,
+		TotalAmount:  totalAmount,
+		SellerPayout: sellerPayout,
+
+		// Settlement-currency sale; checkout sets the buyer's and seller's currencies
+		BuyerTotal:     totalAmount,
+		BuyerFXRate:    money.OneRate(),
+		SellerCurrency: price.Currency,
+		SellerFXRate:   money.OneRate(),
+
+		Status: model.StatusPendingPayment,
+	}
+}
+
 // GetOrder retrieves an order by ID
 func (s *OrderService) GetOrder(ctx context.Context, orderID int64) (*model.Order, error) {
 	return s.repo.GetOrderByID(ctx, orderID)
@@ -155,163 +276,796 @@ func (s *OrderService) GetSellerOrders(ctx context.Context, sellerID int64, stat
 	return s.repo.GetSellerOrders(ctx, sellerID, status, page, pageSize)
 }
 
-// UpdateOrderStatus updates the status of an order
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, newStatus, updatedBy string) (*model.Order, error) {
-	// Validate new status
-	if !model.IsValidStatus(newStatus) {
-		return nil, fmt.Errorf("invalid status: %s", newStatus)
+// SearchOrders returns one page of orders matching the search and the cursor
+// for the next page (empty on the last page)
+func (s *OrderService) SearchOrders(ctx context.Context, search *model.OrderSearch) ([]*model.Order, string, error) {
+	if err := search.Normalize(); err != nil {
+		return nil, "", err
 	}
 
-	// Get current order
-	order, err := s.repo.GetOrderByID(ctx, orderID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check if transition is allowed
-	if !order.CanTransitionTo(newStatus) {
-		return nil, fmt.Errorf("cannot transition from %s to %s", order.Status, newStatus)
-	}
+	return s.repo.SearchOrders(ctx, search)
+}
 
-	// Update status
-	err = s.repo.UpdateOrderStatus(ctx, orderID, newStatus)
+// UpdateOrderStatus moves an order to a new status on behalf of an actor
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string, actor lifecycle.Actor, note string) (*model.Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return updated order
-	return s.repo.GetOrderByID(ctx, orderID)
+	return s.transition(ctx, order, newStatus, actor, note, nil)
 }
 
 // MarkAsPaid marks an order as paid (called by Payment Service)
 func (s *OrderService) MarkAsPaid(ctx context.Context, orderID int64) (*model.Order, error) {
-	return s.UpdateOrderStatus(ctx, orderID, model.StatusPaid, "payment_service")
+	return s.UpdateOrderStatus(ctx, orderID, model.StatusPaid, lifecycle.System(), "")
 }
 
 // MarkAsProcessing marks an order as processing (seller acknowledges)
 func (s *OrderService) MarkAsProcessing(ctx context.Context, orderID, sellerID int64) (*model.Order, error) {
-	// Verify seller
+	seller := lifecycle.Actor{Role: lifecycle.RoleSeller, UserID: sellerID}
+	return s.UpdateOrderStatus(ctx, orderID, model.StatusProcessing, seller, "")
+}
+
+// AddTrackingNumber adds tracking information and marks as shipped
+func (s *OrderService) AddTrackingNumber(ctx context.Context, orderID, sellerID int64, trackingNumber, carrier string) (*model.Order, error) {
+	// Validate inputs
+	if trackingNumber == "" {
+		return nil, fmt.Errorf("tracking number is required")
+	}
+	if carrier == "" {
+		return nil, fmt.Errorf("carrier is required")
+	}
+
 	order, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	if order.SellerID != sellerID {
-		return nil, fmt.Errorf("unauthorized: not the seller of this order")
+	order.TrackingNumber = sql.NullString{String: trackingNumber, Valid: true}
+	order.Carrier = sql.NullString{String: carrier, Valid: true}
+
+	seller := lifecycle.Actor{Role: lifecycle.RoleSeller, UserID: sellerID}
+	note := fmt.Sprintf("%s %s", carrier, trackingNumber)
+
+	return s.transition(ctx, order, model.StatusShipped, seller, note, func(tx pgx.Tx) error {
+		return s.repo.AddTrackingNumber(ctx, tx, orderID, trackingNumber, carrier)
+	})
+}
+
+// MarkAsDelivered marks an order as delivered
+func (s *OrderService) MarkAsDelivered(ctx context.Context, orderID int64) (*model.Order, error) {
+	return s.UpdateOrderStatus(ctx, orderID, model.StatusDelivered, lifecycle.System(), "")
+}
+
+// MarkAsCompleted marks an order as completed
+// This can be done by buyer confirmation or automatically after N days
+func (s *OrderService) MarkAsCompleted(ctx context.Context, orderID int64, actor lifecycle.Actor) (*model.Order, error) {
+	return s.UpdateOrderStatus(ctx, orderID, model.StatusCompleted, actor, "")
+}
+
+// CancelOrder cancels an order. A paid order is refunded to the buyer by
+// the cancellation hooks.
+func (s *OrderService) CancelOrder(ctx context.Context, orderID int64, actor lifecycle.Actor, reason string) (*model.Order, error) {
+	return s.UpdateOrderStatus(ctx, orderID, model.StatusCancelled, actor, reason)
+}
+
+// ReceiveForAuthentication records that the item arrived at the authentication center
+func (s *OrderService) ReceiveForAuthentication(ctx context.Context, orderID, authenticatorID int64, notes string) (*model.Order, error) {
+	return s.recordAuthentication(ctx, orderID, authenticatorID, model.AuthenticationReceived, notes, nil)
+}
+
+// RecordAuthenticationResult records the authenticator's verdict. A failed item
+// requires notes explaining why; photos are optional in both cases.
+func (s *OrderService) RecordAuthenticationResult(ctx context.Context, orderID, authenticatorID int64, passed bool, notes string, photoURLs []string) (*model.Order, error) {
+	result := model.AuthenticationPassed
+	if !passed {
+		result = model.AuthenticationFailed
+	}
+
+	return s.recordAuthentication(ctx, orderID, authenticatorID, result, notes, photoURLs)
+}
+
+// GetAuthentications retrieves the authentication records for an order
+func (s *OrderService) GetAuthentications(ctx context.Context, orderID int64) ([]*model.Authentication, error) {
+	// Verify order exists
+	_, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.UpdateOrderStatus(ctx, orderID, model.StatusProcessing, fmt.Sprintf("seller_%d", sellerID))
+	return s.repo.GetAuthentications(ctx, orderID)
 }
 
-// AddTrackingNumber adds tracking information and marks as shipped
-func (s *OrderService) AddTrackingNumber(ctx context.Context, orderID, sellerID int64, trackingNumber, carrier string) (*model.Order, error) {
-	// Verify seller
+// recordAuthentication stores the authentication record and moves the order
+// to the matching status in one transaction
+func (s *OrderService) recordAuthentication(
+	ctx context.Context,
+	orderID, authenticatorID int64,
+	result, notes string,
+	photoURLs []string,
+) (*model.Order, error) {
+	actor, err := s.authenticatorActor(ctx, authenticatorID)
+	if err != nil {
+		return nil, err
+	}
+
 	order, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	if order.SellerID != sellerID {
-		return nil, fmt.Errorf("unauthorized: not the seller of this order")
+	newStatus := model.StatusReceivedAtAuthentication
+	switch result {
+	case model.AuthenticationPassed:
+		newStatus = model.StatusAuthenticated
+	case model.AuthenticationFailed:
+		newStatus = model.StatusFailedAuthentication
 	}
 
-	// Validate order status
-	if order.Status != model.StatusProcessing && order.Status != model.StatusPaid {
-		return nil, fmt.Errorf("cannot add tracking: order must be in processing or paid status")
+	auth := &model.Authentication{
+		OrderID:         orderID,
+		AuthenticatorID: authenticatorID,
+		Result:          result,
+		PhotoURLs:       photoURLs,
+	}
+	if notes != "" {
+		auth.Notes = sql.NullString{String: notes, Valid: true}
 	}
 
-	// Validate inputs
-	if trackingNumber == "" {
-		return nil, fmt.Errorf("tracking number is required")
+	return s.transition(ctx, order, newStatus, actor, notes, func(tx pgx.Tx) error {
+		return s.repo.CreateAuthentication(ctx, tx, auth)
+	})
+}
+
+// ResolveActor checks the role a user claims against their account. Admin
+// and authenticator roles must be the user's own; buyers and sellers are
+// checked against the order by the lifecycle.
+func (s *OrderService) ResolveActor(ctx context.Context, claimed lifecycle.Role, userID int64) (lifecycle.Actor, error) {
+	switch claimed {
+	case lifecycle.RoleAuthenticator:
+		return s.authenticatorActor(ctx, userID)
+	case lifecycle.RoleAdmin:
+		role, err := s.repo.GetUserRole(ctx, userID)
+		if err != nil {
+			return lifecycle.Actor{}, err
+		}
+		if role != string(lifecycle.RoleAdmin) {
+			return lifecycle.Actor{}, fmt.Errorf("unauthorized: user is not an admin")
+		}
 	}
-	if carrier == "" {
-		return nil, fmt.Errorf("carrier is required")
+
+	return lifecycle.Actor{Role: claimed, UserID: userID}, nil
+}
+
+// authenticatorActor verifies that the user may authenticate items
+func (s *OrderService) authenticatorActor(ctx context.Context, userID int64) (lifecycle.Actor, error) {
+	role, err := s.repo.GetUserRole(ctx, userID)
+	if err != nil {
+		return lifecycle.Actor{}, err
+	}
+
+	switch role {
+	case string(lifecycle.RoleAuthenticator):
+		return lifecycle.Actor{Role: lifecycle.RoleAuthenticator, UserID: userID}, nil
+	case string(lifecycle.RoleAdmin):
+		return lifecycle.Actor{Role: lifecycle.RoleAdmin, UserID: userID}, nil
 	}
 
-	// Add tracking number (also updates status to shipped)
-	err = s.repo.AddTrackingNumber(ctx, orderID, trackingNumber, carrier)
+	return lifecycle.Actor{}, fmt.Errorf("unauthorized: user is not an authenticator")
+}
+
+// UnpaidOrderPolicy controls what happens to orders the buyer never pays for
+type UnpaidOrderPolicy struct {
+	Timeout      time.Duration // how long an order may stay in pending_payment
+	RelistAsk    bool          // re-activate the seller's ask; otherwise it is withdrawn
+	StrikePoints int32         // strike points applied to the buyer (0 disables)
+}
+
+// unpaidOrderBatchSize caps how many orders one sweep cancels
+const unpaidOrderBatchSize = 100
+
+// CancelUnpaidOrders cancels orders left unpaid past the policy deadline and
+// returns how many were canceled
+func (s *OrderService) CancelUnpaidOrders(ctx context.Context, policy UnpaidOrderPolicy) (int, error) {
+	orders, err := s.repo.ListUnpaidOrders(ctx, time.Now().Add(-policy.Timeout), unpaidOrderBatchSize)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return s.repo.GetOrderByID(ctx, orderID)
+	reason := fmt.Sprintf("Payment not received within %s", policy.Timeout)
+
+	canceled := 0
+	for _, order := range orders {
+		if _, err := s.CancelOrder(ctx, order.ID, lifecycle.System(), reason); err != nil {
+			log.Printf("Failed to cancel unpaid order %s: %v", order.OrderNumber, err)
+			continue
+		}
+		canceled++
+
+		if policy.RelistAsk {
+			s.relistAsk(ctx, order)
+		} else {
+			s.cancelMatch(ctx, order)
+		}
+		if policy.StrikePoints > 0 {
+			s.strikeBuyer(ctx, order, policy.StrikePoints)
+		}
+	}
+
+	return canceled, nil
 }
 
-// MarkAsDelivered marks an order as delivered
-func (s *OrderService) MarkAsDelivered(ctx context.Context, orderID int64) (*model.Order, error) {
-	return s.UpdateOrderStatus(ctx, orderID, model.StatusDelivered, "system")
+// StartUnpaidOrderWorker runs CancelUnpaidOrders on every tick until ctx is canceled
+func (s *OrderService) StartUnpaidOrderWorker(ctx context.Context, interval time.Duration, policy UnpaidOrderPolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			canceled, err := s.CancelUnpaidOrders(ctx, policy)
+			if err != nil {
+				log.Printf("Unpaid order worker failed: %v", err)
+				continue
+			}
+			if canceled > 0 {
+				log.Printf("Canceled %d unpaid orders", canceled)
+			}
+		}
+	}
 }
 
-// MarkAsCompleted marks an order as completed
-// This can be done by buyer confirmation or automatically after N days
-func (s *OrderService) MarkAsCompleted(ctx context.Context, orderID int64, completedBy string) (*model.Order, error) {
-	order, err := s.repo.GetOrderByID(ctx, orderID)
+// AutoCompletePolicy controls when delivered orders complete without the buyer
+type AutoCompletePolicy struct {
+	InspectionDays int           // default window; vertical_order_policies overrides it per category
+	ReminderBefore time.Duration // how long before the window closes the buyer is reminded
+}
+
+// autoCompleteBatchSize caps how many orders one sweep completes
+const autoCompleteBatchSize = 100
+
+// CompleteInspectedOrders reminds buyers whose inspection window is about to
+// close, then completes delivered orders whose window has closed without a
+// dispute. Completion triggers the seller payout. It returns how many orders
+// were completed.
+func (s *OrderService) CompleteInspectedOrders(ctx context.Context, policy AutoCompletePolicy) (int, error) {
+	now := time.Now()
+
+	if s.notificationClient != nil && policy.ReminderBefore > 0 {
+		pending, err := s.repo.ListOrdersToRemind(ctx, policy.InspectionDays, now.Add(policy.ReminderBefore), autoCompleteBatchSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range pending {
+			if p.CompleteAt.After(now) {
+				s.remindInspectionEnding(ctx, p)
+			}
+		}
+	}
+
+	pending, err := s.repo.ListOrdersToAutoComplete(ctx, policy.InspectionDays, now, autoCompleteBatchSize)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	if order.Status != model.StatusDelivered {
-		return nil, fmt.Errorf("order must be delivered before completion")
+	completed := 0
+	for _, p := range pending {
+		if _, err := s.MarkAsCompleted(ctx, p.Order.ID, lifecycle.System()); err != nil {
+			log.Printf("Failed to auto-complete order %s: %v", p.Order.OrderNumber, err)
+			continue
+		}
+		completed++
+	}
+
+	return completed, nil
+}
+
+// StartAutoCompleteWorker runs CompleteInspectedOrders on every tick until ctx is canceled
+func (s *OrderService) StartAutoCompleteWorker(ctx context.Context, interval time.Duration, policy AutoCompletePolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			completed, err := s.CompleteInspectedOrders(ctx, policy)
+			if err != nil {
+				log.Printf("Auto-complete worker failed: %v", err)
+				continue
+			}
+			if completed > 0 {
+				log.Printf("Auto-completed %d delivered orders", completed)
+			}
+		}
+	}
+}
+
+// remindInspectionEnding tells the buyer the order completes soon unless they open a dispute
+func (s *OrderService) remindInspectionEnding(ctx context.Context, p *model.PendingCompletion) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"order_id":     p.Order.ID,
+		"order_number": p.Order.OrderNumber,
+		"complete_at":  p.CompleteAt,
+	})
+
+	resp, err := s.notificationClient.SendNotification(ctx, &notificationPb.SendNotificationRequest{
+		UserId: p.Order.BuyerID,
+		Type:   "order_inspection_ending",
+		Title:  "Your inspection window is closing",
+		Message: fmt.Sprintf("Order %s will be completed on %s. If something is wrong with your item, open a dispute before then.",
+			p.Order.OrderNumber, p.CompleteAt.Format("Jan 2, 15:04 MST")),
+		Data:      string(data),
+		SendEmail: true,
+	})
+	if err != nil {
+		log.Printf("Failed to remind buyer of order %s: %v", p.Order.OrderNumber, err)
+		return
+	}
+	if resp.Error != "" {
+		log.Printf("Failed to remind buyer of order %s: %s", p.Order.OrderNumber, resp.Error)
+		return
+	}
+
+	if err := s.repo.MarkInspectionReminderSent(ctx, p.Order.ID); err != nil {
+		log.Printf("Order %s: %v", p.Order.OrderNumber, err)
+	}
+}
+
+// relistAsk puts the seller's ask back on the market through the Bidding Service
+func (s *OrderService) relistAsk(ctx context.Context, order *model.Order) {
+	if s.biddingClient == nil {
+		return
+	}
+
+	resp, err := s.biddingClient.RelistAsk(ctx, &biddingPb.RelistAskRequest{
+		MatchId: order.MatchID,
+	})
+	if err != nil {
+		log.Printf("Failed to relist ask for order %s: %v", order.OrderNumber, err)
+	} else if resp.Error != "" {
+		log.Printf("Failed to relist ask for order %s: %s", order.OrderNumber, resp.Error)
+	}
+}
+
+// cancelMatch fails the order's match and withdraws the seller's ask
+// through the Bidding Service
+func (s *OrderService) cancelMatch(ctx context.Context, order *model.Order) {
+	if s.biddingClient == nil {
+		return
 	}
 
-	return s.UpdateOrderStatus(ctx, orderID, model.StatusCompleted, completedBy)
+	resp, err := s.biddingClient.CancelMatch(ctx, &biddingPb.CancelMatchRequest{
+		MatchId: order.MatchID,
+	})
+	if err != nil {
+		log.Printf("Failed to cancel match for order %s: %v", order.OrderNumber, err)
+	} else if resp.Error != "" {
+		log.Printf("Failed to cancel match for order %s: %s", order.OrderNumber, resp.Error)
+	}
+}
+
+// strikeBuyer records a strike against the buyer who did not pay
+func (s *OrderService) strikeBuyer(ctx context.Context, order *model.Order, points int32) {
+	if s.userClient == nil {
+		return
+	}
+
+	resp, err := s.userClient.AddStrike(ctx, &userPb.AddStrikeRequest{
+		UserId:  order.BuyerID,
+		Points:  points,
+		Reason:  userModel.StrikeReasonUnpaidOrder,
+		OrderId: order.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to add strike for order %s: %v", order.OrderNumber, err)
+	} else if resp.Error != "" {
+		log.Printf("Failed to add strike for order %s: %s", order.OrderNumber, resp.Error)
+	}
 }
 
-// CancelOrder cancels an order
-func (s *OrderService) CancelOrder(ctx context.Context, orderID, userID int64, reason string) (*model.Order, error) {
-	// Get order
+// PartyActor resolves a user to the buyer or seller side of an order
+func (s *OrderService) PartyActor(ctx context.Context, orderID, userID int64) (lifecycle.Actor, error) {
 	order, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		return nil, err
+		return lifecycle.Actor{}, err
 	}
 
-	// Check if user is authorized (buyer or seller)
-	if order.BuyerID != userID && order.SellerID != userID {
-		return nil, fmt.Errorf("unauthorized: not a party to this order")
+	switch userID {
+	case order.BuyerID:
+		return lifecycle.Actor{Role: lifecycle.RoleBuyer, UserID: userID}, nil
+	case order.SellerID:
+		return lifecycle.Actor{Role: lifecycle.RoleSeller, UserID: userID}, nil
 	}
 
-	// Check if order can be canceled
-	if !order.CanBeCancelled() {
-		return nil, fmt.Errorf("order cannot be canceled in current status: %s", order.Status)
+	return lifecycle.Actor{}, fmt.Errorf("unauthorized: not a party to this order")
+}
+
+// transition checks the change against the lifecycle, then updates the order,
+// records it in the status history and queues its hooks in one transaction.
+// prepare, if set, runs inside that transaction before the status update.
+// The hooks run right after commit; the hook worker retries those that fail.
+func (s *OrderService) transition(
+	ctx context.Context,
+	order *model.Order,
+	newStatus string,
+	actor lifecycle.Actor,
+	note string,
+	prepare func(tx pgx.Tx) error,
+) (*model.Order, error) {
+	change := &lifecycle.Change{
+		Order: order,
+		From:  order.Status,
+		To:    newStatus,
+		Actor: actor,
+		Note:  note,
 	}
 
-	// Validate reason
-	if reason == "" {
-		return nil, fmt.Errorf("cancellation reason is required")
+	if err := s.machine.Check(ctx, change); err != nil {
+		return nil, err
 	}
 
-	// Cancel order
-	err = s.repo.CancelOrder(ctx, orderID, reason)
+	tx, err := s.repo.BeginTx(ctx)
 	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if prepare != nil {
+		if err := prepare(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	entry := &model.OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: sql.NullString{String: order.Status, Valid: true},
+		ToStatus:   newStatus,
+		CreatedBy:  actor.String(),
+		ActorRole:  string(actor.Role),
+	}
+	if note != "" {
+		entry.Note = sql.NullString{String: note, Valid: true}
+	}
+	if actor.UserID != 0 {
+		entry.ActorID = sql.NullInt64{Int64: actor.UserID, Valid: true}
+	}
+
+	if err := s.repo.TransitionStatus(ctx, tx, entry); err != nil {
 		return nil, err
 	}
 
-	// TODO: If order was paid, initiate refund via Payment Service
+	jobs := s.machine.Jobs(change)
+	if err := s.repo.EnqueueHookJobs(ctx, tx, jobs); err != nil {
+		return nil, err
+	}
 
-	return s.repo.GetOrderByID(ctx, orderID)
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.machine.RunJobs(ctx, s.repo, jobs)
+
+	return s.repo.GetOrderByID(ctx, order.ID)
 }
 
-// GetOrderStatusHistory retrieves the status history for an order
-func (s *OrderService) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusHistory, error) {
-	// Verify order exists
-	_, err := s.repo.GetOrderByID(ctx, orderID)
+const (
+	// hookBatchSize caps how many hook jobs one sweep runs
+	hookBatchSize = 100
+	// hookStaleAfter is when a processing hook job is assumed abandoned
+	hookStaleAfter = 10 * time.Minute
+)
+
+// ProcessHookJobs runs the status hooks that are due for a retry, or were
+// queued by a process that stopped before running them, and returns how
+// many succeeded
+func (s *OrderService) ProcessHookJobs(ctx context.Context) (int, error) {
+	jobs, err := s.repo.ClaimHookJobs(ctx, hookStaleAfter, hookBatchSize)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return s.repo.GetOrderStatusHistory(ctx, orderID)
+	return s.machine.RunJobs(ctx, s.repo, jobs), nil
 }
 
-// GetShippingStatus gets shipping information for an order
-func (s *OrderService) GetShippingStatus(ctx context.Context, orderID int64) (*model.Order, error) {
-	order, err := s.repo.GetOrderByID(ctx, orderID)
+// StartHookWorker periodically retries failed status hooks until ctx is canceled
+func (s *OrderService) StartHookWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			done, err := s.ProcessHookJobs(ctx)
+			if err != nil {
+				log.Printf("Hook worker failed: %v", err)
+				continue
+			}
+			if done > 0 {
+				log.Printf("Retried %d order hooks", done)
+			}
+		}
+	}
+}
+
+// registerHooks wires the side effects of entering each status
+func (s *OrderService) registerHooks() {
+	for _, status := range model.ValidStatuses() {
+		s.machine.OnEnter(status, "notify", s.notifyStatusChange)
+	}
+
+	s.machine.OnEnter(model.StatusCompleted, "complete_sale", s.completeSale)
+	s.machine.OnEnter(model.StatusCompleted, "ledger", s.postSale)
+	s.machine.OnEnter(model.StatusCompleted, "payout", s.triggerPayout)
+	s.machine.OnEnter(model.StatusCancelled, "release_inventory", s.releaseUnshippedInventory)
+	s.machine.OnEnter(model.StatusCancelled, "reverse_fee", s.reverseFee)
+	s.machine.OnEnter(model.StatusCancelled, "refund_buyer", s.refundCancelled)
+	s.machine.OnEnter(model.StatusRefunded, "release_inventory", s.releaseUnshippedInventory)
+	s.machine.OnEnter(model.StatusRefunded, "reverse_fee", s.reverseFee)
+
+	s.machine.OnEnter(model.StatusFailedAuthentication, "refund_buyer", s.refundBuyer)
+	s.machine.OnEnter(model.StatusFailedAuthentication, "return_to_seller", s.returnToSeller)
+	s.machine.OnEnter(model.StatusFailedAuthentication, "release_inventory", s.releaseInventory)
+}
+
+// notifyStatusChange tells buyer and seller about the new status
+func (s *OrderService) notifyStatusChange(ctx context.Context, c *lifecycle.Change) error {
+	if s.notificationClient == nil {
+		return nil
+	}
+
+	req := &notificationPb.NotifyOrderUpdateRequest{
+		OrderId:     c.Order.ID,
+		OrderNumber: c.Order.OrderNumber,
+		BuyerId:     c.Order.BuyerID,
+		SellerId:    c.Order.SellerID,
+		OldStatus:   c.From,
+		NewStatus:   c.To,
+	}
+	if c.Order.TrackingNumber.Valid {
+		req.TrackingNumber = c.Order.TrackingNumber.String
+	}
+
+	resp, err := s.notificationClient.NotifyOrderUpdate(ctx, req)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Error)
+	}
+
+	return nil
+}
+
+// completeSale consumes the units the seller's ask reserved on the product
+// size, from the same reservation the ask made
+func (s *OrderService) completeSale(ctx context.Context, c *lifecycle.Change) error {
+	if s.productClient == nil {
+		return nil
+	}
+
+	resp, err := s.productClient.CompleteSale(ctx, &productPb.CompleteSaleRequest{
+		SizeId:   c.Order.SizeID,
+		Quantity: c.Order.Quantity,
+		OrderId:  c.Order.InventoryReference,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Error)
+	}
+
+	return nil
+}
+
+// postSale moves the buyer's payment to the seller and the platform in the
+// ledger, net of partial refunds
+func (s *OrderService) postSale(ctx context.Context, c *lifecycle.Change) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	order := c.Order
+	refunded, err := s.refundedTotal(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	// Refunds were converted at the order's own rate
+	quote := fxModel.Snapshot(order.TotalAmount.Currency, order.BuyerTotal.Currency, order.BuyerFXRate)
+
+	return s.ledger.RecordSale(ctx, &ledgerModel.Sale{
+		OrderID:         order.ID,
+		BuyerID:         order.BuyerID,
+		SellerID:        order.SellerID,
+		BuyerPaid:       order.BuyerTotal.Sub(quote.Apply(refunded)),
+		Total:           order.TotalAmount.Sub(refunded),
+		SellerPayable:   order.SellerPayout.Sub(refunded),
+		PlatformRevenue: order.TotalAmount.Sub(order.SellerPayout),
+	})
+}
+
+// releaseInventory makes the reserved units available again
+func (s *OrderService) releaseInventory(ctx context.Context, c *lifecycle.Change) error {
+	if s.productClient == nil {
+		return nil
+	}
+
+	resp, err := s.productClient.ReleaseInventory(ctx, &productPb.ReleaseInventoryRequest{
+		SizeId:   c.Order.SizeID,
+		Quantity: c.Order.Quantity,
+		OrderId:  c.Order.InventoryReference,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Error)
+	}
+
+	return nil
+}
+
+// refundBuyer refunds the buyer in full when the item fails authentication
+func (s *OrderService) refundBuyer(ctx context.Context, c *lifecycle.Change) error {
+	return s.refundOrder(ctx, c.Order, fmt.Sprintf("Failed authentication: %s", c.Note))
+}
+
+// refundCancelled refunds what is left of the buyer's payment when a paid
+// order is cancelled. An order closed by a full refund has nothing left.
+func (s *OrderService) refundCancelled(ctx context.Context, c *lifecycle.Change) error {
+	if c.From == model.StatusPendingPayment {
+		return nil
+	}
+
+	refunded, err := s.refundedTotal(ctx, c.Order)
+	if err != nil {
+		return err
+	}
+	if !refunded.LessThan(c.Order.TotalAmount) {
+		return nil
+	}
+
+	return s.refundOrder(ctx, c.Order, fmt.Sprintf("Order cancelled: %s", c.Note))
+}
+
+// refundOrder refunds the buyer's payment for the order in full. The
+// refund is keyed by order, so a retry never refunds twice.
+func (s *OrderService) refundOrder(ctx context.Context, order *model.Order, reason string) error {
+	if s.paymentClient == nil {
+		return nil
+	}
+
+	resp, err := s.paymentClient.CreateRefund(ctx, &paymentPb.CreateRefundRequest{
+		OrderId:        order.ID,
+		Reason:         reason,
+		IdempotencyKey: fmt.Sprintf("order:%d:full-refund", order.ID),
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+
+	return nil
+}
+
+// returnToSeller opens a return shipment for the item that failed authentication
+func (s *OrderService) returnToSeller(ctx context.Context, c *lifecycle.Change) error {
+	return s.repo.CreateReturn(ctx, &model.OrderReturn{
+		OrderID:  c.Order.ID,
+		SellerID: c.Order.SellerID,
+		Reason:   c.Note,
+		Status:   model.ReturnStatusPending,
+	})
+}
+
+// triggerPayout credits the seller's wallet once the buyer has accepted the
+// order; the Payment Service pays wallets out in scheduled batches. The
+// credit is held while the buyer has a dispute open on the order.
+func (s *OrderService) triggerPayout(ctx context.Context, c *lifecycle.Change) error {
+	dispute, err := s.repo.GetOpenDisputeByOrderID(ctx, c.Order.ID)
+	if err != nil {
+		return err
+	}
+	if dispute != nil {
+		log.Printf("Order %s: payout held while dispute %d is open", c.Order.OrderNumber, dispute.ID)
+		return s.repo.MarkDisputePayoutHeld(ctx, dispute.ID)
+	}
+
+	return s.creditSeller(ctx, c.Order)
+}
+
+// creditSeller credits the seller's wallet with their share, less partial
+// refunds
+func (s *OrderService) creditSeller(ctx context.Context, order *model.Order) error {
+	if s.paymentClient == nil {
+		return nil
+	}
+
+	refunded, err := s.refundedTotal(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	amount := order.SellerPayout.Sub(refunded)
+	if !amount.IsPositive() {
+		log.Printf("Order %s: nothing to pay out after %s in refunds", order.OrderNumber, refunded)
+		return nil
+	}
+
+	resp, err := s.paymentClient.CreditSellerWallet(ctx, &paymentPb.CreditSellerWalletRequest{
+		OrderId:     order.ID,
+		SellerId:    order.SellerID,
+		AmountMinor: amount.Amount,
+		Currency:    amount.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+
+	return nil
+}
+
+// refundedTotal is what was refunded on an order, in the settlement
+// currency. Refunds are recorded as adjustments in the buyer's currency and
+// converted back at the order's own rate. A partial refund granted in a
+// dispute is only recorded once the provider confirms it, so the disputes'
+// total stands in until then.
+func (s *OrderService) refundedTotal(ctx context.Context, order *model.Order) (money.Money, error) {
+	disputed, err := s.repo.GetDisputeRefundTotal(ctx, order.ID)
+	if err != nil {
+		return money.Money{}, err
 	}
 
-	if !order.TrackingNumber.Valid {
-		return nil, fmt.Errorf("no tracking information available")
+	adjustments, err := s.repo.GetAdjustments(ctx, order.ID)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	paid := money.Zero(order.BuyerTotal.Currency)
+	for _, adj := range adjustments {
+		if adj.Type == model.AdjustmentTypeRefund {
+			paid = paid.Add(adj.Amount)
+		}
 	}
 
-	return order, nil
+	quote := fxModel.Snapshot(order.TotalAmount.Currency, order.BuyerTotal.Currency, order.BuyerFXRate).Inverse()
+
+	return money.Max(disputed, quote.Apply(paid)), nil
+}
+
+// buyerQuote quotes the settlement currency in currency (empty: the
+// settlement currency)
+func (s *OrderService) buyerQuote(ctx context.Context, currency string) (*fxModel.Quote, error) {
+	if currency == "" || currency == money.DefaultCurrency {
+		return fxModel.Snapshot(money.DefaultCurrency, money.DefaultCurrency, money.OneRate()), nil
+	}
+	if s.fxService == nil {
+		return nil, fmt.Errorf("payments must be in %s", money.DefaultCurrency)
+	}
+
+	return s.fxService.Quote(ctx, money.DefaultCurrency, currency)
+}
+
+// GetOrderStatusHistory retrieves the status history for an order
+func (s *OrderService) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusHistory, error) {
+	// Verify order exists
+	_, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetOrderStatusHistory(ctx, orderID)
 }
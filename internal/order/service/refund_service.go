@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// ApplyRefund records a refund of the buyer's payment as an adjustment on
+// the order (called by Payment Service). A full refund also closes the
+// order: refunded where the lifecycle allows it, canceled otherwise. The
+// status hooks release the inventory, reverse the fee and notify both
+// parties. The same reference is applied once.
+func (s *OrderService) ApplyRefund(ctx context.Context, orderID int64, reference string, amount money.Money, full bool, reason string) (*model.Order, error) {
+	if reference == "" {
+		return nil, fmt.Errorf("refund reference is required")
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("refund amount must be greater than 0")
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	adj := &model.OrderAdjustment{
+		OrderID:   orderID,
+		Type:      model.AdjustmentTypeRefund,
+		Amount:    amount,
+		Reference: reference,
+	}
+	if reason != "" {
+		adj.Reason = sql.NullString{String: reason, Valid: true}
+	}
+
+	recorded, err := s.repo.CreateAdjustment(ctx, adj)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		log.Printf("Order %s: refund %s was already applied", order.OrderNumber, reference)
+	}
+
+	if !full || order.Status == model.StatusRefunded || order.Status == model.StatusCancelled {
+		return order, nil
+	}
+
+	note := "Payment refunded in full"
+	if reason != "" {
+		note = fmt.Sprintf("%s: %s", note, reason)
+	}
+
+	target, err := s.refundTarget(ctx, order, note)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.transition(ctx, order, target, lifecycle.System(), note, nil)
+}
+
+// GetAdjustments retrieves the adjustments recorded on an order
+func (s *OrderService) GetAdjustments(ctx context.Context, orderID int64) ([]*model.OrderAdjustment, error) {
+	return s.repo.GetAdjustments(ctx, orderID)
+}
+
+// refundTarget picks the status a fully refunded order closes in
+func (s *OrderService) refundTarget(ctx context.Context, order *model.Order, note string) (string, error) {
+	for _, status := range []string{model.StatusRefunded, model.StatusCancelled} {
+		change := &lifecycle.Change{
+			Order: order,
+			From:  order.Status,
+			To:    status,
+			Actor: lifecycle.System(),
+			Note:  note,
+		}
+		if s.machine.Check(ctx, change) == nil {
+			return status, nil
+		}
+	}
+
+	return "", fmt.Errorf("order %s cannot be closed from %s", order.OrderNumber, order.Status)
+}
+
+// releaseUnshippedInventory releases the units an order closed before it
+// shipped still reserves. Once shipped the units have left the seller, and
+// failed authentication released them already.
+func (s *OrderService) releaseUnshippedInventory(ctx context.Context, c *lifecycle.Change) error {
+	switch c.From {
+	case model.StatusPendingPayment, model.StatusPaid, model.StatusProcessing:
+		return s.releaseInventory(ctx, c)
+	default:
+		return nil
+	}
+}
+
+// reverseFee takes the fee recorded for the order's match out of revenue
+func (s *OrderService) reverseFee(ctx context.Context, c *lifecycle.Change) error {
+	if s.feeRepo == nil {
+		return nil
+	}
+
+	reason := fmt.Sprintf("Order %s %s", c.Order.OrderNumber, c.To)
+	if c.Note != "" {
+		reason = fmt.Sprintf("%s: %s", reason, c.Note)
+	}
+
+	_, err := s.feeRepo.ReverseTransactionFee(ctx, c.Order.MatchID, reason)
+	return err
+}
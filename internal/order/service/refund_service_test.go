@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+func TestApplyFullRefundClosesOrder(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		released bool
+	}{
+		{model.StatusPaid, model.StatusRefunded, true},
+		{model.StatusDelivered, model.StatusRefunded, false},
+		{model.StatusShipped, model.StatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			ts := newTestService()
+			order := ts.addOrder(11, tt.from)
+
+			if _, err := ts.ApplyRefund(context.Background(), order.ID, "re_1", order.BuyerTotal, true, "requested_by_customer"); err != nil {
+				t.Fatalf("ApplyRefund: %v", err)
+			}
+
+			if status := ts.status(t, order.ID); status != tt.to {
+				t.Errorf("status = %s, want %s", status, tt.to)
+			}
+			ts.checkHooks(t)
+
+			if len(ts.fees.matches) != 1 || ts.fees.matches[0] != order.MatchID {
+				t.Errorf("fees reversed for matches %v, want [%d]", ts.fees.matches, order.MatchID)
+			}
+
+			// Units still reserved go back on sale; shipped units have left the seller
+			if !tt.released {
+				if len(ts.products.released) != 0 {
+					t.Errorf("inventory released after the order left %s", tt.from)
+				}
+			} else if len(ts.products.released) != 1 || ts.products.released[0].OrderId != order.InventoryReference {
+				t.Errorf("released %+v, want reservation %s", ts.products.released, order.InventoryReference)
+			}
+
+			// The buyer already has the money back
+			if len(ts.payments.refunds) != 0 {
+				t.Errorf("closing the order refunded the buyer again: %+v", ts.payments.refunds)
+			}
+		})
+	}
+}
+
+func TestApplyPartialRefundKeepsOrderOpen(t *testing.T) {
+	ts := newTestService()
+	order := ts.addOrder(11, model.StatusDelivered)
+
+	if _, err := ts.ApplyRefund(context.Background(), order.ID, "re_1", usd(5000), false, "damaged box"); err != nil {
+		t.Fatalf("ApplyRefund: %v", err)
+	}
+
+	if status := ts.status(t, order.ID); status != model.StatusDelivered {
+		t.Errorf("status = %s, want %s", status, model.StatusDelivered)
+	}
+	if len(ts.store.adjustments) != 1 || !ts.store.adjustments[0].Amount.Equal(usd(5000)) {
+		t.Errorf("adjustments = %+v, want one of 50.00", ts.store.adjustments)
+	}
+	if len(ts.fees.matches) != 0 || len(ts.store.jobs) != 0 {
+		t.Error("partial refund ran status hooks")
+	}
+}
+
+func TestApplyRefundOncePerReference(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestService()
+	order := ts.addOrder(11, model.StatusPaid)
+
+	for i := 0; i < 2; i++ {
+		if _, err := ts.ApplyRefund(ctx, order.ID, "re_1", order.BuyerTotal, true, ""); err != nil {
+			t.Fatalf("ApplyRefund %d: %v", i+1, err)
+		}
+	}
+
+	if len(ts.store.adjustments) != 1 {
+		t.Errorf("adjustments = %d, want 1", len(ts.store.adjustments))
+	}
+	if len(ts.store.jobs) == 0 || ts.store.commits != 1 {
+		t.Errorf("transitions committed = %d, want 1", ts.store.commits)
+	}
+	if len(ts.products.released) != 1 {
+		t.Errorf("inventory released %d times, want once", len(ts.products.released))
+	}
+}
+
+func TestCancelPaidOrderRefundsBuyerOnce(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestService()
+	order := ts.addOrder(11, model.StatusPaid)
+
+	cancelled, err := ts.CancelOrder(ctx, order.ID, lifecycle.System(), "Seller out of stock")
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if cancelled.Status != model.StatusCancelled {
+		t.Errorf("status = %s, want %s", cancelled.Status, model.StatusCancelled)
+	}
+	ts.checkHooks(t)
+
+	if len(ts.payments.refunds) != 1 {
+		t.Fatalf("refunds = %d, want 1", len(ts.payments.refunds))
+	}
+	if key := ts.payments.refunds[0].IdempotencyKey; key != fmt.Sprintf("order:%d:full-refund", order.ID) {
+		t.Errorf("refund idempotency key = %s", key)
+	}
+
+	// A retried hook finds the buyer refunded, even if the key has expired
+	ts.payments.keys = make(map[string]bool)
+	change := &lifecycle.Change{Order: cancelled, From: model.StatusPaid, To: model.StatusCancelled, Actor: lifecycle.System()}
+	if err := ts.refundCancelled(ctx, change); err != nil {
+		t.Fatalf("refundCancelled: %v", err)
+	}
+	if len(ts.payments.refunds) != 1 {
+		t.Errorf("refunds after a retried hook = %d, want 1", len(ts.payments.refunds))
+	}
+}
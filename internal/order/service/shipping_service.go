@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/carrier"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+// defaultSneakerParcel is a single pair in its box plus packaging
+var defaultSneakerParcel = carrier.Parcel{
+	WeightOz: 48,
+	LengthIn: 14,
+	WidthIn:  10,
+	HeightIn: 5,
+}
+
+// GetShippingRates quotes prepaid label options for the seller of an order
+func (s *OrderService) GetShippingRates(ctx context.Context, orderID, sellerID int64) ([]carrier.Rate, error) {
+	if s.carrierProvider == nil {
+		return nil, fmt.Errorf("shipping labels are not available")
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.SellerID != sellerID {
+		return nil, fmt.Errorf("unauthorized: not the seller of this order")
+	}
+
+	from, err := s.sellerAddress(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.carrierProvider.GetRates(ctx, from, s.authCenter, defaultSneakerParcel)
+}
+
+// CreateShippingLabel buys a prepaid label for the seller and stores it on the
+// order. An empty service selects the cheapest rate. The order moves to
+// shipped on the carrier's first scan (see RecordTrackingEvent).
+func (s *OrderService) CreateShippingLabel(ctx context.Context, orderID, sellerID int64, service string) (*model.Order, *carrier.Label, error) {
+	if s.carrierProvider == nil {
+		return nil, nil, fmt.Errorf("shipping labels are not available")
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.SellerID != sellerID {
+		return nil, nil, fmt.Errorf("unauthorized: not the seller of this order")
+	}
+	if order.LabelURL.Valid {
+		return nil, nil, fmt.Errorf("a shipping label was already created for this order")
+	}
+	if !s.canMoveTo(order, model.StatusShipped) {
+		return nil, nil, fmt.Errorf("cannot create shipping label for order in status %s", order.Status)
+	}
+
+	from, err := s.sellerAddress(ctx, order)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	label, err := s.carrierProvider.CreateLabel(ctx, carrier.LabelRequest{
+		Reference: order.OrderNumber,
+		From:      from,
+		To:        s.authCenter,
+		Parcel:    defaultSneakerParcel,
+		Service:   service,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create shipping label: %w", err)
+	}
+
+	if err := s.repo.AttachLabel(ctx, orderID, label.TrackingNumber, label.Carrier, label.Service, label.LabelURL); err != nil {
+		// Labels are keyed by order number, so a retry gets the same label back
+		log.Printf("Order %s: label %s created but not saved: %v", order.OrderNumber, label.TrackingNumber, err)
+		return nil, nil, err
+	}
+
+	updated, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updated, label, nil
+}
+
+// ShipToBuyer buys the label for sending an authenticated item from the
+// authentication center to the buyer. The order moves to delivered when the
+// carrier reports the delivery.
+func (s *OrderService) ShipToBuyer(ctx context.Context, orderID, authenticatorID int64) (*model.Order, *carrier.Label, error) {
+	if s.carrierProvider == nil {
+		return nil, nil, fmt.Errorf("shipping labels are not available")
+	}
+
+	if _, err := s.authenticatorActor(ctx, authenticatorID); err != nil {
+		return nil, nil, err
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Status != model.StatusAuthenticated {
+		return nil, nil, fmt.Errorf("only authenticated orders can be shipped to the buyer")
+	}
+	if order.OutboundTrackingNumber.Valid {
+		return nil, nil, fmt.Errorf("order was already shipped to the buyer")
+	}
+
+	to, err := s.buyerAddress(ctx, order)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	label, err := s.carrierProvider.CreateLabel(ctx, carrier.LabelRequest{
+		Reference: order.OrderNumber + "-OUT",
+		From:      s.authCenter,
+		To:        to,
+		Parcel:    defaultSneakerParcel,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create shipping label: %w", err)
+	}
+
+	if err := s.repo.AttachOutboundLabel(ctx, orderID, label.TrackingNumber, label.Carrier, label.LabelURL); err != nil {
+		log.Printf("Order %s: outbound label %s created but not saved: %v", order.OrderNumber, label.TrackingNumber, err)
+		return nil, nil, err
+	}
+
+	updated, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updated, label, nil
+}
+
+// RecordTrackingEvent stores a carrier scan and moves the order forward:
+//
+//	inbound in_transit / out_for_delivery -> shipped
+//	inbound delivered                     -> shipped, received_at_authentication
+//	outbound delivered                    -> delivered
+//
+// Scans that no longer apply to the order's status are stored but do not
+// change it. It returns false when the event was already recorded; the
+// transitions are still applied then, so a redelivery finishes what a
+// failure after storing the scan left undone.
+func (s *OrderService) RecordTrackingEvent(ctx context.Context, event *carrier.WebhookEvent) (*model.Order, bool, error) {
+	order, leg, err := s.repo.GetOrderByTrackingNumber(ctx, event.TrackingNumber)
+	if err != nil {
+		return nil, false, err
+	}
+
+	shipmentEvent := &model.ShipmentEvent{
+		OrderID:        order.ID,
+		Leg:            leg,
+		TrackingNumber: event.TrackingNumber,
+		Carrier:        event.Carrier,
+		Status:         event.Status,
+		Description:    sql.NullString{String: event.Description, Valid: event.Description != ""},
+		Location:       sql.NullString{String: event.Location, Valid: event.Location != ""},
+		EventID:        sql.NullString{String: event.EventID, Valid: event.EventID != ""},
+		OccurredAt:     event.OccurredAt,
+	}
+
+	created, err := s.repo.CreateShipmentEvent(ctx, shipmentEvent)
+	if err != nil {
+		return nil, false, err
+	}
+
+	note := fmt.Sprintf("%s scan: %s (%s)", event.Carrier, event.Status, event.TrackingNumber)
+	for _, status := range trackingTargets(leg, event.Status) {
+		if order.Status == status || !s.canMoveTo(order, status) {
+			continue
+		}
+
+		order, err = s.transition(ctx, order, status, lifecycle.System(), note, nil)
+		if err != nil {
+			return nil, created, err
+		}
+	}
+
+	return order, created, nil
+}
+
+// trackingTargets lists the statuses a scan moves an order through, in order
+func trackingTargets(leg, trackingStatus string) []string {
+	switch leg {
+	case model.ShipmentLegInbound:
+		switch trackingStatus {
+		case carrier.TrackingInTransit, carrier.TrackingOutForDelivery:
+			return []string{model.StatusShipped}
+		case carrier.TrackingDelivered:
+			return []string{model.StatusShipped, model.StatusReceivedAtAuthentication}
+		}
+	case model.ShipmentLegOutbound:
+		if trackingStatus == carrier.TrackingDelivered {
+			return []string{model.StatusDelivered}
+		}
+	}
+	return nil
+}
+
+// GetShippingStatus gets shipping information and the tracking timeline for
+// an order. Orders with a prepaid label but no stored scans fall back to
+// asking the carrier provider.
+func (s *OrderService) GetShippingStatus(ctx context.Context, orderID int64) (*model.Order, []*model.ShipmentEvent, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !order.TrackingNumber.Valid {
+		return nil, nil, fmt.Errorf("no tracking information available")
+	}
+
+	events, err := s.repo.GetShipmentEvents(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(events) > 0 || s.carrierProvider == nil || !order.LabelURL.Valid {
+		return order, events, nil
+	}
+
+	tracking, err := s.carrierProvider.Track(ctx, order.TrackingNumber.String)
+	if err != nil {
+		log.Printf("Order %s: failed to track %s: %v", order.OrderNumber, order.TrackingNumber.String, err)
+		return order, nil, nil
+	}
+
+	for _, e := range tracking.Events {
+		events = append(events, &model.ShipmentEvent{
+			OrderID:        order.ID,
+			Leg:            model.ShipmentLegInbound,
+			TrackingNumber: tracking.TrackingNumber,
+			Carrier:        tracking.Carrier,
+			Status:         e.Status,
+			Description:    sql.NullString{String: e.Description, Valid: e.Description != ""},
+			Location:       sql.NullString{String: e.Location, Valid: e.Location != ""},
+			OccurredAt:     e.OccurredAt,
+		})
+	}
+
+	return order, events, nil
+}
+
+// canMoveTo reports whether the order's current status allows moving to status
+func (s *OrderService) canMoveTo(order *model.Order, status string) bool {
+	for _, next := range s.machine.Next(order.Status) {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// sellerAddress returns the seller's default shipping address as the label origin
+func (s *OrderService) sellerAddress(ctx context.Context, order *model.Order) (carrier.Address, error) {
+	address, err := s.repo.GetDefaultShippingAddress(ctx, order.SellerID)
+	if err != nil {
+		return carrier.Address{}, fmt.Errorf("seller shipping address: %w", err)
+	}
+
+	return toCarrierAddress(address), nil
+}
+
+// buyerAddress returns the order's shipping address, or the buyer's default one
+func (s *OrderService) buyerAddress(ctx context.Context, order *model.Order) (carrier.Address, error) {
+	var address *model.Address
+	var err error
+
+	if order.ShippingAddressID.Valid {
+		address, err = s.repo.GetShippingAddress(ctx, order.ShippingAddressID.Int64)
+	} else {
+		address, err = s.repo.GetDefaultShippingAddress(ctx, order.BuyerID)
+	}
+	if err != nil {
+		return carrier.Address{}, fmt.Errorf("buyer shipping address: %w", err)
+	}
+
+	return toCarrierAddress(address), nil
+}
+
+func toCarrierAddress(address *model.Address) carrier.Address {
+	return carrier.Address{
+		Name:       address.Name,
+		Street1:    address.StreetLine1,
+		Street2:    address.StreetLine2,
+		City:       address.City,
+		State:      address.State,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+	}
+}
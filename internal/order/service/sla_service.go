@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/lifecycle"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	notificationPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/notification"
+)
+
+// ShippingSLAPolicy controls how long sellers have to ship a paid order
+type ShippingSLAPolicy struct {
+	ShipWithinHours int           // default window; vertical_order_policies overrides it per category
+	ReminderBefore  time.Duration // how long before the deadline the seller is reminded (0 disables)
+	PenaltyRate     float64       // share of the sale price charged to a seller who misses the deadline
+}
+
+// shippingSLABatchSize caps how many orders one sweep cancels
+const shippingSLABatchSize = 100
+
+// EnforceShippingSLA gives newly paid orders a shipping deadline, reminds
+// sellers whose deadline is close, and cancels orders whose deadline passed:
+// the buyer is refunded in full and a penalty is recorded on the seller. It
+// returns how many orders were canceled.
+func (s *OrderService) EnforceShippingSLA(ctx context.Context, policy ShippingSLAPolicy) (int, error) {
+	if _, err := s.repo.AssignShipDeadlines(ctx, policy.ShipWithinHours); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+
+	if s.notificationClient != nil && policy.ReminderBefore > 0 {
+		pending, err := s.repo.ListShipmentsToRemind(ctx, now.Add(policy.ReminderBefore), shippingSLABatchSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range pending {
+			if p.ShipBy.After(now) {
+				s.remindShipDeadline(ctx, p)
+			}
+		}
+	}
+
+	overdue, err := s.repo.ListOverdueShipments(ctx, now, shippingSLABatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	canceled := 0
+	for _, p := range overdue {
+		if err := s.cancelLateShipment(ctx, p, policy.PenaltyRate); err != nil {
+			log.Printf("Failed to cancel late order %s: %v", p.Order.OrderNumber, err)
+			continue
+		}
+		canceled++
+	}
+
+	return canceled, nil
+}
+
+// StartShippingSLAWorker runs EnforceShippingSLA on every tick until ctx is canceled
+func (s *OrderService) StartShippingSLAWorker(ctx context.Context, interval time.Duration, policy ShippingSLAPolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			canceled, err := s.EnforceShippingSLA(ctx, policy)
+			if err != nil {
+				log.Printf("Shipping SLA worker failed: %v", err)
+				continue
+			}
+			if canceled > 0 {
+				log.Printf("Canceled %d orders not shipped in time", canceled)
+			}
+		}
+	}
+}
+
+// cancelLateShipment refunds the buyer, penalizes the seller and cancels the
+// order. The refund is issued first, so a failed refund leaves the order
+// open for the next sweep. A full refund may already have closed the order
+// as refunded, in which case it is not canceled on top.
+func (s *OrderService) cancelLateShipment(ctx context.Context, p *model.PendingShipment, penaltyRate float64) error {
+	order := p.Order
+	reason := fmt.Sprintf("Seller did not ship by %s", p.ShipBy.Format("Jan 2, 15:04 MST"))
+
+	if err := s.refundOrder(ctx, order, reason); err != nil {
+		return fmt.Errorf("refund failed: %w", err)
+	}
+
+	penalty := &model.SellerPenalty{
+		SellerID: order.SellerID,
+		OrderID:  order.ID,
+		Reason:   model.PenaltyLateShipment,
+		Amount:   order.Price.MulBasisPoints(money.BasisPoints(penaltyRate*100), money.HalfUp),
+		Note:     sql.NullString{String: reason, Valid: true},
+	}
+	created, err := s.repo.CreateSellerPenalty(ctx, penalty)
+	if err != nil {
+		return err
+	}
+	if created {
+		s.notifySellerPenalty(ctx, order, penalty)
+	}
+
+	current, err := s.repo.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	if current.Status != model.StatusRefunded && current.Status != model.StatusCancelled {
+		if _, err := s.CancelOrder(ctx, order.ID, lifecycle.System(), reason); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remindShipDeadline tells the seller the order is canceled unless it ships soon
+func (s *OrderService) remindShipDeadline(ctx context.Context, p *model.PendingShipment) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"order_id":     p.Order.ID,
+		"order_number": p.Order.OrderNumber,
+		"ship_by":      p.ShipBy,
+	})
+
+	resp, err := s.notificationClient.SendNotification(ctx, &notificationPb.SendNotificationRequest{
+		UserId: p.Order.SellerID,
+		Type:   "order_ship_reminder",
+		Title:  "Ship your order soon",
+		Message: fmt.Sprintf("Order %s must ship by %s. Late orders are canceled, the buyer is refunded and a penalty applies.",
+			p.Order.OrderNumber, p.ShipBy.Format("Jan 2, 15:04 MST")),
+		Data:      string(data),
+		SendEmail: true,
+	})
+	if err != nil {
+		log.Printf("Failed to remind seller of order %s: %v", p.Order.OrderNumber, err)
+		return
+	}
+	if resp.Error != "" {
+		log.Printf("Failed to remind seller of order %s: %s", p.Order.OrderNumber, resp.Error)
+		return
+	}
+
+	if err := s.repo.MarkShipReminderSent(ctx, p.Order.ID); err != nil {
+		log.Printf("Order %s: %v", p.Order.OrderNumber, err)
+	}
+}
+
+// notifySellerPenalty tells the seller about the penalty for a late order
+func (s *OrderService) notifySellerPenalty(ctx context.Context, order *model.Order, penalty *model.SellerPenalty) {
+	if s.notificationClient == nil {
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"order_id":     order.ID,
+		"order_number": order.OrderNumber,
+		"penalty_id":   penalty.ID,
+		"reason":       penalty.Reason,
+		"amount":       penalty.Amount,
+	})
+
+	resp, err := s.notificationClient.SendNotification(ctx, &notificationPb.SendNotificationRequest{
+		UserId: order.SellerID,
+		Type:   "seller_penalty",
+		Title:  fmt.Sprintf("Order %s was canceled", order.OrderNumber),
+		Message: fmt.Sprintf("The order was not shipped in time. The buyer was refunded and a %s late shipment penalty was recorded on your account.",
+			penalty.Amount.Format()),
+		Data:      string(data),
+		SendEmail: true,
+	})
+	if err != nil {
+		log.Printf("Failed to notify seller of penalty on order %s: %v", order.OrderNumber, err)
+		return
+	}
+	if resp.Error != "" {
+		log.Printf("Failed to notify seller of penalty on order %s: %s", order.OrderNumber, resp.Error)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+)
+
+var slaPolicy = ShippingSLAPolicy{ShipWithinHours: 48, PenaltyRate: 0.10}
+
+// addPaidOrder stores an order of match matchID paid the given time ago
+func (ts *testService) addPaidOrder(matchID int64, status string, paidAgo time.Duration) *model.Order {
+	order := ts.addOrder(matchID, status)
+	order.PaymentAt = sql.NullTime{Time: time.Now().Add(-paidAgo), Valid: true}
+	return order
+}
+
+func TestEnforceShippingSLACancelsLateOrders(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestService()
+	late := ts.addPaidOrder(11, model.StatusPaid, 72*time.Hour)
+	onTime := ts.addPaidOrder(12, model.StatusProcessing, time.Hour)
+	shipped := ts.addPaidOrder(13, model.StatusShipped, 72*time.Hour)
+
+	canceled, err := ts.EnforceShippingSLA(ctx, slaPolicy)
+	if err != nil {
+		t.Fatalf("EnforceShippingSLA: %v", err)
+	}
+	if canceled != 1 {
+		t.Errorf("canceled = %d, want 1", canceled)
+	}
+	ts.checkHooks(t)
+
+	// The full refund closes the late order
+	if status := ts.status(t, late.ID); status != model.StatusRefunded {
+		t.Errorf("late order = %s, want %s", status, model.StatusRefunded)
+	}
+	if len(ts.payments.refunds) != 1 || ts.payments.refunds[0].OrderId != late.ID {
+		t.Errorf("refunds = %+v, want the late order's", ts.payments.refunds)
+	}
+	if len(ts.products.released) != 1 || ts.products.released[0].OrderId != late.InventoryReference {
+		t.Errorf("released %+v, want reservation %s", ts.products.released, late.InventoryReference)
+	}
+	if len(ts.fees.matches) != 1 || ts.fees.matches[0] != late.MatchID {
+		t.Errorf("fees reversed for matches %v, want [%d]", ts.fees.matches, late.MatchID)
+	}
+
+	if len(ts.store.penalties) != 1 {
+		t.Fatalf("penalties = %d, want 1", len(ts.store.penalties))
+	}
+	penalty := ts.store.penalties[0]
+	if penalty.SellerID != late.SellerID || penalty.Reason != model.PenaltyLateShipment || !penalty.Amount.Equal(usd(2500)) {
+		t.Errorf("penalty = %+v, want 25.00 on seller %d", penalty, late.SellerID)
+	}
+
+	// The order still inside its window got a deadline and stays open
+	if status := ts.status(t, onTime.ID); status != model.StatusProcessing {
+		t.Errorf("on-time order = %s, want %s", status, model.StatusProcessing)
+	}
+	want := onTime.PaymentAt.Time.Add(48 * time.Hour)
+	if shipBy := ts.store.orders[onTime.ID].ShipBy; !shipBy.Valid || !shipBy.Time.Equal(want) {
+		t.Errorf("ship by = %v, want %v", shipBy, want)
+	}
+	if status := ts.status(t, shipped.ID); status != model.StatusShipped {
+		t.Errorf("shipped order = %s, want %s", status, model.StatusShipped)
+	}
+
+	// The next sweep has nothing left to do
+	if canceled, err := ts.EnforceShippingSLA(ctx, slaPolicy); err != nil || canceled != 0 {
+		t.Errorf("second sweep = %d, %v; want 0", canceled, err)
+	}
+	if len(ts.payments.refunds) != 1 || len(ts.store.penalties) != 1 {
+		t.Error("second sweep refunded or penalized again")
+	}
+}
+
+func TestEnforceShippingSLARefundFailureKeepsOrder(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestService()
+	late := ts.addPaidOrder(11, model.StatusPaid, 72*time.Hour)
+	ts.payments.failWith = "provider unavailable"
+
+	canceled, err := ts.EnforceShippingSLA(ctx, slaPolicy)
+	if err != nil {
+		t.Fatalf("EnforceShippingSLA: %v", err)
+	}
+	if canceled != 0 {
+		t.Errorf("canceled = %d, want 0", canceled)
+	}
+	if status := ts.status(t, late.ID); status != model.StatusPaid {
+		t.Errorf("status = %s, want %s", status, model.StatusPaid)
+	}
+	if len(ts.store.penalties) != 0 || len(ts.products.released) != 0 {
+		t.Error("order penalized or released without a refund")
+	}
+
+	// The next sweep retries it
+	ts.payments.failWith = ""
+	if canceled, err := ts.EnforceShippingSLA(ctx, slaPolicy); err != nil || canceled != 1 {
+		t.Errorf("retry sweep = %d, %v; want 1", canceled, err)
+	}
+	if status := ts.status(t, late.ID); status != model.StatusRefunded {
+		t.Errorf("status after retry = %s, want %s", status, model.StatusRefunded)
+	}
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/order/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// memoryStore is an in-memory OrderStore for service tests. Writes apply
+// straight away; transactions only record whether they were committed.
+// Methods no test needs panic through the nil embedded store.
+type memoryStore struct {
+	repository.OrderStore
+
+	orders      map[int64]*model.Order
+	roles       map[int64]string
+	adjustments []*model.OrderAdjustment
+	disputes    map[int64]*model.Dispute
+	events      []*model.DisputeEvent
+	returns     []*model.OrderReturn
+	penalties   []*model.SellerPenalty
+	cart        []*model.CartItem
+	checkouts   map[int64]*model.Checkout
+	jobs        []*model.HookJob
+	commits     int
+	seq         int64
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		orders:    make(map[int64]*model.Order),
+		roles:     make(map[int64]string),
+		disputes:  make(map[int64]*model.Dispute),
+		checkouts: make(map[int64]*model.Checkout),
+	}
+}
+
+func (m *memoryStore) nextID() int64 {
+	m.seq++
+	return m.seq
+}
+
+// memoryTx counts its commit on the store
+type memoryTx struct {
+	pgx.Tx
+	store *memoryStore
+}
+
+func (tx *memoryTx) Commit(ctx context.Context) error {
+	tx.store.commits++
+	return nil
+}
+
+func (tx *memoryTx) Rollback(ctx context.Context) error {
+	return nil
+}
+
+func (m *memoryStore) BeginTx(ctx context.Context) (pgx.Tx, error) {
+	return &memoryTx{store: m}, nil
+}
+
+// addOrder stores order under a new ID
+func (m *memoryStore) addOrder(order *model.Order) *model.Order {
+	order.ID = m.nextID()
+	order.OrderNumber = fmt.Sprintf("ORD-%d", order.ID)
+	m.orders[order.ID] = order
+	return order
+}
+
+func (m *memoryStore) GetOrderByID(ctx context.Context, orderID int64) (*model.Order, error) {
+	order, ok := m.orders[orderID]
+	if !ok {
+		return nil, fmt.Errorf("order not found")
+	}
+	copied := *order
+	return &copied, nil
+}
+
+func (m *memoryStore) TransitionStatus(ctx context.Context, tx pgx.Tx, entry *model.OrderStatusHistory) error {
+	order := m.orders[entry.OrderID]
+	if order.Status != entry.FromStatus.String {
+		return fmt.Errorf("order %s is no longer %s", order.OrderNumber, entry.FromStatus.String)
+	}
+	order.Status = entry.ToStatus
+	return nil
+}
+
+func (m *memoryStore) GetUserRole(ctx context.Context, userID int64) (string, error) {
+	return m.roles[userID], nil
+}
+
+func (m *memoryStore) CreateReturn(ctx context.Context, ret *model.OrderReturn) error {
+	ret.ID = m.nextID()
+	m.returns = append(m.returns, ret)
+	return nil
+}
+
+func (m *memoryStore) CreateAdjustment(ctx context.Context, adj *model.OrderAdjustment) (bool, error) {
+	for _, existing := range m.adjustments {
+		if existing.Reference == adj.Reference {
+			return false, nil
+		}
+	}
+	adj.ID = m.nextID()
+	m.adjustments = append(m.adjustments, adj)
+	return true, nil
+}
+
+func (m *memoryStore) GetAdjustments(ctx context.Context, orderID int64) ([]*model.OrderAdjustment, error) {
+	adjustments := make([]*model.OrderAdjustment, 0)
+	for _, adj := range m.adjustments {
+		if adj.OrderID == orderID {
+			adjustments = append(adjustments, adj)
+		}
+	}
+	return adjustments, nil
+}
+
+// addDispute opens a dispute on order
+func (m *memoryStore) addDispute(order *model.Order, payoutHeld bool) *model.Dispute {
+	dispute := &model.Dispute{
+		ID:         m.nextID(),
+		OrderID:    order.ID,
+		BuyerID:    order.BuyerID,
+		SellerID:   order.SellerID,
+		Reason:     model.DisputeReasonNotAsDescribed,
+		Status:     model.DisputeStatusSellerResponded,
+		PayoutHeld: payoutHeld,
+	}
+	m.disputes[dispute.ID] = dispute
+	return dispute
+}
+
+func (m *memoryStore) GetDispute(ctx context.Context, disputeID int64) (*model.Dispute, error) {
+	dispute, ok := m.disputes[disputeID]
+	if !ok {
+		return nil, fmt.Errorf("dispute not found")
+	}
+	copied := *dispute
+	return &copied, nil
+}
+
+func (m *memoryStore) GetOpenDisputeByOrderID(ctx context.Context, orderID int64) (*model.Dispute, error) {
+	for _, dispute := range m.disputes {
+		if dispute.OrderID == orderID && dispute.IsOpen() {
+			return m.GetDispute(ctx, dispute.ID)
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) ResolveDispute(ctx context.Context, tx pgx.Tx, dispute *model.Dispute) error {
+	stored := m.disputes[dispute.ID]
+	if !stored.IsOpen() {
+		return fmt.Errorf("dispute not found or already resolved")
+	}
+
+	dispute.Status = model.DisputeStatusResolved
+	copied := *dispute
+	m.disputes[dispute.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) MarkDisputePayoutHeld(ctx context.Context, disputeID int64) error {
+	m.disputes[disputeID].PayoutHeld = true
+	return nil
+}
+
+func (m *memoryStore) GetDisputeRefundTotal(ctx context.Context, orderID int64) (money.Money, error) {
+	total := money.Zero(m.orders[orderID].TotalAmount.Currency)
+	for _, dispute := range m.disputes {
+		if dispute.OrderID == orderID && !dispute.IsOpen() &&
+			dispute.Outcome.String == model.DisputeOutcomePartialRefund && dispute.RefundAmount != nil {
+			total = total.Add(*dispute.RefundAmount)
+		}
+	}
+	return total, nil
+}
+
+func (m *memoryStore) AddDisputeEvent(ctx context.Context, tx pgx.Tx, event *model.DisputeEvent, details map[string]interface{}) error {
+	event.ID = m.nextID()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memoryStore) AssignShipDeadlines(ctx context.Context, defaultHours int) (int64, error) {
+	var assigned int64
+	for _, order := range m.orders {
+		if shipPending(order) && order.PaymentAt.Valid && !order.ShipBy.Valid {
+			order.ShipBy.Time = order.PaymentAt.Time.Add(time.Duration(defaultHours) * time.Hour)
+			order.ShipBy.Valid = true
+			assigned++
+		}
+	}
+	return assigned, nil
+}
+
+func (m *memoryStore) ListOverdueShipments(ctx context.Context, now time.Time, limit int) ([]*model.PendingShipment, error) {
+	overdue := make([]*model.PendingShipment, 0)
+	for _, order := range m.orders {
+		if shipPending(order) && order.ShipBy.Valid && !order.ShipBy.Time.After(now) {
+			copied := *order
+			overdue = append(overdue, &model.PendingShipment{Order: &copied, ShipBy: order.ShipBy.Time})
+		}
+	}
+	sort.Slice(overdue, func(i, j int) bool { return overdue[i].ShipBy.Before(overdue[j].ShipBy) })
+
+	if len(overdue) > limit {
+		overdue = overdue[:limit]
+	}
+	return overdue, nil
+}
+
+// shipPending reports whether the seller still has to ship order
+func shipPending(order *model.Order) bool {
+	return order.Status == model.StatusPaid || order.Status == model.StatusProcessing
+}
+
+func (m *memoryStore) CreateSellerPenalty(ctx context.Context, penalty *model.SellerPenalty) (bool, error) {
+	for _, existing := range m.penalties {
+		if existing.OrderID == penalty.OrderID && existing.Reason == penalty.Reason {
+			return false, nil
+		}
+	}
+	penalty.ID = m.nextID()
+	m.penalties = append(m.penalties, penalty)
+	return true, nil
+}
+
+// addCartItem puts quantity pairs of a product size in the buyer's cart
+func (m *memoryStore) addCartItem(buyerID, productID, sizeID int64, quantity int32, price money.Money) *model.CartItem {
+	item := &model.CartItem{
+		ID:         m.nextID(),
+		BuyerID:    buyerID,
+		ProductID:  productID,
+		SizeID:     sizeID,
+		Quantity:   quantity,
+		PriceAtAdd: price,
+	}
+	m.cart = append(m.cart, item)
+	return item
+}
+
+func (m *memoryStore) GetCartItems(ctx context.Context, buyerID int64) ([]*model.CartItem, error) {
+	items := make([]*model.CartItem, 0)
+	for _, item := range m.cart {
+		if item.BuyerID == buyerID {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (m *memoryStore) CreateCheckout(ctx context.Context, checkout *model.Checkout, orders []*model.Order) error {
+	checkout.ID = m.nextID()
+	m.checkouts[checkout.ID] = checkout
+
+	for _, order := range orders {
+		order.CheckoutID.Int64, order.CheckoutID.Valid = checkout.ID, true
+		copied := *m.addOrder(order)
+		m.orders[order.ID] = &copied
+	}
+	return nil
+}
+
+func (m *memoryStore) CompleteCheckout(ctx context.Context, checkoutID int64, paymentIntentID string, cartItemIDs []int64) error {
+	m.checkouts[checkoutID].StripePaymentIntentID.String = paymentIntentID
+	m.checkouts[checkoutID].StripePaymentIntentID.Valid = paymentIntentID != ""
+
+	bought := make(map[int64]bool, len(cartItemIDs))
+	for _, id := range cartItemIDs {
+		bought[id] = true
+	}
+	remaining := make([]*model.CartItem, 0)
+	for _, item := range m.cart {
+		if !bought[item.ID] {
+			remaining = append(remaining, item)
+		}
+	}
+	m.cart = remaining
+	return nil
+}
+
+func (m *memoryStore) EnqueueHookJobs(ctx context.Context, tx pgx.Tx, jobs []*model.HookJob) error {
+	for _, job := range jobs {
+		job.ID = m.nextID()
+		job.Status = model.HookJobStatusPending
+		m.jobs = append(m.jobs, job)
+	}
+	return nil
+}
+
+func (m *memoryStore) job(jobID int64) *model.HookJob {
+	for _, job := range m.jobs {
+		if job.ID == jobID {
+			return job
+		}
+	}
+	panic(fmt.Sprintf("no hook job %d", jobID))
+}
+
+func (m *memoryStore) CompleteHookJob(ctx context.Context, jobID int64) error {
+	m.job(jobID).Status = model.HookJobStatusDone
+	return nil
+}
+
+func (m *memoryStore) RetryHookJob(ctx context.Context, jobID int64, runAfter time.Time, reason string) error {
+	job := m.job(jobID)
+	job.Attempts++
+	job.RunAfter = runAfter
+	job.LastError.String, job.LastError.Valid = reason, true
+	return nil
+}
+
+func (m *memoryStore) FailHookJob(ctx context.Context, jobID int64, reason string) error {
+	job := m.job(jobID)
+	job.Status = model.HookJobStatusFailed
+	job.LastError.String, job.LastError.Valid = reason, true
+	return nil
+}
+
+// pendingJobs returns the hooks that have not succeeded yet
+func (m *memoryStore) pendingJobs() []string {
+	pending := make([]string, 0)
+	for _, job := range m.jobs {
+		if job.Status != model.HookJobStatusDone {
+			pending = append(pending, fmt.Sprintf("%s on %s: %s", job.Hook, job.ToStatus, job.LastError.String))
+		}
+	}
+	return pending
+}
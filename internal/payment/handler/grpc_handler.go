@@ -2,11 +2,13 @@ package handler
 
 import (
 	"context"
+	"errors"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/service"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 	pb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/payment"
 )
 
@@ -32,15 +34,21 @@ func paymentToProto(p *model.Payment) *pb.Payment {
 		PaymentId:      p.PaymentID,
 		OrderId:        p.OrderID,
 		UserId:         p.UserID,
-		Amount:         p.Amount,
+		Amount:         p.Amount.Major(),
 		Currency:       p.Currency,
 		Status:         p.Status,
-		RefundedAmount: p.RefundedAmount,
+		RefundedAmount: p.RefundedAmount.Major(),
 		CreatedAt:      timestamppb.New(p.CreatedAt),
 		UpdatedAt:      timestamppb.New(p.UpdatedAt),
+
+		AmountMinor:         p.Amount.Amount,
+		RefundedAmountMinor: p.RefundedAmount.Amount,
 	}
 
 	// Optional fields
+	if p.CheckoutID.Valid {
+		payment.CheckoutId = p.CheckoutID.Int64
+	}
 	if p.StripePaymentIntentID.Valid {
 		payment.StripePaymentIntentId = p.StripePaymentIntentID.String
 	}
@@ -84,11 +92,14 @@ func payoutToProto(p *model.Payout) *pb.Payout {
 		OrderId:   p.OrderID,
 		SellerId:  p.SellerID,
 		PaymentId: p.PaymentID,
-		Amount:    p.Amount,
+		WalletId:  p.WalletID,
+		Amount:    p.Amount.Major(),
 		Currency:  p.Currency,
 		Status:    p.Status,
 		CreatedAt: timestamppb.New(p.CreatedAt),
 		UpdatedAt: timestamppb.New(p.UpdatedAt),
+
+		AmountMinor: p.Amount.Amount,
 	}
 
 	// Optional fields
@@ -108,29 +119,178 @@ func payoutToProto(p *model.Payout) *pb.Payout {
 	return payout
 }
 
+// Helper: convert model.Wallet to pb.SellerWallet
+func walletToProto(w *model.Wallet) *pb.SellerWallet {
+	if w == nil {
+		return nil
+	}
+
+	wallet := &pb.SellerWallet{
+		Id:                 w.ID,
+		SellerId:           w.SellerID,
+		BalanceMinor:       w.Balance.Amount,
+		Currency:           w.Currency,
+		PayoutSchedule:     w.PayoutSchedule,
+		MinimumPayoutMinor: w.MinimumPayout.Amount,
+		FrozenMinor:        w.FrozenBalance.Amount,
+	}
+
+	// Optional fields
+	if w.StripeAccountID.Valid {
+		wallet.StripeAccountId = w.StripeAccountID.String
+	}
+	if w.NextPayoutAt.Valid {
+		wallet.NextPayoutAt = timestamppb.New(w.NextPayoutAt.Time)
+	}
+	if w.LastPayoutAt.Valid {
+		wallet.LastPayoutAt = timestamppb.New(w.LastPayoutAt.Time)
+	}
+
+	return wallet
+}
+
+// Helper: convert model.WalletTransaction to pb.WalletTransaction
+func walletTransactionToProto(t *model.WalletTransaction) *pb.WalletTransaction {
+	return &pb.WalletTransaction{
+		Id:                t.ID,
+		Type:              t.Type,
+		AmountMinor:       t.Amount.Amount,
+		BalanceAfterMinor: t.BalanceAfter.Amount,
+		Currency:          t.Amount.Currency,
+		OrderId:           t.OrderID.Int64,
+		PayoutId:          t.PayoutID.Int64,
+		CreatedAt:         timestamppb.New(t.CreatedAt),
+		Frozen:            t.FrozenAt.Valid,
+	}
+}
+
+// Helper: convert model.Authorization to pb.Authorization
+func authorizationToProto(a *model.Authorization) *pb.Authorization {
+	if a == nil {
+		return nil
+	}
+
+	auth := &pb.Authorization{
+		Id:             a.ID,
+		BidId:          a.BidID,
+		UserId:         a.UserID,
+		Amount:         a.Amount.Major(),
+		CapturedAmount: a.CapturedAmount.Major(),
+		Currency:       a.Currency,
+		Status:         a.Status,
+		CreatedAt:      timestamppb.New(a.CreatedAt),
+		UpdatedAt:      timestamppb.New(a.UpdatedAt),
+
+		AmountMinor:         a.Amount.Amount,
+		CapturedAmountMinor: a.CapturedAmount.Amount,
+	}
+
+	// Optional fields
+	if a.MatchID.Valid {
+		auth.MatchId = a.MatchID.Int64
+	}
+	if a.StripePaymentIntentID.Valid {
+		auth.StripePaymentIntentId = a.StripePaymentIntentID.String
+	}
+	if a.StripeCustomerID.Valid {
+		auth.StripeCustomerId = a.StripeCustomerID.String
+	}
+	if a.StripePaymentMethodID.Valid {
+		auth.StripePaymentMethodId = a.StripePaymentMethodID.String
+	}
+	if a.FailureReason.Valid {
+		auth.FailureReason = a.FailureReason.String
+	}
+	if a.AuthorizedAt.Valid {
+		auth.AuthorizedAt = timestamppb.New(a.AuthorizedAt.Time)
+	}
+	if a.CapturedAt.Valid {
+		auth.CapturedAt = timestamppb.New(a.CapturedAt.Time)
+	}
+	if a.ReleasedAt.Valid {
+		auth.ReleasedAt = timestamppb.New(a.ReleasedAt.Time)
+	}
+
+	return auth
+}
+
 // CreatePaymentIntent creates a Stripe PaymentIntent
 func (h *PaymentHandler) CreatePaymentIntent(ctx context.Context, req *pb.CreatePaymentIntentRequest) (*pb.CreatePaymentIntentResponse, error) {
+	replay := &pb.CreatePaymentIntentResponse{}
+	replayed, err := h.beginIdempotent(ctx, model.IdempotencyScopePaymentIntent, req.IdempotencyKey, req, replay)
+	if err != nil {
+		return &pb.CreatePaymentIntentResponse{Error: err.Error()}, nil
+	}
+	if replayed {
+		return replay, nil
+	}
+
+	resp := h.createPaymentIntent(ctx, req)
+	h.finishIdempotent(ctx, model.IdempotencyScopePaymentIntent, req.IdempotencyKey, resp)
+
+	return resp, nil
+}
+
+// createPaymentIntent creates the intent for one order
+func (h *PaymentHandler) createPaymentIntent(ctx context.Context, req *pb.CreatePaymentIntentRequest) *pb.CreatePaymentIntentResponse {
 	if req.OrderId == 0 {
-		return &pb.CreatePaymentIntentResponse{Error: "order_id is required"}, nil
+		return &pb.CreatePaymentIntentResponse{Error: "order_id is required"}
 	}
 	if req.UserId == 0 {
-		return &pb.CreatePaymentIntentResponse{Error: "user_id is required"}, nil
+		return &pb.CreatePaymentIntentResponse{Error: "user_id is required"}
 	}
-	if req.Amount <= 0 {
-		return &pb.CreatePaymentIntentResponse{Error: "amount must be greater than 0"}, nil
+	amount := money.FromProto(req.AmountMinor, req.Amount, req.Currency)
+	if !amount.IsPositive() {
+		return &pb.CreatePaymentIntentResponse{Error: "amount must be greater than 0"}
 	}
 
 	payment, clientSecret, err := h.service.CreatePaymentIntent(
 		ctx, req.OrderId, req.UserId,
-		req.Amount, req.Currency, req.StripeCustomerId,
+		amount, req.StripeCustomerId, req.IdempotencyKey,
 	)
 	if err != nil {
-		return &pb.CreatePaymentIntentResponse{Error: err.Error()}, nil
+		return &pb.CreatePaymentIntentResponse{Error: err.Error()}
 	}
 
 	return &pb.CreatePaymentIntentResponse{
 		Payment:      paymentToProto(payment),
 		ClientSecret: clientSecret,
+	}
+}
+
+// CreateCheckoutPaymentIntent creates one PaymentIntent covering several orders
+func (h *PaymentHandler) CreateCheckoutPaymentIntent(ctx context.Context, req *pb.CreateCheckoutPaymentIntentRequest) (*pb.CreateCheckoutPaymentIntentResponse, error) {
+	if req.CheckoutId == 0 {
+		return &pb.CreateCheckoutPaymentIntentResponse{Error: "checkout_id is required"}, nil
+	}
+	if req.UserId == 0 {
+		return &pb.CreateCheckoutPaymentIntentResponse{Error: "user_id is required"}, nil
+	}
+
+	allocations := make([]model.Allocation, len(req.Allocations))
+	for i, a := range req.Allocations {
+		allocations[i] = model.Allocation{
+			OrderID: a.OrderId,
+			Amount:  money.FromProto(a.AmountMinor, a.Amount, req.Currency),
+		}
+	}
+
+	payments, clientSecret, err := h.service.CreateCheckoutPaymentIntent(
+		ctx, req.CheckoutId, req.UserId,
+		allocations, req.StripeCustomerId,
+	)
+	if err != nil {
+		return &pb.CreateCheckoutPaymentIntentResponse{Error: err.Error()}, nil
+	}
+
+	pbPayments := make([]*pb.Payment, len(payments))
+	for i, p := range payments {
+		pbPayments[i] = paymentToProto(p)
+	}
+
+	return &pb.CreateCheckoutPaymentIntentResponse{
+		Payments:     pbPayments,
+		ClientSecret: clientSecret,
 	}, nil
 }
 
@@ -198,19 +358,48 @@ func (h *PaymentHandler) ListPayments(ctx context.Context, req *pb.ListPaymentsR
 
 // CreateRefund creates a refund
 func (h *PaymentHandler) CreateRefund(ctx context.Context, req *pb.CreateRefundRequest) (*pb.CreateRefundResponse, error) {
-	if req.PaymentId == 0 {
-		return &pb.CreateRefundResponse{Error: "payment_id is required"}, nil
+	replay := &pb.CreateRefundResponse{}
+	replayed, err := h.beginIdempotent(ctx, model.IdempotencyScopeRefund, req.IdempotencyKey, req, replay)
+	if err != nil {
+		return &pb.CreateRefundResponse{Error: err.Error()}, nil
+	}
+	if replayed {
+		return replay, nil
+	}
+
+	resp := h.createRefund(ctx, req)
+	h.finishIdempotent(ctx, model.IdempotencyScopeRefund, req.IdempotencyKey, resp)
+
+	return resp, nil
+}
+
+// createRefund issues the refund
+func (h *PaymentHandler) createRefund(ctx context.Context, req *pb.CreateRefundRequest) *pb.CreateRefundResponse {
+	if req.PaymentId == 0 && req.OrderId == 0 {
+		return &pb.CreateRefundResponse{Error: "payment_id or order_id is required"}
 	}
 	if req.Reason == "" {
-		return &pb.CreateRefundResponse{Error: "reason is required"}, nil
+		return &pb.CreateRefundResponse{Error: "reason is required"}
+	}
+
+	paymentID := req.PaymentId
+	if paymentID == 0 {
+		payment, err := h.service.GetPaymentByOrderID(ctx, req.OrderId)
+		if err != nil {
+			return &pb.CreateRefundResponse{Error: err.Error()}
+		}
+		if payment == nil {
+			return &pb.CreateRefundResponse{Error: "no payment found for order"}
+		}
+		paymentID = payment.ID
 	}
 
-	stripeRefundID, err := h.service.CreateRefund(ctx, req.PaymentId, req.Amount, req.Reason)
+	stripeRefundID, err := h.service.CreateRefund(ctx, paymentID, money.FromProto(req.AmountMinor, req.Amount, req.Currency), req.Reason, req.IdempotencyKey)
 	if err != nil {
-		return &pb.CreateRefundResponse{Error: err.Error()}, nil
+		return &pb.CreateRefundResponse{Error: err.Error()}
 	}
 
-	payment, err := h.service.GetPayment(ctx, req.PaymentId)
+	payment, err := h.service.GetPayment(ctx, paymentID)
 	if err != nil {
 		// Log error but don't fail the refund response
 		payment = nil
@@ -219,7 +408,7 @@ func (h *PaymentHandler) CreateRefund(ctx context.Context, req *pb.CreateRefundR
 	return &pb.CreateRefundResponse{
 		Payment:        paymentToProto(payment),
 		StripeRefundId: stripeRefundID,
-	}, nil
+	}
 }
 
 // GetRefundStatus retrieves refund status
@@ -234,7 +423,8 @@ func (h *PaymentHandler) GetRefundStatus(ctx context.Context, req *pb.GetRefundS
 	}
 
 	response := &pb.GetRefundStatusResponse{
-		RefundedAmount: payment.RefundedAmount,
+		RefundedAmount:      payment.RefundedAmount.Major(),
+		RefundedAmountMinor: payment.RefundedAmount.Amount,
 	}
 	if payment.RefundReason.Valid {
 		response.RefundReason = payment.RefundReason.String
@@ -248,49 +438,66 @@ func (h *PaymentHandler) GetRefundStatus(ctx context.Context, req *pb.GetRefundS
 
 // HandleStripeWebhook handles Stripe webhook
 func (h *PaymentHandler) HandleStripeWebhook(ctx context.Context, req *pb.HandleStripeWebhookRequest) (*pb.HandleStripeWebhookResponse, error) {
-	eventType, err := h.service.HandleStripeWebhook(ctx, req.Payload, req.Signature)
+	result, err := h.service.HandleStripeWebhook(ctx, req.Payload, req.Signature)
 	if err != nil {
-		return &pb.HandleStripeWebhookResponse{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
+		response := &pb.HandleStripeWebhookResponse{
+			Success:  false,
+			Error:    err.Error(),
+			Rejected: errors.Is(err, service.ErrInvalidWebhook),
+		}
+		if result != nil {
+			response.EventId = result.EventID
+			response.EventType = result.EventType
+		}
+		return response, nil
 	}
 
 	return &pb.HandleStripeWebhookResponse{
 		Success:   true,
-		EventType: eventType,
+		EventType: result.EventType,
+		EventId:   result.EventID,
+		Duplicate: result.Duplicate,
 	}, nil
 }
 
 // CreatePayout creates a payout
 func (h *PaymentHandler) CreatePayout(ctx context.Context, req *pb.CreatePayoutRequest) (*pb.CreatePayoutResponse, error) {
-	if req.OrderId == 0 {
-		return &pb.CreatePayoutResponse{Error: "order_id is required"}, nil
+	replay := &pb.CreatePayoutResponse{}
+	replayed, err := h.beginIdempotent(ctx, model.IdempotencyScopePayout, req.IdempotencyKey, req, replay)
+	if err != nil {
+		return &pb.CreatePayoutResponse{Error: err.Error()}, nil
 	}
-	if req.SellerId == 0 {
-		return &pb.CreatePayoutResponse{Error: "seller_id is required"}, nil
+	if replayed {
+		return replay, nil
 	}
-	if req.PaymentId == 0 {
-		return &pb.CreatePayoutResponse{Error: "payment_id is required"}, nil
+
+	resp := h.createPayout(ctx, req)
+	h.finishIdempotent(ctx, model.IdempotencyScopePayout, req.IdempotencyKey, resp)
+
+	return resp, nil
+}
+
+// createPayout pays the seller out for one order through their wallet
+func (h *PaymentHandler) createPayout(ctx context.Context, req *pb.CreatePayoutRequest) *pb.CreatePayoutResponse {
+	if req.OrderId == 0 {
+		return &pb.CreatePayoutResponse{Error: "order_id is required"}
 	}
-	if req.Amount <= 0 {
-		return &pb.CreatePayoutResponse{Error: "amount must be greater than 0"}, nil
+	if req.SellerId == 0 {
+		return &pb.CreatePayoutResponse{Error: "seller_id is required"}
 	}
-	if req.StripeAccountId == "" {
-		return &pb.CreatePayoutResponse{Error: "stripe_account_id is required"}, nil
+	amount := money.FromProto(req.AmountMinor, req.Amount, req.Currency)
+	if !amount.IsPositive() {
+		return &pb.CreatePayoutResponse{Error: "amount must be greater than 0"}
 	}
 
-	payout, err := h.service.CreatePayout(
-		ctx, req.OrderId, req.SellerId, req.PaymentId,
-		req.Amount, req.StripeAccountId,
-	)
+	payout, err := h.service.CreatePayout(ctx, req.OrderId, req.SellerId, amount, req.StripeAccountId)
 	if err != nil {
-		return &pb.CreatePayoutResponse{Error: err.Error()}, nil
+		return &pb.CreatePayoutResponse{Error: err.Error()}
 	}
 
 	return &pb.CreatePayoutResponse{
 		Payout: payoutToProto(payout),
-	}, nil
+	}
 }
 
 // GetPayout retrieves a payout
@@ -335,3 +542,142 @@ func (h *PaymentHandler) ListPayouts(ctx context.Context, req *pb.ListPayoutsReq
 		PageSize: pageSize,
 	}, nil
 }
+
+// CreditSellerWallet adds a completed order's proceeds to the seller's wallet
+func (h *PaymentHandler) CreditSellerWallet(ctx context.Context, req *pb.CreditSellerWalletRequest) (*pb.CreditSellerWalletResponse, error) {
+	if req.OrderId == 0 {
+		return &pb.CreditSellerWalletResponse{Error: "order_id is required"}, nil
+	}
+	if req.SellerId == 0 {
+		return &pb.CreditSellerWalletResponse{Error: "seller_id is required"}, nil
+	}
+	amount := money.FromProto(req.AmountMinor, 0, req.Currency)
+	if !amount.IsPositive() {
+		return &pb.CreditSellerWalletResponse{Error: "amount must be greater than 0"}, nil
+	}
+
+	wallet, err := h.service.CreditSellerWallet(ctx, req.OrderId, req.SellerId, amount)
+	if err != nil {
+		return &pb.CreditSellerWalletResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.CreditSellerWalletResponse{Wallet: walletToProto(wallet)}, nil
+}
+
+// GetSellerWallet returns a seller's wallet and its latest transactions
+func (h *PaymentHandler) GetSellerWallet(ctx context.Context, req *pb.GetSellerWalletRequest) (*pb.GetSellerWalletResponse, error) {
+	if req.SellerId == 0 {
+		return &pb.GetSellerWalletResponse{Error: "seller_id is required"}, nil
+	}
+
+	wallet, transactions, err := h.service.GetSellerWallet(ctx, req.SellerId, req.Currency)
+	if err != nil {
+		return &pb.GetSellerWalletResponse{Error: err.Error()}, nil
+	}
+
+	pbTransactions := make([]*pb.WalletTransaction, len(transactions))
+	for i, t := range transactions {
+		pbTransactions[i] = walletTransactionToProto(t)
+	}
+
+	return &pb.GetSellerWalletResponse{
+		Wallet:       walletToProto(wallet),
+		Transactions: pbTransactions,
+	}, nil
+}
+
+// UpdatePayoutSettings changes a seller's payout schedule, minimum and account
+func (h *PaymentHandler) UpdatePayoutSettings(ctx context.Context, req *pb.UpdatePayoutSettingsRequest) (*pb.UpdatePayoutSettingsResponse, error) {
+	if req.SellerId == 0 {
+		return &pb.UpdatePayoutSettingsResponse{Error: "seller_id is required"}, nil
+	}
+
+	var minimum *money.Money
+	if req.MinimumPayoutMinor != nil {
+		m := money.FromProto(*req.MinimumPayoutMinor, 0, req.Currency)
+		minimum = &m
+	}
+
+	wallet, err := h.service.UpdatePayoutSettings(
+		ctx, req.SellerId, req.Currency, req.PayoutSchedule,
+		minimum, req.StripeAccountId,
+	)
+	if err != nil {
+		return &pb.UpdatePayoutSettingsResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.UpdatePayoutSettingsResponse{Wallet: walletToProto(wallet)}, nil
+}
+
+// RequestPayout pays out a seller's wallet now
+func (h *PaymentHandler) RequestPayout(ctx context.Context, req *pb.RequestPayoutRequest) (*pb.RequestPayoutResponse, error) {
+	if req.SellerId == 0 {
+		return &pb.RequestPayoutResponse{Error: "seller_id is required"}, nil
+	}
+
+	payout, err := h.service.RequestPayout(ctx, req.SellerId, req.Currency)
+	if err != nil {
+		return &pb.RequestPayoutResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.RequestPayoutResponse{Payout: payoutToProto(payout)}, nil
+}
+
+// AuthorizeBid places a manual-capture hold for a bid
+func (h *PaymentHandler) AuthorizeBid(ctx context.Context, req *pb.AuthorizeBidRequest) (*pb.AuthorizeBidResponse, error) {
+	if req.BidId == 0 {
+		return &pb.AuthorizeBidResponse{Error: "bid_id is required"}, nil
+	}
+	if req.UserId == 0 {
+		return &pb.AuthorizeBidResponse{Error: "user_id is required"}, nil
+	}
+	amount := money.FromProto(req.AmountMinor, req.Amount, req.Currency)
+	if !amount.IsPositive() {
+		return &pb.AuthorizeBidResponse{Error: "amount must be greater than 0"}, nil
+	}
+
+	auth, err := h.service.AuthorizeBid(
+		ctx, req.BidId, req.UserId,
+		amount,
+		req.StripeCustomerId, req.PaymentMethodId,
+	)
+	if err != nil {
+		return &pb.AuthorizeBidResponse{
+			Authorization: authorizationToProto(auth),
+			Error:         err.Error(),
+		}, nil
+	}
+
+	return &pb.AuthorizeBidResponse{Authorization: authorizationToProto(auth)}, nil
+}
+
+// CaptureBidAuthorization captures a bid's hold after a match
+func (h *PaymentHandler) CaptureBidAuthorization(ctx context.Context, req *pb.CaptureBidAuthorizationRequest) (*pb.CaptureBidAuthorizationResponse, error) {
+	if req.BidId == 0 {
+		return &pb.CaptureBidAuthorizationResponse{Error: "bid_id is required"}, nil
+	}
+	if req.MatchId == 0 {
+		return &pb.CaptureBidAuthorizationResponse{Error: "match_id is required"}, nil
+	}
+
+	auth, err := h.service.CaptureBidAuthorization(ctx, req.BidId, req.MatchId, money.FromProto(req.AmountMinor, req.Amount, req.Currency))
+	if err != nil {
+		return &pb.CaptureBidAuthorizationResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.CaptureBidAuthorizationResponse{Authorization: authorizationToProto(auth)}, nil
+}
+
+// ReleaseBidAuthorization releases a bid's hold
+func (h *PaymentHandler) ReleaseBidAuthorization(ctx context.Context, req *pb.ReleaseBidAuthorizationRequest) (*pb.ReleaseBidAuthorizationResponse, error) {
+	if req.BidId == 0 {
+		return &pb.ReleaseBidAuthorizationResponse{Error: "bid_id is required"}, nil
+	}
+
+	auth, err := h.service.ReleaseBidAuthorization(ctx, req.BidId, req.Reason)
+	if err != nil {
+		return &pb.ReleaseBidAuthorizationResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.ReleaseBidAuthorizationResponse{Authorization: authorizationToProto(auth)}, nil
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// idempotentResponse is the response of a payment-mutating RPC
+type idempotentResponse interface {
+	proto.Message
+	GetError() string
+}
+
+// beginIdempotent claims the idempotency key for req. It reports true when
+// an identical request already completed with the key; its response is
+// then decoded into replay. Requests without a key always run.
+func (h *PaymentHandler) beginIdempotent(ctx context.Context, scope, key string, req, replay proto.Message) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+
+	// Deterministic so that equal requests always hash the same
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash request: %w", err)
+	}
+	sum := sha256.Sum256(body)
+
+	stored, err := h.service.BeginIdempotentRequest(ctx, scope, key, hex.EncodeToString(sum[:]))
+	if err != nil || stored == nil {
+		return false, err
+	}
+
+	if err := proto.Unmarshal(stored, replay); err != nil {
+		return false, fmt.Errorf("failed to decode stored response: %w", err)
+	}
+
+	return true, nil
+}
+
+// finishIdempotent stores resp for retries with the key. Failed responses
+// are not stored, so the request can be retried with the same key.
+func (h *PaymentHandler) finishIdempotent(ctx context.Context, scope, key string, resp idempotentResponse) {
+	if key == "" {
+		return
+	}
+
+	var stored []byte
+	if resp.GetError() == "" {
+		var err error
+		if stored, err = proto.Marshal(resp); err != nil {
+			log.Printf("Failed to encode %s response for idempotency key %s: %v", scope, key, err)
+		}
+	}
+
+	if err := h.service.FinishIdempotentRequest(ctx, scope, key, stored); err != nil {
+		log.Printf("Failed to finish %s idempotency key %s: %v", scope, key, err)
+	}
+}
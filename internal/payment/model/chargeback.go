@@ -0,0 +1,55 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Chargeback is a card dispute the buyer's bank opened on a charge, for the
+// share of one order payment. The order's sale is frozen in the seller's
+// wallet until it closes; a lost chargeback is clawed back from the seller.
+type Chargeback struct {
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	ClosedAt        sql.NullTime   `json:"closed_at"`
+	StripeDisputeID string         `json:"stripe_dispute_id"`
+	StripeChargeID  string         `json:"stripe_charge_id"`
+	Currency        string         `json:"currency"`
+	Status          string         `json:"status"`
+	Reason          sql.NullString `json:"reason"`
+	Amount          money.Money    `json:"amount"`
+	ID              int64          `json:"id"`
+	PaymentID       int64          `json:"payment_id"`
+	OrderID         int64          `json:"order_id"`
+}
+
+// Chargeback status constants
+const (
+	ChargebackStatusOpen = "open"
+	ChargebackStatusWon  = "won"
+	ChargebackStatusLost = "lost"
+)
+
+// PayoutHold reports whether an order's proceeds can be paid out
+type PayoutHold struct {
+	OrderStatus    string
+	SellerID       int64
+	OpenDispute    bool
+	OpenChargeback bool
+}
+
+// Reason returns why the order's proceeds are held, or "" if they are not
+func (h *PayoutHold) Reason() string {
+	switch {
+	case h.OrderStatus != "completed":
+		return "order is " + h.OrderStatus + "; funds are held until it is completed"
+	case h.OpenDispute:
+		return "funds are frozen while the order's dispute is open"
+	case h.OpenChargeback:
+		return "funds are frozen while a chargeback is open"
+	default:
+		return ""
+	}
+}
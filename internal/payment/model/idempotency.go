@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// IdempotencyKey is a client key sent with a payment-mutating request. The
+// first request with the key runs; retries get its stored response.
+type IdempotencyKey struct {
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Scope       string    `json:"scope"` // the RPC the key was used with
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	Status      string    `json:"status"`
+	Response    []byte    `json:"-"`
+}
+
+// Idempotency key status constants
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyCompleted  = "completed"
+)
+
+// Idempotency key scopes
+const (
+	IdempotencyScopePaymentIntent = "create_payment_intent"
+	IdempotencyScopeRefund        = "create_refund"
+	IdempotencyScopePayout        = "create_payout"
+)
@@ -3,6 +3,8 @@ package model
 import (
 	"database/sql"
 	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // Payment represents a payment transaction
@@ -21,11 +23,18 @@ type Payment struct {
 	CardBrand             sql.NullString `json:"card_brand"`
 	RefundReason          sql.NullString `json:"refund_reason"`
 	StripeChargeID        sql.NullString `json:"stripe_charge_id"`
-	Amount                float64        `json:"amount"`
-	RefundedAmount        float64        `json:"refunded_amount"`
+	Amount                money.Money    `json:"amount"`
+	RefundedAmount        money.Money    `json:"refunded_amount"`
 	ID                    int64          `json:"id"`
 	UserID                int64          `json:"user_id"`
 	OrderID               int64          `json:"order_id"`
+	CheckoutID            sql.NullInt64  `json:"checkout_id"` // set when several orders share one PaymentIntent
+}
+
+// Allocation is one order's share of a combined checkout payment
+type Allocation struct {
+	OrderID int64       `json:"order_id"`
+	Amount  money.Money `json:"amount"`
 }
 
 // Payout represents a seller payout
@@ -39,11 +48,34 @@ type Payout struct {
 	StripeAccountID  sql.NullString `json:"stripe_account_id"`
 	StripeTransferID sql.NullString `json:"stripe_transfer_id"`
 	FailureReason    sql.NullString `json:"failure_reason"`
-	Amount           float64        `json:"amount"`
+	Amount           money.Money    `json:"amount"`
 	ID               int64          `json:"id"`
-	PaymentID        int64          `json:"payment_id"`
+	PaymentID        int64          `json:"payment_id"` // 0 for wallet batches
 	SellerID         int64          `json:"seller_id"`
-	OrderID          int64          `json:"order_id"`
+	OrderID          int64          `json:"order_id"`  // 0 for wallet batches
+	WalletID         int64          `json:"wallet_id"` // 0 for per-order payouts
+}
+
+// Authorization represents a manual-capture hold placed on a buyer's payment
+// method before their bid becomes active
+type Authorization struct {
+	UpdatedAt             time.Time      `json:"updated_at"`
+	CreatedAt             time.Time      `json:"created_at"`
+	AuthorizedAt          sql.NullTime   `json:"authorized_at"`
+	CapturedAt            sql.NullTime   `json:"captured_at"`
+	ReleasedAt            sql.NullTime   `json:"released_at"`
+	Currency              string         `json:"currency"`
+	Status                string         `json:"status"`
+	StripePaymentIntentID sql.NullString `json:"stripe_payment_intent_id"`
+	StripeCustomerID      sql.NullString `json:"stripe_customer_id"`
+	StripePaymentMethodID sql.NullString `json:"stripe_payment_method_id"`
+	FailureReason         sql.NullString `json:"failure_reason"`
+	MatchID               sql.NullInt64  `json:"match_id"`
+	Amount                money.Money    `json:"amount"`
+	CapturedAmount        money.Money    `json:"captured_amount"`
+	ID                    int64          `json:"id"`
+	BidID                 int64          `json:"bid_id"`
+	UserID                int64          `json:"user_id"`
 }
 
 // Payment status constants
@@ -66,6 +98,15 @@ const (
 	PayoutStatusReversed   = "reversed"
 )
 
+// Authorization status constants
+const (
+	AuthorizationStatusPending    = "pending"
+	AuthorizationStatusAuthorized = "authorized"
+	AuthorizationStatusCaptured   = "captured"
+	AuthorizationStatusReleased   = "released"
+	AuthorizationStatusFailed     = "failed"
+)
+
 // IsSuccessful checks if payment was successful
 func (p *Payment) IsSuccessful() bool {
 	return p.Status == StatusSucceeded ||
@@ -75,13 +116,18 @@ func (p *Payment) IsSuccessful() bool {
 // IsRefundable checks if payment can be refunded
 func (p *Payment) IsRefundable() bool {
 	return p.Status == StatusSucceeded &&
-		p.RefundedAmount < p.Amount
+		p.RefundedAmount.LessThan(p.Amount)
 }
 
 // CanBeRefundedAmount checks how much can be refunded
-func (p *Payment) CanBeRefundedAmount() float64 {
+func (p *Payment) CanBeRefundedAmount() money.Money {
 	if !p.IsRefundable() {
-		return 0
+		return money.Zero(p.Amount.Currency)
 	}
-	return p.Amount - p.RefundedAmount
+	return p.Amount.Sub(p.RefundedAmount)
+}
+
+// IsHeld checks if the authorization still holds funds on the card
+func (a *Authorization) IsHeld() bool {
+	return a.Status == AuthorizationStatusAuthorized
 }
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// StripeEvent is a received Stripe webhook event. EventID is Stripe's ID
+// and is unique, so a redelivered event is recognised.
+type StripeEvent struct {
+	UpdatedAt   time.Time      `json:"updated_at"`
+	ReceivedAt  time.Time      `json:"received_at"`
+	ProcessedAt sql.NullTime   `json:"processed_at"`
+	EventID     string         `json:"event_id"`
+	EventType   string         `json:"event_type"`
+	Status      string         `json:"status"`
+	Error       sql.NullString `json:"error"`
+	Payload     []byte         `json:"-"`
+	ID          int64          `json:"id"`
+	Attempts    int32          `json:"attempts"`
+}
+
+// Stripe event status constants
+const (
+	StripeEventProcessing = "processing"
+	StripeEventProcessed  = "processed"
+	StripeEventIgnored    = "ignored" // event type we do not act on
+	StripeEventFailed     = "failed"  // processed again when Stripe redelivers
+)
+
+// Stripe event types handled by the payment service
+const (
+	EventPaymentIntentSucceeded = "payment_intent.succeeded"
+	EventPaymentIntentFailed    = "payment_intent.payment_failed"
+	EventChargeRefunded         = "charge.refunded"
+	EventTransferCreated        = "transfer.created"
+	EventTransferFailed         = "transfer.failed"
+	EventChargeDisputeCreated   = "charge.dispute.created"
+	EventChargeDisputeClosed    = "charge.dispute.closed"
+)
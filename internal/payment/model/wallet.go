@@ -0,0 +1,106 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Wallet holds what the platform owes a seller in one currency. Completed
+// sales credit it; payouts are paid from it in batches on the seller's
+// schedule once the payable balance reaches MinimumPayout. Refunds after a
+// sale are clawed back from it, so the balance can go negative; future sales
+// recover it before anything is paid out.
+type Wallet struct {
+	UpdatedAt       time.Time      `json:"updated_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	NextPayoutAt    sql.NullTime   `json:"next_payout_at"` // unset for on-demand wallets
+	LastPayoutAt    sql.NullTime   `json:"last_payout_at"`
+	Currency        string         `json:"currency"`
+	PayoutSchedule  string         `json:"payout_schedule"`
+	StripeAccountID sql.NullString `json:"stripe_account_id"`
+	Balance         money.Money    `json:"balance"`
+	FrozenBalance   money.Money    `json:"frozen_balance"` // sales under an open chargeback
+	MinimumPayout   money.Money    `json:"minimum_payout"`
+	ID              int64          `json:"id"`
+	SellerID        int64          `json:"seller_id"`
+}
+
+// WalletTransaction is one change to a wallet balance
+type WalletTransaction struct {
+	CreatedAt    time.Time      `json:"created_at"`
+	Type         string         `json:"type"`
+	OrderID      sql.NullInt64  `json:"order_id"`
+	PayoutID     sql.NullInt64  `json:"payout_id"` // for sales: the batch that paid them out; for clawbacks: the payout reversed
+	FrozenAt     sql.NullTime   `json:"frozen_at"` // for sales: frozen by an open chargeback
+	Reference    sql.NullString `json:"reference"` // for clawbacks: the refund or chargeback reclaimed
+	Amount       money.Money    `json:"amount"`    // negative for payouts and clawbacks
+	BalanceAfter money.Money    `json:"balance_after"`
+	ID           int64          `json:"id"`
+	WalletID     int64          `json:"wallet_id"`
+}
+
+// SaleCredit is what a seller was credited, or paid directly, for an order
+type SaleCredit struct {
+	Amount   money.Money
+	SellerID int64
+	PayoutID int64 // payout that paid the order out; 0 while it is in the wallet
+}
+
+// Clawback is what was reclaimed from a seller for a refund or a lost
+// chargeback after the order's sale was credited
+type Clawback struct {
+	Amount   money.Money
+	PayoutID int64 // payout that had paid the order out; 0 if none
+	Reversed bool  // the clawbacks now add up to the whole payout
+}
+
+// Payout schedules
+const (
+	PayoutScheduleDaily    = "daily"
+	PayoutScheduleWeekly   = "weekly"
+	PayoutScheduleOnDemand = "on_demand"
+)
+
+// Wallet transaction types
+const (
+	WalletTxSale           = "sale"
+	WalletTxPayout         = "payout"
+	WalletTxPayoutReturned = "payout_returned" // the payout's transfer failed
+	WalletTxClawback       = "clawback"        // refund or lost chargeback after the sale
+)
+
+// ValidPayoutSchedule reports whether schedule is a payout schedule
+func ValidPayoutSchedule(schedule string) bool {
+	switch schedule {
+	case PayoutScheduleDaily, PayoutScheduleWeekly, PayoutScheduleOnDemand:
+		return true
+	default:
+		return false
+	}
+}
+
+// NextPayoutAt returns when the batch after one at from runs. On-demand
+// wallets have no scheduled batch.
+func NextPayoutAt(schedule string, from time.Time) sql.NullTime {
+	switch schedule {
+	case PayoutScheduleDaily:
+		return sql.NullTime{Time: from.Add(24 * time.Hour), Valid: true}
+	case PayoutScheduleWeekly:
+		return sql.NullTime{Time: from.Add(7 * 24 * time.Hour), Valid: true}
+	default:
+		return sql.NullTime{}
+	}
+}
+
+// Payable returns the part of the balance that can be paid out
+func (w *Wallet) Payable() money.Money {
+	return w.Balance.Sub(w.FrozenBalance)
+}
+
+// CanPayout reports whether the payable balance is high enough for a payout
+func (w *Wallet) CanPayout() bool {
+	payable := w.Payable()
+	return payable.IsPositive() && !payable.LessThan(w.MinimumPayout)
+}
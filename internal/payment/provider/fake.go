@@ -0,0 +1,451 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+// Call is one recorded Fake method call
+type Call struct {
+	Args   interface{} // the params struct, or the ID for lookups
+	Method string
+}
+
+// Fake is an in-process PaymentProvider. It keeps intents, charges,
+// refunds and transfers in memory, succeeds like a test card would and
+// records every call for assertions. It backs demo mode.
+//
+// Intents created without Confirm are treated as if the buyer completed
+// checkout and succeed straight away; holds (ManualCapture) wait for
+// CaptureIntent. Creates retried with an idempotency key return the first
+// result, like Stripe.
+type Fake struct {
+	intents       map[string]*Intent
+	charges       map[string]*Charge
+	idempotent    map[string]interface{} // method + key -> first result
+	now           func() time.Time
+	webhookSecret string
+	chargeIDs     []string // creation order
+	refunds       []*Refund
+	transfers     []*Transfer
+	calls         []Call
+	seq           int
+	mu            sync.Mutex
+}
+
+// NewFake returns an empty fake. With a webhook secret, ParseWebhook
+// verifies signatures like Stripe does; without one it accepts unsigned
+// events.
+func NewFake(webhookSecret string) *Fake {
+	return &Fake{
+		intents:       make(map[string]*Intent),
+		charges:       make(map[string]*Charge),
+		idempotent:    make(map[string]interface{}),
+		now:           time.Now,
+		webhookSecret: webhookSecret,
+	}
+}
+
+// SetClock sets the time stamped on new charges, refunds and transfers
+func (f *Fake) SetClock(now func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = now
+}
+
+// Name identifies the provider
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+// Calls returns the recorded calls in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := make([]Call, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+// CallCount returns how often method was called
+func (f *Fake) CallCount(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for _, c := range f.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// Refunds returns the refunds created so far
+func (f *Fake) Refunds() []*Refund {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	refunds := make([]*Refund, len(f.refunds))
+	for i, r := range f.refunds {
+		copied := *r
+		refunds[i] = &copied
+	}
+	return refunds
+}
+
+// Transfers returns the transfers created so far
+func (f *Fake) Transfers() []*Transfer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transfers := make([]*Transfer, len(f.transfers))
+	for i, t := range f.transfers {
+		copied := *t
+		transfers[i] = &copied
+	}
+	return transfers
+}
+
+// CreateIntent creates an intent; see Fake for how it settles
+func (f *Fake) CreateIntent(ctx context.Context, params IntentParams) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CreateIntent", params)
+
+	if prev, ok := f.replay("CreateIntent", params.IdempotencyKey); ok {
+		return copyIntent(prev.(*Intent)), nil
+	}
+
+	if params.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be greater than 0")
+	}
+	if params.Confirm && params.PaymentMethodID == "" {
+		return nil, fmt.Errorf("payment method is required to confirm")
+	}
+
+	id := f.nextID("pi")
+	intent := &Intent{
+		ID:           id,
+		ClientSecret: id + "_secret",
+		Currency:     params.Currency,
+		Amount:       params.Amount,
+		Metadata:     params.Metadata,
+	}
+
+	if params.ManualCapture {
+		intent.Status = IntentRequiresCapture
+	} else {
+		f.charge(intent, params.Amount)
+	}
+
+	f.intents[id] = intent
+	f.remember("CreateIntent", params.IdempotencyKey, intent)
+	return copyIntent(intent), nil
+}
+
+// GetIntent returns an intent with its charge
+func (f *Fake) GetIntent(ctx context.Context, intentID string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetIntent", intentID)
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("intent %s: %w", intentID, ErrNotFound)
+	}
+
+	return copyIntent(intent), nil
+}
+
+// ConfirmIntent confirms an intent; already-settled intents are returned
+// unchanged
+func (f *Fake) ConfirmIntent(ctx context.Context, intentID string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ConfirmIntent", intentID)
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("intent %s: %w", intentID, ErrNotFound)
+	}
+
+	switch intent.Status {
+	case IntentRequiresPaymentMethod, IntentRequiresConfirmation:
+		f.charge(intent, intent.Amount)
+	case IntentCanceled:
+		return nil, fmt.Errorf("intent %s is canceled", intentID)
+	}
+
+	return copyIntent(intent), nil
+}
+
+// CaptureIntent captures a hold; amount 0 captures all of it
+func (f *Fake) CaptureIntent(ctx context.Context, intentID string, amount int64) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CaptureIntent", intentID)
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("intent %s: %w", intentID, ErrNotFound)
+	}
+	if intent.Status != IntentRequiresCapture {
+		return nil, fmt.Errorf("intent %s cannot be captured (status: %s)", intentID, intent.Status)
+	}
+	if amount > intent.Amount {
+		return nil, fmt.Errorf("capture amount exceeds the authorized amount")
+	}
+	if amount <= 0 {
+		amount = intent.Amount
+	}
+
+	f.charge(intent, amount)
+	return copyIntent(intent), nil
+}
+
+// CancelIntent cancels an unsettled intent
+func (f *Fake) CancelIntent(ctx context.Context, intentID string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CancelIntent", intentID)
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("intent %s: %w", intentID, ErrNotFound)
+	}
+	if intent.Status == IntentSucceeded {
+		return nil, fmt.Errorf("intent %s already succeeded", intentID)
+	}
+
+	intent.Status = IntentCanceled
+	return copyIntent(intent), nil
+}
+
+// CreateRefund refunds a charge. Charges the fake has not seen (created
+// before a restart in demo mode) are accepted as-is.
+func (f *Fake) CreateRefund(ctx context.Context, params RefundParams) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CreateRefund", params)
+
+	if prev, ok := f.replay("CreateRefund", params.IdempotencyKey); ok {
+		copied := *prev.(*Refund)
+		return &copied, nil
+	}
+
+	if params.ChargeID == "" {
+		return nil, fmt.Errorf("charge ID is required")
+	}
+
+	amount := params.Amount
+	if charge, ok := f.charges[params.ChargeID]; ok {
+		remaining := charge.Amount - charge.AmountRefunded
+		if amount <= 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return nil, fmt.Errorf("refund amount exceeds the unrefunded charge amount")
+		}
+
+		charge.AmountRefunded += amount
+		charge.Refunded = charge.AmountRefunded >= charge.Amount
+	} else if amount <= 0 {
+		return nil, fmt.Errorf("refund amount is required for unknown charge %s", params.ChargeID)
+	}
+
+	r := &Refund{
+		Created:  f.now(),
+		ID:       f.nextID("re"),
+		ChargeID: params.ChargeID,
+		Status:   RefundSucceeded,
+		Amount:   amount,
+	}
+	if charge, ok := f.charges[params.ChargeID]; ok {
+		r.Currency = charge.Currency
+	}
+	f.refunds = append(f.refunds, r)
+	f.remember("CreateRefund", params.IdempotencyKey, r)
+
+	copied := *r
+	return &copied, nil
+}
+
+// CreateTransfer records a transfer. Demo sellers have no connected
+// account, so the destination is optional.
+func (f *Fake) CreateTransfer(ctx context.Context, params TransferParams) (*Transfer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CreateTransfer", params)
+
+	if prev, ok := f.replay("CreateTransfer", params.IdempotencyKey); ok {
+		copied := *prev.(*Transfer)
+		return &copied, nil
+	}
+
+	if params.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be greater than 0")
+	}
+
+	t := &Transfer{
+		Created:     f.now(),
+		Metadata:    params.Metadata,
+		ID:          f.nextID("tr"),
+		Currency:    params.Currency,
+		Destination: params.Destination,
+		Amount:      params.Amount,
+	}
+	f.transfers = append(f.transfers, t)
+	f.remember("CreateTransfer", params.IdempotencyKey, t)
+
+	copied := *t
+	return &copied, nil
+}
+
+// ListCharges returns the charges created in the range, oldest first
+func (f *Fake) ListCharges(ctx context.Context, params ListParams) ([]*Charge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ListCharges", params)
+
+	charges := make([]*Charge, 0)
+	for _, id := range f.chargeIDs {
+		if ch := f.charges[id]; params.Includes(ch.Created) {
+			copied := *ch
+			charges = append(charges, &copied)
+		}
+	}
+	return charges, nil
+}
+
+// ListRefunds returns the refunds created in the range, oldest first
+func (f *Fake) ListRefunds(ctx context.Context, params ListParams) ([]*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ListRefunds", params)
+
+	refunds := make([]*Refund, 0)
+	for _, r := range f.refunds {
+		if params.Includes(r.Created) {
+			copied := *r
+			refunds = append(refunds, &copied)
+		}
+	}
+	return refunds, nil
+}
+
+// ListTransfers returns the transfers created in the range, oldest first
+func (f *Fake) ListTransfers(ctx context.Context, params ListParams) ([]*Transfer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ListTransfers", params)
+
+	transfers := make([]*Transfer, 0)
+	for _, t := range f.transfers {
+		if params.Includes(t.Created) {
+			copied := *t
+			transfers = append(transfers, &copied)
+		}
+	}
+	return transfers, nil
+}
+
+// ParseWebhook decodes a Stripe-format event, verifying the signature when
+// the fake has a webhook secret
+func (f *Fake) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	f.mu.Lock()
+	f.record("ParseWebhook", signature)
+	f.mu.Unlock()
+
+	return parseStripeEvent(payload, signature, f.webhookSecret)
+}
+
+// SignEvent builds a Stripe-format webhook payload for object and the
+// signature header ParseWebhook accepts
+func (f *Fake) SignEvent(eventID, eventType string, object interface{}) ([]byte, string, error) {
+	obj, err := json.Marshal(object)
+	if err != nil {
+		return nil, "", err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":     eventID,
+		"object": "event",
+		"type":   eventType,
+		"data":   map[string]json.RawMessage{"object": obj},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if f.webhookSecret == "" {
+		return payload, "", nil
+	}
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    f.webhookSecret,
+		Timestamp: time.Now(),
+	})
+	return payload, signed.Header, nil
+}
+
+// charge settles an intent with a test-card charge. Callers hold f.mu.
+func (f *Fake) charge(intent *Intent, amount int64) {
+	ch := &Charge{
+		Created:       f.now(),
+		ID:            f.nextID("ch"),
+		IntentID:      intent.ID,
+		Status:        ChargeSucceeded,
+		Currency:      intent.Currency,
+		PaymentMethod: "card",
+		CardLast4:     "4242",
+		CardBrand:     "visa",
+		Amount:        amount,
+	}
+	f.charges[ch.ID] = ch
+	f.chargeIDs = append(f.chargeIDs, ch.ID)
+
+	intent.Status = IntentSucceeded
+	intent.Charge = ch
+}
+
+func (f *Fake) record(method string, args interface{}) {
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+}
+
+// replay returns what an earlier create with the same idempotency key returned
+func (f *Fake) replay(method, key string) (interface{}, bool) {
+	if key == "" {
+		return nil, false
+	}
+	v, ok := f.idempotent[method+":"+key]
+	return v, ok
+}
+
+func (f *Fake) remember(method, key string, result interface{}) {
+	if key != "" {
+		f.idempotent[method+":"+key] = result
+	}
+}
+
+func (f *Fake) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s_fake_%d", prefix, f.seq)
+}
+
+func copyIntent(intent *Intent) *Intent {
+	copied := *intent
+	if intent.Charge != nil {
+		ch := *intent.Charge
+		copied.Charge = &ch
+	}
+	return &copied
+}
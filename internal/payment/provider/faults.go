@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInjected is the default error returned by injected failures
+var ErrInjected = errors.New("injected provider failure")
+
+// methods lists the PaymentProvider methods faults can target
+var methods = map[string]bool{
+	"CreateIntent":   true,
+	"GetIntent":      true,
+	"ConfirmIntent":  true,
+	"CaptureIntent":  true,
+	"CancelIntent":   true,
+	"CreateRefund":   true,
+	"CreateTransfer": true,
+	"ListCharges":    true,
+	"ListRefunds":    true,
+	"ListTransfers":  true,
+	"ParseWebhook":   true,
+}
+
+type fault struct {
+	err   error
+	rate  float64 // probability per call, used when count is 0
+	count int     // remaining forced failures
+}
+
+// FailureInjector wraps a PaymentProvider and fails selected methods, either
+// the next N calls or a share of all calls. Failing calls never reach the
+// wrapped provider.
+type FailureInjector struct {
+	next   PaymentProvider
+	faults map[string]*fault
+	rand   *rand.Rand
+	mu     sync.Mutex
+}
+
+// NewFailureInjector wraps next without any faults configured
+func NewFailureInjector(next PaymentProvider) *FailureInjector {
+	return &FailureInjector{
+		next:   next,
+		faults: make(map[string]*fault),
+		rand:   rand.New(rand.NewSource(1)),
+	}
+}
+
+// FailNext makes the next n calls of method fail with err (ErrInjected if nil)
+func (f *FailureInjector) FailNext(method string, n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	flt := f.fault(method)
+	flt.count = n
+	flt.err = err
+}
+
+// FailRate makes a share of calls of method fail with err (ErrInjected if
+// nil); 1 fails every call, 0 disables the fault
+func (f *FailureInjector) FailRate(method string, rate float64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	flt := f.fault(method)
+	flt.rate = rate
+	flt.err = err
+}
+
+// Reset clears all faults
+func (f *FailureInjector) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faults = make(map[string]*fault)
+}
+
+// Name identifies the wrapped provider
+func (f *FailureInjector) Name() string {
+	return f.next.Name()
+}
+
+// CreateIntent fails or delegates
+func (f *FailureInjector) CreateIntent(ctx context.Context, params IntentParams) (*Intent, error) {
+	if err := f.check("CreateIntent"); err != nil {
+		return nil, err
+	}
+	return f.next.CreateIntent(ctx, params)
+}
+
+// GetIntent fails or delegates
+func (f *FailureInjector) GetIntent(ctx context.Context, intentID string) (*Intent, error) {
+	if err := f.check("GetIntent"); err != nil {
+		return nil, err
+	}
+	return f.next.GetIntent(ctx, intentID)
+}
+
+// ConfirmIntent fails or delegates
+func (f *FailureInjector) ConfirmIntent(ctx context.Context, intentID string) (*Intent, error) {
+	if err := f.check("ConfirmIntent"); err != nil {
+		return nil, err
+	}
+	return f.next.ConfirmIntent(ctx, intentID)
+}
+
+// CaptureIntent fails or delegates
+func (f *FailureInjector) CaptureIntent(ctx context.Context, intentID string, amount int64) (*Intent, error) {
+	if err := f.check("CaptureIntent"); err != nil {
+		return nil, err
+	}
+	return f.next.CaptureIntent(ctx, intentID, amount)
+}
+
+// CancelIntent fails or delegates
+func (f *FailureInjector) CancelIntent(ctx context.Context, intentID string) (*Intent, error) {
+	if err := f.check("CancelIntent"); err != nil {
+		return nil, err
+	}
+	return f.next.CancelIntent(ctx, intentID)
+}
+
+// CreateRefund fails or delegates
+func (f *FailureInjector) CreateRefund(ctx context.Context, params RefundParams) (*Refund, error) {
+	if err := f.check("CreateRefund"); err != nil {
+		return nil, err
+	}
+	return f.next.CreateRefund(ctx, params)
+}
+
+// CreateTransfer fails or delegates
+func (f *FailureInjector) CreateTransfer(ctx context.Context, params TransferParams) (*Transfer, error) {
+	if err := f.check("CreateTransfer"); err != nil {
+		return nil, err
+	}
+	return f.next.CreateTransfer(ctx, params)
+}
+
+// ListCharges fails or delegates
+func (f *FailureInjector) ListCharges(ctx context.Context, params ListParams) ([]*Charge, error) {
+	if err := f.check("ListCharges"); err != nil {
+		return nil, err
+	}
+	return f.next.ListCharges(ctx, params)
+}
+
+// ListRefunds fails or delegates
+func (f *FailureInjector) ListRefunds(ctx context.Context, params ListParams) ([]*Refund, error) {
+	if err := f.check("ListRefunds"); err != nil {
+		return nil, err
+	}
+	return f.next.ListRefunds(ctx, params)
+}
+
+// ListTransfers fails or delegates
+func (f *FailureInjector) ListTransfers(ctx context.Context, params ListParams) ([]*Transfer, error) {
+	if err := f.check("ListTransfers"); err != nil {
+		return nil, err
+	}
+	return f.next.ListTransfers(ctx, params)
+}
+
+// ParseWebhook fails or delegates
+func (f *FailureInjector) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	if err := f.check("ParseWebhook"); err != nil {
+		return nil, err
+	}
+	return f.next.ParseWebhook(payload, signature)
+}
+
+// check reports the injected error for this call of method, if any
+func (f *FailureInjector) check(method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	flt, ok := f.faults[method]
+	if !ok {
+		return nil
+	}
+
+	fail := false
+	switch {
+	case flt.count > 0:
+		flt.count--
+		fail = true
+	case flt.rate > 0:
+		fail = f.rand.Float64() < flt.rate
+	}
+	if !fail {
+		return nil
+	}
+
+	if flt.err != nil {
+		return flt.err
+	}
+	return fmt.Errorf("%s: %w", method, ErrInjected)
+}
+
+// fault returns the fault for method, creating it. Callers hold f.mu.
+func (f *FailureInjector) fault(method string) *fault {
+	flt, ok := f.faults[method]
+	if !ok {
+		flt = &fault{}
+		f.faults[method] = flt
+	}
+	return flt
+}
+
+// ParseFaults parses a fault spec such as "CreateTransfer,CreateRefund=0.25"
+// into failure rates per method. A method without a rate always fails.
+func ParseFaults(spec string) (map[string]float64, error) {
+	faults := make(map[string]float64)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		method, rateStr, hasRate := strings.Cut(part, "=")
+		method = strings.TrimSpace(method)
+		if !methods[method] {
+			return nil, fmt.Errorf("unknown provider method in fault spec: %s", method)
+		}
+
+		rate := 1.0
+		if hasRate {
+			var err error
+			rate, err = strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid failure rate for %s: %s", method, rateStr)
+			}
+		}
+
+		faults[method] = rate
+	}
+
+	return faults, nil
+}
@@ -0,0 +1,219 @@
+// Package provider abstracts the payment processor behind PaymentProvider so
+// payment logic does not depend on Stripe being reachable. Amounts are in
+// minor units (cents).
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/config"
+)
+
+// PaymentProvider moves money: payment intents (charges and manual-capture
+// holds), refunds, transfers to connected seller accounts, and the webhook
+// events that report their outcome.
+type PaymentProvider interface {
+	// Name identifies the provider ("stripe", "fake")
+	Name() string
+
+	CreateIntent(ctx context.Context, params IntentParams) (*Intent, error)
+	GetIntent(ctx context.Context, intentID string) (*Intent, error)
+	ConfirmIntent(ctx context.Context, intentID string) (*Intent, error)
+	// CaptureIntent captures amount (0 = everything) of a held intent
+	CaptureIntent(ctx context.Context, intentID string, amount int64) (*Intent, error)
+	CancelIntent(ctx context.Context, intentID string) (*Intent, error)
+
+	CreateRefund(ctx context.Context, params RefundParams) (*Refund, error)
+	CreateTransfer(ctx context.Context, params TransferParams) (*Transfer, error)
+
+	// ListCharges, ListRefunds and ListTransfers return the objects created
+	// in a date range, for reconciliation
+	ListCharges(ctx context.Context, params ListParams) ([]*Charge, error)
+	ListRefunds(ctx context.Context, params ListParams) ([]*Refund, error)
+	ListTransfers(ctx context.Context, params ListParams) ([]*Transfer, error)
+
+	// ParseWebhook verifies a webhook payload against its signature header
+	ParseWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// Intent statuses
+const (
+	IntentRequiresPaymentMethod = "requires_payment_method"
+	IntentRequiresConfirmation  = "requires_confirmation"
+	IntentRequiresCapture       = "requires_capture"
+	IntentProcessing            = "processing"
+	IntentSucceeded             = "succeeded"
+	IntentCanceled              = "canceled"
+)
+
+// Refund reasons the processor understands; other reasons travel as metadata
+const (
+	RefundReasonDuplicate           = "duplicate"
+	RefundReasonFraudulent          = "fraudulent"
+	RefundReasonRequestedByCustomer = "requested_by_customer"
+)
+
+// IntentParams describes a payment intent to create
+type IntentParams struct {
+	Metadata        map[string]string
+	IdempotencyKey  string // a retry with the same key returns the first intent
+	Currency        string
+	CustomerID      string
+	PaymentMethodID string
+	Amount          int64
+	ManualCapture   bool // place a hold, captured later
+	Confirm         bool // confirm immediately with PaymentMethodID
+	OffSession      bool
+}
+
+// Intent is a payment intent
+type Intent struct {
+	Charge       *Charge // latest charge, when known
+	Metadata     map[string]string
+	ID           string
+	ClientSecret string
+	Status       string
+	Currency     string
+	FailureMsg   string // last payment error
+	Amount       int64
+}
+
+// Charge statuses
+const (
+	ChargePending   = "pending"
+	ChargeSucceeded = "succeeded"
+	ChargeFailed    = "failed"
+)
+
+// Charge is money collected by an intent
+type Charge struct {
+	Created        time.Time
+	ID             string
+	IntentID       string
+	Status         string
+	Currency       string
+	PaymentMethod  string
+	CardLast4      string
+	CardBrand      string
+	FeeCurrency    string
+	Amount         int64
+	AmountRefunded int64
+	Fee            int64 // processor fee, when the balance transaction is known
+	Refunded       bool  // fully refunded
+}
+
+// RefundParams describes a refund to create
+type RefundParams struct {
+	Metadata       map[string]string
+	IdempotencyKey string // a retry with the same key returns the first refund
+	ChargeID       string
+	Reason         string
+	Amount         int64 // 0 refunds the remaining amount
+}
+
+// RefundSucceeded is the status of a refund that was paid back
+const RefundSucceeded = "succeeded"
+
+// Refund is money returned to the buyer
+type Refund struct {
+	Created  time.Time
+	ID       string
+	ChargeID string
+	Status   string
+	Currency string
+	Amount   int64
+}
+
+// TransferParams describes a transfer to a connected account
+type TransferParams struct {
+	Metadata       map[string]string
+	IdempotencyKey string // a retry with the same key returns the first transfer
+	Currency       string
+	Destination    string
+	Amount         int64
+}
+
+// Transfer is money moved to a seller's connected account
+type Transfer struct {
+	Created     time.Time
+	Metadata    map[string]string
+	ID          string
+	Currency    string
+	Destination string
+	Amount      int64
+	Reversed    bool
+}
+
+// ListParams selects the objects created in [From, To)
+type ListParams struct {
+	From time.Time
+	To   time.Time
+}
+
+// Includes reports whether t falls in the range
+func (p ListParams) Includes(t time.Time) bool {
+	return !t.Before(p.From) && t.Before(p.To)
+}
+
+// Dispute is a chargeback the buyer's bank opened on a charge
+type Dispute struct {
+	ID       string
+	ChargeID string
+	Currency string
+	Reason   string
+	Status   string // needs_response, under_review, won, lost, ...
+	Amount   int64
+}
+
+// Dispute statuses that close it
+const (
+	DisputeWon  = "won"
+	DisputeLost = "lost"
+)
+
+// Event is a verified webhook event. The object matching Type is set;
+// Raw keeps the event's data object for types the provider does not model.
+type Event struct {
+	Intent   *Intent
+	Charge   *Charge
+	Transfer *Transfer
+	Dispute  *Dispute
+	ID       string
+	Type     string
+	Raw      json.RawMessage
+}
+
+// ErrNotFound is returned for unknown intents and charges
+var ErrNotFound = errors.New("not found")
+
+// New builds the provider selected by cfg.Mode: "real" calls Stripe,
+// anything else uses the in-process fake. cfg.Faults wraps the fake in a
+// failure injector.
+func New(cfg config.StripeConfig) (PaymentProvider, error) {
+	if cfg.Mode == "real" {
+		if cfg.Faults != "" {
+			return nil, fmt.Errorf("fault injection is only available with the fake provider")
+		}
+		return NewStripe(cfg.SecretKey, cfg.WebhookSecret)
+	}
+
+	fake := NewFake(cfg.WebhookSecret)
+	if cfg.Faults == "" {
+		return fake, nil
+	}
+
+	faults, err := ParseFaults(cfg.Faults)
+	if err != nil {
+		return nil, err
+	}
+	injector := NewFailureInjector(fake)
+	for method, rate := range faults {
+		injector.FailRate(method, rate, nil)
+	}
+
+	return injector, nil
+}
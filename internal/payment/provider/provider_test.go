@@ -0,0 +1,351 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFakeIntentLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("")
+
+	intent, err := f.CreateIntent(ctx, IntentParams{Amount: 12500, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if intent.Status != IntentSucceeded || intent.Charge == nil {
+		t.Fatalf("expected a settled intent with a charge, got %+v", intent)
+	}
+	if intent.Charge.Amount != 12500 || intent.Charge.CardLast4 != "4242" {
+		t.Errorf("unexpected charge %+v", intent.Charge)
+	}
+
+	got, err := f.GetIntent(ctx, intent.ID)
+	if err != nil {
+		t.Fatalf("GetIntent: %v", err)
+	}
+	if got.Charge == nil || got.Charge.ID != intent.Charge.ID {
+		t.Errorf("GetIntent returned charge %+v, want %s", got.Charge, intent.Charge.ID)
+	}
+
+	if _, err := f.GetIntent(ctx, "pi_missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetIntent of unknown intent: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestFakeHoldCaptureAndCancel(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("")
+
+	hold := IntentParams{Amount: 20000, Currency: "usd", PaymentMethodID: "pm_card_visa", ManualCapture: true, Confirm: true}
+
+	captured, err := f.CreateIntent(ctx, hold)
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if captured.Status != IntentRequiresCapture {
+		t.Fatalf("hold status = %s, want %s", captured.Status, IntentRequiresCapture)
+	}
+
+	if _, err := f.CaptureIntent(ctx, captured.ID, 25000); err == nil {
+		t.Error("capturing more than authorized should fail")
+	}
+	captured, err = f.CaptureIntent(ctx, captured.ID, 18000)
+	if err != nil {
+		t.Fatalf("CaptureIntent: %v", err)
+	}
+	if captured.Status != IntentSucceeded || captured.Charge.Amount != 18000 {
+		t.Errorf("unexpected captured intent %+v", captured)
+	}
+	if _, err := f.CancelIntent(ctx, captured.ID); err == nil {
+		t.Error("canceling a captured intent should fail")
+	}
+
+	released, err := f.CreateIntent(ctx, hold)
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	released, err = f.CancelIntent(ctx, released.ID)
+	if err != nil {
+		t.Fatalf("CancelIntent: %v", err)
+	}
+	if released.Status != IntentCanceled {
+		t.Errorf("released status = %s, want %s", released.Status, IntentCanceled)
+	}
+
+	if _, err := f.CreateIntent(ctx, IntentParams{Amount: 100, Confirm: true}); err == nil {
+		t.Error("confirming without a payment method should fail")
+	}
+}
+
+func TestFakeRefunds(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("")
+
+	intent, err := f.CreateIntent(ctx, IntentParams{Amount: 10000, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	chargeID := intent.Charge.ID
+
+	if _, err := f.CreateRefund(ctx, RefundParams{ChargeID: chargeID, Amount: 4000}); err != nil {
+		t.Fatalf("partial refund: %v", err)
+	}
+	if _, err := f.CreateRefund(ctx, RefundParams{ChargeID: chargeID, Amount: 7000}); err == nil {
+		t.Error("refunding more than remains should fail")
+	}
+	rest, err := f.CreateRefund(ctx, RefundParams{ChargeID: chargeID})
+	if err != nil {
+		t.Fatalf("remaining refund: %v", err)
+	}
+	if rest.Amount != 6000 {
+		t.Errorf("remaining refund amount = %d, want 6000", rest.Amount)
+	}
+
+	got, _ := f.GetIntent(ctx, intent.ID)
+	if !got.Charge.Refunded || got.Charge.AmountRefunded != 10000 {
+		t.Errorf("charge after refunds = %+v, want fully refunded", got.Charge)
+	}
+
+	// Charges from before a restart are unknown but still refundable
+	if _, err := f.CreateRefund(ctx, RefundParams{ChargeID: "ch_old", Amount: 500}); err != nil {
+		t.Errorf("refund of unknown charge with amount: %v", err)
+	}
+	if _, err := f.CreateRefund(ctx, RefundParams{ChargeID: "ch_old"}); err == nil {
+		t.Error("refund of unknown charge without amount should fail")
+	}
+
+	if n := len(f.Refunds()); n != 3 {
+		t.Errorf("recorded %d refunds, want 3", n)
+	}
+}
+
+func TestFakeRecordsCalls(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("")
+
+	params := TransferParams{Amount: 9000, Currency: "usd", Destination: "acct_1"}
+	tr, err := f.CreateTransfer(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	if _, err := f.GetIntent(ctx, "pi_missing"); err == nil {
+		t.Fatal("expected GetIntent to fail")
+	}
+
+	calls := f.Calls()
+	if len(calls) != 2 || calls[0].Method != "CreateTransfer" || calls[1].Method != "GetIntent" {
+		t.Fatalf("unexpected calls %+v", calls)
+	}
+	if calls[0].Args.(TransferParams).Destination != "acct_1" {
+		t.Errorf("recorded args %+v", calls[0].Args)
+	}
+	if f.CallCount("CreateTransfer") != 1 || f.CallCount("CreateRefund") != 0 {
+		t.Error("CallCount does not match recorded calls")
+	}
+
+	transfers := f.Transfers()
+	if len(transfers) != 1 || transfers[0].ID != tr.ID || transfers[0].Amount != 9000 {
+		t.Errorf("unexpected transfers %+v", transfers)
+	}
+}
+
+func TestFailureInjectorFailNext(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake("")
+	inj := NewFailureInjector(fake)
+
+	inj.FailNext("CreateTransfer", 2, nil)
+	params := TransferParams{Amount: 100, Currency: "usd"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := inj.CreateTransfer(ctx, params); !errors.Is(err, ErrInjected) {
+			t.Fatalf("call %d: got %v, want ErrInjected", i, err)
+		}
+	}
+	if _, err := inj.CreateTransfer(ctx, params); err != nil {
+		t.Fatalf("third call: %v", err)
+	}
+
+	// Failed calls never reach the wrapped provider
+	if n := fake.CallCount("CreateTransfer"); n != 1 {
+		t.Errorf("fake saw %d transfers, want 1", n)
+	}
+
+	custom := errors.New("card declined")
+	inj.FailNext("CreateIntent", 1, custom)
+	if _, err := inj.CreateIntent(ctx, IntentParams{Amount: 100}); !errors.Is(err, custom) {
+		t.Errorf("got %v, want the configured error", err)
+	}
+
+	inj.FailNext("CreateRefund", 1, nil)
+	inj.Reset()
+	if _, err := inj.CreateRefund(ctx, RefundParams{ChargeID: "ch_x", Amount: 100}); err != nil {
+		t.Errorf("after Reset: %v", err)
+	}
+}
+
+func TestFailureInjectorFailRate(t *testing.T) {
+	ctx := context.Background()
+	inj := NewFailureInjector(NewFake(""))
+
+	inj.FailRate("CreateIntent", 1, nil)
+	for i := 0; i < 5; i++ {
+		if _, err := inj.CreateIntent(ctx, IntentParams{Amount: 100}); !errors.Is(err, ErrInjected) {
+			t.Fatalf("rate 1: got %v, want ErrInjected", err)
+		}
+	}
+
+	inj.FailRate("CreateIntent", 0.5, nil)
+	failed := 0
+	for i := 0; i < 200; i++ {
+		if _, err := inj.CreateIntent(ctx, IntentParams{Amount: 100}); err != nil {
+			failed++
+		}
+	}
+	if failed == 0 || failed == 200 {
+		t.Errorf("rate 0.5 failed %d of 200 calls", failed)
+	}
+
+	inj.FailRate("CreateIntent", 0, nil)
+	if _, err := inj.CreateIntent(ctx, IntentParams{Amount: 100}); err != nil {
+		t.Errorf("rate 0: %v", err)
+	}
+}
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults("CreateTransfer, CreateRefund=0.25")
+	if err != nil {
+		t.Fatalf("ParseFaults: %v", err)
+	}
+	if faults["CreateTransfer"] != 1 || faults["CreateRefund"] != 0.25 || len(faults) != 2 {
+		t.Errorf("unexpected faults %v", faults)
+	}
+
+	for _, spec := range []string{"Charge", "CreateRefund=2", "CreateRefund=x"} {
+		if _, err := ParseFaults(spec); err == nil {
+			t.Errorf("ParseFaults(%q) should fail", spec)
+		}
+	}
+}
+
+func TestFakeWebhookRoundTrip(t *testing.T) {
+	f := NewFake("whsec_test")
+
+	payload, sig, err := f.SignEvent("evt_1", "charge.refunded", map[string]interface{}{
+		"id":              "ch_1",
+		"object":          "charge",
+		"amount":          5000,
+		"amount_refunded": 5000,
+		"refunded":        true,
+		"payment_intent":  "pi_1",
+	})
+	if err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+
+	event, err := f.ParseWebhook(payload, sig)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	if event.ID != "evt_1" || event.Type != "charge.refunded" || event.Charge == nil {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if event.Charge.AmountRefunded != 5000 || !event.Charge.Refunded || event.Charge.IntentID != "pi_1" {
+		t.Errorf("unexpected charge %+v", event.Charge)
+	}
+
+	if _, err := f.ParseWebhook(payload, "t=1,v1=bad"); err == nil {
+		t.Error("a bad signature should be rejected")
+	}
+}
+
+func TestFakeWebhookUnsigned(t *testing.T) {
+	f := NewFake("")
+
+	payload, sig, err := f.SignEvent("evt_2", "transfer.created", map[string]interface{}{
+		"id":     "tr_1",
+		"object": "transfer",
+		"amount": 900,
+	})
+	if err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+
+	event, err := f.ParseWebhook(payload, sig)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	if event.Transfer == nil || event.Transfer.ID != "tr_1" || event.Transfer.Amount != 900 {
+		t.Errorf("unexpected transfer %+v", event.Transfer)
+	}
+}
+
+func TestFakeWebhookDispute(t *testing.T) {
+	f := NewFake("")
+
+	payload, sig, err := f.SignEvent("evt_3", "charge.dispute.closed", map[string]interface{}{
+		"id":       "dp_1",
+		"object":   "dispute",
+		"amount":   5000,
+		"currency": "usd",
+		"charge":   "ch_1",
+		"reason":   "fraudulent",
+		"status":   "lost",
+	})
+	if err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+
+	event, err := f.ParseWebhook(payload, sig)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	d := event.Dispute
+	if d == nil || d.ID != "dp_1" || d.ChargeID != "ch_1" || d.Amount != 5000 || d.Status != DisputeLost {
+		t.Errorf("unexpected dispute %+v", d)
+	}
+}
+
+func TestFakeIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("")
+
+	params := IntentParams{Amount: 5000, Currency: "usd", IdempotencyKey: "intent-1"}
+	first, err := f.CreateIntent(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	again, err := f.CreateIntent(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateIntent retry: %v", err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("retried intent %s, want %s", again.ID, first.ID)
+	}
+
+	refund := RefundParams{ChargeID: first.Charge.ID, Amount: 2000, IdempotencyKey: "refund-1"}
+	r1, err := f.CreateRefund(ctx, refund)
+	if err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+	r2, err := f.CreateRefund(ctx, refund)
+	if err != nil {
+		t.Fatalf("CreateRefund retry: %v", err)
+	}
+	if r2.ID != r1.ID || len(f.Refunds()) != 1 {
+		t.Errorf("retried refund created %d refunds", len(f.Refunds()))
+	}
+
+	transfer := TransferParams{Amount: 4000, Currency: "usd", IdempotencyKey: "payout-1"}
+	if _, err := f.CreateTransfer(ctx, transfer); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	if _, err := f.CreateTransfer(ctx, transfer); err != nil {
+		t.Fatalf("CreateTransfer retry: %v", err)
+	}
+	if len(f.Transfers()) != 1 {
+		t.Errorf("retried transfer created %d transfers", len(f.Transfers()))
+	}
+}
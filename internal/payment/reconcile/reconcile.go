@@ -0,0 +1,580 @@
+// Package reconcile compares what the payment provider reports for a date
+// range with the marketplace's payments and payouts. It only reads; the
+// Payment Service decides which mismatches to repair.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// Slack widens the provider fetch on both sides of the range. Payments and
+// payouts are stamped a little before or after their provider objects, so
+// objects just outside the range still match records inside it.
+const Slack = time.Hour
+
+// Mismatch kinds
+const (
+	KindMissingRecord     = "missing_record"      // at the provider, not in the database
+	KindMissingAtProvider = "missing_at_provider" // in the database, not at the provider
+	KindAmount            = "amount"
+	KindStatus            = "status"
+)
+
+// Provider objects a mismatch is about
+const (
+	ObjectCharge   = "charge"
+	ObjectRefund   = "refund"
+	ObjectTransfer = "transfer"
+)
+
+// Repairs the Payment Service can apply safely. Each applies the provider's
+// state the way the missed webhook would have.
+const (
+	RepairSettlePayments = "settle_payments" // charge succeeded, payments still open
+	RepairSyncRefunds    = "sync_refunds"    // charge refunded more than recorded
+	RepairConfirmPayout  = "confirm_payout"  // transfer sent, payout still open
+)
+
+// Mismatch is one difference between the provider and the database
+type Mismatch struct {
+	Charge      *provider.Charge   `json:"-"` // set for charge repairs
+	Transfer    *provider.Transfer `json:"-"` // set for payout repairs
+	Kind        string             `json:"kind"`
+	Object      string             `json:"object"`
+	ProviderID  string             `json:"provider_id,omitempty"`
+	Detail      string             `json:"detail"`
+	Repair      string             `json:"repair,omitempty"` // empty when it needs a person
+	RepairError string             `json:"repair_error,omitempty"`
+	PaymentIDs  []int64            `json:"payment_ids,omitempty"`
+	PayoutID    int64              `json:"payout_id,omitempty"`
+	Repaired    bool               `json:"repaired"`
+}
+
+// Statement is what the provider reports around a date range
+type Statement struct {
+	Range     provider.ListParams
+	Charges   []*provider.Charge
+	Refunds   []*provider.Refund
+	Transfers []*provider.Transfer
+}
+
+// Records is the database's side of a statement
+type Records struct {
+	HoldIntents map[string]bool // bid-hold intents, which have no payment until captured
+	Payments    []*model.Payment
+	Payouts     []*model.Payout
+}
+
+// Report lists the mismatches found for a date range
+type Report struct {
+	From       time.Time   `json:"from"`
+	To         time.Time   `json:"to"`
+	Mismatches []*Mismatch `json:"mismatches"`
+	Charges    int         `json:"charges"`
+	Refunds    int         `json:"refunds"`
+	Transfers  int         `json:"transfers"`
+}
+
+// Unresolved counts the mismatches that are not repaired
+func (r *Report) Unresolved() int {
+	n := 0
+	for _, m := range r.Mismatches {
+		if !m.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// Fetch lists the provider's charges, refunds and transfers created in the
+// range, widened by Slack on both sides
+func Fetch(ctx context.Context, p provider.PaymentProvider, params provider.ListParams) (*Statement, error) {
+	if !params.From.Before(params.To) {
+		return nil, fmt.Errorf("reconciliation range is empty")
+	}
+
+	wide := provider.ListParams{From: params.From.Add(-Slack), To: params.To.Add(Slack)}
+	stmt := &Statement{Range: params}
+
+	var err error
+	if stmt.Charges, err = p.ListCharges(ctx, wide); err != nil {
+		return nil, err
+	}
+	if stmt.Refunds, err = p.ListRefunds(ctx, wide); err != nil {
+		return nil, err
+	}
+	if stmt.Transfers, err = p.ListTransfers(ctx, wide); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// IntentIDs returns the intents of the statement's charges
+func (s *Statement) IntentIDs() []string {
+	ids := make([]string, 0, len(s.Charges))
+	for _, ch := range s.Charges {
+		if ch.IntentID != "" {
+			ids = append(ids, ch.IntentID)
+		}
+	}
+	return ids
+}
+
+// ChargeIDs returns the statement's charges and the charges its refunds
+// were taken from
+func (s *Statement) ChargeIDs() []string {
+	ids := make([]string, 0, len(s.Charges)+len(s.Refunds))
+	for _, ch := range s.Charges {
+		ids = append(ids, ch.ID)
+	}
+	for _, r := range s.Refunds {
+		ids = append(ids, r.ChargeID)
+	}
+	return ids
+}
+
+// TransferIDs returns the statement's transfers
+func (s *Statement) TransferIDs() []string {
+	ids := make([]string, 0, len(s.Transfers))
+	for _, t := range s.Transfers {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+// PayoutIDs returns the wallet payouts the statement's transfers name in
+// their metadata
+func (s *Statement) PayoutIDs() []int64 {
+	return s.metadataIDs("payout_id")
+}
+
+// OrderIDs returns the orders the statement's per-order transfers name in
+// their metadata
+func (s *Statement) OrderIDs() []int64 {
+	return s.metadataIDs("order_id")
+}
+
+func (s *Statement) metadataIDs(key string) []int64 {
+	ids := make([]int64, 0)
+	for _, t := range s.Transfers {
+		if id, ok := metadataID(t, key); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Compare matches the statement against the records. Provider objects are
+// reported missing only when created in the range; records only when
+// settled in it.
+func Compare(stmt *Statement, records *Records) *Report {
+	c := newComparison(stmt, records)
+
+	for _, ch := range stmt.Charges {
+		c.compareCharge(ch)
+	}
+	for _, r := range stmt.Refunds {
+		c.compareRefund(r)
+	}
+	for _, t := range stmt.Transfers {
+		c.compareTransfer(t)
+	}
+	c.findUnmatched()
+
+	return c.report
+}
+
+type comparison struct {
+	stmt           *Statement
+	records        *Records
+	report         *Report
+	chargesByID    map[string]*provider.Charge
+	byIntent       map[string][]*model.Payment
+	byCharge       map[string][]*model.Payment
+	payoutsByID    map[int64]*model.Payout
+	payoutsByXfer  map[string]*model.Payout
+	matchedPayment map[int64]bool
+	matchedPayout  map[int64]bool
+}
+
+func newComparison(stmt *Statement, records *Records) *comparison {
+	c := &comparison{
+		stmt:           stmt,
+		records:        records,
+		report:         &Report{From: stmt.Range.From, To: stmt.Range.To, Mismatches: make([]*Mismatch, 0)},
+		chargesByID:    make(map[string]*provider.Charge),
+		byIntent:       make(map[string][]*model.Payment),
+		byCharge:       make(map[string][]*model.Payment),
+		payoutsByID:    make(map[int64]*model.Payout),
+		payoutsByXfer:  make(map[string]*model.Payout),
+		matchedPayment: make(map[int64]bool),
+		matchedPayout:  make(map[int64]bool),
+	}
+
+	for _, ch := range stmt.Charges {
+		c.chargesByID[ch.ID] = ch
+	}
+	for _, p := range records.Payments {
+		if p.StripePaymentIntentID.Valid {
+			c.byIntent[p.StripePaymentIntentID.String] = append(c.byIntent[p.StripePaymentIntentID.String], p)
+		}
+		if p.StripeChargeID.Valid {
+			c.byCharge[p.StripeChargeID.String] = append(c.byCharge[p.StripeChargeID.String], p)
+		}
+	}
+	for _, p := range records.Payouts {
+		c.payoutsByID[p.ID] = p
+		if p.StripeTransferID.Valid {
+			c.payoutsByXfer[p.StripeTransferID.String] = p
+		}
+	}
+
+	return c
+}
+
+func (c *comparison) add(m *Mismatch) {
+	c.report.Mismatches = append(c.report.Mismatches, m)
+}
+
+// paymentsFor returns the payments a charge settles: those of its intent,
+// or those that recorded it
+func (c *comparison) paymentsFor(ch *provider.Charge) []*model.Payment {
+	if payments := c.byIntent[ch.IntentID]; ch.IntentID != "" && len(payments) > 0 {
+		return payments
+	}
+	return c.byCharge[ch.ID]
+}
+
+func (c *comparison) compareCharge(ch *provider.Charge) {
+	payments := c.paymentsFor(ch)
+	for _, p := range payments {
+		c.matchedPayment[p.ID] = true
+	}
+
+	if !c.stmt.Range.Includes(ch.Created) {
+		return
+	}
+	c.report.Charges++
+
+	// Failed and pending attempts settle nothing; the intent may still
+	// succeed with another charge
+	if ch.Status != provider.ChargeSucceeded {
+		return
+	}
+
+	if len(payments) == 0 {
+		if !c.records.HoldIntents[ch.IntentID] {
+			c.add(&Mismatch{
+				Kind:       KindMissingRecord,
+				Object:     ObjectCharge,
+				ProviderID: ch.ID,
+				Detail:     fmt.Sprintf("charge of %s (intent %s) has no payment", chargeAmount(ch, ch.Amount), ch.IntentID),
+			})
+		}
+		return
+	}
+
+	ids := paymentIDs(payments)
+	currency := payments[0].Amount.Currency
+	var totalMinor, refundedMinor int64
+	for _, p := range payments {
+		totalMinor += p.Amount.Amount
+		refundedMinor += p.RefundedAmount.Amount
+	}
+	total, refunded := money.New(totalMinor, currency), money.New(refundedMinor, currency)
+
+	if !strings.EqualFold(ch.Currency, currency) || total.Amount != ch.Amount {
+		c.add(&Mismatch{
+			Kind:       KindAmount,
+			Object:     ObjectCharge,
+			ProviderID: ch.ID,
+			PaymentIDs: ids,
+			Detail:     fmt.Sprintf("charged %s, payments total %s", chargeAmount(ch, ch.Amount), total),
+		})
+	}
+
+	open, recorded := 0, 0
+	for _, p := range payments {
+		switch p.Status {
+		case model.StatusPending, model.StatusProcessing, model.StatusFailed:
+			open++
+		}
+		if p.StripeChargeID.Valid && p.StripeChargeID.String == ch.ID {
+			recorded++
+		}
+	}
+
+	if open > 0 {
+		c.add(&Mismatch{
+			Charge:     ch,
+			Kind:       KindStatus,
+			Object:     ObjectCharge,
+			ProviderID: ch.ID,
+			PaymentIDs: ids,
+			Detail:     fmt.Sprintf("charge succeeded, %d of %d payments still open", open, len(payments)),
+			Repair:     RepairSettlePayments,
+		})
+	} else if recorded < len(payments) {
+		c.add(&Mismatch{
+			Kind:       KindStatus,
+			Object:     ObjectCharge,
+			ProviderID: ch.ID,
+			PaymentIDs: ids,
+			Detail:     "payments record a different charge",
+		})
+	}
+
+	switch {
+	case ch.AmountRefunded > refunded.Amount:
+		m := &Mismatch{
+			Kind:       KindAmount,
+			Object:     ObjectRefund,
+			ProviderID: ch.ID,
+			PaymentIDs: ids,
+			Detail:     fmt.Sprintf("refunded %s at the provider, %s recorded", chargeAmount(ch, ch.AmountRefunded), refunded),
+		}
+		// The refund webhook finds payments by charge and can only
+		// attribute a partial refund of a shared charge to one payment
+		if open == 0 && recorded == len(payments) && (len(payments) == 1 || ch.Refunded) {
+			m.Charge = ch
+			m.Repair = RepairSyncRefunds
+		}
+		c.add(m)
+	case ch.AmountRefunded < refunded.Amount:
+		c.add(&Mismatch{
+			Kind:       KindAmount,
+			Object:     ObjectRefund,
+			ProviderID: ch.ID,
+			PaymentIDs: ids,
+			Detail:     fmt.Sprintf("refunded %s at the provider, %s recorded", chargeAmount(ch, ch.AmountRefunded), refunded),
+		})
+	case ch.Refunded:
+		for _, p := range payments {
+			if p.Status != model.StatusRefunded {
+				c.add(&Mismatch{
+					Kind:       KindStatus,
+					Object:     ObjectRefund,
+					ProviderID: ch.ID,
+					PaymentIDs: []int64{p.ID},
+					Detail:     fmt.Sprintf("charge fully refunded, payment is %s", p.Status),
+				})
+			}
+		}
+	}
+}
+
+// compareRefund checks refunds whose charge the statement does not cover;
+// refunds of listed charges are compared through the charge's total
+func (c *comparison) compareRefund(r *provider.Refund) {
+	if !c.stmt.Range.Includes(r.Created) {
+		return
+	}
+	c.report.Refunds++
+
+	if r.Status != provider.RefundSucceeded {
+		return
+	}
+
+	ch, listed := c.chargesByID[r.ChargeID]
+	payments := c.byCharge[r.ChargeID]
+	if listed {
+		payments = c.paymentsFor(ch)
+	}
+
+	if len(payments) == 0 {
+		if !listed || !c.records.HoldIntents[ch.IntentID] {
+			c.add(&Mismatch{
+				Kind:       KindMissingRecord,
+				Object:     ObjectRefund,
+				ProviderID: r.ID,
+				Detail:     fmt.Sprintf("refund of %s on charge %s has no payment", money.New(r.Amount, strings.ToUpper(r.Currency)), r.ChargeID),
+			})
+		}
+		return
+	}
+	if listed {
+		return
+	}
+
+	// Without the charge only the refunds seen so far are known, which
+	// the recorded total must at least cover
+	seen := int64(0)
+	for _, other := range c.stmt.Refunds {
+		if other.ChargeID == r.ChargeID && other.Status == provider.RefundSucceeded {
+			seen += other.Amount
+		}
+	}
+	refunded := int64(0)
+	for _, p := range payments {
+		refunded += p.RefundedAmount.Amount
+	}
+	if seen > refunded && c.lastRefundOf(r) {
+		c.add(&Mismatch{
+			Kind:       KindAmount,
+			Object:     ObjectRefund,
+			ProviderID: r.ChargeID,
+			PaymentIDs: paymentIDs(payments),
+			Detail:     fmt.Sprintf("refunded at least %s at the provider, %s recorded", money.New(seen, payments[0].Amount.Currency), money.New(refunded, payments[0].Amount.Currency)),
+		})
+	}
+}
+
+// lastRefundOf reports whether r is the charge's last refund in the
+// range, so a charge is reported once
+func (c *comparison) lastRefundOf(r *provider.Refund) bool {
+	last := r
+	for _, other := range c.stmt.Refunds {
+		if other.ChargeID == r.ChargeID && c.stmt.Range.Includes(other.Created) {
+			last = other
+		}
+	}
+	return last == r
+}
+
+// payoutFor returns the payout a transfer paid: the one that recorded it,
+// or the one its metadata names if that payout has no transfer yet
+func (c *comparison) payoutFor(t *provider.Transfer) *model.Payout {
+	if p := c.payoutsByXfer[t.ID]; p != nil {
+		return p
+	}
+
+	if id, ok := metadataID(t, "payout_id"); ok {
+		if p := c.payoutsByID[id]; p != nil && !p.StripeTransferID.Valid {
+			return p
+		}
+	}
+
+	if orderID, ok := metadataID(t, "order_id"); ok {
+		for _, p := range c.records.Payouts {
+			if p.OrderID == orderID && p.WalletID == 0 && !p.StripeTransferID.Valid {
+				return p
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *comparison) compareTransfer(t *provider.Transfer) {
+	payout := c.payoutFor(t)
+	if payout != nil {
+		c.matchedPayout[payout.ID] = true
+	}
+
+	if !c.stmt.Range.Includes(t.Created) {
+		return
+	}
+	c.report.Transfers++
+
+	if payout == nil {
+		c.add(&Mismatch{
+			Kind:       KindMissingRecord,
+			Object:     ObjectTransfer,
+			ProviderID: t.ID,
+			Detail:     fmt.Sprintf("transfer of %s to %q has no payout", transferAmount(t), t.Destination),
+		})
+		return
+	}
+
+	if !strings.EqualFold(t.Currency, payout.Currency) || t.Amount != payout.Amount.Amount {
+		c.add(&Mismatch{
+			Kind:       KindAmount,
+			Object:     ObjectTransfer,
+			ProviderID: t.ID,
+			PayoutID:   payout.ID,
+			Detail:     fmt.Sprintf("transferred %s, payout is %s", transferAmount(t), payout.Amount),
+		})
+	}
+
+	status := &Mismatch{Kind: KindStatus, Object: ObjectTransfer, ProviderID: t.ID, PayoutID: payout.ID}
+	switch {
+	case t.Reversed && payout.Status != model.PayoutStatusReversed && payout.Status != model.PayoutStatusFailed:
+		status.Detail = fmt.Sprintf("transfer reversed, payout is %s", payout.Status)
+	case t.Reversed:
+		return
+	case payout.Status == model.PayoutStatusPending || payout.Status == model.PayoutStatusProcessing:
+		status.Detail = fmt.Sprintf("transfer sent, payout is %s", payout.Status)
+		status.Transfer = t
+		status.Repair = RepairConfirmPayout
+	case payout.Status == model.PayoutStatusFailed:
+		// A failed wallet payout went back to the wallet; paying it out
+		// again would pay the seller twice
+		status.Detail = "transfer sent, payout failed"
+	case payout.Status == model.PayoutStatusReversed:
+		status.Detail = "payout reversed, transfer was not"
+	default:
+		return
+	}
+	c.add(status)
+}
+
+// findUnmatched reports settled payments and sent payouts in the range
+// that no provider object accounts for
+func (c *comparison) findUnmatched() {
+	for _, p := range c.records.Payments {
+		if c.matchedPayment[p.ID] || (!p.IsSuccessful() && p.Status != model.StatusRefunded) {
+			continue
+		}
+
+		settled := p.CreatedAt
+		if p.ProcessedAt.Valid {
+			settled = p.ProcessedAt.Time
+		}
+		if !c.stmt.Range.Includes(settled) {
+			continue
+		}
+
+		c.add(&Mismatch{
+			Kind:       KindMissingAtProvider,
+			Object:     ObjectCharge,
+			ProviderID: p.StripeChargeID.String,
+			PaymentIDs: []int64{p.ID},
+			Detail:     fmt.Sprintf("payment %s of %s is %s without a provider charge", p.PaymentID, p.Amount, p.Status),
+		})
+	}
+
+	for _, p := range c.records.Payouts {
+		if c.matchedPayout[p.ID] || p.Status == model.PayoutStatusFailed || !c.stmt.Range.Includes(p.CreatedAt) {
+			continue
+		}
+
+		c.add(&Mismatch{
+			Kind:       KindMissingAtProvider,
+			Object:     ObjectTransfer,
+			ProviderID: p.StripeTransferID.String,
+			PayoutID:   p.ID,
+			Detail:     fmt.Sprintf("payout %s of %s is %s without a provider transfer", p.PayoutID, p.Amount, p.Status),
+		})
+	}
+}
+
+func paymentIDs(payments []*model.Payment) []int64 {
+	ids := make([]int64, len(payments))
+	for i, p := range payments {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func metadataID(t *provider.Transfer, key string) (int64, bool) {
+	id, err := strconv.ParseInt(t.Metadata[key], 10, 64)
+	return id, err == nil && id > 0
+}
+
+// chargeAmount puts an amount in the charge's currency
+func chargeAmount(ch *provider.Charge, amount int64) money.Money {
+	return money.New(amount, strings.ToUpper(ch.Currency))
+}
+
+func transferAmount(t *provider.Transfer) money.Money {
+	return money.New(t.Amount, strings.ToUpper(t.Currency))
+}
@@ -0,0 +1,239 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+var day = provider.ListParams{
+	From: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+	To:   time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+}
+
+// fakeAt stamps the fake's new objects at offset into day
+func fakeAt(f *provider.Fake, offset time.Duration) {
+	f.SetClock(func() time.Time { return day.From.Add(offset) })
+}
+
+func charge(t *testing.T, f *provider.Fake, amount int64) *provider.Intent {
+	t.Helper()
+	intent, err := f.CreateIntent(context.Background(), provider.IntentParams{Amount: amount, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	return intent
+}
+
+func settledPayment(id int64, intent *provider.Intent, amount int64, at time.Time) *model.Payment {
+	return &model.Payment{
+		ID:                    id,
+		PaymentID:             "PAY-test",
+		OrderID:               id,
+		Status:                model.StatusSucceeded,
+		Currency:              "USD",
+		Amount:                money.New(amount, "USD"),
+		RefundedAmount:        money.Zero("USD"),
+		StripePaymentIntentID: sql.NullString{String: intent.ID, Valid: true},
+		StripeChargeID:        sql.NullString{String: intent.Charge.ID, Valid: true},
+		CreatedAt:             at,
+		ProcessedAt:           sql.NullTime{Time: at, Valid: true},
+	}
+}
+
+func paidPayout(id int64, tr *provider.Transfer, at time.Time) *model.Payout {
+	return &model.Payout{
+		ID:               id,
+		PayoutID:         "PO-test",
+		Status:           model.PayoutStatusPaid,
+		Currency:         "USD",
+		Amount:           money.New(tr.Amount, "USD"),
+		StripeTransferID: sql.NullString{String: tr.ID, Valid: true},
+		CreatedAt:        at,
+	}
+}
+
+func run(t *testing.T, f *provider.Fake, records *Records) *Report {
+	t.Helper()
+	stmt, err := Fetch(context.Background(), f, day)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	return Compare(stmt, records)
+}
+
+func find(report *Report, kind, object string) *Mismatch {
+	for _, m := range report.Mismatches {
+		if m.Kind == kind && m.Object == object {
+			return m
+		}
+	}
+	return nil
+}
+
+func TestReconcileMatchingRecords(t *testing.T) {
+	ctx := context.Background()
+	f := provider.NewFake("")
+	fakeAt(f, 10*time.Hour)
+	at := day.From.Add(10 * time.Hour)
+
+	intent := charge(t, f, 12500)
+	if _, err := f.CreateRefund(ctx, provider.RefundParams{ChargeID: intent.Charge.ID, Amount: 2500}); err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+	tr, err := f.CreateTransfer(ctx, provider.TransferParams{Amount: 9000, Currency: "usd", Metadata: map[string]string{"order_id": "1"}})
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+
+	payment := settledPayment(1, intent, 12500, at)
+	payment.Status = model.StatusPartiallyRefunded
+	payment.RefundedAmount = money.New(2500, "USD")
+
+	report := run(t, f, &Records{
+		Payments: []*model.Payment{payment},
+		Payouts:  []*model.Payout{paidPayout(1, tr, at)},
+	})
+
+	if report.Charges != 1 || report.Refunds != 1 || report.Transfers != 1 {
+		t.Errorf("checked %d/%d/%d objects, want 1/1/1", report.Charges, report.Refunds, report.Transfers)
+	}
+	for _, m := range report.Mismatches {
+		t.Errorf("unexpected mismatch %+v", m)
+	}
+}
+
+func TestReconcileReportsMismatches(t *testing.T) {
+	ctx := context.Background()
+	f := provider.NewFake("")
+	fakeAt(f, 10*time.Hour)
+	at := day.From.Add(10 * time.Hour)
+
+	unknown := charge(t, f, 5000)
+	short := charge(t, f, 8000)
+	open := charge(t, f, 7000)
+	refunded := charge(t, f, 6000)
+	if _, err := f.CreateRefund(ctx, provider.RefundParams{ChargeID: refunded.Charge.ID}); err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+
+	sent, err := f.CreateTransfer(ctx, provider.TransferParams{Amount: 4000, Currency: "usd", Metadata: map[string]string{"payout_id": "7"}})
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	if _, err := f.CreateTransfer(ctx, provider.TransferParams{Amount: 3000, Currency: "usd"}); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+
+	pendingOpen := settledPayment(3, open, 7000, at)
+	pendingOpen.Status = model.StatusPending
+	pendingOpen.StripeChargeID = sql.NullString{}
+
+	stuck := &model.Payout{ID: 7, PayoutID: "PO-7", Status: model.PayoutStatusPending, Currency: "USD", Amount: money.New(4000, "USD"), CreatedAt: at}
+	orphan := &model.Payout{ID: 8, PayoutID: "PO-8", Status: model.PayoutStatusPaid, Currency: "USD", Amount: money.New(1000, "USD"), CreatedAt: at}
+
+	ghost := settledPayment(5, &provider.Intent{ID: "pi_elsewhere", Charge: &provider.Charge{ID: "ch_elsewhere"}}, 900, at)
+
+	report := run(t, f, &Records{
+		Payments: []*model.Payment{
+			settledPayment(2, short, 8500, at),
+			pendingOpen,
+			settledPayment(4, refunded, 6000, at),
+			ghost,
+		},
+		Payouts: []*model.Payout{stuck, orphan},
+	})
+
+	tests := []struct {
+		kind, object, providerID, repair string
+	}{
+		{KindMissingRecord, ObjectCharge, unknown.Charge.ID, ""},
+		{KindAmount, ObjectCharge, short.Charge.ID, ""},
+		{KindStatus, ObjectCharge, open.Charge.ID, RepairSettlePayments},
+		{KindAmount, ObjectRefund, refunded.Charge.ID, RepairSyncRefunds},
+		{KindStatus, ObjectTransfer, sent.ID, RepairConfirmPayout},
+		{KindMissingRecord, ObjectTransfer, "", ""},
+		{KindMissingAtProvider, ObjectCharge, ghost.StripeChargeID.String, ""},
+		{KindMissingAtProvider, ObjectTransfer, "", ""},
+	}
+
+	for _, tt := range tests {
+		found := false
+		for _, m := range report.Mismatches {
+			if m.Kind != tt.kind || m.Object != tt.object || (tt.providerID != "" && m.ProviderID != tt.providerID) {
+				continue
+			}
+			found = true
+			if m.Repair != tt.repair {
+				t.Errorf("%s %s %s: repair = %q, want %q", tt.kind, tt.object, m.ProviderID, m.Repair, tt.repair)
+			}
+		}
+		if !found {
+			t.Errorf("missing %s %s mismatch %s", tt.kind, tt.object, tt.providerID)
+		}
+	}
+
+	if len(report.Mismatches) != len(tests) {
+		for _, m := range report.Mismatches {
+			t.Logf("mismatch %+v", m)
+		}
+		t.Errorf("got %d mismatches, want %d", len(report.Mismatches), len(tests))
+	}
+
+	if m := find(report, KindStatus, ObjectTransfer); m == nil || m.Transfer == nil || m.PayoutID != 7 {
+		t.Errorf("payout repair should carry the transfer and payout 7, got %+v", m)
+	}
+	if report.Unresolved() != len(report.Mismatches) {
+		t.Errorf("nothing was repaired, but %d of %d are unresolved", report.Unresolved(), len(report.Mismatches))
+	}
+}
+
+func TestReconcileRangeEdges(t *testing.T) {
+	f := provider.NewFake("")
+
+	// Settled just before midnight, charged just after: matched through
+	// the slack, not reported on either side
+	fakeAt(f, 24*time.Hour+20*time.Minute)
+	late := charge(t, f, 4000)
+
+	// Two days earlier: outside the statement entirely
+	fakeAt(f, -48*time.Hour)
+	charge(t, f, 3000)
+
+	// A bid hold captured in the range has no payment
+	fakeAt(f, 12*time.Hour)
+	hold, err := f.CreateIntent(context.Background(), provider.IntentParams{Amount: 2000, Currency: "usd", ManualCapture: true})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if _, err := f.CaptureIntent(context.Background(), hold.ID, 0); err != nil {
+		t.Fatalf("CaptureIntent: %v", err)
+	}
+
+	report := run(t, f, &Records{
+		HoldIntents: map[string]bool{hold.ID: true},
+		Payments:    []*model.Payment{settledPayment(1, late, 4000, day.To.Add(-5*time.Minute))},
+	})
+
+	if report.Charges != 1 {
+		t.Errorf("checked %d charges, want only the hold's", report.Charges)
+	}
+	for _, m := range report.Mismatches {
+		t.Errorf("unexpected mismatch %+v", m)
+	}
+}
+
+func TestFetchRejectsEmptyRange(t *testing.T) {
+	f := provider.NewFake("")
+	if _, err := Fetch(context.Background(), f, provider.ListParams{From: day.To, To: day.From}); err == nil {
+		t.Error("an empty range should be rejected")
+	}
+	if n := f.CallCount("ListCharges"); n != 0 {
+		t.Errorf("ListCharges called %d times for an empty range", n)
+	}
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// CreateAuthorization creates a new payment authorization (hold) for a bid
+func (r *PaymentRepository) CreateAuthorization(ctx context.Context, auth *model.Authorization) (*model.Authorization, error) {
+	query := `
+		INSERT INTO payment_authorizations (
+			bid_id, user_id,
+			stripe_payment_intent_id, stripe_customer_id, stripe_payment_method_id,
+			amount, currency, status, failure_reason, authorized_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		auth.BidID, auth.UserID,
+		auth.StripePaymentIntentID, auth.StripeCustomerID, auth.StripePaymentMethodID,
+		auth.Amount, auth.Currency, auth.Status, auth.FailureReason, auth.AuthorizedAt,
+	).Scan(&auth.ID, &auth.CreatedAt, &auth.UpdatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authorization: %w", err)
+	}
+
+	return auth, nil
+}
+
+// GetAuthorizationByBidID retrieves the authorization placed for a bid
+func (r *PaymentRepository) GetAuthorizationByBidID(ctx context.Context, bidID int64) (*model.Authorization, error) {
+	query := `
+		SELECT
+			id, bid_id, user_id, match_id,
+			stripe_payment_intent_id, stripe_customer_id, stripe_payment_method_id,
+			amount, captured_amount, currency, status, failure_reason,
+			authorized_at, captured_at, released_at,
+			created_at, updated_at
+		FROM payment_authorizations
+		WHERE bid_id = $1
+	`
+
+	auth := &model.Authorization{}
+	err := r.db.QueryRow(ctx, query, bidID).Scan(
+		&auth.ID, &auth.BidID, &auth.UserID, &auth.MatchID,
+		&auth.StripePaymentIntentID, &auth.StripeCustomerID, &auth.StripePaymentMethodID,
+		&auth.Amount, &auth.CapturedAmount, &auth.Currency, &auth.Status, &auth.FailureReason,
+		&auth.AuthorizedAt, &auth.CapturedAt, &auth.ReleasedAt,
+		&auth.CreatedAt, &auth.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("authorization not found")
+		}
+		return nil, fmt.Errorf("failed to get authorization: %w", err)
+	}
+
+	labelAuthorization(auth)
+	return auth, nil
+}
+
+// MarkAuthorizationCaptured records a capture against the hold
+func (r *PaymentRepository) MarkAuthorizationCaptured(ctx context.Context, authID, matchID int64, capturedAmount money.Money) error {
+	query := `
+		UPDATE payment_authorizations
+		SET
+			status = 'captured',
+			match_id = $1,
+			captured_amount = $2,
+			captured_at = NOW()
+		WHERE id = $3 AND status = 'authorized'
+	`
+
+	result, err := r.db.Exec(ctx, query, matchID, capturedAmount, authID)
+	if err != nil {
+		return fmt.Errorf("failed to capture authorization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("authorization not found or not held")
+	}
+
+	return nil
+}
+
+// MarkAuthorizationReleased records that the hold was canceled
+func (r *PaymentRepository) MarkAuthorizationReleased(ctx context.Context, authID int64, reason string) error {
+	query := `
+		UPDATE payment_authorizations
+		SET
+			status = 'released',
+			failure_reason = NULLIF($1, ''),
+			released_at = NOW()
+		WHERE id = $2 AND status = 'authorized'
+	`
+
+	result, err := r.db.Exec(ctx, query, reason, authID)
+	if err != nil {
+		return fmt.Errorf("failed to release authorization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("authorization not found or not held")
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+)
+
+const chargebackColumns = `
+	id, stripe_dispute_id, stripe_charge_id, payment_id, order_id,
+	amount, currency, reason, status, closed_at, created_at, updated_at
+`
+
+func scanChargeback(row pgx.Row) (*model.Chargeback, error) {
+	cb := &model.Chargeback{}
+	err := row.Scan(
+		&cb.ID, &cb.StripeDisputeID, &cb.StripeChargeID, &cb.PaymentID, &cb.OrderID,
+		&cb.Amount, &cb.Currency, &cb.Reason, &cb.Status, &cb.ClosedAt, &cb.CreatedAt, &cb.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	cb.Amount = cb.Amount.WithCurrency(cb.Currency)
+	return cb, nil
+}
+
+// OpenChargeback records a chargeback on an order payment. A chargeback is
+// recorded once per payment; the returned flag is false when it already was.
+func (r *PaymentRepository) OpenChargeback(ctx context.Context, cb *model.Chargeback) (bool, error) {
+	query := `
+		INSERT INTO chargebacks (stripe_dispute_id, stripe_charge_id, payment_id, order_id, amount, currency, reason, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (stripe_dispute_id, payment_id) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		cb.StripeDisputeID, cb.StripeChargeID, cb.PaymentID, cb.OrderID,
+		cb.Amount, cb.Currency, cb.Reason, model.ChargebackStatusOpen,
+	).Scan(&cb.ID, &cb.CreatedAt, &cb.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record chargeback: %w", err)
+	}
+	cb.Status = model.ChargebackStatusOpen
+
+	return true, nil
+}
+
+// CloseChargebacks closes the open chargebacks of a Stripe dispute with
+// status and returns all of the dispute's chargebacks, so that a retried
+// close finishes what an earlier attempt started
+func (r *PaymentRepository) CloseChargebacks(ctx context.Context, disputeID, status string) ([]*model.Chargeback, error) {
+	_, err := r.db.Exec(ctx, `
+		UPDATE chargebacks
+		SET status = $1, closed_at = NOW()
+		WHERE stripe_dispute_id = $2 AND status = $3
+	`, status, disputeID, model.ChargebackStatusOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close chargebacks: %w", err)
+	}
+
+	query := `SELECT ` + chargebackColumns + ` FROM chargebacks WHERE stripe_dispute_id = $1 ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chargebacks: %w", err)
+	}
+	defer rows.Close()
+
+	chargebacks := make([]*model.Chargeback, 0)
+	for rows.Next() {
+		cb, err := scanChargeback(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chargeback: %w", err)
+		}
+		chargebacks = append(chargebacks, cb)
+	}
+
+	return chargebacks, rows.Err()
+}
+
+// GetPayoutHold reports whether an order's proceeds can be paid out: the
+// order must be completed with no dispute or chargeback open
+func (r *PaymentRepository) GetPayoutHold(ctx context.Context, orderID int64) (*model.PayoutHold, error) {
+	query := `
+		SELECT
+			o.status, o.seller_id,
+			EXISTS (SELECT 1 FROM disputes d WHERE d.order_id = o.id AND d.status <> 'resolved'),
+			EXISTS (SELECT 1 FROM chargebacks c WHERE c.order_id = o.id AND c.status = $2)
+		FROM orders o
+		WHERE o.id = $1
+	`
+
+	hold := &model.PayoutHold{}
+	err := r.db.QueryRow(ctx, query, orderID, model.ChargebackStatusOpen).Scan(
+		&hold.OrderStatus, &hold.SellerID, &hold.OpenDispute, &hold.OpenChargeback,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("order not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout hold: %w", err)
+	}
+
+	return hold, nil
+}
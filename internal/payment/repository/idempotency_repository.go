@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+)
+
+// BeginIdempotentRequest claims an idempotency key for a request and
+// reports whether the request should run. A key stuck in processing for
+// longer than stale (the handler crashed) is claimed again by the same
+// request.
+func (r *PaymentRepository) BeginIdempotentRequest(ctx context.Context, k *model.IdempotencyKey, stale time.Duration) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (scope, key, request_hash, status)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET updated_at = NOW()
+		WHERE idempotency_keys.status = $4
+			AND idempotency_keys.request_hash = $3
+			AND idempotency_keys.updated_at < NOW() - make_interval(secs => $5)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		k.Scope, k.Key, k.RequestHash, model.IdempotencyProcessing, stale.Seconds(),
+	).Scan(&k.CreatedAt, &k.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil // Already used
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record idempotency key: %w", err)
+	}
+
+	k.Status = model.IdempotencyProcessing
+	return true, nil
+}
+
+// GetIdempotencyKey returns a used idempotency key, or nil if there is none
+func (r *PaymentRepository) GetIdempotencyKey(ctx context.Context, scope, key string) (*model.IdempotencyKey, error) {
+	query := `
+		SELECT scope, key, request_hash, status, response, created_at, updated_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`
+
+	k := &model.IdempotencyKey{}
+	err := r.db.QueryRow(ctx, query, scope, key).Scan(
+		&k.Scope, &k.Key, &k.RequestHash, &k.Status, &k.Response, &k.CreatedAt, &k.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return k, nil
+}
+
+// CompleteIdempotentRequest stores the response replayed to retries
+func (r *PaymentRepository) CompleteIdempotentRequest(ctx context.Context, scope, key string, response []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = $1, response = $2
+		WHERE scope = $3 AND key = $4
+	`
+
+	if _, err := r.db.Exec(ctx, query, model.IdempotencyCompleted, response, scope, key); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotentRequest frees a key whose request failed, so a retry
+// with it runs again
+func (r *PaymentRepository) ReleaseIdempotentRequest(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status = $3`
+
+	if _, err := r.db.Exec(ctx, query, scope, key, model.IdempotencyProcessing); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
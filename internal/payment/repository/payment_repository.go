@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 type PaymentRepository struct {
@@ -21,18 +23,18 @@ func NewPaymentRepository(db *pgxpool.Pool) *PaymentRepository {
 func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *model.Payment) (*model.Payment, error) {
 	query := `
 		INSERT INTO payments (
-			payment_id, order_id, user_id,
+			payment_id, order_id, user_id, checkout_id,
 			stripe_payment_intent_id, stripe_customer_id,
 			amount, currency, status
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
 		)
 		RETURNING id, created_at, updated_at
 	`
 
 	err := r.db.QueryRow(
 		ctx, query,
-		payment.PaymentID, payment.OrderID, payment.UserID,
+		payment.PaymentID, payment.OrderID, payment.UserID, payment.CheckoutID,
 		payment.StripePaymentIntentID, payment.StripeCustomerID,
 		payment.Amount, payment.Currency, payment.Status,
 	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
@@ -44,6 +46,45 @@ func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *model.Pa
 	return payment, nil
 }
 
+// CreateCheckoutPayments creates the payments of a checkout atomically,
+// one per order
+func (r *PaymentRepository) CreateCheckoutPayments(ctx context.Context, payments []*model.Payment) error {
+	query := `
+		INSERT INTO payments (
+			payment_id, order_id, user_id, checkout_id,
+			stripe_payment_intent_id, stripe_customer_id,
+			amount, currency, status
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		RETURNING id, payment_id, created_at, updated_at
+	`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, payment := range payments {
+		err := tx.QueryRow(
+			ctx, query,
+			payment.PaymentID, payment.OrderID, payment.UserID, payment.CheckoutID,
+			payment.StripePaymentIntentID, payment.StripeCustomerID,
+			payment.Amount, payment.Currency, payment.Status,
+		).Scan(&payment.ID, &payment.PaymentID, &payment.CreatedAt, &payment.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create payment for order %d: %w", payment.OrderID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // GetPaymentByID retrieves a payment by ID
 func (r *PaymentRepository) GetPaymentByID(ctx context.Context, paymentID int64) (*model.Payment, error) {
 	query := `
@@ -54,7 +95,7 @@ func (r *PaymentRepository) GetPaymentByID(ctx context.Context, paymentID int64)
 			payment_method, card_last4, card_brand,
 			refunded_amount, refund_reason,
 			processed_at, refunded_at,
-			created_at, updated_at
+			created_at, updated_at, checkout_id
 		FROM payments
 		WHERE id = $1
 	`
@@ -67,7 +108,7 @@ func (r *PaymentRepository) GetPaymentByID(ctx context.Context, paymentID int64)
 		&payment.PaymentMethod, &payment.CardLast4, &payment.CardBrand,
 		&payment.RefundedAmount, &payment.RefundReason,
 		&payment.ProcessedAt, &payment.RefundedAt,
-		&payment.CreatedAt, &payment.UpdatedAt,
+		&payment.CreatedAt, &payment.UpdatedAt, &payment.CheckoutID,
 	)
 
 	if err != nil {
@@ -77,6 +118,7 @@ func (r *PaymentRepository) GetPaymentByID(ctx context.Context, paymentID int64)
 		return nil, fmt.Errorf("failed to get payment: %w", err)
 	}
 
+	labelPayment(payment)
 	return payment, nil
 }
 
@@ -90,7 +132,7 @@ func (r *PaymentRepository) GetPaymentByOrderID(ctx context.Context, orderID int
 			payment_method, card_last4, card_brand,
 			refunded_amount, refund_reason,
 			processed_at, refunded_at,
-			created_at, updated_at
+			created_at, updated_at, checkout_id
 		FROM payments
 		WHERE order_id = $1
 	`
@@ -103,7 +145,7 @@ func (r *PaymentRepository) GetPaymentByOrderID(ctx context.Context, orderID int
 		&payment.PaymentMethod, &payment.CardLast4, &payment.CardBrand,
 		&payment.RefundedAmount, &payment.RefundReason,
 		&payment.ProcessedAt, &payment.RefundedAt,
-		&payment.CreatedAt, &payment.UpdatedAt,
+		&payment.CreatedAt, &payment.UpdatedAt, &payment.CheckoutID,
 	)
 
 	if err != nil {
@@ -113,6 +155,7 @@ func (r *PaymentRepository) GetPaymentByOrderID(ctx context.Context, orderID int
 		return nil, fmt.Errorf("failed to get payment: %w", err)
 	}
 
+	labelPayment(payment)
 	return payment, nil
 }
 
@@ -166,36 +209,120 @@ func (r *PaymentRepository) UpdatePaymentWithCharge(ctx context.Context, payment
 	return nil
 }
 
-// UpdatePaymentRefund updates payment refund information
-func (r *PaymentRepository) UpdatePaymentRefund(ctx context.Context, paymentID int64, refundedAmount float64, reason string) error {
+// UpdateCheckoutPaymentsWithCharge records the shared charge on every
+// payment of a checkout
+func (r *PaymentRepository) UpdateCheckoutPaymentsWithCharge(ctx context.Context, checkoutID int64, chargeID, paymentMethod, cardLast4, cardBrand string) error {
 	query := `
 		UPDATE payments
 		SET 
-			refunded_amount = refunded_amount + $1,
+			stripe_charge_id = $1,
+			payment_method = $2,
+			card_last4 = $3,
+			card_brand = $4,
+			status = $5,
+			processed_at = NOW()
+		WHERE checkout_id = $6
+	`
+
+	result, err := r.db.Exec(
+		ctx, query,
+		chargeID, paymentMethod, cardLast4, cardBrand,
+		model.StatusSucceeded, checkoutID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update checkout payments: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("checkout payments not found")
+	}
+
+	return nil
+}
+
+// RefundPayment refunds a payment while holding its row lock, so concurrent
+// refunds of one payment are validated and recorded one at a time. refund
+// gets the locked payment and returns the amount it refunded, which then
+// raises the payment's refunded total. The total only ever rises, so a
+// charge.refunded webhook that arrived first is not counted twice. It
+// returns the payment as it was before the refund.
+func (r *PaymentRepository) RefundPayment(
+	ctx context.Context,
+	paymentID int64,
+	reason string,
+	refund func(payment *model.Payment) (money.Money, error),
+) (*model.Payment, money.Money, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, money.Money{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+		SELECT 
+			id, payment_id, order_id, user_id,
+			stripe_payment_intent_id, stripe_charge_id, stripe_customer_id,
+			amount, currency, status,
+			payment_method, card_last4, card_brand,
+			refunded_amount, refund_reason,
+			processed_at, refunded_at,
+			created_at, updated_at, checkout_id
+		FROM payments
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	payment := &model.Payment{}
+	err = tx.QueryRow(ctx, query, paymentID).Scan(
+		&payment.ID, &payment.PaymentID, &payment.OrderID, &payment.UserID,
+		&payment.StripePaymentIntentID, &payment.StripeChargeID, &payment.StripeCustomerID,
+		&payment.Amount, &payment.Currency, &payment.Status,
+		&payment.PaymentMethod, &payment.CardLast4, &payment.CardBrand,
+		&payment.RefundedAmount, &payment.RefundReason,
+		&payment.ProcessedAt, &payment.RefundedAt,
+		&payment.CreatedAt, &payment.UpdatedAt, &payment.CheckoutID,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, money.Money{}, fmt.Errorf("payment not found")
+		}
+		return nil, money.Money{}, fmt.Errorf("failed to lock payment: %w", err)
+	}
+	labelPayment(payment)
+
+	amount, err := refund(payment)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	update := `
+		UPDATE payments
+		SET 
+			refunded_amount = GREATEST(refunded_amount, $1),
 			refund_reason = $2,
 			refunded_at = NOW(),
 			status = CASE 
-				WHEN (refunded_amount + $1) >= amount THEN $3
+				WHEN GREATEST(refunded_amount, $1) >= amount THEN $3
 				ELSE $4
 			END
 		WHERE id = $5
 	`
 
-	result, err := r.db.Exec(
-		ctx, query,
-		refundedAmount, reason,
+	_, err = tx.Exec(
+		ctx, update,
+		payment.RefundedAmount.Add(amount), reason,
 		model.StatusRefunded, model.StatusPartiallyRefunded,
 		paymentID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update refund: %w", err)
+		return nil, money.Money{}, fmt.Errorf("failed to update refund: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("payment not found")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, money.Money{}, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	return payment, amount, nil
 }
 
 // ListPaymentsByUser lists payments for a user
@@ -208,7 +335,7 @@ func (r *PaymentRepository) ListPaymentsByUser(ctx context.Context, userID int64
 			payment_method, card_last4, card_brand,
 			refunded_amount, refund_reason,
 			processed_at, refunded_at,
-			created_at, updated_at
+			created_at, updated_at, checkout_id
 		FROM payments
 		WHERE user_id = $1
 	`
@@ -260,11 +387,12 @@ func (r *PaymentRepository) ListPaymentsByUser(ctx context.Context, userID int64
 			&payment.PaymentMethod, &payment.CardLast4, &payment.CardBrand,
 			&payment.RefundedAmount, &payment.RefundReason,
 			&payment.ProcessedAt, &payment.RefundedAt,
-			&payment.CreatedAt, &payment.UpdatedAt,
+			&payment.CreatedAt, &payment.UpdatedAt, &payment.CheckoutID,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan payment: %w", err)
 		}
+		labelPayment(payment)
 		payments = append(payments, payment)
 	}
 
@@ -273,40 +401,15 @@ func (r *PaymentRepository) ListPaymentsByUser(ctx context.Context, userID int64
 
 // ========== Payout Methods ==========
 
-// CreatePayout creates a new payout
-func (r *PaymentRepository) CreatePayout(ctx context.Context, payout *model.Payout) (*model.Payout, error) {
-	query := `
-		INSERT INTO payouts (
-			payout_id, order_id, seller_id, payment_id,
-			stripe_account_id, amount, currency, status
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
-		)
-		RETURNING id, created_at, updated_at
-	`
-
-	err := r.db.QueryRow(
-		ctx, query,
-		payout.PayoutID, payout.OrderID, payout.SellerID, payout.PaymentID,
-		payout.StripeAccountID, payout.Amount, payout.Currency, payout.Status,
-	).Scan(&payout.ID, &payout.CreatedAt, &payout.UpdatedAt)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create payout: %w", err)
-	}
-
-	return payout, nil
-}
-
 // GetPayoutByID retrieves a payout by ID
 func (r *PaymentRepository) GetPayoutByID(ctx context.Context, payoutID int64) (*model.Payout, error) {
 	query := `
 		SELECT 
-			id, payout_id, order_id, seller_id, payment_id,
+			id, payout_id, COALESCE(order_id, 0), seller_id, COALESCE(payment_id, 0),
 			stripe_transfer_id, stripe_account_id,
 			amount, currency, status,
 			failure_reason, processed_at,
-			created_at, updated_at
+			created_at, updated_at, COALESCE(wallet_id, 0)
 		FROM payouts
 		WHERE id = $1
 	`
@@ -317,7 +420,7 @@ func (r *PaymentRepository) GetPayoutByID(ctx context.Context, payoutID int64) (
 		&payout.StripeTransferID, &payout.StripeAccountID,
 		&payout.Amount, &payout.Currency, &payout.Status,
 		&payout.FailureReason, &payout.ProcessedAt,
-		&payout.CreatedAt, &payout.UpdatedAt,
+		&payout.CreatedAt, &payout.UpdatedAt, &payout.WalletID,
 	)
 
 	if err != nil {
@@ -327,6 +430,7 @@ func (r *PaymentRepository) GetPayoutByID(ctx context.Context, payoutID int64) (
 		return nil, fmt.Errorf("failed to get payout: %w", err)
 	}
 
+	labelPayout(payout)
 	return payout, nil
 }
 
@@ -357,11 +461,11 @@ func (r *PaymentRepository) UpdatePayoutStatus(ctx context.Context, payoutID int
 func (r *PaymentRepository) ListPayoutsBySeller(ctx context.Context, sellerID int64, status string, page, pageSize int32) ([]*model.Payout, int64, error) {
 	baseQuery := `
 		SELECT 
-			id, payout_id, order_id, seller_id, payment_id,
+			id, payout_id, COALESCE(order_id, 0), seller_id, COALESCE(payment_id, 0),
 			stripe_transfer_id, stripe_account_id,
 			amount, currency, status,
 			failure_reason, processed_at,
-			created_at, updated_at
+			created_at, updated_at, COALESCE(wallet_id, 0)
 		FROM payouts
 		WHERE seller_id = $1
 	`
@@ -411,13 +515,32 @@ func (r *PaymentRepository) ListPayoutsBySeller(ctx context.Context, sellerID in
 			&payout.StripeTransferID, &payout.StripeAccountID,
 			&payout.Amount, &payout.Currency, &payout.Status,
 			&payout.FailureReason, &payout.ProcessedAt,
-			&payout.CreatedAt, &payout.UpdatedAt,
+			&payout.CreatedAt, &payout.UpdatedAt, &payout.WalletID,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan payout: %w", err)
 		}
+		labelPayout(payout)
 		payouts = append(payouts, payout)
 	}
 
 	return payouts, total, nil
 }
+
+// labelPayment puts amounts scanned from a payments row in the row's
+// currency
+func labelPayment(p *model.Payment) {
+	p.Amount = p.Amount.WithCurrency(p.Currency)
+	p.RefundedAmount = p.RefundedAmount.WithCurrency(p.Currency)
+}
+
+// labelPayout puts a scanned payout's amount in the row's currency
+func labelPayout(p *model.Payout) {
+	p.Amount = p.Amount.WithCurrency(p.Currency)
+}
+
+// labelAuthorization puts a scanned hold's amounts in the row's currency
+func labelAuthorization(a *model.Authorization) {
+	a.Amount = a.Amount.WithCurrency(a.Currency)
+	a.CapturedAmount = a.CapturedAmount.WithCurrency(a.Currency)
+}
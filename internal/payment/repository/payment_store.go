@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// PaymentStore defines the database operations the payment service uses.
+// PaymentRepository implements it on Postgres.
+type PaymentStore interface {
+	// ============================
+	// PAYMENTS
+	// ============================
+
+	CreatePayment(ctx context.Context, payment *model.Payment) (*model.Payment, error)
+	CreateCheckoutPayments(ctx context.Context, payments []*model.Payment) error
+	GetPaymentByID(ctx context.Context, paymentID int64) (*model.Payment, error)
+	GetPaymentByOrderID(ctx context.Context, orderID int64) (*model.Payment, error)
+	ListPaymentsByUser(ctx context.Context, userID int64, status string, page, pageSize int32) ([]*model.Payment, int64, error)
+	UpdatePaymentWithCharge(ctx context.Context, paymentID int64, chargeID, paymentMethod, cardLast4, cardBrand string) error
+	UpdateCheckoutPaymentsWithCharge(ctx context.Context, checkoutID int64, chargeID, paymentMethod, cardLast4, cardBrand string) error
+
+	// RefundPayment locks the payment, asks refund how much to refund and
+	// records it
+	RefundPayment(ctx context.Context, paymentID int64, reason string, refund func(payment *model.Payment) (money.Money, error)) (*model.Payment, money.Money, error)
+
+	// ============================
+	// BID AUTHORIZATIONS
+	// ============================
+
+	CreateAuthorization(ctx context.Context, auth *model.Authorization) (*model.Authorization, error)
+	GetAuthorizationByBidID(ctx context.Context, bidID int64) (*model.Authorization, error)
+	MarkAuthorizationCaptured(ctx context.Context, authID, matchID int64, capturedAmount money.Money) error
+	MarkAuthorizationReleased(ctx context.Context, authID int64, reason string) error
+
+	// ============================
+	// SELLER WALLETS AND PAYOUTS
+	// ============================
+
+	OpenWallet(ctx context.Context, w *model.Wallet) (*model.Wallet, error)
+	GetWallet(ctx context.Context, sellerID int64, currency string) (*model.Wallet, error)
+	GetWalletTransactions(ctx context.Context, walletID int64, limit int32) ([]*model.WalletTransaction, error)
+	UpdateWalletSettings(ctx context.Context, w *model.Wallet) error
+	GetDueWallets(ctx context.Context, now time.Time, limit int) ([]*model.Wallet, error)
+	SetNextPayoutAt(ctx context.Context, walletID int64, next sql.NullTime) error
+
+	// CreditSale credits an order's sale once; the flag is false when it
+	// already was
+	CreditSale(ctx context.Context, w *model.Wallet, orderID int64) (*model.Wallet, bool, error)
+	GetSaleCredit(ctx context.Context, orderID int64) (*model.SaleCredit, error)
+	ClawbackSale(ctx context.Context, w *model.Wallet, orderID int64, credit *model.SaleCredit, amount money.Money, reference string) (*model.Clawback, error)
+	FreezeSale(ctx context.Context, orderID int64) (bool, error)
+	UnfreezeSale(ctx context.Context, orderID int64) (bool, error)
+
+	BeginWalletPayout(ctx context.Context, walletID int64, next sql.NullTime) (*model.Payout, error)
+	MarkPayoutProcessing(ctx context.Context, payoutID int64, transferID string) error
+	MarkPayoutFailed(ctx context.Context, payoutID int64, reason string) error
+	ReturnPayoutToWallet(ctx context.Context, payout *model.Payout) (bool, error)
+	GetPayoutByID(ctx context.Context, payoutID int64) (*model.Payout, error)
+	GetPayoutByTransferID(ctx context.Context, transferID string) (*model.Payout, error)
+	UpdatePayoutFromTransfer(ctx context.Context, transferID, status, failureReason string, fromStatuses []string) (bool, error)
+	ListPayoutsBySeller(ctx context.Context, sellerID int64, status string, page, pageSize int32) ([]*model.Payout, int64, error)
+
+	// ============================
+	// CHARGEBACKS
+	// ============================
+
+	GetPayoutHold(ctx context.Context, orderID int64) (*model.PayoutHold, error)
+	OpenChargeback(ctx context.Context, cb *model.Chargeback) (bool, error)
+	CloseChargebacks(ctx context.Context, disputeID, status string) ([]*model.Chargeback, error)
+
+	// ============================
+	// WEBHOOKS
+	// ============================
+
+	BeginStripeEvent(ctx context.Context, event *model.StripeEvent, stale time.Duration) (bool, error)
+	FinishStripeEvent(ctx context.Context, eventID, status, errMsg string) error
+	GetPaymentsByIntentID(ctx context.Context, paymentIntentID string) ([]*model.Payment, error)
+	GetPaymentsByChargeID(ctx context.Context, chargeID string) ([]*model.Payment, error)
+	MarkIntentPaymentsSucceeded(ctx context.Context, paymentIntentID, chargeID, paymentMethod, cardLast4, cardBrand string) (int64, error)
+	MarkIntentPaymentsFailed(ctx context.Context, paymentIntentID, reason string) (int64, error)
+	SyncPaymentRefund(ctx context.Context, paymentID int64, totalRefunded money.Money) (bool, error)
+
+	// ============================
+	// IDEMPOTENCY KEYS
+	// ============================
+
+	BeginIdempotentRequest(ctx context.Context, k *model.IdempotencyKey, stale time.Duration) (bool, error)
+	GetIdempotencyKey(ctx context.Context, scope, key string) (*model.IdempotencyKey, error)
+	CompleteIdempotentRequest(ctx context.Context, scope, key string, response []byte) error
+	ReleaseIdempotentRequest(ctx context.Context, scope, key string) error
+
+	// ============================
+	// RECONCILIATION
+	// ============================
+
+	ListPaymentsForReconciliation(ctx context.Context, from, to time.Time, intentIDs, chargeIDs []string) ([]*model.Payment, error)
+	ListPayoutsForReconciliation(ctx context.Context, from, to time.Time, transferIDs []string, payoutIDs, orderIDs []int64) ([]*model.Payout, error)
+	GetHoldIntentIDs(ctx context.Context, intentIDs []string) (map[string]bool, error)
+}
+
+var _ PaymentStore = (*PaymentRepository)(nil)
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+)
+
+// ListPaymentsForReconciliation retrieves the payments settled (or created,
+// while unsettled) in [from, to) and those of the given intents and charges
+func (r *PaymentRepository) ListPaymentsForReconciliation(ctx context.Context, from, to time.Time, intentIDs, chargeIDs []string) ([]*model.Payment, error) {
+	return r.listPayments(ctx, `
+		(COALESCE(processed_at, created_at) >= $1 AND COALESCE(processed_at, created_at) < $2)
+		OR stripe_payment_intent_id = ANY($3)
+		OR stripe_charge_id = ANY($4)`,
+		from, to, intentIDs, chargeIDs,
+	)
+}
+
+// ListPayoutsForReconciliation retrieves the payouts created in [from, to),
+// those paid by the given transfers, the given wallet payouts and the
+// per-order payouts of the given orders
+func (r *PaymentRepository) ListPayoutsForReconciliation(ctx context.Context, from, to time.Time, transferIDs []string, payoutIDs, orderIDs []int64) ([]*model.Payout, error) {
+	query := `
+		SELECT
+			id, payout_id, COALESCE(order_id, 0), seller_id, COALESCE(payment_id, 0),
+			stripe_transfer_id, stripe_account_id,
+			amount, currency, status,
+			failure_reason, processed_at,
+			created_at, updated_at, COALESCE(wallet_id, 0)
+		FROM payouts
+		WHERE (created_at >= $1 AND created_at < $2)
+			OR stripe_transfer_id = ANY($3)
+			OR id = ANY($4)
+			OR (order_id = ANY($5) AND wallet_id IS NULL)
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, from, to, transferIDs, payoutIDs, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := make([]*model.Payout, 0)
+	for rows.Next() {
+		payout := &model.Payout{}
+		err := rows.Scan(
+			&payout.ID, &payout.PayoutID, &payout.OrderID, &payout.SellerID, &payout.PaymentID,
+			&payout.StripeTransferID, &payout.StripeAccountID,
+			&payout.Amount, &payout.Currency, &payout.Status,
+			&payout.FailureReason, &payout.ProcessedAt,
+			&payout.CreatedAt, &payout.UpdatedAt, &payout.WalletID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payout: %w", err)
+		}
+		labelPayout(payout)
+		payouts = append(payouts, payout)
+	}
+
+	return payouts, rows.Err()
+}
+
+// GetHoldIntentIDs returns which of the intents are bid holds rather than
+// order payments
+func (r *PaymentRepository) GetHoldIntentIDs(ctx context.Context, intentIDs []string) (map[string]bool, error) {
+	query := `
+		SELECT stripe_payment_intent_id
+		FROM payment_authorizations
+		WHERE stripe_payment_intent_id = ANY($1)
+	`
+
+	rows, err := r.db.Query(ctx, query, intentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold intents: %w", err)
+	}
+	defer rows.Close()
+
+	holds := make(map[string]bool)
+	for rows.Next() {
+		var intentID string
+		if err := rows.Scan(&intentID); err != nil {
+			return nil, fmt.Errorf("failed to scan hold intent: %w", err)
+		}
+		holds[intentID] = true
+	}
+
+	return holds, rows.Err()
+}
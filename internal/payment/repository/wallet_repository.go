@@ -0,0 +1,576 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+const walletColumns = `
+	id, seller_id, currency, balance, frozen_balance, payout_schedule, minimum_payout,
+	stripe_account_id, next_payout_at, last_payout_at, created_at, updated_at
+`
+
+func scanWallet(row pgx.Row) (*model.Wallet, error) {
+	w := &model.Wallet{}
+	err := row.Scan(
+		&w.ID, &w.SellerID, &w.Currency, &w.Balance, &w.FrozenBalance, &w.PayoutSchedule, &w.MinimumPayout,
+		&w.StripeAccountID, &w.NextPayoutAt, &w.LastPayoutAt, &w.CreatedAt, &w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	w.Balance = w.Balance.WithCurrency(w.Currency)
+	w.FrozenBalance = w.FrozenBalance.WithCurrency(w.Currency)
+	w.MinimumPayout = w.MinimumPayout.WithCurrency(w.Currency)
+	return w, nil
+}
+
+// GetWallet returns a seller's wallet in currency, or nil if none was opened
+func (r *PaymentRepository) GetWallet(ctx context.Context, sellerID int64, currency string) (*model.Wallet, error) {
+	query := `SELECT ` + walletColumns + ` FROM seller_wallets WHERE seller_id = $1 AND currency = $2`
+
+	wallet, err := scanWallet(r.db.QueryRow(ctx, query, sellerID, currency))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// OpenWallet returns the seller's wallet in the currency of w, creating it
+// from w when the seller has none
+func (r *PaymentRepository) OpenWallet(ctx context.Context, w *model.Wallet) (*model.Wallet, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	wallet, err := lockWallet(ctx, tx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// lockWallet opens the wallet described by w if needed and locks it until
+// the transaction ends
+func lockWallet(ctx context.Context, tx pgx.Tx, w *model.Wallet) (*model.Wallet, error) {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO seller_wallets (seller_id, currency, payout_schedule, minimum_payout, next_payout_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (seller_id, currency) DO NOTHING
+	`, w.SellerID, w.Currency, w.PayoutSchedule, w.MinimumPayout, w.NextPayoutAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wallet: %w", err)
+	}
+
+	query := `SELECT ` + walletColumns + ` FROM seller_wallets WHERE seller_id = $1 AND currency = $2 FOR UPDATE`
+
+	wallet, err := scanWallet(tx.QueryRow(ctx, query, w.SellerID, w.Currency))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// CreditSale adds a completed order's proceeds to the seller's wallet,
+// opening it from w if needed. A sale is credited once; the returned flag is
+// false when it already was. Orders already paid by a transfer of their own
+// are refused.
+func (r *PaymentRepository) CreditSale(ctx context.Context, w *model.Wallet, orderID int64) (*model.Wallet, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	amount := w.Balance
+	wallet, err := lockWallet(ctx, tx, w)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var transferred bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM payouts
+			WHERE order_id = $1 AND wallet_id IS NULL AND status <> ALL($2)
+		)
+	`, orderID, []string{model.PayoutStatusFailed, model.PayoutStatusReversed}).Scan(&transferred)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check order payouts: %w", err)
+	}
+	if transferred {
+		return nil, false, fmt.Errorf("order %d was already paid out by transfer", orderID)
+	}
+
+	balance := wallet.Balance.Add(amount)
+
+	var txID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO wallet_transactions (wallet_id, type, amount, balance_after, order_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id) WHERE type = 'sale' DO NOTHING
+		RETURNING id
+	`, wallet.ID, model.WalletTxSale, amount, balance, orderID).Scan(&txID)
+	if err == pgx.ErrNoRows {
+		return wallet, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to credit sale: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE seller_wallets SET balance = $1 WHERE id = $2`, balance, wallet.ID); err != nil {
+		return nil, false, fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	wallet.Balance = balance
+	return wallet, true, nil
+}
+
+// UpdateWalletSettings stores a wallet's payout schedule, minimum and
+// connected account
+func (r *PaymentRepository) UpdateWalletSettings(ctx context.Context, w *model.Wallet) error {
+	query := `
+		UPDATE seller_wallets
+		SET payout_schedule = $1, minimum_payout = $2, stripe_account_id = $3, next_payout_at = $4
+		WHERE id = $5
+	`
+
+	result, err := r.db.Exec(ctx, query, w.PayoutSchedule, w.MinimumPayout, w.StripeAccountID, w.NextPayoutAt, w.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("wallet not found")
+	}
+
+	return nil
+}
+
+// SetNextPayoutAt moves a wallet's next scheduled batch
+func (r *PaymentRepository) SetNextPayoutAt(ctx context.Context, walletID int64, next sql.NullTime) error {
+	_, err := r.db.Exec(ctx, `UPDATE seller_wallets SET next_payout_at = $1 WHERE id = $2`, next, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule wallet: %w", err)
+	}
+
+	return nil
+}
+
+// GetDueWallets returns scheduled wallets whose next batch is due
+func (r *PaymentRepository) GetDueWallets(ctx context.Context, now time.Time, limit int) ([]*model.Wallet, error) {
+	query := `
+		SELECT ` + walletColumns + `
+		FROM seller_wallets
+		WHERE payout_schedule <> $1 AND next_payout_at <= $2
+		ORDER BY next_payout_at ASC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, model.PayoutScheduleOnDemand, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due wallets: %w", err)
+	}
+	defer rows.Close()
+
+	wallets := make([]*model.Wallet, 0)
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		wallets = append(wallets, wallet)
+	}
+
+	return wallets, rows.Err()
+}
+
+// BeginWalletPayout moves a wallet's payable balance into a new pending
+// payout and schedules the next batch at next. The sales credited since the
+// last batch are attributed to the payout; frozen sales stay in the wallet.
+// It returns nil, changing nothing, when the payable balance is below the
+// wallet's minimum.
+func (r *PaymentRepository) BeginWalletPayout(ctx context.Context, walletID int64, next sql.NullTime) (*model.Payout, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `SELECT ` + walletColumns + ` FROM seller_wallets WHERE id = $1 FOR UPDATE`
+	wallet, err := scanWallet(tx.QueryRow(ctx, query, walletID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock wallet: %w", err)
+	}
+
+	if !wallet.CanPayout() {
+		return nil, nil
+	}
+	amount := wallet.Payable()
+
+	var payoutID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO payouts (seller_id, wallet_id, stripe_account_id, amount, currency, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, wallet.SellerID, wallet.ID, wallet.StripeAccountID, amount, wallet.Currency, model.PayoutStatusPending).Scan(&payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payout: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO wallet_transactions (wallet_id, type, amount, balance_after, payout_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, wallet.ID, model.WalletTxPayout, amount.Neg(), wallet.FrozenBalance, payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to debit wallet: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE wallet_transactions SET payout_id = $1
+		WHERE wallet_id = $2 AND type = $3 AND payout_id IS NULL AND frozen_at IS NULL
+	`, payoutID, wallet.ID, model.WalletTxSale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attribute sales to payout: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE seller_wallets SET balance = frozen_balance, last_payout_at = NOW(), next_payout_at = $1 WHERE id = $2
+	`, next, wallet.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.GetPayoutByID(ctx, payoutID)
+}
+
+// MarkPayoutProcessing stores the transfer sent for a pending payout. The
+// payout is paid once the provider confirms the transfer.
+func (r *PaymentRepository) MarkPayoutProcessing(ctx context.Context, payoutID int64, transferID string) error {
+	query := `
+		UPDATE payouts
+		SET status = $1, stripe_transfer_id = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	_, err := r.db.Exec(ctx, query, model.PayoutStatusProcessing, transferID, payoutID, model.PayoutStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to update payout: %w", err)
+	}
+
+	return nil
+}
+
+// MarkPayoutFailed fails a payout whose transfer could not be sent
+func (r *PaymentRepository) MarkPayoutFailed(ctx context.Context, payoutID int64, reason string) error {
+	query := `
+		UPDATE payouts
+		SET status = $1, failure_reason = $2, processed_at = NOW()
+		WHERE id = $3 AND status IN ($4, $5)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		model.PayoutStatusFailed, reason, payoutID,
+		model.PayoutStatusPending, model.PayoutStatusProcessing,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update payout: %w", err)
+	}
+
+	return nil
+}
+
+// ReturnPayoutToWallet credits a failed batch payout back to its wallet and
+// releases its sales for the next batch. A payout is returned once; the
+// flag is false when it already was.
+func (r *PaymentRepository) ReturnPayoutToWallet(ctx context.Context, payout *model.Payout) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `SELECT ` + walletColumns + ` FROM seller_wallets WHERE id = $1 FOR UPDATE`
+	wallet, err := scanWallet(tx.QueryRow(ctx, query, payout.WalletID))
+	if err != nil {
+		return false, fmt.Errorf("failed to lock wallet: %w", err)
+	}
+
+	balance := wallet.Balance.Add(payout.Amount)
+
+	var txID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO wallet_transactions (wallet_id, type, amount, balance_after, payout_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (type, payout_id) WHERE type IN ('payout', 'payout_returned') DO NOTHING
+		RETURNING id
+	`, wallet.ID, model.WalletTxPayoutReturned, payout.Amount, balance, payout.ID).Scan(&txID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to return payout: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE seller_wallets SET balance = $1 WHERE id = $2`, balance, wallet.ID); err != nil {
+		return false, fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE wallet_transactions SET payout_id = NULL
+		WHERE payout_id = $1 AND type = $2
+	`, payout.ID, model.WalletTxSale)
+	if err != nil {
+		return false, fmt.Errorf("failed to release sales: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetWalletTransactions returns a wallet's latest transactions, newest first
+func (r *PaymentRepository) GetWalletTransactions(ctx context.Context, walletID int64, limit int32) ([]*model.WalletTransaction, error) {
+	query := `
+		SELECT
+			wt.id, wt.wallet_id, wt.type, wt.amount, wt.balance_after,
+			wt.order_id, wt.payout_id, wt.frozen_at, wt.reference, wt.created_at, w.currency
+		FROM wallet_transactions wt
+		JOIN seller_wallets w ON w.id = wt.wallet_id
+		WHERE wt.wallet_id = $1
+		ORDER BY wt.created_at DESC, wt.id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, walletID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]*model.WalletTransaction, 0)
+	for rows.Next() {
+		t := &model.WalletTransaction{}
+		var currency string
+		err := rows.Scan(
+			&t.ID, &t.WalletID, &t.Type, &t.Amount, &t.BalanceAfter,
+			&t.OrderID, &t.PayoutID, &t.FrozenAt, &t.Reference, &t.CreatedAt, &currency,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet transaction: %w", err)
+		}
+		t.Amount = t.Amount.WithCurrency(currency)
+		t.BalanceAfter = t.BalanceAfter.WithCurrency(currency)
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// GetSaleCredit returns what the seller was credited for an order: its sale
+// in the wallet or, for orders paid out before wallets, the order's payout.
+// It returns nil if the seller has not been credited yet.
+func (r *PaymentRepository) GetSaleCredit(ctx context.Context, orderID int64) (*model.SaleCredit, error) {
+	query := `
+		SELECT seller_id, amount, currency, payout_id
+		FROM (
+			SELECT w.seller_id, wt.amount, w.currency, COALESCE(wt.payout_id, 0) AS payout_id, 0 AS priority
+			FROM wallet_transactions wt
+			JOIN seller_wallets w ON w.id = wt.wallet_id
+			WHERE wt.order_id = $1 AND wt.type = $2
+			UNION ALL
+			SELECT seller_id, amount, currency, id, 1
+			FROM payouts
+			WHERE order_id = $1 AND status <> $3
+		) credits
+		ORDER BY priority ASC, payout_id DESC
+		LIMIT 1
+	`
+
+	credit := &model.SaleCredit{}
+	var currency string
+	err := r.db.QueryRow(ctx, query, orderID, model.WalletTxSale, model.PayoutStatusFailed).Scan(
+		&credit.SellerID, &credit.Amount, &currency, &credit.PayoutID,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sale credit: %w", err)
+	}
+	credit.Amount = credit.Amount.WithCurrency(currency)
+
+	return credit, nil
+}
+
+// ClawbackSale reclaims amount from the seller's wallet for an order whose
+// buyer got money back after the sale was credited. The clawbacks of an
+// order never exceed its credit, and each reference is reclaimed once. The
+// balance may go negative; future sales recover it. A payout the clawbacks
+// add up to is marked reversed. It returns nil when nothing was reclaimed.
+func (r *PaymentRepository) ClawbackSale(ctx context.Context, w *model.Wallet, orderID int64, credit *model.SaleCredit, amount money.Money, reference string) (*model.Clawback, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	wallet, err := lockWallet(ctx, tx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	reclaimed := money.Zero(wallet.Currency)
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(-SUM(amount), 0) FROM wallet_transactions WHERE order_id = $1 AND type = $2
+	`, orderID, model.WalletTxClawback).Scan(&reclaimed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum clawbacks: %w", err)
+	}
+	reclaimed = reclaimed.WithCurrency(wallet.Currency)
+
+	due := money.Min(amount, credit.Amount.Sub(reclaimed))
+	if !due.IsPositive() {
+		return nil, nil
+	}
+
+	balance := wallet.Balance.Sub(due)
+	payoutID := sql.NullInt64{Int64: credit.PayoutID, Valid: credit.PayoutID != 0}
+
+	var txID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO wallet_transactions (wallet_id, type, amount, balance_after, order_id, payout_id, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (reference) WHERE type = 'clawback' DO NOTHING
+		RETURNING id
+	`, wallet.ID, model.WalletTxClawback, due.Neg(), balance, orderID, payoutID, reference).Scan(&txID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record clawback: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE seller_wallets SET balance = $1 WHERE id = $2`, balance, wallet.ID); err != nil {
+		return nil, fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	clawback := &model.Clawback{Amount: due, PayoutID: credit.PayoutID}
+
+	if payoutID.Valid {
+		result, err := tx.Exec(ctx, `
+			UPDATE payouts SET status = $1
+			WHERE id = $2 AND status = $3 AND amount <= (
+				SELECT -SUM(amount) FROM wallet_transactions WHERE payout_id = $2 AND type = $4
+			)
+		`, model.PayoutStatusReversed, credit.PayoutID, model.PayoutStatusPaid, model.WalletTxClawback)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reverse payout: %w", err)
+		}
+		clawback.Reversed = result.RowsAffected() > 0
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return clawback, nil
+}
+
+// FreezeSale keeps an order's sale out of payout batches while a chargeback
+// is open. Sales already paid out have nothing to freeze. It reports
+// whether the sale was frozen.
+func (r *PaymentRepository) FreezeSale(ctx context.Context, orderID int64) (bool, error) {
+	return r.setSaleFrozen(ctx, orderID, true)
+}
+
+// UnfreezeSale releases an order's frozen sale once it has no open
+// chargeback left. It reports whether the sale was released.
+func (r *PaymentRepository) UnfreezeSale(ctx context.Context, orderID int64) (bool, error) {
+	return r.setSaleFrozen(ctx, orderID, false)
+}
+
+func (r *PaymentRepository) setSaleFrozen(ctx context.Context, orderID int64, frozen bool) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Lock the wallet before the sale, in the order every wallet change does
+	var walletID int64
+	err = tx.QueryRow(ctx, `
+		SELECT wallet_id FROM wallet_transactions WHERE order_id = $1 AND type = $2
+	`, orderID, model.WalletTxSale).Scan(&walletID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get sale: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT id FROM seller_wallets WHERE id = $1 FOR UPDATE`, walletID); err != nil {
+		return false, fmt.Errorf("failed to lock wallet: %w", err)
+	}
+
+	query := `
+		UPDATE wallet_transactions SET frozen_at = NOW()
+		WHERE order_id = $1 AND type = $2 AND frozen_at IS NULL AND payout_id IS NULL
+		RETURNING amount
+	`
+	delta := `frozen_balance + $1`
+	if !frozen {
+		query = `
+			UPDATE wallet_transactions SET frozen_at = NULL
+			WHERE order_id = $1 AND type = $2 AND frozen_at IS NOT NULL
+				AND NOT EXISTS (SELECT 1 FROM chargebacks WHERE order_id = $1 AND status = 'open')
+			RETURNING amount
+		`
+		delta = `frozen_balance - $1`
+	}
+
+	var amount money.Money
+	err = tx.QueryRow(ctx, query, orderID, model.WalletTxSale).Scan(&amount)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update sale: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE seller_wallets SET frozen_balance = `+delta+` WHERE id = $2`, amount, walletID); err != nil {
+		return false, fmt.Errorf("failed to update frozen balance: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
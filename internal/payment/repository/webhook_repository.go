@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// BeginStripeEvent records a received webhook event and reports whether it
+// should be processed. Redeliveries of processed or ignored events return
+// false; failed events, and events stuck in processing for longer than
+// stale (the handler crashed), are claimed again.
+func (r *PaymentRepository) BeginStripeEvent(ctx context.Context, event *model.StripeEvent, stale time.Duration) (bool, error) {
+	query := `
+		INSERT INTO stripe_events (event_id, event_type, payload, status)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id) DO UPDATE
+		SET status = $4, attempts = stripe_events.attempts + 1, error = NULL
+		WHERE stripe_events.status = $5
+			OR (stripe_events.status = $4 AND stripe_events.updated_at < NOW() - make_interval(secs => $6))
+		RETURNING id, attempts, received_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		event.EventID, event.EventType, event.Payload,
+		model.StripeEventProcessing, model.StripeEventFailed, stale.Seconds(),
+	).Scan(&event.ID, &event.Attempts, &event.ReceivedAt, &event.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil // Already handled
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record stripe event: %w", err)
+	}
+
+	event.Status = model.StripeEventProcessing
+	return true, nil
+}
+
+// FinishStripeEvent stores the outcome of processing a webhook event
+func (r *PaymentRepository) FinishStripeEvent(ctx context.Context, eventID, status, errMsg string) error {
+	query := `
+		UPDATE stripe_events
+		SET status = $1, error = NULLIF($2, ''), processed_at = NOW()
+		WHERE event_id = $3
+	`
+
+	result, err := r.db.Exec(ctx, query, status, errMsg, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to update stripe event: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("stripe event not found")
+	}
+
+	return nil
+}
+
+// GetPaymentsByIntentID retrieves the payments created for a PaymentIntent.
+// A checkout's orders share one intent, so there can be several.
+func (r *PaymentRepository) GetPaymentsByIntentID(ctx context.Context, paymentIntentID string) ([]*model.Payment, error) {
+	return r.listPayments(ctx, "stripe_payment_intent_id = $1", paymentIntentID)
+}
+
+// GetPaymentsByChargeID retrieves the payments settled by a Stripe charge
+func (r *PaymentRepository) GetPaymentsByChargeID(ctx context.Context, chargeID string) ([]*model.Payment, error) {
+	return r.listPayments(ctx, "stripe_charge_id = $1", chargeID)
+}
+
+func (r *PaymentRepository) listPayments(ctx context.Context, where string, args ...interface{}) ([]*model.Payment, error) {
+	query := `
+		SELECT
+			id, payment_id, order_id, user_id,
+			stripe_payment_intent_id, stripe_charge_id, stripe_customer_id,
+			amount, currency, status,
+			payment_method, card_last4, card_brand,
+			refunded_amount, refund_reason,
+			processed_at, refunded_at,
+			created_at, updated_at, checkout_id
+		FROM payments
+		WHERE ` + where + `
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := make([]*model.Payment, 0)
+	for rows.Next() {
+		payment := &model.Payment{}
+		err := rows.Scan(
+			&payment.ID, &payment.PaymentID, &payment.OrderID, &payment.UserID,
+			&payment.StripePaymentIntentID, &payment.StripeChargeID, &payment.StripeCustomerID,
+			&payment.Amount, &payment.Currency, &payment.Status,
+			&payment.PaymentMethod, &payment.CardLast4, &payment.CardBrand,
+			&payment.RefundedAmount, &payment.RefundReason,
+			&payment.ProcessedAt, &payment.RefundedAt,
+			&payment.CreatedAt, &payment.UpdatedAt, &payment.CheckoutID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		labelPayment(payment)
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+// MarkIntentPaymentsSucceeded moves the still-open payments of a
+// PaymentIntent to succeeded. Empty charge details keep what is stored.
+func (r *PaymentRepository) MarkIntentPaymentsSucceeded(ctx context.Context, paymentIntentID, chargeID, paymentMethod, cardLast4, cardBrand string) (int64, error) {
+	query := `
+		UPDATE payments
+		SET
+			stripe_charge_id = COALESCE(NULLIF($1, ''), stripe_charge_id),
+			payment_method = COALESCE(NULLIF($2, ''), payment_method),
+			card_last4 = COALESCE(NULLIF($3, ''), card_last4),
+			card_brand = COALESCE(NULLIF($4, ''), card_brand),
+			status = $5,
+			failure_reason = NULL,
+			processed_at = COALESCE(processed_at, NOW())
+		WHERE stripe_payment_intent_id = $6
+			AND status IN ($7, $8, $9)
+	`
+
+	result, err := r.db.Exec(
+		ctx, query,
+		chargeID, paymentMethod, cardLast4, cardBrand,
+		model.StatusSucceeded, paymentIntentID,
+		model.StatusPending, model.StatusProcessing, model.StatusFailed,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update payments: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// MarkIntentPaymentsFailed records a failed attempt on the open payments of
+// a PaymentIntent. The buyer may retry the same intent, which succeeds them.
+func (r *PaymentRepository) MarkIntentPaymentsFailed(ctx context.Context, paymentIntentID, reason string) (int64, error) {
+	query := `
+		UPDATE payments
+		SET status = $1, failure_reason = $2, processed_at = NOW()
+		WHERE stripe_payment_intent_id = $3
+			AND status IN ($4, $5)
+	`
+
+	result, err := r.db.Exec(
+		ctx, query,
+		model.StatusFailed, reason, paymentIntentID,
+		model.StatusPending, model.StatusProcessing,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update payments: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// SyncPaymentRefund raises the refunded total of a payment to what Stripe
+// reports. It never lowers it, so replays and refunds already recorded by
+// CreateRefund are no-ops. Returns whether the payment changed.
+func (r *PaymentRepository) SyncPaymentRefund(ctx context.Context, paymentID int64, totalRefunded money.Money) (bool, error) {
+	query := `
+		UPDATE payments
+		SET
+			refunded_amount = LEAST($1, amount),
+			refunded_at = NOW(),
+			status = CASE
+				WHEN LEAST($1, amount) >= amount THEN $2
+				ELSE $3
+			END
+		WHERE id = $4
+			AND refunded_amount < LEAST($1, amount)
+	`
+
+	result, err := r.db.Exec(
+		ctx, query,
+		totalRefunded,
+		model.StatusRefunded, model.StatusPartiallyRefunded,
+		paymentID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to sync refund: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// GetPayoutByTransferID retrieves the payout paid by a Stripe transfer
+func (r *PaymentRepository) GetPayoutByTransferID(ctx context.Context, transferID string) (*model.Payout, error) {
+	query := `
+		SELECT
+			id, payout_id, COALESCE(order_id, 0), seller_id, COALESCE(payment_id, 0),
+			stripe_transfer_id, stripe_account_id,
+			amount, currency, status,
+			failure_reason, processed_at,
+			created_at, updated_at, COALESCE(wallet_id, 0)
+		FROM payouts
+		WHERE stripe_transfer_id = $1
+	`
+
+	payout := &model.Payout{}
+	err := r.db.QueryRow(ctx, query, transferID).Scan(
+		&payout.ID, &payout.PayoutID, &payout.OrderID, &payout.SellerID, &payout.PaymentID,
+		&payout.StripeTransferID, &payout.StripeAccountID,
+		&payout.Amount, &payout.Currency, &payout.Status,
+		&payout.FailureReason, &payout.ProcessedAt,
+		&payout.CreatedAt, &payout.UpdatedAt, &payout.WalletID,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get payout: %w", err)
+	}
+
+	labelPayout(payout)
+	return payout, nil
+}
+
+// UpdatePayoutFromTransfer applies a transfer outcome to its payout if the
+// payout is currently in one of fromStatuses. Returns whether it changed.
+func (r *PaymentRepository) UpdatePayoutFromTransfer(ctx context.Context, transferID, status, failureReason string, fromStatuses []string) (bool, error) {
+	query := `
+		UPDATE payouts
+		SET
+			status = $1,
+			failure_reason = NULLIF($2, ''),
+			processed_at = NOW()
+		WHERE stripe_transfer_id = $3
+			AND status = ANY($4)
+	`
+
+	result, err := r.db.Exec(ctx, query, status, failureReason, transferID, fromStatuses)
+	if err != nil {
+		return false, fmt.Errorf("failed to update payout: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// reclaimFromSeller claws back from the seller what the buyer got back on a
+// payment after the order's sale was credited. reference identifies the
+// refund or chargeback, so each is reclaimed once. Orders not credited yet
+// have nothing to reclaim.
+func (s *PaymentService) reclaimFromSeller(ctx context.Context, payment *model.Payment, returned money.Money, reference string) error {
+	credit, err := s.repo.GetSaleCredit(ctx, payment.OrderID)
+	if err != nil || credit == nil {
+		return err
+	}
+
+	amount := sellerShare(credit.Amount, returned, payment.Amount)
+	wallet := s.newWallet(credit.SellerID, credit.Amount.Currency)
+
+	clawback, err := s.repo.ClawbackSale(ctx, wallet, payment.OrderID, credit, amount, reference)
+	if err != nil || clawback == nil {
+		return err
+	}
+
+	if clawback.Reversed {
+		log.Printf("Payout %d reversed by clawbacks on order %d", clawback.PayoutID, payment.OrderID)
+	}
+
+	return s.postClawback(ctx, payment, credit.SellerID, reference, clawback.Amount)
+}
+
+// refundReference identifies the refund that took a payment's refunded
+// total to refunded
+func refundReference(paymentID int64, refunded money.Money) string {
+	return fmt.Sprintf("refund:%d:%d", paymentID, refunded.Amount)
+}
+
+// sellerShare returns what the seller owes back when the buyer got returned
+// of paid. The seller bears the refund up to their credit; payments in
+// another currency than the credit reclaim the same share of it.
+func sellerShare(credit, returned, paid money.Money) money.Money {
+	if returned.SameCurrency(credit) {
+		return returned
+	}
+
+	return credit.MulRat(returned.Amount, paid.Amount, money.HalfUp)
+}
+
+// handleDisputeCreated records a chargeback on each order payment of the
+// disputed charge and freezes their sales in the sellers' wallets. A
+// checkout charge is shared by several orders; the disputed amount is
+// allocated to them in order.
+func (s *PaymentService) handleDisputeCreated(ctx context.Context, d *provider.Dispute) error {
+	payments, err := s.repo.GetPaymentsByChargeID(ctx, d.ChargeID)
+	if err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		// Subscription invoices are not order payments
+		return nil
+	}
+
+	remaining := money.New(d.Amount, payments[0].Amount.Currency)
+	for _, payment := range payments {
+		share := money.Min(remaining, payment.Amount.Sub(payment.RefundedAmount))
+		if !share.IsPositive() {
+			continue
+		}
+		remaining = remaining.Sub(share)
+
+		cb := &model.Chargeback{
+			StripeDisputeID: d.ID,
+			StripeChargeID:  d.ChargeID,
+			PaymentID:       payment.ID,
+			OrderID:         payment.OrderID,
+			Amount:          share,
+			Currency:        share.Currency,
+		}
+		if d.Reason != "" {
+			cb.Reason.String, cb.Reason.Valid = d.Reason, true
+		}
+
+		if _, err := s.repo.OpenChargeback(ctx, cb); err != nil {
+			return err
+		}
+		if _, err := s.repo.FreezeSale(ctx, payment.OrderID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleDisputeClosed closes the dispute's chargebacks and releases the
+// frozen sales. A lost chargeback is clawed back from the seller.
+func (s *PaymentService) handleDisputeClosed(ctx context.Context, d *provider.Dispute) error {
+	status := model.ChargebackStatusWon
+	if d.Status == provider.DisputeLost {
+		status = model.ChargebackStatusLost
+	}
+
+	chargebacks, err := s.repo.CloseChargebacks(ctx, d.ID, status)
+	if err != nil {
+		return err
+	}
+
+	for _, cb := range chargebacks {
+		if _, err := s.repo.UnfreezeSale(ctx, cb.OrderID); err != nil {
+			return err
+		}
+		if cb.Status != model.ChargebackStatusLost {
+			continue
+		}
+
+		payment, err := s.repo.GetPaymentByID(ctx, cb.PaymentID)
+		if err != nil {
+			return err
+		}
+		if err := s.postChargeback(ctx, cb, payment); err != nil {
+			return err
+		}
+		if err := s.reclaimFromSeller(ctx, payment, cb.Amount, fmt.Sprintf("chargeback:%d", cb.ID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// addPayment records a settled payment for an order on chargeID
+func (m *memoryStore) addPayment(id, orderID int64, amount money.Money, chargeID string) {
+	m.payments[id] = &model.Payment{
+		ID:             id,
+		OrderID:        orderID,
+		UserID:         7,
+		Amount:         amount,
+		Currency:       amount.Currency,
+		Status:         model.StatusSucceeded,
+		RefundedAmount: money.Zero(amount.Currency),
+		StripeChargeID: sql.NullString{String: chargeID, Valid: true},
+	}
+}
+
+func TestChargebackFreezesSale(t *testing.T) {
+	tests := []struct {
+		status  string
+		balance money.Money
+	}{
+		{provider.DisputeWon, usd(25000)},
+		{provider.DisputeLost, usd(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			ctx := context.Background()
+			store := newMemoryStore()
+			s := newTestService(store, provider.NewFake(""))
+			store.completeOrder(1, seller)
+			store.completeOrder(2, seller)
+			store.addPayment(1, 1, usd(25000), "ch_1")
+			creditSale(t, s, 1, usd(25000))
+			creditSale(t, s, 2, usd(10000))
+
+			dispute := &provider.Dispute{ID: "dp_1", ChargeID: "ch_1", Amount: 25000}
+			if err := s.handleDisputeCreated(ctx, dispute); err != nil {
+				t.Fatalf("handleDisputeCreated: %v", err)
+			}
+
+			// Only the undisputed sale is paid out
+			payout, err := s.RequestPayout(ctx, seller, "")
+			if err != nil {
+				t.Fatalf("RequestPayout: %v", err)
+			}
+			if !payout.Amount.Equal(usd(10000)) {
+				t.Errorf("payout = %s, want 100.00", payout.Amount)
+			}
+			if store.sales[1].payoutID != 0 {
+				t.Error("frozen sale was paid out")
+			}
+
+			dispute.Status = tt.status
+			if err := s.handleDisputeClosed(ctx, dispute); err != nil {
+				t.Fatalf("handleDisputeClosed: %v", err)
+			}
+
+			wallet, _, err := s.GetSellerWallet(ctx, seller, "")
+			if err != nil {
+				t.Fatalf("GetSellerWallet: %v", err)
+			}
+			if !wallet.FrozenBalance.IsZero() {
+				t.Errorf("frozen balance after the dispute closed = %s, want 0", wallet.FrozenBalance)
+			}
+			if !wallet.Balance.Equal(tt.balance) {
+				t.Errorf("balance = %s, want %s", wallet.Balance, tt.balance)
+			}
+		})
+	}
+}
+
+func TestCreditUnderOpenChargebackIsFrozen(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	s := newTestService(store, provider.NewFake(""))
+	store.completeOrder(1, seller)
+	store.addPayment(1, 1, usd(25000), "ch_1")
+
+	if err := s.handleDisputeCreated(ctx, &provider.Dispute{ID: "dp_1", ChargeID: "ch_1", Amount: 25000}); err != nil {
+		t.Fatalf("handleDisputeCreated: %v", err)
+	}
+
+	wallet := creditSale(t, s, 1, usd(25000))
+	if !wallet.FrozenBalance.Equal(usd(25000)) {
+		t.Errorf("frozen balance = %s, want 250.00", wallet.FrozenBalance)
+	}
+	if _, err := s.RequestPayout(ctx, seller, ""); err == nil {
+		t.Error("frozen sale was paid out")
+	}
+}
+
+func TestRefundAfterPayoutReversesIt(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	s := newTestService(store, provider.NewFake(""))
+	store.completeOrder(1, seller)
+	store.completeOrder(2, seller)
+	store.addPayment(1, 1, usd(25000), "ch_1")
+	creditSale(t, s, 1, usd(25000))
+
+	payout, err := s.RequestPayout(ctx, seller, "")
+	if err != nil {
+		t.Fatalf("RequestPayout: %v", err)
+	}
+	store.payouts[payout.ID].Status = model.PayoutStatusPaid
+
+	if _, err := s.CreateRefund(ctx, 1, money.Money{}, "requested_by_customer", ""); err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+
+	if status := store.payouts[payout.ID].Status; status != model.PayoutStatusReversed {
+		t.Errorf("payout status = %s, want %s", status, model.PayoutStatusReversed)
+	}
+	wallet, _, err := s.GetSellerWallet(ctx, seller, "")
+	if err != nil {
+		t.Fatalf("GetSellerWallet: %v", err)
+	}
+	if !wallet.Balance.Equal(usd(-25000)) {
+		t.Errorf("balance after clawback = %s, want -250.00", wallet.Balance)
+	}
+
+	// The next sale recovers the negative balance first
+	wallet = creditSale(t, s, 2, usd(30000))
+	if !wallet.Balance.Equal(usd(5000)) {
+		t.Errorf("balance after the next sale = %s, want 50.00", wallet.Balance)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+)
+
+// idempotencyStale is how long a key may stay in processing before a retry
+// is allowed to run the request again
+const idempotencyStale = 10 * time.Minute
+
+// Idempotency key errors. Neither runs the request.
+var (
+	ErrIdempotencyConflict   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// BeginIdempotentRequest claims key for a request with the given hash. It
+// returns nil when the request should run, and the stored response when
+// the same request already completed with the key.
+func (s *PaymentService) BeginIdempotentRequest(ctx context.Context, scope, key, requestHash string) ([]byte, error) {
+	k := &model.IdempotencyKey{Scope: scope, Key: key, RequestHash: requestHash}
+
+	claimed, err := s.repo.BeginIdempotentRequest(ctx, k, idempotencyStale)
+	if err != nil || claimed {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetIdempotencyKey(ctx, scope, key)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case existing == nil:
+		// Released by a failed request between the two queries
+		return nil, ErrIdempotencyInProgress
+	case existing.RequestHash != requestHash:
+		return nil, ErrIdempotencyConflict
+	case existing.Status != model.IdempotencyCompleted:
+		return nil, ErrIdempotencyInProgress
+	}
+
+	return existing.Response, nil
+}
+
+// FinishIdempotentRequest stores the response of a request that ran under
+// key. A nil response (the request failed) frees the key for a retry.
+func (s *PaymentService) FinishIdempotentRequest(ctx context.Context, scope, key string, response []byte) error {
+	if response == nil {
+		return s.repo.ReleaseIdempotentRequest(ctx, scope, key)
+	}
+
+	return s.repo.CompleteIdempotentRequest(ctx, scope, key, response)
+}
+
+// providerIdempotencyKey scopes a client key for the payment provider, so
+// retries that reach the provider do not charge or pay twice either
+func providerIdempotencyKey(scope, key string) string {
+	if key == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", scope, key)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+)
+
+const refundScope = model.IdempotencyScopeRefund
+
+func TestIdempotentRequestReplaysResponse(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(newMemoryStore(), provider.NewFake(""))
+
+	stored, err := s.BeginIdempotentRequest(ctx, refundScope, "key-1", "hash-a")
+	if err != nil || stored != nil {
+		t.Fatalf("first request = %q, %v; want it to run", stored, err)
+	}
+
+	// A retry while the first request runs does not run it again
+	if _, err := s.BeginIdempotentRequest(ctx, refundScope, "key-1", "hash-a"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("concurrent retry error = %v, want %v", err, ErrIdempotencyInProgress)
+	}
+
+	if err := s.FinishIdempotentRequest(ctx, refundScope, "key-1", []byte("response")); err != nil {
+		t.Fatalf("FinishIdempotentRequest: %v", err)
+	}
+
+	stored, err = s.BeginIdempotentRequest(ctx, refundScope, "key-1", "hash-a")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if !bytes.Equal(stored, []byte("response")) {
+		t.Errorf("replayed response = %q, want %q", stored, "response")
+	}
+
+	// Same key, different body
+	if _, err := s.BeginIdempotentRequest(ctx, refundScope, "key-1", "hash-b"); !errors.Is(err, ErrIdempotencyConflict) {
+		t.Errorf("different request error = %v, want %v", err, ErrIdempotencyConflict)
+	}
+
+	// Keys are scoped per operation
+	if stored, err := s.BeginIdempotentRequest(ctx, model.IdempotencyScopePayout, "key-1", "hash-b"); err != nil || stored != nil {
+		t.Errorf("key in another scope = %q, %v; want it to run", stored, err)
+	}
+}
+
+func TestFailedIdempotentRequestCanRetry(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(newMemoryStore(), provider.NewFake(""))
+
+	if _, err := s.BeginIdempotentRequest(ctx, refundScope, "key-1", "hash-a"); err != nil {
+		t.Fatalf("BeginIdempotentRequest: %v", err)
+	}
+	if err := s.FinishIdempotentRequest(ctx, refundScope, "key-1", nil); err != nil {
+		t.Fatalf("FinishIdempotentRequest: %v", err)
+	}
+
+	// The key is free again, also for a corrected request
+	stored, err := s.BeginIdempotentRequest(ctx, refundScope, "key-1", "hash-b")
+	if err != nil || stored != nil {
+		t.Errorf("retry after failure = %q, %v; want it to run", stored, err)
+	}
+}
+
+func TestRefundRetryWithKeyRefundsOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	fake := provider.NewFake("")
+	s := newTestService(store, fake)
+	store.addPayment(1, 1, usd(25000), "ch_1")
+
+	first, err := s.CreateRefund(ctx, 1, usd(5000), "requested_by_customer", "key-1")
+	if err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+
+	// The provider call repeats when the response was lost before it was
+	// stored; the provider key returns the same refund
+	refund, err := fake.CreateRefund(ctx, provider.RefundParams{
+		ChargeID:       "ch_1",
+		Amount:         5000,
+		IdempotencyKey: providerIdempotencyKey(refundScope, "key-1"),
+	})
+	if err != nil {
+		t.Fatalf("provider retry: %v", err)
+	}
+	if refund.ID != first {
+		t.Errorf("provider retry refund = %s, want %s", refund.ID, first)
+	}
+	if n := len(fake.Refunds()); n != 1 {
+		t.Errorf("provider refunds = %d, want 1", n)
+	}
+}
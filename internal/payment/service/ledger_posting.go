@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// postIntentPayments posts the settled payments of an intent and the fee
+// the processor kept from its charge. Checkout payments share the charge,
+// so its fee is posted once, against the first of them.
+func (s *PaymentService) postIntentPayments(ctx context.Context, intentID string, charge *provider.Charge) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	payments, err := s.repo.GetPaymentsByIntentID(ctx, intentID)
+	if err != nil {
+		return err
+	}
+
+	var first *model.Payment
+	for _, payment := range payments {
+		if !payment.IsSuccessful() && payment.Status != model.StatusRefunded {
+			continue
+		}
+		if first == nil {
+			first = payment
+		}
+		if err := s.ledger.RecordPayment(ctx, payment.ID, payment.UserID, payment.Amount); err != nil {
+			return err
+		}
+	}
+
+	if first == nil || charge == nil || charge.Fee <= 0 {
+		return nil
+	}
+
+	currency := charge.FeeCurrency
+	if currency == "" {
+		currency = first.Amount.Currency
+	}
+
+	return s.ledger.RecordProcessorFee(ctx, first.ID, money.New(charge.Fee, currency))
+}
+
+// postRefund posts the refund that brought payment's refunded total to
+// refunded
+func (s *PaymentService) postRefund(ctx context.Context, payment *model.Payment, refunded money.Money) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	return s.ledger.RecordRefund(ctx, payment.ID, payment.UserID, payment.RefundedAmount, refunded)
+}
+
+// postPayout posts a payout paid to its seller
+func (s *PaymentService) postPayout(ctx context.Context, payout *model.Payout) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	return s.ledger.RecordPayout(ctx, payout.ID, payout.SellerID, payout.Amount)
+}
+
+// postChargeback posts a lost chargeback on payment
+func (s *PaymentService) postChargeback(ctx context.Context, cb *model.Chargeback, payment *model.Payment) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	return s.ledger.RecordChargeback(ctx, cb.ID, payment.UserID, cb.Amount)
+}
+
+// postClawback posts money reclaimed from the seller of payment's order
+func (s *PaymentService) postClawback(ctx context.Context, payment *model.Payment, sellerID int64, reference string, amount money.Money) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	return s.ledger.RecordClawback(ctx, payment.OrderID, sellerID, payment.UserID, reference, amount)
+}
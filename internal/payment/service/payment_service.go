@@ -4,82 +4,58 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
-
-	"github.com/stripe/stripe-go/v76"
-	"github.com/stripe/stripe-go/v76/paymentintent"
-	"github.com/stripe/stripe-go/v76/refund"
-	"github.com/stripe/stripe-go/v76/transfer"
+	"log"
+	"time"
 
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
 )
 
 type PaymentService struct {
-	repo       *repository.PaymentRepository
-	stripeMode string // "demo" or "real"
+	repo        repository.PaymentStore
+	provider    provider.PaymentProvider
+	orderClient orderPb.OrderServiceClient   // may be nil; webhooks then leave orders alone
+	ledger      *ledgerService.LedgerService // may be nil; nothing is posted then
+	payouts     PayoutPolicy
 }
 
-func NewPaymentService(repo *repository.PaymentRepository) *PaymentService {
-	// Initialize Stripe API key if in real mode
-	stripeMode := os.Getenv("STRIPE_MODE")
-	if stripeMode == "" {
-		stripeMode = "demo" // Default to demo
-	}
-
-	if stripeMode == "real" {
-		stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
-	}
-
+func NewPaymentService(repo repository.PaymentStore, provider provider.PaymentProvider, orderClient orderPb.OrderServiceClient, ledger *ledgerService.LedgerService, payouts PayoutPolicy) *PaymentService {
 	return &PaymentService{
-		repo:       repo,
-		stripeMode: stripeMode,
+		repo:        repo,
+		provider:    provider,
+		orderClient: orderClient,
+		ledger:      ledger,
+		payouts:     payouts,
 	}
 }
 
-// CreatePaymentIntent creates a Stripe PaymentIntent and payment record
+// CreatePaymentIntent creates a Stripe PaymentIntent and payment record.
+// The idempotency key, if any, also goes to Stripe, so a retry never
+// creates a second intent.
 func (s *PaymentService) CreatePaymentIntent(
 	ctx context.Context,
 	orderID, userID int64,
-	amount float64,
-	currency string,
-	stripeCustomerID string,
+	amount money.Money,
+	stripeCustomerID, idempotencyKey string,
 ) (*model.Payment, string, error) {
 	// Validate amount
-	if amount <= 0 {
+	if !amount.IsPositive() {
 		return nil, "", fmt.Errorf("amount must be greater than 0")
 	}
 
-	// Set default currency
-	if currency == "" {
-		currency = "USD"
-	}
-
-	var stripePaymentIntentID string
-	var clientSecret string
-
-	if s.stripeMode == "real" {
-		// REAL STRIPE: Create PaymentIntent
-		params := &stripe.PaymentIntentParams{
-			Amount:   stripe.Int64(int64(amount * 100)), // Convert to cents
-			Currency: stripe.String(currency),
-		}
-
-		if stripeCustomerID != "" {
-			params.Customer = stripe.String(stripeCustomerID)
-		}
-
-		pi, err := paymentintent.New(params)
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to create Stripe PaymentIntent: %w", err)
-		}
-
-		stripePaymentIntentID = pi.ID
-		clientSecret = pi.ClientSecret
-	} else {
-		// DEMO MODE: Simulate Stripe PaymentIntent
-		stripePaymentIntentID = fmt.Sprintf("pi_demo_%d", orderID)
-		clientSecret = fmt.Sprintf("pi_demo_%d_secret", orderID)
+	intent, err := s.provider.CreateIntent(ctx, provider.IntentParams{
+		Amount:         amount.Amount,
+		Currency:       amount.Currency,
+		CustomerID:     stripeCustomerID,
+		Metadata:       map[string]string{"order_id": fmt.Sprintf("%d", orderID)},
+		IdempotencyKey: providerIdempotencyKey(model.IdempotencyScopePaymentIntent, idempotencyKey),
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Create payment record
@@ -87,13 +63,13 @@ func (s *PaymentService) CreatePaymentIntent(
 		OrderID:  orderID,
 		UserID:   userID,
 		Amount:   amount,
-		Currency: currency,
+		Currency: amount.Currency,
 		Status:   model.StatusPending,
 	}
 
 	// Set Stripe details
 	payment.StripePaymentIntentID = sql.NullString{
-		String: stripePaymentIntentID,
+		String: intent.ID,
 		Valid:  true,
 	}
 
@@ -110,7 +86,67 @@ func (s *PaymentService) CreatePaymentIntent(
 		return nil, "", fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	return createdPayment, clientSecret, nil
+	return createdPayment, intent.ClientSecret, nil
+}
+
+// CreateCheckoutPaymentIntent creates one Stripe PaymentIntent for a cart
+// checkout and one payment record per order holding that order's share.
+// Refunds are then issued per order against the shared charge and can never
+// exceed the order's share.
+func (s *PaymentService) CreateCheckoutPaymentIntent(
+	ctx context.Context,
+	checkoutID, userID int64,
+	allocations []model.Allocation,
+	stripeCustomerID string,
+) ([]*model.Payment, string, error) {
+	if len(allocations) == 0 {
+		return nil, "", fmt.Errorf("at least one order is required")
+	}
+
+	// The intent equals the sum of the shares exactly
+	var total money.Money
+	for _, a := range allocations {
+		if !a.Amount.IsPositive() {
+			return nil, "", fmt.Errorf("amount for order %d must be greater than 0", a.OrderID)
+		}
+		if !total.SameCurrency(a.Amount) {
+			return nil, "", fmt.Errorf("checkout orders must share one currency")
+		}
+		total = total.Add(a.Amount)
+	}
+
+	// One intent for the whole cart
+	intent, err := s.provider.CreateIntent(ctx, provider.IntentParams{
+		Amount:     total.Amount,
+		Currency:   total.Currency,
+		CustomerID: stripeCustomerID,
+		Metadata:   map[string]string{"checkout_id": fmt.Sprintf("%d", checkoutID)},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	payments := make([]*model.Payment, len(allocations))
+	for i, a := range allocations {
+		payments[i] = &model.Payment{
+			OrderID:               a.OrderID,
+			UserID:                userID,
+			CheckoutID:            sql.NullInt64{Int64: checkoutID, Valid: true},
+			Amount:                a.Amount,
+			Currency:              a.Amount.Currency,
+			Status:                model.StatusPending,
+			StripePaymentIntentID: sql.NullString{String: intent.ID, Valid: true},
+		}
+		if stripeCustomerID != "" {
+			payments[i].StripeCustomerID = sql.NullString{String: stripeCustomerID, Valid: true}
+		}
+	}
+
+	if err := s.repo.CreateCheckoutPayments(ctx, payments); err != nil {
+		return nil, "", err
+	}
+
+	return payments, intent.ClientSecret, nil
 }
 
 // ConfirmPayment confirms a payment (called after Stripe confirms)
@@ -130,46 +166,45 @@ func (s *PaymentService) ConfirmPayment(
 		return nil, fmt.Errorf("payment intent ID mismatch")
 	}
 
-	var chargeID, paymentMethod, cardLast4, cardBrand string
-
-	if s.stripeMode == "real" {
-		// REAL STRIPE: Retrieve PaymentIntent to verify status
-		pi, err := paymentintent.Get(stripePaymentIntentID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve payment intent: %w", err)
-		}
-
-		if pi.Status != stripe.PaymentIntentStatusSucceeded {
-			return nil, fmt.Errorf("payment not succeeded: %s", pi.Status)
-		}
+	// Verify with the provider that the intent succeeded
+	intent, err := s.provider.GetIntent(ctx, stripePaymentIntentID)
+	if err != nil {
+		return nil, err
+	}
 
-		// Extract charge details from LatestCharge
-		if pi.LatestCharge != nil {
-			chargeID = pi.LatestCharge.ID
+	if intent.Status != provider.IntentSucceeded {
+		return nil, fmt.Errorf("payment not succeeded: %s", intent.Status)
+	}
 
-			if pi.LatestCharge.PaymentMethodDetails != nil && pi.LatestCharge.PaymentMethodDetails.Card != nil {
-				paymentMethod = "card"
-				cardLast4 = pi.LatestCharge.PaymentMethodDetails.Card.Last4
-				cardBrand = string(pi.LatestCharge.PaymentMethodDetails.Card.Brand)
-			}
-		}
+	var chargeID, paymentMethod, cardLast4, cardBrand string
+	if intent.Charge != nil {
+		chargeID = intent.Charge.ID
+		paymentMethod = intent.Charge.PaymentMethod
+		cardLast4 = intent.Charge.CardLast4
+		cardBrand = intent.Charge.CardBrand
+	}
+
+	// Update payment with charge details. Checkout payments share the
+	// charge, so all of them succeed together.
+	if payment.CheckoutID.Valid {
+		err = s.repo.UpdateCheckoutPaymentsWithCharge(
+			ctx, payment.CheckoutID.Int64,
+			chargeID, paymentMethod, cardLast4, cardBrand,
+		)
 	} else {
-		// DEMO MODE: Simulate successful payment
-		chargeID = fmt.Sprintf("ch_demo_%d", paymentID)
-		paymentMethod = "card"
-		cardLast4 = "4242"
-		cardBrand = "visa"
-	}
-
-	// Update payment with charge details
-	err = s.repo.UpdatePaymentWithCharge(
-		ctx, paymentID,
-		chargeID, paymentMethod, cardLast4, cardBrand,
-	)
+		err = s.repo.UpdatePaymentWithCharge(
+			ctx, paymentID,
+			chargeID, paymentMethod, cardLast4, cardBrand,
+		)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.postIntentPayments(ctx, stripePaymentIntentID, intent.Charge); err != nil {
+		log.Printf("Failed to post payment %d to the ledger: %v", paymentID, err)
+	}
+
 	// TODO: Update order status to 'paid' via Order Service
 	// orderClient.MarkAsPaid(ctx, payment.OrderID)
 
@@ -203,73 +238,74 @@ func (s *PaymentService) ListPayments(
 	return s.repo.ListPaymentsByUser(ctx, userID, status, page, pageSize)
 }
 
-// CreateRefund creates a refund for a payment
+// CreateRefund creates a refund for a payment. The idempotency key, if
+// any, also goes to Stripe, so a retry never refunds twice.
 func (s *PaymentService) CreateRefund(
 	ctx context.Context,
 	paymentID int64,
-	amount float64,
-	reason string,
+	amount money.Money,
+	reason, idempotencyKey string,
 ) (string, error) {
-	// Get payment
-	payment, err := s.repo.GetPaymentByID(ctx, paymentID)
-	if err != nil {
-		return "", err
-	}
-
-	// Validate payment is refundable
-	if !payment.IsRefundable() {
-		return "", fmt.Errorf("payment is not refundable (status: %s)", payment.Status)
-	}
-
-	// If amount not specified, refund full remaining amount
-	if amount <= 0 {
-		amount = payment.CanBeRefundedAmount()
-	}
-
-	// Validate refund amount
-	if amount > payment.CanBeRefundedAmount() {
-		return "", fmt.Errorf("refund amount exceeds available amount")
-	}
-
-	var stripeRefundID string
+	// Concurrent refunds of the payment wait for each other, so each is
+	// validated against what the previous ones left
+	var refundID string
+	payment, amount, err := s.repo.RefundPayment(ctx, paymentID, reason, func(payment *model.Payment) (money.Money, error) {
+		// Validate payment is refundable
+		if !payment.IsRefundable() {
+			return money.Money{}, fmt.Errorf("payment is not refundable (status: %s)", payment.Status)
+		}
 
-	if s.stripeMode == "real" {
-		// REAL STRIPE: Create refund
-		if !payment.StripeChargeID.Valid {
-			return "", fmt.Errorf("no Stripe charge ID found")
+		// If amount not specified, refund full remaining amount
+		if !amount.IsPositive() {
+			amount = payment.CanBeRefundedAmount()
+		}
+		if amount.Currency != payment.Amount.Currency {
+			return money.Money{}, fmt.Errorf("refund is in %s but the payment is in %s", amount.Currency, payment.Amount.Currency)
 		}
 
-		params := &stripe.RefundParams{
-			Charge: stripe.String(payment.StripeChargeID.String),
-			Amount: stripe.Int64(int64(amount * 100)),
+		// Validate refund amount
+		if amount.GreaterThan(payment.CanBeRefundedAmount()) {
+			return money.Money{}, fmt.Errorf("refund amount exceeds available amount")
 		}
-		if reason != "" {
-			params.Reason = stripe.String(reason)
+
+		if !payment.StripeChargeID.Valid {
+			return money.Money{}, fmt.Errorf("no Stripe charge ID found")
 		}
 
-		r, err := refund.New(params)
+		r, err := s.provider.CreateRefund(ctx, provider.RefundParams{
+			ChargeID:       payment.StripeChargeID.String,
+			Amount:         amount.Amount,
+			Reason:         reason,
+			Metadata:       map[string]string{"payment_id": fmt.Sprintf("%d", paymentID)},
+			IdempotencyKey: providerIdempotencyKey(model.IdempotencyScopeRefund, idempotencyKey),
+		})
 		if err != nil {
-			return "", fmt.Errorf("failed to create Stripe refund: %w", err)
+			return money.Money{}, err
 		}
 
-		stripeRefundID = r.ID
-	} else {
-		// DEMO MODE: Simulate Stripe refund
-		stripeRefundID = fmt.Sprintf("re_demo_%d", paymentID)
-	}
-
-	// Update payment with refund details
-	err = s.repo.UpdatePaymentRefund(ctx, paymentID, amount, reason)
+		refundID = r.ID
+		return amount, nil
+	})
 	if err != nil {
 		return "", err
 	}
 
-	// TODO: If fully refunded, cancel order via Order Service
-	// if amount >= payment.CanBeRefundedAmount() {
-	//     orderClient.CancelOrder(ctx, payment.OrderID, "Payment refunded")
-	// }
+	refunded := payment.RefundedAmount.Add(amount)
+	if err := s.postRefund(ctx, payment, refunded); err != nil {
+		log.Printf("Failed to post refund of payment %d to the ledger: %v", paymentID, err)
+	}
+
+	// Refunds after the sale was credited come out of the seller's wallet
+	if err := s.reclaimFromSeller(ctx, payment, amount, refundReference(paymentID, refunded)); err != nil {
+		log.Printf("Failed to reclaim refund of payment %d from the seller: %v", paymentID, err)
+	}
+
+	// The order records the refund; a full refund closes it
+	if err := s.applyOrderRefund(ctx, payment, amount, refunded, reason); err != nil {
+		log.Printf("Failed to apply refund of payment %d to order %d: %v", paymentID, payment.OrderID, err)
+	}
 
-	return stripeRefundID, nil
+	return refundID, nil
 }
 
 // GetRefundStatus retrieves refund information for a payment
@@ -279,7 +315,7 @@ func (s *PaymentService) GetRefundStatus(ctx context.Context, paymentID int64) (
 		return nil, err
 	}
 
-	if payment.RefundedAmount == 0 {
+	if payment.RefundedAmount.IsZero() {
 		return nil, fmt.Errorf("no refunds for this payment")
 	}
 
@@ -288,122 +324,252 @@ func (s *PaymentService) GetRefundStatus(ctx context.Context, paymentID int64) (
 
 // ========== Payout Methods ==========
 
-// CreatePayout creates a payout for a seller
+// CreatePayout pays a seller out for a completed order on demand. The
+// order's proceeds are credited to the seller's wallet, once, and the
+// wallet is paid out now whatever its schedule; the minimum payout still
+// applies. A connected account, if given, is set on a wallet without one.
 func (s *PaymentService) CreatePayout(
 	ctx context.Context,
-	orderID, sellerID, paymentID int64,
-	amount float64,
+	orderID, sellerID int64,
+	amount money.Money,
 	stripeAccountID string,
 ) (*model.Payout, error) {
-	// Validate amount
-	if amount <= 0 {
-		return nil, fmt.Errorf("amount must be greater than 0")
+	wallet, err := s.CreditSellerWallet(ctx, orderID, sellerID, amount)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate Stripe account ID
-	if stripeAccountID == "" {
-		return nil, fmt.Errorf("stripe account ID is required")
+	if stripeAccountID != "" && !wallet.StripeAccountID.Valid {
+		if _, err := s.UpdatePayoutSettings(ctx, sellerID, wallet.Currency, "", nil, stripeAccountID); err != nil {
+			return nil, err
+		}
 	}
 
-	var stripeTransferID string
+	return s.RequestPayout(ctx, sellerID, wallet.Currency)
+}
 
-	if s.stripeMode == "real" {
-		// REAL STRIPE: Create Transfer (Stripe Connect)
-		params := &stripe.TransferParams{
-			Amount:      stripe.Int64(int64(amount * 100)),
-			Currency:    stripe.String("usd"),
-			Destination: stripe.String(stripeAccountID),
-		}
+// GetPayout retrieves a payout by ID
+func (s *PaymentService) GetPayout(ctx context.Context, payoutID int64) (*model.Payout, error) {
+	return s.repo.GetPayoutByID(ctx, payoutID)
+}
 
-		t, err := transfer.New(params)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create Stripe transfer: %w", err)
+// ListPayouts lists payouts for a seller
+func (s *PaymentService) ListPayouts(
+	ctx context.Context,
+	sellerID int64,
+	status string,
+	page, pageSize int32,
+) ([]*model.Payout, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.repo.ListPayoutsBySeller(ctx, sellerID, status, page, pageSize)
+}
+
+// ========== Bid Authorization Methods ==========
+
+// AuthorizeBid places a manual-capture hold on the buyer's payment method.
+// The bid only becomes active once this succeeds.
+func (s *PaymentService) AuthorizeBid(
+	ctx context.Context,
+	bidID, userID int64,
+	amount money.Money,
+	stripeCustomerID, paymentMethodID string,
+) (*model.Authorization, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be greater than 0")
+	}
+
+	auth := &model.Authorization{
+		BidID:    bidID,
+		UserID:   userID,
+		Amount:   amount,
+		Currency: amount.Currency,
+		Status:   model.AuthorizationStatusAuthorized,
+	}
+
+	if stripeCustomerID != "" {
+		auth.StripeCustomerID = sql.NullString{String: stripeCustomerID, Valid: true}
+	}
+	if paymentMethodID != "" {
+		auth.StripePaymentMethodID = sql.NullString{String: paymentMethodID, Valid: true}
+	}
+
+	// Create and confirm a manual-capture intent
+	var holdErr error
+	intent, err := s.provider.CreateIntent(ctx, provider.IntentParams{
+		Amount:          amount.Amount,
+		Currency:        amount.Currency,
+		CustomerID:      stripeCustomerID,
+		PaymentMethodID: paymentMethodID,
+		ManualCapture:   true,
+		Confirm:         true,
+		OffSession:      true,
+		Metadata:        map[string]string{"bid_id": fmt.Sprintf("%d", bidID)},
+	})
+	if err != nil {
+		holdErr = fmt.Errorf("failed to authorize payment method: %w", err)
+	} else {
+		auth.StripePaymentIntentID = sql.NullString{String: intent.ID, Valid: true}
+		if intent.Status != provider.IntentRequiresCapture {
+			holdErr = fmt.Errorf("payment method was not authorized: %s", intent.Status)
 		}
+	}
 
-		stripeTransferID = t.ID
+	if holdErr != nil {
+		auth.Status = model.AuthorizationStatusFailed
+		auth.FailureReason = sql.NullString{String: holdErr.Error(), Valid: true}
 	} else {
-		// DEMO MODE: Simulate transfer
-		stripeTransferID = "tr_demo_immediate"
+		auth.AuthorizedAt = sql.NullTime{Time: time.Now(), Valid: true}
 	}
 
-	// Create payout record
-	payout := &model.Payout{
-		OrderID:   orderID,
-		SellerID:  sellerID,
-		PaymentID: paymentID,
-		Amount:    amount,
-		Currency:  "USD",
-		Status:    model.PayoutStatusPending,
+	createdAuth, err := s.repo.CreateAuthorization(ctx, auth)
+	if err != nil {
+		return nil, err
 	}
 
-	payout.StripeAccountID = sql.NullString{
-		String: stripeAccountID,
-		Valid:  true,
+	if holdErr != nil {
+		return createdAuth, holdErr
 	}
 
-	// Create in database
-	createdPayout, err := s.repo.CreatePayout(ctx, payout)
+	return createdAuth, nil
+}
+
+// CaptureBidAuthorization captures the held funds once the bid is matched.
+// The captured amount may be lower than the hold (the match price is the ask price).
+// The capture becomes the payment of the match's order, which is marked
+// paid. Retrying after a failure settles the order without capturing twice.
+func (s *PaymentService) CaptureBidAuthorization(
+	ctx context.Context,
+	bidID, matchID int64,
+	amount money.Money,
+) (*model.Authorization, error) {
+	auth, err := s.repo.GetAuthorizationByBidID(ctx, bidID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create payout: %w", err)
+		return nil, err
 	}
 
-	// Update status to paid (in real app, this would be updated via webhook)
-	err = s.repo.UpdatePayoutStatus(ctx, createdPayout.ID, model.PayoutStatusPaid, stripeTransferID)
+	// An earlier attempt captured the hold but did not settle the order
+	captured := auth.Status == model.AuthorizationStatusCaptured && auth.MatchID.Int64 == matchID
+
+	if !captured {
+		if !auth.IsHeld() {
+			return nil, fmt.Errorf("authorization cannot be captured (status: %s)", auth.Status)
+		}
+
+		if amount.IsPositive() && amount.Currency != auth.Amount.Currency {
+			return nil, fmt.Errorf("capture is in %s but the hold is in %s", amount.Currency, auth.Amount.Currency)
+		}
+
+		// Capture the full hold if amount not specified
+		if !amount.IsPositive() || amount.GreaterThan(auth.Amount) {
+			amount = auth.Amount
+		}
+
+		if _, err := s.provider.CaptureIntent(ctx, auth.StripePaymentIntentID.String, amount.Amount); err != nil {
+			return nil, err
+		}
+
+		if err := s.repo.MarkAuthorizationCaptured(ctx, auth.ID, matchID, amount); err != nil {
+			return nil, err
+		}
+	}
+
+	auth, err = s.repo.GetAuthorizationByBidID(ctx, bidID)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.repo.GetPayoutByID(ctx, createdPayout.ID)
+	if err := s.settleCapturedMatch(ctx, auth); err != nil {
+		return nil, fmt.Errorf("authorization captured but match %d was not settled: %w", matchID, err)
+	}
+
+	return auth, nil
 }
 
-// GetPayout retrieves a payout by ID
-func (s *PaymentService) GetPayout(ctx context.Context, payoutID int64) (*model.Payout, error) {
-	return s.repo.GetPayoutByID(ctx, payoutID)
+// settleCapturedMatch records a captured hold as the payment of its match's
+// order, creating the order if needed, then settles it like a payment
+// confirmed by Stripe. Without an Order Service there is no order, and the
+// capture is posted on its own.
+func (s *PaymentService) settleCapturedMatch(ctx context.Context, auth *model.Authorization) error {
+	if s.orderClient == nil {
+		if s.ledger != nil {
+			if err := s.ledger.RecordCapture(ctx, auth.ID, auth.UserID, auth.CapturedAmount); err != nil {
+				log.Printf("Failed to post capture of authorization %d to the ledger: %v", auth.ID, err)
+			}
+		}
+		return nil
+	}
+
+	resp, err := s.orderClient.CreateOrder(ctx, &orderPb.CreateOrderRequest{MatchId: auth.MatchID.Int64})
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("failed to create order: %s", resp.Error)
+	}
+
+	intentID := auth.StripePaymentIntentID.String
+	payments, err := s.repo.GetPaymentsByIntentID(ctx, intentID)
+	if err != nil {
+		return err
+	}
+
+	if len(payments) == 0 {
+		_, err := s.repo.CreatePayment(ctx, &model.Payment{
+			OrderID:               resp.Order.Id,
+			UserID:                auth.UserID,
+			Amount:                auth.CapturedAmount,
+			Currency:              auth.CapturedAmount.Currency,
+			Status:                model.StatusPending,
+			StripePaymentIntentID: auth.StripePaymentIntentID,
+			StripeCustomerID:      auth.StripeCustomerID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	intent, err := s.provider.GetIntent(ctx, intentID)
+	if err != nil {
+		return err
+	}
+
+	return s.handleIntentSucceeded(ctx, intent)
 }
 
-// ListPayouts lists payouts for a seller
-func (s *PaymentService) ListPayouts(
+// ReleaseBidAuthorization cancels the hold when a bid is canceled or expires
+func (s *PaymentService) ReleaseBidAuthorization(
 	ctx context.Context,
-	sellerID int64,
-	status string,
-	page, pageSize int32,
-) ([]*model.Payout, int64, error) {
-	if page < 1 {
-		page = 1
+	bidID int64,
+	reason string,
+) (*model.Authorization, error) {
+	auth, err := s.repo.GetAuthorizationByBidID(ctx, bidID)
+	if err != nil {
+		return nil, err
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+
+	if !auth.IsHeld() {
+		return nil, fmt.Errorf("authorization cannot be released (status: %s)", auth.Status)
 	}
 
-	return s.repo.ListPayoutsBySeller(ctx, sellerID, status, page, pageSize)
-}
+	// Cancel the intent to release the hold
+	if _, err := s.provider.CancelIntent(ctx, auth.StripePaymentIntentID.String); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MarkAuthorizationReleased(ctx, auth.ID, reason); err != nil {
+		return nil, err
+	}
 
-// HandleStripeWebhook handles Stripe webhook events
-func (s *PaymentService) HandleStripeWebhook(ctx context.Context, payload []byte, signature string) (string, error) {
-	if s.stripeMode == "demo" {
-		// DEMO MODE: Just return success
-		return "payment_intent.succeeded", nil
-	}
-
-	// REAL STRIPE: Verify webhook signature
-	// webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
-	// event, err := webhook.ConstructEvent(payload, signature, webhookSecret)
-	// if err != nil {
-	//     return "", fmt.Errorf("webhook signature verification failed: %w", err)
-	// }
-
-	// TODO: Handle different event types:
-	// - payment_intent.succeeded → Update payment status
-	// - payment_intent.payment_failed → Mark payment as failed
-	// - charge.refunded → Update refund status
-	// - transfer.created → Update payout status
-	// - transfer.failed → Mark payout as failed
-
-	// For now, return success
-	return "payment_intent.succeeded", nil
+	return s.repo.GetAuthorizationByBidID(ctx, bidID)
 }
 
-// GetStripeMode returns current Stripe mode (demo or real)
-func (s *PaymentService) GetStripeMode() string {
-	return s.stripeMode
+// ProviderName returns the payment provider in use ("stripe" or "fake")
+func (s *PaymentService) ProviderName() string {
+	return s.provider.Name()
 }
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
+)
+
+// orderClient records the refunds applied to orders
+type orderClient struct {
+	orderPb.OrderServiceClient
+	refunds []*orderPb.ApplyRefundRequest
+}
+
+func (c *orderClient) ApplyRefund(ctx context.Context, in *orderPb.ApplyRefundRequest, opts ...grpc.CallOption) (*orderPb.ApplyRefundResponse, error) {
+	c.refunds = append(c.refunds, in)
+	return &orderPb.ApplyRefundResponse{}, nil
+}
+
+func TestCreateRefundCascadesToOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount money.Money
+		status string
+		full   bool
+	}{
+		{"full", money.Money{}, model.StatusRefunded, true},
+		{"partial", usd(5000), model.StatusPartiallyRefunded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newMemoryStore()
+			fake := provider.NewFake("")
+			orders := &orderClient{}
+			s := NewPaymentService(store, fake, orders, nil, PayoutPolicy{DefaultSchedule: model.PayoutScheduleDaily})
+			store.completeOrder(1, seller)
+			store.addPayment(1, 1, usd(25000), "ch_1")
+			creditSale(t, s, 1, usd(25000))
+
+			if _, err := s.CreateRefund(ctx, 1, tt.amount, "requested_by_customer", ""); err != nil {
+				t.Fatalf("CreateRefund: %v", err)
+			}
+
+			refunded := usd(25000)
+			if tt.amount.IsPositive() {
+				refunded = tt.amount
+			}
+			if sent := fake.Refunds(); len(sent) != 1 || sent[0].Amount != refunded.Amount {
+				t.Errorf("provider refunds = %+v, want one of %d", sent, refunded.Amount)
+			}
+			if status := store.payments[1].Status; status != tt.status {
+				t.Errorf("payment status = %s, want %s", status, tt.status)
+			}
+
+			// The seller bears the refund
+			wallet, _, err := s.GetSellerWallet(ctx, seller, "")
+			if err != nil {
+				t.Fatalf("GetSellerWallet: %v", err)
+			}
+			if want := usd(25000).Sub(refunded); !wallet.Balance.Equal(want) {
+				t.Errorf("seller balance = %s, want %s", wallet.Balance, want)
+			}
+
+			// The order closes on a full refund and records a partial one
+			if len(orders.refunds) != 1 {
+				t.Fatalf("order refunds applied = %d, want 1", len(orders.refunds))
+			}
+			applied := orders.refunds[0]
+			if applied.OrderId != 1 || applied.AmountMinor != refunded.Amount || applied.Full != tt.full {
+				t.Errorf("order refund = %+v, want order 1, %d, full %v", applied, refunded.Amount, tt.full)
+			}
+			if want := refundReference(1, refunded); applied.Reference != want {
+				t.Errorf("order refund reference = %s, want %s", applied.Reference, want)
+			}
+		})
+	}
+}
+
+func TestCreateRefundRejectsOverRefund(t *testing.T) {
+	store := newMemoryStore()
+	fake := provider.NewFake("")
+	orders := &orderClient{}
+	s := NewPaymentService(store, fake, orders, nil, PayoutPolicy{})
+	store.addPayment(1, 1, usd(25000), "ch_1")
+
+	if _, err := s.CreateRefund(context.Background(), 1, usd(30000), "requested_by_customer", ""); err == nil {
+		t.Fatal("refund above the payment succeeded")
+	}
+	if len(fake.Refunds()) != 0 || len(orders.refunds) != 0 {
+		t.Error("rejected refund reached the provider or the order")
+	}
+}
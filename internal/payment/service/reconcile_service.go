@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/reconcile"
+)
+
+// ReconcilePayments compares the provider's charges, refunds and transfers
+// created in [from, to) with payments and payouts and reports the
+// mismatches. With repair, mismatches a missed webhook explains are
+// repaired by applying the provider's state the way the webhook would
+// have; everything else is left for a person.
+func (s *PaymentService) ReconcilePayments(ctx context.Context, from, to time.Time, repair bool) (*reconcile.Report, error) {
+	stmt, err := reconcile.Fetch(ctx, s.provider, provider.ListParams{From: from, To: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch provider statement: %w", err)
+	}
+
+	records := &reconcile.Records{}
+	records.Payments, err = s.repo.ListPaymentsForReconciliation(ctx, from, to, stmt.IntentIDs(), stmt.ChargeIDs())
+	if err != nil {
+		return nil, err
+	}
+	records.Payouts, err = s.repo.ListPayoutsForReconciliation(ctx, from, to, stmt.TransferIDs(), stmt.PayoutIDs(), stmt.OrderIDs())
+	if err != nil {
+		return nil, err
+	}
+	records.HoldIntents, err = s.repo.GetHoldIntentIDs(ctx, stmt.IntentIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	report := reconcile.Compare(stmt, records)
+	if !repair {
+		return report, nil
+	}
+
+	for _, m := range report.Mismatches {
+		if m.Repair == "" {
+			continue
+		}
+		if err := s.repairMismatch(ctx, m); err != nil {
+			m.RepairError = err.Error()
+			continue
+		}
+		m.Repaired = true
+	}
+
+	return report, nil
+}
+
+// repairMismatch applies a repairable mismatch through the webhook handler
+// that would have prevented it. The handlers are idempotent, so a repair
+// racing a late webhook applies once.
+func (s *PaymentService) repairMismatch(ctx context.Context, m *reconcile.Mismatch) error {
+	switch m.Repair {
+	case reconcile.RepairSettlePayments:
+		intent, err := s.provider.GetIntent(ctx, m.Charge.IntentID)
+		if err != nil {
+			return err
+		}
+		if intent.Status != provider.IntentSucceeded {
+			return fmt.Errorf("intent %s is %s", intent.ID, intent.Status)
+		}
+		return s.handleIntentSucceeded(ctx, intent)
+
+	case reconcile.RepairSyncRefunds:
+		return s.handleChargeRefunded(ctx, m.Charge)
+
+	case reconcile.RepairConfirmPayout:
+		// The transfer went out but the payout never stored it
+		payout, err := s.repo.GetPayoutByID(ctx, m.PayoutID)
+		if err != nil {
+			return err
+		}
+		if !payout.StripeTransferID.Valid {
+			if err := s.repo.MarkPayoutProcessing(ctx, payout.ID, m.Transfer.ID); err != nil {
+				return err
+			}
+		}
+		return s.handleTransferCreated(ctx, m.Transfer)
+
+	default:
+		return fmt.Errorf("unknown repair: %s", m.Repair)
+	}
+}
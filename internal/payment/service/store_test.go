@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// memoryStore is an in-memory PaymentStore for service tests. It keeps the
+// rules the Postgres repository enforces in SQL: a sale is credited once,
+// a payout is returned once and each clawback reference is reclaimed once.
+// Methods no test needs panic through the nil embedded store.
+type memoryStore struct {
+	repository.PaymentStore
+
+	orders      map[int64]*model.PayoutHold // order status, seller and open dispute
+	transferred map[int64]bool              // orders paid by a transfer of their own
+	wallets     map[int64]*model.Wallet
+	sales       map[int64]*sale // by order
+	payouts     map[int64]*model.Payout
+	returned    map[int64]bool         // payouts credited back to their wallet
+	clawbacks   map[string]money.Money // by reference
+	payments    map[int64]*model.Payment
+	chargebacks []*model.Chargeback
+	keys        map[string]*model.IdempotencyKey
+	seq         int64
+}
+
+// sale is an order's credit in a wallet
+type sale struct {
+	amount    money.Money
+	reclaimed money.Money
+	walletID  int64
+	payoutID  int64
+	frozen    bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		orders:      make(map[int64]*model.PayoutHold),
+		transferred: make(map[int64]bool),
+		wallets:     make(map[int64]*model.Wallet),
+		sales:       make(map[int64]*sale),
+		payouts:     make(map[int64]*model.Payout),
+		returned:    make(map[int64]bool),
+		clawbacks:   make(map[string]money.Money),
+		payments:    make(map[int64]*model.Payment),
+		keys:        make(map[string]*model.IdempotencyKey),
+	}
+}
+
+func (m *memoryStore) nextID() int64 {
+	m.seq++
+	return m.seq
+}
+
+// wallet returns the seller's wallet in w's currency, opening it from w
+func (m *memoryStore) wallet(w *model.Wallet) *model.Wallet {
+	for _, wallet := range m.wallets {
+		if wallet.SellerID == w.SellerID && wallet.Currency == w.Currency {
+			return wallet
+		}
+	}
+
+	wallet := *w
+	wallet.ID = m.nextID()
+	wallet.Balance = money.Zero(w.Currency)
+	wallet.FrozenBalance = money.Zero(w.Currency)
+	m.wallets[wallet.ID] = &wallet
+	return &wallet
+}
+
+func copyWallet(w *model.Wallet) *model.Wallet {
+	copied := *w
+	return &copied
+}
+
+func copyPayout(p *model.Payout) *model.Payout {
+	copied := *p
+	return &copied
+}
+
+func (m *memoryStore) GetPayoutHold(ctx context.Context, orderID int64) (*model.PayoutHold, error) {
+	order, ok := m.orders[orderID]
+	if !ok {
+		return nil, fmt.Errorf("order not found")
+	}
+
+	hold := *order
+	for _, cb := range m.chargebacks {
+		if cb.OrderID == orderID && cb.Status == model.ChargebackStatusOpen {
+			hold.OpenChargeback = true
+		}
+	}
+	return &hold, nil
+}
+
+func (m *memoryStore) CreditSale(ctx context.Context, w *model.Wallet, orderID int64) (*model.Wallet, bool, error) {
+	if m.transferred[orderID] {
+		return nil, false, fmt.Errorf("order %d was already paid out by transfer", orderID)
+	}
+
+	amount := w.Balance
+	wallet := m.wallet(w)
+	if _, ok := m.sales[orderID]; ok {
+		return copyWallet(wallet), false, nil
+	}
+
+	wallet.Balance = wallet.Balance.Add(amount)
+	m.sales[orderID] = &sale{amount: amount, reclaimed: money.Zero(amount.Currency), walletID: wallet.ID}
+	return copyWallet(wallet), true, nil
+}
+
+func (m *memoryStore) GetWallet(ctx context.Context, sellerID int64, currency string) (*model.Wallet, error) {
+	for _, wallet := range m.wallets {
+		if wallet.SellerID == sellerID && wallet.Currency == currency {
+			return copyWallet(wallet), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetWalletTransactions keeps no history; tests check balances
+func (m *memoryStore) GetWalletTransactions(ctx context.Context, walletID int64, limit int32) ([]*model.WalletTransaction, error) {
+	return []*model.WalletTransaction{}, nil
+}
+
+func (m *memoryStore) GetDueWallets(ctx context.Context, now time.Time, limit int) ([]*model.Wallet, error) {
+	due := make([]*model.Wallet, 0)
+	for _, wallet := range m.wallets {
+		if wallet.NextPayoutAt.Valid && !wallet.NextPayoutAt.Time.After(now) {
+			due = append(due, copyWallet(wallet))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (m *memoryStore) SetNextPayoutAt(ctx context.Context, walletID int64, next sql.NullTime) error {
+	m.wallets[walletID].NextPayoutAt = next
+	return nil
+}
+
+func (m *memoryStore) GetSaleCredit(ctx context.Context, orderID int64) (*model.SaleCredit, error) {
+	s, ok := m.sales[orderID]
+	if !ok {
+		return nil, nil
+	}
+	return &model.SaleCredit{Amount: s.amount, SellerID: m.wallets[s.walletID].SellerID, PayoutID: s.payoutID}, nil
+}
+
+func (m *memoryStore) ClawbackSale(ctx context.Context, w *model.Wallet, orderID int64, credit *model.SaleCredit, amount money.Money, reference string) (*model.Clawback, error) {
+	wallet := m.wallet(w)
+	s := m.sales[orderID]
+
+	if _, ok := m.clawbacks[reference]; ok {
+		return nil, nil
+	}
+	due := money.Min(amount, credit.Amount.Sub(s.reclaimed))
+	if !due.IsPositive() {
+		return nil, nil
+	}
+
+	wallet.Balance = wallet.Balance.Sub(due)
+	s.reclaimed = s.reclaimed.Add(due)
+	m.clawbacks[reference] = due
+
+	clawback := &model.Clawback{Amount: due, PayoutID: credit.PayoutID}
+	if payout, ok := m.payouts[credit.PayoutID]; ok && payout.Status == model.PayoutStatusPaid {
+		if !s.reclaimed.LessThan(payout.Amount) {
+			payout.Status = model.PayoutStatusReversed
+			clawback.Reversed = true
+		}
+	}
+	return clawback, nil
+}
+
+func (m *memoryStore) FreezeSale(ctx context.Context, orderID int64) (bool, error) {
+	s, ok := m.sales[orderID]
+	if !ok || s.frozen || s.payoutID != 0 {
+		return false, nil
+	}
+
+	s.frozen = true
+	wallet := m.wallets[s.walletID]
+	wallet.FrozenBalance = wallet.FrozenBalance.Add(s.amount)
+	return true, nil
+}
+
+func (m *memoryStore) UnfreezeSale(ctx context.Context, orderID int64) (bool, error) {
+	s, ok := m.sales[orderID]
+	if !ok || !s.frozen {
+		return false, nil
+	}
+	for _, cb := range m.chargebacks {
+		if cb.OrderID == orderID && cb.Status == model.ChargebackStatusOpen {
+			return false, nil
+		}
+	}
+
+	s.frozen = false
+	wallet := m.wallets[s.walletID]
+	wallet.FrozenBalance = wallet.FrozenBalance.Sub(s.amount)
+	return true, nil
+}
+
+func (m *memoryStore) BeginWalletPayout(ctx context.Context, walletID int64, next sql.NullTime) (*model.Payout, error) {
+	wallet := m.wallets[walletID]
+	if !wallet.CanPayout() {
+		return nil, nil
+	}
+
+	payout := &model.Payout{
+		ID:              m.nextID(),
+		SellerID:        wallet.SellerID,
+		WalletID:        wallet.ID,
+		StripeAccountID: wallet.StripeAccountID,
+		Amount:          wallet.Payable(),
+		Currency:        wallet.Currency,
+		Status:          model.PayoutStatusPending,
+	}
+	m.payouts[payout.ID] = payout
+
+	for _, s := range m.sales {
+		if s.walletID == wallet.ID && s.payoutID == 0 && !s.frozen {
+			s.payoutID = payout.ID
+		}
+	}
+	wallet.Balance = wallet.FrozenBalance
+	wallet.NextPayoutAt = next
+
+	return copyPayout(payout), nil
+}
+
+func (m *memoryStore) MarkPayoutProcessing(ctx context.Context, payoutID int64, transferID string) error {
+	payout := m.payouts[payoutID]
+	if payout.Status == model.PayoutStatusPending {
+		payout.Status = model.PayoutStatusProcessing
+		payout.StripeTransferID = sql.NullString{String: transferID, Valid: true}
+	}
+	return nil
+}
+
+func (m *memoryStore) MarkPayoutFailed(ctx context.Context, payoutID int64, reason string) error {
+	payout := m.payouts[payoutID]
+	if payout.Status == model.PayoutStatusPending || payout.Status == model.PayoutStatusProcessing {
+		payout.Status = model.PayoutStatusFailed
+		payout.FailureReason = sql.NullString{String: reason, Valid: true}
+	}
+	return nil
+}
+
+func (m *memoryStore) ReturnPayoutToWallet(ctx context.Context, payout *model.Payout) (bool, error) {
+	if m.returned[payout.ID] {
+		return false, nil
+	}
+	m.returned[payout.ID] = true
+
+	wallet := m.wallets[payout.WalletID]
+	wallet.Balance = wallet.Balance.Add(payout.Amount)
+	for _, s := range m.sales {
+		if s.payoutID == payout.ID {
+			s.payoutID = 0
+		}
+	}
+	return true, nil
+}
+
+func (m *memoryStore) GetPayoutByID(ctx context.Context, payoutID int64) (*model.Payout, error) {
+	payout, ok := m.payouts[payoutID]
+	if !ok {
+		return nil, fmt.Errorf("payout not found")
+	}
+	return copyPayout(payout), nil
+}
+
+func (m *memoryStore) GetPaymentByID(ctx context.Context, paymentID int64) (*model.Payment, error) {
+	payment, ok := m.payments[paymentID]
+	if !ok {
+		return nil, fmt.Errorf("payment not found")
+	}
+	copied := *payment
+	return &copied, nil
+}
+
+func (m *memoryStore) GetPaymentsByChargeID(ctx context.Context, chargeID string) ([]*model.Payment, error) {
+	payments := make([]*model.Payment, 0)
+	for _, payment := range m.payments {
+		if payment.StripeChargeID.String == chargeID {
+			copied := *payment
+			payments = append(payments, &copied)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].ID < payments[j].ID })
+	return payments, nil
+}
+
+func (m *memoryStore) RefundPayment(ctx context.Context, paymentID int64, reason string, refund func(payment *model.Payment) (money.Money, error)) (*model.Payment, money.Money, error) {
+	payment, err := m.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	amount, err := refund(payment)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	stored := m.payments[paymentID]
+	stored.RefundedAmount = stored.RefundedAmount.Add(amount)
+	stored.RefundReason = sql.NullString{String: reason, Valid: true}
+	stored.Status = model.StatusPartiallyRefunded
+	if !stored.RefundedAmount.LessThan(stored.Amount) {
+		stored.Status = model.StatusRefunded
+	}
+
+	return payment, amount, nil
+}
+
+func (m *memoryStore) OpenChargeback(ctx context.Context, cb *model.Chargeback) (bool, error) {
+	for _, existing := range m.chargebacks {
+		if existing.StripeDisputeID == cb.StripeDisputeID && existing.PaymentID == cb.PaymentID {
+			return false, nil
+		}
+	}
+
+	cb.ID = m.nextID()
+	cb.Status = model.ChargebackStatusOpen
+	copied := *cb
+	m.chargebacks = append(m.chargebacks, &copied)
+	return true, nil
+}
+
+func (m *memoryStore) CloseChargebacks(ctx context.Context, disputeID, status string) ([]*model.Chargeback, error) {
+	closed := make([]*model.Chargeback, 0)
+	for _, cb := range m.chargebacks {
+		if cb.StripeDisputeID != disputeID {
+			continue
+		}
+		if cb.Status == model.ChargebackStatusOpen {
+			cb.Status = status
+		}
+		copied := *cb
+		closed = append(closed, &copied)
+	}
+	return closed, nil
+}
+
+func (m *memoryStore) BeginIdempotentRequest(ctx context.Context, k *model.IdempotencyKey, stale time.Duration) (bool, error) {
+	id := k.Scope + ":" + k.Key
+	if _, ok := m.keys[id]; ok {
+		return false, nil
+	}
+
+	stored := *k
+	stored.Status = model.IdempotencyProcessing
+	m.keys[id] = &stored
+	return true, nil
+}
+
+func (m *memoryStore) GetIdempotencyKey(ctx context.Context, scope, key string) (*model.IdempotencyKey, error) {
+	k, ok := m.keys[scope+":"+key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *k
+	return &copied, nil
+}
+
+func (m *memoryStore) CompleteIdempotentRequest(ctx context.Context, scope, key string, response []byte) error {
+	k := m.keys[scope+":"+key]
+	k.Status = model.IdempotencyCompleted
+	k.Response = response
+	return nil
+}
+
+func (m *memoryStore) ReleaseIdempotentRequest(ctx context.Context, scope, key string) error {
+	delete(m.keys, scope+":"+key)
+	return nil
+}
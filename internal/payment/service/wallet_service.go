@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+// PayoutPolicy holds the payout settings new seller wallets start with
+type PayoutPolicy struct {
+	DefaultSchedule string
+	MinimumPayout   money.Money // applies to wallets in its currency; others have none
+}
+
+// payoutBatchSize caps how many wallets one sweep pays out
+const payoutBatchSize = 100
+
+// walletTransactionsLimit caps the history returned with a wallet
+const walletTransactionsLimit = 50
+
+// newWallet returns the settings a seller's wallet in currency starts with
+func (s *PaymentService) newWallet(sellerID int64, currency string) *model.Wallet {
+	currency = strings.ToUpper(currency)
+
+	minimum := money.Zero(currency)
+	if s.payouts.MinimumPayout.Currency == currency {
+		minimum = s.payouts.MinimumPayout
+	}
+
+	return &model.Wallet{
+		SellerID:       sellerID,
+		Currency:       currency,
+		Balance:        money.Zero(currency),
+		PayoutSchedule: s.payouts.DefaultSchedule,
+		MinimumPayout:  minimum,
+		NextPayoutAt:   model.NextPayoutAt(s.payouts.DefaultSchedule, time.Now()),
+	}
+}
+
+// CreditSellerWallet adds a completed order's proceeds to the seller's
+// wallet. Crediting the same order again changes nothing. Funds are held
+// until the order is completed and its dispute resolved; a sale under an
+// open chargeback is credited frozen.
+func (s *PaymentService) CreditSellerWallet(ctx context.Context, orderID, sellerID int64, amount money.Money) (*model.Wallet, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be greater than 0")
+	}
+
+	hold, err := s.repo.GetPayoutHold(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if hold.SellerID != sellerID {
+		return nil, fmt.Errorf("order %d was not sold by seller %d", orderID, sellerID)
+	}
+	if !hold.OpenChargeback {
+		if reason := hold.Reason(); reason != "" {
+			return nil, fmt.Errorf("order %d: %s", orderID, reason)
+		}
+	}
+
+	w := s.newWallet(sellerID, amount.Currency)
+	w.Balance = amount
+
+	wallet, credited, err := s.repo.CreditSale(ctx, w, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !credited {
+		log.Printf("Order %d was already credited to seller %d", orderID, sellerID)
+	}
+
+	if hold.OpenChargeback {
+		frozen, err := s.repo.FreezeSale(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if frozen {
+			wallet.FrozenBalance = wallet.FrozenBalance.Add(amount)
+		}
+	}
+
+	return wallet, nil
+}
+
+// GetSellerWallet returns a seller's wallet with its latest transactions. A
+// seller with no sales yet gets an empty wallet with the default settings.
+func (s *PaymentService) GetSellerWallet(ctx context.Context, sellerID int64, currency string) (*model.Wallet, []*model.WalletTransaction, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, sellerID, strings.ToUpper(currency))
+	if err != nil {
+		return nil, nil, err
+	}
+	if wallet == nil {
+		return s.newWallet(sellerID, currency), []*model.WalletTransaction{}, nil
+	}
+
+	transactions, err := s.repo.GetWalletTransactions(ctx, wallet.ID, walletTransactionsLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return wallet, transactions, nil
+}
+
+// UpdatePayoutSettings changes a seller's payout schedule, minimum and
+// connected account. Empty values keep the current setting. A new schedule
+// restarts from now.
+func (s *PaymentService) UpdatePayoutSettings(
+	ctx context.Context,
+	sellerID int64,
+	currency, schedule string,
+	minimum *money.Money,
+	stripeAccountID string,
+) (*model.Wallet, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if schedule != "" && !model.ValidPayoutSchedule(schedule) {
+		return nil, fmt.Errorf("invalid payout schedule: %s", schedule)
+	}
+	if minimum != nil && minimum.IsNegative() {
+		return nil, fmt.Errorf("minimum payout cannot be negative")
+	}
+
+	wallet, err := s.repo.OpenWallet(ctx, s.newWallet(sellerID, currency))
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule != "" && schedule != wallet.PayoutSchedule {
+		wallet.PayoutSchedule = schedule
+		wallet.NextPayoutAt = model.NextPayoutAt(schedule, time.Now())
+	}
+	if minimum != nil {
+		wallet.MinimumPayout = minimum.WithCurrency(wallet.Currency)
+	}
+	if stripeAccountID != "" {
+		wallet.StripeAccountID = sql.NullString{String: stripeAccountID, Valid: true}
+	}
+
+	if err := s.repo.UpdateWalletSettings(ctx, wallet); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+// RequestPayout pays out a seller's wallet now, whatever its schedule. The
+// minimum payout still applies.
+func (s *PaymentService) RequestPayout(ctx context.Context, sellerID int64, currency string) (*model.Payout, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, sellerID, strings.ToUpper(currency))
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil || !wallet.CanPayout() {
+		balance := money.Zero(strings.ToUpper(currency))
+		minimum := balance
+		if wallet != nil {
+			balance, minimum = wallet.Balance, wallet.MinimumPayout
+		}
+		return nil, fmt.Errorf("balance %s is below the minimum payout of %s", balance, minimum)
+	}
+
+	payout, err := s.payWallet(ctx, wallet)
+	if err != nil {
+		return nil, err
+	}
+	if payout == nil {
+		// Another payout emptied the wallet first
+		return nil, fmt.Errorf("balance is below the minimum payout")
+	}
+
+	return payout, nil
+}
+
+// RunPayoutBatches pays out every wallet whose scheduled batch is due.
+// Wallets below their minimum wait for the next batch. It returns how many
+// payouts were sent.
+func (s *PaymentService) RunPayoutBatches(ctx context.Context) (int, error) {
+	wallets, err := s.repo.GetDueWallets(ctx, time.Now(), payoutBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	paid := 0
+	for _, wallet := range wallets {
+		payout, err := s.payWallet(ctx, wallet)
+		if err != nil {
+			log.Printf("Failed to pay out wallet %d of seller %d: %v", wallet.ID, wallet.SellerID, err)
+			continue
+		}
+		if payout == nil {
+			next := model.NextPayoutAt(wallet.PayoutSchedule, time.Now())
+			if err := s.repo.SetNextPayoutAt(ctx, wallet.ID, next); err != nil {
+				log.Printf("Wallet %d: %v", wallet.ID, err)
+			}
+			continue
+		}
+		paid++
+	}
+
+	return paid, nil
+}
+
+// StartPayoutWorker runs RunPayoutBatches on every tick until ctx is canceled
+func (s *PaymentService) StartPayoutWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			paid, err := s.RunPayoutBatches(ctx)
+			if err != nil {
+				log.Printf("Payout worker failed: %v", err)
+				continue
+			}
+			if paid > 0 {
+				log.Printf("Sent %d seller payouts", paid)
+			}
+		}
+	}
+}
+
+// payWallet moves the wallet's balance into a payout and transfers it. The
+// payout stays processing until the provider confirms the transfer; if the
+// transfer cannot be sent the funds go back to the wallet. It returns nil
+// when the balance is below the minimum.
+func (s *PaymentService) payWallet(ctx context.Context, wallet *model.Wallet) (*model.Payout, error) {
+	next := model.NextPayoutAt(wallet.PayoutSchedule, time.Now())
+
+	payout, err := s.repo.BeginWalletPayout(ctx, wallet.ID, next)
+	if err != nil || payout == nil {
+		return nil, err
+	}
+
+	t, err := s.provider.CreateTransfer(ctx, provider.TransferParams{
+		Amount:      payout.Amount.Amount,
+		Currency:    strings.ToLower(payout.Currency),
+		Destination: payout.StripeAccountID.String,
+		Metadata:    map[string]string{"payout_id": fmt.Sprintf("%d", payout.ID)},
+	})
+	if err != nil {
+		s.returnPayout(ctx, payout, err.Error())
+		return nil, err
+	}
+
+	if err := s.repo.MarkPayoutProcessing(ctx, payout.ID, t.ID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPayoutByID(ctx, payout.ID)
+}
+
+// returnPayout fails a payout whose transfer was never sent and credits its
+// wallet back. The balance already left the wallet, so failures are logged
+// for reconciliation rather than returned.
+func (s *PaymentService) returnPayout(ctx context.Context, payout *model.Payout, reason string) {
+	if err := s.repo.MarkPayoutFailed(ctx, payout.ID, reason); err != nil {
+		log.Printf("Failed to fail payout %d: %v", payout.ID, err)
+		return
+	}
+	if _, err := s.repo.ReturnPayoutToWallet(ctx, payout); err != nil {
+		log.Printf("Failed to return payout %d to wallet %d: %v", payout.ID, payout.WalletID, err)
+	}
+}
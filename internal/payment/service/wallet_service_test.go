@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+)
+
+const seller = int64(8)
+
+func usd(amount int64) money.Money {
+	return money.New(amount, money.DefaultCurrency)
+}
+
+// newTestService pays out daily once a wallet holds 50.00
+func newTestService(store *memoryStore, p provider.PaymentProvider) *PaymentService {
+	return NewPaymentService(store, p, nil, nil, PayoutPolicy{
+		DefaultSchedule: model.PayoutScheduleDaily,
+		MinimumPayout:   usd(5000),
+	})
+}
+
+// completeOrder records a completed order sold by sellerID
+func (m *memoryStore) completeOrder(orderID, sellerID int64) {
+	m.orders[orderID] = &model.PayoutHold{OrderStatus: "completed", SellerID: sellerID}
+}
+
+func creditSale(t *testing.T, s *PaymentService, orderID int64, amount money.Money) *model.Wallet {
+	t.Helper()
+	wallet, err := s.CreditSellerWallet(context.Background(), orderID, seller, amount)
+	if err != nil {
+		t.Fatalf("CreditSellerWallet(%d): %v", orderID, err)
+	}
+	return wallet
+}
+
+func TestCreditSellerWalletOnce(t *testing.T) {
+	store := newMemoryStore()
+	s := newTestService(store, provider.NewFake(""))
+	store.completeOrder(1, seller)
+
+	creditSale(t, s, 1, usd(25000))
+	wallet := creditSale(t, s, 1, usd(25000))
+
+	if !wallet.Balance.Equal(usd(25000)) {
+		t.Errorf("balance after crediting the order twice = %s, want 250.00", wallet.Balance)
+	}
+}
+
+func TestCreditSellerWalletRefusesTransferredOrder(t *testing.T) {
+	store := newMemoryStore()
+	s := newTestService(store, provider.NewFake(""))
+	store.completeOrder(1, seller)
+	store.transferred[1] = true
+
+	if _, err := s.CreditSellerWallet(context.Background(), 1, seller, usd(25000)); err == nil {
+		t.Fatal("crediting an order already paid by transfer succeeded")
+	}
+
+	wallet, _, err := s.GetSellerWallet(context.Background(), seller, "")
+	if err != nil {
+		t.Fatalf("GetSellerWallet: %v", err)
+	}
+	if !wallet.Balance.IsZero() {
+		t.Errorf("balance = %s, want 0", wallet.Balance)
+	}
+}
+
+func TestCreditSellerWalletHoldsFunds(t *testing.T) {
+	tests := []struct {
+		name string
+		hold model.PayoutHold
+	}{
+		{"order not completed", model.PayoutHold{OrderStatus: "shipped", SellerID: seller}},
+		{"dispute open", model.PayoutHold{OrderStatus: "completed", SellerID: seller, OpenDispute: true}},
+		{"other seller", model.PayoutHold{OrderStatus: "completed", SellerID: seller + 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			s := newTestService(store, provider.NewFake(""))
+			hold := tt.hold
+			store.orders[1] = &hold
+
+			if _, err := s.CreditSellerWallet(context.Background(), 1, seller, usd(25000)); err == nil {
+				t.Error("held order was credited")
+			}
+			if len(store.sales) != 0 {
+				t.Errorf("%d sales credited, want none", len(store.sales))
+			}
+		})
+	}
+}
+
+func TestFailedTransferReturnsFundsToWallet(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	fake := provider.NewFake("")
+	transfers := provider.NewFailureInjector(fake)
+	s := newTestService(store, transfers)
+	store.completeOrder(1, seller)
+	creditSale(t, s, 1, usd(25000))
+
+	transfers.FailNext("CreateTransfer", 1, nil)
+	if _, err := s.RequestPayout(ctx, seller, ""); err == nil {
+		t.Fatal("RequestPayout succeeded with a failing transfer")
+	}
+
+	wallet, _, err := s.GetSellerWallet(ctx, seller, "")
+	if err != nil {
+		t.Fatalf("GetSellerWallet: %v", err)
+	}
+	if !wallet.Balance.Equal(usd(25000)) {
+		t.Errorf("balance after failed transfer = %s, want 250.00", wallet.Balance)
+	}
+	for _, failed := range store.payouts {
+		if failed.Status != model.PayoutStatusFailed {
+			t.Errorf("payout status = %s, want %s", failed.Status, model.PayoutStatusFailed)
+		}
+	}
+
+	// The returned sale goes out with the next payout
+	payout, err := s.RequestPayout(ctx, seller, "")
+	if err != nil {
+		t.Fatalf("retried RequestPayout: %v", err)
+	}
+	if !payout.Amount.Equal(usd(25000)) || payout.Status != model.PayoutStatusProcessing {
+		t.Errorf("retried payout is %s %s, want 250.00 processing", payout.Amount, payout.Status)
+	}
+	if sent := fake.Transfers(); len(sent) != 1 || sent[0].Amount != 25000 {
+		t.Errorf("transfers sent = %+v, want one of 25000", sent)
+	}
+	if store.sales[1].payoutID != payout.ID {
+		t.Errorf("sale paid by payout %d, want %d", store.sales[1].payoutID, payout.ID)
+	}
+}
+
+func TestRunPayoutBatches(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	fake := provider.NewFake("")
+	s := newTestService(store, fake)
+
+	// Seller 8 is due and over the minimum, seller 9 is due but under it and
+	// seller 10 is not due yet
+	for orderID, sellerID := range map[int64]int64{1: 8, 2: 9, 3: 10} {
+		store.completeOrder(orderID, sellerID)
+	}
+	for orderID, amount := range map[int64]int64{1: 25000, 2: 1000, 3: 25000} {
+		hold := store.orders[orderID]
+		if _, err := s.CreditSellerWallet(ctx, orderID, hold.SellerID, usd(amount)); err != nil {
+			t.Fatalf("CreditSellerWallet(%d): %v", orderID, err)
+		}
+	}
+	past := sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	for _, wallet := range store.wallets {
+		if wallet.SellerID != 10 {
+			wallet.NextPayoutAt = past
+		}
+	}
+
+	paid, err := s.RunPayoutBatches(ctx)
+	if err != nil {
+		t.Fatalf("RunPayoutBatches: %v", err)
+	}
+	if paid != 1 {
+		t.Errorf("paid %d wallets, want 1", paid)
+	}
+	if sent := fake.Transfers(); len(sent) != 1 || sent[0].Amount != 25000 {
+		t.Errorf("transfers sent = %+v, want one of 25000", sent)
+	}
+
+	for _, wallet := range store.wallets {
+		switch wallet.SellerID {
+		case 8:
+			if !wallet.Balance.IsZero() {
+				t.Errorf("paid wallet keeps %s", wallet.Balance)
+			}
+		case 9:
+			if !wallet.Balance.Equal(usd(1000)) {
+				t.Errorf("wallet under the minimum has %s, want 10.00", wallet.Balance)
+			}
+			if !wallet.NextPayoutAt.Time.After(time.Now()) {
+				t.Error("wallet under the minimum was not moved to the next batch")
+			}
+		case 10:
+			if !wallet.Balance.Equal(usd(25000)) {
+				t.Errorf("wallet not due has %s, want 250.00", wallet.Balance)
+			}
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/model"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
+	orderPb "github.com/vvkuzmych/sneakers_marketplace/pkg/proto/order"
+)
+
+// stripeEventStale is how long an event may stay in processing before a
+// redelivery is allowed to claim it again
+const stripeEventStale = 10 * time.Minute
+
+// ErrInvalidWebhook marks payloads that failed verification. Redelivering
+// them cannot succeed.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// WebhookResult describes a received webhook event
+type WebhookResult struct {
+	EventID   string
+	EventType string
+	Duplicate bool // already handled; nothing was applied
+}
+
+// HandleStripeWebhook verifies a Stripe webhook, records it by event ID and
+// applies it to payments, payouts and orders. A replayed event is reported
+// as a duplicate and not applied again. Processing errors are returned so
+// the caller answers 5xx and Stripe redelivers; the event is then retried.
+func (s *PaymentService) HandleStripeWebhook(ctx context.Context, payload []byte, signature string) (*WebhookResult, error) {
+	event, err := s.provider.ParseWebhook(payload, signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	result := &WebhookResult{EventID: event.ID, EventType: event.Type}
+
+	stored := &model.StripeEvent{
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   payload,
+	}
+	claimed, err := s.repo.BeginStripeEvent(ctx, stored, stripeEventStale)
+	if err != nil {
+		return result, err
+	}
+	if !claimed {
+		result.Duplicate = true
+		return result, nil
+	}
+
+	handled, err := s.applyStripeEvent(ctx, event)
+
+	status, errMsg := model.StripeEventProcessed, ""
+	switch {
+	case err != nil:
+		status, errMsg = model.StripeEventFailed, err.Error()
+	case !handled:
+		status = model.StripeEventIgnored
+	}
+
+	if finishErr := s.repo.FinishStripeEvent(ctx, event.ID, status, errMsg); finishErr != nil && err == nil {
+		err = finishErr
+	}
+
+	return result, err
+}
+
+// applyStripeEvent dispatches an event by type. It reports false for event
+// types the payment service does not act on.
+func (s *PaymentService) applyStripeEvent(ctx context.Context, event *provider.Event) (bool, error) {
+	switch event.Type {
+	case model.EventPaymentIntentSucceeded, model.EventPaymentIntentFailed:
+		if event.Intent == nil {
+			return true, fmt.Errorf("event %s carries no payment intent", event.ID)
+		}
+		if event.Type == model.EventPaymentIntentSucceeded {
+			return true, s.handleIntentSucceeded(ctx, event.Intent)
+		}
+		return true, s.handleIntentFailed(ctx, event.Intent)
+
+	case model.EventChargeRefunded:
+		if event.Charge == nil {
+			return true, fmt.Errorf("event %s carries no charge", event.ID)
+		}
+		return true, s.handleChargeRefunded(ctx, event.Charge)
+
+	case model.EventTransferCreated, model.EventTransferFailed:
+		if event.Transfer == nil {
+			return true, fmt.Errorf("event %s carries no transfer", event.ID)
+		}
+		if event.Type == model.EventTransferCreated {
+			return true, s.handleTransferCreated(ctx, event.Transfer)
+		}
+		return true, s.handleTransferFailed(ctx, event.Transfer)
+
+	case model.EventChargeDisputeCreated, model.EventChargeDisputeClosed:
+		if event.Dispute == nil {
+			return true, fmt.Errorf("event %s carries no dispute", event.ID)
+		}
+		if event.Type == model.EventChargeDisputeCreated {
+			return true, s.handleDisputeCreated(ctx, event.Dispute)
+		}
+		return true, s.handleDisputeClosed(ctx, event.Dispute)
+
+	default:
+		return false, nil
+	}
+}
+
+// handleIntentSucceeded settles the payments of the intent and marks their
+// orders paid
+func (s *PaymentService) handleIntentSucceeded(ctx context.Context, pi *provider.Intent) error {
+	payments, err := s.repo.GetPaymentsByIntentID(ctx, pi.ID)
+	if err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		// Bid holds and subscription invoices are not order payments
+		return nil
+	}
+
+	// Card details are only present when the charge is expanded
+	var chargeID, paymentMethod, cardLast4, cardBrand string
+	if pi.Charge != nil {
+		chargeID = pi.Charge.ID
+		paymentMethod = pi.Charge.PaymentMethod
+		cardLast4 = pi.Charge.CardLast4
+		cardBrand = pi.Charge.CardBrand
+	}
+
+	if _, err := s.repo.MarkIntentPaymentsSucceeded(ctx, pi.ID, chargeID, paymentMethod, cardLast4, cardBrand); err != nil {
+		return err
+	}
+
+	if err := s.postIntentPayments(ctx, pi.ID, pi.Charge); err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		if payment.Status == model.StatusRefunded || payment.Status == model.StatusPartiallyRefunded {
+			continue
+		}
+		if err := s.updateOrderStatus(ctx, payment.OrderID, "paid", "Payment confirmed by Stripe"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleIntentFailed records the failure on the intent's payments. Orders
+// stay pending_payment: the buyer may retry the same intent, and unpaid
+// orders expire on their own.
+func (s *PaymentService) handleIntentFailed(ctx context.Context, pi *provider.Intent) error {
+	reason := "payment failed"
+	if pi.FailureMsg != "" {
+		reason = pi.FailureMsg
+	}
+
+	_, err := s.repo.MarkIntentPaymentsFailed(ctx, pi.ID, reason)
+	return err
+}
+
+// handleChargeRefunded brings the refunded totals of the charge's payments
+// up to what Stripe reports, claws refunds after a sale back from the
+// seller and applies the refunds to their orders.
+// Refunds issued through CreateRefund are already recorded and change
+// nothing here.
+func (s *PaymentService) handleChargeRefunded(ctx context.Context, charge *provider.Charge) error {
+	payments, err := s.repo.GetPaymentsByChargeID(ctx, charge.ID)
+	if err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+
+	refunded := money.New(charge.AmountRefunded, payments[0].Amount.Currency)
+
+	// Target refunded total per payment
+	targets := make(map[int64]money.Money, len(payments))
+	switch {
+	case len(payments) == 1:
+		targets[payments[0].ID] = refunded
+	case charge.Refunded:
+		// A fully refunded checkout charge refunds every order in it
+		for _, payment := range payments {
+			targets[payment.ID] = payment.Amount
+		}
+	default:
+		// Checkout orders share the charge; their refunds go through
+		// CreateRefund, which records each order's share. Anything beyond
+		// that was refunded outside the platform and cannot be attributed.
+		var recorded money.Money
+		for _, payment := range payments {
+			recorded = recorded.Add(payment.RefundedAmount)
+		}
+		if refunded.GreaterThan(recorded) {
+			return fmt.Errorf("refund of %s on shared charge %s is not attributed to an order", refunded.Sub(recorded), charge.ID)
+		}
+		return nil
+	}
+
+	for _, payment := range payments {
+		target, ok := targets[payment.ID]
+		if !ok {
+			continue
+		}
+
+		changed, err := s.repo.SyncPaymentRefund(ctx, payment.ID, target)
+		if err != nil {
+			return err
+		}
+		if changed {
+			if err := s.postRefund(ctx, payment, target); err != nil {
+				return err
+			}
+			if err := s.reclaimFromSeller(ctx, payment, target.Sub(payment.RefundedAmount), refundReference(payment.ID, target)); err != nil {
+				return err
+			}
+			if err := s.applyOrderRefund(ctx, payment, target.Sub(payment.RefundedAmount), target, "Payment refunded in Stripe"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// handleTransferCreated confirms the payout paid by the transfer
+func (s *PaymentService) handleTransferCreated(ctx context.Context, tr *provider.Transfer) error {
+	err := s.applyTransferOutcome(ctx, tr.ID, model.PayoutStatusPaid, "",
+		[]string{model.PayoutStatusPending, model.PayoutStatusProcessing})
+	if err != nil {
+		return err
+	}
+
+	payout, err := s.repo.GetPayoutByTransferID(ctx, tr.ID)
+	if err != nil || payout == nil || payout.Status != model.PayoutStatusPaid {
+		return err
+	}
+
+	return s.postPayout(ctx, payout)
+}
+
+// handleTransferFailed marks the payout paid by the transfer as failed,
+// returns batch payouts to the seller's wallet and gives the seller the
+// amount back in the ledger
+func (s *PaymentService) handleTransferFailed(ctx context.Context, tr *provider.Transfer) error {
+	err := s.applyTransferOutcome(ctx, tr.ID, model.PayoutStatusFailed, "transfer failed",
+		[]string{model.PayoutStatusPending, model.PayoutStatusProcessing, model.PayoutStatusPaid})
+	if err != nil {
+		return err
+	}
+
+	payout, err := s.repo.GetPayoutByTransferID(ctx, tr.ID)
+	if err != nil || payout == nil || payout.Status != model.PayoutStatusFailed {
+		return err
+	}
+
+	if payout.WalletID != 0 {
+		if _, err := s.repo.ReturnPayoutToWallet(ctx, payout); err != nil {
+			return err
+		}
+	}
+
+	if s.ledger == nil {
+		return nil
+	}
+
+	return s.ledger.RecordPayoutReversal(ctx, payout.ID, payout.SellerID, payout.Amount)
+}
+
+func (s *PaymentService) applyTransferOutcome(ctx context.Context, transferID, status, reason string, fromStatuses []string) error {
+	changed, err := s.repo.UpdatePayoutFromTransfer(ctx, transferID, status, reason, fromStatuses)
+	if err != nil || changed {
+		return err
+	}
+
+	payout, err := s.repo.GetPayoutByTransferID(ctx, transferID)
+	if err != nil {
+		return err
+	}
+	if payout == nil {
+		// Payouts store the transfer ID once Stripe returns it, so
+		// the event can win the race; fail it and let Stripe redeliver
+		return fmt.Errorf("no payout found for transfer %s", transferID)
+	}
+
+	return nil
+}
+
+// applyOrderRefund records a refund of amount on the payment's order, which
+// closes the order once refunded covers the whole payment
+func (s *PaymentService) applyOrderRefund(ctx context.Context, payment *model.Payment, amount, refunded money.Money, reason string) error {
+	if s.orderClient == nil {
+		return nil
+	}
+
+	resp, err := s.orderClient.ApplyRefund(ctx, &orderPb.ApplyRefundRequest{
+		OrderId:     payment.OrderID,
+		Reference:   refundReference(payment.ID, refunded),
+		AmountMinor: amount.Amount,
+		Currency:    amount.Currency,
+		Full:        !refunded.LessThan(payment.Amount),
+		Reason:      reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply refund to order %d: %w", payment.OrderID, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("failed to apply refund to order %d: %s", payment.OrderID, resp.Error)
+	}
+
+	return nil
+}
+
+// updateOrderStatus moves an order on behalf of the system. Transitions the
+// order's lifecycle rejects (the order already moved on) are not errors.
+func (s *PaymentService) updateOrderStatus(ctx context.Context, orderID int64, status, note string) error {
+	if s.orderClient == nil {
+		return nil
+	}
+
+	_, err := s.orderClient.UpdateOrderStatus(ctx, &orderPb.UpdateOrderStatusRequest{
+		OrderId:   orderID,
+		NewStatus: status,
+		Note:      note,
+		UpdatedBy: "system",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update order %d: %w", orderID, err)
+	}
+
+	return nil
+}
@@ -344,6 +344,27 @@ func (h *ProductHandler) ReleaseInventory(ctx context.Context, req *pb.ReleaseIn
 	}, nil
 }
 
+// CompleteSale finalizes reserved inventory once an order is completed
+func (h *ProductHandler) CompleteSale(ctx context.Context, req *pb.CompleteSaleRequest) (*pb.CompleteSaleResponse, error) {
+	if req.SizeId == 0 || req.OrderId == "" {
+		return &pb.CompleteSaleResponse{
+			Success: false,
+			Error:   "size_id and order_id are required",
+		}, nil
+	}
+
+	if err := h.productService.CompleteSale(ctx, req.SizeId, int(req.Quantity), req.OrderId); err != nil {
+		return &pb.CompleteSaleResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.CompleteSaleResponse{
+		Success: true,
+	}, nil
+}
+
 // Helper functions to convert between model and proto
 
 func modelProductToProto(product *model.Product, images []model.ProductImage, sizes []model.Size) *pb.Product {
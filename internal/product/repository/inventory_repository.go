@@ -272,6 +272,10 @@ func (r *InventoryRepository) CompleteSale(ctx context.Context, sizeID int64, qu
 		return fmt.Errorf("failed to get size: %w", err)
 	}
 
+	if size.Reserved < quantity {
+		return fmt.Errorf("cannot sell more than reserved: reserved=%d, sale=%d", size.Reserved, quantity)
+	}
+
 	oldQuantity := size.Quantity
 
 	// Reduce both quantity and reserved
@@ -171,6 +171,11 @@ func (s *ProductService) ReleaseInventory(ctx context.Context, sizeID int64, qua
 	return s.inventoryRepo.ReleaseInventory(ctx, sizeID, quantity, orderID)
 }
 
+// CompleteSale removes sold units from both quantity and reserved
+func (s *ProductService) CompleteSale(ctx context.Context, sizeID int64, quantity int, orderID string) error {
+	return s.inventoryRepo.CompleteSale(ctx, sizeID, quantity, orderID)
+}
+
 // Helper functions
 
 func convertImagePointers(images []*model.ProductImage) []model.ProductImage {
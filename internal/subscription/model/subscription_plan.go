@@ -4,6 +4,8 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // SubscriptionPlan represents a subscription tier (Free, Pro, Elite)
@@ -14,8 +16,8 @@ type SubscriptionPlan struct {
 	Description string `json:"description"`
 
 	// Pricing
-	PriceMonthly float64 `json:"price_monthly"`
-	PriceYearly  float64 `json:"price_yearly"`
+	PriceMonthly money.Money `json:"price_monthly"`
+	PriceYearly  money.Money `json:"price_yearly"`
 
 	// Fee structure
 	BuyerFeePercent  float64 `json:"buyer_fee_percent"`
@@ -80,12 +82,12 @@ func (sp *SubscriptionPlan) IsElite() bool {
 
 // GetMonthlyPrice returns monthly price as cents (for Stripe)
 func (sp *SubscriptionPlan) GetMonthlyPriceCents() int64 {
-	return int64(sp.PriceMonthly * 100)
+	return sp.PriceMonthly.Amount
 }
 
 // GetYearlyPrice returns yearly price as cents (for Stripe)
 func (sp *SubscriptionPlan) GetYearlyPriceCents() int64 {
-	return int64(sp.PriceYearly * 100)
+	return sp.PriceYearly.Amount
 }
 
 // HasUnlimitedListings checks if plan has unlimited listings
@@ -99,17 +101,17 @@ func (sp *SubscriptionPlan) HasUnlimitedTransactions() bool {
 }
 
 // GetYearlySavings calculates savings when paying yearly vs monthly
-func (sp *SubscriptionPlan) GetYearlySavings() float64 {
-	monthlyTotal := sp.PriceMonthly * 12
-	return monthlyTotal - sp.PriceYearly
+func (sp *SubscriptionPlan) GetYearlySavings() money.Money {
+	monthlyTotal := sp.PriceMonthly.Mul(12)
+	return monthlyTotal.Sub(sp.PriceYearly)
 }
 
 // GetYearlySavingsPercent calculates savings percentage
 func (sp *SubscriptionPlan) GetYearlySavingsPercent() float64 {
-	if sp.PriceMonthly == 0 {
+	if sp.PriceMonthly.IsZero() {
 		return 0
 	}
 	savings := sp.GetYearlySavings()
-	monthlyTotal := sp.PriceMonthly * 12
-	return (savings / monthlyTotal) * 100
+	monthlyTotal := sp.PriceMonthly.Mul(12)
+	return float64(savings.Amount) / float64(monthlyTotal.Amount) * 100
 }
@@ -1,8 +1,9 @@
 package model
 
 import (
-	"fmt"
 	"time"
+
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // TransactionType represents the type of subscription transaction
@@ -33,8 +34,8 @@ type SubscriptionTransaction struct {
 	PlanID             int64 `json:"plan_id"`
 
 	// Transaction details
-	Amount   float64 `json:"amount"`
-	Currency string  `json:"currency"`
+	Amount   money.Money `json:"amount"`
+	Currency string      `json:"currency"`
 
 	// Type and status
 	TransactionType TransactionType   `json:"transaction_type"`
@@ -96,7 +97,7 @@ func (st *SubscriptionTransaction) IsRefunded() bool {
 
 // GetAmountCents returns amount in cents (for Stripe)
 func (st *SubscriptionTransaction) GetAmountCents() int64 {
-	return int64(st.Amount * 100)
+	return st.Amount.Amount
 }
 
 // GetFormattedAmount returns formatted amount string
@@ -104,21 +105,11 @@ func (st *SubscriptionTransaction) GetFormattedAmount() string {
 	return formatCurrency(st.Amount, st.Currency)
 }
 
-// formatCurrency formats a float64 amount as currency
-func formatCurrency(amount float64, currency string) string {
+// formatCurrency formats an amount as currency
+func formatCurrency(amount money.Money, currency string) string {
 	// Simple formatting for USD
 	if currency == "USD" {
-		return "$" + formatFloat(amount, 2)
-	}
-	return formatFloat(amount, 2) + " " + currency
-}
-
-// formatFloat formats a float64 to string with fixed decimal places
-func formatFloat(val float64, precision int) string {
-	switch precision {
-	case 2:
-		return fmt.Sprintf("%.2f", val)
-	default:
-		return fmt.Sprintf("%f", val)
+		return "$" + amount.String()
 	}
+	return amount.String() + " " + currency
 }
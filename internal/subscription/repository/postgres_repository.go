@@ -9,6 +9,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/subscription/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // PostgresSubscriptionRepository implements SubscriptionRepository with PostgreSQL
@@ -733,7 +734,7 @@ func (r *PostgresSubscriptionRepository) UpdateTransactionStatus(ctx context.Con
 	return nil
 }
 
-func (r *PostgresSubscriptionRepository) GetRevenueByPlan(ctx context.Context, startDate, endDate time.Time) (map[int64]float64, error) {
+func (r *PostgresSubscriptionRepository) GetRevenueByPlan(ctx context.Context, startDate, endDate time.Time) (map[int64]money.Money, error) {
 	query := `
 		SELECT plan_id, SUM(amount) as revenue
 		FROM subscription_transactions
@@ -749,10 +750,10 @@ func (r *PostgresSubscriptionRepository) GetRevenueByPlan(ctx context.Context, s
 	}
 	defer rows.Close()
 
-	revenue := make(map[int64]float64)
+	revenue := make(map[int64]money.Money)
 	for rows.Next() {
 		var planID int64
-		var amount float64
+		var amount money.Money
 		if err := rows.Scan(&planID, &amount); err != nil {
 			return nil, fmt.Errorf("failed to scan revenue: %w", err)
 		}
@@ -762,7 +763,7 @@ func (r *PostgresSubscriptionRepository) GetRevenueByPlan(ctx context.Context, s
 	return revenue, nil
 }
 
-func (r *PostgresSubscriptionRepository) GetTotalRevenue(ctx context.Context, startDate, endDate time.Time) (float64, error) {
+func (r *PostgresSubscriptionRepository) GetTotalRevenue(ctx context.Context, startDate, endDate time.Time) (money.Money, error) {
 	query := `
 		SELECT COALESCE(SUM(amount), 0) as total_revenue
 		FROM subscription_transactions
@@ -771,10 +772,10 @@ func (r *PostgresSubscriptionRepository) GetTotalRevenue(ctx context.Context, st
 		  AND created_at BETWEEN $1 AND $2
 	`
 
-	var revenue float64
+	var revenue money.Money
 	err := r.db.QueryRow(ctx, query, startDate, endDate).Scan(&revenue)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get total revenue: %w", err)
+		return money.Money{}, fmt.Errorf("failed to get total revenue: %w", err)
 	}
 
 	return revenue, nil
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/subscription/model"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // SubscriptionRepository defines all database operations for subscriptions
@@ -97,8 +98,8 @@ type SubscriptionRepository interface {
 	UpdateTransactionStatus(ctx context.Context, transactionID int64, status model.TransactionStatus) error
 
 	// GetRevenueByPlan calculates total revenue by plan
-	GetRevenueByPlan(ctx context.Context, startDate, endDate time.Time) (map[int64]float64, error)
+	GetRevenueByPlan(ctx context.Context, startDate, endDate time.Time) (map[int64]money.Money, error)
 
 	// GetTotalRevenue calculates total revenue in a date range
-	GetTotalRevenue(ctx context.Context, startDate, endDate time.Time) (float64, error)
+	GetTotalRevenue(ctx context.Context, startDate, endDate time.Time) (money.Money, error)
 }
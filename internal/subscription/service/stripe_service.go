@@ -11,34 +11,40 @@ import (
 
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/customer"
-	"github.com/stripe/stripe-go/v76/paymentintent"
 	"github.com/stripe/stripe-go/v76/subscription"
-	"github.com/stripe/stripe-go/v76/webhook"
 
+	ledgerService "github.com/vvkuzmych/sneakers_marketplace/internal/ledger/service"
+	"github.com/vvkuzmych/sneakers_marketplace/internal/payment/provider"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/subscription/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/subscription/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
-// StripeService handles Stripe-related operations for subscriptions
+// StripeService handles Stripe-related operations for subscriptions.
+// Payment intents and webhook verification go through the payment provider;
+// customers and subscriptions are Stripe Billing objects and call Stripe.
 type StripeService struct {
 	subscriptionRepo repository.SubscriptionRepository
+	provider         provider.PaymentProvider
+	ledger           *ledgerService.LedgerService // may be nil; payments are then not posted
 	stripeAPIKey     string
-	webhookSecret    string
 }
 
 // NewStripeService creates a new Stripe service
 func NewStripeService(
 	repo repository.SubscriptionRepository,
+	provider provider.PaymentProvider,
+	ledger *ledgerService.LedgerService,
 	apiKey string,
-	webhookSecret string,
 ) *StripeService {
 	// Set Stripe API key globally
 	stripe.Key = apiKey
 
 	return &StripeService{
 		subscriptionRepo: repo,
+		provider:         provider,
+		ledger:           ledger,
 		stripeAPIKey:     apiKey,
-		webhookSecret:    webhookSecret,
 	}
 }
 
@@ -206,29 +212,20 @@ func (s *StripeService) UpdateStripeSubscription(
 func (s *StripeService) CreatePaymentIntent(
 	ctx context.Context,
 	userID int64,
-	amount float64,
-	currency string,
+	amount money.Money,
 	metadata map[string]string,
 ) (string, string, error) {
-	// Convert amount to cents
-	amountInt := int64(amount * 100)
-
 	// Add user_id to metadata
 	if metadata == nil {
 		metadata = make(map[string]string)
 	}
 	metadata["user_id"] = strconv.FormatInt(userID, 10)
 
-	params := &stripe.PaymentIntentParams{
-		Params: stripe.Params{
-			Context: ctx,
-		},
-		Amount:   stripe.Int64(amountInt),
-		Currency: stripe.String(currency),
+	intent, err := s.provider.CreateIntent(ctx, provider.IntentParams{
+		Amount:   amount.Amount,
+		Currency: amount.Currency,
 		Metadata: metadata,
-	}
-
-	intent, err := paymentintent.New(params)
+	})
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create payment intent: %w", err)
 	}
@@ -238,13 +235,7 @@ func (s *StripeService) CreatePaymentIntent(
 
 // ConfirmPaymentIntent confirms a payment intent
 func (s *StripeService) ConfirmPaymentIntent(ctx context.Context, paymentIntentID string) error {
-	params := &stripe.PaymentIntentConfirmParams{
-		Params: stripe.Params{
-			Context: ctx,
-		},
-	}
-
-	_, err := paymentintent.Confirm(paymentIntentID, params)
+	_, err := s.provider.ConfirmIntent(ctx, paymentIntentID)
 	if err != nil {
 		return fmt.Errorf("failed to confirm payment intent: %w", err)
 	}
@@ -259,7 +250,7 @@ func (s *StripeService) HandleWebhook(ctx context.Context, payload []byte, signa
 	logWithContext(ctx, "Processing Stripe webhook (payload size: %d bytes)", len(payload))
 
 	// Verify webhook signature
-	event, err := webhook.ConstructEvent(payload, signature, s.webhookSecret)
+	event, err := s.provider.ParseWebhook(payload, signature)
 	if err != nil {
 		logErrorWithContext(ctx, err, "Webhook signature verification failed")
 		return fmt.Errorf("webhook signature verification failed: %w", err)
@@ -287,9 +278,9 @@ func (s *StripeService) HandleWebhook(ctx context.Context, payload []byte, signa
 }
 
 // handleSubscriptionCreated handles subscription.created webhook
-func (s *StripeService) handleSubscriptionCreated(ctx context.Context, event stripe.Event) error {
+func (s *StripeService) handleSubscriptionCreated(ctx context.Context, event *provider.Event) error {
 	var subscription stripe.Subscription
-	if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
+	if err := json.Unmarshal(event.Raw, &subscription); err != nil {
 		return fmt.Errorf("failed to unmarshal subscription: %w", err)
 	}
 
@@ -314,9 +305,9 @@ func (s *StripeService) handleSubscriptionCreated(ctx context.Context, event str
 }
 
 // handleSubscriptionUpdated handles subscription.updated webhook
-func (s *StripeService) handleSubscriptionUpdated(ctx context.Context, event stripe.Event) error {
+func (s *StripeService) handleSubscriptionUpdated(ctx context.Context, event *provider.Event) error {
 	var subscription stripe.Subscription
-	if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
+	if err := json.Unmarshal(event.Raw, &subscription); err != nil {
 		return fmt.Errorf("failed to unmarshal subscription: %w", err)
 	}
 
@@ -340,9 +331,9 @@ func (s *StripeService) handleSubscriptionUpdated(ctx context.Context, event str
 }
 
 // handleSubscriptionDeleted handles subscription.deleted webhook
-func (s *StripeService) handleSubscriptionDeleted(ctx context.Context, event stripe.Event) error {
+func (s *StripeService) handleSubscriptionDeleted(ctx context.Context, event *provider.Event) error {
 	var subscription stripe.Subscription
-	if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
+	if err := json.Unmarshal(event.Raw, &subscription); err != nil {
 		return fmt.Errorf("failed to unmarshal subscription: %w", err)
 	}
 
@@ -361,9 +352,9 @@ func (s *StripeService) handleSubscriptionDeleted(ctx context.Context, event str
 }
 
 // handlePaymentSucceeded handles invoice.payment_succeeded webhook
-func (s *StripeService) handlePaymentSucceeded(ctx context.Context, event stripe.Event) error {
+func (s *StripeService) handlePaymentSucceeded(ctx context.Context, event *provider.Event) error {
 	var invoice stripe.Invoice
-	if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
+	if err := json.Unmarshal(event.Raw, &invoice); err != nil {
 		return fmt.Errorf("failed to unmarshal invoice: %w", err)
 	}
 
@@ -380,7 +371,7 @@ func (s *StripeService) handlePaymentSucceeded(ctx context.Context, event stripe
 		PlanID:                dbSub.PlanID,
 		TransactionType:       model.TransactionPayment,
 		Status:                model.TransactionSucceeded,
-		Amount:                float64(invoice.AmountPaid) / 100, // Convert from cents
+		Amount:                money.New(invoice.AmountPaid, string(invoice.Currency)),
 		Currency:              string(invoice.Currency),
 		StripePaymentIntentID: invoice.PaymentIntent.ID,
 		StripeInvoiceID:       invoice.ID,
@@ -391,15 +382,23 @@ func (s *StripeService) handlePaymentSucceeded(ctx context.Context, event stripe
 	if err := s.subscriptionRepo.CreateTransaction(ctx, transaction); err != nil {
 		// Log error but don't fail
 		fmt.Printf("Warning: Failed to record transaction: %v\n", err)
+		return nil
+	}
+
+	if s.ledger != nil {
+		amount := transaction.Amount.WithCurrency(transaction.Currency)
+		if err := s.ledger.RecordSubscriptionPayment(ctx, transaction.ID, amount); err != nil {
+			logErrorWithContext(ctx, err, "Failed to post subscription payment %d to the ledger", transaction.ID)
+		}
 	}
 
 	return nil
 }
 
 // handlePaymentFailed handles invoice.payment_failed webhook
-func (s *StripeService) handlePaymentFailed(ctx context.Context, event stripe.Event) error {
+func (s *StripeService) handlePaymentFailed(ctx context.Context, event *provider.Event) error {
 	var invoice stripe.Invoice
-	if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
+	if err := json.Unmarshal(event.Raw, &invoice); err != nil {
 		return fmt.Errorf("failed to unmarshal invoice: %w", err)
 	}
 
@@ -422,7 +421,7 @@ func (s *StripeService) handlePaymentFailed(ctx context.Context, event stripe.Ev
 		PlanID:             dbSub.PlanID,
 		TransactionType:    model.TransactionPayment,
 		Status:             model.TransactionFailed,
-		Amount:             float64(invoice.AmountDue) / 100,
+		Amount:             money.New(invoice.AmountDue, string(invoice.Currency)),
 		Currency:           string(invoice.Currency),
 		StripeInvoiceID:    invoice.ID,
 		Description:        "Payment failed",
@@ -556,7 +555,7 @@ func (s *StripeService) SubscribeUserToPlan(
 	}
 
 	// 7. Record initial transaction (will be confirmed by webhook)
-	var amount float64
+	var amount money.Money
 	if cycle == model.BillingYearly {
 		amount = plan.PriceYearly
 	} else {
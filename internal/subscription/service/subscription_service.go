@@ -9,6 +9,7 @@ import (
 
 	"github.com/vvkuzmych/sneakers_marketplace/internal/subscription/model"
 	"github.com/vvkuzmych/sneakers_marketplace/internal/subscription/repository"
+	"github.com/vvkuzmych/sneakers_marketplace/pkg/money"
 )
 
 // SubscriptionService handles business logic for subscriptions
@@ -149,7 +150,7 @@ func (s *SubscriptionService) UpgradeSubscription(ctx context.Context, userID, n
 	}
 
 	// Validate upgrade (can't downgrade using this method)
-	if newPlan.PriceMonthly <= current.Plan.PriceMonthly {
+	if !newPlan.PriceMonthly.GreaterThan(current.Plan.PriceMonthly) {
 		return nil, errors.New("can only upgrade to a higher tier plan")
 	}
 
@@ -185,7 +186,7 @@ func (s *SubscriptionService) DowngradeSubscription(ctx context.Context, userID,
 	}
 
 	// Validate downgrade
-	if newPlan.PriceMonthly >= current.Plan.PriceMonthly {
+	if !newPlan.PriceMonthly.LessThan(current.Plan.PriceMonthly) {
 		return nil, errors.New("can only downgrade to a lower tier plan")
 	}
 
@@ -293,7 +294,7 @@ func (s *SubscriptionService) CreateTransaction(ctx context.Context, transaction
 	if transaction.UserSubscriptionID == 0 {
 		return errors.New("user_subscription_id is required")
 	}
-	if transaction.Amount <= 0 {
+	if !transaction.Amount.IsPositive() {
 		return errors.New("amount must be greater than 0")
 	}
 	if transaction.TransactionType != model.TransactionPayment && transaction.TransactionType != model.TransactionRefund {
@@ -387,9 +388,9 @@ func (s *SubscriptionService) GetSubscriptionStats(ctx context.Context) (map[str
 		return nil, fmt.Errorf("failed to get revenue: %w", err)
 	}
 
-	totalRevenue := 0.0
+	totalRevenue := money.Zero(money.DefaultCurrency)
 	for _, revenue := range revenueByPlan {
-		totalRevenue += revenue
+		totalRevenue = totalRevenue.Add(revenue)
 	}
 
 	stats := map[string]interface{}{
@@ -403,18 +404,18 @@ func (s *SubscriptionService) GetSubscriptionStats(ctx context.Context) (map[str
 }
 
 // GetMonthlyRevenue returns the revenue for a specific month
-func (s *SubscriptionService) GetMonthlyRevenue(ctx context.Context, year, month int) (float64, error) {
+func (s *SubscriptionService) GetMonthlyRevenue(ctx context.Context, year, month int) (money.Money, error) {
 	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	end := start.AddDate(0, 1, 0)
 
 	revenueByPlan, err := s.repo.GetRevenueByPlan(ctx, start, end)
 	if err != nil {
-		return 0, err
+		return money.Money{}, err
 	}
 
-	totalRevenue := 0.0
+	totalRevenue := money.Zero(money.DefaultCurrency)
 	for _, revenue := range revenueByPlan {
-		totalRevenue += revenue
+		totalRevenue = totalRevenue.Add(revenue)
 	}
 
 	return totalRevenue, nil
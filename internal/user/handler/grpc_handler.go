@@ -271,6 +271,29 @@ func (h *UserHandler) DeleteAddress(ctx context.Context, req *pb.DeleteAddressRe
 	}, nil
 }
 
+// AddStrike records a strike against a user's account
+func (h *UserHandler) AddStrike(ctx context.Context, req *pb.AddStrikeRequest) (*pb.AddStrikeResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	var orderID *int64
+	if req.OrderId != 0 {
+		orderID = &req.OrderId
+	}
+
+	total, err := h.userService.AddStrike(ctx, req.UserId, req.Points, req.Reason, orderID)
+	if err != nil {
+		return &pb.AddStrikeResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &pb.AddStrikeResponse{
+		StrikePoints: total,
+	}, nil
+}
+
 // Helper functions to convert between model and proto
 
 func modelUserToProto(user *model.User) *pb.User {
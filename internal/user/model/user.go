@@ -47,6 +47,21 @@ type Session struct {
 	UserID           int64     `json:"user_id"`
 }
 
+// Strike represents a penalty recorded against a user's account
+type Strike struct {
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+	OrderID   *int64    `json:"order_id,omitempty"`
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Points    int32     `json:"points"`
+}
+
+// Strike reasons
+const (
+	StrikeReasonUnpaidOrder = "unpaid_order"
+)
+
 // FullName returns the user's full name
 func (u *User) FullName() string {
 	if u.FirstName == "" && u.LastName == "" {
@@ -323,3 +323,45 @@ func (r *UserRepository) unsetOtherDefaultAddresses(ctx context.Context, userID,
 
 	return nil
 }
+
+// AddStrike records a strike and adds its points to the user's running
+// total, returning the new total
+func (r *UserRepository) AddStrike(ctx context.Context, strike *model.Strike) (int32, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO user_strikes (user_id, order_id, reason, points)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		strike.UserID,
+		strike.OrderID,
+		strike.Reason,
+		strike.Points,
+	).Scan(&strike.ID, &strike.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create strike: %w", err)
+	}
+
+	var total int32
+	err = tx.QueryRow(ctx, `
+		UPDATE users SET strike_points = strike_points + $2
+		WHERE id = $1
+		RETURNING strike_points
+	`, strike.UserID, strike.Points).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update strike points: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit strike: %w", err)
+	}
+
+	return total, nil
+}
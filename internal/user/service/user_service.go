@@ -240,6 +240,25 @@ func (s *UserService) DeleteAddress(ctx context.Context, addressID, userID int64
 	return s.repo.DeleteAddress(ctx, addressID, userID)
 }
 
+// AddStrike penalizes a user's account (e.g. for not paying an order)
+func (s *UserService) AddStrike(ctx context.Context, userID int64, points int32, reason string, orderID *int64) (int32, error) {
+	if points <= 0 {
+		return 0, fmt.Errorf("points must be greater than 0")
+	}
+	if reason == "" {
+		return 0, fmt.Errorf("reason is required")
+	}
+
+	strike := &model.Strike{
+		UserID:  userID,
+		OrderID: orderID,
+		Reason:  reason,
+		Points:  points,
+	}
+
+	return s.repo.AddStrike(ctx, strike)
+}
+
 // Helper: hash token for storage
 func hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_asks_expires_at;
+DROP INDEX IF EXISTS idx_bids_expires_at;
+
+UPDATE bids SET status = 'cancelled' WHERE status = 'canceled';
+UPDATE asks SET status = 'cancelled' WHERE status IN ('canceled', 'pending_reservation');
+
+ALTER TABLE bids DROP CONSTRAINT IF EXISTS bids_status_check;
+ALTER TABLE bids ADD CONSTRAINT bids_status_check
+    CHECK (status IN ('active', 'matched', 'cancelled', 'expired'));
+
+ALTER TABLE asks DROP CONSTRAINT IF EXISTS asks_status_check;
+ALTER TABLE asks ADD CONSTRAINT asks_status_check
+    CHECK (status IN ('active', 'matched', 'cancelled', 'expired'));
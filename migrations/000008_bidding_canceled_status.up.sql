@@ -0,0 +1,22 @@
+-- The bidding service writes 'canceled' (see internal/bidding/model), but the
+-- original CHECK constraints only allowed 'cancelled'. Asks are now canceled
+-- automatically when their inventory reservation fails, so align the spelling.
+-- Asks also wait in pending_reservation, where they cannot be matched, until
+-- their units are reserved.
+
+UPDATE bids SET status = 'canceled' WHERE status = 'cancelled';
+UPDATE asks SET status = 'canceled' WHERE status = 'cancelled';
+
+ALTER TABLE bids DROP CONSTRAINT IF EXISTS bids_status_check;
+ALTER TABLE bids ADD CONSTRAINT bids_status_check
+    CHECK (status IN ('active', 'matched', 'canceled', 'expired'));
+
+ALTER TABLE asks DROP CONSTRAINT IF EXISTS asks_status_check;
+ALTER TABLE asks ADD CONSTRAINT asks_status_check
+    CHECK (status IN ('pending_reservation', 'active', 'matched', 'canceled', 'expired'));
+
+-- Speeds up the expiry worker
+CREATE INDEX IF NOT EXISTS idx_bids_expires_at ON bids(expires_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_asks_expires_at ON asks(expires_at) WHERE status = 'active';
+
+COMMENT ON COLUMN asks.status IS 'pending_reservation: reserving inventory, not matchable, active: waiting for match (inventory reserved), matched: completed, canceled: user canceled or reservation failed, expired: time expired';
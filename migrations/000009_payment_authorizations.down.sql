@@ -0,0 +1,10 @@
+UPDATE bids SET status = 'canceled' WHERE status = 'pending_authorization';
+
+ALTER TABLE bids DROP CONSTRAINT IF EXISTS bids_status_check;
+ALTER TABLE bids ADD CONSTRAINT bids_status_check
+    CHECK (status IN ('active', 'matched', 'canceled', 'expired'));
+ALTER TABLE bids ALTER COLUMN status TYPE VARCHAR(20);
+
+DROP TRIGGER IF EXISTS update_payment_authorizations_updated_at ON payment_authorizations;
+
+DROP TABLE IF EXISTS payment_authorizations;
@@ -0,0 +1,60 @@
+-- Create payment_authorizations table (manual-capture holds placed on bids)
+CREATE TABLE IF NOT EXISTS payment_authorizations (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Relations
+    bid_id BIGINT NOT NULL UNIQUE REFERENCES bids(id) ON DELETE RESTRICT,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    match_id BIGINT REFERENCES matches(id) ON DELETE SET NULL,
+
+    -- Stripe details
+    stripe_payment_intent_id VARCHAR(255) UNIQUE,
+    stripe_customer_id VARCHAR(255),
+    stripe_payment_method_id VARCHAR(255),
+
+    -- Amount
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    captured_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (captured_amount >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+
+    -- Status
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (
+        status IN (
+            'pending',
+            'authorized',
+            'captured',
+            'released',
+            'failed'
+        )
+    ),
+
+    -- Failure info
+    failure_reason TEXT,
+
+    -- Timestamps
+    authorized_at TIMESTAMP,
+    captured_at TIMESTAMP,
+    released_at TIMESTAMP,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Indexes for payment_authorizations
+CREATE INDEX idx_payment_authorizations_user_id ON payment_authorizations(user_id);
+CREATE INDEX idx_payment_authorizations_match_id ON payment_authorizations(match_id);
+CREATE INDEX idx_payment_authorizations_status ON payment_authorizations(status);
+
+-- Trigger for updated_at
+CREATE TRIGGER update_payment_authorizations_updated_at BEFORE UPDATE ON payment_authorizations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Bids wait in pending_authorization until the hold succeeds
+ALTER TABLE bids ALTER COLUMN status TYPE VARCHAR(30);
+ALTER TABLE bids DROP CONSTRAINT IF EXISTS bids_status_check;
+ALTER TABLE bids ADD CONSTRAINT bids_status_check
+    CHECK (status IN ('pending_authorization', 'active', 'matched', 'canceled', 'expired'));
+
+-- Comments for documentation
+COMMENT ON TABLE payment_authorizations IS 'Manual-capture payment holds placed before a bid becomes active';
+COMMENT ON COLUMN payment_authorizations.captured_amount IS 'Amount captured on match (may be lower than the hold)';
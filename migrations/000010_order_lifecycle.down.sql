@@ -0,0 +1,50 @@
+ALTER TABLE orders RENAME COLUMN canceled_at TO cancelled_at;
+
+UPDATE orders SET status = 'cancelled' WHERE status = 'canceled';
+UPDATE order_status_history SET from_status = 'cancelled' WHERE from_status = 'canceled';
+UPDATE order_status_history SET to_status = 'cancelled' WHERE to_status = 'canceled';
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (
+    status IN (
+        'pending_payment',
+        'paid',
+        'processing',
+        'shipped',
+        'delivered',
+        'completed',
+        'cancelled',
+        'refunded'
+    )
+);
+
+DROP INDEX IF EXISTS idx_order_status_history_actor;
+
+ALTER TABLE order_status_history
+    DROP COLUMN IF EXISTS actor_id,
+    DROP COLUMN IF EXISTS actor_role;
+
+CREATE OR REPLACE FUNCTION log_order_status_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.status != NEW.status THEN
+        INSERT INTO order_status_history (
+            order_id,
+            from_status,
+            to_status,
+            created_by
+        ) VALUES (
+            NEW.id,
+            OLD.status,
+            NEW.status,
+            'system'
+        );
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER log_order_status_change_trigger
+    AFTER UPDATE OF status ON orders
+    FOR EACH ROW
+    EXECUTE FUNCTION log_order_status_change();
@@ -0,0 +1,36 @@
+-- Status changes are now recorded by the order service (internal/order/lifecycle)
+-- together with the actor that triggered them, so the trigger that logged every
+-- change as 'system' would only produce duplicate rows.
+DROP TRIGGER IF EXISTS log_order_status_change_trigger ON orders;
+DROP FUNCTION IF EXISTS log_order_status_change();
+
+ALTER TABLE order_status_history
+    ADD COLUMN actor_role VARCHAR(20) NOT NULL DEFAULT 'system'
+        CHECK (actor_role IN ('buyer', 'seller', 'admin', 'system')),
+    ADD COLUMN actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_order_status_history_actor ON order_status_history(actor_id) WHERE actor_id IS NOT NULL;
+
+-- The order service writes 'canceled' and canceled_at (see internal/order/model)
+UPDATE orders SET status = 'canceled' WHERE status = 'cancelled';
+UPDATE order_status_history SET from_status = 'canceled' WHERE from_status = 'cancelled';
+UPDATE order_status_history SET to_status = 'canceled' WHERE to_status = 'cancelled';
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (
+    status IN (
+        'pending_payment',
+        'paid',
+        'processing',
+        'shipped',
+        'delivered',
+        'completed',
+        'canceled',
+        'refunded'
+    )
+);
+
+ALTER TABLE orders RENAME COLUMN cancelled_at TO canceled_at;
+
+COMMENT ON COLUMN order_status_history.created_by IS 'Actor label, e.g. seller_12 or system';
+COMMENT ON COLUMN order_status_history.actor_role IS 'Role that triggered the transition: buyer, seller, admin, system';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_pending_payment;
+
+ALTER TABLE users DROP COLUMN IF EXISTS strike_points;
+
+DROP TABLE IF EXISTS user_strikes;
@@ -0,0 +1,20 @@
+-- Strikes against user accounts (e.g. a buyer who never paid for a matched order)
+CREATE TABLE IF NOT EXISTS user_strikes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id BIGINT REFERENCES orders(id) ON DELETE SET NULL,
+    reason VARCHAR(50) NOT NULL,
+    points INT NOT NULL CHECK (points > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_strikes_user_id ON user_strikes(user_id, created_at DESC);
+
+-- Running total so account standing can be checked without summing strikes
+ALTER TABLE users ADD COLUMN strike_points INT NOT NULL DEFAULT 0;
+
+-- Speeds up the unpaid order worker
+CREATE INDEX IF NOT EXISTS idx_orders_pending_payment ON orders(created_at) WHERE status = 'pending_payment';
+
+COMMENT ON TABLE user_strikes IS 'Penalties recorded against user accounts';
+COMMENT ON COLUMN users.strike_points IS 'Sum of user_strikes.points';
@@ -0,0 +1,22 @@
+COMMENT ON COLUMN users.role IS 'User role: user, admin';
+
+DROP TABLE IF EXISTS order_returns;
+DROP TABLE IF EXISTS order_authentications;
+
+ALTER TABLE order_status_history DROP CONSTRAINT IF EXISTS order_status_history_actor_role_check;
+ALTER TABLE order_status_history ADD CONSTRAINT order_status_history_actor_role_check
+    CHECK (actor_role IN ('buyer', 'seller', 'admin', 'system'));
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (
+    status IN (
+        'pending_payment',
+        'paid',
+        'processing',
+        'shipped',
+        'delivered',
+        'completed',
+        'canceled',
+        'refunded'
+    )
+);
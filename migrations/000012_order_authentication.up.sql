@@ -0,0 +1,58 @@
+-- Items are checked by an authenticator between shipping and delivery
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (
+    status IN (
+        'pending_payment',
+        'paid',
+        'processing',
+        'shipped',
+        'received_at_authentication',
+        'authenticated',
+        'failed_authentication',
+        'delivered',
+        'completed',
+        'canceled',
+        'refunded'
+    )
+);
+
+ALTER TABLE order_status_history DROP CONSTRAINT IF EXISTS order_status_history_actor_role_check;
+ALTER TABLE order_status_history ADD CONSTRAINT order_status_history_actor_role_check
+    CHECK (actor_role IN ('buyer', 'seller', 'authenticator', 'admin', 'system'));
+
+-- Authenticator inspections (one row per step: received, passed, failed)
+CREATE TABLE IF NOT EXISTS order_authentications (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    authenticator_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    result VARCHAR(20) NOT NULL CHECK (result IN ('received', 'passed', 'failed')),
+    notes TEXT,
+    photo_urls TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_authentications_order_id ON order_authentications(order_id, created_at);
+CREATE INDEX idx_order_authentications_authenticator_id ON order_authentications(authenticator_id);
+
+-- Items sent back to the seller (e.g. failed authentication)
+CREATE TABLE IF NOT EXISTS order_returns (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    seller_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'shipped', 'delivered')),
+    tracking_number VARCHAR(100),
+    carrier VARCHAR(50),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_returns_seller_id ON order_returns(seller_id);
+CREATE INDEX idx_order_returns_status ON order_returns(status);
+
+CREATE TRIGGER update_order_returns_updated_at BEFORE UPDATE ON order_returns
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE order_authentications IS 'Authenticator inspection results with notes and photos';
+COMMENT ON TABLE order_returns IS 'Return shipments to sellers';
+COMMENT ON COLUMN users.role IS 'User role: user, authenticator, admin';
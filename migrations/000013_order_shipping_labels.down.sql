@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS label_url;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_service;
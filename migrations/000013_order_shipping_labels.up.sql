@@ -0,0 +1,5 @@
+-- Prepaid shipping labels bought through the carrier provider
+ALTER TABLE orders ADD COLUMN shipping_service VARCHAR(50);
+ALTER TABLE orders ADD COLUMN label_url TEXT;
+
+COMMENT ON COLUMN orders.label_url IS 'Label PDF reference returned by the carrier provider';
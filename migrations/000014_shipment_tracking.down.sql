@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS shipment_events;
+
+DROP INDEX IF EXISTS idx_orders_outbound_tracking_number;
+DROP INDEX IF EXISTS idx_orders_tracking_number;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS outbound_label_url;
+ALTER TABLE orders DROP COLUMN IF EXISTS outbound_carrier;
+ALTER TABLE orders DROP COLUMN IF EXISTS outbound_tracking_number;
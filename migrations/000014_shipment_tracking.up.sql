@@ -0,0 +1,28 @@
+-- Second leg: authentication center -> buyer
+ALTER TABLE orders ADD COLUMN outbound_tracking_number VARCHAR(100);
+ALTER TABLE orders ADD COLUMN outbound_carrier VARCHAR(50);
+ALTER TABLE orders ADD COLUMN outbound_label_url TEXT;
+
+-- Carrier webhooks look orders up by tracking number
+CREATE INDEX idx_orders_tracking_number ON orders(tracking_number) WHERE tracking_number IS NOT NULL;
+CREATE INDEX idx_orders_outbound_tracking_number ON orders(outbound_tracking_number) WHERE outbound_tracking_number IS NOT NULL;
+
+-- Create shipment_events table (carrier scans)
+CREATE TABLE shipment_events (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    leg VARCHAR(20) NOT NULL CHECK (leg IN ('inbound', 'outbound')),
+    tracking_number VARCHAR(100) NOT NULL,
+    carrier VARCHAR(50) NOT NULL,
+    status VARCHAR(30) NOT NULL CHECK (status IN ('label_created', 'in_transit', 'out_for_delivery', 'delivered', 'exception')),
+    description TEXT,
+    location VARCHAR(255),
+    event_id VARCHAR(255),
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    -- Carriers redeliver webhooks; NULL event IDs are never deduplicated
+    CONSTRAINT uq_shipment_events_carrier_event UNIQUE (carrier, event_id)
+);
+
+CREATE INDEX idx_shipment_events_order ON shipment_events(order_id, occurred_at);
@@ -0,0 +1,5 @@
+DELETE FROM audit_logs WHERE admin_id IS NULL;
+ALTER TABLE audit_logs ALTER COLUMN admin_id SET NOT NULL;
+
+DROP TABLE IF EXISTS dispute_events;
+DROP TABLE IF EXISTS disputes;
@@ -0,0 +1,49 @@
+-- Create disputes table (buyer cases against delivered orders)
+CREATE TABLE disputes (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    buyer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    seller_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('not_as_described', 'wrong_item', 'damaged', 'not_received', 'other')),
+    description TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'seller_responded', 'resolved')),
+    outcome VARCHAR(20) CHECK (outcome IN ('full_refund', 'partial_refund', 'return', 'seller_favored')),
+    refund_amount DECIMAL(10, 2),
+    payout_held BOOLEAN NOT NULL DEFAULT FALSE,
+    resolved_by BIGINT REFERENCES users(id),
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_disputes_resolution CHECK (
+        (status = 'resolved') = (outcome IS NOT NULL AND resolved_by IS NOT NULL)
+    )
+);
+
+-- One open case per order
+CREATE UNIQUE INDEX uq_disputes_open_order ON disputes(order_id) WHERE status <> 'resolved';
+CREATE INDEX idx_disputes_status_created ON disputes(status, created_at);
+CREATE INDEX idx_disputes_seller_id ON disputes(seller_id);
+
+CREATE TRIGGER update_disputes_updated_at BEFORE UPDATE ON disputes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Create dispute_events table (case timeline)
+CREATE TABLE dispute_events (
+    id BIGSERIAL PRIMARY KEY,
+    dispute_id BIGINT NOT NULL REFERENCES disputes(id) ON DELETE CASCADE,
+    actor_role VARCHAR(20) NOT NULL CHECK (actor_role IN ('buyer', 'seller', 'admin', 'system')),
+    actor_id BIGINT REFERENCES users(id),
+    action VARCHAR(30) NOT NULL,
+    note TEXT,
+    evidence_urls TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_dispute_events_dispute_id ON dispute_events(dispute_id, created_at);
+
+-- Buyer and seller dispute steps are audited without an admin
+ALTER TABLE audit_logs ALTER COLUMN admin_id DROP NOT NULL;
+
+COMMENT ON TABLE disputes IS 'Buyer disputes; seller payout is held while a case is open';
+COMMENT ON TABLE dispute_events IS 'Dispute timeline: opened, seller response, admin decision';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_orders_delivered;
+ALTER TABLE orders DROP COLUMN IF EXISTS inspection_reminder_sent_at;
+DROP TABLE IF EXISTS vertical_order_policies;
@@ -0,0 +1,20 @@
+-- Inspection window per vertical (products.category); categories without a
+-- row use ORDER_INSPECTION_DAYS
+CREATE TABLE vertical_order_policies (
+    vertical VARCHAR(100) PRIMARY KEY,
+    inspection_days INTEGER NOT NULL CHECK (inspection_days >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_vertical_order_policies_updated_at BEFORE UPDATE ON vertical_order_policies
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Tickets are checked at the door; no need to wait days
+INSERT INTO vertical_order_policies (vertical, inspection_days) VALUES ('tickets', 1);
+
+ALTER TABLE orders ADD COLUMN inspection_reminder_sent_at TIMESTAMP;
+
+CREATE INDEX idx_orders_delivered ON orders(delivered_at) WHERE status = 'delivered';
+
+COMMENT ON TABLE vertical_order_policies IS 'Days a delivered order waits for disputes before auto-completing';
@@ -0,0 +1,14 @@
+-- Buyer receipts and seller statements. A document's number is assigned the
+-- first time it is downloaded and never changes afterwards.
+CREATE SEQUENCE invoice_number_seq;
+
+CREATE TABLE invoices (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('buyer_receipt', 'seller_statement')),
+    invoice_number VARCHAR(50) UNIQUE NOT NULL,
+    issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(order_id, kind)
+);
+
+COMMENT ON TABLE invoices IS 'Stable numbers for buyer receipts (INV-B-...) and seller sale statements (INV-S-...)';
@@ -0,0 +1,22 @@
+-- Seller sales exports and yearly tax summaries, built by the order service
+-- export worker. The file is kept with the job until it is downloaded.
+CREATE TABLE export_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    seller_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('sales', 'tax_summary')),
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'xlsx')),
+    year INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    filename VARCHAR(255),
+    content_type VARCHAR(100),
+    content BYTEA,
+    error TEXT,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_export_jobs_seller ON export_jobs(seller_id, created_at DESC);
+CREATE INDEX idx_export_jobs_pending ON export_jobs(created_at) WHERE status IN ('pending', 'processing');
+
+COMMENT ON TABLE export_jobs IS 'Async seller sales exports (CSV/XLSX)';
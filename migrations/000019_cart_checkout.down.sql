@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_payments_checkout;
+ALTER TABLE payments DROP COLUMN IF EXISTS checkout_id;
+ALTER TABLE payments ADD CONSTRAINT payments_stripe_payment_intent_id_key UNIQUE (stripe_payment_intent_id);
+
+DROP INDEX IF EXISTS idx_orders_checkout;
+ALTER TABLE orders DROP COLUMN IF EXISTS checkout_id;
+
+DROP TABLE IF EXISTS checkouts;
+DROP TABLE IF EXISTS cart_items;
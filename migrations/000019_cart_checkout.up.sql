@@ -0,0 +1,36 @@
+-- Multi-item cart: buyers collect lowest-ask items and check out once
+CREATE TABLE cart_items (
+    id BIGSERIAL PRIMARY KEY,
+    buyer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    size_id BIGINT NOT NULL REFERENCES sizes(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    price_at_add DECIMAL(10, 2) NOT NULL CHECK (price_at_add > 0), -- lowest ask when added; checkout fails if it rose
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(buyer_id, product_id, size_id)
+);
+
+CREATE TRIGGER update_cart_items_updated_at BEFORE UPDATE ON cart_items
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- One checkout creates one order per ask (seller) and one combined payment
+CREATE TABLE checkouts (
+    id BIGSERIAL PRIMARY KEY,
+    buyer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    item_count INTEGER NOT NULL CHECK (item_count > 0),
+    total_amount DECIMAL(10, 2) NOT NULL CHECK (total_amount > 0),
+    stripe_payment_intent_id VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_checkouts_buyer ON checkouts(buyer_id, created_at DESC);
+
+ALTER TABLE orders ADD COLUMN checkout_id BIGINT REFERENCES checkouts(id) ON DELETE SET NULL;
+CREATE INDEX idx_orders_checkout ON orders(checkout_id) WHERE checkout_id IS NOT NULL;
+
+-- Orders of a checkout share one PaymentIntent; each keeps its own payment
+-- row with its share so refunds stay per order
+ALTER TABLE payments ADD COLUMN checkout_id BIGINT REFERENCES checkouts(id) ON DELETE SET NULL;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_stripe_payment_intent_id_key;
+CREATE INDEX idx_payments_checkout ON payments(checkout_id) WHERE checkout_id IS NOT NULL;
@@ -0,0 +1,18 @@
+-- Create order_messages table (buyer/seller thread per order, admins can read and post)
+CREATE TABLE order_messages (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    sender_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    sender_role VARCHAR(20) NOT NULL CHECK (sender_role IN ('buyer', 'seller', 'admin')),
+    body TEXT NOT NULL,
+    original_body TEXT,  -- set when contact details were removed; admins only
+    redacted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_order_messages_redacted CHECK (redacted = (original_body IS NOT NULL))
+);
+
+CREATE INDEX idx_order_messages_order_id ON order_messages(order_id, created_at);
+CREATE INDEX idx_order_messages_redacted ON order_messages(created_at) WHERE redacted;
+
+COMMENT ON TABLE order_messages IS 'Per-order messages; phone numbers and emails are removed before delivery';
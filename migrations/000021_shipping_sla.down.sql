@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS seller_penalties;
+DROP INDEX IF EXISTS idx_orders_ship_by;
+ALTER TABLE orders DROP COLUMN IF EXISTS ship_reminder_sent_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS ship_by;
+DELETE FROM vertical_order_policies WHERE inspection_days IS NULL;
+ALTER TABLE vertical_order_policies ALTER COLUMN inspection_days SET NOT NULL;
+ALTER TABLE vertical_order_policies DROP COLUMN IF EXISTS ship_within_hours;
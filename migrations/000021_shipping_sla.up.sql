@@ -0,0 +1,31 @@
+-- Shipping deadline per vertical; categories without a value use ORDER_SHIP_WITHIN_HOURS.
+-- A vertical may override only one of the two windows.
+ALTER TABLE vertical_order_policies ADD COLUMN ship_within_hours INTEGER CHECK (ship_within_hours > 0);
+ALTER TABLE vertical_order_policies ALTER COLUMN inspection_days DROP NOT NULL;
+
+-- Tickets are transferred electronically
+UPDATE vertical_order_policies SET ship_within_hours = 24 WHERE vertical = 'tickets';
+
+-- Deadline is fixed when the order is paid so policy changes don't move it
+ALTER TABLE orders ADD COLUMN ship_by TIMESTAMP;
+ALTER TABLE orders ADD COLUMN ship_reminder_sent_at TIMESTAMP;
+
+CREATE INDEX idx_orders_ship_by ON orders(ship_by) WHERE status IN ('paid', 'processing');
+
+-- Create seller_penalties table (missed shipping deadlines and similar violations)
+CREATE TABLE seller_penalties (
+    id BIGSERIAL PRIMARY KEY,
+    seller_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('late_shipment')),
+    amount DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_seller_penalties_order_reason UNIQUE (order_id, reason)
+);
+
+CREATE INDEX idx_seller_penalties_seller_id ON seller_penalties(seller_id, created_at);
+
+COMMENT ON COLUMN orders.ship_by IS 'Seller must ship by this time or the order is canceled and refunded';
+COMMENT ON TABLE seller_penalties IS 'Penalties charged to sellers, e.g. for missing the shipping deadline';
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_orders_outbound_carrier;
+DROP INDEX IF EXISTS idx_orders_carrier;
+DROP INDEX IF EXISTS idx_orders_order_number_prefix;
+DROP INDEX IF EXISTS idx_orders_seller_created_at;
+DROP INDEX IF EXISTS idx_orders_buyer_created_at;
+DROP INDEX IF EXISTS idx_orders_total_amount_id;
+DROP INDEX IF EXISTS idx_orders_price_id;
+DROP INDEX IF EXISTS idx_orders_created_at_id;
@@ -0,0 +1,17 @@
+-- Indexes backing order search (keyset pagination and prefix/carrier filters)
+
+-- Keyset pagination on (sort key, id)
+CREATE INDEX idx_orders_created_at_id ON orders(created_at DESC, id DESC);
+CREATE INDEX idx_orders_price_id ON orders(price, id);
+CREATE INDEX idx_orders_total_amount_id ON orders(total_amount, id);
+
+-- Per-user history pages
+CREATE INDEX idx_orders_buyer_created_at ON orders(buyer_id, created_at DESC, id DESC);
+CREATE INDEX idx_orders_seller_created_at ON orders(seller_id, created_at DESC, id DESC);
+
+-- Order number prefix match (LIKE 'ORD-2026%')
+CREATE INDEX idx_orders_order_number_prefix ON orders(order_number text_pattern_ops);
+
+-- Case-insensitive carrier filter
+CREATE INDEX idx_orders_carrier ON orders(LOWER(carrier));
+CREATE INDEX idx_orders_outbound_carrier ON orders(LOWER(outbound_carrier));
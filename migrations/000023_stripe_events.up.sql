@@ -0,0 +1,28 @@
+-- Received Stripe webhook events, keyed by Stripe's event ID so that
+-- redelivered events are recognised and not applied twice
+CREATE TABLE IF NOT EXISTS stripe_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(255) UNIQUE NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (
+        status IN ('processing', 'processed', 'ignored', 'failed')
+    ),
+    attempts INT NOT NULL DEFAULT 1,
+    error TEXT,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stripe_events_type ON stripe_events(event_type);
+CREATE INDEX idx_stripe_events_status ON stripe_events(status) WHERE status <> 'processed';
+
+CREATE TRIGGER update_stripe_events_updated_at BEFORE UPDATE ON stripe_events
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Reason reported by Stripe for failed payments and transfers
+ALTER TABLE payments ADD COLUMN failure_reason TEXT;
+
+COMMENT ON TABLE stripe_events IS 'Stripe webhook events, deduplicated by event ID';
+COMMENT ON COLUMN stripe_events.status IS 'processing, processed, ignored (unhandled type) or failed (retried on redelivery)';
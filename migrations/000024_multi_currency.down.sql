@@ -0,0 +1,37 @@
+ALTER TABLE checkouts ALTER COLUMN total_amount TYPE DECIMAL(10, 2);
+ALTER TABLE checkouts DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS seller_fx_rate,
+    DROP COLUMN IF EXISTS seller_currency,
+    DROP COLUMN IF EXISTS buyer_fx_rate,
+    DROP COLUMN IF EXISTS buyer_total,
+    DROP COLUMN IF EXISTS buyer_currency,
+    DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE matches
+    DROP COLUMN IF EXISTS buyer_fx_rate,
+    DROP COLUMN IF EXISTS buyer_currency,
+    DROP COLUMN IF EXISTS seller_fx_rate,
+    DROP COLUMN IF EXISTS seller_price,
+    DROP COLUMN IF EXISTS seller_currency,
+    DROP COLUMN IF EXISTS currency;
+
+DROP INDEX IF EXISTS idx_bids_price;
+DROP INDEX IF EXISTS idx_asks_price;
+
+ALTER TABLE asks
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS settlement_price,
+    DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE bids
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS settlement_price,
+    DROP COLUMN IF EXISTS currency;
+
+CREATE INDEX idx_bids_price ON bids(product_id, size_id, price DESC) WHERE status = 'active';
+CREATE INDEX idx_asks_price ON asks(product_id, size_id, price ASC) WHERE status = 'active';
+
+DROP TABLE IF EXISTS fx_conversions;
+DROP TABLE IF EXISTS fx_rates;
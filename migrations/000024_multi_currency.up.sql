@@ -0,0 +1,89 @@
+-- Exchange rates, newest per pair wins. Rates are one unit of base_currency
+-- in quote_currency, as published by the source.
+CREATE TABLE IF NOT EXISTS fx_rates (
+    id BIGSERIAL PRIMARY KEY,
+    base_currency VARCHAR(3) NOT NULL,
+    quote_currency VARCHAR(3) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    source VARCHAR(50) NOT NULL,
+    as_of TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (base_currency <> quote_currency)
+);
+
+CREATE INDEX idx_fx_rates_pair ON fx_rates(base_currency, quote_currency, as_of DESC);
+
+-- Audit of every conversion that priced a listing, order, payment or refund
+CREATE TABLE IF NOT EXISTS fx_conversions (
+    id BIGSERIAL PRIMARY KEY,
+    purpose VARCHAR(30) NOT NULL,
+    reference_type VARCHAR(30) NOT NULL,
+    reference_id BIGINT NOT NULL,
+    from_currency VARCHAR(3) NOT NULL,
+    from_amount DECIMAL(12, 2) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    to_amount DECIMAL(12, 2) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,
+    rate_ids BIGINT[] NOT NULL DEFAULT '{}',
+    rate_as_of TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_fx_conversions_reference ON fx_conversions(reference_type, reference_id);
+CREATE INDEX idx_fx_conversions_created_at ON fx_conversions(created_at DESC);
+
+-- Bids and asks keep the price the user entered, in their currency, and the
+-- settlement-currency price it converted to when placed. Matching and the
+-- order book use settlement_price.
+ALTER TABLE bids
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN settlement_price DECIMAL(10, 2),
+    ADD COLUMN fx_rate NUMERIC(20, 10) NOT NULL DEFAULT 1;
+UPDATE bids SET settlement_price = price;
+ALTER TABLE bids ALTER COLUMN settlement_price SET NOT NULL;
+
+ALTER TABLE asks
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN settlement_price DECIMAL(10, 2),
+    ADD COLUMN fx_rate NUMERIC(20, 10) NOT NULL DEFAULT 1;
+UPDATE asks SET settlement_price = price;
+ALTER TABLE asks ALTER COLUMN settlement_price SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_bids_price;
+DROP INDEX IF EXISTS idx_asks_price;
+CREATE INDEX idx_bids_price ON bids(product_id, size_id, settlement_price DESC) WHERE status = 'active';
+CREATE INDEX idx_asks_price ON asks(product_id, size_id, settlement_price ASC) WHERE status = 'active';
+
+-- Matches are priced in the settlement currency and snapshot both sides' rates
+ALTER TABLE matches
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN seller_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN seller_price DECIMAL(10, 2),
+    ADD COLUMN seller_fx_rate NUMERIC(20, 10) NOT NULL DEFAULT 1,
+    ADD COLUMN buyer_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN buyer_fx_rate NUMERIC(20, 10) NOT NULL DEFAULT 1;
+UPDATE matches SET seller_price = price;
+ALTER TABLE matches ALTER COLUMN seller_price SET NOT NULL;
+
+-- Order amounts are in the settlement currency; the buyer pays buyer_total
+-- in buyer_currency
+ALTER TABLE orders
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN buyer_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN buyer_total DECIMAL(12, 2),
+    ADD COLUMN buyer_fx_rate NUMERIC(20, 10) NOT NULL DEFAULT 1,
+    ADD COLUMN seller_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN seller_fx_rate NUMERIC(20, 10) NOT NULL DEFAULT 1;
+UPDATE orders SET buyer_total = total_amount;
+ALTER TABLE orders ALTER COLUMN buyer_total SET NOT NULL;
+
+ALTER TABLE checkouts ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE checkouts ALTER COLUMN total_amount TYPE DECIMAL(12, 2);
+
+COMMENT ON TABLE fx_rates IS 'Exchange rates loaded from the configured rate provider';
+COMMENT ON TABLE fx_conversions IS 'Every currency conversion with the rate it used';
+COMMENT ON COLUMN fx_conversions.rate_ids IS 'fx_rates rows the rate was derived from (two for cross rates)';
+COMMENT ON COLUMN bids.fx_rate IS 'bid currency -> settlement currency rate snapshotted when placed';
+COMMENT ON COLUMN asks.fx_rate IS 'ask currency -> settlement currency rate snapshotted when placed';
+COMMENT ON COLUMN orders.buyer_fx_rate IS 'settlement currency -> buyer_currency rate snapshotted at checkout';
+COMMENT ON COLUMN orders.seller_fx_rate IS 'seller_currency -> settlement currency rate of the ask';
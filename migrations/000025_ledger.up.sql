@@ -0,0 +1,61 @@
+-- Double-entry ledger. Every money movement is one balanced entry: per
+-- currency, its debit lines equal its credit lines.
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(30) NOT NULL CHECK (
+        type IN ('processor_cash', 'buyer_funds', 'seller_payable', 'platform_revenue', 'processor_fees', 'fx_clearing')
+    ),
+    owner_id BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL,
+    balance DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (type, owner_id, currency)
+);
+
+CREATE INDEX idx_ledger_accounts_owner ON ledger_accounts(owner_id) WHERE owner_id <> 0;
+
+CREATE TRIGGER update_ledger_accounts_updated_at BEFORE UPDATE ON ledger_accounts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    entry_key VARCHAR(150) UNIQUE NOT NULL,
+    kind VARCHAR(40) NOT NULL,
+    reference_type VARCHAR(30) NOT NULL,
+    reference_id BIGINT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_entries_reference ON ledger_entries(reference_type, reference_id);
+CREATE INDEX idx_ledger_entries_created_at ON ledger_entries(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS ledger_lines (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL REFERENCES ledger_entries(id),
+    account_id BIGINT NOT NULL REFERENCES ledger_accounts(id),
+    side VARCHAR(6) NOT NULL CHECK (side IN ('debit', 'credit')),
+    amount DECIMAL(14, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL
+);
+
+CREATE INDEX idx_ledger_lines_entry ON ledger_lines(entry_id);
+CREATE INDEX idx_ledger_lines_account ON ledger_lines(account_id);
+
+-- Posted entries are never edited; corrections are new entries
+CREATE OR REPLACE FUNCTION reject_ledger_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger % rows are append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_append_only BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION reject_ledger_change();
+CREATE TRIGGER ledger_lines_append_only BEFORE UPDATE OR DELETE ON ledger_lines
+    FOR EACH ROW EXECUTE FUNCTION reject_ledger_change();
+
+COMMENT ON TABLE ledger_accounts IS 'Ledger accounts, one per type, owner and currency';
+COMMENT ON COLUMN ledger_accounts.owner_id IS 'Buyer or seller user ID; 0 for platform accounts';
+COMMENT ON COLUMN ledger_accounts.balance IS 'Running balance on the account''s normal side (debit for assets and expenses)';
+COMMENT ON COLUMN ledger_entries.entry_key IS 'Identifies the money movement so it is posted once';
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS wallet_transactions;
+
+-- Wallet batches have no single order
+DELETE FROM payouts WHERE order_id IS NULL;
+
+DROP INDEX IF EXISTS idx_payouts_wallet_id;
+ALTER TABLE payouts DROP COLUMN IF EXISTS wallet_id;
+ALTER TABLE payouts ALTER COLUMN order_id SET NOT NULL;
+
+DROP TABLE IF EXISTS seller_wallets;
@@ -0,0 +1,57 @@
+-- Seller wallets. Completed sales credit the wallet and payouts are paid
+-- from it in batches, on the seller's schedule, once the balance reaches
+-- the minimum.
+CREATE TABLE IF NOT EXISTS seller_wallets (
+    id BIGSERIAL PRIMARY KEY,
+    seller_id BIGINT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    currency VARCHAR(3) NOT NULL,
+    balance DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    payout_schedule VARCHAR(20) NOT NULL DEFAULT 'weekly' CHECK (
+        payout_schedule IN ('daily', 'weekly', 'on_demand')
+    ),
+    minimum_payout DECIMAL(14, 2) NOT NULL DEFAULT 0 CHECK (minimum_payout >= 0),
+    stripe_account_id VARCHAR(255),
+    next_payout_at TIMESTAMP,
+    last_payout_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (seller_id, currency)
+);
+
+CREATE INDEX idx_seller_wallets_next_payout ON seller_wallets(next_payout_at)
+    WHERE payout_schedule <> 'on_demand';
+
+CREATE TRIGGER update_seller_wallets_updated_at BEFORE UPDATE ON seller_wallets
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Payouts are paid per wallet batch rather than per order
+ALTER TABLE payouts ALTER COLUMN order_id DROP NOT NULL;
+ALTER TABLE payouts ADD COLUMN wallet_id BIGINT REFERENCES seller_wallets(id) ON DELETE RESTRICT;
+
+CREATE INDEX idx_payouts_wallet_id ON payouts(wallet_id) WHERE wallet_id IS NOT NULL;
+
+-- Every change to a wallet balance
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id BIGINT NOT NULL REFERENCES seller_wallets(id) ON DELETE RESTRICT,
+    type VARCHAR(30) NOT NULL CHECK (
+        type IN ('sale', 'payout', 'payout_returned')
+    ),
+    amount DECIMAL(14, 2) NOT NULL CHECK (amount <> 0),
+    balance_after DECIMAL(14, 2) NOT NULL,
+    order_id BIGINT REFERENCES orders(id) ON DELETE RESTRICT,
+    payout_id BIGINT REFERENCES payouts(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_wallet_transactions_wallet ON wallet_transactions(wallet_id, created_at DESC);
+CREATE INDEX idx_wallet_transactions_payout ON wallet_transactions(payout_id) WHERE payout_id IS NOT NULL;
+
+-- A sale is credited once; a payout is debited and returned at most once
+CREATE UNIQUE INDEX idx_wallet_transactions_sale ON wallet_transactions(order_id) WHERE type = 'sale';
+CREATE UNIQUE INDEX idx_wallet_transactions_payout_once ON wallet_transactions(type, payout_id)
+    WHERE type IN ('payout', 'payout_returned');
+
+COMMENT ON TABLE seller_wallets IS 'Seller balances paid out in scheduled batches';
+COMMENT ON COLUMN seller_wallets.next_payout_at IS 'When the next scheduled batch runs; NULL for on_demand';
+COMMENT ON COLUMN wallet_transactions.payout_id IS 'For sales: the payout batch that paid them out';
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_wallet_transactions_order;
+DROP INDEX IF EXISTS idx_wallet_transactions_clawback;
+
+DELETE FROM wallet_transactions WHERE type = 'clawback';
+ALTER TABLE wallet_transactions DROP CONSTRAINT wallet_transactions_type_check;
+ALTER TABLE wallet_transactions ADD CONSTRAINT wallet_transactions_type_check CHECK (
+    type IN ('sale', 'payout', 'payout_returned')
+);
+
+ALTER TABLE wallet_transactions DROP COLUMN IF EXISTS reference;
+ALTER TABLE wallet_transactions DROP COLUMN IF EXISTS frozen_at;
+ALTER TABLE seller_wallets DROP COLUMN IF EXISTS frozen_balance;
+
+DROP TABLE IF EXISTS chargebacks;
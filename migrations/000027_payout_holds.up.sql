@@ -0,0 +1,48 @@
+-- Chargebacks opened by the buyer's bank, one row per order payment on the
+-- disputed charge. An open chargeback freezes the order's sale in the
+-- seller's wallet; a lost one is clawed back from the seller.
+CREATE TABLE IF NOT EXISTS chargebacks (
+    id BIGSERIAL PRIMARY KEY,
+    stripe_dispute_id VARCHAR(255) NOT NULL,
+    stripe_charge_id VARCHAR(255) NOT NULL,
+    payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE RESTRICT,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE RESTRICT,
+    amount DECIMAL(14, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    reason VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'won', 'lost')),
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (stripe_dispute_id, payment_id)
+);
+
+CREATE INDEX idx_chargebacks_order_open ON chargebacks(order_id) WHERE status = 'open';
+
+CREATE TRIGGER update_chargebacks_updated_at BEFORE UPDATE ON chargebacks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Part of the balance that cannot be paid out while a chargeback is open
+ALTER TABLE seller_wallets ADD COLUMN frozen_balance DECIMAL(14, 2) NOT NULL DEFAULT 0
+    CHECK (frozen_balance >= 0);
+
+-- Sales frozen by an open chargeback stay out of payout batches
+ALTER TABLE wallet_transactions ADD COLUMN frozen_at TIMESTAMP;
+
+-- Refunds and lost chargebacks after a sale are reclaimed from the seller
+ALTER TABLE wallet_transactions DROP CONSTRAINT wallet_transactions_type_check;
+ALTER TABLE wallet_transactions ADD CONSTRAINT wallet_transactions_type_check CHECK (
+    type IN ('sale', 'payout', 'payout_returned', 'clawback')
+);
+
+-- What a clawback reclaims (a refund total or a lost chargeback), so each is
+-- reclaimed once however it was reported
+ALTER TABLE wallet_transactions ADD COLUMN reference VARCHAR(100);
+
+CREATE UNIQUE INDEX idx_wallet_transactions_clawback ON wallet_transactions(reference)
+    WHERE type = 'clawback';
+CREATE INDEX idx_wallet_transactions_order ON wallet_transactions(order_id) WHERE order_id IS NOT NULL;
+
+COMMENT ON TABLE chargebacks IS 'Card disputes opened by the buyer''s bank, per order payment';
+COMMENT ON COLUMN seller_wallets.frozen_balance IS 'Sales frozen by open chargebacks; excluded from payouts';
+COMMENT ON COLUMN wallet_transactions.payout_id IS 'For sales: the payout batch that paid them out. For clawbacks: the payout they reverse';
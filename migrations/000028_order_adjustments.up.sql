@@ -0,0 +1,18 @@
+-- Money returned to the buyer on an order, one row per refund. Partial
+-- refunds leave the order's status alone; the adjustments record them.
+CREATE TABLE IF NOT EXISTS order_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('refund')),
+    amount DECIMAL(14, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    reference VARCHAR(100) NOT NULL UNIQUE,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_adjustments_order_id ON order_adjustments(order_id);
+
+COMMENT ON TABLE order_adjustments IS 'Refunds and other changes to what the buyer paid for an order';
+COMMENT ON COLUMN order_adjustments.amount IS 'In the currency the buyer paid';
+COMMENT ON COLUMN order_adjustments.reference IS 'Identifies the refund so redeliveries record it once';
@@ -0,0 +1,24 @@
+-- Idempotency keys sent with payment-mutating RPCs. A retry with the same
+-- key and request replays the stored response; the same key with another
+-- request is rejected.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(50) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'completed')),
+    response BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (scope, key),
+    CHECK ((status = 'completed') = (response IS NOT NULL))
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+CREATE TRIGGER update_idempotency_keys_updated_at BEFORE UPDATE ON idempotency_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE idempotency_keys IS 'Client idempotency keys for CreatePaymentIntent, CreateRefund and CreatePayout';
+COMMENT ON COLUMN idempotency_keys.scope IS 'The RPC the key was used with';
+COMMENT ON COLUMN idempotency_keys.request_hash IS 'SHA-256 of the request; a different request with the same key is rejected';
+COMMENT ON COLUMN idempotency_keys.response IS 'Protobuf-encoded response replayed to retries';
@@ -0,0 +1,28 @@
+-- Side effects of an order status change (refunds, payouts, ledger postings,
+-- inventory, notifications). One job per hook is queued in the transaction
+-- that changes the status, so a committed change never loses its side
+-- effects; failed hooks are retried with backoff by the order service.
+CREATE TABLE order_hook_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    hook VARCHAR(50) NOT NULL,
+    from_status VARCHAR(30) NOT NULL,
+    to_status VARCHAR(30) NOT NULL,
+    actor_role VARCHAR(20) NOT NULL,
+    actor_id BIGINT,
+    note TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'done', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    run_after TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_hook_jobs_order ON order_hook_jobs(order_id, created_at);
+CREATE INDEX idx_order_hook_jobs_due ON order_hook_jobs(run_after) WHERE status IN ('pending', 'processing');
+CREATE INDEX idx_order_hook_jobs_failed ON order_hook_jobs(created_at) WHERE status = 'failed';
+
+COMMENT ON TABLE order_hook_jobs IS 'Outbox of order status hooks, drained with retries';
+COMMENT ON COLUMN order_hook_jobs.status IS 'failed: gave up after the last attempt, needs support';
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS inventory_reference;
+ALTER TABLE matches DROP COLUMN IF EXISTS inventory_reference;
+ALTER TABLE asks DROP COLUMN IF EXISTS split_from_id;
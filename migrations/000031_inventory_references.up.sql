@@ -0,0 +1,19 @@
+-- An ask reserves its units under 'ask-<id>'. Asks split off it when some of
+-- its units sell keep that reservation, and the match and order carry it so
+-- the sale completes or releases the units it reserved.
+ALTER TABLE asks ADD COLUMN split_from_id BIGINT REFERENCES asks(id);
+
+ALTER TABLE matches ADD COLUMN inventory_reference VARCHAR(50);
+UPDATE matches SET inventory_reference = 'ask-' || ask_id;
+ALTER TABLE matches ALTER COLUMN inventory_reference SET NOT NULL;
+
+ALTER TABLE orders ADD COLUMN inventory_reference VARCHAR(50);
+UPDATE orders o SET inventory_reference = m.inventory_reference
+FROM matches m
+WHERE m.id = o.match_id;
+UPDATE orders SET inventory_reference = order_number WHERE inventory_reference IS NULL;
+ALTER TABLE orders ALTER COLUMN inventory_reference SET NOT NULL;
+
+COMMENT ON COLUMN asks.split_from_id IS 'The ask this one was split off; its reservation holds the units';
+COMMENT ON COLUMN matches.inventory_reference IS 'Reservation of the sold units (inventory_transactions.reference_id)';
+COMMENT ON COLUMN orders.inventory_reference IS 'Reservation the order completes or releases (inventory_transactions.reference_id)';
@@ -0,0 +1,24 @@
+-- Capture of a matched bid's payment hold. It is queued with the match and
+-- retried by the bidding expiry worker; when it runs out of attempts the
+-- hold is released, the bid expires and the ask goes back on the market.
+CREATE TABLE match_captures (
+    match_id BIGINT PRIMARY KEY REFERENCES matches(id) ON DELETE CASCADE,
+    bid_id BIGINT NOT NULL REFERENCES bids(id),
+    ask_id BIGINT NOT NULL REFERENCES asks(id),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'captured', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_match_captures_due ON match_captures(next_attempt_at) WHERE status = 'pending';
+
+CREATE TRIGGER update_match_captures_updated_at BEFORE UPDATE ON match_captures
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE match_captures IS 'Pending and finished captures of matched bids'' payment holds';
+COMMENT ON COLUMN match_captures.amount IS 'Charge in the bid''s currency';
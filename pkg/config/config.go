@@ -4,12 +4,19 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all application configuration
 type Config struct {
 	Services ServicesConfig
+	Bidding  BiddingConfig
+	Order    OrderConfig
+	Shipping ShippingConfig
+	Invoice  InvoiceConfig
+	FX       FXConfig
+	Payout   PayoutConfig
 	Database DatabaseConfig
 	Redis    RedisConfig
 	Stripe   StripeConfig
@@ -61,6 +68,8 @@ type StripeConfig struct {
 	SecretKey      string
 	WebhookSecret  string
 	PublishableKey string
+	Mode           string // "real" calls Stripe; "demo" uses the in-process fake provider
+	Faults         string // fake provider failure injection, e.g. "CreateTransfer,CreateRefund=0.25"
 }
 
 type EmailConfig struct {
@@ -69,6 +78,74 @@ type EmailConfig struct {
 	FromName       string
 }
 
+type BiddingConfig struct {
+	ExpiryInterval     time.Duration
+	RequirePaymentHold bool
+}
+
+type OrderConfig struct {
+	UnpaidTimeout       time.Duration // cancel orders left in pending_payment this long
+	UnpaidCheckInterval time.Duration
+	RelistUnpaidAsk     bool // re-activate the seller's ask instead of leaving it canceled
+	UnpaidStrikePoints  int  // strike points applied to the non-paying buyer (0 disables)
+
+	InspectionDays       int           // default days a delivered order waits before auto-completing
+	InspectionReminder   time.Duration // remind the buyer this long before the window closes (0 disables)
+	AutoCompleteInterval time.Duration
+
+	ShipWithinHours     int           // default hours a seller has to ship a paid order
+	ShipReminder        time.Duration // remind the seller this long before the deadline (0 disables)
+	LateShipPenaltyRate float64       // share of the sale price charged for a missed deadline
+	ShippingSLAInterval time.Duration
+
+	HookRetryInterval time.Duration // how often failed status hooks are retried
+
+	ExportInterval time.Duration // how often queued seller exports are built
+	// Yearly volume at which the tax summary flags a seller as reportable
+	TaxReportGrossThreshold float64
+	TaxReportOrderThreshold int
+}
+
+type ShippingConfig struct {
+	CarrierProvider      string // label provider: "fake"
+	CarrierWebhookSecret string // signs carrier tracking webhooks; the API gateway requires it
+	// Prepaid labels route sneakers through the authentication center
+	AuthCenterName       string
+	AuthCenterStreet     string
+	AuthCenterCity       string
+	AuthCenterState      string
+	AuthCenterPostalCode string
+	AuthCenterCountry    string
+}
+
+type InvoiceConfig struct {
+	// Marketplace details printed on receipts and sale statements
+	IssuerName    string
+	IssuerAddress []string // lines, separated by ";" in INVOICE_ISSUER_ADDRESS
+	IssuerTaxID   string
+	// Tax included in platform fees (0 disables the tax line)
+	TaxRate  float64
+	TaxLabel string
+}
+
+type FXConfig struct {
+	// Currency fees, orders and payouts are computed in; listings in other
+	// currencies are converted to it
+	SettlementCurrency string
+	RateProvider       string // "static" (FX_STATIC_RATES) or "file" (FX_RATES_FILE)
+	StaticRates        string // "EUR=0.92,GBP=0.79": one settlement unit in each currency
+	RatesFile          string
+	RefreshInterval    time.Duration
+	MaxRateAge         time.Duration // refuse to convert with older rates (0 disables)
+}
+
+type PayoutConfig struct {
+	// Defaults for new seller wallets; sellers can change their own
+	DefaultSchedule string  // daily, weekly or on_demand
+	MinimumPayout   float64 // smallest batch paid out, in the settlement currency
+	BatchInterval   time.Duration
+}
+
 type ServicesConfig struct {
 	UserService         string
 	ProductService      string
@@ -157,6 +234,8 @@ func Load() (*Config, error) {
 		SecretKey:      getEnv("STRIPE_SECRET_KEY", ""),
 		WebhookSecret:  getEnv("STRIPE_WEBHOOK_SECRET", ""),
 		PublishableKey: getEnv("STRIPE_PUBLISHABLE_KEY", ""),
+		Mode:           getEnv("STRIPE_MODE", "demo"),
+		Faults:         getEnv("PAYMENT_PROVIDER_FAULTS", ""),
 	}
 
 	// Email
@@ -179,6 +258,73 @@ func Load() (*Config, error) {
 		AuthService:         getEnv("AUTH_SERVICE_ADDR", "localhost:50059"),
 	}
 
+	// Bidding
+	cfg.Bidding = BiddingConfig{
+		ExpiryInterval:     getEnvAsDuration("BIDDING_EXPIRY_INTERVAL", time.Minute),
+		RequirePaymentHold: getEnvAsBool("BIDDING_REQUIRE_PAYMENT_HOLD", false),
+	}
+
+	// Orders
+	cfg.Order = OrderConfig{
+		UnpaidTimeout:       getEnvAsDuration("ORDER_UNPAID_TIMEOUT", 24*time.Hour),
+		UnpaidCheckInterval: getEnvAsDuration("ORDER_UNPAID_CHECK_INTERVAL", 5*time.Minute),
+		RelistUnpaidAsk:     getEnvAsBool("ORDER_RELIST_UNPAID_ASK", true),
+		UnpaidStrikePoints:  getEnvAsInt("ORDER_UNPAID_STRIKE_POINTS", 1),
+
+		InspectionDays:       getEnvAsInt("ORDER_INSPECTION_DAYS", 3),
+		InspectionReminder:   getEnvAsDuration("ORDER_INSPECTION_REMINDER", 24*time.Hour),
+		AutoCompleteInterval: getEnvAsDuration("ORDER_AUTO_COMPLETE_INTERVAL", 15*time.Minute),
+
+		ShipWithinHours:     getEnvAsInt("ORDER_SHIP_WITHIN_HOURS", 72),
+		ShipReminder:        getEnvAsDuration("ORDER_SHIP_REMINDER", 24*time.Hour),
+		LateShipPenaltyRate: getEnvAsFloat("ORDER_LATE_SHIP_PENALTY_RATE", 0.15),
+		ShippingSLAInterval: getEnvAsDuration("ORDER_SHIPPING_SLA_INTERVAL", 15*time.Minute),
+
+		HookRetryInterval: getEnvAsDuration("ORDER_HOOK_RETRY_INTERVAL", time.Minute),
+
+		ExportInterval:          getEnvAsDuration("ORDER_EXPORT_INTERVAL", 30*time.Second),
+		TaxReportGrossThreshold: getEnvAsFloat("TAX_REPORT_GROSS_THRESHOLD", 20000),
+		TaxReportOrderThreshold: getEnvAsInt("TAX_REPORT_ORDER_THRESHOLD", 200),
+	}
+
+	// Shipping
+	cfg.Shipping = ShippingConfig{
+		CarrierProvider:      getEnv("CARRIER_PROVIDER", "fake"),
+		CarrierWebhookSecret: getEnv("CARRIER_WEBHOOK_SECRET", ""),
+		AuthCenterName:       getEnv("SHIPPING_AUTH_CENTER_NAME", "Sneakers Marketplace Authentication"),
+		AuthCenterStreet:     getEnv("SHIPPING_AUTH_CENTER_STREET", "100 Verification Way"),
+		AuthCenterCity:       getEnv("SHIPPING_AUTH_CENTER_CITY", "Memphis"),
+		AuthCenterState:      getEnv("SHIPPING_AUTH_CENTER_STATE", "TN"),
+		AuthCenterPostalCode: getEnv("SHIPPING_AUTH_CENTER_POSTAL_CODE", "38118"),
+		AuthCenterCountry:    getEnv("SHIPPING_AUTH_CENTER_COUNTRY", "US"),
+	}
+
+	// Invoices
+	cfg.Invoice = InvoiceConfig{
+		IssuerName:    getEnv("INVOICE_ISSUER_NAME", "Sneakers Marketplace Inc."),
+		IssuerAddress: strings.Split(getEnv("INVOICE_ISSUER_ADDRESS", "100 Market Street;San Francisco, CA 94105;US"), ";"),
+		IssuerTaxID:   getEnv("INVOICE_ISSUER_TAX_ID", ""),
+		TaxRate:       getEnvAsFloat("INVOICE_TAX_RATE", 0),
+		TaxLabel:      getEnv("INVOICE_TAX_LABEL", "Sales tax"),
+	}
+
+	// Currencies
+	cfg.FX = FXConfig{
+		SettlementCurrency: strings.ToUpper(getEnv("SETTLEMENT_CURRENCY", "USD")),
+		RateProvider:       getEnv("FX_RATE_PROVIDER", "static"),
+		StaticRates:        getEnv("FX_STATIC_RATES", ""),
+		RatesFile:          getEnv("FX_RATES_FILE", ""),
+		RefreshInterval:    getEnvAsDuration("FX_REFRESH_INTERVAL", time.Hour),
+		MaxRateAge:         getEnvAsDuration("FX_MAX_RATE_AGE", 48*time.Hour),
+	}
+
+	// Seller payouts
+	cfg.Payout = PayoutConfig{
+		DefaultSchedule: getEnv("PAYOUT_DEFAULT_SCHEDULE", "weekly"),
+		MinimumPayout:   getEnvAsFloat("PAYOUT_MINIMUM", 25),
+		BatchInterval:   getEnvAsDuration("PAYOUT_BATCH_INTERVAL", time.Hour),
+	}
+
 	// Validate required fields
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -193,6 +339,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT_SECRET must be changed in production")
 	}
 
+	if len(c.FX.SettlementCurrency) != 3 {
+		return fmt.Errorf("SETTLEMENT_CURRENCY must be a 3-letter ISO 4217 code")
+	}
+
+	switch c.Payout.DefaultSchedule {
+	case "daily", "weekly", "on_demand":
+	default:
+		return fmt.Errorf("PAYOUT_DEFAULT_SCHEDULE must be daily, weekly or on_demand")
+	}
+
 	if c.Database.URL == "" {
 		return fmt.Errorf("DATABASE_URL is required")
 	}
@@ -231,6 +387,30 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -0,0 +1,481 @@
+// Package money represents amounts as integer minor units (cents) plus an
+// ISO 4217 currency, so prices, fees, refunds and payouts add up exactly.
+// Every operation that can produce a fraction of a minor unit takes an
+// explicit RoundingMode.
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for amounts stored without a currency. Services
+// set it to the configured settlement currency at startup, before any
+// amounts are read.
+var DefaultCurrency = "USD"
+
+// Money is an amount in minor units of Currency. The zero value is zero in
+// no particular currency and combines with any currency.
+type Money struct {
+	Currency string // ISO 4217 code, e.g. "USD"
+	Amount   int64  // minor units, e.g. cents
+}
+
+// RoundingMode decides what happens to a fraction of a minor unit
+type RoundingMode int
+
+const (
+	// HalfUp rounds to the nearest unit, halves away from zero
+	HalfUp RoundingMode = iota
+	// HalfEven rounds to the nearest unit, halves to the even unit
+	HalfEven
+	// Down truncates toward zero
+	Down
+	// Up rounds away from zero
+	Up
+)
+
+// zeroDecimalCurrencies have no minor unit
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true,
+	"KMF": true, "KRW": true, "MGA": true, "PYG": true, "RWF": true,
+	"UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true,
+	"XPF": true,
+}
+
+// Exponent returns the number of decimal places of currency's minor unit
+func Exponent(currency string) int {
+	if zeroDecimalCurrencies[strings.ToUpper(currency)] {
+		return 0
+	}
+	return 2
+}
+
+// New returns amount minor units of currency
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Zero returns zero in currency
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// FromMajor converts a major-unit float (e.g. 19.99 dollars) using its
+// shortest decimal representation, so 19.99 is 1999 cents and not 1998.
+// Digits beyond the minor unit are rounded with mode; amounts beyond the
+// int64 range saturate.
+func FromMajor(amount float64, currency string, mode RoundingMode) Money {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return Zero(currency)
+	}
+
+	m, err := ParseRound(strconv.FormatFloat(amount, 'f', -1, 64), currency, mode)
+	if err != nil {
+		// Out of range; saturate so validation rejects it downstream
+		if amount < 0 {
+			return New(math.MinInt64, currency)
+		}
+		return New(math.MaxInt64, currency)
+	}
+	return m
+}
+
+// Parse reads a decimal string such as "19.99". It fails if the value has
+// more decimal places than the currency's minor unit.
+func Parse(s, currency string) (Money, error) {
+	m, exact, err := parse(s, currency, Down)
+	if err != nil {
+		return Money{}, err
+	}
+	if !exact {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places", s, Exponent(currency))
+	}
+	return m, nil
+}
+
+// ParseRound reads a decimal string, rounding digits beyond the minor unit
+// with mode
+func ParseRound(s, currency string, mode RoundingMode) (Money, error) {
+	m, _, err := parse(s, currency, mode)
+	return m, err
+}
+
+func parse(s, currency string, mode RoundingMode) (Money, bool, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return Money{}, false, fmt.Errorf("invalid amount %q", s)
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Exponent(currency))), nil)
+	r.Mul(r, new(big.Rat).SetInt(scale))
+
+	amount, exact, err := roundRat(r, mode)
+	if err != nil {
+		return Money{}, false, fmt.Errorf("amount %q: %w", s, err)
+	}
+	return New(amount, currency), exact, nil
+}
+
+// FromProto reads an amount sent as minor units, falling back to the legacy
+// major-unit double for clients that do not set the minor-unit field yet
+func FromProto(minor int64, major float64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if minor != 0 {
+		return New(minor, currency)
+	}
+	return FromMajor(major, currency, HalfUp)
+}
+
+// WithCurrency reads m's decimal value as an amount of currency, for
+// NUMERIC columns scanned before their currency column was known. It does
+// not convert: 12.00 USD becomes 12 JPY. Use Exchange to convert.
+func (m Money) WithCurrency(currency string) Money {
+	currency = strings.ToUpper(currency)
+	from, to := Exponent(m.Currency), Exponent(currency)
+	if m.Currency == "" || from == to {
+		return Money{Amount: m.Amount, Currency: currency}
+	}
+	if to > from {
+		return New(m.Amount*pow10(to-from), currency)
+	}
+	return New(m.MulRat(1, pow10(from-to), HalfUp).Amount, currency)
+}
+
+// Major returns the amount in major units. Use it only at boundaries that
+// still speak floats (legacy proto fields, display).
+func (m Money) Major() float64 {
+	f, _ := strconv.ParseFloat(m.String(), 64)
+	return f
+}
+
+// String formats the amount in major units, e.g. "19.99" or "-0.05"
+func (m Money) String() string {
+	exp := Exponent(m.Currency)
+	if exp == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = uint64(-m.Amount)
+	}
+
+	digits := fmt.Sprintf("%0*d", exp+1, abs)
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// Format formats the amount with its currency, e.g. "19.99 USD"
+func (m Money) Format() string {
+	if m.Currency == "" {
+		return m.String()
+	}
+	return m.String() + " " + m.Currency
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// SameCurrency reports whether m and o can be combined
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == o.Currency || m.Currency == "" || o.Currency == ""
+}
+
+// Cmp compares m and o: -1 if m < o, 0 if equal, +1 if m > o. It panics on
+// mismatched currencies.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Equal reports whether m and o are the same amount in the same currency
+func (m Money) Equal(o Money) bool {
+	return m.SameCurrency(o) && m.Amount == o.Amount
+}
+
+// LessThan reports whether m < o
+func (m Money) LessThan(o Money) bool {
+	return m.Cmp(o) < 0
+}
+
+// GreaterThan reports whether m > o
+func (m Money) GreaterThan(o Money) bool {
+	return m.Cmp(o) > 0
+}
+
+// Add returns m + o. Mixing currencies is a programming error and panics;
+// convert first.
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Sub returns m - o. Mixing currencies panics.
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul returns m * n
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// MulRat returns m * num / den, rounded with mode
+func (m Money) MulRat(num, den int64, mode RoundingMode) Money {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+
+	r := new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num)),
+		big.NewInt(den),
+	)
+	amount, _, err := roundRat(r, mode)
+	if err != nil {
+		panic(err)
+	}
+	return Money{Amount: amount, Currency: m.Currency}
+}
+
+// MulBasisPoints returns bps/10000 of m (950 bps = 9.5%), rounded with mode
+func (m Money) MulBasisPoints(bps int64, mode RoundingMode) Money {
+	return m.MulRat(bps, 10000, mode)
+}
+
+// BasisPoints converts a percentage such as 9.5 to basis points (950),
+// rounding to the nearest basis point
+func BasisPoints(percent float64) int64 {
+	return int64(math.Round(percent * 100))
+}
+
+// Allocate splits m across weights in proportion, without creating or
+// losing minor units. Leftover units go to the largest remainders first,
+// ties to the earlier weight.
+func (m Money) Allocate(weights ...int64) []Money {
+	parts := make([]Money, len(weights))
+	var total int64
+	for _, w := range weights {
+		if w < 0 {
+			panic("money: negative allocation weight")
+		}
+		total += w
+	}
+	if total == 0 {
+		for i := range parts {
+			parts[i] = Zero(m.Currency)
+		}
+		if len(parts) > 0 {
+			parts[0] = m
+		}
+		return parts
+	}
+
+	type remainder struct {
+		rem   *big.Int
+		index int
+	}
+	rems := make([]remainder, len(weights))
+
+	sign := int64(1)
+	abs := m.Amount
+	if abs < 0 {
+		sign, abs = -1, -abs
+	}
+
+	var allocated int64
+	for i, w := range weights {
+		q, r := new(big.Int).QuoRem(
+			new(big.Int).Mul(big.NewInt(abs), big.NewInt(w)),
+			big.NewInt(total),
+			new(big.Int),
+		)
+		parts[i] = Money{Amount: q.Int64(), Currency: m.Currency}
+		allocated += q.Int64()
+		rems[i] = remainder{rem: r, index: i}
+	}
+
+	// Hand out the leftover units, largest remainder first
+	for left := abs - allocated; left > 0; left-- {
+		best := -1
+		for i, r := range rems {
+			if r.rem.Sign() > 0 && (best < 0 || r.rem.Cmp(rems[best].rem) > 0) {
+				best = i
+			}
+		}
+		parts[rems[best].index].Amount++
+		rems[best].rem.SetInt64(0)
+	}
+
+	if sign < 0 {
+		for i := range parts {
+			parts[i].Amount = -parts[i].Amount
+		}
+	}
+	return parts
+}
+
+// Min returns the smaller of a and b
+func Min(a, b Money) Money {
+	if a.LessThan(b) {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of a and b
+func Max(a, b Money) Money {
+	if a.GreaterThan(b) {
+		return a
+	}
+	return b
+}
+
+// Sum adds amounts; the sum of nothing is the zero value
+func Sum(amounts ...Money) Money {
+	var total Money
+	for _, a := range amounts {
+		total = total.Add(a)
+	}
+	return total
+}
+
+func (m Money) mustMatch(o Money) {
+	if !m.SameCurrency(o) {
+		panic(fmt.Sprintf("money: currency mismatch %s vs %s", m.Currency, o.Currency))
+	}
+}
+
+func (m Money) currencyWith(o Money) string {
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return o.Currency
+}
+
+// roundRat rounds r to an integer with mode, reporting whether it was exact
+func roundRat(r *big.Rat, mode RoundingMode) (int64, bool, error) {
+	num, den := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	exact := rem.Sign() == 0
+
+	if !exact {
+		// twice the remainder against the denominator tells below/at/above half
+		half := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den)
+		away := false
+		switch mode {
+		case HalfUp:
+			away = half >= 0
+		case HalfEven:
+			away = half > 0 || (half == 0 && q.Bit(0) == 1)
+		case Up:
+			away = true
+		case Down:
+		}
+		if away {
+			q.Add(q, big.NewInt(int64(num.Sign())))
+		}
+	}
+
+	if !q.IsInt64() {
+		return 0, false, fmt.Errorf("amount out of range")
+	}
+	return q.Int64(), exact, nil
+}
+
+// Scan reads a NUMERIC column. The currency is left unchanged, or set to
+// DefaultCurrency when empty; repositories with a currency column set it
+// after scanning.
+func (m *Money) Scan(src interface{}) error {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	var parsed Money
+	var err error
+	switch v := src.(type) {
+	case nil:
+		parsed = Zero(currency)
+	case string:
+		parsed, err = ParseRound(v, currency, HalfUp)
+	case []byte:
+		parsed, err = ParseRound(string(v), currency, HalfUp)
+	case int64:
+		parsed = New(v*int64(math.Pow10(Exponent(currency))), currency)
+	case float64:
+		parsed = FromMajor(v, currency, HalfUp)
+	default:
+		return fmt.Errorf("cannot scan %T into money", src)
+	}
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Value writes the amount in major units for a NUMERIC column
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// MarshalJSON writes the amount as a JSON number in major units, the shape
+// the API used before amounts were integers
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number or string in major units
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		return nil
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	parsed, err := Parse(s, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,307 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFromMajor(t *testing.T) {
+	tests := []struct {
+		in   float64
+		mode RoundingMode
+		want int64
+	}{
+		{19.99, Down, 1999}, // int64(19.99*100) is 1998
+		{0.29, Down, 29},    // int64(0.29*100) is 28
+		{1.005, HalfUp, 101},
+		{1.005, HalfEven, 100},
+		{1.015, HalfEven, 102},
+		{1.001, Up, 101},
+		{1.009, Down, 100},
+		{-1.005, HalfUp, -101},
+		{-1.009, Down, -100},
+		{250, HalfUp, 25000},
+	}
+
+	for _, tt := range tests {
+		if got := FromMajor(tt.in, "USD", tt.mode); got.Amount != tt.want {
+			t.Errorf("FromMajor(%v, %v) = %d, want %d", tt.in, tt.mode, got.Amount, tt.want)
+		}
+	}
+
+	if got := FromMajor(1234.5, "JPY", HalfUp); got.Amount != 1235 {
+		t.Errorf("JPY has no minor unit: got %d, want 1235", got.Amount)
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse("125.50", "usd")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if m.Amount != 12550 || m.Currency != "USD" {
+		t.Errorf("Parse = %+v", m)
+	}
+
+	for _, bad := range []string{"", "abc", "1.005", "1e3", "1/3"} {
+		if _, err := Parse(bad, "USD"); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+
+	m, err = ParseRound("1.005", "USD", HalfEven)
+	if err != nil || m.Amount != 100 {
+		t.Errorf("ParseRound(1.005, HalfEven) = %+v, %v", m, err)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{New(1999, "USD"), "19.99"},
+		{New(5, "USD"), "0.05"},
+		{New(-5, "USD"), "-0.05"},
+		{New(0, "USD"), "0.00"},
+		{New(1500, "JPY"), "1500"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+
+	if got := New(1999, "USD").Major(); got != 19.99 {
+		t.Errorf("Major() = %v, want 19.99", got)
+	}
+	if got := New(1999, "USD").Format(); got != "19.99 USD" {
+		t.Errorf("Format() = %q", got)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a := New(1000, "USD")
+	b := New(250, "USD")
+
+	if got := a.Add(b); got.Amount != 1250 {
+		t.Errorf("Add = %d", got.Amount)
+	}
+	if got := a.Sub(b); got.Amount != 750 {
+		t.Errorf("Sub = %d", got.Amount)
+	}
+	if got := b.Mul(3); got.Amount != 750 {
+		t.Errorf("Mul = %d", got.Amount)
+	}
+	if !b.LessThan(a) || !a.GreaterThan(b) || a.Cmp(a) != 0 {
+		t.Error("comparisons are wrong")
+	}
+	if got := Min(a, b); got.Amount != 250 {
+		t.Errorf("Min = %d", got.Amount)
+	}
+
+	// The zero value adopts the other currency
+	var total Money
+	total = total.Add(a)
+	if total.Currency != "USD" || total.Amount != 1000 {
+		t.Errorf("zero value Add = %+v", total)
+	}
+	if got := Sum(a, b, b); got.Amount != 1500 {
+		t.Errorf("Sum = %d", got.Amount)
+	}
+}
+
+func TestCurrencyMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("adding USD to EUR should panic")
+		}
+	}()
+	New(100, "USD").Add(New(100, "EUR"))
+}
+
+func TestMulBasisPoints(t *testing.T) {
+	price := New(12345, "USD") // $123.45
+
+	// 9.5% of 123.45 is 11.72775
+	if got := price.MulBasisPoints(950, HalfUp); got.Amount != 1173 {
+		t.Errorf("HalfUp = %d, want 1173", got.Amount)
+	}
+	if got := price.MulBasisPoints(950, Down); got.Amount != 1172 {
+		t.Errorf("Down = %d, want 1172", got.Amount)
+	}
+
+	// 3% of 0.50 is exactly 0.015
+	half := New(50, "USD")
+	if got := half.MulBasisPoints(300, HalfUp); got.Amount != 2 {
+		t.Errorf("HalfUp on a half = %d, want 2", got.Amount)
+	}
+	if got := half.MulBasisPoints(300, HalfEven); got.Amount != 2 {
+		t.Errorf("HalfEven on 1.5 = %d, want 2", got.Amount)
+	}
+	if got := New(150, "USD").MulBasisPoints(100, HalfEven); got.Amount != 2 {
+		t.Errorf("HalfEven on 1.5 = %d, want 2", got.Amount)
+	}
+	if got := New(250, "USD").MulBasisPoints(100, HalfEven); got.Amount != 2 {
+		t.Errorf("HalfEven on 2.5 = %d, want 2", got.Amount)
+	}
+
+	if got := BasisPoints(9.5); got != 950 {
+		t.Errorf("BasisPoints(9.5) = %d", got)
+	}
+	if got := BasisPoints(0.07 * 100); got != 700 {
+		t.Errorf("BasisPoints(7) = %d", got)
+	}
+}
+
+func TestFeeAndPayoutAddUp(t *testing.T) {
+	// Fee plus payout must equal the price for every cent amount
+	for cents := int64(1); cents <= 100000; cents += 7 {
+		price := New(cents, "USD")
+		fee := price.MulBasisPoints(950, HalfUp)
+		payout := price.Sub(fee)
+		if fee.Add(payout) != price {
+			t.Fatalf("price %s: fee %s + payout %s", price, fee, payout)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	parts := New(1000, "USD").Allocate(1, 1, 1)
+	want := []int64{334, 333, 333}
+	for i, p := range parts {
+		if p.Amount != want[i] {
+			t.Errorf("part %d = %d, want %d", i, p.Amount, want[i])
+		}
+	}
+
+	// Proportional split of a checkout total
+	parts = New(10001, "USD").Allocate(2500, 7500)
+	if parts[0].Amount+parts[1].Amount != 10001 || parts[1].Amount != 7501 {
+		t.Errorf("Allocate(2500, 7500) = %+v", parts)
+	}
+
+	neg := New(-100, "USD").Allocate(1, 2)
+	if neg[0].Amount+neg[1].Amount != -100 {
+		t.Errorf("negative allocation lost units: %+v", neg)
+	}
+
+	zero := New(500, "USD").Allocate(0, 0)
+	if zero[0].Amount != 500 || zero[1].Amount != 0 {
+		t.Errorf("zero weights = %+v", zero)
+	}
+}
+
+func TestScanAndValue(t *testing.T) {
+	var m Money
+	if err := m.Scan("123.45"); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if m.Amount != 12345 || m.Currency != DefaultCurrency {
+		t.Errorf("Scan = %+v", m)
+	}
+
+	eur := Money{Currency: "EUR"}
+	if err := eur.Scan([]byte("9.90")); err != nil || eur.Amount != 990 || eur.Currency != "EUR" {
+		t.Errorf("Scan keeps currency: %+v, %v", eur, err)
+	}
+
+	if err := m.Scan(nil); err != nil || !m.IsZero() {
+		t.Errorf("Scan(nil) = %+v, %v", m, err)
+	}
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan(bool) should fail")
+	}
+
+	v, err := New(12345, "USD").Value()
+	if err != nil || v != "123.45" {
+		t.Errorf("Value = %v, %v", v, err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type item struct {
+		Price Money `json:"price"`
+	}
+
+	data, err := json.Marshal(item{Price: New(1999, "USD")})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `{"price":19.99}` {
+		t.Errorf("Marshal = %s", data)
+	}
+
+	var got item
+	if err := json.Unmarshal([]byte(`{"price":125.5}`), &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.Price.Amount != 12550 {
+		t.Errorf("Unmarshal = %+v", got.Price)
+	}
+	if err := json.Unmarshal([]byte(`{"price":"0.10"}`), &got); err != nil || got.Price.Amount != 10 {
+		t.Errorf("Unmarshal string = %+v, %v", got.Price, err)
+	}
+	if err := json.Unmarshal([]byte(`{"price":1.001}`), &got); err == nil {
+		t.Error("sub-cent JSON amounts should be rejected")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	rate, err := ParseRate("0.9231")
+	if err != nil {
+		t.Fatalf("ParseRate: %v", err)
+	}
+	if got := New(10000, "USD").Exchange(rate, "EUR", HalfUp); got != New(9231, "EUR") {
+		t.Errorf("100 USD -> EUR = %v", got)
+	}
+
+	jpy, _ := ParseRate("149.5")
+	if got := New(1999, "USD").Exchange(jpy, "JPY", HalfUp); got != New(2989, "JPY") {
+		t.Errorf("19.99 USD -> JPY = %v, want 2989 JPY", got)
+	}
+	if got := New(2989, "JPY").Exchange(jpy.Inverse(), "USD", HalfUp); got != New(1999, "USD") {
+		t.Errorf("2989 JPY -> USD = %v, want 19.99 USD", got)
+	}
+}
+
+func TestRate(t *testing.T) {
+	for _, bad := range []string{"", "0", "-1.2", "1e3", "0.00000000001"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("ParseRate(%q) should fail", bad)
+		}
+	}
+
+	r, _ := ParseRate("3")
+	if got := r.Inverse().String(); got != "0.3333333333" {
+		t.Errorf("Inverse = %s", got)
+	}
+	eurUSD, _ := ParseRate("1.08")
+	usdJPY, _ := ParseRate("150")
+	if got := eurUSD.Mul(usdJPY).String(); got != "162" {
+		t.Errorf("cross rate = %s", got)
+	}
+
+	var scanned Rate
+	if err := scanned.Scan("1.0800000000"); err != nil || !scanned.Equal(eurUSD) {
+		t.Errorf("Scan = %s, %v", scanned, err)
+	}
+	data, _ := json.Marshal(eurUSD)
+	if string(data) != `"1.08"` {
+		t.Errorf("Marshal = %s", data)
+	}
+}
+
+func TestWithCurrency(t *testing.T) {
+	var m Money
+	if err := m.Scan("1500.00"); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if got := m.WithCurrency("jpy"); got != New(1500, "JPY") {
+		t.Errorf("WithCurrency(JPY) = %+v", got)
+	}
+	if got := New(1500, "JPY").WithCurrency("EUR"); got != New(150000, "EUR") {
+		t.Errorf("WithCurrency(EUR) = %+v", got)
+	}
+}
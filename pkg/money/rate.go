@@ -0,0 +1,188 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RateScale is the number of decimal places an exchange rate keeps. Rates
+// are rounded to it once, when derived, so a stored rate replays a
+// conversion exactly.
+const RateScale = 10
+
+// maxRate keeps a rate's scaled value within int64
+const maxRate = 1e8
+
+// Rate is an exchange rate: one unit of a base currency buys Rate units of
+// the quote currency. The zero value is no rate.
+type Rate struct {
+	r *big.Rat
+}
+
+// ParseRate reads a positive decimal rate such as "0.9231"
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return Rate{}, fmt.Errorf("invalid rate %q", s)
+	}
+	if r.Cmp(big.NewRat(maxRate, 1)) > 0 {
+		return Rate{}, fmt.Errorf("rate %q out of range", s)
+	}
+	r = roundRate(r)
+	if r.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("rate %q must be positive", s)
+	}
+	return Rate{r: r}, nil
+}
+
+// OneRate is the rate between a currency and itself
+func OneRate() Rate {
+	return Rate{r: big.NewRat(1, 1)}
+}
+
+// IsZero reports whether r is the zero value
+func (r Rate) IsZero() bool {
+	return r.r == nil
+}
+
+// IsOne reports whether r converts without changing the amount
+func (r Rate) IsOne() bool {
+	return r.r != nil && r.r.Cmp(big.NewRat(1, 1)) == 0
+}
+
+// Inverse returns the rate for the opposite direction, rounded to RateScale
+func (r Rate) Inverse() Rate {
+	r.mustBeSet()
+	return Rate{r: roundRate(new(big.Rat).Inv(r.r))}
+}
+
+// Mul chains two rates (A->B then B->C gives A->C), rounded to RateScale
+func (r Rate) Mul(o Rate) Rate {
+	r.mustBeSet()
+	o.mustBeSet()
+	return Rate{r: roundRate(new(big.Rat).Mul(r.r, o.r))}
+}
+
+// Equal reports whether both rates are the same number
+func (r Rate) Equal(o Rate) bool {
+	if r.r == nil || o.r == nil {
+		return r.r == o.r
+	}
+	return r.r.Cmp(o.r) == 0
+}
+
+// String writes the rate as a decimal without trailing zeros
+func (r Rate) String() string {
+	if r.r == nil {
+		return ""
+	}
+	s := r.r.FloatString(RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Exchange converts m into currency at rate, where rate is the price of one
+// unit of m's currency in currency, rounded to currency's minor unit with
+// mode
+func (m Money) Exchange(rate Rate, currency string, mode RoundingMode) Money {
+	rate.mustBeSet()
+
+	r := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate.r)
+	from, to := Exponent(m.Currency), Exponent(currency)
+	if to > from {
+		r.Mul(r, new(big.Rat).SetInt64(pow10(to-from)))
+	} else if from > to {
+		r.Quo(r, new(big.Rat).SetInt64(pow10(from-to)))
+	}
+
+	amount, _, err := roundRat(r, mode)
+	if err != nil {
+		panic(err)
+	}
+	return New(amount, currency)
+}
+
+// Scan reads a NUMERIC rate column; NULL leaves the zero value
+func (r *Rate) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case float64:
+		s = fmt.Sprintf("%.*f", RateScale, v)
+	case int64:
+		s = fmt.Sprintf("%d", v)
+	default:
+		return fmt.Errorf("cannot scan %T into rate", src)
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value writes the rate for a NUMERIC column; the zero value is NULL
+func (r Rate) Value() (driver.Value, error) {
+	if r.r == nil {
+		return nil, nil
+	}
+	return r.String(), nil
+}
+
+// MarshalJSON writes the rate as a JSON string so no precision is lost
+func (r Rate) MarshalJSON() ([]byte, error) {
+	if r.r == nil {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + r.String() + `"`), nil
+}
+
+// UnmarshalJSON reads a rate from a JSON string or number
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*r = Rate{}
+		return nil
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r Rate) mustBeSet() {
+	if r.r == nil {
+		panic("money: zero rate")
+	}
+}
+
+// roundRate rounds r half-up to RateScale decimal places
+func roundRate(r *big.Rat) *big.Rat {
+	scale := pow10(RateScale)
+	n, _, err := roundRat(new(big.Rat).Mul(r, new(big.Rat).SetInt64(scale)), HalfUp)
+	if err != nil {
+		panic(err)
+	}
+	return new(big.Rat).SetFrac64(n, scale)
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
@@ -0,0 +1,74 @@
+// Package pagination implements opaque keyset cursors: the sort value and ID
+// of the last row of a page, tied to the sort they were created for.
+package pagination
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeLayout formats time sort values with microsecond precision, matching
+// Postgres TIMESTAMP columns
+const TimeLayout = "2006-01-02 15:04:05.999999"
+
+// Cursor marks the last row of a page
+type Cursor struct {
+	Sort  string // sort the cursor belongs to, e.g. "created_at:desc"
+	Value string // sort value of the last row
+	ID    int64  // tie-breaker
+}
+
+// TimeValue formats a time sort value
+func TimeValue(t time.Time) string {
+	return t.UTC().Format(TimeLayout)
+}
+
+// Encode returns the opaque cursor string handed to clients
+func (c Cursor) Encode() string {
+	raw := c.Sort + "|" + c.Value + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor and checks that it was created for sort. An empty
+// string decodes to nil (first page).
+func Decode(s, sort string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	if parts[0] != sort {
+		return nil, fmt.Errorf("cursor does not match the requested sort")
+	}
+
+	return &Cursor{Sort: parts[0], Value: parts[1], ID: id}, nil
+}
+
+// ValidTime reports whether the cursor value is a time sort value
+func (c *Cursor) ValidTime() bool {
+	_, err := time.Parse(TimeLayout, c.Value)
+	return err == nil
+}
+
+// ValidAmount reports whether the cursor value is a numeric sort value
+func (c *Cursor) ValidAmount() bool {
+	_, err := strconv.ParseFloat(c.Value, 64)
+	return err == nil
+}